@@ -32,7 +32,8 @@ import (
 // - erc20: for ERC20 token accounts
 
 // regularAccountCode returns an account code based on a keystore root fingerprint, a coin code and
-// an account number.
+// an account number. The coin code keeps the codes of coins sharing the same keypaths apart, e.g.
+// the Bitcoin testnet3, testnet4 and signet accounts of a keystore.
 func regularAccountCode(rootFingerprint []byte, coinCode coin.Code, accountNumber uint16) accountsTypes.Code {
 	return accountsTypes.Code(fmt.Sprintf("v0-%x-%s-%d", rootFingerprint, coinCode, accountNumber))
 }
@@ -76,10 +76,12 @@ func sortAccounts(accounts []accounts.Interface) {
 	compareCoin := func(coin1, coin2 coinpkg.Coin) int {
 		getOrder := func(c coinpkg.Coin) (int, bool) {
 			order, ok := map[coinpkg.Code]int{
-				coinpkg.CodeBTC:  0,
-				coinpkg.CodeTBTC: 1,
-				coinpkg.CodeLTC:  2,
-				coinpkg.CodeTLTC: 3,
+				coinpkg.CodeBTC:   0,
+				coinpkg.CodeTBTC:  1,
+				coinpkg.CodeTBTC4: 2,
+				coinpkg.CodeSBTC:  3,
+				coinpkg.CodeLTC:   4,
+				coinpkg.CodeTLTC:  5,
 			}[c.Code()]
 			if ok {
 				return order, true
@@ -90,11 +92,19 @@ func sortAccounts(accounts []accounts.Interface) {
 			if ok {
 				switch ethCoin.ChainID() {
 				case params.MainnetChainConfig.ChainID.Uint64():
-					return 4, true
+					return 6, true
 				case params.GoerliChainConfig.ChainID.Uint64():
-					return 5, true
+					return 7, true
 				case params.SepoliaChainConfig.ChainID.Uint64():
-					return 6, true
+					return 8, true
+				case eth.ChainIDArbitrum:
+					return 9, true
+				case eth.ChainIDOptimism:
+					return 10, true
+				case eth.ChainIDBase:
+					return 11, true
+				case eth.ChainIDPolygon:
+					return 12, true
 				}
 			}
 			return 0, false
@@ -176,9 +186,10 @@ func (backend *Backend) filterAccounts(accountsConfig *config.AccountsConfig, fi
 // SupportedCoins returns the list of coins that can be used with the given keystore.
 func (backend *Backend) SupportedCoins(keystore keystore.Keystore) []coinpkg.Code {
 	allCoins := []coinpkg.Code{
-		coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeRBTC,
+		coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeTBTC4, coinpkg.CodeSBTC, coinpkg.CodeRBTC,
 		coinpkg.CodeLTC, coinpkg.CodeTLTC,
 		coinpkg.CodeETH, coinpkg.CodeGOETH, coinpkg.CodeSEPETH,
+		coinpkg.CodeARBETH, coinpkg.CodeOPETH, coinpkg.CodeBASEETH, coinpkg.CodePOL,
 	}
 	var availableCoins []coinpkg.Code
 	for _, coinCode := range allCoins {
@@ -333,7 +344,7 @@ func (backend *Backend) createAndPersistAccountConfig(
 	accountNumberHardened := uint32(accountNumber) + hardenedKeystart
 
 	switch coinCode {
-	case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeRBTC:
+	case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeTBTC4, coinpkg.CodeSBTC, coinpkg.CodeRBTC:
 		bip44Coin := 1 + hardenedKeystart
 		if coinCode == coinpkg.CodeBTC {
 			bip44Coin = hardenedKeystart
@@ -365,9 +376,12 @@ func (backend *Backend) createAndPersistAccountConfig(
 			},
 			accountsConfig,
 		)
-	case coinpkg.CodeETH, coinpkg.CodeGOETH, coinpkg.CodeSEPETH:
+	case coinpkg.CodeETH, coinpkg.CodeGOETH, coinpkg.CodeSEPETH,
+		coinpkg.CodeARBETH, coinpkg.CodeOPETH, coinpkg.CodeBASEETH, coinpkg.CodePOL:
 		bip44Coin := "1'"
-		if coinCode == coinpkg.CodeETH {
+		if _, isTestnet := coinpkg.TestnetCoins[coinCode]; !isTestnet {
+			// The other EVM networks use the keypath of Ethereum, so that the same address is used
+			// on all of them.
 			bip44Coin = "60'"
 		}
 		return accountCode, backend.persistETHAccountConfig(
@@ -756,13 +770,26 @@ func (backend *Backend) createAndAddAccount(coin coinpkg.Coin, persistedConfig *
 			}
 			return ks, err
 		},
+		ConnectedKeystore: func() keystore.Keystore {
+			ks := backend.Keystore()
+			if ks == nil {
+				return nil
+			}
+			accountRootFingerprint, err := persistedConfig.SigningConfigurations.RootFingerprint()
+			if err != nil || compareRootFingerprint(ks, accountRootFingerprint) != nil {
+				return nil
+			}
+			return ks
+		},
 		OnEvent: func(event accountsTypes.Event) {
 			backend.events <- AccountEvent{
 				Type: "account", Code: persistedConfig.Code,
 				Data: string(event),
 			}
+			backend.onChartAccountEvent(persistedConfig.Code, event)
 			if account != nil && event == accountsTypes.EventSyncDone {
 				backend.notifyNewTxs(account)
+				go backend.updateInternalTransfers()
 			}
 		},
 		RateUpdater: backend.ratesUpdater,
@@ -772,6 +799,15 @@ func (backend *Backend) createAndAddAccount(coin coinpkg.Coin, persistedConfig *
 		GetSaveFilename:  backend.environment.GetSaveFilename,
 		UnsafeSystemOpen: backend.environment.SystemOpen,
 		BtcCurrencyUnit:  backend.config.AppConfig().Backend.BtcUnit,
+		ContactName: func(address string) string {
+			return backend.contactName(coin, address)
+		},
+		InternalTransfers: func(internalID string) []accounts.InternalTransfer {
+			return backend.internalTransferLinks(persistedConfig.Code, internalID)
+		},
+		FiatList: func() []string {
+			return backend.config.AppConfig().Backend.FiatList
+		},
 	}
 
 	switch specificCoin := coin.(type) {
@@ -1154,6 +1190,8 @@ func (backend *Backend) maybeAddP2TR(keystore keystore.Keystore, accounts []*con
 	for _, account := range accounts {
 		if account.CoinCode == coinpkg.CodeBTC ||
 			account.CoinCode == coinpkg.CodeTBTC ||
+			account.CoinCode == coinpkg.CodeTBTC4 ||
+			account.CoinCode == coinpkg.CodeSBTC ||
 			account.CoinCode == coinpkg.CodeRBTC {
 			coin, err := backend.Coin(account.CoinCode)
 			if err != nil {
@@ -1264,6 +1302,7 @@ func (backend *Backend) uninitAccounts(force bool) {
 			backend.onAccountUninit(account)
 		}
 		account.Close()
+		backend.removeChartTimeline(account.Config().Config.Code)
 	}
 	backend.accounts = keep
 }
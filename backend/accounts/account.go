@@ -41,6 +41,16 @@ type TxProposalArgs struct {
 	CustomFee     string
 	SelectedUTXOs map[wire.OutPoint]struct{}
 	Note          string
+	// PayjoinURL is the BIP-78 payjoin endpoint given in the `pj` parameter of a BIP-21 URI. Only
+	// applies to BTC. If empty, no payjoin is attempted.
+	PayjoinURL string
+	// Nonce, GasLimit and Data only apply to ETH. Nonce overrides the next nonce of the account
+	// if not nil, e.g. to replace a stuck transaction.
+	Nonce *uint64
+	// GasLimit overrides the estimated gas limit if not zero.
+	GasLimit uint64
+	// Data is the calldata of the transaction, e.g. to call a contract. Not allowed for tokens.
+	Data []byte
 }
 
 // Interface is the API of a Account.
@@ -90,6 +100,9 @@ type Interface interface {
 	ProposeTxNote(string)
 	// SetTxNote sets a tx note and refreshes the account.
 	SetTxNote(txID string, note string) error
+	// TxFiatValues returns the fiat values of the transaction amount recorded when the transaction
+	// was sent or first seen, keyed by fiat currency. Returns nil if none were recorded.
+	TxFiatValues(txID string) map[string]string
 
 	// ExportCSV exports the given transaction in CSV format (comma-separated).
 	ExportCSV(w io.Writer, transactions []*TransactionData) error
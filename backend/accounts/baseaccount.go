@@ -18,8 +18,10 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"path"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -46,16 +48,36 @@ type AccountConfig struct {
 	// NotesFolder is the folder where the transaction notes are stored. Full path.
 	NotesFolder     string
 	ConnectKeystore func() (keystore.Keystore, error)
-	OnEvent         func(types.Event)
-	RateUpdater     *rates.RateUpdater
-	GetNotifier     func(signing.Configurations) Notifier
-	GetSaveFilename func(suggestedFilename string) string
+	// ConnectedKeystore returns the keystore of the account if it is already connected, or nil
+	// otherwise. Unlike ConnectKeystore, it does not block. Can be nil, in which case no keystore
+	// is assumed to be connected.
+	ConnectedKeystore func() keystore.Keystore
+	OnEvent           func(types.Event)
+	RateUpdater       *rates.RateUpdater
+	GetNotifier       func(signing.Configurations) Notifier
+	GetSaveFilename   func(suggestedFilename string) string
 	// Opens a file in a default application. The filename is not checked.
 	UnsafeSystemOpen func(filename string) error
 	// BtcCurrencyUnit is the unit which should be used to format fiat amounts values expressed in BTC..
 	BtcCurrencyUnit coin.BtcUnit
+	// ContactName returns the name of the address book contact with the given address, or an empty
+	// string if there is none. Can be nil, in which case no contacts are resolved.
+	ContactName func(address string) string
+	// InternalTransfers returns the links of the transaction with the given internal ID to the
+	// transactions of our other accounts, if it is an internal transfer. Can be nil, in which case
+	// no internal transfers are linked.
+	InternalTransfers func(internalID string) []InternalTransfer
+	// FiatList returns the enabled fiat currencies, in which the fiat values of sent and received
+	// transactions are recorded. Can be nil, in which case they are recorded in all fiat currencies
+	// with an exchange rate available.
+	FiatList func() []string
 }
 
+// fiatValuesMaxAge is how old an incoming transaction can be when it is first seen for its fiat
+// value to be recorded using the latest exchange rates. Older transactions, e.g. those found when
+// restoring a wallet, are valued using historical exchange rates instead.
+const fiatValuesMaxAge = 2 * time.Hour
+
 // BaseAccount is an account struct with common functionality to all coin accounts.
 type BaseAccount struct {
 	observable.Implementation
@@ -81,6 +103,12 @@ type BaseAccount struct {
 	proposedTxNote   string
 	proposedTxNoteMu sync.Mutex
 
+	proposedTxFiatValues   map[string]string
+	proposedTxFiatValuesMu sync.Mutex
+
+	// onSyncFinished is called after each sync, see OnSyncFinished.
+	onSyncFinished func()
+
 	log *logrus.Entry
 }
 
@@ -98,12 +126,22 @@ func NewBaseAccount(config *AccountConfig, coin coin.Coin, log *logrus.Entry) *B
 				config.OnEvent(types.EventStatusChanged)
 			}
 			config.OnEvent(types.EventSyncDone)
+			if account.onSyncFinished != nil {
+				account.onSyncFinished()
+			}
 		},
 		log,
 	)
 	return account
 }
 
+// OnSyncFinished sets a function which is called after each sync of the account, e.g. to process
+// the synced transactions. It must be set before the account is initialized. The function is
+// called while the synchronizer is locked and must not wait for it.
+func (account *BaseAccount) OnSyncFinished(f func()) {
+	account.onSyncFinished = f
+}
+
 // Config implements Interface.
 func (account *BaseAccount) Config() *AccountConfig {
 	return account.config
@@ -262,6 +300,133 @@ func (account *BaseAccount) TxNote(txID string) string {
 	return ""
 }
 
+// fiatValues returns the fiat values of the amount at the latest exchange rates in all enabled fiat
+// currencies, as shown to the user. Returns nil if no exchange rates are available.
+func (account *BaseAccount) fiatValues(amount coin.Amount) map[string]string {
+	if account.config.RateUpdater == nil {
+		return nil
+	}
+	latest := account.config.RateUpdater.LatestPrice()[account.coin.Unit(false)]
+	if len(latest) == 0 {
+		return nil
+	}
+	fiats := make([]string, 0, len(latest))
+	if account.config.FiatList != nil {
+		fiats = account.config.FiatList()
+	} else {
+		for fiat := range latest {
+			fiats = append(fiats, fiat)
+		}
+	}
+	coinDecimals := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(account.coin.Decimals(false))), nil)
+	values := map[string]string{}
+	for _, fiat := range fiats {
+		rate, ok := latest[fiat]
+		if !ok {
+			continue
+		}
+		// The rate is used as the shortest decimal representing it, which is the one received from
+		// the rates provider, instead of its exact binary value.
+		decimalRate, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+		if !ok {
+			continue
+		}
+		value := new(big.Rat).Mul(new(big.Rat).SetFrac(amount.BigInt(), coinDecimals), decimalRate)
+		values[fiat] = coin.FormatAsPlainCurrency(value, fiat == rates.BTC.String(), false)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// ProposeTxFiatValues records the fiat values of the amount of the proposed transaction, to be
+// persisted when it is sent. See GetAndClearProposedTxFiatValues().
+func (account *BaseAccount) ProposeTxFiatValues(amount coin.Amount) {
+	values := account.fiatValues(amount)
+	account.proposedTxFiatValuesMu.Lock()
+	defer account.proposedTxFiatValuesMu.Unlock()
+
+	account.proposedTxFiatValues = values
+}
+
+// GetAndClearProposedTxFiatValues returns the fiat values previously recorded using
+// ProposeTxFiatValues(). If none were recorded, nil is returned. The proposed values are cleared by
+// calling this function.
+func (account *BaseAccount) GetAndClearProposedTxFiatValues() map[string]string {
+	account.proposedTxFiatValuesMu.Lock()
+	defer func() {
+		account.proposedTxFiatValues = nil
+		account.proposedTxFiatValuesMu.Unlock()
+	}()
+	return account.proposedTxFiatValues
+}
+
+// SetTxFiatValues persists the fiat values of the amount of a transaction, keyed by fiat currency.
+func (account *BaseAccount) SetTxFiatValues(txID string, values map[string]string) error {
+	// The notes slice is guaranteed to have at least one element by BaseAccount.Initialize.
+	return account.notes[0].SetTxFiatValues(txID, values)
+}
+
+// TxFiatValues implements accounts.Interface.
+func (account *BaseAccount) TxFiatValues(txID string) map[string]string {
+	if len(account.notes) == 0 {
+		return nil
+	}
+	return account.notes[0].TxFiatValues(txID)
+}
+
+// RecordIncomingFiatValues persists the fiat values of incoming transactions seen for the first
+// time, at the latest exchange rates. Only pending and recent transactions are considered, see
+// fiatValuesMaxAge.
+func (account *BaseAccount) RecordIncomingFiatValues(transactions OrderedTransactions) {
+	if len(account.notes) == 0 {
+		return
+	}
+	for _, transaction := range transactions {
+		if transaction.Type != TxTypeReceive || account.TxFiatValues(transaction.InternalID) != nil {
+			continue
+		}
+		if transaction.Status != TxStatusPending &&
+			(transaction.Timestamp == nil || time.Since(*transaction.Timestamp) > fiatValuesMaxAge) {
+			continue
+		}
+		values := account.fiatValues(transaction.Amount)
+		if values == nil {
+			// No exchange rates yet, try again later.
+			return
+		}
+		if err := account.SetTxFiatValues(transaction.InternalID, values); err != nil {
+			account.log.WithError(err).Error("Failed to save the fiat values of a received transaction")
+		}
+	}
+}
+
+// Annotate returns the transactions with the address book contact names of their addresses and the
+// links of internal transfers filled in. The transactions are copied, so the ones passed in are not
+// modified.
+func (account *BaseAccount) Annotate(transactions OrderedTransactions) OrderedTransactions {
+	if account.config.ContactName == nil && account.config.InternalTransfers == nil {
+		return transactions
+	}
+	result := make(OrderedTransactions, len(transactions))
+	for i, transaction := range transactions {
+		txCopy := *transaction
+		if account.config.ContactName != nil {
+			txCopy.Addresses = make([]AddressAndAmount, len(transaction.Addresses))
+			for j, addressAndAmount := range transaction.Addresses {
+				addressAndAmount.ContactName = account.config.ContactName(addressAndAmount.Address)
+				txCopy.Addresses[j] = addressAndAmount
+			}
+		}
+		if account.config.InternalTransfers != nil {
+			txCopy.InternalTransfers = account.config.InternalTransfers(transaction.InternalID)
+		}
+		result[i] = &txCopy
+	}
+	return result
+}
+
 // ExportCSV implements accounts.Account.
 func (account *BaseAccount) ExportCSV(w io.Writer, transactions []*TransactionData) error {
 	writer := csv.NewWriter(w)
@@ -285,6 +450,21 @@ func (account *BaseAccount) ExportCSV(w io.Writer, transactions []*TransactionDa
 			TxTypeSend:     "sent",
 			TxTypeSendSelf: "sent_to_yourself",
 		}[transaction.Type]
+		if transaction.IsInternalTransfer() {
+			switch transaction.Type {
+			case TxTypeReceive:
+				transactionType = "received_from_own_account"
+			case TxTypeSend:
+				transferred := new(big.Int)
+				for _, transfer := range transaction.InternalTransfers {
+					transferred.Add(transferred, transfer.Amount.BigInt())
+				}
+				// Sends that only partially go to our own accounts are exported as regular sends.
+				if transferred.Cmp(transaction.Amount.BigInt()) >= 0 {
+					transactionType = "sent_to_own_account"
+				}
+			}
+		}
 		feeString := ""
 		fee := transaction.Fee
 		if fee != nil {
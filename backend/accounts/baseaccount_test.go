@@ -27,6 +27,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin/mocks"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
@@ -219,5 +220,158 @@ func TestBaseAccount(t *testing.T) {
 				},
 			}))
 
+		require.Equal(t,
+			header+
+				`2020-03-01T16:44:20Z,sent_to_own_account,123,satoshi,101,some-address,some-tx-id,
+2020-03-01T16:44:20Z,received_from_own_account,123,satoshi,,our-address,other-tx-id,
+`,
+			export([]*TransactionData{
+				{
+					Type:              TxTypeSend,
+					TxID:              "some-tx-id",
+					InternalID:        "some-tx-id",
+					Fee:               &fee,
+					Timestamp:         &timestamp,
+					Amount:            coin.NewAmountFromInt64(123),
+					InternalTransfers: []InternalTransfer{{AccountCode: "other", Amount: coin.NewAmountFromInt64(123)}},
+					Addresses: []AddressAndAmount{
+						{Address: "some-address", Amount: coin.NewAmountFromInt64(123)},
+					},
+				},
+				{
+					Type:              TxTypeReceive,
+					TxID:              "other-tx-id",
+					InternalID:        "other-tx-id",
+					Timestamp:         &timestamp,
+					Amount:            coin.NewAmountFromInt64(123),
+					InternalTransfers: []InternalTransfer{{AccountCode: "other", Amount: coin.NewAmountFromInt64(123)}},
+					Addresses: []AddressAndAmount{
+						{Address: "our-address", Amount: coin.NewAmountFromInt64(123), Ours: true},
+					},
+				},
+			}))
+	})
+}
+
+func TestAnnotate(t *testing.T) {
+	cfg := &AccountConfig{
+		Config: &config.Account{Code: "test", Name: "Test"},
+	}
+	mockCoin := &mocks.CoinMock{
+		CodeFunc: func() coin.Code {
+			return coin.CodeTBTC
+		},
+	}
+	account := NewBaseAccount(cfg, mockCoin, logging.Get().WithGroup("baseaccount_test"))
+	transactions := OrderedTransactions{
+		{
+			TxID:       "txid",
+			InternalID: "txid",
+			Addresses: []AddressAndAmount{
+				{Address: "address-alice"},
+				{Address: "address-unknown"},
+			},
+		},
+	}
+
+	// No resolvers configured.
+	require.Equal(t, transactions, account.Annotate(transactions))
+
+	cfg.ContactName = func(address string) string {
+		if address == "address-alice" {
+			return "Alice"
+		}
+		return ""
+	}
+	resolved := account.Annotate(transactions)
+	require.Len(t, resolved, 1)
+	require.Equal(t, "txid", resolved[0].TxID)
+	require.Equal(t, "Alice", resolved[0].Addresses[0].ContactName)
+	require.Equal(t, "", resolved[0].Addresses[1].ContactName)
+	require.False(t, resolved[0].IsInternalTransfer())
+	// The input is not modified.
+	require.Equal(t, "", transactions[0].Addresses[0].ContactName)
+
+	cfg.InternalTransfers = func(internalID string) []InternalTransfer {
+		if internalID == "txid" {
+			return []InternalTransfer{{AccountCode: "other", InternalID: "txid"}}
+		}
+		return nil
+	}
+	resolved = account.Annotate(transactions)
+	require.True(t, resolved[0].IsInternalTransfer())
+	require.Equal(t, "other", string(resolved[0].InternalTransfers[0].AccountCode))
+	require.Equal(t, "Alice", resolved[0].Addresses[0].ContactName)
+	require.False(t, transactions[0].IsInternalTransfer())
+}
+
+func TestFiatValues(t *testing.T) {
+	now := time.Now()
+	cfg := &AccountConfig{
+		Config:      &config.Account{Code: "test", Name: "Test"},
+		NotesFolder: test.TstTempDir("baseaccount_test_fiatvalues"),
+		RateUpdater: rates.MockRateUpdater(now.Add(-time.Hour), now),
+		FiatList:    func() []string { return []string{"USD", "CHF"} },
+	}
+	mockCoin := &mocks.CoinMock{
+		CodeFunc:     func() coin.Code { return coin.CodeBTC },
+		UnitFunc:     func(bool) string { return "BTC" },
+		DecimalsFunc: func(bool) uint { return 8 },
+	}
+	account := NewBaseAccount(cfg, mockCoin, logging.Get().WithGroup("baseaccount_test"))
+	require.NoError(t, account.Initialize("test-account-identifier"))
+
+	// Proposed and sent. CHF is enabled, but has no rate.
+	account.ProposeTxFiatValues(coin.NewAmountFromInt64(12345678))
+	values := account.GetAndClearProposedTxFiatValues()
+	require.Equal(t, map[string]string{"USD": "2469.14"}, values)
+	require.Nil(t, account.GetAndClearProposedTxFiatValues())
+	require.NoError(t, account.SetTxFiatValues("sent", values))
+	require.Equal(t, values, account.TxFiatValues("sent"))
+
+	// Received.
+	old := now.Add(-3 * time.Hour)
+	recent := now.Add(-10 * time.Minute)
+	amount := coin.NewAmountFromInt64(1e8)
+	account.RecordIncomingFiatValues(OrderedTransactions{
+		{InternalID: "pending", Type: TxTypeReceive, Status: TxStatusPending, Amount: amount},
+		{InternalID: "recent", Type: TxTypeReceive, Status: TxStatusComplete, Timestamp: &recent, Amount: amount},
+		{InternalID: "old", Type: TxTypeReceive, Status: TxStatusComplete, Timestamp: &old, Amount: amount},
+		{InternalID: "outgoing", Type: TxTypeSend, Status: TxStatusPending, Amount: amount},
 	})
+	require.Equal(t, map[string]string{"USD": "20000.00"}, account.TxFiatValues("pending"))
+	require.Equal(t, map[string]string{"USD": "20000.00"}, account.TxFiatValues("recent"))
+	require.Nil(t, account.TxFiatValues("old"))
+	require.Nil(t, account.TxFiatValues("outgoing"))
+
+	// Recorded values are not replaced when the transaction is seen again.
+	require.NoError(t, account.SetTxFiatValues("pending", map[string]string{"USD": "1.00"}))
+	account.RecordIncomingFiatValues(OrderedTransactions{
+		{InternalID: "pending", Type: TxTypeReceive, Status: TxStatusComplete, Timestamp: &recent, Amount: amount},
+	})
+	require.Equal(t, map[string]string{"USD": "1.00"}, account.TxFiatValues("pending"))
+}
+
+func TestOnSyncFinished(t *testing.T) {
+	events := []types.Event{}
+	cfg := &AccountConfig{
+		Config:  &config.Account{Code: "test", Name: "Test"},
+		OnEvent: func(event types.Event) { events = append(events, event) },
+	}
+	mockCoin := &mocks.CoinMock{CodeFunc: func() coin.Code { return coin.CodeBTC }}
+	account := NewBaseAccount(cfg, mockCoin, logging.Get().WithGroup("baseaccount_test"))
+	syncs := 0
+	account.OnSyncFinished(func() {
+		require.Equal(t, types.EventSyncDone, events[len(events)-1])
+		syncs++
+	})
+
+	done := account.Synchronizer.IncRequestsCounter()
+	done2 := account.Synchronizer.IncRequestsCounter()
+	done()
+	require.Equal(t, 0, syncs)
+	done2()
+	require.Equal(t, 1, syncs)
+	account.Synchronizer.IncRequestsCounter()()
+	require.Equal(t, 2, syncs)
 }
@@ -41,6 +41,30 @@ var (
 	ErrFeeTooLow = TxValidationError("feeTooLow")
 	// ErrAccountNotsynced is used when the account sync has not successfully finished.
 	ErrAccountNotsynced = TxValidationError("accountNotSynced")
+	// ErrSilentPaymentsNotSupported is returned when the recipient is a silent payment address, but
+	// the keystore cannot compute the shared secret needed to derive the output.
+	ErrSilentPaymentsNotSupported = TxValidationError("silentPaymentsNotSupported")
+	// ErrTxNotReplaceable is returned when the transaction to speed up or cancel is not pending
+	// anymore or was already replaced.
+	ErrTxNotReplaceable = TxValidationError("txNotReplaceable")
+	// ErrENSNameNotFound is returned when the recipient is an ENS name that does not resolve to an
+	// address.
+	ErrENSNameNotFound = TxValidationError("ensNameNotFound")
+	// ErrInvalidNonce is returned when the custom nonce is malformatted.
+	ErrInvalidNonce = TxValidationError("invalidNonce")
+	// ErrNonceGap is returned when the custom nonce is higher than the next nonce of the account. The
+	// transaction would not be mined until the nonces in between are used.
+	ErrNonceGap = TxValidationError("nonceGap")
+	// ErrNonceTooLow is returned when the custom nonce was already used by a confirmed transaction.
+	ErrNonceTooLow = TxValidationError("nonceTooLow")
+	// ErrInvalidGasLimit is returned when the custom gas limit is malformatted.
+	ErrInvalidGasLimit = TxValidationError("invalidGasLimit")
+	// ErrGasLimitTooLow is returned when the custom gas limit is below the intrinsic gas of the
+	// transaction, i.e. the gas it costs before any contract code is executed.
+	ErrGasLimitTooLow = TxValidationError("gasLimitTooLow")
+	// ErrInvalidData is returned when the custom data payload is not hex encoded or not allowed,
+	// e.g. for token transfers.
+	ErrInvalidData = TxValidationError("invalidData")
 
 	// ErrNotAvailable is returned if data required is not available yet. Example: the headers are
 	// not synced yet, which is a prerequisite to making a timeseries of the portfolio.
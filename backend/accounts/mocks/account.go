@@ -81,6 +81,9 @@ var _ accounts.Interface = &InterfaceMock{}
 //			TransactionsFunc: func() (accounts.OrderedTransactions, error) {
 //				panic("mock out the Transactions method")
 //			},
+//			TxFiatValuesFunc: func(txID string) map[string]string {
+//				panic("mock out the TxFiatValues method")
+//			},
 //			TxNoteFunc: func(txID string) string {
 //				panic("mock out the TxNote method")
 //			},
@@ -157,6 +160,9 @@ type InterfaceMock struct {
 	// TransactionsFunc mocks the Transactions method.
 	TransactionsFunc func() (accounts.OrderedTransactions, error)
 
+	// TxFiatValuesFunc mocks the TxFiatValues method.
+	TxFiatValuesFunc func(txID string) map[string]string
+
 	// TxNoteFunc mocks the TxNote method.
 	TxNoteFunc func(txID string) string
 
@@ -240,6 +246,11 @@ type InterfaceMock struct {
 		// Transactions holds details about calls to the Transactions method.
 		Transactions []struct {
 		}
+		// TxFiatValues holds details about calls to the TxFiatValues method.
+		TxFiatValues []struct {
+			// TxID is the txID argument value.
+			TxID string
+		}
 		// TxNote holds details about calls to the TxNote method.
 		TxNote []struct {
 			// TxID is the txID argument value.
@@ -276,6 +287,7 @@ type InterfaceMock struct {
 	lockSetTxNote                 sync.RWMutex
 	lockSynced                    sync.RWMutex
 	lockTransactions              sync.RWMutex
+	lockTxFiatValues              sync.RWMutex
 	lockTxNote                    sync.RWMutex
 	lockTxProposal                sync.RWMutex
 	lockVerifyAddress             sync.RWMutex
@@ -849,6 +861,38 @@ func (mock *InterfaceMock) TransactionsCalls() []struct {
 	return calls
 }
 
+// TxFiatValues calls TxFiatValuesFunc.
+func (mock *InterfaceMock) TxFiatValues(txID string) map[string]string {
+	if mock.TxFiatValuesFunc == nil {
+		panic("InterfaceMock.TxFiatValuesFunc: method is nil but Interface.TxFiatValues was just called")
+	}
+	callInfo := struct {
+		TxID string
+	}{
+		TxID: txID,
+	}
+	mock.lockTxFiatValues.Lock()
+	mock.calls.TxFiatValues = append(mock.calls.TxFiatValues, callInfo)
+	mock.lockTxFiatValues.Unlock()
+	return mock.TxFiatValuesFunc(txID)
+}
+
+// TxFiatValuesCalls gets all the calls that were made to TxFiatValues.
+// Check the length with:
+//
+//	len(mockedInterface.TxFiatValuesCalls())
+func (mock *InterfaceMock) TxFiatValuesCalls() []struct {
+	TxID string
+} {
+	var calls []struct {
+		TxID string
+	}
+	mock.lockTxFiatValues.RLock()
+	calls = mock.calls.TxFiatValues
+	mock.lockTxFiatValues.RUnlock()
+	return calls
+}
+
 // TxNote calls TxNoteFunc.
 func (mock *InterfaceMock) TxNote(txID string) string {
 	if mock.TxNoteFunc == nil {
@@ -32,6 +32,9 @@ type notesData struct {
 
 	// a map of transaction ID to transaction note.
 	TransactionNotes map[string]string `json:"transactions"`
+	// a map of transaction ID to the fiat values of the transaction amount at the time it was sent
+	// or first seen, keyed by fiat currency.
+	TransactionFiatValues map[string]map[string]string `json:"transactionFiatValues,omitempty"`
 }
 
 // read deserializes the json files into notes. If the file does not exist yet, no error is
@@ -116,3 +119,33 @@ func (notes *Notes) TxNote(txID string) string {
 
 	return notes.data.TransactionNotes[txID]
 }
+
+// SetTxFiatValues stores the fiat values of a transaction amount, keyed by fiat currency, e.g.
+// `{"USD": "1234.56"}`. Existing values of the transaction are replaced. Empty values result in the
+// entry being deleted.
+func (notes *Notes) SetTxFiatValues(txID string, values map[string]string) error {
+	notes.dataMu.Lock()
+	defer notes.dataMu.Unlock()
+
+	if notes.data.TransactionFiatValues == nil {
+		notes.data.TransactionFiatValues = map[string]map[string]string{}
+	}
+	if len(values) == 0 {
+		if _, ok := notes.data.TransactionFiatValues[txID]; !ok {
+			return nil
+		}
+		delete(notes.data.TransactionFiatValues, txID)
+	} else {
+		notes.data.TransactionFiatValues[txID] = values
+	}
+	return write(notes.data, notes.filename)
+}
+
+// TxFiatValues fetches the fiat values stored for a transaction, keyed by fiat currency. Returns
+// nil if none were stored. The returned map must not be modified.
+func (notes *Notes) TxFiatValues(txID string) map[string]string {
+	notes.dataMu.RLock()
+	defer notes.dataMu.RUnlock()
+
+	return notes.data.TransactionFiatValues[txID]
+}
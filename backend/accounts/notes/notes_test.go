@@ -68,3 +68,25 @@ func TestMaxLen(t *testing.T) {
 	require.NoError(t, notes.SetTxNote("tx-id", strings.Repeat("x", 1024)))
 	require.Error(t, notes.SetTxNote("tx-id", strings.Repeat("x", 1025)))
 }
+
+func TestTxFiatValues(t *testing.T) {
+	filename := test.TstTempFile("account-notes")
+	notes, err := LoadNotes(filename)
+	require.NoError(t, err)
+	require.Nil(t, notes.TxFiatValues("tx-id"))
+
+	values := map[string]string{"USD": "1234.56", "BTC": "0.01000000"}
+	require.NoError(t, notes.SetTxFiatValues("tx-id", values))
+	require.NoError(t, notes.SetTxNote("tx-id", "note"))
+	require.Equal(t, values, notes.TxFiatValues("tx-id"))
+
+	// Reload notes.
+	notes, err = LoadNotes(filename)
+	require.NoError(t, err)
+	require.Equal(t, values, notes.TxFiatValues("tx-id"))
+	require.Equal(t, "note", notes.TxNote("tx-id"))
+
+	require.NoError(t, notes.SetTxFiatValues("tx-id", nil))
+	require.Nil(t, notes.TxFiatValues("tx-id"))
+	require.Equal(t, "note", notes.TxNote("tx-id"))
+}
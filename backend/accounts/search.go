@@ -0,0 +1,219 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// TransactionsFilter selects transactions. Fields with zero values do not filter.
+type TransactionsFilter struct {
+	// Start and End restrict the transaction time (see `TransactionData.Time()`) to the interval
+	// [Start, End). If either is set, transactions without a time are excluded.
+	Start *time.Time
+	End   *time.Time
+	// Types are the accepted transaction types.
+	Types []TxType
+	// Statuses are the accepted transaction statuses.
+	Statuses []TxStatus
+	// MinAmount and MaxAmount restrict the transaction amount (inclusive), in the smallest unit of
+	// the coin.
+	MinAmount *coin.Amount
+	MaxAmount *coin.Amount
+	// Note matches transactions whose note contains the text, ignoring case.
+	Note string
+	// Address matches transactions with an address or a contact name containing the text, ignoring
+	// case.
+	Address string
+}
+
+// HasAmountRange returns true if the filter restricts the transaction amount.
+func (filter *TransactionsFilter) HasAmountRange() bool {
+	return filter.MinAmount != nil || filter.MaxAmount != nil
+}
+
+// Match returns true if the transaction, which has the given note, passes the filter.
+func (filter *TransactionsFilter) Match(tx *TransactionData, note string) bool {
+	if filter.Start != nil || filter.End != nil {
+		txTime := tx.Time()
+		if txTime == nil ||
+			(filter.Start != nil && txTime.Before(*filter.Start)) ||
+			(filter.End != nil && !txTime.Before(*filter.End)) {
+			return false
+		}
+	}
+	if len(filter.Types) > 0 && !containsTxType(filter.Types, tx.Type) {
+		return false
+	}
+	if len(filter.Statuses) > 0 && !containsTxStatus(filter.Statuses, tx.Status) {
+		return false
+	}
+	if filter.MinAmount != nil && tx.Amount.BigInt().Cmp(filter.MinAmount.BigInt()) < 0 {
+		return false
+	}
+	if filter.MaxAmount != nil && tx.Amount.BigInt().Cmp(filter.MaxAmount.BigInt()) > 0 {
+		return false
+	}
+	if filter.Note != "" && !containsFold(note, filter.Note) {
+		return false
+	}
+	if filter.Address != "" {
+		found := false
+		for _, addressAndAmount := range tx.Addresses {
+			if containsFold(addressAndAmount.Address, filter.Address) ||
+				containsFold(addressAndAmount.ContactName, filter.Address) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTxType(types []TxType, txType TxType) bool {
+	for _, t := range types {
+		if t == txType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTxStatus(statuses []TxStatus, status TxStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// Time returns the confirmation time of the transaction, or the time it was first seen in the app
+// if it is not confirmed yet. Returns nil if neither is known.
+func (tx *TransactionData) Time() *time.Time {
+	if tx.Timestamp != nil {
+		return tx.Timestamp
+	}
+	return tx.CreatedTimestamp
+}
+
+// TransactionsSort is the order of transaction search results.
+type TransactionsSort string
+
+const (
+	// TransactionsSortNewest sorts from newest to oldest, the order of `OrderedTransactions`. This
+	// is the default.
+	TransactionsSortNewest TransactionsSort = "newest"
+	// TransactionsSortOldest sorts from oldest to newest.
+	TransactionsSortOldest TransactionsSort = "oldest"
+	// TransactionsSortAmountDesc sorts from the largest to the smallest amount.
+	TransactionsSortAmountDesc TransactionsSort = "amountDesc"
+	// TransactionsSortAmountAsc sorts from the smallest to the largest amount.
+	TransactionsSortAmountAsc TransactionsSort = "amountAsc"
+)
+
+// TransactionsPage is a page of transaction search results.
+type TransactionsPage struct {
+	Transactions []*TransactionData
+	// NextCursor is passed to the next search to get the next page. It is empty if this is the
+	// last page.
+	NextCursor string
+}
+
+// Search returns the transactions passing the filter, sorted as requested. `note` returns the note
+// of a transaction by its internal ID. ERC20 transactions with a zero amount are always skipped to
+// mitigate address poisoning attacks. They are skipped before pagination, so pages are not short.
+//
+// Results are paginated if limit is positive. The cursor is empty to get the first page, or the
+// `NextCursor` of the previous page, which is only valid for the same filter and sort order.
+func (txs OrderedTransactions) Search(
+	filter *TransactionsFilter,
+	sortBy TransactionsSort,
+	cursor string,
+	limit int,
+	note func(internalID string) string,
+) (*TransactionsPage, error) {
+	result := []*TransactionData{}
+	for _, tx := range txs {
+		if tx.IsErc20 && tx.Amount.BigInt().Sign() == 0 {
+			continue
+		}
+		if filter.Match(tx, note(tx.InternalID)) {
+			result = append(result, tx)
+		}
+	}
+	switch sortBy {
+	case "", TransactionsSortNewest:
+	case TransactionsSortOldest:
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	case TransactionsSortAmountDesc, TransactionsSortAmountAsc:
+		// Stable, so that transactions with the same amount stay sorted from newest to oldest.
+		sort.SliceStable(result, func(i, j int) bool {
+			cmp := result[i].Amount.BigInt().Cmp(result[j].Amount.BigInt())
+			if sortBy == TransactionsSortAmountAsc {
+				return cmp < 0
+			}
+			return cmp > 0
+		})
+	default:
+		return nil, errp.Newf("Unknown sort order: %s", sortBy)
+	}
+	page, nextCursor, err := Paginate(result, func(tx *TransactionData) string {
+		return tx.InternalID
+	}, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &TransactionsPage{Transactions: page, NextCursor: nextCursor}, nil
+}
+
+// Paginate returns the page of at most limit items following the item identified by cursor, or the
+// first page if cursor is empty. All items are returned if limit is not positive. `id` must
+// uniquely identify an item. The returned cursor identifies the last item of the page, and is
+// empty if there are no more items.
+func Paginate[T any](items []T, id func(T) string, cursor string, limit int) ([]T, string, error) {
+	start := 0
+	if cursor != "" {
+		start = -1
+		for i, item := range items {
+			if id(item) == cursor {
+				start = i + 1
+				break
+			}
+		}
+		if start == -1 {
+			return nil, "", errp.New("Invalid cursor")
+		}
+	}
+	items = items[start:]
+	if limit <= 0 || len(items) <= limit {
+		return items, "", nil
+	}
+	return items[:limit], id(items[limit-1]), nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accounts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+func searchTestTransactions() OrderedTransactions {
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC)
+		return &t
+	}
+	return OrderedTransactions{
+		{
+			InternalID: "pending", Type: TxTypeSend, Status: TxStatusPending,
+			Amount: coin.NewAmountFromInt64(300), CreatedTimestamp: day(5),
+			Addresses: []AddressAndAmount{{Address: "bc1qbob", ContactName: "Bob"}},
+		},
+		{
+			InternalID: "self", Type: TxTypeSendSelf, Status: TxStatusComplete,
+			Amount: coin.NewAmountFromInt64(100), Timestamp: day(4),
+			Addresses: []AddressAndAmount{{Address: "bc1qself"}},
+		},
+		{
+			InternalID: "receive-2", Type: TxTypeReceive, Status: TxStatusComplete,
+			Amount: coin.NewAmountFromInt64(500), Timestamp: day(3),
+			Addresses: []AddressAndAmount{{Address: "bc1qalice", ContactName: "Alice"}},
+		},
+		{
+			InternalID: "receive-1", Type: TxTypeReceive, Status: TxStatusComplete,
+			Amount: coin.NewAmountFromInt64(300), Timestamp: day(1),
+			Addresses: []AddressAndAmount{{Address: "bc1qmine"}},
+		},
+		{
+			InternalID: "no-time", Type: TxTypeReceive, Status: TxStatusComplete,
+			Amount: coin.NewAmountFromInt64(1),
+		},
+	}
+}
+
+func internalIDs(txs []*TransactionData) []string {
+	result := []string{}
+	for _, tx := range txs {
+		result = append(result, tx.InternalID)
+	}
+	return result
+}
+
+func TestSearch(t *testing.T) {
+	txs := searchTestTransactions()
+	notes := map[string]string{"receive-1": "Salary January"}
+	note := func(internalID string) string { return notes[internalID] }
+	amount := func(amount int64) *coin.Amount {
+		a := coin.NewAmountFromInt64(amount)
+		return &a
+	}
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		name     string
+		filter   TransactionsFilter
+		sortBy   TransactionsSort
+		expected []string
+	}{
+		{"all", TransactionsFilter{}, "",
+			[]string{"pending", "self", "receive-2", "receive-1", "no-time"}},
+		{"oldest", TransactionsFilter{}, TransactionsSortOldest,
+			[]string{"no-time", "receive-1", "receive-2", "self", "pending"}},
+		{"amount desc", TransactionsFilter{}, TransactionsSortAmountDesc,
+			[]string{"receive-2", "pending", "receive-1", "self", "no-time"}},
+		{"amount asc", TransactionsFilter{}, TransactionsSortAmountAsc,
+			[]string{"no-time", "self", "pending", "receive-1", "receive-2"}},
+		{"date range", TransactionsFilter{Start: &start, End: &end}, "",
+			[]string{"self", "receive-2"}},
+		{"start only", TransactionsFilter{Start: &start}, "",
+			[]string{"pending", "self", "receive-2"}},
+		{"types", TransactionsFilter{Types: []TxType{TxTypeSend, TxTypeSendSelf}}, "",
+			[]string{"pending", "self"}},
+		{"status", TransactionsFilter{Statuses: []TxStatus{TxStatusPending}}, "",
+			[]string{"pending"}},
+		{"amount range", TransactionsFilter{MinAmount: amount(100), MaxAmount: amount(300)}, "",
+			[]string{"pending", "self", "receive-1"}},
+		{"note", TransactionsFilter{Note: "salary"}, "",
+			[]string{"receive-1"}},
+		{"address", TransactionsFilter{Address: "BC1QSELF"}, "",
+			[]string{"self"}},
+		{"contact", TransactionsFilter{Address: "ali"}, "",
+			[]string{"receive-2"}},
+		{"combined", TransactionsFilter{Types: []TxType{TxTypeReceive}, MinAmount: amount(300)}, TransactionsSortOldest,
+			[]string{"receive-1", "receive-2"}},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			page, err := txs.Search(&test.filter, test.sortBy, "", 0, note)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, internalIDs(page.Transactions))
+			require.Equal(t, "", page.NextCursor)
+		})
+	}
+
+	_, err := txs.Search(&TransactionsFilter{}, "unknown", "", 0, note)
+	require.Error(t, err)
+}
+
+func TestSearchPagination(t *testing.T) {
+	txs := searchTestTransactions()
+	note := func(string) string { return "" }
+	filter := &TransactionsFilter{}
+
+	var pages [][]string
+	cursor := ""
+	for {
+		page, err := txs.Search(filter, TransactionsSortOldest, cursor, 2, note)
+		require.NoError(t, err)
+		pages = append(pages, internalIDs(page.Transactions))
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	require.Equal(t, [][]string{
+		{"no-time", "receive-1"},
+		{"receive-2", "self"},
+		{"pending"},
+	}, pages)
+
+	_, err := txs.Search(filter, TransactionsSortOldest, "unknown", 2, note)
+	require.Error(t, err)
+}
+
+func TestSearchSkipsZeroAmountERC20(t *testing.T) {
+	txs := OrderedTransactions{
+		{InternalID: "poison-2", IsErc20: true, Amount: coin.NewAmountFromInt64(0)},
+		{InternalID: "token", IsErc20: true, Amount: coin.NewAmountFromInt64(10)},
+		{InternalID: "poison-1", IsErc20: true, Amount: coin.NewAmountFromInt64(0)},
+		{InternalID: "eth", Amount: coin.NewAmountFromInt64(0)},
+	}
+	note := func(string) string { return "" }
+
+	// The first page is full even though zero amount ERC20 transactions come first.
+	page, err := txs.Search(&TransactionsFilter{}, TransactionsSortNewest, "", 2, note)
+	require.NoError(t, err)
+	require.Equal(t, []string{"token", "eth"}, internalIDs(page.Transactions))
+	require.Equal(t, "", page.NextCursor)
+}
+
+func TestPaginate(t *testing.T) {
+	items := []string{"a", "b", "c", "d"}
+	id := func(s string) string { return s }
+
+	page, cursor, err := Paginate(items, id, "", 0)
+	require.NoError(t, err)
+	require.Equal(t, items, page)
+	require.Equal(t, "", cursor)
+
+	page, cursor, err = Paginate(items, id, "", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, page)
+	require.Equal(t, "b", cursor)
+
+	page, cursor, err = Paginate(items, id, "b", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "d"}, page)
+	require.Equal(t, "", cursor)
+
+	page, cursor, err = Paginate(items, id, "d", 2)
+	require.NoError(t, err)
+	require.Empty(t, page)
+	require.Equal(t, "", cursor)
+
+	_, _, err = Paginate(items, id, "e", 2)
+	require.Error(t, err)
+}
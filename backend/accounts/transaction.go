@@ -22,6 +22,7 @@ import (
 
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 )
@@ -50,6 +51,9 @@ const (
 	// TxStatusFailed means the tx is confirmed but considered failed, e.g. a ETH transaction with a
 	// too low gas limit.
 	TxStatusFailed TxStatus = "failed"
+	// TxStatusDropped means the tx was never confirmed and never will be, e.g. an Ethereum tx that
+	// was replaced by another tx with the same nonce.
+	TxStatusDropped TxStatus = "dropped"
 )
 
 // AddressAndAmount holds an address and the corresponding amount.
@@ -59,6 +63,23 @@ type AddressAndAmount struct {
 	Amount coin.Amount
 	// Ours is true if the address is one of our receive addresses.
 	Ours bool
+	// ContactName is the name of the address book contact with this address, or empty if there is
+	// none.
+	ContactName string
+	// ENSName is the verified primary ENS name of the address, or empty if there is none or it was
+	// not looked up yet.
+	ENSName string
+}
+
+// InternalTransfer links a transaction to the transaction of another of our accounts on the other
+// side of an internal transfer, i.e. coins moved between our own accounts.
+type InternalTransfer struct {
+	// AccountCode is the account on the other side of the transfer.
+	AccountCode types.Code
+	// InternalID is the internal ID of the transaction in the other account.
+	InternalID string
+	// Amount is the amount transferred.
+	Amount coin.Amount
 }
 
 // TransactionData holds transaction data to be shown to the user. It is as coin-agnostic as
@@ -104,6 +125,12 @@ type TransactionData struct {
 	// Addresses money was sent to / received on.
 	Addresses []AddressAndAmount
 
+	// InternalTransfers links a send to the receiving transactions of our other accounts, or a
+	// receive to the sending transaction of another of our accounts. Empty if the transaction is not
+	// an internal transfer. A send can be partially internal: only the sum of the transferred
+	// amounts went to our other accounts.
+	InternalTransfers []InternalTransfer
+
 	// --- Fields only used by BTC follow:
 
 	// FeeRatePerKb is the fee rate of the tx (fee / tx size).
@@ -124,6 +151,11 @@ type TransactionData struct {
 	IsErc20 bool
 }
 
+// IsInternalTransfer returns true if the transaction moves coins between our own accounts.
+func (tx *TransactionData) IsInternalTransfer() bool {
+	return len(tx.InternalTransfers) > 0
+}
+
 // isConfirmed returns true if the transaction has at least one confirmation.
 func (tx *TransactionData) isConfirmed() bool {
 	return tx.Height > 0
@@ -161,6 +193,11 @@ func NewOrderedTransactions(txs []*TransactionData) OrderedTransactions {
 	balance := big.NewInt(0)
 	for i := len(txs) - 1; i >= 0; i-- {
 		tx := txs[i]
+		if tx.Status == TxStatusDropped {
+			// Dropped transactions never affected the balance.
+			tx.Balance = coin.NewAmount(balance)
+			continue
+		}
 		switch tx.Type {
 		case TxTypeReceive:
 			balance.Add(balance, tx.Amount.BigInt())
@@ -260,3 +297,60 @@ func (txs OrderedTransactions) Timeseries(
 	}
 	return result, nil
 }
+
+// TimeseriesSince continues a timeseries after its last entry `last` until `end` in steps of
+// `interval`. Unlike `Timeseries()`, only the transactions confirmed after the last entry are
+// visited, and the balances continue from the balance of the last entry.
+func (txs OrderedTransactions) TimeseriesSince(
+	last TimeseriesEntry, end time.Time, interval time.Duration) ([]TimeseriesEntry, error) {
+	// Confirmed transactions after the last entry, newest first.
+	newTxs := []*TransactionData{}
+	for _, tx := range txs {
+		if !tx.isConfirmed() {
+			continue
+		}
+		if tx.Timestamp == nil {
+			return nil, errp.WithStack(errors.ErrNotAvailable)
+		}
+		if !tx.Timestamp.After(last.Time) {
+			break
+		}
+		newTxs = append(newTxs, tx)
+	}
+
+	result := []TimeseriesEntry{}
+	value := last.Value
+	next := len(newTxs) - 1
+	for currentTime := last.Time.Add(interval); !currentTime.After(end); currentTime = currentTime.Add(interval) {
+		for next >= 0 && !newTxs[next].Timestamp.After(currentTime) {
+			value = newTxs[next].Balance
+			next--
+		}
+		result = append(result, TimeseriesEntry{
+			Time:  currentTime,
+			Value: value,
+		})
+	}
+	return result, nil
+}
+
+// TransactionsState identifies the transactions up to a point in time, see `StateAt()`.
+type TransactionsState struct {
+	// Count is the number of transactions up to and including the latest one.
+	Count int
+	// LatestID is the internal ID of the latest confirmed transaction, or empty if there is none.
+	LatestID string
+}
+
+// StateAt returns the state of the transactions confirmed until `t`. If it is the same for two
+// lists of transactions, their timeseries are the same until `t`. Only the transactions after `t`
+// are visited.
+func (txs OrderedTransactions) StateAt(t time.Time) TransactionsState {
+	for i, tx := range txs {
+		if !tx.isConfirmed() || tx.Timestamp == nil || tx.Timestamp.After(t) {
+			continue
+		}
+		return TransactionsState{Count: len(txs) - i, LatestID: tx.InternalID}
+	}
+	return TransactionsState{}
+}
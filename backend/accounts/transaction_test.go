@@ -148,4 +148,15 @@ func TestOrderedTransactions(t *testing.T) {
 			Value: coin.NewAmountFromInt64(589),
 		},
 	}, timeseries)
+
+	// Continuing a timeseries yields the same entries.
+	continued, err := ordered.TimeseriesSince(
+		timeseries[5], time.Date(2020, 9, 21, 13, 0, 0, 0, time.UTC), 24*time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, timeseries[6:], continued)
+
+	require.Equal(t,
+		TransactionsState{Count: 2, LatestID: ordered[6].InternalID},
+		ordered.StateAt(time.Date(2020, 9, 14, 13, 0, 0, 0, time.UTC)))
+	require.Equal(t, TransactionsState{}, ordered.StateAt(time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)))
 }
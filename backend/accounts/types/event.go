@@ -32,6 +32,10 @@ const (
 	// EventHeadersSynced is fired when the headers finished syncing.
 	EventHeadersSynced Event = "headersSynced"
 
+	// EventTransactionsChanged is fired when the transactions change outside of a sync, e.g. when
+	// the names of their addresses become known.
+	EventTransactionsChanged Event = "transactionsChanged"
+
 	// EventFeeTargetsChanged is fired when the fee targets change.
 	EventFeeTargetsChanged Event = "feeTargetsChanged"
 )
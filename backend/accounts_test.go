@@ -172,6 +172,8 @@ func TestSortAccounts(t *testing.T) {
 		{Code: "acct-btc-2", CoinCode: coinpkg.CodeBTC, SigningConfigurations: btcConfig("m/84'/0'/1'")},
 		{Code: "acct-goeth", CoinCode: coinpkg.CodeGOETH},
 		{Code: "acct-sepeth", CoinCode: coinpkg.CodeSEPETH},
+		{Code: "acct-pol", CoinCode: coinpkg.CodePOL},
+		{Code: "acct-arbeth", CoinCode: coinpkg.CodeARBETH},
 		{Code: "acct-ltc", CoinCode: coinpkg.CodeLTC},
 		{Code: "acct-tltc", CoinCode: coinpkg.CodeTLTC},
 		{Code: "acct-tbtc", CoinCode: coinpkg.CodeTBTC},
@@ -196,6 +198,8 @@ func TestSortAccounts(t *testing.T) {
 		"acct-eth-2-eth-erc20-usdt",
 		"acct-goeth",
 		"acct-sepeth",
+		"acct-arbeth",
+		"acct-pol",
 	}
 
 	for i, acct := range backend.Accounts() {
@@ -349,7 +353,7 @@ func (e environment) Auth() {}
 
 func (e environment) OnAuthSettingChanged(bool) {}
 
-func newBackend(t *testing.T, testing, regtest bool) *Backend {
+func newBackend(t testing.TB, testing, regtest bool) *Backend {
 	t.Helper()
 	b, err := NewBackend(
 		arguments.NewArguments(
@@ -460,6 +464,10 @@ func newBackend(t *testing.T, testing, regtest bool) *Backend {
 		coinpkg.CodeETH,
 		coinpkg.CodeGOETH,
 		coinpkg.CodeSEPETH,
+		coinpkg.CodeARBETH,
+		coinpkg.CodeOPETH,
+		coinpkg.CodeBASEETH,
+		coinpkg.CodePOL,
 	} {
 		c, err := b.Coin(code)
 		require.NoError(t, err)
@@ -488,7 +496,10 @@ func TestSupportedCoins(t *testing.T) {
 		b := newBackend(t, testnetDisabled, regtestDisabled)
 		defer b.Close()
 		require.Equal(t,
-			[]coinpkg.Code{coinpkg.CodeBTC, coinpkg.CodeLTC, coinpkg.CodeETH},
+			[]coinpkg.Code{
+				coinpkg.CodeBTC, coinpkg.CodeLTC, coinpkg.CodeETH,
+				coinpkg.CodeARBETH, coinpkg.CodeOPETH, coinpkg.CodeBASEETH, coinpkg.CodePOL,
+			},
 			b.SupportedCoins(&keystoremock.KeystoreMock{
 				SupportsCoinFunc: func(coin coinpkg.Coin) bool {
 					return true
@@ -501,7 +512,7 @@ func TestSupportedCoins(t *testing.T) {
 		b := newBackend(t, testnetEnabled, regtestDisabled)
 		defer b.Close()
 		require.Equal(t,
-			[]coinpkg.Code{coinpkg.CodeTBTC, coinpkg.CodeTLTC, coinpkg.CodeGOETH, coinpkg.CodeSEPETH},
+			[]coinpkg.Code{coinpkg.CodeTBTC, coinpkg.CodeTBTC4, coinpkg.CodeSBTC, coinpkg.CodeTLTC, coinpkg.CodeGOETH, coinpkg.CodeSEPETH},
 			b.SupportedCoins(&keystoremock.KeystoreMock{
 				SupportsCoinFunc: func(coin coinpkg.Coin) bool {
 					return true
@@ -656,6 +667,27 @@ func TestCreateAndPersistAccountConfig(t *testing.T) {
 			b.Config().AccountsConfig().Lookup("v0-55555555-eth-1"),
 		)
 
+		// Add an Arbitrum account, which has the same address as the first Ethereum account.
+		acctCode, err = b.CreateAndPersistAccountConfig(
+			coinpkg.CodeARBETH,
+			"arbitrum",
+			bitbox02LikeKeystore,
+		)
+		require.NoError(t, err)
+		require.Equal(t, "v0-55555555-arbeth-0", string(acctCode))
+		require.Equal(t,
+			&config.Account{
+				Watch:    nil,
+				CoinCode: "arbeth",
+				Name:     "arbitrum",
+				Code:     "v0-55555555-arbeth-0",
+				SigningConfigurations: signing.Configurations{
+					signing.NewEthereumConfiguration(fingerprint, mustKeypath("m/44'/60'/0'/0/0"), mustXKey("xpub6GP83vJASH1kS7dQPWXFjVHDfYajopbG8U3j8peBH67CRCnb8QmDxZJfWpbgCQNHAzCDJ4MyVYjoh7Yv9yo7PQuZ9YyktgrtD9vmeo67Y4E")),
+				},
+			},
+			b.Config().AccountsConfig().Lookup("v0-55555555-arbeth-0"),
+		)
+
 		// Add another Bitcoin account.
 		acctCode, err = b.CreateAndPersistAccountConfig(
 			coinpkg.CodeBTC,
@@ -23,6 +23,8 @@ import (
 
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
 	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
@@ -68,6 +70,7 @@ var aoppBTCScriptTypeMap = map[string]signing.ScriptType{
 	"p2pkh":  signing.ScriptTypeP2PKH,
 	"p2wpkh": signing.ScriptTypeP2WPKH,
 	"p2sh":   signing.ScriptTypeP2WPKHP2SH,
+	"p2tr":   signing.ScriptTypeP2TR,
 }
 
 type account struct {
@@ -172,7 +175,13 @@ func (backend *Backend) aoppKeystoreRegistered() {
 	if backend.aopp.State != aoppStateAwaitingKeystore {
 		return
 	}
-	if !backend.keystore.CanSignMessage(backend.aopp.coinCode) {
+	canSign := backend.keystore.CanSignMessage(backend.aopp.coinCode)
+	if backend.aopp.coinCode == coinpkg.CodeBTC &&
+		aoppBTCScriptTypeMap[backend.aopp.format] == signing.ScriptTypeP2TR {
+		// Taproot addresses are proven with BIP-322 signatures.
+		canSign = backend.keystore.CanSignMessageBIP322(backend.aopp.coinCode)
+	}
+	if !canSign {
 		backend.aoppSetError(errAOPPUnsupportedKeystore)
 		return
 	}
@@ -350,11 +359,29 @@ func (backend *Backend) aoppChooseAccount(code accountsTypes.Code) {
 	var signature []byte
 	switch account.Coin().Code() {
 	case coinpkg.CodeBTC:
-		sig, err := backend.keystore.SignBTCMessage(
-			[]byte(backend.aopp.Message),
-			addr.AbsoluteKeypath(),
-			account.Config().Config.SigningConfigurations[signingConfigIdx].ScriptType(),
-		)
+		btcCoin, ok := account.Coin().(*btc.Coin)
+		if !ok {
+			log.Error("unexpected BTC coin type")
+			backend.aoppSetError(errAOPPUnknown)
+			return
+		}
+		btcAddress, ok := addr.(*addresses.AccountAddress)
+		if !ok {
+			log.Error("unexpected BTC address type")
+			backend.aoppSetError(errAOPPUnknown)
+			return
+		}
+		// Legacy signatures are used where defined, as they are expected by AOPP. For taproot,
+		// BIP-322 simple signatures are used.
+		format, err := btc.MessageSignatureFormat(
+			"", account.Config().Config.SigningConfigurations[signingConfigIdx].ScriptType())
+		if err != nil {
+			log.WithError(err).Error("signing error")
+			backend.aoppSetError(errAOPPUnknown)
+			return
+		}
+		sig, err := btc.SignMessage(
+			backend.keystore, btcCoin, btcAddress, []byte(backend.aopp.Message), format)
 		if err != nil {
 			if firmware.IsErrorAbort(err) {
 				log.WithError(err).Error("user aborted msg signing")
@@ -15,6 +15,8 @@
 package backend
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,9 +24,12 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
 	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/bip322"
 	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	keystoremock "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/mocks"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
@@ -275,6 +280,39 @@ func TestAOPPSuccess(t *testing.T) {
 		require.Equal(t, aoppStateSuccess, b.AOPP().State)
 	})
 
+	// Taproot addresses are proven with a BIP-322 signature.
+	t.Run("p2tr-bip322", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Address   string `json:"address"`
+				Signature []byte `json:"signature"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			address, err := btcutil.DecodeAddress(body.Address, &chaincfg.MainNetParams)
+			require.NoError(t, err)
+			format, err := bip322.Verify(address, []byte(dummyMsg),
+				base64.StdEncoding.EncodeToString(body.Signature), &chaincfg.MainNetParams)
+			require.NoError(t, err)
+			require.Equal(t, bip322.FormatSimple, format)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		b := newBackend(t, testnetDisabled, regtestDisabled)
+		defer b.Close()
+		params := defaultParams()
+		params.Set("format", "p2tr")
+		params.Set("callback", server.URL)
+		b.HandleURI("aopp:?" + params.Encode())
+		b.AOPPApprove()
+		ks := makeKeystore(t, scriptTypeRef(signing.ScriptTypeP2TR), keystoreHelper.ExtendedPublicKey)
+		ks.CanSignMessageBIP322Func = keystoreHelper.CanSignMessageBIP322
+		ks.SignBTCMessageBIP322Func = keystoreHelper.SignBTCMessageBIP322
+		b.registerKeystore(ks)
+		require.Equal(t, aoppStateSuccess, b.AOPP().State)
+		require.Equal(t, "bc1p", b.AOPP().Address[:4])
+	})
+
 	// Keystore is already registered before the AOPP request.
 	t.Run("user-approve", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -373,6 +411,21 @@ func TestAOPPFailures(t *testing.T) {
 		require.Equal(t, aoppStateError, b.AOPP().State)
 		require.Equal(t, errAOPPUnsupportedKeystore, b.AOPP().ErrorCode)
 	})
+	t.Run("cant_sign_bip322", func(t *testing.T) {
+		b := newBackend(t, testnetDisabled, regtestDisabled)
+		defer b.Close()
+		params := defaultParams()
+		params.Set("format", "p2tr")
+		b.HandleURI("aopp:?" + params.Encode())
+		b.AOPPApprove()
+		ks2 := makeKeystore(t, scriptTypeRef(signing.ScriptTypeP2TR), keystoreHelper.ExtendedPublicKey)
+		ks2.CanSignMessageBIP322Func = func(coinpkg.Code) bool {
+			return false
+		}
+		b.registerKeystore(ks2)
+		require.Equal(t, aoppStateError, b.AOPP().State)
+		require.Equal(t, errAOPPUnsupportedKeystore, b.AOPP().ErrorCode)
+	})
 	t.Run("no_accounts", func(t *testing.T) {
 		b := newBackend(t, testnetDisabled, regtestDisabled)
 		defer b.Close()
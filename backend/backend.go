@@ -16,6 +16,7 @@
 package backend
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"math/big"
@@ -24,6 +25,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
@@ -33,12 +35,15 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/banners"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/electrum"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/testnet4"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/util"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/etherscan"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/jsonrpc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/ltc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/bitbox"
@@ -213,6 +218,20 @@ type Backend struct {
 	coins     map[coinpkg.Code]coinpkg.Coin
 	coinsLock locker.Locker
 
+	// internalTransfers holds the links of internal transfers between our accounts, by account code
+	// and internal transaction ID. See `setInternalTransfers()`.
+	internalTransfers     map[accountsTypes.Code]map[string][]accounts.InternalTransfer
+	internalTransfersLock locker.Locker
+
+	// chartTimelines caches the per-account timelines of the portfolio chart. See
+	// `chartTimeline()`.
+	chartTimelines     chartTimelines
+	chartTimelinesLock locker.Locker
+	// chartTimelinesUpdatePending is set while an update of the chart timelines is waiting to run.
+	// See `requestChartTimelinesUpdate()`.
+	chartTimelinesUpdatePending atomic.Bool
+	chartTimelinesUpdateLock    locker.Locker
+
 	log *logrus.Entry
 
 	socksProxy socksproxy.SocksProxy
@@ -247,6 +266,11 @@ func NewBackend(arguments *arguments.Arguments, environment Environment) (*Backe
 		coins:    map[coinpkg.Code]coinpkg.Coin{},
 		accounts: []accounts.Interface{},
 		aopp:     AOPP{State: aoppStateInactive},
+		chartTimelines: chartTimelines{
+			timelines:  map[accountsTypes.Code]*chartTimeline{},
+			generation: map[accountsTypes.Code]uint64{},
+			syncing:    map[accountsTypes.Code]bool{},
+		},
 
 		makeBtcAccount: func(config *accounts.AccountConfig, coin *btc.Coin, gapLimits *types.GapLimits, log *logrus.Entry) accounts.Interface {
 			return btc.NewAccount(config, coin, gapLimits, log)
@@ -278,7 +302,25 @@ func NewBackend(arguments *arguments.Arguments, environment Environment) (*Backe
 		log.Errorf("RateUpdater DB cache dir: %v", err)
 	}
 	backend.ratesUpdater = rates.NewRateUpdater(hclient, ratesCache)
+	var ratesProviders []rates.Provider
+	for _, providerConfig := range backend.config.AppConfig().Backend.RatesProviders {
+		provider, err := rates.NewProvider(hclient, providerConfig)
+		if err != nil {
+			log.WithError(err).Errorf("Skipping exchange rates provider %q", providerConfig.Type)
+			continue
+		}
+		ratesProviders = append(ratesProviders, provider)
+	}
+	if len(ratesProviders) > 0 {
+		backend.ratesUpdater.SetProviders(ratesProviders...)
+	}
+	backend.checkChartTimelinesProviders()
 	backend.ratesUpdater.Observe(backend.Notify)
+	backend.ratesUpdater.Observe(func(event observable.Event) {
+		if event.Subject == rates.RatesEventSubject {
+			backend.requestChartTimelinesUpdate()
+		}
+	})
 
 	backend.banners = banners.NewBanners()
 	backend.banners.Observe(backend.Notify)
@@ -411,6 +453,10 @@ func (backend *Backend) defaultProdServers(code coinpkg.Code) []*config.ServerIn
 		return backend.config.AppConfig().Backend.BTC.ElectrumServers
 	case coinpkg.CodeTBTC:
 		return backend.config.AppConfig().Backend.TBTC.ElectrumServers
+	case coinpkg.CodeSBTC:
+		return backend.config.AppConfig().Backend.SBTC.ElectrumServers
+	case coinpkg.CodeTBTC4:
+		return backend.config.AppConfig().Backend.TBTC4.ElectrumServers
 	case coinpkg.CodeRBTC:
 		return backend.config.AppConfig().Backend.RBTC.ElectrumServers
 	case coinpkg.CodeLTC:
@@ -442,6 +488,10 @@ mCMuGBNHsbrs6rI1hbI4Qq6GYazLaDRqdCufTA==
 		return []*config.ServerInfo{{Server: "btc1.shiftcrypto.dev:50001", TLS: true, PEMCert: devShiftCA}}
 	case coinpkg.CodeTBTC:
 		return []*config.ServerInfo{{Server: "tbtc1.shiftcrypto.dev:51001", TLS: true, PEMCert: devShiftCA}}
+	case coinpkg.CodeSBTC:
+		return []*config.ServerInfo{{Server: "mempool.space:60602", TLS: true, PEMCert: config.ISRGRootX1}}
+	case coinpkg.CodeTBTC4:
+		return []*config.ServerInfo{{Server: "mempool.space:40002", TLS: true, PEMCert: config.ISRGRootX1}}
 	case coinpkg.CodeRBTC:
 		return []*config.ServerInfo{
 			{Server: "127.0.0.1:52001", TLS: false, PEMCert: ""},
@@ -469,6 +519,76 @@ func (backend *Backend) DevServers() bool {
 	return backend.arguments.DevServers()
 }
 
+// ethNodeChainIDTimeout is the timeout for querying the network of a configured Ethereum node.
+const ethNodeChainIDTimeout = 10 * time.Second
+
+// ethClient returns the client and the transactions source for the EVM network of the given coin
+// code, with CodeETH also covering the tokens on Ethereum mainnet. EtherScan is used unless a
+// JSON-RPC node is configured for the network. If the node can't be used or is connected to a
+// different network, EtherScan is used as well.
+func (backend *Backend) ethClient(code coinpkg.Code) (rpcclient.Interface, eth.TransactionsSource) {
+	appConfig := backend.config.AppConfig().Backend
+	ethConfig := appConfig.ETH
+	chainID := params.MainnetChainConfig.ChainID.Uint64()
+	etherScan := etherscan.NewEtherScan("https://api.etherscan.io/api", backend.etherScanHTTPClient)
+	switch code {
+	case coinpkg.CodeARBETH:
+		ethConfig = appConfig.ARBETH
+		chainID = eth.ChainIDArbitrum
+		etherScan = etherscan.NewEtherScanV2(eth.ChainIDArbitrum, backend.etherScanHTTPClient)
+	case coinpkg.CodeOPETH:
+		ethConfig = appConfig.OPETH
+		chainID = eth.ChainIDOptimism
+		etherScan = etherscan.NewEtherScanV2(eth.ChainIDOptimism, backend.etherScanHTTPClient)
+	case coinpkg.CodeBASEETH:
+		ethConfig = appConfig.BASEETH
+		chainID = eth.ChainIDBase
+		etherScan = etherscan.NewEtherScanV2(eth.ChainIDBase, backend.etherScanHTTPClient)
+	case coinpkg.CodePOL:
+		ethConfig = appConfig.POL
+		chainID = eth.ChainIDPolygon
+		etherScan = etherscan.NewEtherScanV2(eth.ChainIDPolygon, backend.etherScanHTTPClient)
+	}
+	if ethConfig.NodeURL == "" {
+		return etherScan, etherScan
+	}
+	var indexer eth.TransactionsSource
+	if ethConfig.IndexerURL != "" {
+		indexer = etherscan.NewEtherScan(ethConfig.IndexerURL, backend.httpClient)
+	}
+	node, err := jsonrpc.NewClient(ethConfig.NodeURL, backend.httpClient, indexer, ethConfig.ScanBlocks)
+	if err != nil {
+		backend.log.WithError(err).WithField("code", code).Error(
+			"Could not use the Ethereum node, falling back to EtherScan")
+		return etherScan, etherScan
+	}
+	// Sending to a node of another network would sign transactions for the wrong chain, and the
+	// balances and transactions shown would be those of the other network.
+	ctx, cancel := context.WithTimeout(context.Background(), ethNodeChainIDTimeout)
+	nodeChainID, err := node.ChainID(ctx)
+	cancel()
+	switch {
+	case err != nil:
+		// The node might be temporarily unreachable. It is used anyway, like when it goes offline
+		// later, so the account shows the connection error.
+		backend.log.WithError(err).WithField("code", code).Warning(
+			"Could not verify the network of the Ethereum node")
+	case !nodeChainID.IsUint64() || nodeChainID.Uint64() != chainID:
+		backend.log.WithField("code", code).Errorf(
+			"The Ethereum node is connected to chain ID %s instead of %d, falling back to EtherScan",
+			nodeChainID, chainID)
+		return etherScan, etherScan
+	}
+	switch ethConfig.TransactionsSource {
+	case config.ETHTransactionsSourceNone:
+		return node, nil
+	case config.ETHTransactionsSourceEtherScan:
+		return node, etherScan
+	default:
+		return node, node
+	}
+}
+
 // Coin returns the coin with the given code or an error if no such coin exists.
 func (backend *Backend) Coin(code coinpkg.Code) (coinpkg.Coin, error) {
 	defer backend.coinsLock.Lock()()
@@ -479,6 +599,9 @@ func (backend *Backend) Coin(code coinpkg.Code) (coinpkg.Coin, error) {
 	dbFolder := backend.arguments.CacheDirectoryPath()
 
 	erc20Token := erc20TokenByCode(code)
+	if erc20Token == nil {
+		erc20Token = customERC20TokenByCode(backend.config.AccountsConfig(), code)
+	}
 	btcFormatUnit := backend.config.AppConfig().Backend.BtcUnit
 	switch {
 	case code == coinpkg.CodeRBTC:
@@ -488,6 +611,14 @@ func (backend *Backend) Coin(code coinpkg.Code) (coinpkg.Coin, error) {
 		servers := backend.defaultElectrumXServers(code)
 		coin = btc.NewCoin(coinpkg.CodeTBTC, "Bitcoin Testnet", "TBTC", btcFormatUnit, &chaincfg.TestNet3Params, dbFolder, servers,
 			"https://blockstream.info/testnet/tx/", backend.socksProxy)
+	case code == coinpkg.CodeSBTC:
+		servers := backend.defaultElectrumXServers(code)
+		coin = btc.NewCoin(coinpkg.CodeSBTC, "Bitcoin Signet", "SBTC", btcFormatUnit, &chaincfg.SigNetParams, dbFolder, servers,
+			"https://mempool.space/signet/tx/", backend.socksProxy)
+	case code == coinpkg.CodeTBTC4:
+		servers := backend.defaultElectrumXServers(code)
+		coin = btc.NewCoin(coinpkg.CodeTBTC4, "Bitcoin Testnet4", "TBTC4", btcFormatUnit, &testnet4.Params, dbFolder, servers,
+			"https://mempool.space/testnet4/tx/", backend.socksProxy)
 	case code == coinpkg.CodeBTC:
 		servers := backend.defaultElectrumXServers(code)
 		coin = btc.NewCoin(coinpkg.CodeBTC, "Bitcoin", "BTC", btcFormatUnit, &chaincfg.MainNetParams, dbFolder, servers,
@@ -501,10 +632,10 @@ func (backend *Backend) Coin(code coinpkg.Code) (coinpkg.Coin, error) {
 		coin = btc.NewCoin(coinpkg.CodeLTC, "Litecoin", "LTC", coinpkg.BtcUnitDefault, &ltc.MainNetParams, dbFolder, servers,
 			"https://blockchair.com/litecoin/transaction/", backend.socksProxy)
 	case code == coinpkg.CodeETH:
-		etherScan := etherscan.NewEtherScan("https://api.etherscan.io/api", backend.etherScanHTTPClient)
-		coin = eth.NewCoin(etherScan, code, "Ethereum", "ETH", "ETH", params.MainnetChainConfig,
+		client, transactionsSource := backend.ethClient(code)
+		coin = eth.NewCoin(client, code, "Ethereum", "ETH", "ETH", params.MainnetChainConfig,
 			"https://etherscan.io/tx/",
-			etherScan,
+			transactionsSource,
 			nil)
 	case code == coinpkg.CodeGOETH:
 		etherScan := etherscan.NewEtherScan("https://api-goerli.etherscan.io/api", backend.etherScanHTTPClient)
@@ -518,11 +649,35 @@ func (backend *Backend) Coin(code coinpkg.Code) (coinpkg.Coin, error) {
 			"https://sepolia.etherscan.io/tx/",
 			etherScan,
 			nil)
+	case code == coinpkg.CodeARBETH:
+		client, transactionsSource := backend.ethClient(code)
+		coin = eth.NewCoin(client, code, "Arbitrum One", "ETH", "ETH", eth.NewChainConfig(eth.ChainIDArbitrum),
+			"https://arbiscan.io/tx/",
+			transactionsSource,
+			nil)
+	case code == coinpkg.CodeOPETH:
+		client, transactionsSource := backend.ethClient(code)
+		coin = eth.NewCoin(client, code, "OP Mainnet", "ETH", "ETH", eth.NewChainConfig(eth.ChainIDOptimism),
+			"https://optimistic.etherscan.io/tx/",
+			transactionsSource,
+			nil)
+	case code == coinpkg.CodeBASEETH:
+		client, transactionsSource := backend.ethClient(code)
+		coin = eth.NewCoin(client, code, "Base", "ETH", "ETH", eth.NewChainConfig(eth.ChainIDBase),
+			"https://basescan.org/tx/",
+			transactionsSource,
+			nil)
+	case code == coinpkg.CodePOL:
+		client, transactionsSource := backend.ethClient(code)
+		coin = eth.NewCoin(client, code, "Polygon", "POL", "POL", eth.NewChainConfig(eth.ChainIDPolygon),
+			"https://polygonscan.com/tx/",
+			transactionsSource,
+			nil)
 	case erc20Token != nil:
-		etherScan := etherscan.NewEtherScan("https://api.etherscan.io/api", backend.etherScanHTTPClient)
-		coin = eth.NewCoin(etherScan, erc20Token.code, erc20Token.name, erc20Token.unit, "ETH", params.MainnetChainConfig,
+		client, transactionsSource := backend.ethClient(coinpkg.CodeETH)
+		coin = eth.NewCoin(client, erc20Token.code, erc20Token.name, erc20Token.unit, "ETH", params.MainnetChainConfig,
 			"https://etherscan.io/tx/",
-			etherScan,
+			transactionsSource,
 			erc20Token.token,
 		)
 	default:
@@ -15,6 +15,10 @@
 package backend
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -22,6 +26,8 @@ import (
 	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
 	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/etherscan"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/jsonrpc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
 	keystoremock "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/mocks"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
@@ -252,3 +258,36 @@ func TestAccounts(t *testing.T) {
 	require.Equal(t, "My ETH Renamed", b.Config().AccountsConfig().Lookup("v0-55555555-eth-0").Name)
 	require.Equal(t, "My ETH Renamed", lookup(b.Accounts(), "v0-55555555-eth-0").Config().Config.Name)
 }
+
+func TestETHClientChainID(t *testing.T) {
+	// The node is connected to Ethereum mainnet.
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, "eth_chainId", request.Method)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, request.ID)
+		require.NoError(t, err)
+	}))
+	defer node.Close()
+
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+	require.NoError(t, b.config.ModifyAppConfig(func(appConfig *config.AppConfig) error {
+		appConfig.Backend.ETH.NodeURL = node.URL
+		appConfig.Backend.OPETH.NodeURL = node.URL
+		return nil
+	}))
+
+	client, transactionsSource := b.ethClient(coinpkg.CodeETH)
+	require.IsType(t, &jsonrpc.Client{}, client)
+	require.IsType(t, &jsonrpc.Client{}, transactionsSource)
+
+	// The node is not used for another network.
+	client, transactionsSource = b.ethClient(coinpkg.CodeOPETH)
+	require.IsType(t, &etherscan.EtherScan{}, client)
+	require.IsType(t, &etherscan.EtherScan{}, transactionsSource)
+}
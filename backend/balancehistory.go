@@ -0,0 +1,295 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/util"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// BalanceHistoryEntry is one point of a balance timeseries.
+type BalanceHistoryEntry struct {
+	Time int64 `json:"time"`
+	// Amount is the balance in the smallest unit of the coin.
+	Amount string `json:"amount"`
+	// FormattedAmount is the balance in the standard unit of the coin, e.g. BTC.
+	FormattedAmount string `json:"formattedAmount"`
+	// FiatValue is nil if the historical exchange rate at this time is missing.
+	FiatValue          *float64 `json:"fiatValue"`
+	FormattedFiatValue string   `json:"formattedFiatValue"`
+}
+
+// BalanceHistorySeries is the balance history of one account or of all accounts of one coin.
+type BalanceHistorySeries struct {
+	// AccountCode is empty for the series of a coin.
+	AccountCode accountsTypes.Code `json:"accountCode,omitempty"`
+	CoinCode    coin.Code          `json:"coinCode"`
+	Unit        string             `json:"unit"`
+	// DataMissing is true if block headers or historical exchange rates are missing, in which
+	// case the series is incomplete.
+	DataMissing bool                  `json:"dataMissing"`
+	Daily       []BalanceHistoryEntry `json:"daily"`
+	Hourly      []BalanceHistoryEntry `json:"hourly"`
+}
+
+// BalanceHistory contains the balance history of accounts and coins, in the native unit and in
+// fiat.
+type BalanceHistory struct {
+	// Fiat currency of the fiat values.
+	Fiat     string                  `json:"fiat"`
+	Accounts []*BalanceHistorySeries `json:"accounts"`
+	Coins    []*BalanceHistorySeries `json:"coins"`
+	// Latest rate timestamp available among all enabled coins.
+	LastTimestamp int64 `json:"lastTimestamp"`
+}
+
+// balancePoint is a point of a balance timeseries with full precision values.
+type balancePoint struct {
+	time   int64
+	amount *big.Int
+	// fiatValue is nil if the exchange rate is missing.
+	fiatValue *big.Rat
+}
+
+// balancePoints values each entry of the timeseries using `price`, which returns the price of one
+// standard unit of the coin at the given time, or 0 if it is not available.
+func balancePoints(
+	timeseries []accounts.TimeseriesEntry,
+	coinDecimals *big.Int,
+	price func(time.Time) float64,
+) []balancePoint {
+	result := make([]balancePoint, len(timeseries))
+	for i, e := range timeseries {
+		point := balancePoint{
+			time:   e.Time.Unix(),
+			amount: new(big.Int).Set(e.Value.BigInt()),
+		}
+		if e.Value.BigInt().Sign() == 0 {
+			point.fiatValue = new(big.Rat)
+		} else if p := price(e.Time); p != 0 {
+			point.fiatValue = new(big.Rat).Mul(
+				new(big.Rat).SetFrac(point.amount, coinDecimals),
+				new(big.Rat).SetFloat64(p),
+			)
+		}
+		result[i] = point
+	}
+	return result
+}
+
+// mergeBalancePoints sums up the points of several timeseries by time. A fiat value is missing in
+// the result if it is missing in any of the summed points.
+func mergeBalancePoints(series ...[]balancePoint) []balancePoint {
+	merged := map[int64]*balancePoint{}
+	for _, points := range series {
+		for _, point := range points {
+			entry, ok := merged[point.time]
+			if !ok {
+				entry = &balancePoint{time: point.time, amount: new(big.Int), fiatValue: new(big.Rat)}
+				merged[point.time] = entry
+			}
+			entry.amount.Add(entry.amount, point.amount)
+			if entry.fiatValue != nil && point.fiatValue != nil {
+				entry.fiatValue.Add(entry.fiatValue, point.fiatValue)
+			} else {
+				entry.fiatValue = nil
+			}
+		}
+	}
+	result := make([]balancePoint, 0, len(merged))
+	for _, entry := range merged {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].time < result[j].time })
+	return result
+}
+
+// balanceSeries accumulates the daily and hourly points of a series.
+type balanceSeries struct {
+	coin        coin.Coin
+	dataMissing bool
+	daily       [][]balancePoint
+	hourly      [][]balancePoint
+}
+
+// BalanceHistory computes the daily and hourly balance history of each active account and of each
+// coin, summed over all active accounts of that coin. If accountCode is not empty, only that
+// account is included.
+func (backend *Backend) BalanceHistory(accountCode accountsTypes.Code) (*BalanceHistory, error) {
+	fiat := backend.Config().AppConfig().Backend.MainFiat
+	isFiatBtc := fiat == rates.BTC.String()
+	formatBtcAsSat := util.FormatBtcAsSat(backend.Config().AppConfig().Backend.BtcUnit)
+
+	// History until this point in time. Fiat values are missing after this point, so we stop here
+	// even for the native amounts to keep both aligned.
+	until := backend.RatesUpdater().HistoryLatestTimestampAll(backend.allCoinCodes(), fiat)
+	lastTimestamp := until.UnixMilli()
+	if until.IsZero() {
+		until = time.Now()
+		lastTimestamp = 0
+	}
+	// Time from which hourly points are computed.
+	hourlyFrom := time.Now().AddDate(0, 0, -7).Truncate(24 * time.Hour)
+
+	var selectedAccounts []accounts.Interface
+	if accountCode != "" {
+		account := backend.Accounts().lookup(accountCode)
+		if account == nil {
+			return nil, errp.Newf("Could not find account %s", accountCode)
+		}
+		selectedAccounts = []accounts.Interface{account}
+	} else {
+		selectedAccounts = backend.Accounts()
+	}
+
+	toEntries := func(c coin.Coin, points []balancePoint) []BalanceHistoryEntry {
+		result := make([]BalanceHistoryEntry, len(points))
+		for i, point := range points {
+			entry := BalanceHistoryEntry{
+				Time:            point.time,
+				Amount:          point.amount.String(),
+				FormattedAmount: c.FormatAmount(coin.NewAmount(point.amount), false),
+			}
+			if point.fiatValue != nil {
+				value, _ := point.fiatValue.Float64()
+				entry.FiatValue = &value
+				entry.FormattedFiatValue = coin.FormatAsCurrency(point.fiatValue, isFiatBtc, formatBtcAsSat)
+			}
+			result[i] = entry
+		}
+		return result
+	}
+
+	result := &BalanceHistory{
+		Fiat:          fiat,
+		Accounts:      []*BalanceHistorySeries{},
+		Coins:         []*BalanceHistorySeries{},
+		LastTimestamp: lastTimestamp,
+	}
+	coinSeries := map[coin.Code]*balanceSeries{}
+	// Keeps the coins in the order of the accounts.
+	coinCodes := []coin.Code{}
+	for _, account := range selectedAccounts {
+		if account.Config().Config.Inactive {
+			continue
+		}
+		if account.FatalError() {
+			continue
+		}
+		if err := account.Initialize(); err != nil {
+			return nil, err
+		}
+		txs, err := account.Transactions()
+		if err != nil {
+			return nil, err
+		}
+		accountCoin := account.Coin()
+		coinCode := accountCoin.Code()
+		perCoin, ok := coinSeries[coinCode]
+		if !ok {
+			perCoin = &balanceSeries{coin: accountCoin}
+			coinSeries[coinCode] = perCoin
+			coinCodes = append(coinCodes, coinCode)
+		}
+		series := &BalanceHistorySeries{
+			AccountCode: account.Config().Config.Code,
+			CoinCode:    coinCode,
+			Unit:        accountCoin.Unit(false),
+			Daily:       []BalanceHistoryEntry{},
+			Hourly:      []BalanceHistoryEntry{},
+		}
+		result.Accounts = append(result.Accounts, series)
+
+		markMissing := func(reason string) {
+			backend.log.
+				WithField("code", series.AccountCode).
+				Info("BalanceHistory data missing: " + reason)
+			series.DataMissing = true
+			perCoin.dataMissing = true
+		}
+
+		earliestTxTime, err := txs.EarliestTime()
+		if errp.Cause(err) == errors.ErrNotAvailable {
+			markMissing("earliest tx time")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if earliestTxTime.IsZero() {
+			// No timed transaction, the history is empty.
+			continue
+		}
+		earliestPriceAvailable := backend.RatesUpdater().HistoryEarliestTimestamp(string(coinCode), fiat)
+		if earliestPriceAvailable.IsZero() || earliestTxTime.Before(earliestPriceAvailable) {
+			// The native amounts are still computed, only some fiat values will be missing.
+			markMissing("historical rates")
+		}
+
+		timeseriesDaily, err := txs.Timeseries(earliestTxTime.Truncate(24*time.Hour), until, 24*time.Hour)
+		if errp.Cause(err) == errors.ErrNotAvailable {
+			markMissing("timeseries")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		timeseriesHourly, err := txs.Timeseries(hourlyFrom, until, time.Hour)
+		if errp.Cause(err) == errors.ErrNotAvailable {
+			markMissing("timeseries")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// e.g. 1e8 for Bitcoin/Litecoin, 1e18 for Ethereum, etc.
+		coinDecimals := new(big.Int).Exp(
+			big.NewInt(10),
+			big.NewInt(int64(accountCoin.Decimals(false))),
+			nil,
+		)
+		price := func(at time.Time) float64 {
+			return backend.RatesUpdater().HistoricalPriceAt(string(coinCode), fiat, at)
+		}
+		daily := balancePoints(timeseriesDaily, coinDecimals, price)
+		hourly := balancePoints(timeseriesHourly, coinDecimals, price)
+		series.Daily = toEntries(accountCoin, daily)
+		series.Hourly = toEntries(accountCoin, hourly)
+		perCoin.daily = append(perCoin.daily, daily)
+		perCoin.hourly = append(perCoin.hourly, hourly)
+	}
+
+	for _, coinCode := range coinCodes {
+		perCoin := coinSeries[coinCode]
+		result.Coins = append(result.Coins, &BalanceHistorySeries{
+			CoinCode:    coinCode,
+			Unit:        perCoin.coin.Unit(false),
+			DataMissing: perCoin.dataMissing,
+			Daily:       toEntries(perCoin.coin, mergeBalancePoints(perCoin.daily...)),
+			Hourly:      toEntries(perCoin.coin, mergeBalancePoints(perCoin.hourly...)),
+		})
+	}
+	return result, nil
+}
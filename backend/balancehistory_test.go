@@ -0,0 +1,151 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	coinMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalancePoints(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	timeseries := []accounts.TimeseriesEntry{
+		{Time: day(1), Value: coinpkg.NewAmountFromInt64(0)},
+		{Time: day(2), Value: coinpkg.NewAmountFromInt64(50000000)},
+		{Time: day(3), Value: coinpkg.NewAmountFromInt64(150000000)},
+	}
+	price := func(at time.Time) float64 {
+		if at.Equal(day(3)) {
+			return 0
+		}
+		return 40000
+	}
+	points := balancePoints(timeseries, big.NewInt(1e8), price)
+	require.Len(t, points, 3)
+	require.Equal(t, "0", points[0].fiatValue.RatString())
+	require.Equal(t, "20000", points[1].fiatValue.RatString())
+	require.Equal(t, day(2).Unix(), points[1].time)
+	require.Equal(t, big.NewInt(150000000), points[2].amount)
+	require.Nil(t, points[2].fiatValue)
+
+	other := []balancePoint{
+		{time: day(2).Unix(), amount: big.NewInt(10), fiatValue: big.NewRat(1, 2)},
+		{time: day(4).Unix(), amount: big.NewInt(20), fiatValue: big.NewRat(1, 1)},
+	}
+	merged := mergeBalancePoints(points, other)
+	require.Len(t, merged, 4)
+	require.Equal(t, big.NewInt(50000010), merged[1].amount)
+	require.Equal(t, "40001/2", merged[1].fiatValue.RatString())
+	require.Nil(t, merged[2].fiatValue)
+	require.Equal(t, day(4).Unix(), merged[3].time)
+	// The inputs are not modified.
+	require.Equal(t, big.NewInt(50000000), points[1].amount)
+}
+
+func TestBalanceHistory(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	now := time.Now()
+	daysAgo := func(days int) *time.Time {
+		t := now.AddDate(0, 0, -days)
+		return &t
+	}
+	btc := &coinMocks.CoinMock{
+		CodeFunc:     func() coinpkg.Code { return coinpkg.CodeBTC },
+		UnitFunc:     func(bool) string { return "BTC" },
+		DecimalsFunc: func(bool) uint { return 8 },
+		FormatAmountFunc: func(amount coinpkg.Amount, isFee bool) string {
+			return new(big.Rat).SetFrac(amount.BigInt(), big.NewInt(1e8)).FloatString(8)
+		},
+	}
+	makeAccount := func(code accountsTypes.Code, txs []*accounts.TransactionData) accounts.Interface {
+		cfg := &accounts.AccountConfig{Config: &config.Account{Code: code}}
+		return &accountsMocks.InterfaceMock{
+			ConfigFunc:     func() *accounts.AccountConfig { return cfg },
+			CoinFunc:       func() coinpkg.Coin { return btc },
+			FatalErrorFunc: func() bool { return false },
+			InitializeFunc: func() error { return nil },
+			TransactionsFunc: func() (accounts.OrderedTransactions, error) {
+				return accounts.NewOrderedTransactions(txs), nil
+			},
+			CloseFunc: func() {},
+		}
+	}
+	receive := func(height int, at *time.Time, amount int64) *accounts.TransactionData {
+		return &accounts.TransactionData{
+			Type:      accounts.TxTypeReceive,
+			Height:    height,
+			Timestamp: at,
+			Amount:    coinpkg.NewAmountFromInt64(amount),
+		}
+	}
+	b.accounts = AccountsList{
+		makeAccount("btc-1", []*accounts.TransactionData{
+			receive(10, daysAgo(20), 100000000),
+			receive(20, daysAgo(3), 50000000),
+		}),
+		makeAccount("btc-2", []*accounts.TransactionData{
+			receive(15, daysAgo(10), 1000),
+		}),
+		makeAccount("btc-3", nil),
+	}
+
+	history, err := b.BalanceHistory("")
+	require.NoError(t, err)
+	require.Len(t, history.Accounts, 3)
+	require.Len(t, history.Coins, 1)
+
+	account := history.Accounts[0]
+	require.Equal(t, accountsTypes.Code("btc-1"), account.AccountCode)
+	// No historical rates are available in the test.
+	require.True(t, account.DataMissing)
+	require.NotEmpty(t, account.Daily)
+	// The first point is at the start of the day of the first transaction.
+	require.Equal(t, "0", account.Daily[0].Amount)
+	require.Equal(t, "100000000", account.Daily[1].Amount)
+	require.Nil(t, account.Daily[1].FiatValue)
+	last := account.Daily[len(account.Daily)-1]
+	require.Equal(t, "150000000", last.Amount)
+	require.Equal(t, "1.50000000", last.FormattedAmount)
+	require.NotEmpty(t, account.Hourly)
+	require.Equal(t, "150000000", account.Hourly[len(account.Hourly)-1].Amount)
+
+	require.Empty(t, history.Accounts[2].Daily)
+
+	btcSeries := history.Coins[0]
+	require.Equal(t, coinpkg.CodeBTC, btcSeries.CoinCode)
+	require.Empty(t, btcSeries.AccountCode)
+	require.True(t, btcSeries.DataMissing)
+	require.Equal(t, account.Daily[0].Time, btcSeries.Daily[0].Time)
+	require.Equal(t, "150001000", btcSeries.Daily[len(btcSeries.Daily)-1].Amount)
+
+	history, err = b.BalanceHistory("btc-2")
+	require.NoError(t, err)
+	require.Len(t, history.Accounts, 1)
+	require.Equal(t, "1000", history.Coins[0].Daily[1].Amount)
+
+	_, err = b.BalanceHistory("unknown")
+	require.Error(t, err)
+}
@@ -19,12 +19,9 @@ import (
 	"sort"
 	"time"
 
-	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
-	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/util"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
-	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 )
 
 func (backend *Backend) allCoinCodes() []string {
@@ -76,39 +73,6 @@ type Chart struct {
 	LastTimestamp int64 `json:"lastTimestamp"`
 }
 
-func (backend *Backend) addChartData(
-	coinCode coin.Code,
-	fiat string,
-	coinDecimals *big.Int,
-	timeseries []accounts.TimeseriesEntry,
-	chartEntries map[int64]RatChartEntry,
-) {
-	for _, e := range timeseries {
-		price := backend.RatesUpdater().HistoricalPriceAt(
-			string(coinCode),
-			fiat,
-			e.Time)
-		timestamp := e.Time.Unix()
-		chartEntry := chartEntries[timestamp]
-
-		chartEntry.Time = timestamp
-		fiatValue := new(big.Rat).Mul(
-			new(big.Rat).SetFrac(
-				e.Value.BigInt(),
-				coinDecimals,
-			),
-			new(big.Rat).SetFloat64(price),
-		)
-
-		if chartEntry.RatValue == nil {
-			chartEntry.RatValue = new(big.Rat).Set(fiatValue)
-		} else {
-			chartEntry.RatValue.Add(fiatValue, chartEntry.RatValue)
-		}
-		chartEntries[timestamp] = chartEntry
-	}
-}
-
 // ChartData assembles chart data for all active accounts.
 func (backend *Backend) ChartData() (*Chart, error) {
 	// If true, we are missing headers or historical conversion rates necessary to compute the chart
@@ -132,6 +96,7 @@ func (backend *Backend) ChartData() (*Chart, error) {
 	lastTimestamp := until.UnixMilli()
 
 	formatBtcAsSat := util.FormatBtcAsSat(backend.Config().AppConfig().Backend.BtcUnit)
+	hourlyFrom := chartHourlyFrom()
 
 	currentTotal := new(big.Rat)
 	currentTotalMissing := false
@@ -152,6 +117,7 @@ func (backend *Backend) ChartData() (*Chart, error) {
 		if err != nil {
 			return nil, err
 		}
+		generation := backend.chartTimelineGeneration(account.Config().Config.Code)
 		txs, err := account.Transactions()
 		if err != nil {
 			return nil, err
@@ -191,67 +157,21 @@ func (backend *Backend) ChartData() (*Chart, error) {
 			continue
 		}
 
-		// Time from which the chart turns from daily points to hourly points.
-		hourlyFrom := time.Now().AddDate(0, 0, -7).Truncate(24 * time.Hour)
-
-		earliestPriceAvailable := backend.RatesUpdater().HistoryEarliestTimestamp(
-			string(account.Coin().Code()),
-			fiat)
-
-		earliestTxTime, err := txs.EarliestTime()
-		if errp.Cause(err) == errors.ErrNotAvailable {
-			backend.log.WithField("coin", account.Coin().Code()).Info("ChartDataMissing/earliestTxtime")
-			chartDataMissing = true
-			continue
-		}
+		timeline, dataMissing, err := backend.chartTimeline(
+			account, txs, generation, fiat, until, hourlyFrom)
 		if err != nil {
 			return nil, err
 		}
-
-		if earliestTxTime.IsZero() {
-			// Ignore the chart for this account, there is no timed transaction.
-			continue
-		}
-		if earliestPriceAvailable.IsZero() || earliestTxTime.Before(earliestPriceAvailable) {
-			chartDataMissing = true
-			backend.log.
-				WithField("coin", account.Coin().Code()).
-				WithField("earliestTxTime", earliestTxTime).
-				WithField("earliestPriceAvailable", earliestPriceAvailable).
-				Info("ChartDataMissing")
-			continue
-		}
-
-		timeseriesDaily, err := txs.Timeseries(
-			earliestTxTime.Truncate(24*time.Hour),
-			until,
-			24*time.Hour,
-		)
-		if errp.Cause(err) == errors.ErrNotAvailable {
-			backend.log.WithField("coin", account.Coin().Code()).Info("ChartDataMissing")
+		if dataMissing {
 			chartDataMissing = true
 			continue
 		}
-		if err != nil {
-			return nil, err
-		}
-		timeseriesHourly, err := txs.Timeseries(
-			hourlyFrom,
-			until,
-			time.Hour,
-		)
-		if errp.Cause(err) == errors.ErrNotAvailable {
-			backend.log.WithField("coin", account.Coin().Code()).Info("ChartDataMissing")
-			chartDataMissing = true
+		if timeline == nil {
+			// Ignore the chart for this account, there is no timed transaction.
 			continue
 		}
-		if err != nil {
-			return nil, err
-		}
-
-		backend.addChartData(account.Coin().Code(), fiat, coinDecimals, timeseriesDaily, chartEntriesDaily)
-		backend.addChartData(account.Coin().Code(), fiat, coinDecimals, timeseriesHourly, chartEntriesHourly)
-
+		addChartTimeline(timeline.daily, chartEntriesDaily)
+		addChartTimeline(timeline.hourly, chartEntriesHourly)
 	}
 
 	toSortedSlice := func(s map[int64]RatChartEntry, fiat string) []ChartEntry {
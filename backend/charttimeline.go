@@ -0,0 +1,477 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// chartPoint is an account balance and its fiat value at a point in time.
+type chartPoint struct {
+	time    time.Time
+	balance coinpkg.Amount
+	value   *big.Rat
+}
+
+// chartTimeline holds the daily and hourly fiat values of the balance of one account, as shown in
+// the portfolio chart. A timeline is immutable once computed.
+//
+// Cached timelines stay valid as long as the transactions of the account up to the end of the
+// timeline do not change, and no older historical exchange rates become available. Newer exchange
+// rates and newer transactions do not change the existing points, so the timeline is extended with
+// new points starting at the balance of its last point instead of being recomputed.
+//
+// Timelines are also persisted in the cache directory, so they are not recomputed on every start.
+type chartTimeline struct {
+	fiat string
+	// earliestPriceAvailable is the earliest historical exchange rate when the timeline was
+	// computed. Backfilled rates invalidate the timeline.
+	earliestPriceAvailable time.Time
+	// until is the end of the timeline, and txsState the state of the transactions it was computed
+	// from at that time.
+	until    time.Time
+	txsState accounts.TransactionsState
+	daily    []chartPoint
+	hourly   []chartPoint
+}
+
+// valid returns whether the timeline can be extended for the given transactions.
+func (timeline *chartTimeline) valid(
+	txs accounts.OrderedTransactions, fiat string, earliestPriceAvailable time.Time) bool {
+	return timeline.fiat == fiat &&
+		timeline.earliestPriceAvailable.Equal(earliestPriceAvailable) &&
+		txs.StateAt(timeline.until) == timeline.txsState
+}
+
+type chartPointJSON struct {
+	Time    time.Time `json:"time"`
+	Balance string    `json:"balance"`
+	Value   string    `json:"value"`
+}
+
+type chartTimelineJSON struct {
+	Fiat                   string           `json:"fiat"`
+	EarliestPriceAvailable time.Time        `json:"earliestPriceAvailable"`
+	Until                  time.Time        `json:"until"`
+	TxsCount               int              `json:"txsCount"`
+	LatestTxID             string           `json:"latestTxId"`
+	Daily                  []chartPointJSON `json:"daily"`
+	Hourly                 []chartPointJSON `json:"hourly"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (timeline *chartTimeline) MarshalJSON() ([]byte, error) {
+	encodePoints := func(points []chartPoint) []chartPointJSON {
+		result := make([]chartPointJSON, len(points))
+		for i, point := range points {
+			result[i] = chartPointJSON{
+				Time:    point.time,
+				Balance: point.balance.BigInt().String(),
+				Value:   point.value.RatString(),
+			}
+		}
+		return result
+	}
+	return json.Marshal(chartTimelineJSON{
+		Fiat:                   timeline.fiat,
+		EarliestPriceAvailable: timeline.earliestPriceAvailable,
+		Until:                  timeline.until,
+		TxsCount:               timeline.txsState.Count,
+		LatestTxID:             timeline.txsState.LatestID,
+		Daily:                  encodePoints(timeline.daily),
+		Hourly:                 encodePoints(timeline.hourly),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (timeline *chartTimeline) UnmarshalJSON(jsonBytes []byte) error {
+	var decoded chartTimelineJSON
+	if err := json.Unmarshal(jsonBytes, &decoded); err != nil {
+		return errp.WithStack(err)
+	}
+	decodePoints := func(points []chartPointJSON) ([]chartPoint, error) {
+		result := make([]chartPoint, len(points))
+		for i, point := range points {
+			balance, ok := new(big.Int).SetString(point.Balance, 10)
+			if !ok {
+				return nil, errp.Newf("Invalid balance %q", point.Balance)
+			}
+			value, ok := new(big.Rat).SetString(point.Value)
+			if !ok {
+				return nil, errp.Newf("Invalid value %q", point.Value)
+			}
+			result[i] = chartPoint{time: point.Time, balance: coinpkg.NewAmount(balance), value: value}
+		}
+		return result, nil
+	}
+	daily, err := decodePoints(decoded.Daily)
+	if err != nil {
+		return err
+	}
+	hourly, err := decodePoints(decoded.Hourly)
+	if err != nil {
+		return err
+	}
+	*timeline = chartTimeline{
+		fiat:                   decoded.Fiat,
+		earliestPriceAvailable: decoded.EarliestPriceAvailable,
+		until:                  decoded.Until,
+		txsState: accounts.TransactionsState{
+			Count:    decoded.TxsCount,
+			LatestID: decoded.LatestTxID,
+		},
+		daily:  daily,
+		hourly: hourly,
+	}
+	return nil
+}
+
+// chartTimelines caches the chart timelines of the accounts. See `Backend.chartTimeline()`.
+type chartTimelines struct {
+	timelines map[accountsTypes.Code]*chartTimeline
+	// generation is increased whenever the transactions of an account might change. A timeline is
+	// only cached if the generation did not change while it was computed.
+	generation map[accountsTypes.Code]uint64
+	syncing    map[accountsTypes.Code]bool
+}
+
+// chartTimelinesDir returns the directory in which the chart timelines are persisted.
+func (backend *Backend) chartTimelinesDir() string {
+	return filepath.Join(backend.arguments.CacheDirectoryPath(), "charttimelines")
+}
+
+// chartTimelineFilename returns the file in which the chart timeline of the account in the given
+// fiat currency is persisted.
+func (backend *Backend) chartTimelineFilename(code accountsTypes.Code, fiat string) string {
+	return filepath.Join(backend.chartTimelinesDir(), fmt.Sprintf("%s-%s.json", code, fiat))
+}
+
+// loadChartTimeline returns the persisted chart timeline of the account, or nil if there is none.
+func (backend *Backend) loadChartTimeline(code accountsTypes.Code, fiat string) *chartTimeline {
+	jsonBytes, err := os.ReadFile(backend.chartTimelineFilename(code, fiat))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		backend.log.WithError(err).Error("could not read the chart timeline")
+		return nil
+	}
+	timeline := &chartTimeline{}
+	if err := json.Unmarshal(jsonBytes, timeline); err != nil {
+		backend.log.WithError(err).Error("could not decode the chart timeline")
+		return nil
+	}
+	return timeline
+}
+
+// storeChartTimeline persists the chart timeline of the account.
+func (backend *Backend) storeChartTimeline(code accountsTypes.Code, timeline *chartTimeline) error {
+	jsonBytes, err := json.Marshal(timeline)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	if err := os.MkdirAll(backend.chartTimelinesDir(), 0700); err != nil {
+		return errp.WithStack(err)
+	}
+	return errp.WithStack(os.WriteFile(backend.chartTimelineFilename(code, timeline.fiat), jsonBytes, 0600))
+}
+
+// onChartAccountEvent removes the cached chart timeline of an account from memory when it syncs.
+// The persisted timeline is validated against the synced transactions when it is used next. The
+// account also emits EventSyncDone when new exchange rates arrive, which does not remove the
+// timeline as no sync was started.
+func (backend *Backend) onChartAccountEvent(code accountsTypes.Code, event accountsTypes.Event) {
+	defer backend.chartTimelinesLock.Lock()()
+	switch event {
+	case accountsTypes.EventSyncStarted:
+		backend.chartTimelines.syncing[code] = true
+	case accountsTypes.EventSyncDone:
+		if !backend.chartTimelines.syncing[code] {
+			return
+		}
+		delete(backend.chartTimelines.syncing, code)
+	default:
+		return
+	}
+	backend.chartTimelines.generation[code]++
+	delete(backend.chartTimelines.timelines, code)
+}
+
+// removeChartTimeline removes the cached chart timeline of an account which is closed from memory.
+func (backend *Backend) removeChartTimeline(code accountsTypes.Code) {
+	defer backend.chartTimelinesLock.Lock()()
+	backend.chartTimelines.generation[code]++
+	delete(backend.chartTimelines.syncing, code)
+	delete(backend.chartTimelines.timelines, code)
+}
+
+// invalidateChartTimelines removes all cached chart timelines, including the persisted ones.
+func (backend *Backend) invalidateChartTimelines() {
+	defer backend.chartTimelinesLock.Lock()()
+	for code := range backend.chartTimelines.timelines {
+		backend.chartTimelines.generation[code]++
+	}
+	backend.chartTimelines.timelines = map[accountsTypes.Code]*chartTimeline{}
+	if err := os.RemoveAll(backend.chartTimelinesDir()); err != nil {
+		backend.log.WithError(err).Error("could not remove the chart timelines")
+	}
+}
+
+// chartTimelinesProvidersFilename returns the file in which the configured exchange rates providers
+// are stored, to detect when the persisted chart timelines were computed using other providers.
+func (backend *Backend) chartTimelinesProvidersFilename() string {
+	return filepath.Join(backend.arguments.CacheDirectoryPath(), "charttimelines-providers.json")
+}
+
+// checkChartTimelinesProviders removes the persisted chart timelines if the exchange rates
+// providers changed since they were computed, as their fiat values would be based on the rates of
+// the previous providers. The providers are only configured on startup. Timelines in other fiat
+// currencies are persisted separately and don't need to be removed when the fiat currency changes.
+func (backend *Backend) checkChartTimelinesProviders() {
+	providersJSON, err := json.Marshal(backend.config.AppConfig().Backend.RatesProviders)
+	if err != nil {
+		backend.log.WithError(err).Error("could not encode the rates providers")
+		return
+	}
+	persisted, err := os.ReadFile(backend.chartTimelinesProvidersFilename())
+	if err == nil && bytes.Equal(persisted, providersJSON) {
+		return
+	}
+	backend.invalidateChartTimelines()
+	if err := os.WriteFile(backend.chartTimelinesProvidersFilename(), providersJSON, 0600); err != nil {
+		backend.log.WithError(err).Error("could not persist the rates providers")
+	}
+}
+
+// chartTimelineGeneration returns the current generation of the account. It must be retrieved
+// before the transactions the timeline is computed from.
+func (backend *Backend) chartTimelineGeneration(code accountsTypes.Code) uint64 {
+	defer backend.chartTimelinesLock.RLock()()
+	return backend.chartTimelines.generation[code]
+}
+
+// chartTimeline returns the timeline of the account up to `until`, with hourly points starting at
+// `hourlyFrom`. The cached or persisted timeline is extended if possible and otherwise computed
+// from all transactions. `generation` must be retrieved using `chartTimelineGeneration()` before
+// `txs`.
+//
+// A nil timeline is returned if the account has no transactions with a timestamp. dataMissing is
+// true if block headers or historical exchange rates needed for the timeline are missing.
+func (backend *Backend) chartTimeline(
+	account accounts.Interface,
+	txs accounts.OrderedTransactions,
+	generation uint64,
+	fiat string,
+	until time.Time,
+	hourlyFrom time.Time,
+) (timeline *chartTimeline, dataMissing bool, err error) {
+	coinCode := account.Coin().Code()
+	code := account.Config().Config.Code
+	log := backend.log.WithField("coin", coinCode)
+
+	earliestPriceAvailable := backend.RatesUpdater().HistoryEarliestTimestamp(string(coinCode), fiat)
+	earliestTxTime, err := txs.EarliestTime()
+	if errp.Cause(err) == errors.ErrNotAvailable {
+		log.Info("ChartDataMissing/earliestTxtime")
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if earliestTxTime.IsZero() {
+		// There is no timed transaction.
+		return nil, false, nil
+	}
+	if earliestPriceAvailable.IsZero() || earliestTxTime.Before(earliestPriceAvailable) {
+		log.
+			WithField("earliestTxTime", earliestTxTime).
+			WithField("earliestPriceAvailable", earliestPriceAvailable).
+			Info("ChartDataMissing")
+		return nil, true, nil
+	}
+
+	unlock := backend.chartTimelinesLock.RLock()
+	cached := backend.chartTimelines.timelines[code]
+	unlock()
+	if cached == nil || cached.fiat != fiat {
+		cached = backend.loadChartTimeline(code, fiat)
+	}
+	if cached != nil && !cached.valid(txs, fiat, earliestPriceAvailable) {
+		cached = nil
+	}
+
+	// e.g. 1e8 for Bitcoin/Litecoin, 1e18 for Ethereum, etc. Used to convert from the smallest
+	// unit to the standard unit (BTC, LTC; ETH, etc.).
+	coinDecimals := new(big.Int).Exp(
+		big.NewInt(10),
+		big.NewInt(int64(account.Coin().Decimals(false))),
+		nil,
+	)
+	// extend returns the points from `from` to `until` in steps of `interval`. The given points are
+	// reused if they are aligned to `from`, and new points continue from the balance of the last
+	// one.
+	extend := func(points []chartPoint, from time.Time, interval time.Duration) ([]chartPoint, error) {
+		result := []chartPoint{}
+		for _, point := range points {
+			if !point.time.Before(from) && !point.time.After(until) {
+				result = append(result, point)
+			}
+		}
+		if len(result) > 0 && !result[0].time.Equal(from) {
+			result = []chartPoint{}
+		}
+		var timeseries []accounts.TimeseriesEntry
+		var err error
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			timeseries, err = txs.TimeseriesSince(
+				accounts.TimeseriesEntry{Time: last.time, Value: last.balance}, until, interval)
+		} else {
+			timeseries, err = txs.Timeseries(from, until, interval)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range timeseries {
+			price := backend.RatesUpdater().HistoricalPriceAt(string(coinCode), fiat, e.Time)
+			result = append(result, chartPoint{
+				time:    e.Time,
+				balance: e.Value,
+				value: new(big.Rat).Mul(
+					new(big.Rat).SetFrac(e.Value.BigInt(), coinDecimals),
+					new(big.Rat).SetFloat64(price),
+				),
+			})
+		}
+		return result, nil
+	}
+
+	timeline = &chartTimeline{
+		fiat:                   fiat,
+		earliestPriceAvailable: earliestPriceAvailable,
+		until:                  until,
+		txsState:               txs.StateAt(until),
+	}
+	var cachedDaily, cachedHourly []chartPoint
+	if cached != nil {
+		cachedDaily, cachedHourly = cached.daily, cached.hourly
+	}
+	timeline.daily, err = extend(cachedDaily, earliestTxTime.Truncate(24*time.Hour), 24*time.Hour)
+	if errp.Cause(err) == errors.ErrNotAvailable {
+		log.Info("ChartDataMissing")
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	timeline.hourly, err = extend(cachedHourly, hourlyFrom, time.Hour)
+	if errp.Cause(err) == errors.ErrNotAvailable {
+		log.Info("ChartDataMissing")
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	unchanged := cached != nil &&
+		cached.until.Equal(until) &&
+		len(cached.daily) == len(timeline.daily) &&
+		len(cached.hourly) == len(timeline.hourly)
+
+	unlock = backend.chartTimelinesLock.Lock()
+	store := false
+	if backend.chartTimelines.generation[code] == generation && !backend.chartTimelines.syncing[code] {
+		backend.chartTimelines.timelines[code] = timeline
+		store = !unchanged
+	}
+	unlock()
+	// The timeline is immutable, so it is written to disk without holding the lock.
+	if store {
+		if err := backend.storeChartTimeline(code, timeline); err != nil {
+			log.WithError(err).Error("could not persist the chart timeline")
+		}
+	}
+	return timeline, false, nil
+}
+
+// requestChartTimelinesUpdate runs `updateChartTimelines()` in the background. Requests made while
+// an update is waiting to run are coalesced into it, so at most one update runs and one waits.
+func (backend *Backend) requestChartTimelinesUpdate() {
+	if backend.chartTimelinesUpdatePending.Swap(true) {
+		return
+	}
+	go func() {
+		defer backend.chartTimelinesUpdateLock.Lock()()
+		backend.chartTimelinesUpdatePending.Store(false)
+		backend.updateChartTimelines()
+	}()
+}
+
+// updateChartTimelines extends the cached chart timelines of all synced accounts, so that serving
+// the chart after new exchange rates arrive only needs to merge them.
+func (backend *Backend) updateChartTimelines() {
+	fiat := backend.Config().AppConfig().Backend.MainFiat
+	until := backend.RatesUpdater().HistoryLatestTimestampAll(backend.allCoinCodes(), fiat)
+	if until.IsZero() {
+		return
+	}
+	hourlyFrom := chartHourlyFrom()
+	for _, account := range backend.Accounts() {
+		if account.Config().Config.Inactive || account.FatalError() || !account.Synced() {
+			continue
+		}
+		generation := backend.chartTimelineGeneration(account.Config().Config.Code)
+		txs, err := account.Transactions()
+		if err != nil {
+			backend.log.WithError(err).Error("could not get transactions to update the chart")
+			continue
+		}
+		if _, _, err := backend.chartTimeline(account, txs, generation, fiat, until, hourlyFrom); err != nil {
+			backend.log.WithError(err).Error("could not update the chart")
+		}
+	}
+}
+
+// addChartTimeline adds the points of a timeline to the chart entries.
+func addChartTimeline(points []chartPoint, chartEntries map[int64]RatChartEntry) {
+	for _, point := range points {
+		timestamp := point.time.Unix()
+		chartEntry := chartEntries[timestamp]
+		chartEntry.Time = timestamp
+		if chartEntry.RatValue == nil {
+			chartEntry.RatValue = new(big.Rat).Set(point.value)
+		} else {
+			chartEntry.RatValue.Add(point.value, chartEntry.RatValue)
+		}
+		chartEntries[timestamp] = chartEntry
+	}
+}
+
+// chartHourlyFrom returns the time from which the chart turns from daily points to hourly points.
+func chartHourlyFrom() time.Time {
+	return time.Now().AddDate(0, 0, -7).Truncate(24 * time.Hour)
+}
@@ -0,0 +1,261 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	coinMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
+	"github.com/stretchr/testify/require"
+)
+
+// chartTestBackend returns a backend with mocked rates from `from` until now, and btc accounts
+// with a receive transaction every `txInterval` starting at `from`.
+func chartTestBackend(
+	t testing.TB, numAccounts int, from time.Time, txInterval time.Duration) *Backend {
+	t.Helper()
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	b.ratesUpdater.Stop()
+	b.ratesUpdater = rates.MockRateUpdater(from, time.Now())
+
+	btc := &coinMocks.CoinMock{
+		CodeFunc:     func() coinpkg.Code { return coinpkg.CodeBTC },
+		UnitFunc:     func(bool) string { return "BTC" },
+		DecimalsFunc: func(bool) uint { return 8 },
+	}
+	b.accounts = AccountsList{}
+	for i := 0; i < numAccounts; i++ {
+		txs := []*accounts.TransactionData{}
+		height := 1
+		for txTime := from; txTime.Before(time.Now()); txTime = txTime.Add(txInterval) {
+			txTime := txTime
+			txs = append(txs, &accounts.TransactionData{
+				InternalID: fmt.Sprintf("tx-%d", height),
+				Type:       accounts.TxTypeReceive,
+				Height:     height,
+				Timestamp:  &txTime,
+				Amount:     coinpkg.NewAmountFromInt64(1000000),
+			})
+			height++
+		}
+		orderedTxs := accounts.NewOrderedTransactions(txs)
+		balance := accounts.NewBalance(orderedTxs[0].Balance, coinpkg.NewAmountFromInt64(0))
+		cfg := &accounts.AccountConfig{
+			Config: &config.Account{Code: accountsTypes.Code(fmt.Sprintf("btc-%d", i))},
+		}
+		b.accounts = append(b.accounts, &accountsMocks.InterfaceMock{
+			ConfigFunc:       func() *accounts.AccountConfig { return cfg },
+			CoinFunc:         func() coinpkg.Coin { return btc },
+			FatalErrorFunc:   func() bool { return false },
+			InitializeFunc:   func() error { return nil },
+			SyncedFunc:       func() bool { return true },
+			BalanceFunc:      func() (*accounts.Balance, error) { return balance, nil },
+			TransactionsFunc: func() (accounts.OrderedTransactions, error) { return orderedTxs, nil },
+			CloseFunc:        func() {},
+		})
+	}
+	return b
+}
+
+func TestChartTimeline(t *testing.T) {
+	from := time.Now().AddDate(0, 0, -30).Truncate(24 * time.Hour)
+	b := chartTestBackend(t, 2, from, 24*time.Hour)
+	defer b.Close()
+
+	account := b.accounts[0]
+	code := account.Config().Config.Code
+	txs, err := account.Transactions()
+	require.NoError(t, err)
+	until := b.RatesUpdater().HistoryLatestTimestamp("btc", "USD")
+	hourlyFrom := chartHourlyFrom()
+
+	// Computed up to one day earlier, then extended.
+	generation := b.chartTimelineGeneration(code)
+	timeline, dataMissing, err := b.chartTimeline(
+		account, txs, generation, "USD", until.Add(-24*time.Hour), hourlyFrom)
+	require.NoError(t, err)
+	require.False(t, dataMissing)
+	require.Equal(t, from, timeline.daily[0].time)
+	require.Equal(t, timeline, b.chartTimelines.timelines[code])
+
+	extended, dataMissing, err := b.chartTimeline(account, txs, generation, "USD", until, hourlyFrom)
+	require.NoError(t, err)
+	require.False(t, dataMissing)
+	require.Len(t, extended.daily, len(timeline.daily)+1)
+	require.Len(t, extended.hourly, len(timeline.hourly)+24)
+	// The existing points are reused.
+	require.Same(t, timeline.daily[0].value, extended.daily[0].value)
+	require.Same(t, timeline.hourly[0].value, extended.hourly[0].value)
+	// 0.01 BTC received each day at 20000 USD.
+	last := extended.daily[len(extended.daily)-1]
+	require.Equal(t, "6200", last.value.FloatString(0))
+
+	// The full computation yields the same points.
+	b.invalidateChartTimelines()
+	full, _, err := b.chartTimeline(account, txs, b.chartTimelineGeneration(code), "USD", until, hourlyFrom)
+	require.NoError(t, err)
+	require.Equal(t, extended, full)
+
+	// An EventSyncDone caused by new rates keeps the timeline.
+	b.onChartAccountEvent(code, accountsTypes.EventSyncDone)
+	require.NotNil(t, b.chartTimelines.timelines[code])
+
+	// A sync invalidates the timeline, and it is not cached while syncing.
+	generation = b.chartTimelineGeneration(code)
+	b.onChartAccountEvent(code, accountsTypes.EventSyncStarted)
+	require.Nil(t, b.chartTimelines.timelines[code])
+	_, _, err = b.chartTimeline(account, txs, b.chartTimelineGeneration(code), "USD", until, hourlyFrom)
+	require.NoError(t, err)
+	require.Nil(t, b.chartTimelines.timelines[code])
+	b.onChartAccountEvent(code, accountsTypes.EventSyncDone)
+	// Transactions retrieved before the sync finished are not cached.
+	_, _, err = b.chartTimeline(account, txs, generation, "USD", until, hourlyFrom)
+	require.NoError(t, err)
+	require.Nil(t, b.chartTimelines.timelines[code])
+
+	// Another fiat is not available.
+	_, dataMissing, err = b.chartTimeline(account, txs, b.chartTimelineGeneration(code), "EUR", until, hourlyFrom)
+	require.NoError(t, err)
+	require.True(t, dataMissing)
+
+	b.updateChartTimelines()
+	require.Len(t, b.chartTimelines.timelines, 2)
+
+	chart, err := b.ChartData()
+	require.NoError(t, err)
+	require.False(t, chart.DataMissing)
+	require.Equal(t, 12400.0, chart.DataDaily[len(chart.DataDaily)-2].Value)
+	require.Equal(t, 12400.0, *chart.Total)
+
+	b.removeChartTimeline(code)
+	require.Len(t, b.chartTimelines.timelines, 1)
+}
+
+func TestChartTimelinePersisted(t *testing.T) {
+	from := time.Now().AddDate(0, 0, -30).Truncate(24 * time.Hour)
+	b := chartTestBackend(t, 1, from, 24*time.Hour)
+	defer b.Close()
+
+	account := b.accounts[0]
+	code := account.Config().Config.Code
+	txs, err := account.Transactions()
+	require.NoError(t, err)
+	until := b.RatesUpdater().HistoryLatestTimestamp("btc", "USD")
+	hourlyFrom := chartHourlyFrom()
+
+	timeline, _, err := b.chartTimeline(
+		account, txs, b.chartTimelineGeneration(code), "USD", until.Add(-48*time.Hour), hourlyFrom)
+	require.NoError(t, err)
+	persisted := b.loadChartTimeline(code, "USD")
+	require.Equal(t, timeline.daily[len(timeline.daily)-1].value.RatString(),
+		persisted.daily[len(persisted.daily)-1].value.RatString())
+	require.True(t, persisted.valid(txs, "USD", timeline.earliestPriceAvailable))
+	require.Nil(t, b.loadChartTimeline(code, "EUR"))
+
+	// A sync removes the timeline from memory, the persisted timeline is extended afterwards.
+	b.onChartAccountEvent(code, accountsTypes.EventSyncStarted)
+	b.onChartAccountEvent(code, accountsTypes.EventSyncDone)
+	extended, _, err := b.chartTimeline(account, txs, b.chartTimelineGeneration(code), "USD", until, hourlyFrom)
+	require.NoError(t, err)
+	require.Len(t, extended.daily, len(timeline.daily)+2)
+	require.Len(t, b.loadChartTimeline(code, "USD").daily, len(extended.daily))
+
+	// The persisted timeline is not used if the transactions before its end changed. Newer
+	// transactions don't matter.
+	require.True(t, persisted.valid(txs[1:], "USD", timeline.earliestPriceAvailable))
+	require.False(t, persisted.valid(txs[:len(txs)-1], "USD", timeline.earliestPriceAvailable))
+	require.False(t, persisted.valid(txs[3:], "USD", timeline.earliestPriceAvailable))
+	require.False(t, persisted.valid(txs, "USD", timeline.earliestPriceAvailable.Add(-time.Hour)))
+
+	b.invalidateChartTimelines()
+	require.Nil(t, b.loadChartTimeline(code, "USD"))
+	full, _, err := b.chartTimeline(account, txs, b.chartTimelineGeneration(code), "USD", until, hourlyFrom)
+	require.NoError(t, err)
+	// Compared as JSON, as the persisted times have a different location.
+	extendedJSON, err := json.Marshal(extended)
+	require.NoError(t, err)
+	fullJSON, err := json.Marshal(full)
+	require.NoError(t, err)
+	require.JSONEq(t, string(fullJSON), string(extendedJSON))
+
+	// The persisted timelines are kept as long as the rates providers don't change.
+	b.checkChartTimelinesProviders()
+	require.NotNil(t, b.loadChartTimeline(code, "USD"))
+	appConfig := b.config.AppConfig()
+	appConfig.Backend.RatesProviders = []rates.ProviderConfig{{Type: rates.ProviderKraken}}
+	require.NoError(t, b.config.SetAppConfig(appConfig))
+	b.checkChartTimelinesProviders()
+	require.Nil(t, b.loadChartTimeline(code, "USD"))
+	require.Nil(t, b.chartTimelines.timelines[code])
+	b.checkChartTimelinesProviders()
+	_, _, err = b.chartTimeline(account, txs, b.chartTimelineGeneration(code), "USD", until, hourlyFrom)
+	require.NoError(t, err)
+	b.checkChartTimelinesProviders()
+	require.NotNil(t, b.loadChartTimeline(code, "USD"))
+}
+
+func TestRequestChartTimelinesUpdate(t *testing.T) {
+	from := time.Now().AddDate(0, 0, -30).Truncate(24 * time.Hour)
+	b := chartTestBackend(t, 2, from, 24*time.Hour)
+	defer b.Close()
+
+	// Requests while an update is running are coalesced into one more update.
+	unlock := b.chartTimelinesUpdateLock.Lock()
+	for i := 0; i < 10; i++ {
+		b.requestChartTimelinesUpdate()
+	}
+	require.True(t, b.chartTimelinesUpdatePending.Load())
+	unlock()
+	require.Eventually(t, func() bool {
+		defer b.chartTimelinesLock.RLock()()
+		return len(b.chartTimelines.timelines) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.False(t, b.chartTimelinesUpdatePending.Load())
+}
+
+func benchmarkChartData(b *testing.B, cached bool) {
+	from := time.Now().AddDate(-3, 0, 0)
+	backend := chartTestBackend(b, 20, from, 24*time.Hour)
+	defer backend.Close()
+	_, err := backend.ChartData()
+	require.NoError(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			// Rebuild everything like before the timelines were cached.
+			backend.invalidateChartTimelines()
+		}
+		if _, err := backend.ChartData(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkChartDataFull(b *testing.B) {
+	benchmarkChartData(b, false)
+}
+
+func BenchmarkChartDataCached(b *testing.B) {
+	benchmarkChartData(b, true)
+}
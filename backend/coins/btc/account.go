@@ -148,6 +148,8 @@ func NewAccount(
 		},
 		log: log,
 	}
+	// Recorded in the background, as the synchronizer can't be waited on while it finishes.
+	account.OnSyncFinished(func() { go account.recordIncomingFiatValues() })
 	return account
 }
 
@@ -684,7 +686,7 @@ func (account *Account) Transactions() (accounts.OrderedTransactions, error) {
 	if account.fatalError.Load() {
 		return nil, errp.New("can't call Transactions() after a fatal error")
 	}
-	return account.transactions.Transactions(
+	txs, err := account.transactions.Transactions(
 		func(scriptHashHex blockchain.ScriptHashHex) bool {
 			for _, subacc := range account.subaccounts {
 				if subacc.changeAddresses.LookupByScriptHashHex(scriptHashHex) != nil {
@@ -693,6 +695,23 @@ func (account *Account) Transactions() (accounts.OrderedTransactions, error) {
 			}
 			return false
 		})
+	if err != nil {
+		return nil, err
+	}
+	return account.Annotate(txs), nil
+}
+
+// recordIncomingFiatValues records the fiat values of the incoming transactions found in a sync.
+func (account *Account) recordIncomingFiatValues() {
+	if account.isClosed() {
+		return
+	}
+	txs, err := account.Transactions()
+	if err != nil {
+		account.log.WithError(err).Error("Could not record the fiat values of incoming transactions")
+		return
+	}
+	account.RecordIncomingFiatValues(txs)
 }
 
 // GetUnusedReceiveAddresses returns a number of unused addresses. Returns nil if the account is not initialized.
@@ -860,28 +879,29 @@ var defaultScriptType = "p2wpkh"
 //	`format` is the script type that should be used in the address derivation, as received by the widget
 //		(see https://github.com/pocketbitcoin/request-address#requestaddressv0messagescripttype).
 //		If format is empty, native segwit type is used as a fallback.
+//	`signatureFormat` is the requested message signature format, see `MessageSignatureFormat()`.
 //
 // Returned values:
 //
 //	#1: is the first unused address corresponding to the account and the script type identified by the input values.
 //	#2: base64 encoding of the message signature, obtained using the private key linked to the address.
 //	#3: is an optional error that could be generated during the execution of the function.
-func SignBTCAddress(account accounts.Interface, message string, format string) (string, string, error) {
+func SignBTCAddress(
+	account accounts.Interface, message string, format string, signatureFormat string,
+) (string, string, error) {
 	if !exchanges.IsPocketSupported(account) {
 		return "", "", errp.Newf("Coin not supported %s", account.Coin().Code())
 	}
+	coin, ok := account.Coin().(*Coin)
+	if !ok {
+		return "", "", errp.Newf("Coin not supported %s", account.Coin().Code())
+	}
 
 	keystore, err := account.Config().ConnectKeystore()
 	if err != nil {
 		return "", "", err
 	}
 
-	canSign := keystore.CanSignMessage(account.Coin().Code())
-	if !canSign {
-		return "", "", errp.Newf("The connected device or keystore cannot sign messages for %s",
-			account.Coin().Code())
-	}
-
 	unused := account.GetUnusedReceiveAddresses()
 	// Use the format hint to get a compatible address
 	if len(format) == 0 {
@@ -897,13 +917,20 @@ func SignBTCAddress(account accounts.Interface, message string, format string) (
 		err := fmt.Errorf("Unknown format: %s", format)
 		return "", "", err
 	}
-	addr := unused[signingConfigIdx].Addresses[0]
+	addr, ok := unused[signingConfigIdx].Addresses[0].(*addresses.AccountAddress)
+	if !ok {
+		return "", "", errp.New("Unexpected address type")
+	}
 
-	sig, err := keystore.SignBTCMessage(
-		[]byte(message),
-		addr.AbsoluteKeypath(),
-		account.Config().Config.SigningConfigurations[signingConfigIdx].ScriptType(),
-	)
+	sigFormat, err := MessageSignatureFormat(signatureFormat, expectedScriptType)
+	if err != nil {
+		return "", "", err
+	}
+	if !CanSignMessageFormat(keystore, coin, sigFormat) {
+		return "", "", errp.Newf("The connected device or keystore cannot sign messages for %s",
+			account.Coin().Code())
+	}
+	sig, err := SignMessage(keystore, coin, addr, []byte(message), sigFormat)
 	if err != nil {
 		return "", "", err
 	}
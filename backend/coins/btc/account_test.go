@@ -20,20 +20,35 @@ import (
 	"math/big"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/btcutil/txsort"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
 	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/silentpayments"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	keystoremock "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/software"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/socksproxy"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,13 +60,14 @@ func TestAccount(t *testing.T) {
 	dbFolder := test.TstTempDir("btc-dbfolder")
 	defer func() { _ = os.RemoveAll(dbFolder) }()
 
-	coin := btc.NewCoin(
+	btcCoin := btc.NewCoin(
 		code, "Bitcoin Testnet", unit, coin.BtcUnitDefault, net, dbFolder, nil, explorer, socksproxy.NewSocksProxy(false, ""))
 
 	blockchainMock := &blockchainMock.BlockchainMock{}
 	blockchainMock.MockRegisterOnConnectionErrorChangedEvent = func(f func(error)) {}
+	blockchainMock.MockRelayFee = func() (btcutil.Amount, error) { return 1000, nil }
 
-	coin.TstSetMakeBlockchain(func() blockchain.Interface { return blockchainMock })
+	btcCoin.TstSetMakeBlockchain(func() blockchain.Interface { return blockchainMock })
 
 	keypath, err := signing.NewAbsoluteKeypath("m/49'/1'/0'")
 	require.NoError(t, err)
@@ -66,6 +82,7 @@ func TestAccount(t *testing.T) {
 		keypath,
 		xpub)}
 
+	keystoreConnected := true
 	account := btc.NewAccount(
 		&accounts.AccountConfig{
 			Config: &config.Account{
@@ -78,8 +95,16 @@ func TestAccount(t *testing.T) {
 			RateUpdater:     nil,
 			GetNotifier:     func(signing.Configurations) accounts.Notifier { return nil },
 			GetSaveFilename: func(suggestedFilename string) string { return suggestedFilename },
+			ConnectedKeystore: func() keystore.Keystore {
+				if !keystoreConnected {
+					return nil
+				}
+				return &keystoremock.KeystoreMock{
+					SupportsSilentPaymentsFunc: func(coin.Coin) bool { return false },
+				}
+			},
 		},
-		coin, nil,
+		btcCoin, nil,
 		logging.Get().WithGroup("account_test"),
 	)
 	require.False(t, account.Synced())
@@ -96,6 +121,26 @@ func TestAccount(t *testing.T) {
 	require.Equal(t, accounts.OrderedTransactions{}, transactions)
 
 	require.Equal(t, []*btc.SpendableOutput{}, account.SpendableOutputs())
+
+	// Rejected in the proposal already if the keystore can't sign it.
+	privateKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	silentPaymentAddress, err := silentpayments.NewAddress(
+		privateKey.PubKey(), privateKey.PubKey(), net)
+	require.NoError(t, err)
+	args := &accounts.TxProposalArgs{
+		RecipientAddress: silentPaymentAddress.EncodeAddress(),
+		Amount:           coin.NewSendAmount("0.001"),
+		FeeTargetCode:    accounts.FeeTargetCodeCustom,
+		CustomFee:        "1",
+	}
+	_, _, _, err = account.TxProposal(args)
+	require.Equal(t, errors.ErrSilentPaymentsNotSupported, errp.Cause(err))
+
+	// Without a connected keystore, it is only checked when signing.
+	keystoreConnected = false
+	_, _, _, err = account.TxProposal(args)
+	require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
 }
 
 func TestInsuredAccountAddresses(t *testing.T) {
@@ -193,3 +238,104 @@ func TestInsuredAccountAddresses(t *testing.T) {
 	require.Equal(t, *account2.GetUnusedReceiveAddresses()[0].ScriptType, signing.ScriptTypeP2WPKH)
 
 }
+
+func TestProofOfReserves(t *testing.T) {
+	code := coin.CodeTBTC
+	unit := "TBTC"
+	net := &chaincfg.TestNet3Params
+
+	dbFolder := test.TstTempDir("btc-dbfolder")
+	defer func() { _ = os.RemoveAll(dbFolder) }()
+
+	btcCoin := btc.NewCoin(
+		code, "Bitcoin Testnet", unit, coin.BtcUnitDefault, net, dbFolder, nil, explorer, socksproxy.NewSocksProxy(false, ""))
+
+	master, err := hdkeychain.NewMaster(make([]byte, 32), net)
+	require.NoError(t, err)
+	keypath, err := signing.NewAbsoluteKeypath("m/84'/1'/0'")
+	require.NoError(t, err)
+	xprv, err := keypath.Derive(master)
+	require.NoError(t, err)
+	xpub, err := xprv.Neuter()
+	require.NoError(t, err)
+	configuration := signing.NewBitcoinConfiguration(
+		signing.ScriptTypeP2WPKH, []byte{1, 2, 3, 4}, keypath, xpub)
+
+	// Fund the first receive addresses of the account with one confirmed tx.
+	const numOutputs = 3
+	fundingTx := wire.NewMsgTx(wire.TxVersion)
+	fundingTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	for index := uint32(0); index < numOutputs; index++ {
+		address := addresses.NewAccountAddress(configuration,
+			signing.NewEmptyRelativeKeypath().Child(0, false).Child(index, false),
+			net, logging.Get().WithGroup("account_test"))
+		fundingTx.AddTxOut(wire.NewTxOut(100000000, address.PubkeyScript()))
+	}
+	history := map[blockchain.ScriptHashHex]blockchain.TxHistory{}
+	for _, txOut := range fundingTx.TxOut {
+		history[blockchain.NewScriptHashHex(txOut.PkScript)] = blockchain.TxHistory{
+			{Height: 10, TXHash: blockchain.TXHash(fundingTx.TxHash())},
+		}
+	}
+
+	blockchainMock := &blockchainMock.BlockchainMock{
+		MockScriptHashSubscribe: func(
+			setupAndTeardown func() func(), scriptHash blockchain.ScriptHashHex, success func(string)) {
+			defer setupAndTeardown()()
+			success(history[scriptHash].Status())
+		},
+		MockScriptHashGetHistory: func(scriptHash blockchain.ScriptHashHex) (blockchain.TxHistory, error) {
+			return history[scriptHash], nil
+		},
+		MockTransactionGet: func(txHash chainhash.Hash) (*wire.MsgTx, error) {
+			if txHash != fundingTx.TxHash() {
+				return nil, errp.New("transaction not found")
+			}
+			return fundingTx, nil
+		},
+		MockRegisterOnConnectionErrorChangedEvent: func(f func(error)) {},
+	}
+	btcCoin.TstSetMakeBlockchain(func() blockchain.Interface { return blockchainMock })
+
+	notifierMock := &accountsMocks.Notifier{}
+	notifierMock.On("Put", mock.Anything).Return(nil)
+
+	account := btc.NewAccount(
+		&accounts.AccountConfig{
+			Config: &config.Account{
+				Code:                  "accountcode",
+				Name:                  "accountname",
+				SigningConfigurations: signing.Configurations{configuration},
+			},
+			DBFolder:        dbFolder,
+			OnEvent:         func(accountsTypes.Event) {},
+			RateUpdater:     nil,
+			GetNotifier:     func(signing.Configurations) accounts.Notifier { return notifierMock },
+			GetSaveFilename: func(suggestedFilename string) string { return suggestedFilename },
+			ConnectKeystore: func() (keystore.Keystore, error) {
+				return software.NewKeystore(master), nil
+			},
+		},
+		btcCoin, nil,
+		logging.Get().WithGroup("account_test"),
+	)
+	require.NoError(t, account.Initialize())
+	defer account.Close()
+	require.Eventually(t,
+		func() bool { return len(account.SpendableOutputs()) == numOutputs },
+		5*time.Second, 10*time.Millisecond)
+
+	message := []byte("Audit 2025-03-31")
+	proof, err := account.ProofOfReserves(message, nil)
+	require.NoError(t, err)
+	require.Len(t, proof.UnsignedTx.TxIn, numOutputs+1)
+	// The commitment input comes first, so the proof is not BIP69 ordered.
+	require.False(t, txsort.IsSorted(proof.UnsignedTx))
+
+	amount, err := btcCoin.VerifyProofOfReserves(proof, message)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(numOutputs*100000000), amount)
+
+	_, err = btcCoin.VerifyProofOfReserves(proof, []byte("another message"))
+	require.Error(t, err)
+}
@@ -0,0 +1,293 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bip322 implements generic message signing and verification according to BIP-322, see
+// https://github.com/bitcoin/bips/blob/master/bip-0322.mediawiki.
+//
+// A BIP-322 signature proves control of an address by signing a virtual transaction spending from
+// it. The legacy signature format of BIP-137 (also used by Electrum) is supported for verification
+// as well, as it is still the most common format for p2pkh and segwit v0 addresses.
+package bip322
+
+import (
+	"bytes"
+	"encoding/base64"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// Format is the format of a message signature.
+type Format string
+
+const (
+	// FormatLegacy is the 65 byte signature format of BIP-137, which is also used by Electrum. It
+	// is only defined for p2pkh, p2wpkh-p2sh and p2wpkh addresses.
+	FormatLegacy Format = "legacy"
+	// FormatSimple is the BIP-322 simple format: the witness of the to_sign transaction. It is only
+	// defined for segwit addresses.
+	FormatSimple Format = "bip322-simple"
+	// FormatFull is the BIP-322 full format: the whole to_sign transaction.
+	FormatFull Format = "bip322-full"
+)
+
+// legacyMagic is prepended to messages signed in the legacy format.
+const legacyMagic = "Bitcoin Signed Message:\n"
+
+// MessageHash returns the BIP-322 tagged hash of the message.
+func MessageHash(message []byte) []byte {
+	return chainhash.TaggedHash([]byte("BIP0322-signed-message"), message)[:]
+}
+
+// ToSpend returns the virtual to_spend transaction of BIP-322, which commits to the message and
+// has one output with the pkScript of the address to prove.
+func ToSpend(message []byte, pkScript []byte) *wire.MsgTx {
+	scriptSig, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).AddData(MessageHash(message)).Script()
+	if err != nil {
+		// Can't happen, the script has a fixed size.
+		panic(err)
+	}
+	toSpend := wire.NewMsgTx(0)
+	toSpend.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  scriptSig,
+		Sequence:         0,
+	})
+	toSpend.AddTxOut(wire.NewTxOut(0, pkScript))
+	return toSpend
+}
+
+// ToSign returns the unsigned virtual to_sign transaction of BIP-322 spending the to_spend
+// transaction. Signing its only input produces the signature.
+func ToSign(toSpend *wire.MsgTx) *wire.MsgTx {
+	toSpendHash := toSpend.TxHash()
+	toSign := wire.NewMsgTx(0)
+	toSign.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: toSpendHash, Index: 0},
+		Sequence:         0,
+	})
+	toSign.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+	return toSign
+}
+
+// PrevOutputFetcher returns the fetcher for the output spent by the to_sign transaction, as needed
+// to compute its signature hashes.
+func PrevOutputFetcher(pkScript []byte) txscript.PrevOutputFetcher {
+	return txscript.NewCannedPrevOutputFetcher(pkScript, 0)
+}
+
+// PkScript returns the pkScript of the single key address of the given script type. Only the
+// script types which can be signed with BIP-322 by this package are supported.
+func PkScript(scriptType signing.ScriptType, publicKey *btcec.PublicKey) ([]byte, error) {
+	switch scriptType {
+	case signing.ScriptTypeP2WPKH:
+		return txscript.NewScriptBuilder().
+			AddOp(txscript.OP_0).
+			AddData(btcutil.Hash160(publicKey.SerializeCompressed())).
+			Script()
+	case signing.ScriptTypeP2TR:
+		outputKey := txscript.ComputeTaprootKeyNoScript(publicKey)
+		return txscript.NewScriptBuilder().
+			AddOp(txscript.OP_1).
+			AddData(schnorr.SerializePubKey(outputKey)).
+			Script()
+	default:
+		return nil, errp.Newf("BIP-322 signing not supported for script type %s", scriptType)
+	}
+}
+
+// Encode encodes the witness of the signed to_sign transaction as a BIP-322 signature of the given
+// format. pkScript is the pkScript of the signing address and is needed for the full format.
+func Encode(format Format, message []byte, pkScript []byte, witness wire.TxWitness) ([]byte, error) {
+	var result bytes.Buffer
+	switch format {
+	case FormatSimple:
+		if err := writeWitness(&result, witness); err != nil {
+			return nil, err
+		}
+	case FormatFull:
+		toSign := ToSign(ToSpend(message, pkScript))
+		toSign.TxIn[0].Witness = witness
+		if err := toSign.Serialize(&result); err != nil {
+			return nil, errp.WithStack(err)
+		}
+	default:
+		return nil, errp.Newf("unsupported BIP-322 format %s", format)
+	}
+	return result.Bytes(), nil
+}
+
+func writeWitness(w *bytes.Buffer, witness wire.TxWitness) error {
+	if err := wire.WriteVarInt(w, 0, uint64(len(witness))); err != nil {
+		return errp.WithStack(err)
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(w, 0, item); err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func readWitness(serialized []byte) (wire.TxWitness, error) {
+	reader := bytes.NewReader(serialized)
+	count, err := wire.ReadVarInt(reader, 0)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if count > uint64(len(serialized)) {
+		return nil, errp.New("invalid witness")
+	}
+	witness := make(wire.TxWitness, count)
+	for i := range witness {
+		witness[i], err = wire.ReadVarBytes(reader, 0, txscript.MaxScriptSize, "witness item")
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+	}
+	if reader.Len() != 0 {
+		return nil, errp.New("trailing data after witness")
+	}
+	return witness, nil
+}
+
+// Verify checks that signature is a valid signature of the message by the address. The signature is
+// base64 encoded and can be in any of the formats defined in Format, which is detected
+// automatically and returned. The address does not need to belong to the wallet.
+//
+// Legacy signatures are verified leniently like Electrum does: the key recovered from a compressed
+// signature may be that of a p2pkh, p2wpkh-p2sh or p2wpkh address, independent of the header byte.
+//
+// BIP-322 signatures with additional inputs (proof of funds) are not supported.
+func Verify(address btcutil.Address, message []byte, signature string, net *chaincfg.Params) (Format, error) {
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return "", errp.Wrap(err, "signature is not base64 encoded")
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	if len(signatureBytes) == 65 && signatureBytes[0] >= 27 && signatureBytes[0] <= 42 {
+		if verifyLegacy(address, message, signatureBytes, net) == nil {
+			return FormatLegacy, nil
+		}
+	}
+	if witness, err := readWitness(signatureBytes); err == nil {
+		toSign := ToSign(ToSpend(message, pkScript))
+		toSign.TxIn[0].Witness = witness
+		if err := verifyToSign(toSign, pkScript); err != nil {
+			return "", err
+		}
+		return FormatSimple, nil
+	}
+	toSign := &wire.MsgTx{}
+	reader := bytes.NewReader(signatureBytes)
+	if err := toSign.Deserialize(reader); err != nil || reader.Len() != 0 {
+		return "", errp.New("invalid signature encoding")
+	}
+	if len(toSign.TxIn) != 1 {
+		return "", errp.New("BIP-322 proof of funds is not supported")
+	}
+	if toSign.TxIn[0].PreviousOutPoint != (wire.OutPoint{Hash: ToSpend(message, pkScript).TxHash()}) {
+		return "", errp.New("signature does not spend the to_spend transaction")
+	}
+	if len(toSign.TxOut) != 1 || toSign.TxOut[0].Value != 0 ||
+		!bytes.Equal(toSign.TxOut[0].PkScript, []byte{txscript.OP_RETURN}) {
+		return "", errp.New("to_sign transaction must have a single empty OP_RETURN output")
+	}
+	if err := verifyToSign(toSign, pkScript); err != nil {
+		return "", err
+	}
+	return FormatFull, nil
+}
+
+// verifyToSign executes the script of the signed to_sign transaction.
+func verifyToSign(toSign *wire.MsgTx, pkScript []byte) error {
+	prevOuts := PrevOutputFetcher(pkScript)
+	engine, err := txscript.NewEngine(pkScript, toSign, 0, txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(toSign, prevOuts), 0, prevOuts)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	if err := engine.Execute(); err != nil {
+		return errp.Wrap(err, "invalid signature")
+	}
+	return nil
+}
+
+// LegacyMessageHash returns the hash signed by legacy (BIP-137) message signatures.
+func LegacyMessageHash(message []byte) []byte {
+	var buf bytes.Buffer
+	// Writing to a bytes.Buffer can't fail.
+	_ = wire.WriteVarString(&buf, 0, legacyMagic)
+	_ = wire.WriteVarBytes(&buf, 0, message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+func verifyLegacy(address btcutil.Address, message []byte, signature []byte, net *chaincfg.Params) error {
+	// Headers 27-30 are for uncompressed keys, 31-34 for compressed keys. BIP-137 adds 35-38 for
+	// p2wpkh-p2sh and 39-42 for p2wpkh, both with compressed keys.
+	header := signature[0]
+	if header >= 35 {
+		header = 31 + (header-27)%4
+	}
+	compact := append([]byte{header}, signature[1:]...)
+	publicKey, compressed, err := ecdsa.RecoverCompact(compact, LegacyMessageHash(message))
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	var serializedPublicKey []byte
+	if compressed {
+		serializedPublicKey = publicKey.SerializeCompressed()
+	} else {
+		serializedPublicKey = publicKey.SerializeUncompressed()
+	}
+	pubKeyHash := btcutil.Hash160(serializedPublicKey)
+	candidates := []func() (btcutil.Address, error){
+		func() (btcutil.Address, error) { return btcutil.NewAddressPubKeyHash(pubKeyHash, net) },
+	}
+	if compressed {
+		candidates = append(candidates,
+			func() (btcutil.Address, error) { return btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, net) },
+			func() (btcutil.Address, error) {
+				redeemScript, err := txscript.NewScriptBuilder().
+					AddOp(txscript.OP_0).AddData(pubKeyHash).Script()
+				if err != nil {
+					return nil, err
+				}
+				return btcutil.NewAddressScriptHash(redeemScript, net)
+			},
+		)
+	}
+	for _, candidate := range candidates {
+		candidateAddress, err := candidate()
+		if err != nil {
+			return errp.WithStack(err)
+		}
+		if candidateAddress.EncodeAddress() == address.EncodeAddress() {
+			return nil
+		}
+	}
+	return errp.New("signature does not match the address")
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bip322_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/bip322"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors from BIP-322.
+const (
+	testWIF           = "L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k"
+	testP2WPKHAddress = "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l"
+	testP2TRAddress   = "bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3"
+)
+
+func decodeAddress(t *testing.T, address string) btcutil.Address {
+	t.Helper()
+	decoded, err := btcutil.DecodeAddress(address, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestMessageHash(t *testing.T) {
+	require.Equal(t,
+		"c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1",
+		hex.EncodeToString(bip322.MessageHash([]byte(""))))
+	require.Equal(t,
+		"f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a",
+		hex.EncodeToString(bip322.MessageHash([]byte("Hello World"))))
+}
+
+func TestTransactions(t *testing.T) {
+	pkScript, err := txscript.PayToAddrScript(decodeAddress(t, testP2WPKHAddress))
+	require.NoError(t, err)
+
+	toSpend := bip322.ToSpend([]byte(""), pkScript)
+	require.Equal(t,
+		"c5680aa69bb8d860bf82d4e9cd3504b55dde018de765a91bb566283c545a99a7",
+		toSpend.TxHash().String())
+	require.Equal(t,
+		"1e9654e951a5ba44c8604c4de6c67fd78a27e81dcadcfe1edf638ba3aaebaed6",
+		bip322.ToSign(toSpend).TxHash().String())
+
+	toSpend = bip322.ToSpend([]byte("Hello World"), pkScript)
+	require.Equal(t,
+		"b79d196740ad5217771c1098fc4a4b51e0535c32236c71f1ea4d61a2d603352b",
+		toSpend.TxHash().String())
+	require.Equal(t,
+		"88737ae86f2077145f93cc4b153ae9a1cb8d56afa511988c149c5c8c9d93bddf",
+		bip322.ToSign(toSpend).TxHash().String())
+}
+
+func TestVerifyVectors(t *testing.T) {
+	tests := []struct {
+		address   string
+		message   string
+		signature string
+	}{
+		{
+			address:   testP2WPKHAddress,
+			message:   "",
+			signature: "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			address:   testP2WPKHAddress,
+			message:   "Hello World",
+			signature: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			address:   testP2TRAddress,
+			message:   "Hello World",
+			signature: "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ==",
+		},
+	}
+	for _, test := range tests {
+		format, err := bip322.Verify(
+			decodeAddress(t, test.address), []byte(test.message), test.signature,
+			&chaincfg.MainNetParams)
+		require.NoError(t, err)
+		require.Equal(t, bip322.FormatSimple, format)
+
+		// Wrong message.
+		_, err = bip322.Verify(
+			decodeAddress(t, test.address), []byte(test.message+"!"), test.signature,
+			&chaincfg.MainNetParams)
+		require.Error(t, err)
+	}
+
+	// Signature of another address.
+	_, err := bip322.Verify(
+		decodeAddress(t, "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"), []byte("Hello World"),
+		tests[1].signature, &chaincfg.MainNetParams)
+	require.Error(t, err)
+
+	_, err = bip322.Verify(
+		decodeAddress(t, testP2WPKHAddress), []byte(""), "not base64!", &chaincfg.MainNetParams)
+	require.Error(t, err)
+}
+
+func TestSignVerify(t *testing.T) {
+	wif, err := btcutil.DecodeWIF(testWIF)
+	require.NoError(t, err)
+	privateKey := wif.PrivKey
+
+	for _, test := range []struct {
+		scriptType signing.ScriptType
+		address    string
+	}{
+		{signing.ScriptTypeP2WPKH, testP2WPKHAddress},
+		{signing.ScriptTypeP2TR, testP2TRAddress},
+	} {
+		pkScript, err := bip322.PkScript(test.scriptType, privateKey.PubKey())
+		require.NoError(t, err)
+		address := decodeAddress(t, test.address)
+		expectedPkScript, err := txscript.PayToAddrScript(address)
+		require.NoError(t, err)
+		require.Equal(t, expectedPkScript, pkScript)
+
+		message := []byte("Hello World")
+		toSign := bip322.ToSign(bip322.ToSpend(message, pkScript))
+		sigHashes := txscript.NewTxSigHashes(toSign, bip322.PrevOutputFetcher(pkScript))
+		var witness [][]byte
+		if test.scriptType == signing.ScriptTypeP2TR {
+			witness, err = txscript.TaprootWitnessSignature(
+				toSign, sigHashes, 0, 0, pkScript, txscript.SigHashDefault, privateKey)
+		} else {
+			witness, err = txscript.WitnessSignature(
+				toSign, sigHashes, 0, 0, pkScript, txscript.SigHashAll, privateKey, true)
+		}
+		require.NoError(t, err)
+
+		for _, format := range []bip322.Format{bip322.FormatSimple, bip322.FormatFull} {
+			signature, err := bip322.Encode(format, message, pkScript, witness)
+			require.NoError(t, err)
+			verifiedFormat, err := bip322.Verify(address, message,
+				base64.StdEncoding.EncodeToString(signature), &chaincfg.MainNetParams)
+			require.NoError(t, err)
+			require.Equal(t, format, verifiedFormat)
+		}
+		_, err = bip322.Encode(bip322.FormatLegacy, message, pkScript, witness)
+		require.Error(t, err)
+	}
+
+	_, err = bip322.PkScript(signing.ScriptTypeP2PKH, privateKey.PubKey())
+	require.Error(t, err)
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	wif, err := btcutil.DecodeWIF(testWIF)
+	require.NoError(t, err)
+	message := []byte("Hello World")
+	signature, err := ecdsa.SignCompact(wif.PrivKey, bip322.LegacyMessageHash(message), true)
+	require.NoError(t, err)
+
+	pubKeyHash := btcutil.Hash160(wif.PrivKey.PubKey().SerializeCompressed())
+	p2pkh, err := btcutil.NewAddressPubKeyHash(pubKeyHash, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	recID := signature[0] - 31
+	for _, test := range []struct {
+		address btcutil.Address
+		header  byte
+	}{
+		{p2pkh, 31 + recID},
+		// Electrum uses the p2pkh header for segwit addresses.
+		{decodeAddress(t, testP2WPKHAddress), 31 + recID},
+		// BIP-137 header for p2wpkh.
+		{decodeAddress(t, testP2WPKHAddress), 39 + recID},
+	} {
+		signature[0] = test.header
+		format, err := bip322.Verify(test.address, message,
+			base64.StdEncoding.EncodeToString(signature), &chaincfg.MainNetParams)
+		require.NoError(t, err)
+		require.Equal(t, bip322.FormatLegacy, format)
+	}
+
+	// Legacy signatures are not defined for taproot.
+	_, err = bip322.Verify(decodeAddress(t, testP2TRAddress), message,
+		base64.StdEncoding.EncodeToString(signature), &chaincfg.MainNetParams)
+	require.Error(t, err)
+}
@@ -18,6 +18,7 @@ package btc
 import (
 	"fmt"
 	"math/big"
+	"net/http"
 	"os"
 	"path"
 	"sync"
@@ -29,6 +30,7 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/db/headersdb"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/electrum"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/headers"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/silentpayments"
 	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
@@ -52,6 +54,7 @@ type Coin struct {
 	dbFolder              string
 	makeBlockchain        func() blockchain.Interface
 	blockExplorerTxPrefix string
+	socksProxy            socksproxy.SocksProxy
 
 	observable.Implementation
 
@@ -82,6 +85,7 @@ func NewCoin(
 		net:                   net,
 		dbFolder:              dbFolder,
 		blockExplorerTxPrefix: blockExplorerTxPrefix,
+		socksProxy:            socksProxy,
 		makeBlockchain: func() blockchain.Interface {
 			return electrum.NewElectrumConnection(
 				servers,
@@ -94,6 +98,12 @@ func NewCoin(
 	return coin
 }
 
+// httpClient returns the client used to contact third party HTTP services, e.g. payjoin receivers.
+// It goes through the socks proxy if one is configured.
+func (coin *Coin) httpClient() (*http.Client, error) {
+	return coin.socksProxy.GetHTTPClient()
+}
+
 // TstSetMakeBlockchain must only be used in unit tests to provide a mock instance for the
 // blockchain interface.
 func (coin *Coin) TstSetMakeBlockchain(f func() blockchain.Interface) {
@@ -173,7 +183,7 @@ func (coin *Coin) GetFormatUnit(bool) string {
 		switch coin.code {
 		case coinpkg.CodeBTC:
 			return "sat"
-		case coinpkg.CodeTBTC:
+		case coinpkg.CodeTBTC, coinpkg.CodeSBTC, coinpkg.CodeTBTC4:
 			return "tsat"
 		}
 	}
@@ -261,7 +271,7 @@ func (coin *Coin) DecodeAddress(address string) (btcutil.Address, error) {
 	}
 	if _, ok := btcAddress.(*btcutil.AddressTaproot); ok {
 		switch coin.code {
-		case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeRBTC:
+		case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeSBTC, coinpkg.CodeTBTC4, coinpkg.CodeRBTC:
 			// Taproot activated on Bitcoin.
 		default:
 			// Taproot not activated on other coins.
@@ -271,6 +281,22 @@ func (coin *Coin) DecodeAddress(address string) (btcutil.Address, error) {
 	return btcAddress, nil
 }
 
+// DecodeSilentPaymentAddress decodes a BIP-352 silent payment address, checking that the format
+// matches the coin's network. Silent payments are only available on Bitcoin.
+func (coin *Coin) DecodeSilentPaymentAddress(address string) (*silentpayments.Address, error) {
+	switch coin.code {
+	case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeSBTC, coinpkg.CodeTBTC4, coinpkg.CodeRBTC:
+	default:
+		return nil, errp.WithStack(errors.ErrInvalidAddress)
+	}
+	silentPaymentAddress, err := silentpayments.DecodeAddress(address, coin.Net())
+	if err != nil {
+		coin.log.WithError(err).Info("Invalid silent payment address")
+		return nil, errp.WithStack(errors.ErrInvalidAddress)
+	}
+	return silentPaymentAddress, nil
+}
+
 // Close implements coinpkg.Coin.
 func (coin *Coin) Close() error {
 	coin.log.Info("closing coin")
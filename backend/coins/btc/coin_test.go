@@ -209,3 +209,14 @@ func (s *testSuite) TestDecodeAddress() {
 	}
 
 }
+
+func (s *testSuite) TestVerifyMessageUnsupportedCoin() {
+	_, err := s.coin.VerifyMessage("address", []byte("message"), "signature")
+	s.Require().Error(err)
+	switch s.code {
+	case coin.CodeLTC, coin.CodeTLTC:
+		s.Require().Contains(err.Error(), "not supported")
+	default:
+		s.Require().Equal(errors.ErrInvalidAddress, errp.Cause(err))
+	}
+}
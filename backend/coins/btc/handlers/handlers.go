@@ -17,15 +17,21 @@ package handlers
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/big"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
@@ -35,8 +41,11 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/util"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/etherscan"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/export"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/config"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
@@ -71,6 +80,9 @@ func NewHandlers(
 	handleFunc("/verify-address", handlers.ensureAccountInitialized(handlers.postVerifyAddress)).Methods("POST")
 	handleFunc("/verify-extended-public-key", handlers.ensureAccountInitialized(handlers.postVerifyExtendedPublicKey)).Methods("POST")
 	handleFunc("/sign-address", handlers.ensureAccountInitialized(handlers.postSignBTCAddress)).Methods("POST")
+	handleFunc("/verify-message", handlers.ensureAccountInitialized(handlers.postVerifyBTCMessage)).Methods("POST")
+	handleFunc("/proof-of-reserves", handlers.ensureAccountInitialized(handlers.postProofOfReserves)).Methods("POST")
+	handleFunc("/verify-proof-of-reserves", handlers.ensureAccountInitialized(handlers.postVerifyProofOfReserves)).Methods("POST")
 	handleFunc("/has-secure-output", handlers.ensureAccountInitialized(handlers.getHasSecureOutput)).Methods("GET")
 	handleFunc("/propose-tx-note", handlers.ensureAccountInitialized(handlers.postProposeTxNote)).Methods("POST")
 	handleFunc("/notes/tx", handlers.ensureAccountInitialized(handlers.postSetTxNote)).Methods("POST")
@@ -78,6 +90,11 @@ func NewHandlers(
 	handleFunc("/eth-sign-msg", handlers.ensureAccountInitialized(handlers.postEthSignMsg)).Methods("POST")
 	handleFunc("/eth-sign-typed-msg", handlers.ensureAccountInitialized(handlers.postEthSignTypedMsg)).Methods("POST")
 	handleFunc("/eth-sign-wallet-connect-tx", handlers.ensureAccountInitialized(handlers.postEthSignWalletConnectTx)).Methods("POST")
+	handleFunc("/eth-describe-typed-msg", handlers.ensureAccountInitialized(handlers.postEthDescribeTypedMsg)).Methods("POST")
+	handleFunc("/eth-describe-wallet-connect-tx", handlers.ensureAccountInitialized(handlers.postEthDescribeWalletConnectTx)).Methods("POST")
+	handleFunc("/eth-replace-tx-proposal", handlers.ensureAccountInitialized(handlers.postEthReplaceTxProposal)).Methods("POST")
+	handleFunc("/eth-erc20-allowances", handlers.ensureAccountInitialized(handlers.getEthERC20Allowances)).Methods("GET")
+	handleFunc("/eth-approve-tx-proposal", handlers.ensureAccountInitialized(handlers.postEthApproveTxProposal)).Methods("POST")
 	return handlers
 }
 
@@ -114,19 +131,36 @@ func (handlers *Handlers) formatAmountAsJSON(amount coin.Amount, isFee bool) For
 	}
 }
 
-func (handlers *Handlers) formatAmountAtTimeAsJSON(amount coin.Amount, timeStamp *time.Time) *FormattedAmount {
+// formatAmountAtTimeAsJSON formats the amount with its fiat values at the given time. The fiat
+// values recorded when the transaction was sent or first seen are preferred over historical
+// exchange rates. The time can be nil if the fiat values are recorded.
+func (handlers *Handlers) formatAmountAtTimeAsJSON(
+	amount coin.Amount, timeStamp *time.Time, fiatValues map[string]string) *FormattedAmount {
 	accountCoin := handlers.account.Coin()
-	return &FormattedAmount{
-		Amount: accountCoin.FormatAmount(amount, false),
-		Unit:   accountCoin.GetFormatUnit(false),
-		Conversions: coin.ConversionsAtTime(
+	formatBtcAsSat := util.FormatBtcAsSat(handlers.account.Config().BtcCurrencyUnit)
+	conversions := map[string]string{}
+	if timeStamp != nil {
+		conversions = coin.ConversionsAtTime(
 			amount,
-			handlers.account.Coin(),
+			accountCoin,
 			false,
 			handlers.account.Config().RateUpdater,
-			util.FormatBtcAsSat(handlers.account.Config().BtcCurrencyUnit),
+			formatBtcAsSat,
 			timeStamp,
-		),
+		)
+	}
+	for fiat, value := range fiatValues {
+		rat, ok := new(big.Rat).SetString(value)
+		if !ok {
+			handlers.log.Errorf("Invalid recorded fiat value %q", value)
+			continue
+		}
+		conversions[fiat] = coin.FormatAsCurrency(rat, fiat == rates.BTC.String(), formatBtcAsSat)
+	}
+	return &FormattedAmount{
+		Amount:      accountCoin.FormatAmount(amount, false),
+		Unit:        accountCoin.GetFormatUnit(false),
+		Conversions: conversions,
 	}
 }
 
@@ -147,7 +181,14 @@ type Transaction struct {
 	Fee                      FormattedAmount   `json:"fee"`
 	Time                     *string           `json:"time"`
 	Addresses                []string          `json:"addresses"`
-	Note                     string            `json:"note"`
+	// ContactNames maps addresses to the names of their address book contacts.
+	ContactNames map[string]string `json:"contactNames"`
+	// ENSNames maps addresses to their primary ENS names.
+	ENSNames map[string]string `json:"ensNames"`
+	Note     string            `json:"note"`
+	// InternalTransfers links the transaction to the transactions of our other accounts if it is an
+	// internal transfer.
+	InternalTransfers []InternalTransfer `json:"internalTransfers"`
 
 	// BTC specific fields.
 	VSize        int64           `json:"vsize"`
@@ -160,6 +201,13 @@ type Transaction struct {
 	Nonce *uint64 `json:"nonce"`
 }
 
+// InternalTransfer is the JSON representation of `accounts.InternalTransfer`.
+type InternalTransfer struct {
+	AccountCode types.Code      `json:"accountCode"`
+	InternalID  string          `json:"internalID"`
+	Amount      FormattedAmount `json:"amount"`
+}
+
 func (handlers *Handlers) ensureAccountInitialized(h func(*http.Request) (interface{}, error)) func(*http.Request) (interface{}, error) {
 	return func(request *http.Request) (interface{}, error) {
 		if handlers.account == nil {
@@ -178,34 +226,53 @@ func (handlers *Handlers) getTxInfoJSON(txInfo *accounts.TransactionData, detail
 	}
 	var formattedTime *string
 	var amountAtTime *FormattedAmount
+	fiatValues := handlers.account.TxFiatValues(txInfo.InternalID)
 	if txInfo.Timestamp != nil {
 		t := txInfo.Timestamp.Format(time.RFC3339)
 		formattedTime = &t
-		amountAtTime = handlers.formatAmountAtTimeAsJSON(txInfo.Amount, txInfo.Timestamp)
+		amountAtTime = handlers.formatAmountAtTimeAsJSON(txInfo.Amount, txInfo.Timestamp, fiatValues)
 	} else if txInfo.CreatedTimestamp != nil {
 		t := txInfo.CreatedTimestamp.Format(time.RFC3339)
 		formattedTime = &t
 	}
+	if amountAtTime == nil && fiatValues != nil {
+		amountAtTime = handlers.formatAmountAtTimeAsJSON(txInfo.Amount, nil, fiatValues)
+	}
 
 	addresses := []string{}
+	contactNames := map[string]string{}
+	ensNames := map[string]string{}
 	for _, addressAndAmount := range txInfo.Addresses {
 		addresses = append(addresses, addressAndAmount.Address)
+		if addressAndAmount.ContactName != "" {
+			contactNames[addressAndAmount.Address] = addressAndAmount.ContactName
+		}
+		if addressAndAmount.ENSName != "" {
+			ensNames[addressAndAmount.Address] = addressAndAmount.ENSName
+		}
+	}
+	internalTransfers := []InternalTransfer{}
+	for _, transfer := range txInfo.InternalTransfers {
+		internalTransfers = append(internalTransfers, InternalTransfer{
+			AccountCode: transfer.AccountCode,
+			InternalID:  transfer.InternalID,
+			Amount:      handlers.formatAmountAsJSON(transfer.Amount, false),
+		})
 	}
 	txInfoJSON := Transaction{
 		TxID:                     txInfo.TxID,
 		InternalID:               txInfo.InternalID,
 		NumConfirmations:         txInfo.NumConfirmations,
 		NumConfirmationsComplete: txInfo.NumConfirmationsComplete,
-		Type: map[accounts.TxType]string{
-			accounts.TxTypeReceive:  "receive",
-			accounts.TxTypeSend:     "send",
-			accounts.TxTypeSendSelf: "send_to_self",
-		}[txInfo.Type],
-		Status:    txInfo.Status,
-		Amount:    handlers.formatAmountAsJSON(txInfo.Amount, false),
-		Time:      formattedTime,
-		Addresses: addresses,
-		Note:      handlers.account.TxNote(txInfo.InternalID),
+		Type:                     txTypeNames[txInfo.Type],
+		Status:                   txInfo.Status,
+		Amount:                   handlers.formatAmountAsJSON(txInfo.Amount, false),
+		Time:                     formattedTime,
+		Addresses:                addresses,
+		ContactNames:             contactNames,
+		ENSNames:                 ensNames,
+		Note:                     handlers.account.TxNote(txInfo.InternalID),
+		InternalTransfers:        internalTransfers,
 	}
 
 	if detail {
@@ -228,21 +295,124 @@ func (handlers *Handlers) getTxInfoJSON(txInfo *accounts.TransactionData, detail
 	return txInfoJSON
 }
 
-func (handlers *Handlers) getAccountTransactions(_ *http.Request) (interface{}, error) {
+// txTypeNames are the names of the transaction types in the API.
+var txTypeNames = map[accounts.TxType]string{
+	accounts.TxTypeReceive:  "receive",
+	accounts.TxTypeSend:     "send",
+	accounts.TxTypeSendSelf: "send_to_self",
+}
+
+// TxTypeName returns the name of the transaction type in the API.
+func TxTypeName(txType accounts.TxType) string {
+	return txTypeNames[txType]
+}
+
+// TransactionsQuery holds the parsed query parameters of a transaction search.
+type TransactionsQuery struct {
+	Filter accounts.TransactionsFilter
+	Sort   accounts.TransactionsSort
+	Cursor string
+	Limit  int
+}
+
+// ParseTransactionsQuery parses the query parameters of a transaction search. All parameters are
+// optional:
+//   - start, end: RFC3339 times, see `accounts.TransactionsFilter`.
+//   - type: comma separated list of transaction types, e.g. "send,send_to_self".
+//   - status: comma separated list of transaction statuses, e.g. "pending,failed".
+//   - minAmount, maxAmount: amounts in the format unit of the coin. They can't be used if
+//     accountCoin is nil.
+//   - note, address: text to search in the note, and in the addresses and their contact names.
+//   - sort: one of the `accounts.TransactionsSort` values.
+//   - cursor, limit: pagination, see `accounts.OrderedTransactions.Search()`.
+func ParseTransactionsQuery(values url.Values, accountCoin coin.Coin) (*TransactionsQuery, error) {
+	query := &TransactionsQuery{
+		Sort:   accounts.TransactionsSort(values.Get("sort")),
+		Cursor: values.Get("cursor"),
+	}
+	filter := &query.Filter
+	for _, bound := range []struct {
+		param  string
+		target **time.Time
+	}{{"start", &filter.Start}, {"end", &filter.End}} {
+		if value := values.Get(bound.param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, errp.Wrap(err, fmt.Sprintf("Invalid %s", bound.param))
+			}
+			*bound.target = &t
+		}
+	}
+	if value := values.Get("type"); value != "" {
+	outer:
+		for _, name := range strings.Split(value, ",") {
+			for txType, txTypeName := range txTypeNames {
+				if name == txTypeName {
+					filter.Types = append(filter.Types, txType)
+					continue outer
+				}
+			}
+			return nil, errp.Newf("Invalid transaction type: %s", name)
+		}
+	}
+	if value := values.Get("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			filter.Statuses = append(filter.Statuses, accounts.TxStatus(status))
+		}
+	}
+	for _, bound := range []struct {
+		param  string
+		target **coin.Amount
+	}{{"minAmount", &filter.MinAmount}, {"maxAmount", &filter.MaxAmount}} {
+		if value := values.Get(bound.param); value != "" {
+			if accountCoin == nil {
+				return nil, errp.Newf("%s requires an account", bound.param)
+			}
+			amount, err := accountCoin.ParseAmount(value)
+			if err != nil {
+				return nil, err
+			}
+			*bound.target = &amount
+		}
+	}
+	filter.Note = values.Get("note")
+	filter.Address = values.Get("address")
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		query.Limit = limit
+	}
+	return query, nil
+}
+
+// getAccountTransactions returns the transactions of the account, optionally filtered, sorted and
+// paginated according to the query parameters documented at `ParseTransactionsQuery()`.
+func (handlers *Handlers) getAccountTransactions(r *http.Request) (interface{}, error) {
 	var result struct {
 		Success      bool          `json:"success"`
 		Transactions []Transaction `json:"list"`
+		NextCursor   string        `json:"nextCursor,omitempty"`
+		ErrorMessage string        `json:"errorMessage,omitempty"`
+	}
+	query, err := ParseTransactionsQuery(r.URL.Query(), handlers.account.Coin())
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result, nil
 	}
 	txs, err := handlers.account.Transactions()
 	if err != nil {
 		return result, nil
 	}
+	page, err := txs.Search(&query.Filter, query.Sort, query.Cursor, query.Limit, handlers.account.TxNote)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result, nil
+	}
+	result.NextCursor = page.NextCursor
 	result.Transactions = []Transaction{}
-	for _, txInfo := range txs {
-		if txInfo.IsErc20 && big.NewInt(0).Cmp(txInfo.Amount.BigInt()) == 0 {
-			// skipping 0 amount erc20 txs to mitigate Address Poisoning attack
-			continue
-		}
+	for _, txInfo := range page.Transactions {
 		result.Transactions = append(result.Transactions, handlers.getTxInfoJSON(txInfo, false))
 	}
 	result.Success = true
@@ -265,12 +435,34 @@ func (handlers *Handlers) getAccountTransaction(r *http.Request) (interface{}, e
 	return nil, nil
 }
 
-func (handlers *Handlers) postExportTransactions(_ *http.Request) (interface{}, error) {
+// postExportTransactions exports the transactions of the account to a file chosen by the user.
+// The optional JSON body selects the format (see `export.Formats()`), the fiat currency of the fiat
+// values and the time range of the transactions. Without a format, the legacy CSV format of
+// `accounts.Interface.ExportCSV()` is used.
+func (handlers *Handlers) postExportTransactions(r *http.Request) (interface{}, error) {
 	type result struct {
 		Success      bool   `json:"success"`
 		ErrorMessage string `json:"errorMessage"`
 	}
-	name := fmt.Sprintf("%s-%s-export.csv", time.Now().Format("2006-01-02-at-15-04-05"), handlers.account.Config().Config.Code)
+	var request struct {
+		Format export.Format `json:"format"`
+		Fiat   string        `json:"fiat"`
+		Start  *time.Time    `json:"start"`
+		End    *time.Time    `json:"end"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		return result{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	extension := "csv"
+	if request.Format != "" {
+		exporter, err := export.Lookup(request.Format)
+		if err != nil {
+			return result{Success: false, ErrorMessage: err.Error()}, nil
+		}
+		extension = exporter.Extension()
+	}
+	name := fmt.Sprintf("%s-%s-export.%s",
+		time.Now().Format("2006-01-02-at-15-04-05"), handlers.account.Config().Config.Code, extension)
 	downloadsDir, err := config.DownloadsDir()
 	if err != nil {
 		handlers.log.WithError(err).Error("error exporting account")
@@ -288,13 +480,30 @@ func (handlers *Handlers) postExportTransactions(_ *http.Request) (interface{},
 		handlers.log.WithError(err).Error("error exporting account")
 		return result{Success: false, ErrorMessage: err.Error()}, nil
 	}
+	filter := &accounts.TransactionsFilter{Start: request.Start, End: request.End}
 
 	file, err := os.Create(path)
 	if err != nil {
 		handlers.log.WithError(err).Error("error exporting account")
 		return result{Success: false, ErrorMessage: err.Error()}, nil
 	}
-	if err := handlers.account.ExportCSV(file, transactions); err != nil {
+	if request.Format == "" {
+		filtered := []*accounts.TransactionData{}
+		for _, tx := range transactions {
+			if filter.Match(tx, "") {
+				filtered = append(filtered, tx)
+			}
+		}
+		err = handlers.account.ExportCSV(file, filtered)
+	} else {
+		options := &export.Options{Fiat: request.Fiat}
+		if rateUpdater := handlers.account.Config().RateUpdater; rateUpdater != nil {
+			options.Price = rateUpdater.HistoricalPriceAt
+		}
+		err = export.Export(
+			file, request.Format, export.FromAccount(handlers.account, transactions, filter), options)
+	}
+	if err != nil {
 		_ = file.Close()
 		handlers.log.WithError(err).Error("error exporting account")
 		return result{Success: false, ErrorMessage: err.Error()}, nil
@@ -366,7 +575,13 @@ func (input *sendTxInput) UnmarshalJSON(jsonBytes []byte) error {
 		Amount        string   `json:"amount"`
 		SelectedUTXOS []string `json:"selectedUTXOS"`
 		Note          string   `json:"note"`
-		Counter       int      `json:"counter"`
+		PayjoinURL    string   `json:"payjoinURL"`
+		// Nonce, GasLimit and Data are optional ETH overrides. Nonce and GasLimit are decimal, Data
+		// is hex encoded.
+		Nonce    string `json:"nonce"`
+		GasLimit string `json:"gasLimit"`
+		Data     string `json:"data"`
+		Counter  int    `json:"counter"`
 	}{}
 	if err := json.Unmarshal(jsonBytes, &jsonBody); err != nil {
 		return errp.WithStack(err)
@@ -394,9 +609,42 @@ func (input *sendTxInput) UnmarshalJSON(jsonBytes []byte) error {
 		input.SelectedUTXOs[*outPoint] = struct{}{}
 	}
 	input.Note = jsonBody.Note
+	input.PayjoinURL = jsonBody.PayjoinURL
+	input.Nonce, input.GasLimit, err = parseEthTxOverrides(jsonBody.Nonce, jsonBody.GasLimit)
+	if err != nil {
+		return err
+	}
+	if jsonBody.Data != "" {
+		input.Data, err = hex.DecodeString(strings.TrimPrefix(jsonBody.Data, "0x"))
+		if err != nil {
+			return errp.WithStack(errors.ErrInvalidData)
+		}
+	}
 	return nil
 }
 
+// parseEthTxOverrides parses the optional decimal nonce and gas limit of an ETH transaction. Empty
+// values are not overridden.
+func parseEthTxOverrides(nonceString, gasLimitString string) (*uint64, uint64, error) {
+	var nonce *uint64
+	if nonceString != "" {
+		parsed, err := strconv.ParseUint(nonceString, 10, 64)
+		if err != nil {
+			return nil, 0, errp.WithStack(errors.ErrInvalidNonce)
+		}
+		nonce = &parsed
+	}
+	var gasLimit uint64
+	if gasLimitString != "" {
+		var err error
+		gasLimit, err = strconv.ParseUint(gasLimitString, 10, 64)
+		if err != nil || gasLimit == 0 {
+			return nil, 0, errp.WithStack(errors.ErrInvalidGasLimit)
+		}
+	}
+	return nonce, gasLimit, nil
+}
+
 func (handlers *Handlers) postAccountSendTx(r *http.Request) (interface{}, error) {
 	err := handlers.account.SendTx()
 	if errp.Cause(err) == keystore.ErrSigningAborted {
@@ -408,6 +656,9 @@ func (handlers *Handlers) postAccountSendTx(r *http.Request) (interface{}, error
 		if err.Error() == etherscan.ERC20GasErr {
 			result["errorCode"] = errors.ERC20InsufficientGasFunds.Error()
 		}
+		if errp.Cause(err) == errors.ErrSilentPaymentsNotSupported {
+			result["errorCode"] = errors.ErrSilentPaymentsNotSupported.Error()
+		}
 		return result, nil
 	}
 	return map[string]interface{}{"success": true}, nil
@@ -432,6 +683,104 @@ func (handlers *Handlers) postAccountTxProposal(r *http.Request) (interface{}, e
 	if err != nil {
 		return txProposalError(err)
 	}
+	result := map[string]interface{}{
+		"success": true,
+		"amount":  handlers.formatAmountAsJSON(outputAmount, false),
+		"fee":     handlers.formatAmountAsJSON(fee, true),
+		"total":   handlers.formatAmountAsJSON(total, false),
+	}
+	if ethAccount, ok := handlers.account.(*eth.Account); ok {
+		// The recipient can be an ENS name, the user must see the address it resolved to.
+		result["recipientAddress"] = ethAccount.ProposedRecipient()
+	}
+	return result, nil
+}
+
+func (handlers *Handlers) postEthReplaceTxProposal(r *http.Request) (interface{}, error) {
+	var input eth.ReplaceTxArgs
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return txProposalError(errp.New("Must be an ETH based account"))
+	}
+	outputAmount, fee, total, err := ethAccount.ReplaceTxProposal(&input)
+	if err != nil {
+		return txProposalError(err)
+	}
+	return map[string]interface{}{
+		"success": true,
+		"amount":  handlers.formatAmountAsJSON(outputAmount, false),
+		"fee":     handlers.formatAmountAsJSON(fee, true),
+		"total":   handlers.formatAmountAsJSON(total, false),
+	}, nil
+}
+
+func (handlers *Handlers) getEthERC20Allowances(_ *http.Request) (interface{}, error) {
+	type jsonAllowance struct {
+		ContractAddress string `json:"contractAddress"`
+		Name            string `json:"name"`
+		Symbol          string `json:"symbol"`
+		Spender         string `json:"spender"`
+		// Amount is in the unit of the token, nil if it could not be fetched.
+		Amount    *string `json:"amount"`
+		Unlimited bool    `json:"unlimited"`
+	}
+	type response struct {
+		Success      bool            `json:"success"`
+		Allowances   []jsonAllowance `json:"allowances,omitempty"`
+		ErrorMessage string          `json:"errorMessage,omitempty"`
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return response{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	allowances, err := ethAccount.ERC20Allowances()
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not list ERC20 allowances")
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	result := make([]jsonAllowance, len(allowances))
+	for i, allowance := range allowances {
+		result[i] = jsonAllowance{
+			ContractAddress: allowance.ContractAddress.Hex(),
+			Name:            allowance.Name,
+			Symbol:          allowance.Symbol,
+			Spender:         allowance.Spender.Hex(),
+			Unlimited:       allowance.Unlimited,
+		}
+		if allowance.Amount != nil {
+			amount := erc20.FormatAmount(allowance.Amount, uint(allowance.Decimals))
+			result[i].Amount = &amount
+		}
+	}
+	return response{Success: true, Allowances: result}, nil
+}
+
+func (handlers *Handlers) postEthApproveTxProposal(r *http.Request) (interface{}, error) {
+	var input struct {
+		eth.ApproveTxArgs
+		// Optional overrides, decimal like in the send tx input.
+		Nonce    string `json:"nonce"`
+		GasLimit string `json:"gasLimit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		return txProposalError(errp.WithStack(err))
+	}
+	var err error
+	input.ApproveTxArgs.Nonce, input.ApproveTxArgs.GasLimit, err = parseEthTxOverrides(input.Nonce, input.GasLimit)
+	if err != nil {
+		return txProposalError(err)
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return txProposalError(errp.New("Must be an ETH based account"))
+	}
+	outputAmount, fee, total, err := ethAccount.ApproveTxProposal(&input.ApproveTxArgs)
+	if err != nil {
+		return txProposalError(err)
+	}
 	return map[string]interface{}{
 		"success": true,
 		"amount":  handlers.formatAmountAsJSON(outputAmount, false),
@@ -702,6 +1051,55 @@ func (handlers *Handlers) postEthSignWalletConnectTx(r *http.Request) (interface
 	}, nil
 }
 
+type describeResponse struct {
+	Success      bool                `json:"success"`
+	Summary      *eth.SigningSummary `json:"summary,omitempty"`
+	ErrorMessage string              `json:"errorMessage,omitempty"`
+}
+
+// postEthDescribeTypedMsg interprets an EIP-712 typed message before it is signed with
+// postEthSignTypedMsg.
+func (handlers *Handlers) postEthDescribeTypedMsg(r *http.Request) (interface{}, error) {
+	var args struct {
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return describeResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return describeResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	summary, err := ethAccount.DescribeTypedMsg(args.Data)
+	if err != nil {
+		handlers.log.WithError(err).Error("Failed to describe typed data")
+		return describeResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return describeResponse{Success: true, Summary: summary}, nil
+}
+
+// postEthDescribeWalletConnectTx interprets a transaction before it is signed with
+// postEthSignWalletConnectTx.
+func (handlers *Handlers) postEthDescribeWalletConnectTx(r *http.Request) (interface{}, error) {
+	var args struct {
+		ChainId uint64                `json:"chainId"`
+		Tx      eth.WalletConnectArgs `json:"tx"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return describeResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	ethAccount, ok := handlers.account.(*eth.Account)
+	if !ok {
+		return describeResponse{Success: false, ErrorMessage: "Must be an ETH based account"}, nil
+	}
+	summary, err := ethAccount.DescribeWalletConnectTx(args.ChainId, args.Tx)
+	if err != nil {
+		handlers.log.WithError(err).Error("Failed to describe transaction")
+		return describeResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	return describeResponse{Success: true, Summary: summary}, nil
+}
+
 func (handlers *Handlers) postSignBTCAddress(r *http.Request) (interface{}, error) {
 	type response struct {
 		Success      bool   `json:"success"`
@@ -715,6 +1113,8 @@ func (handlers *Handlers) postSignBTCAddress(r *http.Request) (interface{}, erro
 		AccountCode types.Code `json:"accountCode"`
 		Msg         string     `json:"msg"`
 		Format      string     `json:"format"`
+		// SignatureFormat is optional, see `btc.MessageSignatureFormat()`.
+		SignatureFormat string `json:"signatureFormat"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		return response{Success: false, ErrorMessage: err.Error()}, nil
@@ -731,7 +1131,8 @@ func (handlers *Handlers) postSignBTCAddress(r *http.Request) (interface{}, erro
 	address, signature, err := btc.SignBTCAddress(
 		account,
 		request.Msg,
-		request.Format)
+		request.Format,
+		request.SignatureFormat)
 	if err != nil {
 		if firmware.IsErrorAbort(err) {
 			return response{Success: false, ErrorCode: errp.ErrUserAbort.Error()}, nil
@@ -745,3 +1146,156 @@ func (handlers *Handlers) postSignBTCAddress(r *http.Request) (interface{}, erro
 	}
 	return response{Success: true, Address: address, Signature: signature}, nil
 }
+
+func (handlers *Handlers) postVerifyBTCMessage(r *http.Request) (interface{}, error) {
+	type response struct {
+		Success      bool   `json:"success"`
+		Valid        bool   `json:"valid"`
+		Format       string `json:"format,omitempty"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+
+	var request struct {
+		Address   string `json:"address"`
+		Msg       string `json:"msg"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	coin, ok := handlers.account.Coin().(*btc.Coin)
+	if !ok {
+		return response{
+			Success:      false,
+			ErrorMessage: "An account must be BTC based to support message verification.",
+		}, nil
+	}
+	format, err := coin.VerifyMessage(request.Address, []byte(request.Msg), request.Signature)
+	if err != nil {
+		handlers.log.WithError(err).Info("Message signature verification failed")
+		return response{Success: true, Valid: false, ErrorMessage: err.Error()}, nil
+	}
+	return response{Success: true, Valid: true, Format: string(format)}, nil
+}
+
+func (handlers *Handlers) postProofOfReserves(r *http.Request) (interface{}, error) {
+	type response struct {
+		Success bool `json:"success"`
+		// Proof is the base64 encoded PSBT of the proof.
+		Proof        string `json:"proof"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+		ErrorCode    string `json:"errorCode,omitempty"`
+	}
+
+	var request struct {
+		Message string `json:"message"`
+		// OutPoints are the outputs to prove. If empty, all outputs of the account are proven.
+		OutPoints []string `json:"outPoints"`
+		// SaveToFile additionally saves the proof as a PSBT file.
+		SaveToFile bool `json:"saveToFile"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	account, ok := handlers.account.(*btc.Account)
+	if !ok {
+		return response{
+			Success:      false,
+			ErrorMessage: "An account must be BTC based to support proofs of reserves.",
+		}, nil
+	}
+	outPoints := make([]wire.OutPoint, len(request.OutPoints))
+	for index, outPointString := range request.OutPoints {
+		outPoint, err := util.ParseOutPoint([]byte(outPointString))
+		if err != nil {
+			return response{Success: false, ErrorMessage: err.Error()}, nil
+		}
+		outPoints[index] = *outPoint
+	}
+
+	proof, err := account.ProofOfReserves([]byte(request.Message), outPoints)
+	if err != nil {
+		if firmware.IsErrorAbort(err) {
+			return response{Success: false, ErrorCode: errp.ErrUserAbort.Error()}, nil
+		}
+		if errp.Cause(err) == backend.ErrWrongKeystore {
+			return response{Success: false, ErrorCode: backend.ErrWrongKeystore.Error()}, nil
+		}
+		handlers.log.WithField("code", account.Config().Config.Code).Error(err)
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	encodedProof, err := proof.B64Encode()
+	if err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	if request.SaveToFile {
+		if err := handlers.saveProofOfReserves(proof); err != nil {
+			handlers.log.WithError(err).Error("error saving proof of reserves")
+			return response{Success: false, ErrorMessage: err.Error()}, nil
+		}
+	}
+	return response{Success: true, Proof: encodedProof}, nil
+}
+
+// saveProofOfReserves writes the proof as a binary PSBT file to a location chosen by the user.
+func (handlers *Handlers) saveProofOfReserves(proof *psbt.Packet) error {
+	name := fmt.Sprintf("%s-%s-proof-of-reserves.psbt",
+		time.Now().Format("2006-01-02-at-15-04-05"), handlers.account.Config().Config.Code)
+	downloadsDir, err := config.DownloadsDir()
+	if err != nil {
+		return err
+	}
+	path := handlers.account.Config().GetSaveFilename(filepath.Join(downloadsDir, name))
+	if path == "" {
+		return nil
+	}
+	handlers.log.Infof("Export proof of reserves to %s.", path)
+	file, err := os.Create(path)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	if err := proof.Serialize(file); err != nil {
+		_ = file.Close()
+		return errp.WithStack(err)
+	}
+	return errp.WithStack(file.Close())
+}
+
+func (handlers *Handlers) postVerifyProofOfReserves(r *http.Request) (interface{}, error) {
+	type response struct {
+		Success      bool             `json:"success"`
+		Valid        bool             `json:"valid"`
+		Amount       *FormattedAmount `json:"amount,omitempty"`
+		ErrorMessage string           `json:"errorMessage,omitempty"`
+	}
+
+	var request struct {
+		Message string `json:"message"`
+		// Proof is the base64 encoded PSBT of the proof.
+		Proof string `json:"proof"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	coin, ok := handlers.account.Coin().(*btc.Coin)
+	if !ok {
+		return response{
+			Success:      false,
+			ErrorMessage: "An account must be BTC based to support proofs of reserves.",
+		}, nil
+	}
+	proof, err := psbt.NewFromRawBytes(strings.NewReader(strings.TrimSpace(request.Proof)), true)
+	if err != nil {
+		return response{Success: true, Valid: false, ErrorMessage: err.Error()}, nil
+	}
+	amount, err := coin.VerifyProofOfReserves(proof, []byte(request.Message))
+	if err != nil {
+		handlers.log.WithError(err).Info("Proof of reserves verification failed")
+		return response{Success: true, Valid: false, ErrorMessage: err.Error()}, nil
+	}
+	formattedAmount := handlers.formatBTCAmountAsJSON(amount, false)
+	return response{Success: true, Valid: true, Amount: &formattedAmount}, nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/testnet4"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/ltc"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/locker"
@@ -142,6 +143,23 @@ func (headers *Headers) checkpoint() *chaincfg.Checkpoint {
 		return &chaincfg.Checkpoint{
 			Height: 1723210,
 			Hash:   mustUnhex("00000000a2aa46899e5eda73c816b55903799a4feb321c903d9baeacc9443925")}
+	// Signet and testnet4 only pin their genesis blocks, and the proof of work and difficulty of
+	// all headers is checked from there. Unlike the checkpoints above, there is no checkpoint of
+	// these chains in btcd's chaincfg or in another source we vendor which a block hash could be
+	// cross-checked against, and a checkpoint that is not on the chain would stop the header sync
+	// for good. Their coins have no value and their chains are short enough to be verified from
+	// the genesis block. A recent checkpoint should be added here once one can be taken from a
+	// reviewed source. All signets share the same genesis block. The fixed challenge of the
+	// default signet is enforced by a signature in the coinbase of each block, which can't be
+	// checked using headers only.
+	case chaincfg.SigNetParams.Net: // SBTC
+		return &chaincfg.Checkpoint{
+			Height: 0,
+			Hash:   mustUnhex("00000008819873e925422c1ff0f99f7cc9bbb232af63a077a480a3633bee1ef6")}
+	case testnet4.Net: // TBTC4
+		return &chaincfg.Checkpoint{
+			Height: 0,
+			Hash:   mustUnhex("00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043")}
 	case ltc.MainNetParams.Net: // LTC
 		return &chaincfg.Checkpoint{
 			Height: 1837000,
@@ -265,7 +283,12 @@ func (headers *Headers) getTarget(db DBInterface, index int) (*big.Int, error) {
 	if last == nil {
 		return nil, errp.Newf("header at %d not found", lastIndex)
 	}
-	lastTarget := btcdBlockchain.CompactToBig(last.Bits)
+	baseTarget := btcdBlockchain.CompactToBig(last.Bits)
+	if headers.net.Net == testnet4.Net {
+		// BIP-94: the new target is based on the first block of the window, as the last one can be
+		// a min difficulty block.
+		baseTarget = btcdBlockchain.CompactToBig(first.Bits)
+	}
 	timespan := last.Timestamp.Unix() - first.Timestamp.Unix()
 
 	minRetargetTimespan := targetTimespan / headers.net.RetargetAdjustmentFactor
@@ -275,7 +298,7 @@ func (headers *Headers) getTarget(db DBInterface, index int) (*big.Int, error) {
 	} else if timespan > maxRetargetTimespan {
 		timespan = maxRetargetTimespan
 	}
-	newTarget := new(big.Int).Mul(lastTarget, big.NewInt(timespan))
+	newTarget := new(big.Int).Mul(baseTarget, big.NewInt(timespan))
 	newTarget.Div(newTarget, big.NewInt(targetTimespan))
 	if newTarget.Cmp(headers.net.PowLimit) > 0 {
 		newTarget.Set(headers.net.PowLimit)
@@ -283,9 +306,37 @@ func (headers *Headers) getTarget(db DBInterface, index int) (*big.Int, error) {
 	return newTarget, nil
 }
 
+// expectedTarget returns the target the header at the given index must have. On testnet4, a block
+// found more than 20 minutes after the previous one can have the minimum difficulty, except for
+// the first block of a difficulty window.
+func (headers *Headers) expectedTarget(
+	db DBInterface, index int, previousHeader, header *wire.BlockHeader) (*big.Int, error) {
+	if headers.net.ReduceMinDifficulty && index%headers.blocksPerRetarget() != 0 &&
+		header.Timestamp.After(previousHeader.Timestamp.Add(headers.net.MinDiffReductionTime)) {
+		return headers.net.PowLimit, nil
+	}
+	return headers.getTarget(db, index)
+}
+
+func (headers *Headers) blocksPerRetarget() int {
+	return int(headers.net.TargetTimespan / headers.net.TargetTimePerBlock)
+}
+
+// checkTimewarp applies the timewarp rule of BIP-94 on testnet4: the first block of a difficulty
+// window can't be more than 10 minutes older than the previous block.
+func (headers *Headers) checkTimewarp(index int, previousHeader, header *wire.BlockHeader) error {
+	if headers.net.Net != testnet4.Net || index%headers.blocksPerRetarget() != 0 {
+		return nil
+	}
+	if header.Timestamp.Before(previousHeader.Timestamp.Add(-testnet4.MaxTimewarp)) {
+		return errp.Newf("header %d violates the timewarp rule", index)
+	}
+	return nil
+}
+
 func (headers *Headers) powHash(msg []byte) chainhash.Hash {
 	switch headers.net.Net {
-	case chaincfg.MainNetParams.Net:
+	case chaincfg.MainNetParams.Net, chaincfg.SigNetParams.Net, testnet4.Net:
 		return chainhash.DoubleHashH(msg)
 	case ltc.MainNetParams.Net:
 		const (
@@ -337,8 +388,12 @@ func (headers *Headers) canConnect(db DBInterface, tip int, header *wire.BlockHe
 			headers.log.Infof("checkpoint at %d matches", tip)
 		}
 		// Check Difficulty, PoW.
-		if headers.net.Net == chaincfg.MainNetParams.Net || headers.net.Net == ltc.MainNetParams.Net {
-			newTarget, err := headers.getTarget(db, tip)
+		switch headers.net.Net {
+		case chaincfg.MainNetParams.Net, ltc.MainNetParams.Net, chaincfg.SigNetParams.Net, testnet4.Net:
+			if err := headers.checkTimewarp(tip, previousHeader, header); err != nil {
+				return err
+			}
+			newTarget, err := headers.expectedTarget(db, tip, previousHeader, header)
 			if err != nil {
 				return err
 			}
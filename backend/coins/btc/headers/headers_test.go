@@ -18,8 +18,11 @@ import (
 	"testing"
 	"time"
 
+	btcdBlockchain "github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/testnet4"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 )
@@ -45,3 +48,58 @@ func TestClose(t *testing.T) {
 	}
 
 }
+
+func TestTestnet4Difficulty(t *testing.T) {
+	const windowTarget = 0x1c0fffff
+	start := time.Unix(1714777860, 0)
+	// One difficulty window of exactly two weeks, where the last block has the minimum difficulty.
+	headerAt := func(height int) *wire.BlockHeader {
+		bits := uint32(windowTarget)
+		if height == 2015 {
+			bits = testnet4.Params.PowLimitBits
+		}
+		return &wire.BlockHeader{
+			Bits:      bits,
+			Timestamp: start.Add(time.Duration(height) * 14 * 24 * time.Hour / 2015),
+		}
+	}
+	db := &dbMock{
+		headerByHeight: func(height int) (*wire.BlockHeader, error) {
+			return headerAt(height), nil
+		},
+	}
+	headers := NewHeaders(
+		&testnet4.Params,
+		db,
+		&mocks.BlockchainMock{},
+		(&logrus.Logger{}).WithField("group", "headers_test"),
+	)
+
+	// Regular block in the first window.
+	previous := headerAt(1000)
+	header := &wire.BlockHeader{Timestamp: previous.Timestamp.Add(10 * time.Minute)}
+	target, err := headers.expectedTarget(db, 1001, previous, header)
+	require.NoError(t, err)
+	require.Equal(t, testnet4.Params.GenesisBlock.Header.Bits, btcdBlockchain.BigToCompact(target))
+
+	// Min difficulty block after 20 minutes.
+	header.Timestamp = previous.Timestamp.Add(20*time.Minute + time.Second)
+	target, err = headers.expectedTarget(db, 1001, previous, header)
+	require.NoError(t, err)
+	require.Equal(t, testnet4.Params.PowLimitBits, btcdBlockchain.BigToCompact(target))
+
+	// The first block of a window never has the minimum difficulty, and its target is based on the
+	// first block of the previous window (BIP-94), not the min difficulty block at its end.
+	previous = headerAt(2015)
+	header.Timestamp = previous.Timestamp.Add(time.Hour)
+	target, err = headers.expectedTarget(db, 2016, previous, header)
+	require.NoError(t, err)
+	require.Equal(t, uint32(windowTarget), btcdBlockchain.BigToCompact(target))
+
+	// Timewarp rule.
+	header.Timestamp = previous.Timestamp.Add(-testnet4.MaxTimewarp)
+	require.NoError(t, headers.checkTimewarp(2016, previous, header))
+	header.Timestamp = previous.Timestamp.Add(-testnet4.MaxTimewarp - time.Second)
+	require.Error(t, headers.checkTimewarp(2016, previous, header))
+	require.NoError(t, headers.checkTimewarp(2017, previous, header))
+}
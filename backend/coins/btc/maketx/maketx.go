@@ -23,6 +23,7 @@ import (
 	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/silentpayments"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
 	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
@@ -45,11 +46,23 @@ type TxProposal struct {
 	// Amount is the amount that is sent out. The fee is not included and is deducted on top.
 	Amount btcutil.Amount
 	// Fee is the mining fee used.
-	Fee         btcutil.Amount
-	Transaction *wire.MsgTx
+	Fee btcutil.Amount
+	// FeeRatePerKb is the fee rate the transaction was created with.
+	FeeRatePerKb btcutil.Amount
+	Transaction  *wire.MsgTx
 	// ChangeAddress is the address of the wallet to which the change of the transaction is sent.
 	ChangeAddress   *addresses.AccountAddress
 	PreviousOutputs PreviousOutputs
+	// SilentPaymentAddress is set if the recipient is a BIP-352 silent payment address. The
+	// recipient output then has the pkScript `silentpayments.PlaceholderPkScript()` until the
+	// actual output is derived right before signing.
+	SilentPaymentAddress *silentpayments.Address
+	// PayjoinURL is the BIP-78 payjoin endpoint of the recipient. If set, a payjoin is attempted
+	// when sending, falling back to the regular transaction on failure.
+	PayjoinURL string
+	// SkipBIP69 is set for transactions which are not ordered by us, e.g. a payjoin or a proof of
+	// reserves, so that signing does not require them to follow BIP69.
+	SkipBIP69 bool
 }
 
 // Total is amount+fee.
@@ -122,6 +135,8 @@ func setRBF(coin coinpkg.Coin, tx *wire.MsgTx) {
 	for _, txIn := range tx.TxIn {
 		if coin.Code() == coinpkg.CodeBTC ||
 			coin.Code() == coinpkg.CodeTBTC ||
+			coin.Code() == coinpkg.CodeSBTC ||
+			coin.Code() == coinpkg.CodeTBTC4 ||
 			coin.Code() == coinpkg.CodeRBTC {
 			// Enable RBF
 			// https://github.com/bitcoin/bips/blob/master/bip-0125.mediawiki#summary
@@ -176,6 +191,7 @@ func NewTxSpendAll(
 		Coin:            coin,
 		Amount:          btcutil.Amount(output.Value),
 		Fee:             maxRequiredFee,
+		FeeRatePerKb:    feePerKb,
 		Transaction:     unsignedTransaction,
 		PreviousOutputs: previousOutputs,
 	}, nil
@@ -257,6 +273,7 @@ func NewTx(
 			Coin:            coin,
 			Amount:          targetAmount,
 			Fee:             finalFee,
+			FeeRatePerKb:    feePerKb,
 			Transaction:     unsignedTransaction,
 			ChangeAddress:   changeAddress,
 			PreviousOutputs: previousOutputs,
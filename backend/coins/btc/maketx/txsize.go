@@ -70,6 +70,15 @@ func sigScriptWitnessSize(configuration *signing.Configuration) (int, int) {
 	}
 }
 
+// EstimateInputVSize gives the worst case virtual size of a single input spending an output of the
+// given configuration. The segwit marker and flag are not included.
+func EstimateInputVSize(configuration *signing.Configuration) int {
+	sigScriptSize, witnessSize := sigScriptWitnessSize(configuration)
+	weight := 4*calcInputSize(sigScriptSize) + witnessSize
+	// return weight/4 rounded up.
+	return (weight + 3) / 4
+}
+
 // estimateTxSize gives the worst case tx size estimate. The unit of the result is vbyte (virtual
 // bytes), for the purpose of fee calculation.
 // https://en.bitcoin.it/wiki/Weight_units
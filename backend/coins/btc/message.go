@@ -0,0 +1,105 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"encoding/base64"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/bip322"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// MessageSignatureFormat returns the format in which to sign a message with an address of the given
+// script type. `requested` is the format requested by the party asking for the signature. If it is
+// empty, the legacy format is used, except for taproot addresses, for which it is not defined and
+// the BIP-322 simple format is used instead.
+func MessageSignatureFormat(requested string, scriptType signing.ScriptType) (bip322.Format, error) {
+	switch format := bip322.Format(requested); format {
+	case "":
+		if scriptType == signing.ScriptTypeP2TR {
+			return bip322.FormatSimple, nil
+		}
+		return bip322.FormatLegacy, nil
+	case bip322.FormatLegacy:
+		if scriptType == signing.ScriptTypeP2TR {
+			return "", errp.New("Legacy message signatures are not defined for taproot addresses")
+		}
+		return format, nil
+	case bip322.FormatSimple, bip322.FormatFull:
+		return format, nil
+	default:
+		return "", errp.Newf("Unknown message signature format: %s", requested)
+	}
+}
+
+// CanSignMessageFormat returns true if the keystore can sign messages in the given format for the
+// given coin.
+func CanSignMessageFormat(keystore keystore.Keystore, coin *Coin, format bip322.Format) bool {
+	if format == bip322.FormatLegacy {
+		return keystore.CanSignMessage(coin.Code())
+	}
+	return keystore.CanSignMessageBIP322(coin.Code())
+}
+
+// SignMessage makes the keystore sign the message with the key of the address, returning the
+// signature in the given format.
+func SignMessage(
+	keystore keystore.Keystore,
+	coin *Coin,
+	address *addresses.AccountAddress,
+	message []byte,
+	format bip322.Format,
+) ([]byte, error) {
+	scriptType := address.Configuration.ScriptType()
+	if format == bip322.FormatLegacy {
+		return keystore.SignBTCMessage(message, address.AbsoluteKeypath(), scriptType)
+	}
+	witness, err := keystore.SignBTCMessageBIP322(message, address.AbsoluteKeypath(), scriptType)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := bip322.Encode(format, message, address.PubkeyScript(), witness)
+	if err != nil {
+		return nil, err
+	}
+	// Sanity check: the signature must be valid for the address.
+	if _, err := bip322.Verify(
+		address.Address, message, base64.StdEncoding.EncodeToString(signature), coin.Net(),
+	); err != nil {
+		return nil, errp.WithMessage(err, "Created an invalid BIP-322 signature")
+	}
+	return signature, nil
+}
+
+// VerifyMessage checks that the base64 encoded signature is a valid signature of the message by the
+// address, which does not need to belong to the wallet. Legacy (BIP-137) and BIP-322 signatures are
+// supported. The detected format of the signature is returned. Only Bitcoin is supported, as other
+// coins use their own magic prefix for legacy signatures and BIP-322 is not defined for them.
+func (coin *Coin) VerifyMessage(address string, message []byte, signature string) (bip322.Format, error) {
+	switch coin.code {
+	case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeSBTC, coinpkg.CodeTBTC4, coinpkg.CodeRBTC:
+	default:
+		return "", errp.Newf("Message verification is not supported for %s", coin.code)
+	}
+	decodedAddress, err := coin.DecodeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return bip322.Verify(decodedAddress, message, signature, coin.Net())
+}
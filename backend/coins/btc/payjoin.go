@@ -0,0 +1,154 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/payjoin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// sendPayjoin tries to replace the signed original transaction of the tx proposal with a payjoin
+// transaction (BIP-78) and broadcasts it. It returns the broadcast payjoin transaction, or nil if
+// the payjoin failed at any point, in which case the original transaction should be broadcast
+// instead.
+func (account *Account) sendPayjoin(txProposal *maketx.TxProposal) *wire.MsgTx {
+	payjoinProposal, err := account.payjoinProposal(txProposal)
+	if err != nil {
+		account.log.WithError(err).Warn("Payjoin failed, falling back to the original transaction")
+		return nil
+	}
+	account.log.Info("Signed payjoin transaction is broadcasted")
+	if err := account.coin.Blockchain().TransactionBroadcast(payjoinProposal.Transaction); err != nil {
+		account.log.WithError(err).Warn(
+			"Payjoin broadcast failed, falling back to the original transaction")
+		return nil
+	}
+	return payjoinProposal.Transaction
+}
+
+// payjoinProposal requests a payjoin proposal for the signed original transaction from the
+// receiver, checks it and signs it.
+func (account *Account) payjoinProposal(txProposal *maketx.TxProposal) (*maketx.TxProposal, error) {
+	keystore, err := account.Config().ConnectKeystore()
+	if err != nil {
+		return nil, err
+	}
+	if !keystore.SupportsPayjoin(account.coin) {
+		return nil, errp.New("The keystore does not support payjoin")
+	}
+
+	getPrevTx := account.coin.Blockchain().TransactionGet
+	original, err := payjoin.OriginalPSBT(txProposal.Transaction, txProposal.PreviousOutputs, getPrevTx)
+	if err != nil {
+		return nil, err
+	}
+	firstInput := txProposal.Transaction.TxIn[0]
+	firstInputAddress := account.getAddress(
+		txProposal.PreviousOutputs[firstInput.PreviousOutPoint].ScriptHashHex())
+	if firstInputAddress == nil {
+		return nil, errp.New("Input address not found in account")
+	}
+	senderInputVSize := maketx.EstimateInputVSize(firstInputAddress.Configuration)
+	params := &payjoin.Params{
+		AdditionalFeeOutputIndex: -1,
+		MinFeeRatePerKb:          txProposal.FeeRatePerKb,
+	}
+	if txProposal.ChangeAddress != nil {
+		changePkScript := txProposal.ChangeAddress.PubkeyScript()
+		for index, txOut := range txProposal.Transaction.TxOut {
+			if bytes.Equal(txOut.PkScript, changePkScript) {
+				params.AdditionalFeeOutputIndex = index
+				params.MaxAdditionalFeeContribution = txProposal.FeeRatePerKb *
+					btcutil.Amount(senderInputVSize) / 1000
+				break
+			}
+		}
+	}
+
+	httpClient, err := account.coin.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	account.log.Info("Requesting payjoin proposal")
+	ctx, cancel := context.WithTimeout(context.Background(), payjoin.RequestTimeout)
+	defer cancel()
+	proposal, err := payjoin.RequestProposal(ctx, httpClient, txProposal.PayjoinURL, original, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := payjoin.CheckProposal(original, proposal, params, senderInputVSize); err != nil {
+		return nil, err
+	}
+	fee, err := proposal.GetTxFee()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	transaction, err := payjoin.Transaction(proposal)
+	if err != nil {
+		return nil, err
+	}
+	spentOutputs, err := payjoin.PreviousOutputs(proposal)
+	if err != nil {
+		return nil, err
+	}
+	previousOutputs := make(maketx.PreviousOutputs, len(spentOutputs))
+	for outPoint, txOut := range spentOutputs {
+		previousOutputs[outPoint] = &transactions.SpendableOutput{TxOut: txOut}
+	}
+
+	// Verify the receiver's signatures now, as the validity check after signing must not fail.
+	sigHashes := txscript.NewTxSigHashes(transaction, previousOutputs)
+	for index, txIn := range transaction.TxIn {
+		if _, isSenderInput := txProposal.PreviousOutputs[txIn.PreviousOutPoint]; isSenderInput {
+			continue
+		}
+		spentOutput := previousOutputs[txIn.PreviousOutPoint]
+		engine, err := txscript.NewEngine(spentOutput.PkScript, transaction, index,
+			txscript.StandardVerifyFlags, nil, sigHashes, spentOutput.Value, previousOutputs)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if err := engine.Execute(); err != nil {
+			return nil, errp.Wrap(err, "Invalid signature of payjoin receiver input")
+		}
+	}
+
+	payjoinTxProposal := &maketx.TxProposal{
+		Coin:            txProposal.Coin,
+		Amount:          txProposal.Amount,
+		Fee:             fee,
+		FeeRatePerKb:    txProposal.FeeRatePerKb,
+		Transaction:     transaction,
+		ChangeAddress:   txProposal.ChangeAddress,
+		PreviousOutputs: previousOutputs,
+		SkipBIP69:       true,
+	}
+	account.log.Info("Signing payjoin transaction")
+	if err := account.signTransaction(payjoinTxProposal, getPrevTx); err != nil {
+		return nil, err
+	}
+	if err := payjoin.CheckFeeRate(transaction, fee, params); err != nil {
+		return nil, err
+	}
+	return payjoinTxProposal, nil
+}
@@ -0,0 +1,440 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package payjoin implements the sender side of payjoin (BIP-78), see
+// https://github.com/bitcoin/bips/blob/master/bip-0078.mediawiki.
+//
+// The sender signs a regular transaction (the original PSBT) and posts it to the payjoin endpoint
+// of the receiver. The receiver adds some of its own inputs and returns the payjoin proposal,
+// which the sender checks, signs and broadcasts. If anything fails, the sender broadcasts the
+// original transaction instead, which pays the receiver all the same.
+package payjoin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+const (
+	// RequestTimeout is how long we wait for the receiver to respond before broadcasting the
+	// original transaction instead, as recommended by BIP-78.
+	RequestTimeout = time.Minute
+	// maxResponseSize limits the size of the proposal PSBT we read from the receiver.
+	maxResponseSize = 1 << 20
+)
+
+// Params are the parameters sent to the receiver along with the original PSBT.
+type Params struct {
+	// AdditionalFeeOutputIndex is the index of the sender's change output, from which the receiver
+	// may deduct the fee for the inputs it adds. -1 if there is no change output.
+	AdditionalFeeOutputIndex int
+	// MaxAdditionalFeeContribution is the maximum amount the receiver may deduct from the change
+	// output.
+	MaxAdditionalFeeContribution btcutil.Amount
+	// MinFeeRatePerKb is the minimum fee rate the payjoin transaction must have after signing.
+	MinFeeRatePerKb btcutil.Amount
+}
+
+// ReceiverError is a well-known error returned by the receiver, see
+// https://github.com/bitcoin/bips/blob/master/bip-0078.mediawiki#receivers-well-known-errors.
+type ReceiverError struct {
+	Code    string `json:"errorCode"`
+	Message string `json:"message"`
+}
+
+// Error implements error.
+func (err *ReceiverError) Error() string {
+	return fmt.Sprintf("payjoin receiver error %s: %s", err.Code, err.Message)
+}
+
+// ValidateEndpoint parses the payjoin endpoint given in the `pj` parameter of a BIP-21 URI. The
+// endpoint must use https, unless it is a Tor onion service.
+func ValidateEndpoint(endpoint string) (*url.URL, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	switch endpointURL.Scheme {
+	case "https":
+	case "http":
+		if !strings.HasSuffix(endpointURL.Hostname(), ".onion") {
+			return nil, errp.New("payjoin endpoint must use https")
+		}
+	default:
+		return nil, errp.Newf("unsupported payjoin endpoint scheme: %s", endpointURL.Scheme)
+	}
+	if endpointURL.Host == "" {
+		return nil, errp.New("payjoin endpoint has no host")
+	}
+	return endpointURL, nil
+}
+
+// OriginalPSBT creates the finalized original PSBT from the signed original transaction. Segwit
+// inputs get the spent output as the witness UTXO, legacy inputs the previous transaction fetched
+// with getPrevTx as the non-witness UTXO.
+func OriginalPSBT(
+	transaction *wire.MsgTx,
+	previousOutputs txscript.PrevOutputFetcher,
+	getPrevTx func(chainhash.Hash) (*wire.MsgTx, error),
+) (*psbt.Packet, error) {
+	unsignedTx := transaction.Copy()
+	for _, txIn := range unsignedTx.TxIn {
+		txIn.SignatureScript = nil
+		txIn.Witness = nil
+	}
+	packet, err := psbt.NewFromUnsignedTx(unsignedTx)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	for index, txIn := range transaction.TxIn {
+		spentOutput := previousOutputs.FetchPrevOutput(txIn.PreviousOutPoint)
+		if spentOutput == nil {
+			return nil, errp.New("missing spent output")
+		}
+		pInput := &packet.Inputs[index]
+		if txscript.IsWitnessProgram(spentOutput.PkScript) ||
+			txscript.GetScriptClass(spentOutput.PkScript) == txscript.ScriptHashTy {
+			pInput.WitnessUtxo = spentOutput
+		} else {
+			prevTx, err := getPrevTx(txIn.PreviousOutPoint.Hash)
+			if err != nil {
+				return nil, err
+			}
+			pInput.NonWitnessUtxo = prevTx
+		}
+		if len(txIn.SignatureScript) > 0 {
+			pInput.FinalScriptSig = txIn.SignatureScript
+		}
+		if len(txIn.Witness) > 0 {
+			var witness bytes.Buffer
+			if err := psbt.WriteTxWitness(&witness, txIn.Witness); err != nil {
+				return nil, errp.WithStack(err)
+			}
+			pInput.FinalScriptWitness = witness.Bytes()
+		}
+	}
+	return packet, nil
+}
+
+// RequestProposal posts the original PSBT to the payjoin endpoint of the receiver and returns the
+// payjoin proposal. The proposal must be checked with CheckProposal() before signing it.
+//
+// Payment output substitution is always disabled, so the receiver can't redirect the payment to a
+// script that was not shown to and confirmed by the user.
+func RequestProposal(
+	ctx context.Context,
+	httpClient *http.Client,
+	endpoint string,
+	original *psbt.Packet,
+	params *Params,
+) (*psbt.Packet, error) {
+	endpointURL, err := ValidateEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	query := endpointURL.Query()
+	query.Set("v", "1")
+	query.Set("disableoutputsubstitution", "true")
+	if params.AdditionalFeeOutputIndex >= 0 {
+		query.Set("additionalfeeoutputindex", strconv.Itoa(params.AdditionalFeeOutputIndex))
+		query.Set("maxadditionalfeecontribution",
+			strconv.FormatInt(int64(params.MaxAdditionalFeeContribution), 10))
+	}
+	query.Set("minfeerate",
+		strconv.FormatFloat(float64(params.MinFeeRatePerKb)/1000, 'f', -1, 64))
+	endpointURL.RawQuery = query.Encode()
+
+	body, err := original.B64Encode()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	request, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, endpointURL.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	request.Header.Set("Content-Type", "text/plain")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if response.StatusCode != http.StatusOK {
+		receiverError := &ReceiverError{}
+		if err := json.Unmarshal(responseBody, receiverError); err != nil || receiverError.Code == "" {
+			return nil, errp.Newf("payjoin endpoint returned status %d", response.StatusCode)
+		}
+		return nil, errp.WithStack(receiverError)
+	}
+	proposal, err := psbt.NewFromRawBytes(bytes.NewReader(bytes.TrimSpace(responseBody)), true)
+	if err != nil {
+		return nil, errp.Wrap(err, "could not parse payjoin proposal")
+	}
+	return proposal, nil
+}
+
+// spentOutput returns the output spent by the given PSBT input, if the input contains UTXO
+// information.
+func spentOutput(pInput *psbt.PInput, outPoint wire.OutPoint) (*wire.TxOut, error) {
+	if pInput.NonWitnessUtxo != nil {
+		if pInput.NonWitnessUtxo.TxHash() != outPoint.Hash ||
+			int(outPoint.Index) >= len(pInput.NonWitnessUtxo.TxOut) {
+			return nil, errp.New("non-witness UTXO does not match the input")
+		}
+		return pInput.NonWitnessUtxo.TxOut[outPoint.Index], nil
+	}
+	if pInput.WitnessUtxo != nil {
+		return pInput.WitnessUtxo, nil
+	}
+	return nil, errp.New("missing UTXO information")
+}
+
+func isFinalized(pInput *psbt.PInput) bool {
+	return len(pInput.FinalScriptSig) > 0 || len(pInput.FinalScriptWitness) > 0
+}
+
+// vsize returns the virtual size of the finalized PSBT.
+func vsize(packet *psbt.Packet) (int64, error) {
+	transaction, err := psbt.Extract(packet)
+	if err != nil {
+		return 0, errp.WithStack(err)
+	}
+	return mempool.GetTxVirtualSize(btcutil.NewTx(transaction)), nil
+}
+
+// CheckProposal performs the checks of the sender on the payjoin proposal, see
+// https://github.com/bitcoin/bips/blob/master/bip-0078.mediawiki#senders-payjoin-proposal-checklist.
+// senderInputVSize is the estimated virtual size of an input of the sender, used to bound the fee
+// the receiver may deduct from the change output.
+//
+// On success, the UTXO information of the sender's inputs, which the receiver must remove, is
+// restored in the proposal, so that it contains the outputs spent by all inputs. The fee rate of
+// the proposal can only be checked after signing, see CheckFeeRate().
+func CheckProposal(
+	original *psbt.Packet,
+	proposal *psbt.Packet,
+	params *Params,
+	senderInputVSize int,
+) error {
+	originalTx := original.UnsignedTx
+	proposalTx := proposal.UnsignedTx
+	if proposalTx.Version != originalTx.Version {
+		return errp.New("payjoin proposal changed the transaction version")
+	}
+	if proposalTx.LockTime != originalTx.LockTime {
+		return errp.New("payjoin proposal changed the locktime")
+	}
+	if len(proposal.Inputs) != len(proposalTx.TxIn) || len(proposal.Outputs) != len(proposalTx.TxOut) {
+		return errp.New("malformed payjoin proposal")
+	}
+	// The receiver only adds inputs and can't remove outputs, so a proposal with fewer of them is
+	// invalid. This also guarantees the proposal has an input.
+	if len(proposalTx.TxIn) == 0 || len(proposalTx.TxIn) < len(originalTx.TxIn) {
+		return errp.New("payjoin proposal has fewer inputs than the original")
+	}
+	if len(proposalTx.TxOut) < len(originalTx.TxOut) {
+		return errp.New("payjoin proposal has fewer outputs than the original")
+	}
+
+	originalInputs := make(map[wire.OutPoint]int, len(originalTx.TxIn))
+	senderScriptClasses := map[txscript.ScriptClass]struct{}{}
+	for index, txIn := range originalTx.TxIn {
+		originalInputs[txIn.PreviousOutPoint] = index
+		output, err := spentOutput(&original.Inputs[index], txIn.PreviousOutPoint)
+		if err != nil {
+			return err
+		}
+		senderScriptClasses[txscript.GetScriptClass(output.PkScript)] = struct{}{}
+	}
+
+	senderInputsFound := 0
+	receiverInputs := 0
+	sequence := proposalTx.TxIn[0].Sequence
+	for index, txIn := range proposalTx.TxIn {
+		pInput := &proposal.Inputs[index]
+		if len(pInput.Bip32Derivation) != 0 || len(pInput.TaprootBip32Derivation) != 0 {
+			return errp.New("payjoin proposal input contains keypaths")
+		}
+		if len(pInput.PartialSigs) != 0 || len(pInput.TaprootKeySpendSig) != 0 ||
+			len(pInput.TaprootScriptSpendSig) != 0 {
+			return errp.New("payjoin proposal input contains partial signatures")
+		}
+		if txIn.Sequence != sequence {
+			return errp.New("payjoin proposal inputs have mixed sequence numbers")
+		}
+		originalIndex, isSenderInput := originalInputs[txIn.PreviousOutPoint]
+		if isSenderInput {
+			if txIn.Sequence != originalTx.TxIn[originalIndex].Sequence {
+				return errp.New("payjoin proposal changed the sequence number of a sender input")
+			}
+			if isFinalized(pInput) {
+				return errp.New("payjoin proposal has a finalized sender input")
+			}
+			if pInput.NonWitnessUtxo != nil || pInput.WitnessUtxo != nil {
+				return errp.New("payjoin proposal sender input contains UTXO information")
+			}
+			senderInputsFound++
+			continue
+		}
+		receiverInputs++
+		if !isFinalized(pInput) {
+			return errp.New("payjoin proposal has an unfinalized receiver input")
+		}
+		output, err := spentOutput(pInput, txIn.PreviousOutPoint)
+		if err != nil {
+			return err
+		}
+		if len(senderScriptClasses) == 1 {
+			if _, ok := senderScriptClasses[txscript.GetScriptClass(output.PkScript)]; !ok {
+				return errp.New("payjoin proposal receiver input has a different script type")
+			}
+		}
+	}
+	if senderInputsFound != len(originalTx.TxIn) {
+		return errp.New("payjoin proposal is missing sender inputs")
+	}
+
+	// Restore the UTXO information of the sender inputs.
+	for index, txIn := range proposalTx.TxIn {
+		if originalIndex, ok := originalInputs[txIn.PreviousOutPoint]; ok {
+			proposal.Inputs[index].WitnessUtxo = original.Inputs[originalIndex].WitnessUtxo
+			proposal.Inputs[index].NonWitnessUtxo = original.Inputs[originalIndex].NonWitnessUtxo
+		}
+	}
+
+	originalFee, err := original.GetTxFee()
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	proposalFee, err := proposal.GetTxFee()
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	if proposalFee < originalFee {
+		return errp.New("payjoin proposal decreased the absolute fee")
+	}
+	originalVSize, err := vsize(original)
+	if err != nil {
+		return err
+	}
+
+	// The original outputs must appear in the same order in the proposal. Outputs not matching the
+	// next original output are outputs added by the receiver.
+	originalOutputIndex := 0
+	for index, txOut := range proposalTx.TxOut {
+		pOutput := &proposal.Outputs[index]
+		if len(pOutput.Bip32Derivation) != 0 || len(pOutput.TaprootBip32Derivation) != 0 {
+			return errp.New("payjoin proposal output contains keypaths")
+		}
+		if originalOutputIndex >= len(originalTx.TxOut) ||
+			!bytes.Equal(txOut.PkScript, originalTx.TxOut[originalOutputIndex].PkScript) {
+			continue
+		}
+		originalValue := originalTx.TxOut[originalOutputIndex].Value
+		if originalOutputIndex == params.AdditionalFeeOutputIndex {
+			contribution := btcutil.Amount(originalValue - txOut.Value)
+			if contribution > params.MaxAdditionalFeeContribution {
+				return errp.New("payjoin proposal fee contribution exceeds the maximum")
+			}
+			if contribution > proposalFee-originalFee {
+				return errp.New("payjoin proposal fee contribution does not only pay fees")
+			}
+			// The contribution may pay at most the original fee rate for the added inputs.
+			maxContribution := btcutil.Amount(
+				int64(originalFee) * int64(senderInputVSize) * int64(receiverInputs) / originalVSize)
+			if contribution > maxContribution {
+				return errp.New("payjoin proposal fee contribution is too high for the added inputs")
+			}
+		} else if txOut.Value < originalValue {
+			return errp.New("payjoin proposal decreased an output value")
+		}
+		originalOutputIndex++
+	}
+	if originalOutputIndex != len(originalTx.TxOut) {
+		return errp.New("payjoin proposal is missing outputs")
+	}
+	return nil
+}
+
+// CheckFeeRate checks that the signed payjoin transaction pays at least the minimum fee rate.
+func CheckFeeRate(transaction *wire.MsgTx, fee btcutil.Amount, params *Params) error {
+	vsize := mempool.GetTxVirtualSize(btcutil.NewTx(transaction))
+	if int64(fee)*1000 < int64(params.MinFeeRatePerKb)*vsize {
+		return errp.New("payjoin transaction fee rate is too low")
+	}
+	return nil
+}
+
+// Transaction returns the transaction of the checked payjoin proposal, with the signatures of the
+// receiver's inputs filled in. The sender's inputs are left to be signed.
+func Transaction(proposal *psbt.Packet) (*wire.MsgTx, error) {
+	transaction := proposal.UnsignedTx.Copy()
+	for index, txIn := range transaction.TxIn {
+		pInput := &proposal.Inputs[index]
+		txIn.SignatureScript = pInput.FinalScriptSig
+		if len(pInput.FinalScriptWitness) == 0 {
+			continue
+		}
+		witnessReader := bytes.NewReader(pInput.FinalScriptWitness)
+		count, err := wire.ReadVarInt(witnessReader, 0)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if count > uint64(len(pInput.FinalScriptWitness)) {
+			return nil, errp.New("invalid final witness")
+		}
+		txIn.Witness = make(wire.TxWitness, count)
+		for i := range txIn.Witness {
+			txIn.Witness[i], err = wire.ReadVarBytes(
+				witnessReader, 0, txscript.MaxScriptSize, "witness item")
+			if err != nil {
+				return nil, errp.WithStack(err)
+			}
+		}
+	}
+	return transaction, nil
+}
+
+// PreviousOutputs returns the outputs spent by the checked payjoin proposal, by outpoint.
+func PreviousOutputs(proposal *psbt.Packet) (map[wire.OutPoint]*wire.TxOut, error) {
+	previousOutputs := make(map[wire.OutPoint]*wire.TxOut, len(proposal.Inputs))
+	for index, txIn := range proposal.UnsignedTx.TxIn {
+		output, err := spentOutput(&proposal.Inputs[index], txIn.PreviousOutPoint)
+		if err != nil {
+			return nil, err
+		}
+		previousOutputs[txIn.PreviousOutPoint] = output
+	}
+	return previousOutputs, nil
+}
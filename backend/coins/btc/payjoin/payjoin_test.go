@@ -0,0 +1,413 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package payjoin_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/payjoin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	senderUTXOValue   = 100000
+	receiverUTXOValue = 50000
+	paymentValue      = 60000
+	originalFee       = 300
+	// feeRatePerKb is 2 sat/vB.
+	feeRatePerKb = 2000
+	// inputVSize is the estimated vsize of a P2WPKH input.
+	inputVSize = 68
+)
+
+type party struct {
+	privateKey *btcec.PrivateKey
+	pkScript   []byte
+	outPoint   wire.OutPoint
+	utxo       *wire.TxOut
+}
+
+func newParty(t *testing.T, seed string, value int64) *party {
+	t.Helper()
+	hash := sha256.Sum256([]byte(seed))
+	privateKey, _ := btcec.PrivKeyFromBytes(hash[:])
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).
+		AddData(btcutil.Hash160(privateKey.PubKey().SerializeCompressed())).Script()
+	require.NoError(t, err)
+	return &party{
+		privateKey: privateKey,
+		pkScript:   pkScript,
+		outPoint:   wire.OutPoint{Hash: chainhash.HashH([]byte(seed)), Index: 1},
+		utxo:       wire.NewTxOut(value, pkScript),
+	}
+}
+
+func (p *party) sign(
+	t *testing.T, transaction *wire.MsgTx, index int, prevOuts txscript.PrevOutputFetcher) {
+	t.Helper()
+	witness, err := txscript.WitnessSignature(transaction, txscript.NewTxSigHashes(transaction, prevOuts),
+		index, p.utxo.Value, p.pkScript, txscript.SigHashAll, p.privateKey, true)
+	require.NoError(t, err)
+	transaction.TxIn[index].Witness = witness
+}
+
+type fixture struct {
+	sender   *party
+	receiver *party
+	original *psbt.Packet
+	params   *payjoin.Params
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	sender := newParty(t, "sender", senderUTXOValue)
+	receiver := newParty(t, "receiver", receiverUTXOValue)
+	changePkScript := newParty(t, "change", 0).pkScript
+
+	transaction := wire.NewMsgTx(2)
+	transaction.AddTxIn(wire.NewTxIn(&sender.outPoint, nil, nil))
+	transaction.TxIn[0].Sequence = wire.MaxTxInSequenceNum - 1
+	transaction.AddTxOut(wire.NewTxOut(paymentValue, receiver.pkScript))
+	transaction.AddTxOut(wire.NewTxOut(senderUTXOValue-paymentValue-originalFee, changePkScript))
+	transaction.LockTime = 800000
+	prevOuts := txscript.NewCannedPrevOutputFetcher(sender.utxo.PkScript, sender.utxo.Value)
+	sender.sign(t, transaction, 0, prevOuts)
+
+	original, err := payjoin.OriginalPSBT(
+		transaction,
+		prevOuts,
+		func(chainhash.Hash) (*wire.MsgTx, error) { panic("not needed for segwit inputs") },
+	)
+	require.NoError(t, err)
+	require.True(t, original.IsComplete())
+	require.Equal(t, sender.utxo, original.Inputs[0].WitnessUtxo)
+
+	return &fixture{
+		sender:   sender,
+		receiver: receiver,
+		original: original,
+		params: &payjoin.Params{
+			AdditionalFeeOutputIndex:     1,
+			MaxAdditionalFeeContribution: feeRatePerKb * inputVSize / 1000,
+			MinFeeRatePerKb:              feeRatePerKb,
+		},
+	}
+}
+
+// proposal acts like a BIP-78 receiver: it adds its input, collects the value of it in the payment
+// output and deducts the fee for it from the sender's change.
+func (f *fixture) proposal(t *testing.T, original *psbt.Packet) *psbt.Packet {
+	t.Helper()
+	transaction := original.UnsignedTx.Copy()
+	transaction.TxIn = append(
+		[]*wire.TxIn{wire.NewTxIn(&f.receiver.outPoint, nil, nil)}, transaction.TxIn...)
+	transaction.TxIn[0].Sequence = transaction.TxIn[1].Sequence
+	transaction.TxOut[0].Value += receiverUTXOValue
+	transaction.TxOut[1].Value -= int64(f.params.MaxAdditionalFeeContribution)
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(map[wire.OutPoint]*wire.TxOut{
+		f.receiver.outPoint: f.receiver.utxo,
+		f.sender.outPoint:   f.sender.utxo,
+	})
+	f.receiver.sign(t, transaction, 0, prevOuts)
+	var witness bytes.Buffer
+	require.NoError(t, psbt.WriteTxWitness(&witness, transaction.TxIn[0].Witness))
+	transaction.TxIn[0].Witness = nil
+
+	proposal, err := psbt.NewFromUnsignedTx(transaction)
+	require.NoError(t, err)
+	proposal.Inputs[0].WitnessUtxo = f.receiver.utxo
+	proposal.Inputs[0].FinalScriptWitness = witness.Bytes()
+	return proposal
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	_, err := payjoin.ValidateEndpoint("https://example.com/pj?x=1")
+	require.NoError(t, err)
+	_, err = payjoin.ValidateEndpoint("http://2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid.onion/pj")
+	require.NoError(t, err)
+	_, err = payjoin.ValidateEndpoint("http://example.com/pj")
+	require.Error(t, err)
+	_, err = payjoin.ValidateEndpoint("ftp://example.com/pj")
+	require.Error(t, err)
+	_, err = payjoin.ValidateEndpoint("https:///pj")
+	require.Error(t, err)
+}
+
+func TestPayjoin(t *testing.T) {
+	f := newFixture(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+		query := r.URL.Query()
+		require.Equal(t, "1", query.Get("v"))
+		require.Equal(t, "yes", query.Get("existing"))
+		require.Equal(t, "true", query.Get("disableoutputsubstitution"))
+		require.Equal(t, "1", query.Get("additionalfeeoutputindex"))
+		require.Equal(t, "136", query.Get("maxadditionalfeecontribution"))
+		require.Equal(t, "2", query.Get("minfeerate"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		original, err := psbt.NewFromRawBytes(bytes.NewReader(body), true)
+		require.NoError(t, err)
+		require.True(t, original.IsComplete())
+		response, err := f.proposal(t, original).B64Encode()
+		require.NoError(t, err)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	proposal, err := payjoin.RequestProposal(
+		context.Background(), server.Client(), server.URL+"/pj?existing=yes", f.original, f.params)
+	require.NoError(t, err)
+	require.NoError(t, payjoin.CheckProposal(f.original, proposal, f.params, inputVSize))
+	// The sender's UTXO information was restored.
+	require.Equal(t, f.sender.utxo, proposal.Inputs[1].WitnessUtxo)
+	fee, err := proposal.GetTxFee()
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(originalFee+136), fee)
+
+	transaction, err := payjoin.Transaction(proposal)
+	require.NoError(t, err)
+	previousOutputs, err := payjoin.PreviousOutputs(proposal)
+	require.NoError(t, err)
+	require.Equal(t, map[wire.OutPoint]*wire.TxOut{
+		f.receiver.outPoint: f.receiver.utxo,
+		f.sender.outPoint:   f.sender.utxo,
+	}, previousOutputs)
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(previousOutputs)
+	f.sender.sign(t, transaction, 1, prevOuts)
+	sigHashes := txscript.NewTxSigHashes(transaction, prevOuts)
+	for index, txIn := range transaction.TxIn {
+		spentOutput := previousOutputs[txIn.PreviousOutPoint]
+		engine, err := txscript.NewEngine(spentOutput.PkScript, transaction, index,
+			txscript.StandardVerifyFlags, nil, sigHashes, spentOutput.Value, prevOuts)
+		require.NoError(t, err)
+		require.NoError(t, engine.Execute())
+	}
+	require.NoError(t, payjoin.CheckFeeRate(transaction, fee, f.params))
+	require.Error(t, payjoin.CheckFeeRate(transaction, originalFee, f.params))
+}
+
+func TestRequestProposalReceiverError(t *testing.T) {
+	f := newFixture(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errorCode": "not-enough-money", "message": "not enough money"}`))
+	}))
+	defer server.Close()
+	_, err := payjoin.RequestProposal(context.Background(), server.Client(), server.URL, f.original, f.params)
+	require.Error(t, err)
+	receiverError, ok := errp.Cause(err).(*payjoin.ReceiverError)
+	require.True(t, ok)
+	require.Equal(t, "not-enough-money", receiverError.Code)
+
+	_, err = payjoin.RequestProposal(
+		context.Background(), server.Client(), "http://example.com", f.original, f.params)
+	require.Error(t, err)
+}
+
+func TestRequestProposalTimeout(t *testing.T) {
+	f := newFixture(t)
+	done := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := payjoin.RequestProposal(ctx, server.Client(), server.URL, f.original, f.params)
+	require.ErrorIs(t, errp.Cause(err), context.DeadlineExceeded)
+}
+
+func TestCheckProposal(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(f *fixture, proposal *psbt.Packet)
+	}{
+		{
+			name: "changed version",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.Version = 1
+			},
+		},
+		{
+			name: "changed locktime",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.LockTime++
+			},
+		},
+		{
+			name: "changed sequence",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				for _, txIn := range proposal.UnsignedTx.TxIn {
+					txIn.Sequence = wire.MaxTxInSequenceNum
+				}
+			},
+		},
+		{
+			name: "mixed sequences",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxIn[0].Sequence = wire.MaxTxInSequenceNum
+			},
+		},
+		{
+			name: "partial signature",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.Inputs[1].PartialSigs = []*psbt.PartialSig{{}}
+			},
+		},
+		{
+			name: "input keypath",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.Inputs[1].Bip32Derivation = []*psbt.Bip32Derivation{{}}
+			},
+		},
+		{
+			name: "output keypath",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.Outputs[0].Bip32Derivation = []*psbt.Bip32Derivation{{}}
+			},
+		},
+		{
+			name: "finalized sender input",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.Inputs[1].FinalScriptWitness = f.original.Inputs[0].FinalScriptWitness
+			},
+		},
+		{
+			name: "sender input with UTXO information",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.Inputs[1].WitnessUtxo = f.sender.utxo
+			},
+		},
+		{
+			name: "unfinalized receiver input",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.Inputs[0].FinalScriptWitness = nil
+			},
+		},
+		{
+			name: "receiver input without UTXO information",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.Inputs[0].WitnessUtxo = nil
+			},
+		},
+		{
+			name: "receiver input of a different type",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.Inputs[0].WitnessUtxo = wire.NewTxOut(receiverUTXOValue,
+					append([]byte{txscript.OP_1, txscript.OP_DATA_32}, make([]byte, 32)...))
+			},
+		},
+		{
+			name: "missing sender input",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxIn = proposal.UnsignedTx.TxIn[:1]
+				proposal.Inputs = proposal.Inputs[:1]
+			},
+		},
+		{
+			name: "no inputs",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxIn = nil
+				proposal.Inputs = nil
+			},
+		},
+		{
+			name: "missing output",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxOut = proposal.UnsignedTx.TxOut[:1]
+				proposal.Outputs = proposal.Outputs[:1]
+			},
+		},
+		{
+			name: "substituted payment output",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxOut[0].PkScript = f.sender.pkScript
+			},
+		},
+		{
+			name: "decreased payment output",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxOut[0].Value = paymentValue - 1
+			},
+		},
+		{
+			name: "fee contribution above maximum",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxOut[0].Value--
+				proposal.UnsignedTx.TxOut[1].Value--
+			},
+		},
+		{
+			name: "fee contribution paying the receiver",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxOut[0].Value += 100
+			},
+		},
+		{
+			name: "decreased fee",
+			mutate: func(f *fixture, proposal *psbt.Packet) {
+				proposal.UnsignedTx.TxOut[0].Value += 1000
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			proposal := f.proposal(t, f.original)
+			require.NoError(t,
+				payjoin.CheckProposal(f.original, f.proposal(t, f.original), f.params, inputVSize))
+			test.mutate(f, proposal)
+			require.Error(t, payjoin.CheckProposal(f.original, proposal, f.params, inputVSize))
+		})
+	}
+
+	t.Run("contribution too high for the added inputs", func(t *testing.T) {
+		f := newFixture(t)
+		proposal := f.proposal(t, f.original)
+		// The receiver is allowed to deduct more, but the sender input is estimated to be small.
+		require.Error(t, payjoin.CheckProposal(f.original, proposal, f.params, 10))
+	})
+
+	t.Run("no fee output", func(t *testing.T) {
+		f := newFixture(t)
+		f.params.AdditionalFeeOutputIndex = -1
+		proposal := f.proposal(t, f.original)
+		require.Error(t, payjoin.CheckProposal(f.original, proposal, f.params, inputVSize))
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btc
+
+import (
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/reserves"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// ProofOfReserves creates a BIP-127 proof of reserves for the message, proving control of the given
+// unspent outputs of the account, or of all of them if outPoints is empty. The signed proof is
+// returned as a finalized PSBT.
+func (account *Account) ProofOfReserves(message []byte, outPoints []wire.OutPoint) (*psbt.Packet, error) {
+	keystore, err := account.Config().ConnectKeystore()
+	if err != nil {
+		return nil, err
+	}
+	if !keystore.SupportsProofOfReserves(account.coin) {
+		return nil, errp.New("The keystore does not support proofs of reserves")
+	}
+
+	account.Synchronizer.WaitSynchronized()
+	utxos, err := account.transactions.SpendableOutputs()
+	if err != nil {
+		return nil, err
+	}
+	if len(outPoints) == 0 {
+		for outPoint := range utxos {
+			outPoints = append(outPoints, outPoint)
+		}
+	}
+	outputs := make(map[wire.OutPoint]*wire.TxOut, len(outPoints))
+	for _, outPoint := range outPoints {
+		utxo, ok := utxos[outPoint]
+		if !ok {
+			return nil, errp.Newf("%s is not an unspent output of the account", outPoint)
+		}
+		address := account.getAddress(utxo.ScriptHashHex())
+		if address == nil {
+			return nil, errp.New("Output address not found in account")
+		}
+		if address.Configuration.ScriptType() == signing.ScriptTypeP2PKH {
+			return nil, errp.New("Proofs of reserves are only supported for segwit outputs")
+		}
+		outputs[outPoint] = utxo.TxOut
+	}
+
+	proof, spentOutputs, err := reserves.NewProof(message, outputs)
+	if err != nil {
+		return nil, err
+	}
+	previousOutputs := make(maketx.PreviousOutputs, len(spentOutputs))
+	for outPoint, txOut := range spentOutputs {
+		previousOutputs[outPoint] = &transactions.SpendableOutput{TxOut: txOut}
+	}
+	txProposal := &maketx.TxProposal{
+		Coin:            account.coin,
+		Amount:          btcutil.Amount(proof.TxOut[0].Value),
+		Transaction:     proof,
+		PreviousOutputs: previousOutputs,
+		// The commitment input comes first, so the proof can't be ordered by BIP69.
+		SkipBIP69: true,
+	}
+	account.log.Info("Signing proof of reserves")
+	if err := account.signTransaction(txProposal, account.coin.Blockchain().TransactionGet); err != nil {
+		return nil, err
+	}
+	return reserves.PSBT(proof, previousOutputs)
+}
+
+// VerifyProofOfReserves checks the BIP-127 proof of reserves for the message against the current
+// UTXO set and returns the proven amount. The proof does not need to belong to the wallet.
+func (coin *Coin) VerifyProofOfReserves(proof *psbt.Packet, message []byte) (btcutil.Amount, error) {
+	return reserves.Verify(proof, message, coin.Blockchain())
+}
@@ -0,0 +1,254 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reserves implements proofs of reserves according to BIP-127, see
+// https://github.com/bitcoin/bips/blob/master/bip-0127.mediawiki.
+//
+// A proof of reserves is a transaction spending the outputs to prove, which can never be valid on
+// chain: its first input, the commitment input, spends a non-existent output derived from a
+// message, e.g. a challenge chosen by the auditor. The commitment input is signed with the key of
+// the first proven output, which binds the proof to the message.
+package reserves
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// commitmentPrefix is prepended to the message before hashing it into the commitment input.
+const commitmentPrefix = "Proof-of-Reserves: "
+
+// CommitmentOutPoint returns the non-existent outpoint spent by the commitment input of a proof
+// for the given message.
+func CommitmentOutPoint(message []byte) wire.OutPoint {
+	preimage := append([]byte(commitmentPrefix), message...)
+	return wire.OutPoint{Hash: chainhash.DoubleHashH(preimage), Index: 0}
+}
+
+// CommitmentPrevOut returns the output which the commitment input is signed as spending. It has no
+// value and the pkScript of the first proven output.
+func CommitmentPrevOut(pkScript []byte) *wire.TxOut {
+	return wire.NewTxOut(0, pkScript)
+}
+
+// UnspendablePkScript returns the pkScript of the single output of a proof: a p2pkh output to the
+// hash of the single byte 0x00, for which no key is known.
+func UnspendablePkScript() []byte {
+	script, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160([]byte{0})).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		// Can't happen, the script has a fixed size.
+		panic(err)
+	}
+	return script
+}
+
+// NewProof returns the unsigned proof transaction for the message, spending the given outputs. The
+// first input is the commitment input, followed by the outputs sorted by outpoint. The returned
+// previous outputs contain the outputs and the output spent by the commitment input, as needed to
+// sign the proof.
+func NewProof(
+	message []byte,
+	outputs map[wire.OutPoint]*wire.TxOut,
+) (*wire.MsgTx, map[wire.OutPoint]*wire.TxOut, error) {
+	if len(outputs) == 0 {
+		return nil, nil, errp.New("There are no outputs to prove")
+	}
+	outPoints := make([]wire.OutPoint, 0, len(outputs))
+	for outPoint := range outputs {
+		outPoints = append(outPoints, outPoint)
+	}
+	sort.Slice(outPoints, func(i, j int) bool {
+		if outPoints[i].Hash != outPoints[j].Hash {
+			return bytes.Compare(outPoints[i].Hash[:], outPoints[j].Hash[:]) < 0
+		}
+		return outPoints[i].Index < outPoints[j].Index
+	})
+
+	commitmentOutPoint := CommitmentOutPoint(message)
+	previousOutputs := map[wire.OutPoint]*wire.TxOut{
+		commitmentOutPoint: CommitmentPrevOut(outputs[outPoints[0]].PkScript),
+	}
+	proof := wire.NewMsgTx(wire.TxVersion)
+	proof.AddTxIn(wire.NewTxIn(&commitmentOutPoint, nil, nil))
+	var total int64
+	for _, outPoint := range outPoints {
+		outPoint := outPoint // avoid reference reuse due to range loop
+		proof.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+		previousOutputs[outPoint] = outputs[outPoint]
+		total += outputs[outPoint].Value
+	}
+	proof.AddTxOut(wire.NewTxOut(total, UnspendablePkScript()))
+	return proof, previousOutputs, nil
+}
+
+// PSBT returns the signed proof as a finalized PSBT, the format in which proofs are exchanged.
+// Every input gets the spent output as witness utxo.
+func PSBT(proof *wire.MsgTx, prevOuts txscript.PrevOutputFetcher) (*psbt.Packet, error) {
+	unsigned := proof.Copy()
+	for _, txIn := range unsigned.TxIn {
+		txIn.SignatureScript = nil
+		txIn.Witness = nil
+	}
+	packet, err := psbt.NewFromUnsignedTx(unsigned)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	for index, txIn := range proof.TxIn {
+		pInput := &packet.Inputs[index]
+		pInput.WitnessUtxo = prevOuts.FetchPrevOutput(txIn.PreviousOutPoint)
+		if len(txIn.SignatureScript) > 0 {
+			pInput.FinalScriptSig = txIn.SignatureScript
+		}
+		if len(txIn.Witness) > 0 {
+			var witness bytes.Buffer
+			if err := psbt.WriteTxWitness(&witness, txIn.Witness); err != nil {
+				return nil, errp.WithStack(err)
+			}
+			pInput.FinalScriptWitness = witness.Bytes()
+		}
+	}
+	return packet, nil
+}
+
+// Verify checks that the proof, a finalized PSBT, is a valid proof of reserves for the message and
+// that all outputs it spends are currently unspent. The proven amount is returned.
+//
+// Only proofs of segwit outputs signed with SIGHASH_ALL are supported, so that no signature can be
+// taken from another transaction.
+func Verify(proof *psbt.Packet, message []byte, blockchainAPI blockchain.Interface) (btcutil.Amount, error) {
+	transaction, err := psbt.Extract(proof)
+	if err != nil {
+		return 0, errp.Wrap(err, "The proof is not finalized")
+	}
+	if len(transaction.TxIn) < 2 {
+		return 0, errp.New("The proof does not spend any outputs")
+	}
+	if transaction.TxIn[0].PreviousOutPoint != CommitmentOutPoint(message) {
+		return 0, errp.New("The proof does not commit to the message")
+	}
+	if len(transaction.TxOut) != 1 ||
+		!bytes.Equal(transaction.TxOut[0].PkScript, UnspendablePkScript()) {
+		return 0, errp.New("The proof must have a single unspendable output")
+	}
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	var total int64
+	for _, txIn := range transaction.TxIn[1:] {
+		if prevOuts.FetchPrevOutput(txIn.PreviousOutPoint) != nil {
+			return 0, errp.Newf("The proof spends output %s twice", txIn.PreviousOutPoint)
+		}
+		txOut, err := unspentOutput(blockchainAPI, txIn.PreviousOutPoint)
+		if err != nil {
+			return 0, err
+		}
+		prevOuts.AddPrevOut(txIn.PreviousOutPoint, txOut)
+		total += txOut.Value
+	}
+	firstPrevOut := prevOuts.FetchPrevOutput(transaction.TxIn[1].PreviousOutPoint)
+	prevOuts.AddPrevOut(transaction.TxIn[0].PreviousOutPoint, CommitmentPrevOut(firstPrevOut.PkScript))
+	if transaction.TxOut[0].Value != total {
+		return 0, errp.New("The output value of the proof must equal the proven amount")
+	}
+
+	sigHashes := txscript.NewTxSigHashes(transaction, prevOuts)
+	for index, txIn := range transaction.TxIn {
+		if err := checkSigHashAll(txIn); err != nil {
+			return 0, err
+		}
+		spentOutput := prevOuts.FetchPrevOutput(txIn.PreviousOutPoint)
+		engine, err := txscript.NewEngine(spentOutput.PkScript, transaction, index,
+			txscript.StandardVerifyFlags, nil, sigHashes, spentOutput.Value, prevOuts)
+		if err != nil {
+			return 0, errp.WithStack(err)
+		}
+		if err := engine.Execute(); err != nil {
+			return 0, errp.Wrap(err, fmt.Sprintf("Invalid signature of input %d", index))
+		}
+	}
+	return btcutil.Amount(total), nil
+}
+
+// unspentOutput fetches the output from the blockchain and checks that it is unspent.
+func unspentOutput(blockchainAPI blockchain.Interface, outPoint wire.OutPoint) (*wire.TxOut, error) {
+	transaction, err := blockchainAPI.TransactionGet(outPoint.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if outPoint.Index >= uint32(len(transaction.TxOut)) {
+		return nil, errp.Newf("Output %s does not exist", outPoint)
+	}
+	txOut := transaction.TxOut[outPoint.Index]
+	history, err := blockchainAPI.ScriptHashGetHistory(blockchain.NewScriptHashHex(txOut.PkScript))
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, entry := range history {
+		txHash := entry.TXHash.Hash()
+		if txHash == outPoint.Hash {
+			found = true
+			continue
+		}
+		historyTx, err := blockchainAPI.TransactionGet(txHash)
+		if err != nil {
+			return nil, err
+		}
+		for _, txIn := range historyTx.TxIn {
+			if txIn.PreviousOutPoint == outPoint {
+				return nil, errp.Newf("Output %s is spent", outPoint)
+			}
+		}
+	}
+	if !found {
+		return nil, errp.Newf("Output %s does not exist", outPoint)
+	}
+	return txOut, nil
+}
+
+// checkSigHashAll checks that the input of a single key segwit output is signed with SIGHASH_ALL.
+func checkSigHashAll(txIn *wire.TxIn) error {
+	witness := txIn.Witness
+	switch len(witness) {
+	case 1:
+		// Taproot key path spend, where SIGHASH_DEFAULT is SIGHASH_ALL.
+		signature := witness[0]
+		if len(signature) == 64 ||
+			(len(signature) == 65 && txscript.SigHashType(signature[64]) == txscript.SigHashAll) {
+			return nil
+		}
+	case 2:
+		// p2wpkh, possibly nested in p2sh: <signature> <pubkey>.
+		signature := witness[0]
+		if len(signature) > 0 &&
+			txscript.SigHashType(signature[len(signature)-1]) == txscript.SigHashAll {
+			return nil
+		}
+	}
+	return errp.New("Only SIGHASH_ALL signatures of segwit inputs are supported")
+}
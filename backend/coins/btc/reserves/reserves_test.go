@@ -0,0 +1,218 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reserves_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/bip322"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
+	blockchainMock "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/reserves"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/stretchr/testify/require"
+)
+
+var message = []byte("Audit 2024-06-30, challenge 5f2c")
+
+// chain is a minimal blockchain with one funding tx per owned output.
+type chain struct {
+	transactions map[chainhash.Hash]*wire.MsgTx
+	history      map[blockchain.ScriptHashHex]blockchain.TxHistory
+}
+
+func newChain() *chain {
+	return &chain{
+		transactions: map[chainhash.Hash]*wire.MsgTx{},
+		history:      map[blockchain.ScriptHashHex]blockchain.TxHistory{},
+	}
+}
+
+func (c *chain) addTx(tx *wire.MsgTx, pkScripts ...[]byte) {
+	txHash := tx.TxHash()
+	c.transactions[txHash] = tx
+	for _, pkScript := range pkScripts {
+		scriptHash := blockchain.NewScriptHashHex(pkScript)
+		c.history[scriptHash] = append(c.history[scriptHash],
+			&blockchain.TxInfo{Height: 10, TXHash: blockchain.TXHash(txHash)})
+	}
+}
+
+func (c *chain) blockchain() *blockchainMock.BlockchainMock {
+	return &blockchainMock.BlockchainMock{
+		MockTransactionGet: func(txHash chainhash.Hash) (*wire.MsgTx, error) {
+			tx, ok := c.transactions[txHash]
+			if !ok {
+				return nil, errors.New("transaction not found")
+			}
+			return tx, nil
+		},
+		MockScriptHashGetHistory: func(scriptHash blockchain.ScriptHashHex) (blockchain.TxHistory, error) {
+			return c.history[scriptHash], nil
+		},
+	}
+}
+
+type owned struct {
+	privateKey *btcec.PrivateKey
+	scriptType signing.ScriptType
+	pkScript   []byte
+}
+
+// fund creates a funding tx with an output to each key and returns the owned outputs.
+func fund(t *testing.T, c *chain, scriptTypes ...signing.ScriptType) map[wire.OutPoint]*owned {
+	t.Helper()
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: uint32(len(c.transactions))}, nil, nil))
+	result := map[wire.OutPoint]*owned{}
+	pkScripts := [][]byte{}
+	for _, scriptType := range scriptTypes {
+		privateKey, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		pkScript, err := bip322.PkScript(scriptType, privateKey.PubKey())
+		require.NoError(t, err)
+		tx.AddTxOut(wire.NewTxOut(100000000, pkScript))
+		pkScripts = append(pkScripts, pkScript)
+		result[wire.OutPoint{Index: uint32(len(tx.TxOut) - 1)}] = &owned{privateKey, scriptType, pkScript}
+	}
+	c.addTx(tx, pkScripts...)
+	withHash := map[wire.OutPoint]*owned{}
+	for outPoint, o := range result {
+		outPoint.Hash = tx.TxHash()
+		withHash[outPoint] = o
+	}
+	return withHash
+}
+
+// prove creates and signs a proof of the owned outputs, like a keystore would.
+func prove(t *testing.T, msg []byte, ownedOutputs map[wire.OutPoint]*owned) *psbt.Packet {
+	t.Helper()
+	outputs := map[wire.OutPoint]*wire.TxOut{}
+	byScript := map[string]*owned{}
+	for outPoint, o := range ownedOutputs {
+		outputs[outPoint] = wire.NewTxOut(100000000, o.pkScript)
+		byScript[string(o.pkScript)] = o
+	}
+	proof, spentOutputs, err := reserves.NewProof(msg, outputs)
+	require.NoError(t, err)
+	prevOuts := txscript.NewMultiPrevOutFetcher(spentOutputs)
+	sigHashes := txscript.NewTxSigHashes(proof, prevOuts)
+	for index, txIn := range proof.TxIn {
+		spentOutput := spentOutputs[txIn.PreviousOutPoint]
+		o := byScript[string(spentOutput.PkScript)]
+		if o.scriptType == signing.ScriptTypeP2TR {
+			txIn.Witness, err = txscript.TaprootWitnessSignature(proof, sigHashes, index,
+				spentOutput.Value, spentOutput.PkScript, txscript.SigHashDefault, o.privateKey)
+		} else {
+			txIn.Witness, err = txscript.WitnessSignature(proof, sigHashes, index,
+				spentOutput.Value, spentOutput.PkScript, txscript.SigHashAll, o.privateKey, true)
+		}
+		require.NoError(t, err)
+	}
+	packet, err := reserves.PSBT(proof, prevOuts)
+	require.NoError(t, err)
+	return packet
+}
+
+func TestNewProof(t *testing.T) {
+	_, _, err := reserves.NewProof(message, nil)
+	require.Error(t, err)
+
+	c := newChain()
+	ownedOutputs := fund(t, c, signing.ScriptTypeP2WPKH, signing.ScriptTypeP2TR)
+	outputs := map[wire.OutPoint]*wire.TxOut{}
+	for outPoint, o := range ownedOutputs {
+		outputs[outPoint] = wire.NewTxOut(100000000, o.pkScript)
+	}
+	proof, spentOutputs, err := reserves.NewProof(message, outputs)
+	require.NoError(t, err)
+	require.Len(t, proof.TxIn, 3)
+	require.Equal(t, reserves.CommitmentOutPoint(message), proof.TxIn[0].PreviousOutPoint)
+	require.Equal(t, uint32(0), proof.TxIn[1].PreviousOutPoint.Index)
+	require.Equal(t, uint32(1), proof.TxIn[2].PreviousOutPoint.Index)
+	require.Len(t, proof.TxOut, 1)
+	require.Equal(t, int64(200000000), proof.TxOut[0].Value)
+	require.Equal(t, reserves.UnspendablePkScript(), proof.TxOut[0].PkScript)
+	require.Len(t, spentOutputs, 3)
+	commitmentPrevOut := spentOutputs[proof.TxIn[0].PreviousOutPoint]
+	require.Equal(t, int64(0), commitmentPrevOut.Value)
+	require.Equal(t, spentOutputs[proof.TxIn[1].PreviousOutPoint].PkScript, commitmentPrevOut.PkScript)
+
+	require.NotEqual(t,
+		reserves.CommitmentOutPoint(message), reserves.CommitmentOutPoint([]byte("other")))
+}
+
+func TestVerify(t *testing.T) {
+	c := newChain()
+	ownedOutputs := fund(t, c, signing.ScriptTypeP2WPKH, signing.ScriptTypeP2TR, signing.ScriptTypeP2WPKH)
+	proof := prove(t, message, ownedOutputs)
+
+	// Round trip through the serialized PSBT.
+	var serialized bytes.Buffer
+	require.NoError(t, proof.Serialize(&serialized))
+	parsed, err := psbt.NewFromRawBytes(&serialized, false)
+	require.NoError(t, err)
+
+	amount, err := reserves.Verify(parsed, message, c.blockchain())
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(300000000), amount)
+
+	_, err = reserves.Verify(proof, []byte("other message"), c.blockchain())
+	require.Error(t, err)
+
+	// Claiming more than the proven amount.
+	proof.UnsignedTx.TxOut[0].Value++
+	_, err = reserves.Verify(proof, message, c.blockchain())
+	require.Error(t, err)
+	proof.UnsignedTx.TxOut[0].Value--
+
+	// Invalid signature.
+	proof.Inputs[1].FinalScriptWitness = proof.Inputs[2].FinalScriptWitness
+	_, err = reserves.Verify(proof, message, c.blockchain())
+	require.Error(t, err)
+}
+
+func TestVerifySpent(t *testing.T) {
+	c := newChain()
+	ownedOutputs := fund(t, c, signing.ScriptTypeP2WPKH)
+	proof := prove(t, message, ownedOutputs)
+	_, err := reserves.Verify(proof, message, c.blockchain())
+	require.NoError(t, err)
+
+	for outPoint, o := range ownedOutputs {
+		outPoint := outPoint
+		spend := wire.NewMsgTx(wire.TxVersion)
+		spend.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+		spend.AddTxOut(wire.NewTxOut(1000, []byte{txscript.OP_TRUE}))
+		c.addTx(spend, o.pkScript)
+	}
+	_, err = reserves.Verify(proof, message, c.blockchain())
+	require.Error(t, err)
+}
+
+func TestVerifyUnknownOutput(t *testing.T) {
+	c := newChain()
+	proof := prove(t, message, fund(t, c, signing.ScriptTypeP2TR))
+	_, err := reserves.Verify(proof, message, newChain().blockchain())
+	require.Error(t, err)
+}
@@ -15,15 +15,21 @@
 package btc
 
 import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil/txsort"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/silentpayments"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	keystorePkg "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 )
@@ -41,8 +47,9 @@ type ProposedTransaction struct {
 	FormatUnit coin.BtcUnit
 }
 
-// signTransaction signs all inputs. It assumes all outputs spent belong to this
-// wallet. previousOutputs must contain all outputs which are spent by the transaction.
+// signTransaction signs all inputs belonging to this wallet. Inputs of other parties, e.g. the
+// receiver in a payjoin, must already be signed. previousOutputs must contain all outputs which are
+// spent by the transaction.
 func (account *Account) signTransaction(
 	txProposal *maketx.TxProposal,
 	getPrevTx func(chainhash.Hash) (*wire.MsgTx, error),
@@ -53,6 +60,21 @@ func (account *Account) signTransaction(
 	}
 	previousOutputs := txProposal.PreviousOutputs
 
+	keystore, err := account.Config().ConnectKeystore()
+	if err != nil {
+		return err
+	}
+	if txProposal.SilentPaymentAddress != nil {
+		if err := account.deriveSilentPaymentOutput(keystore, txProposal); err != nil {
+			return err
+		}
+	}
+
+	// Sanity check before anything is signed.
+	if !txProposal.SkipBIP69 && !txsort.IsSorted(txProposal.Transaction) {
+		return errp.New("tx not bip69 conformant")
+	}
+
 	proposedTransaction := &ProposedTransaction{
 		TXProposal:                   txProposal,
 		AccountSigningConfigurations: signingConfigs,
@@ -63,10 +85,6 @@ func (account *Account) signTransaction(
 		FormatUnit:                   account.coin.formatUnit,
 	}
 
-	keystore, err := account.Config().ConnectKeystore()
-	if err != nil {
-		return err
-	}
 	if err := keystore.SignTransaction(proposedTransaction); err != nil {
 		return err
 	}
@@ -74,6 +92,9 @@ func (account *Account) signTransaction(
 	for index, input := range txProposal.Transaction.TxIn {
 		spentOutput := previousOutputs[input.PreviousOutPoint]
 		address := proposedTransaction.GetAccountAddress(spentOutput.ScriptHashHex())
+		if address == nil {
+			continue
+		}
 		signature := proposedTransaction.Signatures[index]
 		if signature == nil {
 			return errp.New("Signature missing")
@@ -90,11 +111,83 @@ func (account *Account) signTransaction(
 	return nil
 }
 
+// deriveSilentPaymentOutput replaces the placeholder recipient output of a tx paying to a silent
+// payment address with the taproot output derived according to BIP-352. The keystore computes the
+// ECDH shared secret, as it needs the private keys of the inputs.
+func (account *Account) deriveSilentPaymentOutput(
+	keystore keystorePkg.Keystore,
+	txProposal *maketx.TxProposal,
+) error {
+	transaction := txProposal.Transaction
+	placeholder := silentpayments.PlaceholderPkScript()
+	var output *wire.TxOut
+	for _, txOut := range transaction.TxOut {
+		if bytes.Equal(txOut.PkScript, placeholder) {
+			output = txOut
+			break
+		}
+	}
+	if output == nil {
+		// Already derived in a previous signing attempt of the same proposal.
+		return nil
+	}
+	if !keystore.SupportsSilentPayments(account.coin) {
+		return errp.WithStack(errors.ErrSilentPaymentsNotSupported)
+	}
+
+	inputConfigurations := make([]*signing.Configuration, len(transaction.TxIn))
+	inputPublicKeys := make([]*btcec.PublicKey, len(transaction.TxIn))
+	outPoints := make([]wire.OutPoint, len(transaction.TxIn))
+	for index, txIn := range transaction.TxIn {
+		spentOutput, ok := txProposal.PreviousOutputs[txIn.PreviousOutPoint]
+		if !ok {
+			return errp.New("There needs to be exactly one output being spent per input!")
+		}
+		address := account.getAddress(spentOutput.ScriptHashHex())
+		if address == nil {
+			return errp.New("Input address not found in account")
+		}
+		publicKey, err := silentpayments.InputPublicKey(
+			address.Configuration.ScriptType(), address.Configuration.PublicKey())
+		if err != nil {
+			return err
+		}
+		inputConfigurations[index] = address.Configuration
+		inputPublicKeys[index] = publicKey
+		outPoints[index] = txIn.PreviousOutPoint
+	}
+	sumPublicKeys, err := silentpayments.SumPublicKeys(inputPublicKeys)
+	if err != nil {
+		return err
+	}
+	inputHash, err := silentpayments.InputHash(outPoints, sumPublicKeys)
+	if err != nil {
+		return err
+	}
+	silentPaymentAddress := txProposal.SilentPaymentAddress
+	sharedSecret, err := keystore.SilentPaymentSharedSecret(
+		inputConfigurations, inputHash, silentPaymentAddress.ScanPubKey)
+	if err != nil {
+		return err
+	}
+	// We only pay to one silent payment address per tx, so k=0.
+	outputKey, err := silentpayments.OutputKey(sharedSecret, silentPaymentAddress.SpendPubKey, 0)
+	if err != nil {
+		return err
+	}
+	pkScript, err := silentpayments.PkScript(outputKey)
+	if err != nil {
+		return err
+	}
+	output.PkScript = pkScript
+	// The new output script can change the BIP69 output order.
+	txsort.InPlaceSort(transaction)
+	account.log.Info("Derived silent payment output")
+	return nil
+}
+
 func txValidityCheck(transaction *wire.MsgTx, previousOutputs maketx.PreviousOutputs,
 	sigHashes *txscript.TxSigHashes) error {
-	if !txsort.IsSorted(transaction) {
-		return errp.New("tx not bip69 conformant")
-	}
 	for index, txIn := range transaction.TxIn {
 		spentOutput, ok := previousOutputs[txIn.PreviousOutPoint]
 		if !ok {
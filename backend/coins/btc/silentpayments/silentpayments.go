@@ -0,0 +1,330 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package silentpayments implements the sending side of BIP-352 silent payments:
+// https://github.com/bitcoin/bips/blob/master/bip-0352.mediawiki
+package silentpayments
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/testnet4"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+const (
+	tagInputs       = "BIP0352/Inputs"
+	tagSharedSecret = "BIP0352/SharedSecret"
+
+	// payloadLen is the length of the address payload: the serialized scan and spend public keys.
+	payloadLen = 2 * 33
+	// versionInvalid is the address version reserved for a backwards incompatible upgrade.
+	versionInvalid = 31
+
+	// oddPrefix is the first byte of a compressed public key with an odd Y coordinate.
+	oddPrefix = 0x03
+)
+
+// hrp returns the human readable part of silent payment addresses for the given network.
+func hrp(net *chaincfg.Params) (string, error) {
+	switch net.Net {
+	case chaincfg.MainNetParams.Net:
+		return "sp", nil
+	case chaincfg.TestNet3Params.Net, testnet4.Net, chaincfg.SigNetParams.Net:
+		return "tsp", nil
+	case chaincfg.RegressionNetParams.Net:
+		return "sprt", nil
+	default:
+		return "", errp.Newf("silent payments not supported on network %s", net.Name)
+	}
+}
+
+// Address is a decoded silent payment address.
+type Address struct {
+	encoded string
+	// ScanPubKey is B_scan, the public key the receiver uses to scan for payments.
+	ScanPubKey *btcec.PublicKey
+	// SpendPubKey is B_m, the (possibly labeled) public key the payment outputs are derived from.
+	SpendPubKey *btcec.PublicKey
+}
+
+// IsAddress returns true if the string looks like a silent payment address of the given
+// network. It does not validate the address, use DecodeAddress() for that.
+func IsAddress(address string, net *chaincfg.Params) bool {
+	expectedHRP, err := hrp(net)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(address), expectedHRP+"1")
+}
+
+// DecodeAddress decodes a bech32m encoded silent payment address and checks that it belongs to
+// the given network.
+func DecodeAddress(address string, net *chaincfg.Params) (*Address, error) {
+	expectedHRP, err := hrp(net)
+	if err != nil {
+		return nil, err
+	}
+	// Silent payment addresses exceed the 90 character limit of BIP-173.
+	decodedHRP, data, err := bech32.DecodeNoLimit(address)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if decodedHRP != expectedHRP {
+		return nil, errp.Newf("wrong network prefix %s", decodedHRP)
+	}
+	if len(data) == 0 {
+		return nil, errp.New("missing silent payment address version")
+	}
+	// DecodeNoLimit accepts both bech32 and bech32m checksums, but only bech32m is valid here.
+	reencoded, err := bech32.EncodeM(decodedHRP, data)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if reencoded != strings.ToLower(address) {
+		return nil, errp.New("silent payment address must be bech32m encoded")
+	}
+	version := data[0]
+	if version == versionInvalid {
+		return nil, errp.Newf("unsupported silent payment address version %d", version)
+	}
+	payload, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	// Version 0 has exactly 66 bytes. Future versions must be readable by version 0 senders by
+	// taking the first 66 bytes.
+	if len(payload) < payloadLen || (version == 0 && len(payload) != payloadLen) {
+		return nil, errp.Newf("invalid silent payment address length %d", len(payload))
+	}
+	scanPubKey, err := btcec.ParsePubKey(payload[:33])
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	spendPubKey, err := btcec.ParsePubKey(payload[33:payloadLen])
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &Address{
+		encoded:     strings.ToLower(address),
+		ScanPubKey:  scanPubKey,
+		SpendPubKey: spendPubKey,
+	}, nil
+}
+
+// NewAddress creates a version 0 silent payment address for the given network.
+func NewAddress(scanPubKey, spendPubKey *btcec.PublicKey, net *chaincfg.Params) (*Address, error) {
+	hrp, err := hrp(net)
+	if err != nil {
+		return nil, err
+	}
+	payload := append(scanPubKey.SerializeCompressed(), spendPubKey.SerializeCompressed()...)
+	data, err := bech32.ConvertBits(payload, 8, 5, true)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	encoded, err := bech32.EncodeM(hrp, append([]byte{0}, data...))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &Address{
+		encoded:     encoded,
+		ScanPubKey:  scanPubKey,
+		SpendPubKey: spendPubKey,
+	}, nil
+}
+
+// EncodeAddress returns the bech32m encoding of the address.
+func (address *Address) EncodeAddress() string {
+	return address.encoded
+}
+
+// String implements fmt.Stringer.
+func (address *Address) String() string {
+	return address.encoded
+}
+
+// InputPublicKey returns the public key an input of the given script type contributes to the sum
+// of input public keys. For P2TR key path spends, this is the taproot output key with an even Y
+// coordinate. Returns an error if the script type is not eligible for silent payments.
+func InputPublicKey(scriptType signing.ScriptType, publicKey *btcec.PublicKey) (*btcec.PublicKey, error) {
+	switch scriptType {
+	case signing.ScriptTypeP2PKH, signing.ScriptTypeP2WPKHP2SH, signing.ScriptTypeP2WPKH:
+		return publicKey, nil
+	case signing.ScriptTypeP2TR:
+		outputKey := txscript.ComputeTaprootKeyNoScript(publicKey)
+		// Lift the x-only key to the point with the even Y coordinate.
+		return schnorr.ParsePubKey(schnorr.SerializePubKey(outputKey))
+	default:
+		return nil, errp.Newf("script type %s not eligible for silent payments", scriptType)
+	}
+}
+
+// InputPrivateKey returns the private key matching InputPublicKey(), i.e. the taproot tweaked key
+// negated if needed for P2TR inputs, and the unmodified key otherwise.
+func InputPrivateKey(scriptType signing.ScriptType, privateKey *btcec.PrivateKey) (*btcec.PrivateKey, error) {
+	switch scriptType {
+	case signing.ScriptTypeP2PKH, signing.ScriptTypeP2WPKHP2SH, signing.ScriptTypeP2WPKH:
+		return privateKey, nil
+	case signing.ScriptTypeP2TR:
+		tweaked := txscript.TweakTaprootPrivKey(*privateKey, nil)
+		if tweaked.PubKey().SerializeCompressed()[0] == oddPrefix {
+			tweaked.Key.Negate()
+		}
+		return tweaked, nil
+	default:
+		return nil, errp.Newf("script type %s not eligible for silent payments", scriptType)
+	}
+}
+
+func isInfinity(point *btcec.JacobianPoint) bool {
+	return (point.X.IsZero() && point.Y.IsZero()) || point.Z.IsZero()
+}
+
+// SumPublicKeys returns A, the sum of the public keys of all eligible inputs.
+func SumPublicKeys(publicKeys []*btcec.PublicKey) (*btcec.PublicKey, error) {
+	if len(publicKeys) == 0 {
+		return nil, errp.New("no eligible inputs")
+	}
+	var sum btcec.JacobianPoint
+	publicKeys[0].AsJacobian(&sum)
+	for _, publicKey := range publicKeys[1:] {
+		var point btcec.JacobianPoint
+		publicKey.AsJacobian(&point)
+		btcec.AddNonConst(&sum, &point, &sum)
+	}
+	if isInfinity(&sum) {
+		return nil, errp.New("sum of input public keys is the point at infinity")
+	}
+	sum.ToAffine()
+	return btcec.NewPublicKey(&sum.X, &sum.Y), nil
+}
+
+// serializeOutPoint serializes an outpoint like in a transaction: the txid in little endian
+// followed by the 4 byte little endian output index.
+func serializeOutPoint(outPoint wire.OutPoint) []byte {
+	result := make([]byte, chainhash.HashSize+4)
+	copy(result, outPoint.Hash[:])
+	binary.LittleEndian.PutUint32(result[chainhash.HashSize:], outPoint.Index)
+	return result
+}
+
+// InputHash computes input_hash = hash_BIP0352/Inputs(outpoint_L || A), where outpoint_L is the
+// lexicographically smallest serialized outpoint among all inputs of the transaction and A is the
+// sum of the eligible input public keys.
+func InputHash(outPoints []wire.OutPoint, sumPublicKeys *btcec.PublicKey) ([]byte, error) {
+	if len(outPoints) == 0 {
+		return nil, errp.New("no inputs")
+	}
+	smallest := serializeOutPoint(outPoints[0])
+	for _, outPoint := range outPoints[1:] {
+		serialized := serializeOutPoint(outPoint)
+		if bytes.Compare(serialized, smallest) < 0 {
+			smallest = serialized
+		}
+	}
+	return chainhash.TaggedHash(
+		[]byte(tagInputs), smallest, sumPublicKeys.SerializeCompressed())[:], nil
+}
+
+// scalar parses a 32 byte big endian number as a scalar. Values which are zero or not smaller than
+// the curve order are invalid.
+func scalar(b []byte) (*btcec.ModNScalar, error) {
+	if len(b) != 32 {
+		return nil, errp.New("invalid scalar")
+	}
+	var result btcec.ModNScalar
+	if overflow := result.SetByteSlice(b); overflow || result.IsZero() {
+		return nil, errp.New("invalid scalar")
+	}
+	return &result, nil
+}
+
+// SharedSecret computes the ECDH shared secret input_hash·a·B_scan, where a is the sum of the
+// private keys of the eligible inputs, as returned by InputPrivateKey().
+func SharedSecret(
+	privateKeys []*btcec.PrivateKey, inputHash []byte, scanPubKey *btcec.PublicKey,
+) (*btcec.PublicKey, error) {
+	if len(privateKeys) == 0 {
+		return nil, errp.New("no eligible inputs")
+	}
+	var sum btcec.ModNScalar
+	for _, privateKey := range privateKeys {
+		sum.Add(&privateKey.Key)
+	}
+	if sum.IsZero() {
+		return nil, errp.New("sum of input private keys is zero")
+	}
+	inputHashScalar, err := scalar(inputHash)
+	if err != nil {
+		return nil, err
+	}
+	sum.Mul(inputHashScalar)
+
+	var scanPoint, result btcec.JacobianPoint
+	scanPubKey.AsJacobian(&scanPoint)
+	btcec.ScalarMultNonConst(&sum, &scanPoint, &result)
+	result.ToAffine()
+	return btcec.NewPublicKey(&result.X, &result.Y), nil
+}
+
+// OutputKey derives the taproot output key P_k = B_m + t_k·G for the k-th output paying to the
+// same scan key, where t_k = hash_BIP0352/SharedSecret(ser_P(ecdh_shared_secret) || ser_32(k)).
+func OutputKey(sharedSecret *btcec.PublicKey, spendPubKey *btcec.PublicKey, k uint32) (*btcec.PublicKey, error) {
+	var kBytes [4]byte
+	binary.BigEndian.PutUint32(kBytes[:], k)
+	tk, err := scalar(chainhash.TaggedHash(
+		[]byte(tagSharedSecret), sharedSecret.SerializeCompressed(), kBytes[:])[:])
+	if err != nil {
+		return nil, err
+	}
+	var tkG, spendPoint, result btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(tk, &tkG)
+	spendPubKey.AsJacobian(&spendPoint)
+	btcec.AddNonConst(&spendPoint, &tkG, &result)
+	if isInfinity(&result) {
+		return nil, errp.New("output key is the point at infinity")
+	}
+	result.ToAffine()
+	return btcec.NewPublicKey(&result.X, &result.Y), nil
+}
+
+// PkScript returns the P2TR output script paying to the given output key.
+func PkScript(outputKey *btcec.PublicKey) ([]byte, error) {
+	pkScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_1).
+		AddData(schnorr.SerializePubKey(outputKey)).
+		Script()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return pkScript, nil
+}
+
+// PlaceholderPkScript is a P2TR output script of the same size as the final silent payment output.
+// It is used in tx proposals until the real output is derived when signing, which requires the
+// keystore to take part.
+func PlaceholderPkScript() []byte {
+	return append([]byte{txscript.OP_1, txscript.OP_DATA_32}, make([]byte, 32)...)
+}
@@ -0,0 +1,320 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silentpayments_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/silentpayments"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/testnet4"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
+	"github.com/stretchr/testify/require"
+)
+
+// A valid mainnet address taken from the BIP-352 test vectors.
+const testAddress = "sp1qqgste7k9hx0qftg6qmwlkqtwuy6cycyavzmzj85c6qdfhjdpdjtdgqjuexzk6murw56suy3e0rd2cgqvycxttddwsvgxe2usfpxumr70xc9pkqwv"
+
+func privateKey(seed string) *btcec.PrivateKey {
+	hash := sha256.Sum256([]byte(seed))
+	privateKey, _ := btcec.PrivKeyFromBytes(hash[:])
+	return privateKey
+}
+
+func TestDecodeAddress(t *testing.T) {
+	address, err := silentpayments.DecodeAddress(testAddress, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Equal(t, testAddress, address.EncodeAddress())
+
+	reencoded, err := silentpayments.NewAddress(
+		address.ScanPubKey, address.SpendPubKey, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.Equal(t, testAddress, reencoded.EncodeAddress())
+
+	require.True(t, silentpayments.IsAddress(testAddress, &chaincfg.MainNetParams))
+	require.False(t, silentpayments.IsAddress(testAddress, &chaincfg.TestNet3Params))
+	require.False(t, silentpayments.IsAddress("bc1qxyz", &chaincfg.MainNetParams))
+
+	// Wrong network.
+	_, err = silentpayments.DecodeAddress(testAddress, &chaincfg.TestNet3Params)
+	require.Error(t, err)
+
+	// Invalid checksum.
+	_, err = silentpayments.DecodeAddress(testAddress[:len(testAddress)-1]+"q", &chaincfg.MainNetParams)
+	require.Error(t, err)
+
+	_, data, err := bech32.DecodeNoLimit(testAddress)
+	require.NoError(t, err)
+
+	// bech32 instead of bech32m checksum.
+	bech32Address, err := bech32.Encode("sp", data)
+	require.NoError(t, err)
+	_, err = silentpayments.DecodeAddress(bech32Address, &chaincfg.MainNetParams)
+	require.Error(t, err)
+
+	// Version 31 is reserved.
+	v31 := append([]byte{31}, data[1:]...)
+	v31Address, err := bech32.EncodeM("sp", v31)
+	require.NoError(t, err)
+	_, err = silentpayments.DecodeAddress(v31Address, &chaincfg.MainNetParams)
+	require.Error(t, err)
+
+	// Future versions can have a longer payload, of which only the first 66 bytes are read.
+	payload, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	require.NoError(t, err)
+	longPayload, err := bech32.ConvertBits(append(payload, 0xaa, 0xbb), 8, 5, true)
+	require.NoError(t, err)
+	v1Address, err := bech32.EncodeM("sp", append([]byte{1}, longPayload...))
+	require.NoError(t, err)
+	v1Decoded, err := silentpayments.DecodeAddress(v1Address, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	require.True(t, v1Decoded.ScanPubKey.IsEqual(address.ScanPubKey))
+	require.True(t, v1Decoded.SpendPubKey.IsEqual(address.SpendPubKey))
+
+	// Version 0 must have exactly 66 bytes.
+	v0Address, err := bech32.EncodeM("sp", append([]byte{0}, longPayload...))
+	require.NoError(t, err)
+	_, err = silentpayments.DecodeAddress(v0Address, &chaincfg.MainNetParams)
+	require.Error(t, err)
+}
+
+func TestDecodeAddressTestnet4(t *testing.T) {
+	mainnetAddress, err := silentpayments.DecodeAddress(testAddress, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+
+	address, err := silentpayments.NewAddress(
+		mainnetAddress.ScanPubKey, mainnetAddress.SpendPubKey, &testnet4.Params)
+	require.NoError(t, err)
+	encoded := address.EncodeAddress()
+	require.True(t, strings.HasPrefix(encoded, "tsp1"))
+
+	decoded, err := silentpayments.DecodeAddress(encoded, &testnet4.Params)
+	require.NoError(t, err)
+	require.Equal(t, encoded, decoded.EncodeAddress())
+	require.True(t, silentpayments.IsAddress(encoded, &testnet4.Params))
+
+	// Mainnet addresses are rejected on testnet4 and vice versa.
+	require.False(t, silentpayments.IsAddress(testAddress, &testnet4.Params))
+	_, err = silentpayments.DecodeAddress(encoded, &chaincfg.MainNetParams)
+	require.Error(t, err)
+}
+
+func TestInputPrivateKey(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		privKey := privateKey(string(rune('a' + i)))
+		for _, scriptType := range []signing.ScriptType{
+			signing.ScriptTypeP2PKH,
+			signing.ScriptTypeP2WPKHP2SH,
+			signing.ScriptTypeP2WPKH,
+			signing.ScriptTypeP2TR,
+		} {
+			inputPrivKey, err := silentpayments.InputPrivateKey(scriptType, privKey)
+			require.NoError(t, err)
+			inputPubKey, err := silentpayments.InputPublicKey(scriptType, privKey.PubKey())
+			require.NoError(t, err)
+			require.True(t, inputPrivKey.PubKey().IsEqual(inputPubKey))
+		}
+		taprootPubKey, err := silentpayments.InputPublicKey(signing.ScriptTypeP2TR, privKey.PubKey())
+		require.NoError(t, err)
+		require.Equal(t,
+			schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(privKey.PubKey())),
+			schnorr.SerializePubKey(taprootPubKey))
+	}
+	_, err := silentpayments.InputPublicKey(signing.ScriptType("p2wsh"), privateKey("a").PubKey())
+	require.Error(t, err)
+}
+
+// TestSenderReceiver checks that the output derived by the sender can be found by the receiver,
+// which computes the shared secret as input_hash·b_scan·A.
+func TestSenderReceiver(t *testing.T) {
+	scanKey := privateKey("scan")
+	spendKey := privateKey("spend")
+	address, err := silentpayments.NewAddress(
+		scanKey.PubKey(), spendKey.PubKey(), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+	decoded, err := silentpayments.DecodeAddress(address.EncodeAddress(), &chaincfg.TestNet3Params)
+	require.NoError(t, err)
+
+	inputs := []struct {
+		scriptType signing.ScriptType
+		privKey    *btcec.PrivateKey
+		outPoint   wire.OutPoint
+	}{
+		{signing.ScriptTypeP2WPKH, privateKey("input0"), wire.OutPoint{Hash: chainhash.HashH([]byte("tx0")), Index: 3}},
+		{signing.ScriptTypeP2TR, privateKey("input1"), wire.OutPoint{Hash: chainhash.HashH([]byte("tx1")), Index: 0}},
+		{signing.ScriptTypeP2WPKHP2SH, privateKey("input2"), wire.OutPoint{Hash: chainhash.HashH([]byte("tx2")), Index: 1}},
+	}
+	privKeys := []*btcec.PrivateKey{}
+	pubKeys := []*btcec.PublicKey{}
+	outPoints := []wire.OutPoint{}
+	for _, input := range inputs {
+		privKey, err := silentpayments.InputPrivateKey(input.scriptType, input.privKey)
+		require.NoError(t, err)
+		pubKey, err := silentpayments.InputPublicKey(input.scriptType, input.privKey.PubKey())
+		require.NoError(t, err)
+		privKeys = append(privKeys, privKey)
+		pubKeys = append(pubKeys, pubKey)
+		outPoints = append(outPoints, input.outPoint)
+	}
+	sumPubKeys, err := silentpayments.SumPublicKeys(pubKeys)
+	require.NoError(t, err)
+	inputHash, err := silentpayments.InputHash(outPoints, sumPubKeys)
+	require.NoError(t, err)
+
+	// The input hash does not depend on the input order.
+	reversed := []wire.OutPoint{outPoints[2], outPoints[1], outPoints[0]}
+	inputHash2, err := silentpayments.InputHash(reversed, sumPubKeys)
+	require.NoError(t, err)
+	require.Equal(t, inputHash, inputHash2)
+
+	// Sender.
+	sharedSecret, err := silentpayments.SharedSecret(privKeys, inputHash, decoded.ScanPubKey)
+	require.NoError(t, err)
+	outputKey, err := silentpayments.OutputKey(sharedSecret, decoded.SpendPubKey, 0)
+	require.NoError(t, err)
+
+	// Receiver.
+	var tweak btcec.ModNScalar
+	require.False(t, tweak.SetByteSlice(inputHash))
+	tweak.Mul(&scanKey.Key)
+	var sumPoint, receiverSecret btcec.JacobianPoint
+	sumPubKeys.AsJacobian(&sumPoint)
+	btcec.ScalarMultNonConst(&tweak, &sumPoint, &receiverSecret)
+	receiverSecret.ToAffine()
+	receiverOutputKey, err := silentpayments.OutputKey(
+		btcec.NewPublicKey(&receiverSecret.X, &receiverSecret.Y), spendKey.PubKey(), 0)
+	require.NoError(t, err)
+	require.True(t, outputKey.IsEqual(receiverOutputKey))
+
+	// Different k yields a different output.
+	outputKey1, err := silentpayments.OutputKey(sharedSecret, decoded.SpendPubKey, 1)
+	require.NoError(t, err)
+	require.False(t, outputKey.IsEqual(outputKey1))
+
+	pkScript, err := silentpayments.PkScript(outputKey)
+	require.NoError(t, err)
+	require.Len(t, pkScript, len(silentpayments.PlaceholderPkScript()))
+	require.Equal(t, txscript.WitnessV1TaprootTy, txscript.GetScriptClass(pkScript))
+	require.Equal(t, txscript.WitnessV1TaprootTy,
+		txscript.GetScriptClass(silentpayments.PlaceholderPkScript()))
+}
+
+// TestSendingVectors checks the output keys derived by the sender against the sending test vectors
+// of BIP-352 with P2PKH inputs.
+// https://github.com/bitcoin/bips/blob/master/bip-0352/send_and_receive_test_vectors.json
+func TestSendingVectors(t *testing.T) {
+	type input struct {
+		txID       string
+		vout       uint32
+		privateKey string
+	}
+	const (
+		txID1       = "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16"
+		txID2       = "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d"
+		privateKey1 = "eadc78165ff1f8ea94ad7cfdc54990738a4c53f6e0507b42154201b8e5dff3b1"
+		privateKey2 = "93f5ed907ad5b2bdbbdcb5d9116ebc0a4e1f92f910d5260237fa45a9408aad16"
+	)
+	vectors := []struct {
+		comment   string
+		inputs    []input
+		outputKey string
+	}{
+		{
+			comment:   "Simple send: two inputs",
+			inputs:    []input{{txID1, 0, privateKey1}, {txID2, 0, privateKey2}},
+			outputKey: "3e9fce73d4e77a4809908e3c3a2e54ee147b9312dc5044a193d1fc85de46e3c1",
+		},
+		{
+			comment:   "Simple send: two inputs, order reversed",
+			inputs:    []input{{txID2, 0, privateKey2}, {txID1, 0, privateKey1}},
+			outputKey: "3e9fce73d4e77a4809908e3c3a2e54ee147b9312dc5044a193d1fc85de46e3c1",
+		},
+		{
+			comment:   "Simple send: two inputs from the same transaction",
+			inputs:    []input{{txID1, 3, privateKey1}, {txID1, 7, privateKey2}},
+			outputKey: "79e71baa2ba3fc66396de3a04f168c7bf24d6870ec88ca877754790c1db357b6",
+		},
+		{
+			comment:   "Simple send: two inputs from the same transaction, order reversed",
+			inputs:    []input{{txID1, 7, privateKey2}, {txID1, 3, privateKey1}},
+			outputKey: "79e71baa2ba3fc66396de3a04f168c7bf24d6870ec88ca877754790c1db357b6",
+		},
+	}
+	address, err := silentpayments.DecodeAddress(testAddress, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	for _, vector := range vectors {
+		t.Run(vector.comment, func(t *testing.T) {
+			privKeys := []*btcec.PrivateKey{}
+			pubKeys := []*btcec.PublicKey{}
+			outPoints := []wire.OutPoint{}
+			for _, input := range vector.inputs {
+				privKeyBytes, err := hex.DecodeString(input.privateKey)
+				require.NoError(t, err)
+				privKey, _ := btcec.PrivKeyFromBytes(privKeyBytes)
+				inputPrivKey, err := silentpayments.InputPrivateKey(signing.ScriptTypeP2PKH, privKey)
+				require.NoError(t, err)
+				inputPubKey, err := silentpayments.InputPublicKey(signing.ScriptTypeP2PKH, privKey.PubKey())
+				require.NoError(t, err)
+				txHash, err := chainhash.NewHashFromStr(input.txID)
+				require.NoError(t, err)
+				privKeys = append(privKeys, inputPrivKey)
+				pubKeys = append(pubKeys, inputPubKey)
+				outPoints = append(outPoints, wire.OutPoint{Hash: *txHash, Index: input.vout})
+			}
+			sumPubKeys, err := silentpayments.SumPublicKeys(pubKeys)
+			require.NoError(t, err)
+			inputHash, err := silentpayments.InputHash(outPoints, sumPubKeys)
+			require.NoError(t, err)
+			sharedSecret, err := silentpayments.SharedSecret(privKeys, inputHash, address.ScanPubKey)
+			require.NoError(t, err)
+			outputKey, err := silentpayments.OutputKey(sharedSecret, address.SpendPubKey, 0)
+			require.NoError(t, err)
+			require.Equal(t, vector.outputKey, hex.EncodeToString(schnorr.SerializePubKey(outputKey)))
+		})
+	}
+}
+
+func TestSharedSecretErrors(t *testing.T) {
+	scanKey := privateKey("scan")
+	inputHash := chainhash.HashB([]byte("input hash"))
+
+	_, err := silentpayments.SharedSecret(nil, inputHash, scanKey.PubKey())
+	require.Error(t, err)
+
+	// Keys that cancel each other out.
+	key := privateKey("key")
+	var negated btcec.ModNScalar
+	negated.Set(&key.Key).Negate()
+	_, err = silentpayments.SharedSecret(
+		[]*btcec.PrivateKey{key, btcec.PrivKeyFromScalar(&negated)}, inputHash, scanKey.PubKey())
+	require.Error(t, err)
+	_, err = silentpayments.SumPublicKeys(
+		[]*btcec.PublicKey{key.PubKey(), btcec.PrivKeyFromScalar(&negated).PubKey()})
+	require.Error(t, err)
+
+	// Invalid input hash.
+	_, err = silentpayments.SharedSecret(
+		[]*btcec.PrivateKey{key}, make([]byte, 32), scanKey.PubKey())
+	require.Error(t, err)
+}
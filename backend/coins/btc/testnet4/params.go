@@ -0,0 +1,144 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testnet4 defines the chain parameters of the Bitcoin test network version 4 (BIP-94),
+// which are not part of the btcd version we use. See
+// https://github.com/bitcoin/bips/blob/master/bip-0094.mediawiki.
+package testnet4
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Net represents the test network (version 4).
+const Net wire.BitcoinNet = 0x283f161c
+
+// MaxTimewarp is the maximum number of seconds the timestamp of the first block of a difficulty
+// period may be before the timestamp of the previous block (BIP-94).
+const MaxTimewarp = 600 * time.Second
+
+// powLimit is the highest proof of work value a block can have, the same as on testnet3.
+var powLimit = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 224), big.NewInt(1))
+
+func mustHash(s string) *chainhash.Hash {
+	hash, err := chainhash.NewHashFromStr(s)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+}
+
+func genesisCoinbaseTx() *wire.MsgTx {
+	scriptSig, err := txscript.NewScriptBuilder().
+		AddInt64(486604799).
+		// The number 4 is pushed as data, not as OP_4.
+		AddOps([]byte{txscript.OP_DATA_1, 4}).
+		AddData([]byte("03/May/2024 000000000000000000001ebd58c244970b3aa9d783bb001011fbe8ea8e98e00e")).
+		Script()
+	if err != nil {
+		panic(err)
+	}
+	pkScript, err := txscript.NewScriptBuilder().
+		AddData(make([]byte, 33)).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	if err != nil {
+		panic(err)
+	}
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  scriptSig,
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	tx.AddTxOut(wire.NewTxOut(50*1e8, pkScript))
+	return tx
+}
+
+func genesisBlock() *wire.MsgBlock {
+	coinbase := genesisCoinbaseTx()
+	return &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    1,
+			MerkleRoot: coinbase.TxHash(),
+			Timestamp:  time.Unix(1714777860, 0),
+			Bits:       0x1d00ffff,
+			Nonce:      393743547,
+		},
+		Transactions: []*wire.MsgTx{coinbase},
+	}
+}
+
+// Params defines the network parameters for the test network (version 4). Only the parameters
+// needed by the wallet are set.
+var Params = chaincfg.Params{
+	Name:        "testnet4",
+	Net:         Net,
+	DefaultPort: "48333",
+	DNSSeeds: []chaincfg.DNSSeed{
+		{Host: "seed.testnet4.bitcoin.sprovoost.nl", HasFiltering: false},
+		{Host: "seed.testnet4.wiz.biz", HasFiltering: false},
+	},
+
+	// Chain parameters
+	GenesisBlock:             genesisBlock(),
+	GenesisHash:              mustHash("00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043"),
+	PowLimit:                 powLimit,
+	PowLimitBits:             0x1d00ffff,
+	BIP0034Height:            1,
+	BIP0065Height:            1,
+	BIP0066Height:            1,
+	CoinbaseMaturity:         100,
+	SubsidyReductionInterval: 210000,
+	TargetTimespan:           time.Hour * 24 * 14, // 14 days
+	TargetTimePerBlock:       time.Minute * 10,    // 10 minutes
+	RetargetAdjustmentFactor: 4,                   // 25% less, 400% more
+	ReduceMinDifficulty:      true,
+	MinDiffReductionTime:     time.Minute * 20, // TargetTimePerBlock * 2
+	GenerateSupported:        false,
+
+	// Mempool parameters
+	RelayNonStdTxs: true,
+
+	// Human-readable part for Bech32 encoded segwit addresses, as defined in
+	// BIP 173.
+	Bech32HRPSegwit: "tb", // always tb for test net
+
+	// Address encoding magics
+	PubKeyHashAddrID:        0x6f, // starts with m or n
+	ScriptHashAddrID:        0xc4, // starts with 2
+	WitnessPubKeyHashAddrID: 0x03, // starts with QW
+	WitnessScriptHashAddrID: 0x28, // starts with T7n
+	PrivateKeyID:            0xef, // starts with 9 (uncompressed) or c (compressed)
+
+	// BIP32 hierarchical deterministic extended key magics
+	HDPrivateKeyID: [4]byte{0x04, 0x35, 0x83, 0x94}, // starts with tprv
+	HDPublicKeyID:  [4]byte{0x04, 0x35, 0x87, 0xcf}, // starts with tpub
+
+	// BIP44 coin type used in the hierarchical deterministic path for
+	// address generation.
+	HDCoinType: 1,
+}
+
+func init() {
+	if err := chaincfg.Register(&Params); err != nil {
+		panic("failed to register network: " + err.Error())
+	}
+}
@@ -0,0 +1,15 @@
+package testnet4_test
+
+import (
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/testnet4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenesis(t *testing.T) {
+	require.Equal(t,
+		"7aa0a7ae1e223414cb807e40cd57e667b718e42aaf9306db9102fe28912b7b4e",
+		testnet4.Params.GenesisBlock.Header.MerkleRoot.String())
+	require.Equal(t, *testnet4.Params.GenesisHash, testnet4.Params.GenesisBlock.BlockHash())
+}
@@ -26,6 +26,8 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/addresses"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/maketx"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/payjoin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/silentpayments"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/transactions"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/util"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
@@ -133,13 +135,35 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (
 
 	account.log.Debug("Prepare new transaction")
 
-	address, err := account.coin.DecodeAddress(args.RecipientAddress)
-	if err != nil {
-		return nil, nil, err
-	}
-	pkScript, err := util.PkScriptFromAddress(address)
-	if err != nil {
-		return nil, nil, err
+	var pkScript []byte
+	var silentPaymentAddress *silentpayments.Address
+	if silentpayments.IsAddress(args.RecipientAddress, account.coin.Net()) {
+		var err error
+		silentPaymentAddress, err = account.coin.DecodeSilentPaymentAddress(args.RecipientAddress)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Checked here already if the keystore is connected, so the user does not confirm a
+		// proposal that can't be signed. Otherwise, it is checked when signing, as connecting the
+		// keystore blocks.
+		if connectedKeystore := account.Config().ConnectedKeystore; connectedKeystore != nil {
+			keystore := connectedKeystore()
+			if keystore != nil && !keystore.SupportsSilentPayments(account.coin) {
+				return nil, nil, errp.WithStack(errors.ErrSilentPaymentsNotSupported)
+			}
+		}
+		// The output depends on the selected inputs and is derived when signing. The placeholder
+		// has the same size, so the fee estimation is not affected.
+		pkScript = silentpayments.PlaceholderPkScript()
+	} else {
+		address, err := account.coin.DecodeAddress(args.RecipientAddress)
+		if err != nil {
+			return nil, nil, err
+		}
+		pkScript, err = util.PkScriptFromAddress(address)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 	utxo, err := account.transactions.SpendableOutputs()
 	if err != nil {
@@ -208,6 +232,16 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (
 			return nil, nil, err
 		}
 	}
+	txProposal.SilentPaymentAddress = silentPaymentAddress
+	// Receivers can't add inputs to a tx paying to a silent payment address, as the output is
+	// derived from the inputs.
+	if args.PayjoinURL != "" && silentPaymentAddress == nil {
+		if _, err := payjoin.ValidateEndpoint(args.PayjoinURL); err != nil {
+			account.log.WithError(err).Warn("Ignoring invalid payjoin endpoint")
+		} else {
+			txProposal.PayjoinURL = args.PayjoinURL
+		}
+	}
 	account.log.Debugf("creating tx with %d inputs, %d outputs",
 		len(txProposal.Transaction.TxIn), len(txProposal.Transaction.TxOut))
 	return utxo, txProposal, nil
@@ -241,16 +275,29 @@ func (account *Account) SendTx() error {
 		return errp.WithMessage(err, "Failed to sign transaction")
 	}
 
-	account.log.Info("Signed transaction is broadcasted")
-	if err := account.coin.Blockchain().TransactionBroadcast(txProposal.Transaction); err != nil {
-		return err
+	transaction := txProposal.Transaction
+	if txProposal.PayjoinURL != "" {
+		if payjoinTransaction := account.sendPayjoin(txProposal); payjoinTransaction != nil {
+			transaction = payjoinTransaction
+		}
+	}
+	if transaction == txProposal.Transaction {
+		account.log.Info("Signed transaction is broadcasted")
+		if err := account.coin.Blockchain().TransactionBroadcast(transaction); err != nil {
+			return err
+		}
 	}
 
 	note := account.BaseAccount.GetAndClearProposedTxNote()
-	if err := account.SetTxNote(txProposal.Transaction.TxHash().String(), note); err != nil {
+	if err := account.SetTxNote(transaction.TxHash().String(), note); err != nil {
 		// Not critical.
 		account.log.WithError(err).Error("Failed to save transaction note when sending a tx")
 	}
+	fiatValues := account.BaseAccount.GetAndClearProposedTxFiatValues()
+	if err := account.SetTxFiatValues(transaction.TxHash().String(), fiatValues); err != nil {
+		// Not critical.
+		account.log.WithError(err).Error("Failed to save the fiat values when sending a tx")
+	}
 	return nil
 }
 
@@ -270,6 +317,7 @@ func (account *Account) TxProposal(
 	}
 
 	account.activeTxProposal = txProposal
+	account.ProposeTxFiatValues(coin.NewAmountFromInt64(int64(txProposal.Amount)))
 
 	account.log.WithField("fee", txProposal.Fee).Debug("Returning fee")
 	return coin.NewAmountFromInt64(int64(txProposal.Amount)),
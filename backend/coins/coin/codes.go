@@ -22,6 +22,10 @@ const (
 	CodeBTC Code = "btc"
 	// CodeTBTC is Bitcoin Testnet.
 	CodeTBTC Code = "tbtc"
+	// CodeSBTC is Bitcoin Signet.
+	CodeSBTC Code = "sbtc"
+	// CodeTBTC4 is Bitcoin Testnet4.
+	CodeTBTC4 Code = "tbtc4"
 	// CodeRBTC is Bitcoin Regtest.
 	CodeRBTC Code = "rbtc"
 	// CodeLTC is Litecoin.
@@ -34,6 +38,14 @@ const (
 	CodeGOETH Code = "goeth"
 	// CodeSEPETH is Ethereum Sepolia.
 	CodeSEPETH Code = "sepeth"
+	// CodeARBETH is Ether on Arbitrum One.
+	CodeARBETH Code = "arbeth"
+	// CodeOPETH is Ether on OP Mainnet (Optimism).
+	CodeOPETH Code = "opeth"
+	// CodeBASEETH is Ether on Base.
+	CodeBASEETH Code = "baseeth"
+	// CodePOL is POL, the native token of Polygon PoS.
+	CodePOL Code = "pol"
 	// If you add coins, don't forget to update `testnetCoins` below.
 	// There are some more coin codes for the supported erc20 tokens in erc20.go.
 )
@@ -52,6 +64,8 @@ const (
 // TestnetCoins is the subset of all coins which are available in testnet mode.
 var TestnetCoins = map[Code]struct{}{
 	CodeTBTC:   {},
+	CodeSBTC:   {},
+	CodeTBTC4:  {},
 	CodeTLTC:   {},
 	CodeGOETH:  {},
 	CodeSEPETH: {},
@@ -39,7 +39,6 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/locker"
 	ethereum "github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -99,6 +98,13 @@ type Account struct {
 	// if not nil, SendTx() will sign and send this transaction. Set by TxProposal().
 	activeTxProposal *TxProposal
 
+	// ensLookupLock covers the ENS names lookup of the transactions, of which at most one runs at a
+	// time, and the addresses to look up next. See lookupENSNamesInBackground().
+	ensLookupLock       locker.Locker
+	ensLookupRunning    bool
+	ensLookupPending    []ethcommon.Address
+	ensLookupHasPending bool
+
 	// quitChan is used to send a quit signal to the accounts long running routines that
 	// should listen to it.
 	quitChan chan struct{}
@@ -213,11 +219,16 @@ func (account *Account) Initialize() error {
 		account.signingConfiguration.ExtendedPublicKey(),
 	)
 
+	// The notes are loaded before the first update, which records the fiat values of incoming
+	// transactions in them.
+	if err := account.BaseAccount.Initialize(accountIdentifier); err != nil {
+		return err
+	}
+
 	account.coin.Initialize()
 	done := account.Synchronizer.IncRequestsCounter()
 	go account.poll(done)
-
-	return account.BaseAccount.Initialize(accountIdentifier)
+	return nil
 }
 
 func (account *Account) poll(initDone func()) {
@@ -273,6 +284,10 @@ func (account *Account) updateOutgoingTransactions(tipHeight uint64) {
 	for idx, tx := range outgoingTransactions {
 		txLog := account.log.WithField("idx", idx)
 		remoteTx, err := account.coin.client.TransactionReceiptWithBlockNumber(context.TODO(), tx.Transaction.Hash())
+		if (remoteTx == nil || err != nil) && tx.Dropped() {
+			// Replaced transactions are not rebroadcast, the node would reject them anyway.
+			continue
+		}
 		if remoteTx == nil || err != nil {
 			// Transaction not found. This usually happens for pending transactions.
 			// In this case, check if the node actually knows about the transaction, and if not, re-broadcast.
@@ -304,6 +319,24 @@ func (account *Account) updateOutgoingTransactions(tipHeight uint64) {
 			}
 		}
 	}
+	// Only one transaction per nonce can be confirmed. The others are dropped, e.g. a speed-up if the
+	// original transaction was confirmed first.
+	confirmedNonces := map[uint64]ethcommon.Hash{}
+	for _, tx := range outgoingTransactions {
+		if tx.Height > 0 {
+			confirmedNonces[tx.Transaction.Nonce()] = tx.Transaction.Hash()
+		}
+	}
+	for _, tx := range outgoingTransactions {
+		confirmedHash, ok := confirmedNonces[tx.Transaction.Nonce()]
+		if !ok || tx.Height > 0 || (tx.ReplacedBy != nil && *tx.ReplacedBy == confirmedHash) {
+			continue
+		}
+		tx.ReplacedBy = &confirmedHash
+		if err := dbTx.PutOutgoingTransaction(tx); err != nil {
+			account.log.WithError(err).Error("could not update outgoing tx")
+		}
+	}
 	if err := dbTx.Commit(); err != nil {
 		account.log.WithError(err).Error("could not commit db tx")
 		return
@@ -398,11 +431,13 @@ func (account *Account) update() error {
 		)
 	}
 	account.transactions = append(outgoingTransactionsData, confirmedTansactions...)
+	account.lookupENSNamesInBackground(counterparties(account.transactions))
 	for _, transaction := range account.transactions {
 		if err := account.notifier.Put([]byte(transaction.TxID)); err != nil {
 			return err
 		}
 	}
+	account.RecordIncomingFiatValues(account.transactions)
 
 	var balance *big.Int
 	if account.coin.erc20Token != nil {
@@ -473,7 +508,8 @@ func (account *Account) Notifier() accounts.Notifier {
 // Transactions implements accounts.Interface.
 func (account *Account) Transactions() (accounts.OrderedTransactions, error) {
 	account.Synchronizer.WaitSynchronized()
-	return accounts.NewOrderedTransactions(account.transactions), nil
+	txs := accounts.NewOrderedTransactions(account.transactions)
+	return account.annotateENSNames(account.Annotate(txs)), nil
 }
 
 // Balance implements accounts.Interface.
@@ -490,6 +526,12 @@ type TxProposal struct {
 	// Value can be the same as Tx.Value(), but in case of e.g. ERC20, tx.Value() is zero, while the
 	// Token value is encoded in the contract input data.
 	Value *big.Int
+	// Recipient is the recipient of the coins, which is the resolved address if an ENS name was
+	// entered. It is nil for replacement and approval transactions.
+	Recipient *ethcommon.Address
+	// Replaces is the hash of the pending transaction with the same nonce this transaction replaces,
+	// or nil. See ReplaceTxProposal() and the Nonce of accounts.TxProposalArgs.
+	Replaces *ethcommon.Hash
 	// Signer contains the sighash algo, which depends on the block number.
 	Signer types.Signer
 	// KeyPath is the location of this account's address/pubkey/privkey.
@@ -497,10 +539,10 @@ type TxProposal struct {
 }
 
 func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error) {
-	if !IsValidEthAddress(args.RecipientAddress) {
-		return nil, errp.WithStack(errors.ErrInvalidAddress)
+	address, err := account.resolveRecipient(args.RecipientAddress)
+	if err != nil {
+		return nil, err
 	}
-	address := ethcommon.HexToAddress(args.RecipientAddress)
 
 	suggestedGasFeeCap, suggestedGasTipCap, err := account.gasFees(args)
 	if err != nil {
@@ -514,6 +556,14 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error
 	if !account.Synced() {
 		return nil, errp.WithStack(errors.ErrAccountNotsynced)
 	}
+	if len(args.Data) > 0 && account.coin.erc20Token != nil {
+		return nil, errp.WithStack(errors.ErrInvalidData)
+	}
+
+	nonce, replaces, err := account.txNonce(args, suggestedGasFeeCap, suggestedGasTipCap)
+	if err != nil {
+		return nil, err
+	}
 
 	var value *big.Int
 	if args.Amount.SendAll() {
@@ -531,13 +581,9 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error
 	var message ethereum.CallMsg
 
 	if account.coin.erc20Token != nil {
-		parsed, err := abi.JSON(strings.NewReader(erc20.IERC20ABI))
-		if err != nil {
-			panic(errp.WithStack(err))
-		}
-		erc20ContractData, err := parsed.Pack("transfer", &address, value)
+		erc20ContractData, err := packERC20Call("transfer", &address, value)
 		if err != nil {
-			panic(errp.WithStack(err))
+			return nil, err
 		}
 		contractAddress := account.coin.erc20Token.ContractAddress()
 		message = ethereum.CallMsg{
@@ -557,6 +603,7 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error
 			Gas:      0,
 			GasPrice: big.NewInt(0),
 			Value:    value,
+			Data:     args.Data,
 		}
 	}
 
@@ -570,17 +617,11 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error
 			}
 		}
 	}
-	gasLimit, err := account.coin.client.EstimateGas(context.TODO(), message)
+	gasLimit, fee, err := account.txGas(args, message, nonce, suggestedGasFeeCap, suggestedGasTipCap)
 	if err != nil {
-		if strings.Contains(err.Error(), etherscan.ERC20GasErr) {
-			return nil, errp.WithStack(errors.ErrInsufficientFunds)
-		}
-		account.log.WithError(err).Error("Could not estimate the gas limit.")
-		return nil, errp.WithStack(errors.TxValidationError(err.Error()))
+		return nil, err
 	}
 
-	fee := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), suggestedGasFeeCap)
-
 	// Adjust amount with fee
 	if account.coin.erc20Token != nil {
 		// in erc 20 tokens, the amount is in the token unit, while the fee is in ETH, so there is
@@ -606,49 +647,124 @@ func (account *Account) newTx(args *accounts.TxProposalArgs) (*TxProposal, error
 		}
 	}
 
-	keystore, err := account.Config().ConnectKeystore()
+	tx, err := account.buildTx(message, nonce, gasLimit, suggestedGasFeeCap, suggestedGasTipCap)
 	if err != nil {
 		return nil, err
 	}
 
-	var tx *types.Transaction
+	return &TxProposal{
+		Coin:      account.coin,
+		Tx:        tx,
+		Fee:       fee,
+		Value:     value,
+		Recipient: &address,
+		Replaces:  replaces,
+		Signer:    types.NewLondonSigner(account.coin.net.ChainID),
+		Keypath:   account.signingConfiguration.AbsoluteKeypath(),
+	}, nil
+}
 
+// packERC20Call encodes a call of a method of an ERC20 token contract.
+func packERC20Call(method string, args ...interface{}) ([]byte, error) {
+	parsed, err := erc20.IERC20MetaData.GetAbi()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	data, err := parsed.Pack(method, args...)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return data, nil
+}
+
+// txGas returns the gas limit of the call, which is estimated unless it is given in
+// `args.GasLimit`, and the fee of the transaction including the L1 data fee.
+func (account *Account) txGas(
+	args *accounts.TxProposalArgs,
+	message ethereum.CallMsg,
+	nonce uint64,
+	gasFeeCap, gasTipCap *big.Int,
+) (uint64, *big.Int, error) {
+	var gasLimit uint64
+	if args.GasLimit != 0 {
+		// Not estimated, e.g. because the estimation fails for a contract call that would revert
+		// in the current state of the chain.
+		if args.GasLimit < intrinsicGas(message.Data) {
+			return 0, nil, errp.WithStack(errors.ErrGasLimitTooLow)
+		}
+		gasLimit = args.GasLimit
+	} else {
+		var err error
+		gasLimit, err = account.coin.client.EstimateGas(context.TODO(), message)
+		if err != nil {
+			if strings.Contains(err.Error(), etherscan.ERC20GasErr) {
+				return 0, nil, errp.WithStack(errors.ErrInsufficientFunds)
+			}
+			account.log.WithError(err).Error("Could not estimate the gas limit.")
+			return 0, nil, errp.WithStack(errors.TxValidationError(err.Error()))
+		}
+	}
+
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasFeeCap)
+	// The L1 data fee depends on the size of the transaction, which does not change noticeably
+	// when the value is adjusted for SendAll.
+	l1DataFee, err := account.coin.l1DataFee(context.TODO(), types.NewTx(&types.DynamicFeeTx{
+		ChainID:   account.coin.net.ChainID,
+		Nonce:     nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       gasLimit,
+		To:        message.To,
+		Value:     message.Value,
+		Data:      message.Data,
+	}))
+	if err != nil {
+		account.log.WithError(err).Error("Could not get the L1 data fee.")
+		return 0, nil, errp.WithStack(errors.ErrFeesNotAvailable)
+	}
+	fee.Add(fee, l1DataFee)
+	return gasLimit, fee, nil
+}
+
+// buildTx builds the transaction of the call. It is an EIP-1559 transaction if the keystore
+// supports it, and a legacy transaction otherwise.
+func (account *Account) buildTx(
+	message ethereum.CallMsg,
+	nonce uint64,
+	gasLimit uint64,
+	gasFeeCap, gasTipCap *big.Int,
+) (*types.Transaction, error) {
+	keystore, err := account.Config().ConnectKeystore()
+	if err != nil {
+		return nil, err
+	}
 	if keystore.SupportsEIP1559() {
-		txData := &types.DynamicFeeTx{
-			Nonce:     account.nextNonce,
-			GasTipCap: suggestedGasTipCap,
-			GasFeeCap: suggestedGasFeeCap,
+		return types.NewTx(&types.DynamicFeeTx{
+			Nonce:     nonce,
+			GasTipCap: gasTipCap,
+			GasFeeCap: gasFeeCap,
 			Gas:       gasLimit,
 			To:        message.To,
 			Value:     message.Value,
 			Data:      message.Data,
-		}
-		tx = types.NewTx(txData)
-	} else {
-		tx = types.NewTransaction(
-			account.nextNonce,
-			*message.To,
-			message.Value,
-			gasLimit,
-			// use the maxFeePerGas (aka gasFeeCap) as gasPrice for legacy transactions
-			// the estimated maxFeePerGas is base fee + priority fee, and so is the appropriate
-			// legacy gasPrice setting for current network conditions
-			suggestedGasFeeCap,
-			message.Data)
+		}), nil
 	}
-
-	return &TxProposal{
-		Coin:    account.coin,
-		Tx:      tx,
-		Fee:     fee,
-		Value:   value,
-		Signer:  types.NewLondonSigner(account.coin.net.ChainID),
-		Keypath: account.signingConfiguration.AbsoluteKeypath(),
-	}, nil
+	return types.NewTransaction(
+		nonce,
+		*message.To,
+		message.Value,
+		gasLimit,
+		// use the maxFeePerGas (aka gasFeeCap) as gasPrice for legacy transactions
+		// the estimated maxFeePerGas is base fee + priority fee, and so is the appropriate
+		// legacy gasPrice setting for current network conditions
+		gasFeeCap,
+		message.Data), nil
 }
 
-// storePendingOutgoingTransaction puts an outgoing tx into the db with height 0 (pending).
-func (account *Account) storePendingOutgoingTransaction(transaction *types.Transaction) error {
+// storePendingOutgoingTransaction puts an outgoing tx into the db with height 0 (pending). If
+// replaces is not nil, the stored outgoing tx with this hash is marked as replaced.
+func (account *Account) storePendingOutgoingTransaction(
+	transaction *types.Transaction, replaces *ethcommon.Hash) error {
 	dbTx, err := account.db.Begin()
 	if err != nil {
 		return err
@@ -661,6 +777,22 @@ func (account *Account) storePendingOutgoingTransaction(transaction *types.Trans
 		}); err != nil {
 		return err
 	}
+	if replaces != nil {
+		outgoingTransactions, err := dbTx.OutgoingTransactions()
+		if err != nil {
+			return err
+		}
+		for _, tx := range outgoingTransactions {
+			if tx.Transaction.Hash() != *replaces {
+				continue
+			}
+			replacedBy := transaction.Hash()
+			tx.ReplacedBy = &replacedBy
+			if err := dbTx.PutOutgoingTransaction(tx); err != nil {
+				return err
+			}
+		}
+	}
 	if err := dbTx.Commit(); err != nil {
 		return err
 	}
@@ -692,7 +824,7 @@ func (account *Account) SendTx() error {
 	if err := account.coin.client.SendTransaction(context.TODO(), txProposal.Tx); err != nil {
 		return errp.WithStack(err)
 	}
-	if err := account.storePendingOutgoingTransaction(txProposal.Tx); err != nil {
+	if err := account.storePendingOutgoingTransaction(txProposal.Tx, txProposal.Replaces); err != nil {
 		return err
 	}
 
@@ -701,12 +833,18 @@ func (account *Account) SendTx() error {
 		// Not critical.
 		account.log.WithError(err).Error("Failed to save transaction note when sending a tx")
 	}
+	fiatValues := account.BaseAccount.GetAndClearProposedTxFiatValues()
+	if err := account.SetTxFiatValues(txProposal.Tx.Hash().Hex(), fiatValues); err != nil {
+		// Not critical.
+		account.log.WithError(err).Error("Failed to save the fiat values when sending a tx")
+	}
 	account.enqueueUpdateCh <- struct{}{}
 	return nil
 }
 
 // feeTargets returns three priorities with fee targets estimated by Etherscan
-// https://docs.etherscan.io/api-endpoints/gas-tracker#get-gas-oracle
+// https://docs.etherscan.io/api-endpoints/gas-tracker#get-gas-oracle, or from the fee history if
+// the client is a JSON-RPC node, see package feehistory.
 // If the service should not be reachable, we fallback to only one priority, estimated by
 // the ETH RPC eth_gasPrice endpoint.
 func (account *Account) feeTargets() []*ethtypes.FeeTarget {
@@ -775,6 +913,7 @@ func (account *Account) TxProposal(
 		return coin.Amount{}, coin.Amount{}, coin.Amount{}, err
 	}
 	account.activeTxProposal = txProposal
+	account.ProposeTxFiatValues(coin.NewAmount(txProposal.Value))
 
 	var total *big.Int
 	if account.coin.erc20Token != nil {
@@ -27,6 +27,8 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
 	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
@@ -48,17 +50,34 @@ func TestMain(m *testing.M) {
 }
 
 func newAccount(t *testing.T) *Account {
+	t.Helper()
+	return newAccountWithNet(t, params.GoerliChainConfig, nil)
+}
+
+// newAccountWithNet creates an account on the given network. client is used as the RPC client if
+// not nil.
+func newAccountWithNet(t *testing.T, net *params.ChainConfig, client rpcclient.Interface) *Account {
+	t.Helper()
+	if client == nil {
+		client = newClientMock()
+	}
+	return newAccountWithCoin(
+		t, NewCoin(client, coin.CodeGOETH, "Goerli", "GOETH", "GOETH", net, "", nil, nil))
+}
+
+// newAccountWithCoin creates an account of the given coin.
+func newAccountWithCoin(t *testing.T, coin *Coin) *Account {
 	t.Helper()
 	log := logging.Get().WithGroup("account_test")
 
-	net := &chaincfg.TestNet3Params
+	btcNet := &chaincfg.TestNet3Params
 
 	dbFolder := test.TstTempDir("eth-dbfolder")
 	defer func() { _ = os.RemoveAll(dbFolder) }()
 
 	keypath, err := signing.NewAbsoluteKeypath("m/60'/1'/0'/0")
 	require.NoError(t, err)
-	xpub, err := hdkeychain.NewMaster(make([]byte, 32), net)
+	xpub, err := hdkeychain.NewMaster(make([]byte, 32), btcNet)
 	require.NoError(t, err)
 	xpub, err = xpub.Neuter()
 	require.NoError(t, err)
@@ -68,21 +87,6 @@ func newAccount(t *testing.T) *Account {
 		keypath,
 		xpub)}
 
-	client := &mocks.InterfaceMock{
-		EstimateGasFunc: func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
-			return 21000, nil
-		},
-		BlockNumberFunc: func(ctx context.Context) (*big.Int, error) {
-			return big.NewInt(100), nil
-		},
-		BalanceFunc: func(ctx context.Context, account common.Address) (*big.Int, error) {
-			return big.NewInt(1e18), nil
-		},
-		PendingNonceAtFunc: func(ctx context.Context, account common.Address) (uint64, error) {
-			return 0, nil
-		},
-	}
-	coin := NewCoin(client, coin.CodeGOETH, "Goerli", "GOETH", "GOETH", params.GoerliChainConfig, "", nil, nil)
 	acct := NewAccount(
 		&accounts.AccountConfig{
 			Config: &config.Account{
@@ -112,6 +116,27 @@ func newAccount(t *testing.T) *Account {
 	return acct
 }
 
+// newClientMock returns an RPC client mock for an account with 1 ETH and no transactions.
+func newClientMock() *mocks.InterfaceMock {
+	return &mocks.InterfaceMock{
+		EstimateGasFunc: func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+			return 21000, nil
+		},
+		BlockNumberFunc: func(ctx context.Context) (*big.Int, error) {
+			return big.NewInt(100), nil
+		},
+		BalanceFunc: func(ctx context.Context, account common.Address) (*big.Int, error) {
+			return big.NewInt(1e18), nil
+		},
+		PendingNonceAtFunc: func(ctx context.Context, account common.Address) (uint64, error) {
+			return 0, nil
+		},
+		NonceAtFunc: func(ctx context.Context, account common.Address) (uint64, error) {
+			return 0, nil
+		},
+	}
+}
+
 func TestTxProposal(t *testing.T) {
 	acct := newAccount(t)
 	defer acct.Close()
@@ -205,3 +230,54 @@ func TestMatchesAddress(t *testing.T) {
 		require.Equal(t, err, nil)
 	})
 }
+
+// mockERC20Discoverer is a transactions source that reports fixed ERC20 token activity.
+type mockERC20Discoverer struct {
+	activity []*erc20.TokenActivity
+}
+
+func (m *mockERC20Discoverer) Transactions(
+	blockTipHeight *big.Int,
+	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*accounts.TransactionData, error) {
+	return nil, nil
+}
+
+func (m *mockERC20Discoverer) ERC20TokenActivity(address common.Address) ([]*erc20.TokenActivity, error) {
+	return m.activity, nil
+}
+
+func TestDiscoverERC20Tokens(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	// The transactions source does not support discovery.
+	_, err := acct.DiscoverERC20Tokens()
+	require.Error(t, err)
+
+	tokenA := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	tokenB := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	acct.coin.TstSetTransactionsSource(&mockERC20Discoverer{activity: []*erc20.TokenActivity{
+		{ContractAddress: tokenA, Name: "A", Symbol: "AAA", Decimals: 6, Transfers: 2},
+		{ContractAddress: tokenB, Name: "B", Symbol: "BBB", Decimals: 18, Transfers: 1},
+	}})
+	acct.coin.TstSetClient(&mocks.InterfaceMock{
+		ERC20BalanceFunc: func(account common.Address, erc20Token *erc20.Token) (*big.Int, error) {
+			require.Equal(t, acct.address.Address, account)
+			if erc20Token.ContractAddress() == tokenB {
+				return nil, errp.New("failed")
+			}
+			require.Equal(t, uint(6), erc20Token.Decimals())
+			return big.NewInt(1000), nil
+		},
+	})
+	discovered, err := acct.DiscoverERC20Tokens()
+	require.NoError(t, err)
+	require.Len(t, discovered, 2)
+	require.Equal(t, tokenA, discovered[0].ContractAddress)
+	require.Equal(t, 2, discovered[0].Transfers)
+	require.Equal(t, big.NewInt(1000), discovered[0].Balance)
+	require.Equal(t, "BBB", discovered[1].Symbol)
+	require.Nil(t, discovered[1].Balance)
+}
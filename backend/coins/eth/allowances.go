@@ -0,0 +1,231 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/decoder"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ERC20ApprovalLister can be implemented by a TransactionsSource that can list the ERC20 token
+// approvals an address has given, e.g. from the `Approval` events of the token contracts.
+type ERC20ApprovalLister interface {
+	// ERC20Approvals returns the approvals with the address as owner, oldest first. A token and
+	// spender pair can appear multiple times if the allowance was changed.
+	ERC20Approvals(owner ethcommon.Address) ([]*erc20.Approval, error)
+}
+
+// ERC20Allowance is an outstanding allowance of the account, i.e. an amount of a token a spender
+// may transfer from the account.
+type ERC20Allowance struct {
+	ContractAddress ethcommon.Address
+	Name            string
+	Symbol          string
+	Decimals        uint8
+	Spender         ethcommon.Address
+	// Amount is the current allowance in the smallest unit of the token, or nil if it could not be
+	// fetched.
+	Amount *big.Int
+	// Unlimited is true if the spender may transfer all tokens of the account, now and in the
+	// future.
+	Unlimited bool
+}
+
+// ERC20Allowances lists the allowances the account has given in all token contracts. Allowances
+// that were revoked or used up are not included.
+func (account *Account) ERC20Allowances() ([]*ERC20Allowance, error) {
+	if IsERC20(account) {
+		return nil, errp.New("allowances can only be listed for Ethereum accounts")
+	}
+	address, err := account.Address()
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := account.coin.transactionsSource.(ERC20ApprovalLister)
+	if !ok {
+		return nil, errp.Newf("listing allowances is not supported for %s", account.coin.code)
+	}
+	approvals, err := lister.ERC20Approvals(address.Address)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.TODO()
+	metadataByContract := map[ethcommon.Address]*erc20.Metadata{}
+	seen := map[erc20.Approval]bool{}
+	result := []*ERC20Allowance{}
+	for _, approval := range approvals {
+		if seen[*approval] {
+			continue
+		}
+		seen[*approval] = true
+		metadata, ok := metadataByContract[approval.ContractAddress]
+		if !ok {
+			metadata, err = account.coin.ERC20Metadata(ctx, approval.ContractAddress)
+			if err != nil {
+				// Not a valid token, e.g. an NFT contract.
+				account.log.WithError(err).Infof("Skipping contract %s", approval.ContractAddress.Hex())
+			}
+			metadataByContract[approval.ContractAddress] = metadata
+		}
+		if metadata == nil {
+			continue
+		}
+		allowance := &ERC20Allowance{
+			ContractAddress: approval.ContractAddress,
+			Name:            metadata.Name,
+			Symbol:          metadata.Symbol,
+			Decimals:        metadata.Decimals,
+			Spender:         approval.Spender,
+		}
+		amount, err := erc20.Allowance(
+			ctx, account.coin.client, approval.ContractAddress, address.Address, approval.Spender)
+		if err != nil {
+			// Shown without an amount, as the allowance may still be outstanding.
+			account.log.WithError(err).Errorf(
+				"Could not fetch the allowance of token %s", approval.ContractAddress.Hex())
+			result = append(result, allowance)
+			continue
+		}
+		if amount.Sign() == 0 {
+			continue
+		}
+		allowance.Amount = amount
+		allowance.Unlimited = decoder.IsUnlimited(amount)
+		result = append(result, allowance)
+	}
+	return result, nil
+}
+
+// ApproveTxArgs are the arguments to change the allowance of a spender, see ApproveTxProposal().
+type ApproveTxArgs struct {
+	// ContractAddress is the address of the token contract.
+	ContractAddress string `json:"contractAddress"`
+	Spender         string `json:"spender"`
+	// Amount is the new allowance in the unit of the token, e.g. "1.5". "0" revokes the allowance.
+	Amount        string                 `json:"amount"`
+	FeeTargetCode accounts.FeeTargetCode `json:"feeTarget"`
+	// CustomFee is the fee in Gwei if FeeTargetCode is `FeeTargetCodeCustom`.
+	CustomFee string `json:"customFee"`
+	// Nonce and GasLimit are optional overrides, see accounts.TxProposalArgs.
+	Nonce    *uint64 `json:"-"`
+	GasLimit uint64  `json:"-"`
+}
+
+// newApproveTx creates a transaction calling `approve()` of the token contract to set the
+// allowance of the spender. Some tokens, e.g. USDT, only allow changing a non-zero allowance by
+// revoking it first, in which case the gas estimation fails.
+func (account *Account) newApproveTx(args *ApproveTxArgs) (*TxProposal, error) {
+	if IsERC20(account) {
+		return nil, errp.New("allowances can only be changed from Ethereum accounts")
+	}
+	if !IsValidEthAddress(args.ContractAddress) || !IsValidEthAddress(args.Spender) {
+		return nil, errp.WithStack(errors.ErrInvalidAddress)
+	}
+	contractAddress := ethcommon.HexToAddress(args.ContractAddress)
+	spender := ethcommon.HexToAddress(args.Spender)
+
+	txArgs := &accounts.TxProposalArgs{
+		FeeTargetCode: args.FeeTargetCode,
+		CustomFee:     args.CustomFee,
+		Nonce:         args.Nonce,
+		GasLimit:      args.GasLimit,
+	}
+	suggestedGasFeeCap, suggestedGasTipCap, err := account.gasFees(txArgs)
+	if err != nil {
+		if _, ok := errp.Cause(err).(errors.TxValidationError); ok {
+			return nil, err
+		}
+		account.log.WithError(err).Error("error getting the gas price")
+		return nil, errp.WithStack(errors.ErrFeesNotAvailable)
+	}
+
+	if !account.Synced() {
+		return nil, errp.WithStack(errors.ErrAccountNotsynced)
+	}
+
+	nonce, replaces, err := account.txNonce(txArgs, suggestedGasFeeCap, suggestedGasTipCap)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := account.coin.ERC20Metadata(context.TODO(), contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := coin.NewAmountFromString(
+		args.Amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(metadata.Decimals)), nil))
+	if err != nil || amount.BigInt().Sign() < 0 || amount.BigInt().BitLen() > 256 {
+		return nil, errp.WithStack(errors.ErrInvalidAmount)
+	}
+
+	data, err := packERC20Call("approve", spender, amount.BigInt())
+	if err != nil {
+		return nil, err
+	}
+	message := ethereum.CallMsg{
+		From: account.address.Address,
+		To:   &contractAddress,
+		Gas:  0,
+		// Gas price has to be 0 for the Etherscan EstimateGas call to succeed.
+		GasPrice: big.NewInt(0),
+		Value:    big.NewInt(0),
+		Data:     data,
+	}
+	gasLimit, fee, err := account.txGas(txArgs, message, nonce, suggestedGasFeeCap, suggestedGasTipCap)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Cmp(account.balance.BigInt()) > 0 {
+		return nil, errp.WithStack(errors.ErrInsufficientFunds)
+	}
+
+	tx, err := account.buildTx(message, nonce, gasLimit, suggestedGasFeeCap, suggestedGasTipCap)
+	if err != nil {
+		return nil, err
+	}
+	return &TxProposal{
+		Coin:     account.coin,
+		Tx:       tx,
+		Fee:      fee,
+		Value:    big.NewInt(0),
+		Replaces: replaces,
+		Signer:   types.NewLondonSigner(account.coin.net.ChainID),
+		Keypath:  account.signingConfiguration.AbsoluteKeypath(),
+	}, nil
+}
+
+// ApproveTxProposal proposes a transaction setting the allowance of a spender of one of the
+// account's tokens, e.g. to revoke an unlimited approval. It is sent with SendTx(). The returned
+// values are the same as the ones of TxProposal(). The amount is zero, as no ETH is sent.
+func (account *Account) ApproveTxProposal(args *ApproveTxArgs) (coin.Amount, coin.Amount, coin.Amount, error) {
+	defer account.updateLock.Lock()()
+	txProposal, err := account.newApproveTx(args)
+	if err != nil {
+		return coin.Amount{}, coin.Amount{}, coin.Amount{}, err
+	}
+	account.activeTxProposal = txProposal
+	account.ProposeTxFiatValues(coin.NewAmount(txProposal.Value))
+	return coin.NewAmount(txProposal.Value), coin.NewAmount(txProposal.Fee), coin.NewAmount(txProposal.Fee), nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// mockERC20ApprovalLister is a transactions source that reports fixed approvals.
+type mockERC20ApprovalLister struct {
+	approvals []*erc20.Approval
+}
+
+func (m *mockERC20ApprovalLister) Transactions(
+	blockTipHeight *big.Int,
+	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*accounts.TransactionData, error) {
+	return nil, nil
+}
+
+func (m *mockERC20ApprovalLister) ERC20Approvals(owner common.Address) ([]*erc20.Approval, error) {
+	return m.approvals, nil
+}
+
+func TestERC20Allowances(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	// The transactions source does not support listing approvals.
+	_, err := acct.ERC20Allowances()
+	require.Error(t, err)
+
+	token := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	nft := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	router := common.HexToAddress("0x0000000000000000000000000000000000000003")
+	revoked := common.HexToAddress("0x0000000000000000000000000000000000000004")
+	broken := common.HexToAddress("0x0000000000000000000000000000000000000005")
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	parsed, err := abi.JSON(strings.NewReader(erc20.IERC20ABI))
+	require.NoError(t, err)
+	allowance := parsed.Methods["allowance"]
+	acct.coin.TstSetClient(&mocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			if *msg.To != token {
+				return nil, nil
+			}
+			if bytes.HasPrefix(msg.Data, allowance.ID) {
+				args, err := allowance.Inputs.Unpack(msg.Data[4:])
+				require.NoError(t, err)
+				require.Equal(t, acct.address.Address, args[0])
+				switch args[1] {
+				case router:
+					return allowance.Outputs.Pack(maxUint256)
+				case revoked:
+					return allowance.Outputs.Pack(big.NewInt(0))
+				case broken:
+					return nil, errp.New("failed")
+				}
+				return allowance.Outputs.Pack(big.NewInt(2500000))
+			}
+			for name, value := range map[string]interface{}{
+				"name":     "Token",
+				"symbol":   "TKN",
+				"decimals": uint8(6),
+			} {
+				method := parsed.Methods[name]
+				if bytes.Equal(msg.Data, method.ID) {
+					return method.Outputs.Pack(value)
+				}
+			}
+			return nil, errp.New("unexpected call")
+		},
+	})
+	acct.coin.TstSetTransactionsSource(&mockERC20ApprovalLister{approvals: []*erc20.Approval{
+		{ContractAddress: token, Spender: router},
+		{ContractAddress: nft, Spender: router},
+		{ContractAddress: token, Spender: revoked},
+		{ContractAddress: token, Spender: router},
+		{ContractAddress: token, Spender: broken},
+		{ContractAddress: token, Spender: acct.address.Address},
+	}})
+
+	allowances, err := acct.ERC20Allowances()
+	require.NoError(t, err)
+	require.Equal(t, []*ERC20Allowance{
+		{
+			ContractAddress: token, Name: "Token", Symbol: "TKN", Decimals: 6,
+			Spender: router, Amount: maxUint256, Unlimited: true,
+		},
+		{
+			ContractAddress: token, Name: "Token", Symbol: "TKN", Decimals: 6,
+			Spender: broken,
+		},
+		{
+			ContractAddress: token, Name: "Token", Symbol: "TKN", Decimals: 6,
+			Spender: acct.address.Address, Amount: big.NewInt(2500000),
+		},
+	}, allowances)
+}
+
+func TestApproveTxProposal(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	token := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	spender := common.HexToAddress("0x0000000000000000000000000000000000000003")
+	parsed, err := abi.JSON(strings.NewReader(erc20.IERC20ABI))
+	require.NoError(t, err)
+	var estimated ethereum.CallMsg
+	acct.coin.TstSetClient(&mocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			decimals := parsed.Methods["decimals"]
+			if *msg.To == token && bytes.Equal(msg.Data, decimals.ID) {
+				return decimals.Outputs.Pack(uint8(6))
+			}
+			return nil, nil
+		},
+		EstimateGasFunc: func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+			estimated = call
+			return 50000, nil
+		},
+	})
+
+	args := &ApproveTxArgs{
+		ContractAddress: token.Hex(),
+		Spender:         spender.Hex(),
+		Amount:          "0",
+		FeeTargetCode:   accounts.FeeTargetCodeCustom,
+		CustomFee:       "10",
+	}
+	value, fee, total, err := acct.ApproveTxProposal(args)
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(0), value)
+	require.Equal(t, coin.NewAmountFromInt64(50000*10e9), fee)
+	require.Equal(t, fee, total)
+	tx := acct.activeTxProposal.Tx
+	require.Equal(t, token, *tx.To())
+	require.Equal(t, big.NewInt(0), tx.Value())
+	require.Equal(t, uint64(50000), tx.Gas())
+	expectedData, err := parsed.Pack("approve", spender, big.NewInt(0))
+	require.NoError(t, err)
+	require.Equal(t, expectedData, tx.Data())
+	require.Equal(t, expectedData, estimated.Data)
+
+	// Reduce the allowance, in the unit of the token.
+	args.Amount = "1.5"
+	_, _, _, err = acct.ApproveTxProposal(args)
+	require.NoError(t, err)
+	expectedData, err = parsed.Pack("approve", spender, big.NewInt(1500000))
+	require.NoError(t, err)
+	require.Equal(t, expectedData, acct.activeTxProposal.Tx.Data())
+
+	args.Amount = "-1"
+	_, _, _, err = acct.ApproveTxProposal(args)
+	require.Equal(t, errors.ErrInvalidAmount, errp.Cause(err))
+
+	args.Amount = "0"
+	args.Spender = "0x1234"
+	_, _, _, err = acct.ApproveTxProposal(args)
+	require.Equal(t, errors.ErrInvalidAddress, errp.Cause(err))
+
+	args.Spender = spender.Hex()
+	args.CustomFee = "100000"
+	_, _, _, err = acct.ApproveTxProposal(args)
+	require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
+
+	// The gas limit and nonce overrides apply like when sending.
+	args.CustomFee = "10"
+	args.GasLimit = 80000
+	estimated = ethereum.CallMsg{}
+	_, fee, _, err = acct.ApproveTxProposal(args)
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(80000*10e9), fee)
+	require.Equal(t, uint64(80000), acct.activeTxProposal.Tx.Gas())
+	require.Nil(t, estimated.Data)
+
+	args.GasLimit = 1000
+	_, _, _, err = acct.ApproveTxProposal(args)
+	require.Equal(t, errors.ErrGasLimitTooLow, errp.Cause(err))
+
+	args.GasLimit = 0
+	nonce := acct.nextNonce + 1
+	args.Nonce = &nonce
+	_, _, _, err = acct.ApproveTxProposal(args)
+	require.Equal(t, errors.ErrNonceGap, errp.Cause(err))
+}
@@ -16,12 +16,14 @@
 package eth
 
 import (
+	"context"
 	"math/big"
 	"strings"
 
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/ens"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
@@ -55,6 +57,9 @@ type Coin struct {
 
 	transactionsSource TransactionsSource
 
+	// ensNames caches the primary ENS names of the counterparties of transactions.
+	ensNames *ens.NameCache
+
 	log *logrus.Entry
 }
 
@@ -87,6 +92,8 @@ func NewCoin(
 
 		erc20Token: erc20Token,
 
+		ensNames: ens.NewNameCache(ensNamesTTL),
+
 		log: logging.Get().WithGroup("coin").WithField("code", code),
 	}
 }
@@ -206,6 +213,12 @@ func (coin *Coin) ERC20Token() *erc20.Token {
 	return coin.erc20Token
 }
 
+// ERC20Metadata reads the name, symbol and decimals of the ERC20 token contract at the given
+// address on chain.
+func (coin *Coin) ERC20Metadata(ctx context.Context, contractAddress common.Address) (*erc20.Metadata, error) {
+	return erc20.FetchMetadata(ctx, coin.client, contractAddress)
+}
+
 // Close implements coin.Coin.
 func (coin *Coin) Close() error {
 	// TODO: shut down rpc connection.
@@ -0,0 +1,345 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package decoder interprets the calldata of Ethereum transactions and EIP-712 typed messages
+// proposed by dapps, so that the user can see what they are about to sign.
+package decoder
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Action is the kind of a decoded transaction or typed message.
+type Action string
+
+const (
+	// ActionSend is a transfer of the native coin without calldata.
+	ActionSend Action = "send"
+	// ActionTransfer is a transfer of ERC20 tokens, or of an ERC721 token for transferFrom, which
+	// has the same signature.
+	ActionTransfer Action = "transfer"
+	// ActionApprove allows the spender to transfer tokens of the user, either via the token
+	// contract or via Permit2.
+	ActionApprove Action = "approve"
+	// ActionApproveAll allows the spender to transfer all NFTs of the user in a collection.
+	ActionApproveAll Action = "approveAll"
+	// ActionNFTTransfer is a transfer of ERC721 or ERC1155 tokens.
+	ActionNFTTransfer Action = "nftTransfer"
+	// ActionPermit is a signed approval (EIP-2612 or Permit2), which anyone can submit on chain.
+	ActionPermit Action = "permit"
+	// ActionSwap is a trade on a DEX router.
+	ActionSwap Action = "swap"
+	// ActionUnknown is a contract call or typed message that is not recognized.
+	ActionUnknown Action = "unknown"
+)
+
+// Summary is the interpretation of a transaction or typed message. Fields that do not apply to the
+// action are nil.
+type Summary struct {
+	Action Action
+	// Method is the name of the called function or the primary type of the typed message, if
+	// known.
+	Method string
+	// Contract is the called contract or the contract verifying the typed message.
+	Contract *common.Address
+	// Token is the token contract the action is about. For swaps, it is the token sold, nil if the
+	// native coin is sold.
+	Token *common.Address
+	// Amount is the amount of Token in its smallest unit. For swaps, it is the amount sold, or the
+	// maximum amount sold.
+	Amount *big.Int
+	// TokenID identifies the NFT for NFT transfers.
+	TokenID   *big.Int
+	Recipient *common.Address
+	Spender   *common.Address
+	// Unlimited is true if the spender is allowed to spend an unlimited amount, or all NFTs.
+	Unlimited bool
+	// Expiration is the unix timestamp after which a permit or Permit2 approval expires.
+	Expiration *big.Int
+	// TokenOut is the token bought in swaps, nil if the native coin is bought or unknown.
+	TokenOut *common.Address
+	// AmountOut is the amount of TokenOut bought, or the minimum amount bought.
+	AmountOut *big.Int
+	// Tokens is the number of tokens in batch permits and batch NFT transfers, in which case Amount
+	// and TokenID are nil.
+	Tokens int
+}
+
+// unlimitedBits is the bit length from which an allowance is considered unlimited. Dapps usually
+// approve the maximum uint256 or, for Permit2, the maximum uint160, which no token supply comes
+// close to.
+const unlimitedBits = 160
+
+// IsUnlimited returns true if the allowance is so large that it is effectively unlimited.
+func IsUnlimited(amount *big.Int) bool {
+	return amount != nil && amount.BitLen() >= unlimitedBits
+}
+
+const erc20ABI = `[
+{"name":"transfer","type":"function","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+{"name":"transferFrom","type":"function","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+{"name":"approve","type":"function","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}]},
+{"name":"increaseAllowance","type":"function","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}]}
+]`
+
+// ERC721 and ERC1155. Overloaded functions are named with a suffix by the abi package, e.g.
+// safeTransferFrom0.
+const nftABI = `[
+{"name":"setApprovalForAll","type":"function","inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}]},
+{"name":"safeTransferFrom","type":"function","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}]},
+{"name":"safeTransferFrom","type":"function","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"data","type":"bytes"}]},
+{"name":"safeTransferFrom","type":"function","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"}]},
+{"name":"safeBatchTransferFrom","type":"function","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"ids","type":"uint256[]"},{"name":"amounts","type":"uint256[]"},{"name":"data","type":"bytes"}]}
+]`
+
+// https://github.com/Uniswap/permit2/blob/main/src/interfaces/IAllowanceTransfer.sol
+const permit2ABI = `[
+{"name":"approve","type":"function","inputs":[{"name":"token","type":"address"},{"name":"spender","type":"address"},{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"}]}
+]`
+
+// Uniswap V2 compatible routers, which are also deployed by Sushiswap, Pancakeswap and others, and
+// the Uniswap Universal Router.
+const routerABI = `[
+{"name":"swapExactTokensForTokens","type":"function","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"name":"swapTokensForExactTokens","type":"function","inputs":[{"name":"amountOut","type":"uint256"},{"name":"amountInMax","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"name":"swapExactETHForTokens","type":"function","inputs":[{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"name":"swapETHForExactTokens","type":"function","inputs":[{"name":"amountOut","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"name":"swapExactTokensForETH","type":"function","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"name":"swapTokensForExactETH","type":"function","inputs":[{"name":"amountOut","type":"uint256"},{"name":"amountInMax","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"name":"execute","type":"function","inputs":[{"name":"commands","type":"bytes"},{"name":"inputs","type":"bytes[]"},{"name":"deadline","type":"uint256"}]},
+{"name":"execute","type":"function","inputs":[{"name":"commands","type":"bytes"},{"name":"inputs","type":"bytes[]"}]}
+]`
+
+// arguments are the unpacked arguments of a function call.
+type arguments []interface{}
+
+func (args arguments) address(i int) *common.Address {
+	if i >= len(args) {
+		return nil
+	}
+	address, ok := args[i].(common.Address)
+	if !ok {
+		return nil
+	}
+	return &address
+}
+
+func (args arguments) number(i int) *big.Int {
+	if i >= len(args) {
+		return nil
+	}
+	number, _ := args[i].(*big.Int)
+	return number
+}
+
+// path returns the first and the last token of a swap path.
+func (args arguments) path(i int) (*common.Address, *common.Address) {
+	if i >= len(args) {
+		return nil, nil
+	}
+	path, ok := args[i].([]common.Address)
+	if !ok || len(path) == 0 {
+		return nil, nil
+	}
+	return &path[0], &path[len(path)-1]
+}
+
+// standard is a set of functions and how to interpret calls to them. decode returns nil if the
+// call can't be interpreted.
+type standard struct {
+	abi    abi.ABI
+	decode func(method string, args arguments, to common.Address, value *big.Int) *Summary
+}
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(errp.WithStack(err))
+	}
+	return parsed
+}
+
+var standards = []standard{
+	{abi: mustParseABI(erc20ABI), decode: decodeERC20},
+	{abi: mustParseABI(nftABI), decode: decodeNFT},
+	{abi: mustParseABI(permit2ABI), decode: decodePermit2},
+	{abi: mustParseABI(routerABI), decode: decodeRouter},
+}
+
+// DecodeTx interprets a transaction calling `to` with the given native value and calldata. Calls
+// that are not recognized are reported as ActionUnknown with the called contract.
+func DecodeTx(to common.Address, value *big.Int, data []byte) *Summary {
+	if len(data) == 0 {
+		return &Summary{Action: ActionSend, Recipient: &to, Amount: value}
+	}
+	summary := decodeCall(to, value, data)
+	if summary == nil {
+		summary = &Summary{Action: ActionUnknown}
+	}
+	summary.Contract = &to
+	return summary
+}
+
+func decodeCall(to common.Address, value *big.Int, data []byte) *Summary {
+	if len(data) < 4 {
+		return nil
+	}
+	if summary := decodeExactInputSingle(data); summary != nil {
+		return summary
+	}
+	for _, standard := range standards {
+		method, err := standard.abi.MethodById(data[:4])
+		if err != nil {
+			continue
+		}
+		args, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil
+		}
+		summary := standard.decode(method.Name, args, to, value)
+		if summary != nil {
+			summary.Method = method.RawName
+		}
+		return summary
+	}
+	return nil
+}
+
+func decodeERC20(method string, args arguments, to common.Address, value *big.Int) *Summary {
+	switch method {
+	case "transfer":
+		return &Summary{Action: ActionTransfer, Token: &to, Recipient: args.address(0), Amount: args.number(1)}
+	case "transferFrom":
+		return &Summary{Action: ActionTransfer, Token: &to, Recipient: args.address(1), Amount: args.number(2)}
+	case "approve", "increaseAllowance":
+		return &Summary{
+			Action:    ActionApprove,
+			Token:     &to,
+			Spender:   args.address(0),
+			Amount:    args.number(1),
+			Unlimited: IsUnlimited(args.number(1)),
+		}
+	}
+	return nil
+}
+
+func decodeNFT(method string, args arguments, to common.Address, value *big.Int) *Summary {
+	switch method {
+	case "setApprovalForAll":
+		approved, _ := args[1].(bool)
+		return &Summary{Action: ActionApproveAll, Token: &to, Spender: args.address(0), Unlimited: approved}
+	case "safeTransferFrom", "safeTransferFrom0":
+		return &Summary{Action: ActionNFTTransfer, Token: &to, Recipient: args.address(1), TokenID: args.number(2)}
+	case "safeTransferFrom1":
+		return &Summary{
+			Action:    ActionNFTTransfer,
+			Token:     &to,
+			Recipient: args.address(1),
+			TokenID:   args.number(2),
+			Amount:    args.number(3),
+		}
+	case "safeBatchTransferFrom":
+		ids, _ := args[2].([]*big.Int)
+		return &Summary{Action: ActionNFTTransfer, Token: &to, Recipient: args.address(1), Tokens: len(ids)}
+	}
+	return nil
+}
+
+func decodePermit2(method string, args arguments, to common.Address, value *big.Int) *Summary {
+	return &Summary{
+		Action:     ActionApprove,
+		Token:      args.address(0),
+		Spender:    args.address(1),
+		Amount:     args.number(2),
+		Unlimited:  IsUnlimited(args.number(2)),
+		Expiration: args.number(3),
+	}
+}
+
+func decodeRouter(method string, args arguments, to common.Address, value *big.Int) *Summary {
+	summary := &Summary{Action: ActionSwap}
+	switch method {
+	case "swapExactTokensForTokens", "swapExactTokensForETH":
+		summary.Token, summary.TokenOut = args.path(2)
+		summary.Amount, summary.AmountOut = args.number(0), args.number(1)
+		summary.Recipient = args.address(3)
+	case "swapTokensForExactTokens", "swapTokensForExactETH":
+		summary.Token, summary.TokenOut = args.path(2)
+		summary.Amount, summary.AmountOut = args.number(1), args.number(0)
+		summary.Recipient = args.address(3)
+	case "swapExactETHForTokens", "swapETHForExactTokens":
+		_, summary.TokenOut = args.path(1)
+		summary.Amount, summary.AmountOut = value, args.number(0)
+		summary.Recipient = args.address(2)
+	case "execute", "execute0":
+		// The commands of the Universal Router are not decoded, only the native value is known.
+		if value != nil && value.Sign() > 0 {
+			summary.Amount = value
+		}
+		return summary
+	default:
+		return nil
+	}
+	// The path of swaps from or to the native coin contains the wrapped native token (WETH), which
+	// is not what the user sends or receives.
+	if strings.HasPrefix(method, "swapExactETH") || strings.HasPrefix(method, "swapETH") {
+		summary.Token = nil
+	}
+	if strings.HasSuffix(method, "ETH") {
+		summary.TokenOut = nil
+	}
+	return summary
+}
+
+// exactInputSingleSelectors are the selectors of exactInputSingle of the Uniswap V3 SwapRouter and
+// SwapRouter02, mapped to the index of amountIn in the parameters. SwapRouter02 dropped the
+// deadline. The parameters are a struct of static fields, which is encoded as consecutive words.
+var exactInputSingleSelectors = map[[4]byte]int{
+	// exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))
+	{0x41, 0x4b, 0xf3, 0x89}: 5,
+	// exactInputSingle((address,address,uint24,address,uint256,uint256,uint160))
+	{0x04, 0xe4, 0x5a, 0xaf}: 4,
+}
+
+func decodeExactInputSingle(data []byte) *Summary {
+	var selector [4]byte
+	copy(selector[:], data)
+	amountInIndex, ok := exactInputSingleSelectors[selector]
+	if !ok {
+		return nil
+	}
+	words := data[4:]
+	if len(words) != 32*(amountInIndex+3) {
+		return nil
+	}
+	word := func(i int) []byte { return words[32*i : 32*(i+1)] }
+	address := func(i int) *common.Address {
+		address := common.BytesToAddress(word(i))
+		return &address
+	}
+	return &Summary{
+		Action:    ActionSwap,
+		Method:    "exactInputSingle",
+		Token:     address(0),
+		TokenOut:  address(1),
+		Recipient: address(3),
+		Amount:    new(big.Int).SetBytes(word(amountInIndex)),
+		AmountOut: new(big.Int).SetBytes(word(amountInIndex + 1)),
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoder
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	token     = common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+	tokenOut  = common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	recipient = common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	spender   = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+	maxUint   = math.MaxBig256
+)
+
+func pack(t *testing.T, definition string, method string, args ...interface{}) []byte {
+	t.Helper()
+	data, err := mustParseABI(definition).Pack(method, args...)
+	require.NoError(t, err)
+	return data
+}
+
+func TestDecodeTx(t *testing.T) {
+	t.Run("send", func(t *testing.T) {
+		require.Equal(t,
+			&Summary{Action: ActionSend, Recipient: &recipient, Amount: big.NewInt(1e18)},
+			DecodeTx(recipient, big.NewInt(1e18), nil))
+	})
+
+	t.Run("erc20 transfer", func(t *testing.T) {
+		require.Equal(t,
+			&Summary{
+				Action: ActionTransfer, Method: "transfer", Contract: &token, Token: &token,
+				Recipient: &recipient, Amount: big.NewInt(1000),
+			},
+			DecodeTx(token, big.NewInt(0), pack(t, erc20ABI, "transfer", recipient, big.NewInt(1000))))
+	})
+
+	t.Run("erc20 approve", func(t *testing.T) {
+		summary := DecodeTx(token, nil, pack(t, erc20ABI, "approve", spender, big.NewInt(1000)))
+		require.Equal(t, ActionApprove, summary.Action)
+		require.Equal(t, &spender, summary.Spender)
+		require.Equal(t, big.NewInt(1000), summary.Amount)
+		require.False(t, summary.Unlimited)
+
+		summary = DecodeTx(token, nil, pack(t, erc20ABI, "approve", spender, maxUint))
+		require.True(t, summary.Unlimited)
+	})
+
+	t.Run("nft", func(t *testing.T) {
+		summary := DecodeTx(token, nil, pack(t, nftABI, "setApprovalForAll", spender, true))
+		require.Equal(t, ActionApproveAll, summary.Action)
+		require.Equal(t, "setApprovalForAll", summary.Method)
+		require.Equal(t, &spender, summary.Spender)
+		require.True(t, summary.Unlimited)
+
+		summary = DecodeTx(token, nil, pack(t, nftABI, "safeTransferFrom0",
+			recipient, recipient, big.NewInt(7), []byte{}))
+		require.Equal(t, ActionNFTTransfer, summary.Action)
+		require.Equal(t, "safeTransferFrom", summary.Method)
+		require.Equal(t, big.NewInt(7), summary.TokenID)
+		require.Nil(t, summary.Amount)
+
+		summary = DecodeTx(token, nil, pack(t, nftABI, "safeTransferFrom1",
+			recipient, recipient, big.NewInt(7), big.NewInt(3), []byte{}))
+		require.Equal(t, ActionNFTTransfer, summary.Action)
+		require.Equal(t, big.NewInt(7), summary.TokenID)
+		require.Equal(t, big.NewInt(3), summary.Amount)
+
+		summary = DecodeTx(token, nil, pack(t, nftABI, "safeBatchTransferFrom",
+			recipient, recipient, []*big.Int{big.NewInt(1), big.NewInt(2)},
+			[]*big.Int{big.NewInt(1), big.NewInt(1)}, []byte{}))
+		require.Equal(t, 2, summary.Tokens)
+	})
+
+	t.Run("permit2 approve", func(t *testing.T) {
+		maxUint160 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+		summary := DecodeTx(spender, nil, pack(t, permit2ABI, "approve",
+			token, recipient, maxUint160, big.NewInt(1700000000)))
+		require.Equal(t, &Summary{
+			Action: ActionApprove, Method: "approve", Contract: &spender, Token: &token,
+			Spender: &recipient, Amount: maxUint160, Unlimited: true, Expiration: big.NewInt(1700000000),
+		}, summary)
+	})
+
+	t.Run("uniswap v2", func(t *testing.T) {
+		weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+		summary := DecodeTx(spender, nil, pack(t, routerABI, "swapExactTokensForTokens",
+			big.NewInt(100), big.NewInt(90), []common.Address{token, weth, tokenOut}, recipient, big.NewInt(1)))
+		require.Equal(t, ActionSwap, summary.Action)
+		require.Equal(t, &token, summary.Token)
+		require.Equal(t, &tokenOut, summary.TokenOut)
+		require.Equal(t, big.NewInt(100), summary.Amount)
+		require.Equal(t, big.NewInt(90), summary.AmountOut)
+		require.Equal(t, &recipient, summary.Recipient)
+
+		summary = DecodeTx(spender, big.NewInt(5), pack(t, routerABI, "swapExactETHForTokens",
+			big.NewInt(90), []common.Address{weth, tokenOut}, recipient, big.NewInt(1)))
+		require.Nil(t, summary.Token)
+		require.Equal(t, &tokenOut, summary.TokenOut)
+		require.Equal(t, big.NewInt(5), summary.Amount)
+
+		summary = DecodeTx(spender, nil, pack(t, routerABI, "swapTokensForExactETH",
+			big.NewInt(5), big.NewInt(100), []common.Address{token, weth}, recipient, big.NewInt(1)))
+		require.Equal(t, &token, summary.Token)
+		require.Nil(t, summary.TokenOut)
+		require.Equal(t, big.NewInt(100), summary.Amount)
+		require.Equal(t, big.NewInt(5), summary.AmountOut)
+	})
+
+	t.Run("uniswap v3", func(t *testing.T) {
+		for signature, amountInIndex := range map[string]int{
+			"exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))": 5,
+			"exactInputSingle((address,address,uint24,address,uint256,uint256,uint160))":         4,
+		} {
+			data := crypto.Keccak256([]byte(signature))[:4]
+			words := make([]*big.Int, amountInIndex+3)
+			for i := range words {
+				words[i] = big.NewInt(0)
+			}
+			words[0] = new(big.Int).SetBytes(token.Bytes())
+			words[1] = new(big.Int).SetBytes(tokenOut.Bytes())
+			words[3] = new(big.Int).SetBytes(recipient.Bytes())
+			words[amountInIndex] = big.NewInt(100)
+			words[amountInIndex+1] = big.NewInt(90)
+			for _, word := range words {
+				data = append(data, math.U256Bytes(word)...)
+			}
+			summary := DecodeTx(spender, nil, data)
+			require.Equal(t, &Summary{
+				Action: ActionSwap, Method: "exactInputSingle", Contract: &spender, Token: &token,
+				TokenOut: &tokenOut, Recipient: &recipient, Amount: big.NewInt(100),
+				AmountOut: big.NewInt(90),
+			}, summary, signature)
+		}
+	})
+
+	t.Run("universal router", func(t *testing.T) {
+		summary := DecodeTx(spender, big.NewInt(5), pack(t, routerABI, "execute",
+			[]byte{0x0b}, [][]byte{{0x01}}, big.NewInt(1)))
+		require.Equal(t, &Summary{
+			Action: ActionSwap, Method: "execute", Contract: &spender, Amount: big.NewInt(5),
+		}, summary)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		require.Equal(t,
+			&Summary{Action: ActionUnknown, Contract: &token},
+			DecodeTx(token, nil, []byte{0x12, 0x34, 0x56, 0x78, 0x00}))
+		// Known selector with invalid arguments.
+		require.Equal(t,
+			&Summary{Action: ActionUnknown, Contract: &token},
+			DecodeTx(token, nil, pack(t, erc20ABI, "transfer", recipient, big.NewInt(1))[:20]))
+	})
+}
+
+func TestDecodeTypedData(t *testing.T) {
+	t.Run("eip-2612", func(t *testing.T) {
+		summary, err := DecodeTypedData([]byte(`{
+			"types": {},
+			"primaryType": "Permit",
+			"domain": {"name": "USD Coin", "chainId": 1, "verifyingContract": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"},
+			"message": {
+				"owner": "0xa29163852021BF4C139D03Dff59ae763AC73e84e",
+				"spender": "0x000000000022D473030F116dDEE9F6B43aC78BA3",
+				"value": "115792089237316195423570985008687907853269984665640564039457584007913129639935",
+				"nonce": 0,
+				"deadline": 1700000000
+			}
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, &Summary{
+			Action: ActionPermit, Method: "Permit", Contract: &tokenOut, Token: &tokenOut,
+			Spender: &spender, Amount: maxUint, Unlimited: true, Expiration: big.NewInt(1700000000),
+		}, summary)
+	})
+
+	t.Run("dai", func(t *testing.T) {
+		summary, err := DecodeTypedData([]byte(`{
+			"primaryType": "Permit",
+			"domain": {"verifyingContract": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"},
+			"message": {"holder": "0xa29163852021BF4C139D03Dff59ae763AC73e84e", "spender": "0x000000000022D473030F116dDEE9F6B43aC78BA3", "nonce": 1, "expiry": "0x10", "allowed": true}
+		}`))
+		require.NoError(t, err)
+		require.True(t, summary.Unlimited)
+		require.Nil(t, summary.Amount)
+		require.Equal(t, big.NewInt(16), summary.Expiration)
+	})
+
+	t.Run("permit2", func(t *testing.T) {
+		summary, err := DecodeTypedData([]byte(`{
+			"primaryType": "PermitSingle",
+			"domain": {"name": "Permit2", "verifyingContract": "0x000000000022D473030F116dDEE9F6B43aC78BA3"},
+			"message": {
+				"details": {"token": "0xdAC17F958D2ee523a2206206994597C13D831ec7", "amount": "1000", "expiration": "1700000000", "nonce": "0"},
+				"spender": "0xa29163852021BF4C139D03Dff59ae763AC73e84e",
+				"sigDeadline": "1700000000"
+			}
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, &Summary{
+			Action: ActionPermit, Method: "PermitSingle", Contract: &spender, Token: &token,
+			Spender: &recipient, Amount: big.NewInt(1000), Expiration: big.NewInt(1700000000),
+		}, summary)
+
+		summary, err = DecodeTypedData([]byte(`{
+			"primaryType": "PermitBatch",
+			"domain": {"verifyingContract": "0x000000000022D473030F116dDEE9F6B43aC78BA3"},
+			"message": {
+				"details": [
+					{"token": "0xdAC17F958D2ee523a2206206994597C13D831ec7", "amount": "1000"},
+					{"token": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "amount": "1461501637330902918203684832716283019655932542975"}
+				],
+				"spender": "0xa29163852021BF4C139D03Dff59ae763AC73e84e"
+			}
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, ActionPermit, summary.Action)
+		require.Equal(t, 2, summary.Tokens)
+		require.True(t, summary.Unlimited)
+
+		summary, err = DecodeTypedData([]byte(`{
+			"primaryType": "PermitWitnessTransferFrom",
+			"domain": {"verifyingContract": "0x000000000022D473030F116dDEE9F6B43aC78BA3"},
+			"message": {
+				"permitted": {"token": "0xdAC17F958D2ee523a2206206994597C13D831ec7", "amount": "1000"},
+				"spender": "0xa29163852021BF4C139D03Dff59ae763AC73e84e",
+				"nonce": "1",
+				"deadline": "1700000000",
+				"witness": {}
+			}
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, &token, summary.Token)
+		require.Equal(t, big.NewInt(1000), summary.Amount)
+		require.False(t, summary.Unlimited)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		summary, err := DecodeTypedData([]byte(`{
+			"primaryType": "Mail",
+			"domain": {"name": "Ether Mail"},
+			"message": {"contents": "Hello, Bob!"}
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, &Summary{Action: ActionUnknown, Method: "Mail"}, summary)
+
+		_, err = DecodeTypedData([]byte(`not json`))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// typedData is the part of an EIP-712 typed message needed to interpret it. The message is decoded
+// with json.Number so that large numbers keep their precision.
+type typedData struct {
+	PrimaryType string `json:"primaryType"`
+	Domain      struct {
+		VerifyingContract string `json:"verifyingContract"`
+	} `json:"domain"`
+	Message map[string]interface{} `json:"message"`
+}
+
+// message is a struct in a typed message.
+type message map[string]interface{}
+
+func (m message) child(key string) message {
+	child, _ := m[key].(map[string]interface{})
+	return child
+}
+
+func (m message) address(key string) *common.Address {
+	value, _ := m[key].(string)
+	return parseAddress(value)
+}
+
+// parseAddress returns nil if the value is not a hex address.
+func parseAddress(value string) *common.Address {
+	if !common.IsHexAddress(value) {
+		return nil
+	}
+	address := common.HexToAddress(value)
+	return &address
+}
+
+// number parses integers, which can be JSON numbers or decimal or hex strings.
+func (m message) number(key string) *big.Int {
+	var value string
+	switch v := m[key].(type) {
+	case json.Number:
+		value = v.String()
+	case string:
+		value = v
+	default:
+		return nil
+	}
+	number, ok := math.ParseBig256(value)
+	if !ok {
+		return nil
+	}
+	return number
+}
+
+func (m message) boolean(key string) bool {
+	value, _ := m[key].(bool)
+	return value
+}
+
+// DecodeTypedData interprets an EIP-712 typed message as sent to eth_signTypedData. Permits of
+// EIP-2612, DAI and Permit2 are recognized, all other messages are reported as ActionUnknown with
+// the verifying contract.
+func DecodeTypedData(data []byte) (*Summary, error) {
+	var parsed typedData
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	msg := message(parsed.Message)
+	summary := &Summary{Action: ActionUnknown}
+	switch parsed.PrimaryType {
+	case "Permit":
+		// EIP-2612 permits are verified by the token contract itself.
+		summary.Action = ActionPermit
+		summary.Token = parseAddress(parsed.Domain.VerifyingContract)
+		summary.Spender = msg.address("spender")
+		if _, ok := msg["allowed"]; ok {
+			// DAI permits allow all or nothing.
+			summary.Unlimited = msg.boolean("allowed")
+			summary.Expiration = msg.number("expiry")
+		} else {
+			summary.Amount = msg.number("value")
+			summary.Unlimited = IsUnlimited(summary.Amount)
+			summary.Expiration = msg.number("deadline")
+		}
+	case "PermitSingle":
+		// Permit2 allowance, see
+		// https://github.com/Uniswap/permit2/blob/main/src/interfaces/IAllowanceTransfer.sol
+		details := msg.child("details")
+		summary.Action = ActionPermit
+		summary.Token = details.address("token")
+		summary.Spender = msg.address("spender")
+		summary.Amount = details.number("amount")
+		summary.Unlimited = IsUnlimited(summary.Amount)
+		summary.Expiration = details.number("expiration")
+	case "PermitBatch":
+		details, _ := msg["details"].([]interface{})
+		summary.Action = ActionPermit
+		summary.Spender = msg.address("spender")
+		summary.Tokens = len(details)
+		for _, detail := range details {
+			detail, _ := detail.(map[string]interface{})
+			if IsUnlimited(message(detail).number("amount")) {
+				summary.Unlimited = true
+			}
+		}
+	case "PermitTransferFrom", "PermitWitnessTransferFrom":
+		// Permit2 one-time transfer, e.g. of UniswapX orders, see
+		// https://github.com/Uniswap/permit2/blob/main/src/interfaces/ISignatureTransfer.sol
+		permitted := msg.child("permitted")
+		summary.Action = ActionPermit
+		summary.Token = permitted.address("token")
+		summary.Spender = msg.address("spender")
+		summary.Amount = permitted.number("amount")
+		summary.Unlimited = IsUnlimited(summary.Amount)
+		summary.Expiration = msg.number("deadline")
+	}
+	summary.Method = parsed.PrimaryType
+	summary.Contract = parseAddress(parsed.Domain.VerifyingContract)
+	return summary, nil
+}
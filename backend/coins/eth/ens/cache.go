@@ -0,0 +1,73 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ens
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type cacheEntry struct {
+	name    string
+	expires time.Time
+}
+
+// NameCache caches the primary names of addresses, including the absence of a name, as looking
+// them up takes several contract calls.
+type NameCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[common.Address]cacheEntry
+}
+
+// NewNameCache creates a cache keeping names for the given duration.
+func NewNameCache(ttl time.Duration) *NameCache {
+	return &NameCache{
+		ttl:     ttl,
+		entries: map[common.Address]cacheEntry{},
+	}
+}
+
+// Name returns the cached name of the address. ok is false if the address was not looked up yet or
+// the entry expired.
+func (cache *NameCache) Name(address common.Address) (name string, ok bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	entry, ok := cache.entries[address]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.name, true
+}
+
+// Lookup returns the primary name of the address from the cache, or looks it up with
+// LookupAddress() and caches it. Failed lookups are not cached.
+func (cache *NameCache) Lookup(ctx context.Context, caller ContractCaller, address common.Address) (string, error) {
+	if name, ok := cache.Name(address); ok {
+		return name, nil
+	}
+	name, err := LookupAddress(ctx, caller, address)
+	if err != nil {
+		return "", err
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.entries[address] = cacheEntry{name: name, expires: time.Now().Add(cache.ttl)}
+	return name, nil
+}
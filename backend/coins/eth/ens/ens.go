@@ -0,0 +1,213 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ens resolves Ethereum Name Service names like `vitalik.eth` to addresses and back, see
+// https://docs.ens.domains.
+package ens
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RegistryAddress is the address of the ENS registry, which is the same on Ethereum mainnet and
+// the Sepolia testnet.
+var RegistryAddress = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+var (
+	// ErrInvalidName is returned for names that are not valid normalized ENS names.
+	ErrInvalidName = errors.New("invalid ENS name")
+	// ErrNotFound is returned if a name has no resolver or no address record.
+	ErrNotFound = errors.New("ENS name not found")
+)
+
+const contractsABI = `[
+{"name":"resolver","type":"function","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]},
+{"name":"addr","type":"function","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]},
+{"name":"name","type":"function","stateMutability":"view","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"string"}]}
+]`
+
+var parsedABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(contractsABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// ContractCaller executes calls of view functions, e.g. an rpcclient.Interface.
+type ContractCaller interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// IsName returns true if the string looks like an ENS name rather than a hex address. It does not
+// check if the name is valid.
+func IsName(s string) bool {
+	return strings.Contains(s, ".") && !strings.HasPrefix(s, "0x")
+}
+
+// Normalize normalizes a name according to ENSIP-15, see
+// https://docs.ens.domains/ensip/15. Only names consisting of ASCII letters, digits, hyphens and
+// underscores are supported. Names with other characters, e.g. emoji, are rejected, as
+// normalizing them requires the Unicode tables of ENSIP-15 and a wrongly normalized name could
+// resolve to an address controlled by somebody else.
+func Normalize(name string) (string, error) {
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if label == "" {
+			return "", errp.WithStack(ErrInvalidName)
+		}
+		normalized := make([]byte, len(label))
+		for j := 0; j < len(label); j++ {
+			c := label[j]
+			switch {
+			case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-':
+			case c >= 'A' && c <= 'Z':
+				c += 'a' - 'A'
+			case c == '_':
+				// Underscores are only allowed at the start of a label.
+				if j > 0 && label[j-1] != '_' {
+					return "", errp.WithStack(ErrInvalidName)
+				}
+			default:
+				return "", errp.WithStack(ErrInvalidName)
+			}
+			normalized[j] = c
+		}
+		// Reserved for punycode and other label extensions.
+		if len(normalized) >= 4 && normalized[2] == '-' && normalized[3] == '-' {
+			return "", errp.WithStack(ErrInvalidName)
+		}
+		labels[i] = string(normalized)
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// Namehash computes the node of a normalized name, which identifies it in the ENS contracts.
+func Namehash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node.Bytes(), crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// call calls a function of the ABI taking a node and returns its only output.
+func call(ctx context.Context, caller ContractCaller, contract common.Address, method string, node common.Hash) (
+	interface{}, error) {
+	data, err := parsedABI.Pack(method, node)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if len(result) == 0 {
+		// No contract at the address, or the function is not implemented.
+		return nil, errp.WithStack(ErrNotFound)
+	}
+	outputs, err := parsedABI.Unpack(method, result)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return outputs[0], nil
+}
+
+// resolver returns the resolver contract of the node.
+func resolver(ctx context.Context, caller ContractCaller, node common.Hash) (common.Address, error) {
+	output, err := call(ctx, caller, RegistryAddress, "resolver", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	address := output.(common.Address)
+	if address == (common.Address{}) {
+		return common.Address{}, errp.WithStack(ErrNotFound)
+	}
+	return address, nil
+}
+
+// Resolve returns the Ethereum address of the name, read from the `addr()` record of the resolver
+// the registry lists for the name.
+func Resolve(ctx context.Context, caller ContractCaller, name string) (common.Address, error) {
+	normalized, err := Normalize(name)
+	if err != nil {
+		return common.Address{}, err
+	}
+	node := Namehash(normalized)
+	resolverAddress, err := resolver(ctx, caller, node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	output, err := call(ctx, caller, resolverAddress, "addr", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+	address := output.(common.Address)
+	if address == (common.Address{}) {
+		return common.Address{}, errp.WithStack(ErrNotFound)
+	}
+	return address, nil
+}
+
+// LookupAddress returns the primary name of the address, which its owner set in the reverse
+// registrar. The name is only returned if it resolves back to the address, as anybody can claim any
+// name as their primary name. An empty name is returned if there is none.
+func LookupAddress(ctx context.Context, caller ContractCaller, address common.Address) (string, error) {
+	node := Namehash(strings.ToLower(address.Hex()[2:]) + ".addr.reverse")
+	resolverAddress, err := resolver(ctx, caller, node)
+	if errp.Cause(err) == ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	output, err := call(ctx, caller, resolverAddress, "name", node)
+	if errp.Cause(err) == ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	name := output.(string)
+	if name == "" {
+		return "", nil
+	}
+	resolved, err := Resolve(ctx, caller, name)
+	switch {
+	case errp.Cause(err) == ErrNotFound || errp.Cause(err) == ErrInvalidName:
+		return "", nil
+	case err != nil:
+		return "", err
+	case resolved != address:
+		return "", nil
+	}
+	normalized, err := Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
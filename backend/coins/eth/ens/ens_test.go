@@ -0,0 +1,205 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ens
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeENS serves the registry and one resolver contract.
+type fakeENS struct {
+	t        *testing.T
+	resolver common.Address
+	// resolvers, addresses and names are keyed by node.
+	resolvers map[common.Hash]common.Address
+	addresses map[common.Hash]common.Address
+	names     map[common.Hash]string
+	calls     int
+}
+
+func newFakeENS(t *testing.T) *fakeENS {
+	t.Helper()
+	return &fakeENS{
+		t:         t,
+		resolver:  common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41"),
+		resolvers: map[common.Hash]common.Address{},
+		addresses: map[common.Hash]common.Address{},
+		names:     map[common.Hash]string{},
+	}
+}
+
+func (f *fakeENS) register(name string, address common.Address) {
+	node := Namehash(name)
+	f.resolvers[node] = f.resolver
+	f.addresses[node] = address
+}
+
+func (f *fakeENS) setPrimaryName(address common.Address, name string) {
+	node := Namehash(strings.ToLower(address.Hex()[2:]) + ".addr.reverse")
+	f.resolvers[node] = f.resolver
+	f.names[node] = name
+}
+
+func (f *fakeENS) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	require.Nil(f.t, blockNumber)
+	f.calls++
+	for name, method := range parsedABI.Methods {
+		if !bytes.HasPrefix(msg.Data, method.ID) {
+			continue
+		}
+		node := common.BytesToHash(msg.Data[4:])
+		switch {
+		case *msg.To == RegistryAddress && name == "resolver":
+			return method.Outputs.Pack(f.resolvers[node])
+		case *msg.To == f.resolver && name == "addr":
+			return method.Outputs.Pack(f.addresses[node])
+		case *msg.To == f.resolver && name == "name":
+			return method.Outputs.Pack(f.names[node])
+		}
+	}
+	return nil, nil
+}
+
+func TestNormalize(t *testing.T) {
+	for name, expected := range map[string]string{
+		"vitalik.eth":      "vitalik.eth",
+		"Vitalik.ETH":      "vitalik.eth",
+		"_dmarc.foo.eth":   "_dmarc.foo.eth",
+		"__x.eth":          "__x.eth",
+		"-a-.eth":          "-a-.eth",
+		"my-name-1234.eth": "my-name-1234.eth",
+	} {
+		normalized, err := Normalize(name)
+		require.NoError(t, err, name)
+		require.Equal(t, expected, normalized)
+	}
+	for _, name := range []string{
+		"",
+		"vitalik..eth",
+		".eth",
+		"vitalik.eth.",
+		"a_b.eth",
+		"xn--ls8h.eth",
+		"ab--c.eth",
+		"vitalik eth",
+		"vitalik.eth/",
+		"vitаlik.eth", // Cyrillic a
+		"💩.eth",
+	} {
+		_, err := Normalize(name)
+		require.Equal(t, ErrInvalidName, errp.Cause(err), name)
+	}
+}
+
+func TestNamehash(t *testing.T) {
+	// Test vectors of EIP-137.
+	require.Equal(t, common.Hash{}, Namehash(""))
+	require.Equal(t,
+		common.HexToHash("0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae"),
+		Namehash("eth"))
+	require.Equal(t,
+		common.HexToHash("0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f"),
+		Namehash("foo.eth"))
+}
+
+func TestIsName(t *testing.T) {
+	require.True(t, IsName("vitalik.eth"))
+	require.False(t, IsName("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045"))
+	require.False(t, IsName("vitalik"))
+}
+
+func TestResolve(t *testing.T) {
+	fake := newFakeENS(t)
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	fake.register("vitalik.eth", address)
+	fake.register("noaddr.eth", common.Address{})
+
+	resolved, err := Resolve(context.Background(), fake, "Vitalik.eth")
+	require.NoError(t, err)
+	require.Equal(t, address, resolved)
+
+	_, err = Resolve(context.Background(), fake, "unknown.eth")
+	require.Equal(t, ErrNotFound, errp.Cause(err))
+	_, err = Resolve(context.Background(), fake, "noaddr.eth")
+	require.Equal(t, ErrNotFound, errp.Cause(err))
+	_, err = Resolve(context.Background(), fake, "in valid.eth")
+	require.Equal(t, ErrInvalidName, errp.Cause(err))
+}
+
+func TestLookupAddress(t *testing.T) {
+	fake := newFakeENS(t)
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	impostor := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	fake.register("vitalik.eth", address)
+	fake.setPrimaryName(address, "vitalik.eth")
+	// Anybody can set any primary name, it must resolve back to the address.
+	fake.setPrimaryName(impostor, "vitalik.eth")
+
+	name, err := LookupAddress(context.Background(), fake, address)
+	require.NoError(t, err)
+	require.Equal(t, "vitalik.eth", name)
+
+	name, err = LookupAddress(context.Background(), fake, impostor)
+	require.NoError(t, err)
+	require.Equal(t, "", name)
+
+	name, err = LookupAddress(context.Background(), fake, common.HexToAddress("0x02"))
+	require.NoError(t, err)
+	require.Equal(t, "", name)
+}
+
+func TestNameCache(t *testing.T) {
+	fake := newFakeENS(t)
+	address := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	other := common.HexToAddress("0x02")
+	fake.register("vitalik.eth", address)
+	fake.setPrimaryName(address, "vitalik.eth")
+
+	cache := NewNameCache(time.Hour)
+	_, ok := cache.Name(address)
+	require.False(t, ok)
+	name, err := cache.Lookup(context.Background(), fake, address)
+	require.NoError(t, err)
+	require.Equal(t, "vitalik.eth", name)
+	name, ok = cache.Name(address)
+	require.True(t, ok)
+	require.Equal(t, "vitalik.eth", name)
+
+	// Addresses without a name are cached too.
+	_, err = cache.Lookup(context.Background(), fake, other)
+	require.NoError(t, err)
+	calls := fake.calls
+	name, err = cache.Lookup(context.Background(), fake, other)
+	require.NoError(t, err)
+	require.Equal(t, "", name)
+	require.Equal(t, calls, fake.calls)
+
+	// Expired entries are looked up again.
+	cache = NewNameCache(-time.Second)
+	_, err = cache.Lookup(context.Background(), fake, address)
+	require.NoError(t, err)
+	_, ok = cache.Name(address)
+	require.False(t, ok)
+}
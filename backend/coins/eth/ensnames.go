@@ -0,0 +1,206 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/ens"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ensNamesTTL is the duration for which the primary names of addresses are cached.
+const ensNamesTTL = 24 * time.Hour
+
+// maxENSLookups is the maximum number of addresses looked up per account update, so that a long
+// history does not delay the sync. The remaining addresses are looked up in the next updates.
+const maxENSLookups = 20
+
+// SupportsENS returns true if ENS names can be resolved on the network of the coin. The ENS
+// registry is deployed on Ethereum mainnet and the Sepolia testnet.
+func (coin *Coin) SupportsENS() bool {
+	switch coin.ChainID() {
+	case params.MainnetChainConfig.ChainID.Uint64(), params.SepoliaChainConfig.ChainID.Uint64():
+		return true
+	}
+	return false
+}
+
+// ResolveENSName returns the address an ENS name like `vitalik.eth` resolves to.
+func (coin *Coin) ResolveENSName(ctx context.Context, name string) (ethcommon.Address, error) {
+	if !coin.SupportsENS() {
+		return ethcommon.Address{}, errp.Newf("ENS is not supported for %s", coin.code)
+	}
+	return ens.Resolve(ctx, coin.client, name)
+}
+
+// resolveRecipient parses a recipient, which can be a hex address or an ENS name.
+func (account *Account) resolveRecipient(recipient string) (ethcommon.Address, error) {
+	if IsValidEthAddress(recipient) {
+		return ethcommon.HexToAddress(recipient), nil
+	}
+	if !ens.IsName(recipient) || !account.coin.SupportsENS() {
+		return ethcommon.Address{}, errp.WithStack(errors.ErrInvalidAddress)
+	}
+	address, err := account.coin.ResolveENSName(context.TODO(), recipient)
+	switch errp.Cause(err) {
+	case nil:
+		return address, nil
+	case ens.ErrInvalidName:
+		return ethcommon.Address{}, errp.WithStack(errors.ErrInvalidAddress)
+	case ens.ErrNotFound:
+		return ethcommon.Address{}, errp.WithStack(errors.ErrENSNameNotFound)
+	}
+	account.log.WithError(err).Error("Could not resolve ENS name")
+	return ethcommon.Address{}, err
+}
+
+// ProposedRecipient returns the recipient of the active transaction proposal, which is the resolved
+// address if an ENS name was entered. It is empty if there is no active proposal.
+func (account *Account) ProposedRecipient() string {
+	defer account.updateLock.RLock()()
+	if account.activeTxProposal == nil || account.activeTxProposal.Recipient == nil {
+		return ""
+	}
+	return account.activeTxProposal.Recipient.Hex()
+}
+
+// counterparties returns the addresses of the transactions that are not ours.
+func counterparties(transactions []*accounts.TransactionData) []ethcommon.Address {
+	result := []ethcommon.Address{}
+	for _, transaction := range transactions {
+		for _, addressAndAmount := range transaction.Addresses {
+			if !addressAndAmount.Ours && ethcommon.IsHexAddress(addressAndAmount.Address) {
+				result = append(result, ethcommon.HexToAddress(addressAndAmount.Address))
+			}
+		}
+	}
+	return result
+}
+
+// lookupENSNamesInBackground looks up the primary names of the addresses in the background. If a
+// lookup of the account is still running, the addresses are looked up after it instead, replacing
+// the addresses of earlier requests. EventTransactionsChanged is fired if new names were found, so
+// that they are shown in the transactions. The lookups stop when the account is closed.
+func (account *Account) lookupENSNamesInBackground(addresses []ethcommon.Address) {
+	if !account.coin.SupportsENS() {
+		return
+	}
+	unlock := account.ensLookupLock.Lock()
+	account.ensLookupPending = addresses
+	account.ensLookupHasPending = true
+	if account.ensLookupRunning {
+		unlock()
+		return
+	}
+	account.ensLookupRunning = true
+	unlock()
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-account.quitChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		closed := func() bool {
+			select {
+			case <-account.quitChan:
+				return true
+			default:
+				return false
+			}
+		}
+		for {
+			unlock := account.ensLookupLock.Lock()
+			if !account.ensLookupHasPending || closed() {
+				account.ensLookupRunning = false
+				unlock()
+				return
+			}
+			addresses := account.ensLookupPending
+			account.ensLookupPending = nil
+			account.ensLookupHasPending = false
+			unlock()
+			if account.lookupENSNames(ctx, addresses) && !closed() {
+				account.Config().OnEvent(accountsTypes.EventTransactionsChanged)
+			}
+		}
+	}()
+}
+
+// lookupENSNames looks up the primary names of the addresses that are not cached yet. Returns true
+// if a name was found. The lookups are aborted when the context is canceled.
+func (account *Account) lookupENSNames(ctx context.Context, addresses []ethcommon.Address) bool {
+	lookups := 0
+	found := false
+	for _, address := range addresses {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, ok := account.coin.ensNames.Name(address); ok {
+			continue
+		}
+		if lookups == maxENSLookups {
+			break
+		}
+		lookups++
+		name, err := account.coin.ensNames.Lookup(ctx, account.coin.client, address)
+		if err != nil {
+			account.log.WithError(err).Errorf("Could not look up the ENS name of %s", address.Hex())
+			continue
+		}
+		if name != "" {
+			found = true
+		}
+	}
+	return found
+}
+
+// annotateENSNames returns the transactions with the cached primary names of their addresses
+// filled in. Transactions are copied if they are modified.
+func (account *Account) annotateENSNames(transactions accounts.OrderedTransactions) accounts.OrderedTransactions {
+	if !account.coin.SupportsENS() {
+		return transactions
+	}
+	result := make(accounts.OrderedTransactions, len(transactions))
+	for i, transaction := range transactions {
+		result[i] = transaction
+		for j, addressAndAmount := range transaction.Addresses {
+			if addressAndAmount.Ours || !ethcommon.IsHexAddress(addressAndAmount.Address) {
+				continue
+			}
+			name, _ := account.coin.ensNames.Name(ethcommon.HexToAddress(addressAndAmount.Address))
+			if name == "" {
+				continue
+			}
+			if result[i] == transaction {
+				txCopy := *transaction
+				txCopy.Addresses = append([]accounts.AddressAndAmount(nil), transaction.Addresses...)
+				result[i] = &txCopy
+			}
+			result[i].Addresses[j].ENSName = name
+		}
+	}
+	return result
+}
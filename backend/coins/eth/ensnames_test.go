@@ -0,0 +1,227 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/ens"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTransactionsSource is a transactions source that returns fixed transactions.
+type mockTransactionsSource struct {
+	transactions []*accounts.TransactionData
+}
+
+func (m *mockTransactionsSource) Transactions(
+	blockTipHeight *big.Int,
+	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*accounts.TransactionData, error) {
+	return m.transactions, nil
+}
+
+// ensCallContract serves the ENS registry and a resolver with one name, which is also the primary
+// name of its address.
+func ensCallContract(t *testing.T, name string, address common.Address) func(
+	ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(`[
+{"name":"resolver","type":"function","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]},
+{"name":"addr","type":"function","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"address"}]},
+{"name":"name","type":"function","inputs":[{"name":"node","type":"bytes32"}],"outputs":[{"name":"","type":"string"}]}
+]`))
+	require.NoError(t, err)
+	resolver := common.HexToAddress("0x4976fb03C32e5B8cfe2b6cCB31c09Ba78EBaBa41")
+	node := ens.Namehash(name)
+	reverseNode := ens.Namehash(strings.ToLower(address.Hex()[2:]) + ".addr.reverse")
+	return func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+		method, err := parsed.MethodById(msg.Data[:4])
+		require.NoError(t, err)
+		requested := common.BytesToHash(msg.Data[4:])
+		switch {
+		case *msg.To == ens.RegistryAddress && (requested == node || requested == reverseNode):
+			return method.Outputs.Pack(resolver)
+		case *msg.To == ens.RegistryAddress:
+			return method.Outputs.Pack(common.Address{})
+		case *msg.To == resolver && method.Name == "addr" && requested == node:
+			return method.Outputs.Pack(address)
+		case *msg.To == resolver && method.Name == "name" && requested == reverseNode:
+			return method.Outputs.Pack(name)
+		}
+		return nil, nil
+	}
+}
+
+func TestENSRecipient(t *testing.T) {
+	vitalik := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	client := newClientMock()
+	client.CallContractFunc = ensCallContract(t, "vitalik.eth", vitalik)
+	acct := newAccountWithNet(t, params.MainnetChainConfig, client)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	args := &accounts.TxProposalArgs{
+		RecipientAddress: "Vitalik.eth",
+		Amount:           coin.NewSendAmount("0.1"),
+		FeeTargetCode:    accounts.FeeTargetCodeCustom,
+		CustomFee:        "20",
+	}
+	_, _, _, err := acct.TxProposal(args)
+	require.NoError(t, err)
+	require.Equal(t, vitalik, *acct.activeTxProposal.Tx.To())
+	require.Equal(t, vitalik.Hex(), acct.ProposedRecipient())
+
+	args.RecipientAddress = "unknown.eth"
+	_, _, _, err = acct.TxProposal(args)
+	require.Equal(t, errors.ErrENSNameNotFound, errp.Cause(err))
+
+	args.RecipientAddress = "xn--ls8h.eth"
+	_, _, _, err = acct.TxProposal(args)
+	require.Equal(t, errors.ErrInvalidAddress, errp.Cause(err))
+
+	// ENS is not available on other networks.
+	goerliAcct := newAccount(t)
+	defer goerliAcct.Close()
+	goerliAcct.Synchronizer.WaitSynchronized()
+	args.RecipientAddress = "vitalik.eth"
+	_, _, _, err = goerliAcct.TxProposal(args)
+	require.Equal(t, errors.ErrInvalidAddress, errp.Cause(err))
+}
+
+func TestENSNamesInTransactions(t *testing.T) {
+	vitalik := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	other := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	client := newClientMock()
+	client.CallContractFunc = ensCallContract(t, "vitalik.eth", vitalik)
+	acct := newAccountWithNet(t, params.MainnetChainConfig, client)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+	notifier := &accountsMocks.Notifier{}
+	notifier.On("Put", mock.Anything).Return(nil)
+	acct.notifier = notifier
+	acct.coin.TstSetTransactionsSource(&mockTransactionsSource{
+		transactions: []*accounts.TransactionData{
+			{
+				TxID: "0x01", InternalID: "0x01", Type: accounts.TxTypeReceive,
+				Amount:    coin.NewAmountFromInt64(1),
+				Addresses: []accounts.AddressAndAmount{{Address: vitalik.Hex(), Amount: coin.NewAmountFromInt64(1)}},
+			},
+			{
+				TxID: "0x02", InternalID: "0x02", Type: accounts.TxTypeSend,
+				Amount:    coin.NewAmountFromInt64(1),
+				Addresses: []accounts.AddressAndAmount{{Address: other.Hex(), Amount: coin.NewAmountFromInt64(1)}},
+			},
+		},
+	})
+	acct.enqueueUpdateCh <- struct{}{}
+
+	ensName := func(txID string) string {
+		transactions, err := acct.Transactions()
+		require.NoError(t, err)
+		for _, transaction := range transactions {
+			if transaction.TxID == txID {
+				return transaction.Addresses[0].ENSName
+			}
+		}
+		return "missing"
+	}
+	require.Eventually(t, func() bool { return ensName("0x01") == "vitalik.eth" }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "", ensName("0x02"))
+}
+
+func TestLookupENSNames(t *testing.T) {
+	vitalik := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	other := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	client := newClientMock()
+	callContract := ensCallContract(t, "vitalik.eth", vitalik)
+	var calls atomic.Int32
+	client.CallContractFunc = func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+		calls.Add(1)
+		return callContract(ctx, msg, blockNumber)
+	}
+	acct := newAccountWithNet(t, params.MainnetChainConfig, client)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	// Wait for the lookup started by the initial update.
+	require.Eventually(t, func() bool {
+		defer acct.ensLookupLock.Lock()()
+		return !acct.ensLookupRunning
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// No lookup is started while another one is running, the addresses are looked up after it.
+	unlock := acct.ensLookupLock.Lock()
+	acct.ensLookupRunning = true
+	unlock()
+	acct.lookupENSNamesInBackground([]common.Address{vitalik})
+	require.Equal(t, int32(0), calls.Load())
+	unlock = acct.ensLookupLock.Lock()
+	require.True(t, acct.ensLookupHasPending)
+	require.Equal(t, []common.Address{vitalik}, acct.ensLookupPending)
+	acct.ensLookupRunning = false
+	acct.ensLookupHasPending = false
+	acct.ensLookupPending = nil
+	unlock()
+
+	require.False(t, acct.lookupENSNames(context.Background(), []common.Address{other}))
+	require.True(t, acct.lookupENSNames(context.Background(), []common.Address{other, vitalik}))
+	// Cached names are not looked up again.
+	lookupCalls := calls.Load()
+	require.False(t, acct.lookupENSNames(context.Background(), []common.Address{other, vitalik}))
+	require.Equal(t, lookupCalls, calls.Load())
+}
+
+func TestLookupENSNamesStopsOnClose(t *testing.T) {
+	vitalik := common.HexToAddress("0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045")
+	client := newClientMock()
+	callContract := ensCallContract(t, "vitalik.eth", vitalik)
+	var calls atomic.Int32
+	client.CallContractFunc = func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+		calls.Add(1)
+		return callContract(ctx, msg, blockNumber)
+	}
+	acct := newAccountWithNet(t, params.MainnetChainConfig, client)
+	acct.Synchronizer.WaitSynchronized()
+	acct.Close()
+
+	acct.lookupENSNamesInBackground([]common.Address{vitalik})
+	require.Eventually(t, func() bool {
+		defer acct.ensLookupLock.Lock()()
+		return !acct.ensLookupRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(0), calls.Load())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.False(t, acct.lookupENSNames(ctx, []common.Address{vitalik}))
+	require.Equal(t, int32(0), calls.Load())
+}
@@ -4,10 +4,27 @@ From https://github.com/OpenZeppelin/openzeppelin-solidity/blob/79346123eade98fb
 pragma solidity ^0.5.0;
 
 /**
- * @dev Interface of the ERC20 standard as defined in the EIP. Does not include
- * the optional functions; to access them see `ERC20Detailed`.
+ * @dev Interface of the ERC20 standard as defined in the EIP, including the
+ * optional functions of `ERC20Detailed`.
  */
 interface IERC20 {
+    /**
+     * @dev Returns the name of the token. Optional.
+     */
+    function name() external view returns (string memory);
+
+    /**
+     * @dev Returns the symbol of the token, usually a shorter version of the
+     * name. Optional.
+     */
+    function symbol() external view returns (string memory);
+
+    /**
+     * @dev Returns the number of decimals used to get its user representation.
+     * Optional.
+     */
+    function decimals() external view returns (uint8);
+
     /**
      * @dev Returns the amount of tokens in existence.
      */
@@ -0,0 +1,134 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erc20
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ContractCaller executes read-only contract calls, e.g. an rpcclient.Interface.
+type ContractCaller interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// codelessCaller adapts a ContractCaller to bind.ContractCaller. The code of a contract is only
+// requested by the bindings if a call returned nothing, which means that there is no contract at
+// the address or that it does not implement the function, so no code is reported.
+type codelessCaller struct {
+	ContractCaller
+}
+
+// CodeAt implements bind.ContractCaller.
+func (codelessCaller) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+// Metadata is the information a token contract reports about itself. Name and symbol are
+// optional in ERC20 and are empty if the contract does not implement them.
+type Metadata struct {
+	Name     string
+	Symbol   string
+	Decimals uint8
+}
+
+// FetchMetadata reads the name, symbol and decimals of the token contract at the given address.
+// An error is returned if the decimals can't be read, as amounts can't be converted without them.
+func FetchMetadata(ctx context.Context, caller ContractCaller, contractAddress common.Address) (*Metadata, error) {
+	contract, err := NewIERC20Caller(contractAddress, codelessCaller{caller})
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	decimals, err := contract.Decimals(opts)
+	if err != nil {
+		return nil, errp.WithMessage(err, "could not read the decimals of the token")
+	}
+	// Some early tokens return bytes32 instead of a string, which can't be decoded. They are
+	// treated like tokens without a name or symbol.
+	name, err := contract.Name(opts)
+	if err != nil && ctx.Err() != nil {
+		return nil, errp.WithStack(ctx.Err())
+	}
+	symbol, err := contract.Symbol(opts)
+	if err != nil && ctx.Err() != nil {
+		return nil, errp.WithStack(ctx.Err())
+	}
+	return &Metadata{Name: name, Symbol: symbol, Decimals: decimals}, nil
+}
+
+// BalanceOf reads the token balance of the account from the token contract at the given address.
+func BalanceOf(ctx context.Context, caller ContractCaller, contractAddress, account common.Address) (*big.Int, error) {
+	contract, err := NewIERC20Caller(contractAddress, codelessCaller{caller})
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	balance, err := contract.BalanceOf(&bind.CallOpts{Context: ctx}, account)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return balance, nil
+}
+
+// FormatAmount formats an amount in the smallest unit of a token in the unit of the token, without
+// trailing zeros.
+func FormatAmount(amount *big.Int, decimals uint) string {
+	factor := new(big.Int).Exp(big.NewInt(10), new(big.Int).SetUint64(uint64(decimals)), nil)
+	s := new(big.Rat).SetFrac(amount, factor).FloatString(int(decimals))
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// Allowance reads the amount the spender may still transfer from the owner's balance of the token
+// at the given address.
+func Allowance(
+	ctx context.Context, caller ContractCaller, contractAddress, owner, spender common.Address) (*big.Int, error) {
+	contract, err := NewIERC20Caller(contractAddress, codelessCaller{caller})
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	allowance, err := contract.Allowance(&bind.CallOpts{Context: ctx}, owner, spender)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return allowance, nil
+}
+
+// Approval is an `Approval` event of a token contract, emitted when an owner sets the allowance of
+// a spender.
+type Approval struct {
+	ContractAddress common.Address
+	Spender         common.Address
+}
+
+// TokenActivity is the activity of an address in one token contract.
+type TokenActivity struct {
+	ContractAddress common.Address
+	// Name, Symbol and Decimals are as reported by the source of the activity. Name and symbol can
+	// be empty.
+	Name     string
+	Symbol   string
+	Decimals uint
+	// Transfers is the number of token transfers from or to the address.
+	Transfers int
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ERC20TokenDiscoverer can be implemented by a TransactionsSource that can list all ERC20 tokens an
+// address has sent or received, e.g. from the `Transfer` events of the token contracts.
+type ERC20TokenDiscoverer interface {
+	// ERC20TokenActivity returns one entry per token contract the address has transferred tokens
+	// of.
+	ERC20TokenActivity(address common.Address) ([]*erc20.TokenActivity, error)
+}
+
+// DiscoveredERC20Token is an ERC20 token found in the history of an account.
+type DiscoveredERC20Token struct {
+	erc20.TokenActivity
+	// Balance is the current balance in the smallest unit of the token, or nil if it could not be
+	// fetched.
+	Balance *big.Int
+}
+
+// DiscoverERC20Tokens finds all ERC20 tokens the account has sent or received and fetches their
+// current balance. This includes tokens that were sent to the account without being asked for,
+// e.g. airdrops.
+func (account *Account) DiscoverERC20Tokens() ([]*DiscoveredERC20Token, error) {
+	if IsERC20(account) {
+		return nil, errp.New("tokens can only be discovered for Ethereum accounts")
+	}
+	address, err := account.Address()
+	if err != nil {
+		return nil, err
+	}
+	discoverer, ok := account.coin.transactionsSource.(ERC20TokenDiscoverer)
+	if !ok {
+		return nil, errp.Newf("token discovery is not supported for %s", account.coin.code)
+	}
+	activities, err := discoverer.ERC20TokenActivity(address.Address)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*DiscoveredERC20Token, len(activities))
+	for i, activity := range activities {
+		result[i] = &DiscoveredERC20Token{TokenActivity: *activity}
+		balance, err := account.coin.client.ERC20Balance(
+			address.Address,
+			erc20.NewToken(activity.ContractAddress.Hex(), activity.Decimals))
+		if err != nil {
+			account.log.WithError(err).Errorf(
+				"Could not fetch the balance of token %s", activity.ContractAddress.Hex())
+			continue
+		}
+		result[i].Balance = balance
+	}
+	return result, nil
+}
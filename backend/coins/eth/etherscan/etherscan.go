@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // CallInterval is the duration between etherscan requests.
@@ -50,6 +51,9 @@ const ERC20GasErr = "insufficient funds for gas * price + value"
 type EtherScan struct {
 	url        string
 	httpClient *http.Client
+	// chainID selects the network in the multichain API, see NewEtherScanV2(). 0 if the URL
+	// already determines the network.
+	chainID uint64
 }
 
 // NewEtherScan creates a new instance of EtherScan.
@@ -60,8 +64,22 @@ func NewEtherScan(url string, httpClient *http.Client) *EtherScan {
 	}
 }
 
+// NewEtherScanV2 creates a new instance of EtherScan using the multichain API, which serves all
+// networks supported by Etherscan (e.g. Arbiscan, Basescan) with one URL. The network is selected
+// by its chain ID.
+func NewEtherScanV2(chainID uint64, httpClient *http.Client) *EtherScan {
+	return &EtherScan{
+		url:        "https://api.etherscan.io/v2/api",
+		httpClient: httpClient,
+		chainID:    chainID,
+	}
+}
+
 func (etherScan *EtherScan) call(params url.Values, result interface{}) error {
 	params.Set("apikey", apiKey)
+	if etherScan.chainID != 0 {
+		params.Set("chainid", strconv.FormatUint(etherScan.chainID, 10))
+	}
 	response, err := etherScan.httpClient.Get(etherScan.url + "?" + params.Encode())
 	if err != nil {
 		return errp.WithStack(err)
@@ -351,6 +369,120 @@ func (etherScan *EtherScan) Transactions(
 	return append(transactionsNormal, transactionsInternal...), nil
 }
 
+// ERC20TokenActivity implements eth.ERC20TokenDiscoverer. It lists the ERC20 token transfers of the
+// address in all token contracts.
+func (etherScan *EtherScan) ERC20TokenActivity(address common.Address) ([]*erc20.TokenActivity, error) {
+	params := url.Values{}
+	params.Set("module", "account")
+	params.Set("action", "tokentx")
+	params.Set("address", address.Hex())
+	params.Set("startblock", "0")
+	params.Set("tag", "latest")
+	params.Set("sort", "asc")
+
+	result := struct {
+		Result []struct {
+			ContractAddress common.Address `json:"contractAddress"`
+			TokenName       string         `json:"tokenName"`
+			TokenSymbol     string         `json:"tokenSymbol"`
+			TokenDecimal    string         `json:"tokenDecimal"`
+		}
+	}{}
+	if err := etherScan.call(params, &result); err != nil {
+		return nil, err
+	}
+	activities := []*erc20.TokenActivity{}
+	byContract := map[common.Address]*erc20.TokenActivity{}
+	for _, transfer := range result.Result {
+		activity, ok := byContract[transfer.ContractAddress]
+		if !ok {
+			// Tokens without decimals are reported with an empty string.
+			decimals, _ := strconv.ParseUint(transfer.TokenDecimal, 10, 8)
+			activity = &erc20.TokenActivity{
+				ContractAddress: transfer.ContractAddress,
+				Name:            transfer.TokenName,
+				Symbol:          transfer.TokenSymbol,
+				Decimals:        uint(decimals),
+			}
+			byContract[transfer.ContractAddress] = activity
+			activities = append(activities, activity)
+		}
+		activity.Transfers++
+	}
+	return activities, nil
+}
+
+// approvalTopic is the topic of the ERC20 `Approval` event.
+var approvalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+
+// logsPageSize is the maximum number of logs EtherScan returns per request.
+const logsPageSize = 1000
+
+// ERC20Approvals implements eth.ERC20ApprovalLister. It lists the `Approval` events of the address
+// as owner in all token contracts, oldest first.
+func (etherScan *EtherScan) ERC20Approvals(owner common.Address) ([]*erc20.Approval, error) {
+	approvals := []*erc20.Approval{}
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("module", "logs")
+		params.Set("action", "getLogs")
+		params.Set("fromBlock", "0")
+		params.Set("toBlock", "latest")
+		params.Set("topic0", approvalTopic.Hex())
+		params.Set("topic1", common.BytesToHash(owner.Bytes()).Hex())
+		params.Set("topic0_1_opr", "and")
+		params.Set("page", strconv.Itoa(page))
+		params.Set("offset", strconv.Itoa(logsPageSize))
+
+		result := struct {
+			Result []struct {
+				Address common.Address `json:"address"`
+				Topics  []common.Hash  `json:"topics"`
+			}
+		}{}
+		if err := etherScan.call(params, &result); err != nil {
+			return nil, err
+		}
+		for _, log := range result.Result {
+			// ERC721 approvals have the same topic, but the token ID as an additional topic.
+			if len(log.Topics) != 3 {
+				continue
+			}
+			approvals = append(approvals, &erc20.Approval{
+				ContractAddress: log.Address,
+				Spender:         common.BytesToAddress(log.Topics[2].Bytes()),
+			})
+		}
+		if len(result.Result) < logsPageSize {
+			return approvals, nil
+		}
+	}
+}
+
+// ContractVerified implements eth.ContractVerifier. A contract is verified if its source code was
+// published on EtherScan. Addresses without a contract are reported as not verified.
+func (etherScan *EtherScan) ContractVerified(address common.Address) (bool, error) {
+	params := url.Values{}
+	params.Set("module", "contract")
+	params.Set("action", "getsourcecode")
+	params.Set("address", address.Hex())
+
+	result := struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Result  []struct {
+			SourceCode string `json:"SourceCode"`
+		} `json:"result"`
+	}{}
+	if err := etherScan.call(params, &result); err != nil {
+		return false, err
+	}
+	if result.Status != "1" || len(result.Result) == 0 {
+		return false, errp.Newf("could not get the source code of %s: %s", address.Hex(), result.Message)
+	}
+	return result.Result[0].SourceCode != "", nil
+}
+
 // ----- RPC node proxy methods follow
 
 func (etherScan *EtherScan) rpcCall(params url.Values, result interface{}) error {
@@ -534,6 +666,19 @@ func (etherScan *EtherScan) PendingNonceAt(ctx context.Context, account common.A
 	return uint64(result), nil
 }
 
+// NonceAt implements rpc.Interface.
+func (etherScan *EtherScan) NonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	params := url.Values{}
+	params.Set("action", "eth_getTransactionCount")
+	params.Set("address", account.Hex())
+	params.Set("tag", "latest")
+	var result hexutil.Uint64
+	if err := etherScan.rpcCall(params, &result); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
 // SendTransaction implements rpc.Interface.
 func (etherScan *EtherScan) SendTransaction(ctx context.Context, tx *types.Transaction) error {
 	encodedTx, err := tx.MarshalBinary() // canonical RLP encoding, works for legacy and EIP-1559 txs
@@ -578,6 +723,11 @@ func (etherScan *EtherScan) FeeTargets(ctx context.Context) ([]*ethtypes.FeeTarg
 			BaseFee string `json:"suggestBaseFee"`
 		} `json:"result"`
 	}
+	if etherScan.chainID != 0 {
+		// The gas oracle only covers Ethereum mainnet, and it is in whole Gwei, which is too coarse
+		// for L2s. The caller falls back to eth_gasPrice.
+		return nil, errp.Newf("no gas oracle for chain ID %d", etherScan.chainID)
+	}
 	params := url.Values{}
 	params.Set("module", "gastracker")
 	params.Set("action", "gasoracle")
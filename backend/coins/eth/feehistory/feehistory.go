@@ -0,0 +1,189 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package feehistory estimates EIP-1559 fee targets from the `eth_feeHistory` of a JSON-RPC node,
+// without relying on a third-party gas oracle.
+package feehistory
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// blockCount is the number of recent blocks the fees are estimated from, about four minutes on
+// Ethereum mainnet.
+const blockCount = 20
+
+// targets are the estimated fee targets, from the lowest to the highest. Nodes require the
+// percentiles of `eth_feeHistory` in ascending order.
+var targets = []struct {
+	code accounts.FeeTargetCode
+	// percentile is the percentile of the priority fees paid in a block, weighted by gas used.
+	percentile float64
+	// baseFeePermille is the fee cap's share of the expected base fee. The base fee rises by at
+	// most 12.5% per block, so e.g. 1250 allows for two full blocks before the transaction is
+	// delayed.
+	baseFeePermille int64
+}{
+	{code: accounts.FeeTargetCodeLow, percentile: 10, baseFeePermille: 1125},
+	{code: accounts.FeeTargetCodeNormal, percentile: 50, baseFeePermille: 1250},
+	{code: accounts.FeeTargetCodeHigh, percentile: 75, baseFeePermille: 2000},
+}
+
+// minTip is the minimum priority fee of the targets in Wei. Blocks of L2s often contain no priority
+// fees at all, but a fee target without one is rejected.
+var minTip = big.NewInt(1)
+
+// Caller performs JSON-RPC calls, e.g. *rpc.Client.
+type Caller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// History is the result of `eth_feeHistory`.
+type History struct {
+	OldestBlock *hexutil.Big `json:"oldestBlock"`
+	// BaseFee contains the base fee of each block and of the next block after the newest one.
+	BaseFee      []*hexutil.Big `json:"baseFeePerGas"`
+	GasUsedRatio []float64      `json:"gasUsedRatio"`
+	// Reward contains the priority fees at the requested percentiles for each block.
+	Reward [][]*hexutil.Big `json:"reward"`
+}
+
+// Fetch returns the fee history of the given number of recent blocks, with the priority fees at
+// the given percentiles.
+func Fetch(ctx context.Context, caller Caller, blocks uint64, percentiles []float64) (*History, error) {
+	var history History
+	err := caller.CallContext(ctx, &history, "eth_feeHistory", hexutil.Uint64(blocks), "latest", percentiles)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return &history, nil
+}
+
+// median returns the median of the numbers, or zero if there are none. The slice is sorted.
+func median(numbers []*big.Int) *big.Int {
+	if len(numbers) == 0 {
+		return big.NewInt(0)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i].Cmp(numbers[j]) < 0 })
+	middle := len(numbers) / 2
+	if len(numbers)%2 == 1 {
+		return new(big.Int).Set(numbers[middle])
+	}
+	sum := new(big.Int).Add(numbers[middle-1], numbers[middle])
+	return sum.Div(sum, big.NewInt(2))
+}
+
+// expectedBaseFee returns the base fee to plan for. It is the base fee of the next block, unless the
+// base fee is rising, in which case the rise over the average of the history is extrapolated once,
+// up to twice the base fee of the next block.
+func expectedBaseFee(history *History) *big.Int {
+	nextBaseFee := (*big.Int)(history.BaseFee[len(history.BaseFee)-1])
+	sum := new(big.Int)
+	for _, baseFee := range history.BaseFee[:len(history.BaseFee)-1] {
+		sum.Add(sum, (*big.Int)(baseFee))
+	}
+	average := sum.Div(sum, big.NewInt(int64(len(history.BaseFee)-1)))
+	if nextBaseFee.Cmp(average) <= 0 {
+		return new(big.Int).Set(nextBaseFee)
+	}
+	expected := new(big.Int).Mul(nextBaseFee, nextBaseFee)
+	expected.Div(expected, average)
+	maxExpected := new(big.Int).Mul(nextBaseFee, big.NewInt(2))
+	if expected.Cmp(maxExpected) > 0 {
+		return maxExpected
+	}
+	return expected
+}
+
+// Estimate computes the low, normal and high fee targets from a fee history with the priority fees
+// at the percentiles returned by Percentiles(). The priority fee of a target is the median of the
+// priority fees at its percentile over all non-empty blocks. The fee cap adds headroom over the
+// expected base fee, which follows the trend of the history.
+func Estimate(history *History) ([]*ethtypes.FeeTarget, error) {
+	blocks := len(history.GasUsedRatio)
+	if blocks == 0 || len(history.BaseFee) != blocks+1 || len(history.Reward) != blocks {
+		return nil, errp.New("invalid fee history")
+	}
+	for _, baseFee := range history.BaseFee {
+		if baseFee == nil || (*big.Int)(baseFee).Sign() <= 0 {
+			return nil, errp.New("the node does not support EIP-1559")
+		}
+	}
+	for _, rewards := range history.Reward {
+		if len(rewards) != len(targets) {
+			return nil, errp.New("invalid fee history")
+		}
+	}
+	baseFee := expectedBaseFee(history)
+	result := make([]*ethtypes.FeeTarget, len(targets))
+	for i, target := range targets {
+		tips := []*big.Int{}
+		for block, rewards := range history.Reward {
+			// Empty blocks report a priority fee of zero, which says nothing about the fees needed.
+			if history.GasUsedRatio[block] == 0 || rewards[i] == nil {
+				continue
+			}
+			tips = append(tips, (*big.Int)(rewards[i]))
+		}
+		tip := median(tips)
+		if tip.Cmp(minTip) < 0 {
+			tip.Set(minTip)
+		}
+		feeCap := new(big.Int).Mul(baseFee, big.NewInt(target.baseFeePermille))
+		feeCap.Div(feeCap, big.NewInt(1000))
+		feeCap.Add(feeCap, tip)
+		result[i] = &ethtypes.FeeTarget{
+			TargetCode: target.code,
+			GasTipCap:  tip,
+			GasFeeCap:  feeCap,
+		}
+	}
+	return result, nil
+}
+
+// Percentiles returns the percentiles of the priority fees Estimate() expects in the history.
+func Percentiles() []float64 {
+	percentiles := make([]float64, len(targets))
+	for i, target := range targets {
+		percentiles[i] = target.percentile
+	}
+	return percentiles
+}
+
+// Estimator estimates fee targets from the fee history of a JSON-RPC node. Its FeeTargets() can be
+// used as the FeeTargets() of an rpcclient.Interface.
+type Estimator struct {
+	caller Caller
+}
+
+// NewEstimator creates an estimator using the node of the given caller.
+func NewEstimator(caller Caller) *Estimator {
+	return &Estimator{caller: caller}
+}
+
+// FeeTargets returns the low, normal and high fee targets estimated from the recent blocks.
+func (estimator *Estimator) FeeTargets(ctx context.Context) ([]*ethtypes.FeeTarget, error) {
+	history, err := Fetch(ctx, estimator.caller, blockCount, Percentiles())
+	if err != nil {
+		return nil, err
+	}
+	return Estimate(history)
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feehistory
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func loadFixture(t *testing.T, name string) *History {
+	t.Helper()
+	contents, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	var history History
+	require.NoError(t, json.Unmarshal(contents, &history))
+	return &history
+}
+
+// fakeCaller answers `eth_feeHistory` with a fixture and records the arguments.
+type fakeCaller struct {
+	t       *testing.T
+	fixture string
+	method  string
+	args    []interface{}
+}
+
+func (caller *fakeCaller) CallContext(
+	ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	caller.method = method
+	caller.args = args
+	contents, err := os.ReadFile(filepath.Join("testdata", caller.fixture))
+	require.NoError(caller.t, err)
+	return json.Unmarshal(contents, result)
+}
+
+func TestEstimate(t *testing.T) {
+	t.Run("rising base fee", func(t *testing.T) {
+		// The base fee rose from 18 to 31.4 Gwei, so a base fee of 45.6 Gwei is planned for. Block 6
+		// is empty and ignored.
+		feeTargets, err := Estimate(loadFixture(t, "mainnet.json"))
+		require.NoError(t, err)
+		require.Equal(t, []*ethtypes.FeeTarget{
+			{
+				TargetCode: accounts.FeeTargetCodeLow,
+				GasTipCap:  big.NewInt(90000000),
+				GasFeeCap:  big.NewInt(51440820702),
+			},
+			{
+				TargetCode: accounts.FeeTargetCodeNormal,
+				GasTipCap:  big.NewInt(1600000000),
+				GasFeeCap:  big.NewInt(58656467447),
+			},
+			{
+				TargetCode: accounts.FeeTargetCodeHigh,
+				GasTipCap:  big.NewInt(3100000000),
+				GasFeeCap:  big.NewInt(94390347916),
+			},
+		}, feeTargets)
+	})
+
+	t.Run("falling base fee without priority fees", func(t *testing.T) {
+		// The base fee of the next block is used as is, and the priority fee is the minimum.
+		feeTargets, err := Estimate(loadFixture(t, "l2.json"))
+		require.NoError(t, err)
+		require.Equal(t, []*ethtypes.FeeTarget{
+			{
+				TargetCode: accounts.FeeTargetCodeLow,
+				GasTipCap:  big.NewInt(1),
+				GasFeeCap:  big.NewInt(61061219),
+			},
+			{
+				TargetCode: accounts.FeeTargetCodeNormal,
+				GasTipCap:  big.NewInt(1),
+				GasFeeCap:  big.NewInt(67845799),
+			},
+			{
+				TargetCode: accounts.FeeTargetCodeHigh,
+				GasTipCap:  big.NewInt(1),
+				GasFeeCap:  big.NewInt(108553279),
+			},
+		}, feeTargets)
+	})
+
+	t.Run("base fee doubling", func(t *testing.T) {
+		// The extrapolated rise is capped at twice the next base fee.
+		history := &History{
+			BaseFee: []*hexutil.Big{
+				(*hexutil.Big)(big.NewInt(1e9)),
+				(*hexutil.Big)(big.NewInt(10e9)),
+			},
+			GasUsedRatio: []float64{1},
+			Reward: [][]*hexutil.Big{{
+				(*hexutil.Big)(big.NewInt(1e8)),
+				(*hexutil.Big)(big.NewInt(2e8)),
+				(*hexutil.Big)(big.NewInt(3e8)),
+			}},
+		}
+		feeTargets, err := Estimate(history)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(20e9*1125/1000+1e8), feeTargets[0].GasFeeCap)
+		require.Equal(t, big.NewInt(20e9*2+3e8), feeTargets[2].GasFeeCap)
+	})
+
+	t.Run("pre-London", func(t *testing.T) {
+		history := loadFixture(t, "mainnet.json")
+		history.BaseFee[3] = (*hexutil.Big)(big.NewInt(0))
+		_, err := Estimate(history)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := Estimate(&History{})
+		require.Error(t, err)
+
+		history := loadFixture(t, "mainnet.json")
+		history.BaseFee = history.BaseFee[1:]
+		_, err = Estimate(history)
+		require.Error(t, err)
+
+		history = loadFixture(t, "mainnet.json")
+		history.Reward[0] = history.Reward[0][:2]
+		_, err = Estimate(history)
+		require.Error(t, err)
+	})
+}
+
+func TestMedian(t *testing.T) {
+	require.Equal(t, big.NewInt(0), median(nil))
+	require.Equal(t, big.NewInt(2), median([]*big.Int{big.NewInt(3), big.NewInt(1), big.NewInt(2)}))
+	require.Equal(t, big.NewInt(2), median([]*big.Int{big.NewInt(4), big.NewInt(1), big.NewInt(3), big.NewInt(1)}))
+}
+
+func TestEstimator(t *testing.T) {
+	caller := &fakeCaller{t: t, fixture: "mainnet.json"}
+	feeTargets, err := NewEstimator(caller).FeeTargets(context.Background())
+	require.NoError(t, err)
+	require.Len(t, feeTargets, 3)
+	require.Equal(t, "eth_feeHistory", caller.method)
+	require.Equal(t,
+		[]interface{}{hexutil.Uint64(blockCount), "latest", []float64{10, 50, 75}},
+		caller.args)
+}
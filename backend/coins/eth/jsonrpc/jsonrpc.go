@@ -0,0 +1,274 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonrpc is a client for a plain Ethereum JSON-RPC node, e.g. a self-hosted Geth or
+// Nethermind node, which can be used instead of EtherScan.
+package jsonrpc
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"sync"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/feehistory"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultScanBlocks is the number of recent blocks scanned for ETH transactions if no indexer is
+// configured, about three hours of blocks. Scanning fetches all transactions of each block.
+const DefaultScanBlocks = 1000
+
+var (
+	_ rpcclient.Interface      = (*Client)(nil)
+	_ eth.TransactionsSource   = (*Client)(nil)
+	_ eth.ERC20TokenDiscoverer = (*Client)(nil)
+	_ eth.ERC20ApprovalLister  = (*Client)(nil)
+)
+
+// Client is an rpcclient.Interface and eth.TransactionsSource backed by a JSON-RPC node.
+//
+// Standard nodes can't list the transactions of an address. ERC20 transfers are found in the
+// `Transfer` event logs of the token contracts. ETH transactions are fetched from an indexer if one
+// is configured, otherwise the most recent blocks are scanned. Transactions found by scanning are
+// kept for the lifetime of the client, but older transactions and internal transactions
+// (transfers by contracts) are not found.
+type Client struct {
+	rpc          *rpc.Client
+	feeEstimator *feehistory.Estimator
+	// indexer is an optional source of ETH transactions, e.g. an Etherscan-compatible API.
+	indexer    eth.TransactionsSource
+	scanBlocks uint64
+
+	// scansLock covers scans.
+	scansLock sync.Mutex
+	scans     map[common.Address]*blockScan
+
+	log *logrus.Entry
+}
+
+// NewClient creates a client for the JSON-RPC node at the given HTTP(S) URL. indexer can be nil,
+// in which case the last scanBlocks blocks are scanned for ETH transactions. If scanBlocks is 0,
+// DefaultScanBlocks is used.
+func NewClient(url string, httpClient *http.Client, indexer eth.TransactionsSource, scanBlocks uint64) (*Client, error) {
+	client, err := rpc.DialHTTPWithClient(url, httpClient)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if scanBlocks == 0 {
+		scanBlocks = DefaultScanBlocks
+	}
+	return &Client{
+		rpc:          client,
+		feeEstimator: feehistory.NewEstimator(client),
+		indexer:      indexer,
+		scanBlocks:   scanBlocks,
+		scans:        map[common.Address]*blockScan{},
+		log:          logging.Get().WithGroup("jsonrpc"),
+	}, nil
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
+
+func toCallArg(msg ethereum.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.GasFeeCap != nil {
+		arg["maxFeePerGas"] = (*hexutil.Big)(msg.GasFeeCap)
+	}
+	if msg.GasTipCap != nil {
+		arg["maxPriorityFeePerGas"] = (*hexutil.Big)(msg.GasTipCap)
+	}
+	return arg
+}
+
+// TransactionReceiptWithBlockNumber implements rpcclient.Interface.
+func (client *Client) TransactionReceiptWithBlockNumber(
+	ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+	var result *rpcclient.RPCTransactionReceipt
+	if err := client.rpc.CallContext(ctx, &result, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return result, nil
+}
+
+// TransactionByHash implements rpcclient.Interface.
+func (client *Client) TransactionByHash(
+	ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	var result *rpcclient.RPCTransaction
+	if err := client.rpc.CallContext(ctx, &result, "eth_getTransactionByHash", hash); err != nil {
+		return nil, false, errp.WithStack(err)
+	}
+	if result == nil {
+		return nil, false, errp.WithStack(ethereum.NotFound)
+	}
+	return &result.Transaction, result.BlockNumber == nil, nil
+}
+
+// BlockNumber implements rpcclient.Interface.
+func (client *Client) BlockNumber(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := client.rpc.CallContext(ctx, &result, "eth_blockNumber"); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return (*big.Int)(&result), nil
+}
+
+// ChainID returns the chain ID of the network the node is connected to.
+func (client *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := client.rpc.CallContext(ctx, &result, "eth_chainId"); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return (*big.Int)(&result), nil
+}
+
+// Balance implements rpcclient.Interface.
+func (client *Client) Balance(ctx context.Context, account common.Address) (*big.Int, error) {
+	var result hexutil.Big
+	if err := client.rpc.CallContext(ctx, &result, "eth_getBalance", account, "latest"); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return (*big.Int)(&result), nil
+}
+
+// ERC20Balance implements rpcclient.Interface.
+func (client *Client) ERC20Balance(account common.Address, erc20Token *erc20.Token) (*big.Int, error) {
+	return erc20.BalanceOf(context.TODO(), client, erc20Token.ContractAddress(), account)
+}
+
+// CallContract implements rpcclient.Interface.
+func (client *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	err := client.rpc.CallContext(ctx, &result, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return result, nil
+}
+
+// SendTransaction implements rpcclient.Interface.
+func (client *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	encodedTx, err := tx.MarshalBinary()
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	return errp.WithStack(
+		client.rpc.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(encodedTx)))
+}
+
+// PendingNonceAt implements rpcclient.Interface.
+func (client *Client) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var result hexutil.Uint64
+	if err := client.rpc.CallContext(ctx, &result, "eth_getTransactionCount", account, "pending"); err != nil {
+		return 0, errp.WithStack(err)
+	}
+	return uint64(result), nil
+}
+
+// NonceAt implements rpcclient.Interface.
+func (client *Client) NonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var result hexutil.Uint64
+	if err := client.rpc.CallContext(ctx, &result, "eth_getTransactionCount", account, "latest"); err != nil {
+		return 0, errp.WithStack(err)
+	}
+	return uint64(result), nil
+}
+
+// EstimateGas implements rpcclient.Interface.
+func (client *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	var result hexutil.Uint64
+	if err := client.rpc.CallContext(ctx, &result, "eth_estimateGas", toCallArg(msg)); err != nil {
+		return 0, errp.WithStack(err)
+	}
+	return uint64(result), nil
+}
+
+// SuggestGasPrice implements rpcclient.Interface.
+func (client *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := client.rpc.CallContext(ctx, &result, "eth_gasPrice"); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return (*big.Int)(&result), nil
+}
+
+// FeeTargets implements rpcclient.Interface. The low, normal and high targets are estimated from
+// the fee history of the node. If the node does not support `eth_feeHistory`, the single target
+// suggested by the node is returned.
+func (client *Client) FeeTargets(ctx context.Context) ([]*ethtypes.FeeTarget, error) {
+	feeTargets, err := client.feeEstimator.FeeTargets(ctx)
+	if err == nil {
+		return feeTargets, nil
+	}
+	client.log.WithError(err).Warning("Could not estimate fees from the fee history")
+	return client.suggestedFeeTargets(ctx)
+}
+
+// suggestedFeeTargets returns the target suggested by the node. The node only suggests a priority
+// fee, so there is a single target, with a fee cap of twice the current base fee plus the priority
+// fee, like most wallets use by default. This allows the base fee to double before the transaction
+// is delayed.
+func (client *Client) suggestedFeeTargets(ctx context.Context) ([]*ethtypes.FeeTarget, error) {
+	var header *types.Header
+	if err := client.rpc.CallContext(ctx, &header, "eth_getBlockByNumber", "latest", false); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if header == nil || header.BaseFee == nil {
+		return nil, errp.New("the node does not support EIP-1559")
+	}
+	var tip hexutil.Big
+	if err := client.rpc.CallContext(ctx, &tip, "eth_maxPriorityFeePerGas"); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	feeCap := new(big.Int).Add(new(big.Int).Mul(header.BaseFee, big.NewInt(2)), (*big.Int)(&tip))
+	return []*ethtypes.FeeTarget{
+		{
+			TargetCode: accounts.FeeTargetCodeNormal,
+			GasFeeCap:  feeCap,
+			GasTipCap:  (*big.Int)(&tip),
+		},
+	}, nil
+}
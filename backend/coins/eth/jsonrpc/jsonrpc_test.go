@@ -0,0 +1,523 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/feehistory"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ourAddress   = common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	otherAddress = common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	tokenAddress = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	nftAddress   = common.HexToAddress("0x2222222222222222222222222222222222222222")
+)
+
+func blockTime(number uint64) time.Time {
+	return time.Unix(int64(1700000000+12*number), 0)
+}
+
+type logFilter struct {
+	Address *common.Address `json:"address"`
+	Topics  []*common.Hash  `json:"topics"`
+}
+
+type callArgs struct {
+	To   common.Address `json:"to"`
+	Data hexutil.Bytes  `json:"data"`
+}
+
+// fakeNode serves the `eth` namespace of a JSON-RPC node from an in-memory chain.
+type fakeNode struct {
+	t      *testing.T
+	parsed abi.ABI
+
+	mu            sync.Mutex
+	tip           uint64
+	transactions  map[uint64][]*rpcTransaction
+	receipts      map[common.Hash]*rpcReceipt
+	logs          []types.Log
+	tokens        map[common.Address]erc20.Metadata
+	tokenBalances map[common.Address]*big.Int
+	// feeHistory is returned by `eth_feeHistory`. If nil, the method is not supported.
+	feeHistory *feehistory.History
+	// blocksFetched counts the requested blocks with full transactions.
+	blocksFetched int
+}
+
+func (node *fakeNode) BlockNumber() hexutil.Uint64 {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	return hexutil.Uint64(node.tip)
+}
+
+// ChainId serves `eth_chainId`, the method name is derived from the RPC method name.
+func (node *fakeNode) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1))
+}
+
+func (node *fakeNode) GetBalance(address common.Address, block string) *hexutil.Big {
+	require.Equal(node.t, ourAddress, address)
+	require.Equal(node.t, "latest", block)
+	return (*hexutil.Big)(big.NewInt(1e18))
+}
+
+func (node *fakeNode) MaxPriorityFeePerGas() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(2e9))
+}
+
+func (node *fakeNode) FeeHistory(
+	blocks hexutil.Uint64, lastBlock string, percentiles []float64) (*feehistory.History, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if node.feeHistory == nil {
+		return nil, errp.New("the method eth_feeHistory does not exist/is not available")
+	}
+	require.Equal(node.t, "latest", lastBlock)
+	require.Equal(node.t, feehistory.Percentiles(), percentiles)
+	return node.feeHistory, nil
+}
+
+func (node *fakeNode) GetBlockByNumber(number string, full bool) (interface{}, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if number == "latest" {
+		return &types.Header{
+			Number:     new(big.Int).SetUint64(node.tip),
+			Difficulty: big.NewInt(0),
+			BaseFee:    big.NewInt(30e9),
+		}, nil
+	}
+	height, err := hexutil.DecodeUint64(number)
+	if err != nil {
+		return nil, err
+	}
+	if height > node.tip {
+		return nil, nil
+	}
+	header := rpcHeader{
+		Number:    hexutil.Uint64(height),
+		Timestamp: hexutil.Uint64(blockTime(height).Unix()),
+	}
+	if !full {
+		hashes := []common.Hash{}
+		for _, tx := range node.transactions[height] {
+			hashes = append(hashes, tx.Hash)
+		}
+		return map[string]interface{}{
+			"number":       header.Number,
+			"timestamp":    header.Timestamp,
+			"transactions": hashes,
+		}, nil
+	}
+	node.blocksFetched++
+	return &rpcBlock{
+		rpcHeader:    header,
+		Transactions: append([]*rpcTransaction{}, node.transactions[height]...),
+	}, nil
+}
+
+func (node *fakeNode) GetTransactionReceipt(hash common.Hash) *rpcReceipt {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	return node.receipts[hash]
+}
+
+func (node *fakeNode) GetTransactionByHash(hash common.Hash) *rpcTransaction {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	for _, txs := range node.transactions {
+		for _, tx := range txs {
+			if tx.Hash == hash {
+				return tx
+			}
+		}
+	}
+	return nil
+}
+
+func (node *fakeNode) GetLogs(filter logFilter) []types.Log {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	result := []types.Log{}
+	for _, log := range node.logs {
+		if filter.Address != nil && *filter.Address != log.Address {
+			continue
+		}
+		matches := len(filter.Topics) <= len(log.Topics)
+		for i, topic := range filter.Topics {
+			if matches && topic != nil && *topic != log.Topics[i] {
+				matches = false
+			}
+		}
+		if matches {
+			result = append(result, log)
+		}
+	}
+	return result
+}
+
+func (node *fakeNode) Call(args callArgs, block string) (hexutil.Bytes, error) {
+	require.Equal(node.t, "latest", block)
+	balanceOf := node.parsed.Methods["balanceOf"]
+	if bytes.HasPrefix(args.Data, balanceOf.ID) {
+		return balanceOf.Outputs.Pack(node.tokenBalances[args.To])
+	}
+	token, ok := node.tokens[args.To]
+	if !ok {
+		return hexutil.Bytes{}, nil
+	}
+	for name, value := range map[string]interface{}{
+		"name":     token.Name,
+		"symbol":   token.Symbol,
+		"decimals": token.Decimals,
+	} {
+		method := node.parsed.Methods[name]
+		if bytes.Equal(args.Data, method.ID) {
+			return method.Outputs.Pack(value)
+		}
+	}
+	return nil, errp.New("unexpected call")
+}
+
+func (node *fakeNode) addTransfer(
+	contract common.Address, height uint64, hash common.Hash, index uint, from, to common.Address, value int64) {
+	node.logs = append(node.logs, types.Log{
+		Address: contract,
+		Topics: []common.Hash{
+			transferTopic,
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data:        common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+		BlockNumber: height,
+		TxHash:      hash,
+		Index:       index,
+	})
+}
+
+func newFakeNode(t *testing.T, indexer *indexerMock, scanBlocks uint64) (*fakeNode, *Client) {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(erc20.IERC20ABI))
+	require.NoError(t, err)
+	node := &fakeNode{
+		t:             t,
+		parsed:        parsed,
+		tip:           20,
+		transactions:  map[uint64][]*rpcTransaction{},
+		receipts:      map[common.Hash]*rpcReceipt{},
+		tokens:        map[common.Address]erc20.Metadata{},
+		tokenBalances: map[common.Address]*big.Int{},
+	}
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName("eth", node))
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	t.Cleanup(server.Stop)
+
+	var client *Client
+	if indexer == nil {
+		client, err = NewClient(httpServer.URL, http.DefaultClient, nil, scanBlocks)
+	} else {
+		client, err = NewClient(httpServer.URL, http.DefaultClient, indexer, scanBlocks)
+	}
+	require.NoError(t, err)
+	return node, client
+}
+
+type indexerMock struct {
+	called bool
+}
+
+func (indexer *indexerMock) Transactions(
+	blockTipHeight *big.Int,
+	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*accounts.TransactionData, error) {
+	indexer.called = true
+	return []*accounts.TransactionData{{TxID: "indexed"}}, nil
+}
+
+func TestClient(t *testing.T) {
+	node, client := newFakeNode(t, nil, 0)
+	node.tokenBalances[tokenAddress] = big.NewInt(12345)
+	ctx := context.Background()
+
+	blockNumber, err := client.BlockNumber(ctx)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(20), blockNumber)
+
+	chainID, err := client.ChainID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1), chainID)
+
+	balance, err := client.Balance(ctx, ourAddress)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1e18), balance)
+
+	tokenBalance, err := client.ERC20Balance(ourAddress, erc20.NewToken(tokenAddress.Hex(), 18))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(12345), tokenBalance)
+
+	// Without `eth_feeHistory`, the target suggested by the node is used.
+	feeTargets, err := client.FeeTargets(ctx)
+	require.NoError(t, err)
+	require.Len(t, feeTargets, 1)
+	require.Equal(t, accounts.FeeTargetCodeNormal, feeTargets[0].TargetCode)
+	require.Equal(t, big.NewInt(2e9), feeTargets[0].GasTipCap)
+	require.Equal(t, big.NewInt(62e9), feeTargets[0].GasFeeCap)
+}
+
+func TestFeeTargets(t *testing.T) {
+	node, client := newFakeNode(t, nil, 0)
+	gwei := func(amount int64) *hexutil.Big {
+		return (*hexutil.Big)(new(big.Int).Mul(big.NewInt(amount), big.NewInt(1e9)))
+	}
+	node.feeHistory = &feehistory.History{
+		OldestBlock:  (*hexutil.Big)(big.NewInt(19)),
+		BaseFee:      []*hexutil.Big{gwei(40), gwei(40), gwei(30)},
+		GasUsedRatio: []float64{0.5, 0.2},
+		Reward:       [][]*hexutil.Big{{gwei(1), gwei(2), gwei(3)}, {gwei(1), gwei(2), gwei(5)}},
+	}
+	feeTargets, err := client.FeeTargets(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []*ethtypes.FeeTarget{
+		{
+			TargetCode: accounts.FeeTargetCodeLow,
+			GasTipCap:  gwei(1).ToInt(),
+			GasFeeCap:  big.NewInt(34.75e9),
+		},
+		{
+			TargetCode: accounts.FeeTargetCodeNormal,
+			GasTipCap:  gwei(2).ToInt(),
+			GasFeeCap:  big.NewInt(39.5e9),
+		},
+		{
+			TargetCode: accounts.FeeTargetCodeHigh,
+			GasTipCap:  gwei(4).ToInt(),
+			GasFeeCap:  big.NewInt(64e9),
+		},
+	}, feeTargets)
+}
+
+func TestERC20Transactions(t *testing.T) {
+	node, client := newFakeNode(t, nil, 0)
+	receive := common.HexToHash("0x01")
+	send := common.HexToHash("0x02")
+	sendSelf := common.HexToHash("0x03")
+	node.addTransfer(tokenAddress, 5, receive, 0, otherAddress, ourAddress, 100)
+	node.addTransfer(tokenAddress, 8, send, 3, ourAddress, otherAddress, 40)
+	node.addTransfer(tokenAddress, 9, sendSelf, 0, ourAddress, ourAddress, 1)
+	// Transfers of other tokens and NFT transfers are ignored.
+	node.addTransfer(nftAddress, 9, sendSelf, 1, ourAddress, otherAddress, 1)
+	node.logs = append(node.logs, types.Log{
+		Address: tokenAddress,
+		Topics: []common.Hash{
+			transferTopic,
+			common.BytesToHash(ourAddress.Bytes()),
+			common.BytesToHash(otherAddress.Bytes()),
+			common.BigToHash(big.NewInt(1)),
+		},
+		BlockNumber: 9,
+		TxHash:      common.HexToHash("0x04"),
+	})
+	for nonce, hash := range []common.Hash{send, sendSelf} {
+		node.transactions[8+uint64(nonce)] = []*rpcTransaction{{
+			Hash:  hash,
+			From:  ourAddress,
+			To:    &tokenAddress,
+			Value: (*hexutil.Big)(big.NewInt(0)),
+			Nonce: hexutil.Uint64(nonce),
+		}}
+		status := hexutil.Uint64(types.ReceiptStatusSuccessful)
+		node.receipts[hash] = &rpcReceipt{
+			Status:            &status,
+			GasUsed:           50000,
+			EffectiveGasPrice: (*hexutil.Big)(big.NewInt(1e9)),
+		}
+	}
+
+	txs, err := client.Transactions(
+		big.NewInt(20), ourAddress, big.NewInt(20), erc20.NewToken(tokenAddress.Hex(), 18))
+	require.NoError(t, err)
+	require.Len(t, txs, 3)
+
+	require.Equal(t, sendSelf.Hex(), txs[0].TxID)
+	require.Equal(t, accounts.TxTypeSendSelf, txs[0].Type)
+	require.Equal(t, 12, txs[0].NumConfirmations)
+	require.Equal(t, accounts.TxStatusComplete, txs[0].Status)
+	require.Equal(t, uint64(1), *txs[0].Nonce)
+
+	require.Equal(t, send.Hex(), txs[1].TxID)
+	require.Equal(t, accounts.TxTypeSend, txs[1].Type)
+	require.Equal(t, coin.NewAmountFromInt64(40), txs[1].Amount)
+	require.Equal(t, otherAddress.Hex(), txs[1].Addresses[0].Address)
+	require.Equal(t, coin.NewAmountFromInt64(50000e9), *txs[1].Fee)
+	require.True(t, txs[1].FeeIsDifferentUnit)
+	require.True(t, txs[1].IsErc20)
+	require.Equal(t, uint64(0), *txs[1].Nonce)
+	require.Equal(t, blockTime(8), *txs[1].Timestamp)
+
+	require.Equal(t, receive.Hex(), txs[2].TxID)
+	require.Equal(t, accounts.TxTypeReceive, txs[2].Type)
+	require.Equal(t, coin.NewAmountFromInt64(100), txs[2].Amount)
+	require.Equal(t, 5, txs[2].Height)
+	require.Nil(t, txs[2].Fee)
+	require.Nil(t, txs[2].Nonce)
+}
+
+func TestScanTransactions(t *testing.T) {
+	node, client := newFakeNode(t, nil, 5)
+	node.tip = 10
+	addTx := func(height uint64, hash common.Hash, from common.Address, to *common.Address) {
+		node.mu.Lock()
+		defer node.mu.Unlock()
+		node.transactions[height] = append(node.transactions[height], &rpcTransaction{
+			Hash:  hash,
+			From:  from,
+			To:    to,
+			Value: (*hexutil.Big)(big.NewInt(1000)),
+		})
+		status := hexutil.Uint64(types.ReceiptStatusSuccessful)
+		if hash == common.HexToHash("0x03") {
+			status = hexutil.Uint64(types.ReceiptStatusFailed)
+		}
+		node.receipts[hash] = &rpcReceipt{
+			Status:            &status,
+			GasUsed:           21000,
+			EffectiveGasPrice: (*hexutil.Big)(big.NewInt(1e9)),
+		}
+	}
+	// Too old to be found.
+	addTx(3, common.HexToHash("0x01"), otherAddress, &ourAddress)
+	addTx(7, common.HexToHash("0x02"), ourAddress, &otherAddress)
+	addTx(10, common.HexToHash("0x03"), otherAddress, &ourAddress)
+	// Unrelated.
+	addTx(10, common.HexToHash("0x04"), otherAddress, &tokenAddress)
+
+	txs, err := client.Transactions(big.NewInt(10), ourAddress, big.NewInt(10), nil)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	require.Equal(t, 5, node.blocksFetched)
+	require.Equal(t, common.HexToHash("0x03").Hex(), txs[0].TxID)
+	require.Equal(t, accounts.TxTypeReceive, txs[0].Type)
+	require.Equal(t, accounts.TxStatusFailed, txs[0].Status)
+	require.Equal(t, common.HexToHash("0x02").Hex(), txs[1].TxID)
+	require.Equal(t, accounts.TxTypeSend, txs[1].Type)
+	require.Equal(t, coin.NewAmountFromInt64(21000e9), *txs[1].Fee)
+	require.Equal(t, uint64(0), *txs[1].Nonce)
+	require.False(t, txs[1].IsErc20)
+
+	// Transactions found before are kept when they move out of the scanned range.
+	node.mu.Lock()
+	node.tip = 12
+	node.blocksFetched = 0
+	node.mu.Unlock()
+	addTx(12, common.HexToHash("0x05"), ourAddress, nil)
+	contractAddress := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	node.receipts[common.HexToHash("0x05")].ContractAddress = &contractAddress
+
+	txs, err = client.Transactions(big.NewInt(12), ourAddress, big.NewInt(12), nil)
+	require.NoError(t, err)
+	require.Equal(t, 5, node.blocksFetched)
+	require.Len(t, txs, 3)
+	require.Equal(t, common.HexToHash("0x05").Hex(), txs[0].TxID)
+	require.Equal(t, contractAddress.Hex(), txs[0].Addresses[0].Address)
+	require.Equal(t, common.HexToHash("0x03").Hex(), txs[1].TxID)
+	require.Equal(t, common.HexToHash("0x02").Hex(), txs[2].TxID)
+}
+
+func TestTransactionsIndexer(t *testing.T) {
+	indexer := &indexerMock{}
+	node, client := newFakeNode(t, indexer, 0)
+	node.addTransfer(tokenAddress, 5, common.HexToHash("0x01"), 0, otherAddress, ourAddress, 100)
+
+	txs, err := client.Transactions(big.NewInt(20), ourAddress, big.NewInt(20), nil)
+	require.NoError(t, err)
+	require.Equal(t, []*accounts.TransactionData{{TxID: "indexed"}}, txs)
+	require.Zero(t, node.blocksFetched)
+
+	// Token transfers are still rebuilt from the logs of the node.
+	indexer.called = false
+	txs, err = client.Transactions(
+		big.NewInt(20), ourAddress, big.NewInt(20), erc20.NewToken(tokenAddress.Hex(), 18))
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	require.False(t, indexer.called)
+}
+
+func TestERC20TokenActivity(t *testing.T) {
+	node, client := newFakeNode(t, nil, 0)
+	secondToken := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	node.tokens[tokenAddress] = erc20.Metadata{Name: "Token", Symbol: "TKN", Decimals: 6}
+	node.tokens[secondToken] = erc20.Metadata{Name: "Second", Symbol: "SEC", Decimals: 18}
+	node.addTransfer(secondToken, 3, common.HexToHash("0x01"), 0, otherAddress, ourAddress, 1)
+	node.addTransfer(tokenAddress, 5, common.HexToHash("0x02"), 0, otherAddress, ourAddress, 100)
+	node.addTransfer(tokenAddress, 8, common.HexToHash("0x03"), 0, ourAddress, otherAddress, 40)
+	// Not a token contract.
+	node.addTransfer(nftAddress, 9, common.HexToHash("0x04"), 0, otherAddress, ourAddress, 1)
+
+	activities, err := client.ERC20TokenActivity(ourAddress)
+	require.NoError(t, err)
+	require.Equal(t, []*erc20.TokenActivity{
+		{ContractAddress: secondToken, Name: "Second", Symbol: "SEC", Decimals: 18, Transfers: 1},
+		{ContractAddress: tokenAddress, Name: "Token", Symbol: "TKN", Decimals: 6, Transfers: 2},
+	}, activities)
+}
+
+func TestERC20Approvals(t *testing.T) {
+	node, client := newFakeNode(t, nil, 0)
+	approve := func(contract common.Address, owner, spender common.Address, topics ...common.Hash) {
+		node.logs = append(node.logs, types.Log{
+			Address: contract,
+			Topics: append([]common.Hash{
+				approvalTopic,
+				common.BytesToHash(owner.Bytes()),
+				common.BytesToHash(spender.Bytes()),
+			}, topics...),
+			Data: common.LeftPadBytes(big.NewInt(1).Bytes(), 32),
+		})
+	}
+	approve(tokenAddress, ourAddress, otherAddress)
+	approve(tokenAddress, otherAddress, ourAddress)
+	// ERC721 approval of a single token ID.
+	approve(nftAddress, ourAddress, otherAddress, common.BigToHash(big.NewInt(7)))
+
+	approvals, err := client.ERC20Approvals(ourAddress)
+	require.NoError(t, err)
+	require.Equal(t, []*erc20.Approval{
+		{ContractAddress: tokenAddress, Spender: otherAddress},
+	}, approvals)
+}
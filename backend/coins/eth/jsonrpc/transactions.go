@@ -0,0 +1,511 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonrpc
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// batchSize is the number of requests sent to the node in one batch.
+const batchSize = 50
+
+// transferTopic is the topic of the ERC20 `Transfer(address,address,uint256)` event.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// approvalTopic is the topic of the ERC20 `Approval(address,address,uint256)` event.
+var approvalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+
+type rpcTransaction struct {
+	Hash  common.Hash     `json:"hash"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Value *hexutil.Big    `json:"value"`
+	Nonce hexutil.Uint64  `json:"nonce"`
+}
+
+type rpcHeader struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+}
+
+type rpcBlock struct {
+	rpcHeader
+	Transactions []*rpcTransaction `json:"transactions"`
+}
+
+type rpcReceipt struct {
+	// Status is nil for transactions before the Byzantium fork.
+	Status            *hexutil.Uint64 `json:"status"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	EffectiveGasPrice *hexutil.Big    `json:"effectiveGasPrice"`
+	ContractAddress   *common.Address `json:"contractAddress"`
+}
+
+// minedTransaction is an ETH transaction or an ERC20 transfer in a block.
+type minedTransaction struct {
+	hash  common.Hash
+	from  common.Address
+	to    common.Address
+	value *big.Int
+	// nonce is nil if unknown.
+	nonce     *uint64
+	height    uint64
+	timestamp time.Time
+	// receipt is nil if it was not fetched, in which case the fee is unknown.
+	receipt *rpcReceipt
+}
+
+// blockScan is the result of scanning blocks for the ETH transactions of an address.
+type blockScan struct {
+	// scannedTo is the last scanned block, 0 if no blocks were scanned yet.
+	scannedTo    uint64
+	transactions []*minedTransaction
+}
+
+func (tx *minedTransaction) transactionData(
+	address common.Address, blockTipHeight *big.Int, isERC20 bool) *accounts.TransactionData {
+	var txType accounts.TxType
+	switch {
+	case tx.from == address && tx.to == address:
+		txType = accounts.TxTypeSendSelf
+	case tx.from == address:
+		txType = accounts.TxTypeSend
+	default:
+		txType = accounts.TxTypeReceive
+	}
+	numConfirmations := 0
+	if tip := blockTipHeight.Uint64(); tip >= tx.height {
+		numConfirmations = int(tip - tx.height + 1)
+	}
+	status := accounts.TxStatusPending
+	switch {
+	case tx.receipt != nil && tx.receipt.Status != nil &&
+		uint64(*tx.receipt.Status) == types.ReceiptStatusFailed:
+		status = accounts.TxStatusFailed
+	case numConfirmations >= ethtypes.NumConfirmationsComplete:
+		status = accounts.TxStatusComplete
+	}
+	var fee *coin.Amount
+	var gas uint64
+	if tx.receipt != nil {
+		gas = uint64(tx.receipt.GasUsed)
+		if tx.receipt.EffectiveGasPrice != nil {
+			amount := coin.NewAmount(new(big.Int).Mul(
+				new(big.Int).SetUint64(gas), tx.receipt.EffectiveGasPrice.ToInt()))
+			fee = &amount
+		}
+	}
+	timestamp := tx.timestamp
+	amount := coin.NewAmount(tx.value)
+	return &accounts.TransactionData{
+		Fee:                      fee,
+		FeeIsDifferentUnit:       isERC20,
+		Timestamp:                &timestamp,
+		TxID:                     tx.hash.Hex(),
+		InternalID:               tx.hash.Hex(),
+		Height:                   int(tx.height),
+		NumConfirmations:         numConfirmations,
+		NumConfirmationsComplete: ethtypes.NumConfirmationsComplete,
+		Status:                   status,
+		Type:                     txType,
+		Amount:                   amount,
+		Addresses:                []accounts.AddressAndAmount{{Address: tx.to.Hex(), Amount: amount}},
+		Gas:                      gas,
+		Nonce:                    tx.nonce,
+		IsErc20:                  isERC20,
+	}
+}
+
+// batchCall sends the requests to the node in batches of batchSize.
+func (client *Client) batchCall(ctx context.Context, elems []rpc.BatchElem) error {
+	for start := 0; start < len(elems); start += batchSize {
+		end := start + batchSize
+		if end > len(elems) {
+			end = len(elems)
+		}
+		if err := client.rpc.BatchCallContext(ctx, elems[start:end]); err != nil {
+			return errp.WithStack(err)
+		}
+		for _, elem := range elems[start:end] {
+			if elem.Error != nil {
+				return errp.WithMessage(elem.Error, elem.Method)
+			}
+		}
+	}
+	return nil
+}
+
+// fetchReceipts fetches the receipts of the transactions. If withNonces is true, the transactions
+// are fetched as well to know their nonces.
+func (client *Client) fetchReceipts(ctx context.Context, txs []*minedTransaction, withNonces bool) error {
+	var elems []rpc.BatchElem
+	receipts := make([]*rpcReceipt, len(txs))
+	transactions := make([]*rpcTransaction, len(txs))
+	for i, tx := range txs {
+		elems = append(elems, rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{tx.hash},
+			Result: &receipts[i],
+		})
+		if withNonces {
+			elems = append(elems, rpc.BatchElem{
+				Method: "eth_getTransactionByHash",
+				Args:   []interface{}{tx.hash},
+				Result: &transactions[i],
+			})
+		}
+	}
+	if err := client.batchCall(ctx, elems); err != nil {
+		return err
+	}
+	for i, tx := range txs {
+		tx.receipt = receipts[i]
+		if transactions[i] != nil {
+			nonce := uint64(transactions[i].Nonce)
+			tx.nonce = &nonce
+		}
+	}
+	return nil
+}
+
+// blockRequests returns the requests for the blocks with the given numbers, with full transactions
+// if fullTxs is true. result(i) is where the i-th block is stored.
+func blockRequests(numbers []uint64, fullTxs bool, result func(int) interface{}) []rpc.BatchElem {
+	elems := make([]rpc.BatchElem, len(numbers))
+	for i, number := range numbers {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeUint64(number), fullTxs},
+			Result: result(i),
+		}
+	}
+	return elems
+}
+
+// fetchTimestamps fetches the timestamps of the blocks with the given numbers.
+func (client *Client) fetchTimestamps(ctx context.Context, numbers []uint64) (map[uint64]time.Time, error) {
+	headers := make([]*rpcHeader, len(numbers))
+	elems := blockRequests(numbers, false, func(i int) interface{} { return &headers[i] })
+	if err := client.batchCall(ctx, elems); err != nil {
+		return nil, err
+	}
+	timestamps := map[uint64]time.Time{}
+	for i, header := range headers {
+		if header == nil {
+			return nil, errp.Newf("block %d not found", numbers[i])
+		}
+		timestamps[numbers[i]] = time.Unix(int64(header.Timestamp), 0)
+	}
+	return timestamps, nil
+}
+
+// fetchBlocks fetches the blocks with the given numbers, including their transactions.
+func (client *Client) fetchBlocks(ctx context.Context, numbers []uint64) ([]*rpcBlock, error) {
+	blocks := make([]*rpcBlock, len(numbers))
+	elems := blockRequests(numbers, true, func(i int) interface{} { return &blocks[i] })
+	if err := client.batchCall(ctx, elems); err != nil {
+		return nil, err
+	}
+	for i, block := range blocks {
+		if block == nil {
+			return nil, errp.Newf("block %d not found", numbers[i])
+		}
+	}
+	return blocks, nil
+}
+
+// transferLogs returns the `Transfer` events from or to the address up to endBlock, in the token
+// contract if not nil, otherwise in all token contracts.
+func (client *Client) transferLogs(
+	ctx context.Context, contract *common.Address, address common.Address, endBlock *big.Int) ([]types.Log, error) {
+	addressTopic := common.BytesToHash(address.Bytes())
+	var logs []types.Log
+	seen := map[common.Hash]map[uint]bool{}
+	for _, topics := range [][]interface{}{
+		{transferTopic, addressTopic},
+		{transferTopic, nil, addressTopic},
+	} {
+		filter := map[string]interface{}{
+			"fromBlock": "0x0",
+			"toBlock":   toBlockNumArg(endBlock),
+			"topics":    topics,
+		}
+		if contract != nil {
+			filter["address"] = *contract
+		}
+		var result []types.Log
+		if err := client.rpc.CallContext(ctx, &result, "eth_getLogs", filter); err != nil {
+			return nil, errp.WithStack(err)
+		}
+		for _, log := range result {
+			// ERC721 transfers have the same topic, but the token ID as an additional topic.
+			if log.Removed || len(log.Topics) != 3 {
+				continue
+			}
+			// Transfers to self are found by both filters.
+			if seen[log.TxHash][log.Index] {
+				continue
+			}
+			if seen[log.TxHash] == nil {
+				seen[log.TxHash] = map[uint]bool{}
+			}
+			seen[log.TxHash][log.Index] = true
+			logs = append(logs, log)
+		}
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber > logs[j].BlockNumber
+		}
+		return logs[i].Index > logs[j].Index
+	})
+	return logs, nil
+}
+
+// erc20Transactions rebuilds the transfers of the token from or to the address from the event
+// logs. Only the first transfer of a transaction is used, like EtherScan does.
+func (client *Client) erc20Transactions(
+	ctx context.Context, address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*minedTransaction, error) {
+	contract := erc20Token.ContractAddress()
+	logs, err := client.transferLogs(ctx, &contract, address, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	var txs []*minedTransaction
+	var sends []*minedTransaction
+	seen := map[common.Hash]bool{}
+	heights := map[uint64]bool{}
+	var blockNumbers []uint64
+	for _, log := range logs {
+		if seen[log.TxHash] {
+			continue
+		}
+		seen[log.TxHash] = true
+		tx := &minedTransaction{
+			hash:   log.TxHash,
+			from:   common.BytesToAddress(log.Topics[1].Bytes()),
+			to:     common.BytesToAddress(log.Topics[2].Bytes()),
+			value:  new(big.Int).SetBytes(log.Data),
+			height: log.BlockNumber,
+		}
+		txs = append(txs, tx)
+		if tx.from == address {
+			sends = append(sends, tx)
+		}
+		if !heights[log.BlockNumber] {
+			heights[log.BlockNumber] = true
+			blockNumbers = append(blockNumbers, log.BlockNumber)
+		}
+	}
+	timestamps, err := client.fetchTimestamps(ctx, blockNumbers)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range txs {
+		tx.timestamp = timestamps[tx.height]
+	}
+	// The fee is only relevant for the transfers sent by the address, which also paid the fee.
+	if err := client.fetchReceipts(ctx, sends, true); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// scanTransactions scans the blocks since the last scan of the address for its ETH transactions,
+// but at most the last scanBlocks blocks up to endBlock. The most recent blocks are rescanned in
+// case of a reorg.
+func (client *Client) scanTransactions(
+	ctx context.Context, address common.Address, endBlock uint64) ([]*minedTransaction, error) {
+	client.scansLock.Lock()
+	defer client.scansLock.Unlock()
+	scan, ok := client.scans[address]
+	if !ok {
+		scan = &blockScan{}
+		client.scans[address] = scan
+	}
+	var from uint64
+	if endBlock >= client.scanBlocks {
+		from = endBlock - client.scanBlocks + 1
+	}
+	if scan.scannedTo > 0 && scan.scannedTo+1 > ethtypes.NumConfirmationsComplete {
+		if rescanFrom := scan.scannedTo + 1 - ethtypes.NumConfirmationsComplete; rescanFrom > from {
+			from = rescanFrom
+		}
+	}
+	var found []*minedTransaction
+	for start := from; start <= endBlock; start += batchSize {
+		end := start + batchSize - 1
+		if end > endBlock {
+			end = endBlock
+		}
+		var numbers []uint64
+		for number := start; number <= end; number++ {
+			numbers = append(numbers, number)
+		}
+		blocks, err := client.fetchBlocks(ctx, numbers)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range blocks {
+			for _, tx := range block.Transactions {
+				if tx.From != address && (tx.To == nil || *tx.To != address) {
+					continue
+				}
+				nonce := uint64(tx.Nonce)
+				value := new(big.Int)
+				if tx.Value != nil {
+					value = tx.Value.ToInt()
+				}
+				mined := &minedTransaction{
+					hash:      tx.Hash,
+					from:      tx.From,
+					value:     value,
+					nonce:     &nonce,
+					height:    uint64(block.Number),
+					timestamp: time.Unix(int64(block.Timestamp), 0),
+				}
+				if tx.To != nil {
+					mined.to = *tx.To
+				}
+				found = append(found, mined)
+			}
+		}
+	}
+	if err := client.fetchReceipts(ctx, found, false); err != nil {
+		return nil, err
+	}
+	for _, tx := range found {
+		// Contract creations have no recipient.
+		if tx.to == (common.Address{}) && tx.receipt != nil && tx.receipt.ContractAddress != nil {
+			tx.to = *tx.receipt.ContractAddress
+		}
+	}
+
+	var kept []*minedTransaction
+	for _, tx := range scan.transactions {
+		if tx.height < from {
+			kept = append(kept, tx)
+		}
+	}
+	scan.transactions = append(found, kept...)
+	sort.SliceStable(scan.transactions, func(i, j int) bool {
+		return scan.transactions[i].height > scan.transactions[j].height
+	})
+	scan.scannedTo = endBlock
+	return append([]*minedTransaction{}, scan.transactions...), nil
+}
+
+// Transactions implements eth.TransactionsSource. See Client for where the transactions come from.
+func (client *Client) Transactions(
+	blockTipHeight *big.Int,
+	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*accounts.TransactionData, error) {
+	var txs []*minedTransaction
+	var err error
+	switch {
+	case erc20Token != nil:
+		txs, err = client.erc20Transactions(context.TODO(), address, endBlock, erc20Token)
+	case client.indexer != nil:
+		return client.indexer.Transactions(blockTipHeight, address, endBlock, nil)
+	default:
+		txs, err = client.scanTransactions(context.TODO(), address, endBlock.Uint64())
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*accounts.TransactionData, len(txs))
+	for i, tx := range txs {
+		result[i] = tx.transactionData(address, blockTipHeight, erc20Token != nil)
+	}
+	return result, nil
+}
+
+// ERC20TokenActivity implements eth.ERC20TokenDiscoverer. The tokens are found in the `Transfer`
+// events of all contracts and their metadata is read from the contracts.
+func (client *Client) ERC20TokenActivity(address common.Address) ([]*erc20.TokenActivity, error) {
+	ctx := context.TODO()
+	logs, err := client.transferLogs(ctx, nil, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	activities := []*erc20.TokenActivity{}
+	byContract := map[common.Address]*erc20.TokenActivity{}
+	// The logs are sorted by descending block number, but the tokens are listed in the order they
+	// were first used, like EtherScan does.
+	for i := len(logs) - 1; i >= 0; i-- {
+		log := logs[i]
+		activity, ok := byContract[log.Address]
+		if !ok {
+			activity = &erc20.TokenActivity{ContractAddress: log.Address}
+			metadata, err := erc20.FetchMetadata(ctx, client, log.Address)
+			if err != nil {
+				// Not a valid token, e.g. an NFT contract.
+				client.log.WithError(err).Infof("Skipping contract %s", log.Address.Hex())
+				byContract[log.Address] = nil
+				continue
+			}
+			activity.Name = metadata.Name
+			activity.Symbol = metadata.Symbol
+			activity.Decimals = uint(metadata.Decimals)
+			byContract[log.Address] = activity
+			activities = append(activities, activity)
+		}
+		if activity != nil {
+			activity.Transfers++
+		}
+	}
+	return activities, nil
+}
+
+// ERC20Approvals implements eth.ERC20ApprovalLister. The approvals are found in the `Approval`
+// events of all contracts, oldest first.
+func (client *Client) ERC20Approvals(owner common.Address) ([]*erc20.Approval, error) {
+	filter := map[string]interface{}{
+		"fromBlock": "0x0",
+		"toBlock":   "latest",
+		"topics":    []interface{}{approvalTopic, common.BytesToHash(owner.Bytes())},
+	}
+	var logs []types.Log
+	if err := client.rpc.CallContext(context.TODO(), &logs, "eth_getLogs", filter); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	approvals := []*erc20.Approval{}
+	for _, log := range logs {
+		// ERC721 approvals have the same topic, but the token ID as an additional topic.
+		if log.Removed || len(log.Topics) != 3 {
+			continue
+		}
+		approvals = append(approvals, &erc20.Approval{
+			ContractAddress: log.Address,
+			Spender:         common.BytesToAddress(log.Topics[2].Bytes()),
+		})
+	}
+	return approvals, nil
+}
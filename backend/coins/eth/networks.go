@@ -0,0 +1,100 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Chain IDs of the supported EVM networks besides Ethereum and its testnets.
+// https://chainlist.org
+const (
+	ChainIDOptimism uint64 = 10
+	ChainIDPolygon  uint64 = 137
+	ChainIDBase     uint64 = 8453
+	ChainIDArbitrum uint64 = 42161
+)
+
+// NewChainConfig returns the config of an EVM network with the given chain ID. The hard forks are
+// the ones of Ethereum mainnet, which all supported networks have adopted as far as transaction
+// signing is concerned.
+func NewChainConfig(chainID uint64) *params.ChainConfig {
+	config := *params.MainnetChainConfig
+	config.ChainID = new(big.Int).SetUint64(chainID)
+	return &config
+}
+
+// gasPriceOracleAddress is the address of the GasPriceOracle contract predeployed on OP Stack
+// chains. See https://docs.optimism.io/stack/smart-contracts#gaspriceoracle.
+var gasPriceOracleAddress = common.HexToAddress("0x420000000000000000000000000000000000000F")
+
+const gasPriceOracleABI = `[{"inputs":[{"internalType":"bytes","name":"_data","type":"bytes"}],"name":"getL1Fee","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`
+
+var gasPriceOracleParsedABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(gasPriceOracleABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// isOPStack returns true if the network is an OP Stack chain, where transactions pay an L1 data
+// fee on top of the gas.
+func isOPStack(chainID uint64) bool {
+	return chainID == ChainIDOptimism || chainID == ChainIDBase
+}
+
+// l1DataFee returns the fee charged on OP Stack chains for publishing the transaction on Ethereum,
+// which is paid in addition to the gas and is not included in the gas estimate. The transaction
+// does not have to be signed, the oracle accounts for the signature. It is zero on all other
+// networks. Arbitrum also charges for L1 data, but includes it in the estimated gas limit.
+func (coin *Coin) l1DataFee(ctx context.Context, tx *types.Transaction) (*big.Int, error) {
+	if !isOPStack(coin.ChainID()) {
+		return big.NewInt(0), nil
+	}
+	serializedTx, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	data, err := gasPriceOracleParsedABI.Pack("getL1Fee", serializedTx)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	output, err := coin.client.CallContract(ctx, ethereum.CallMsg{
+		To:   &gasPriceOracleAddress,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := gasPriceOracleParsedABI.Unpack("getL1Fee", output)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	fee, ok := result[0].(*big.Int)
+	if !ok {
+		return nil, errp.New("unexpected L1 fee")
+	}
+	return fee, nil
+}
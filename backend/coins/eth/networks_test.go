@@ -0,0 +1,76 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChainConfig(t *testing.T) {
+	config := NewChainConfig(ChainIDArbitrum)
+	require.Equal(t, big.NewInt(42161), config.ChainID)
+	require.Equal(t, params.MainnetChainConfig.LondonBlock, config.LondonBlock)
+	require.Equal(t, big.NewInt(1), params.MainnetChainConfig.ChainID)
+}
+
+func TestL1DataFee(t *testing.T) {
+	recipient := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(int64(ChainIDBase)),
+		GasTipCap: big.NewInt(1e6),
+		GasFeeCap: big.NewInt(1e7),
+		Gas:       21000,
+		To:        &recipient,
+		Value:     big.NewInt(1e17),
+	})
+	var calls int
+	client := &mocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			calls++
+			require.Equal(t, gasPriceOracleAddress, *msg.To)
+			require.Equal(t, crypto.Keccak256([]byte("getL1Fee(bytes)"))[:4], msg.Data[:4])
+			return math.U256Bytes(big.NewInt(123456)), nil
+		},
+	}
+
+	newCoin := func(chainID uint64) *Coin {
+		return NewCoin(client, coin.CodeBASEETH, "Base", "ETH", "ETH", NewChainConfig(chainID), "", nil, nil)
+	}
+
+	fee, err := newCoin(ChainIDBase).l1DataFee(context.Background(), tx)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(123456), fee)
+	require.Equal(t, 1, calls)
+
+	// Arbitrum and Polygon do not have a separate L1 fee.
+	for _, chainID := range []uint64{ChainIDArbitrum, ChainIDPolygon} {
+		fee, err := newCoin(chainID).l1DataFee(context.Background(), tx)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(0), fee)
+	}
+	require.Equal(t, 1, calls)
+}
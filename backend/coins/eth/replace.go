@@ -0,0 +1,200 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// replacementPriceBump is the minimum increase in percent of the fees of a replacement
+// transaction over the transaction it replaces. Nodes reject replacements with a smaller increase,
+// see the `txpool.pricebump` option of Geth.
+const replacementPriceBump = 10
+
+// ReplaceTxArgs are the arguments to replace a pending transaction, see ReplaceTxProposal().
+type ReplaceTxArgs struct {
+	// TxID is the hash of the pending transaction to replace.
+	TxID string `json:"txID"`
+	// Cancel is true to cancel the transaction, false to speed it up.
+	Cancel        bool                   `json:"cancel"`
+	FeeTargetCode accounts.FeeTargetCode `json:"feeTarget"`
+	// CustomFee is the fee in Gwei if FeeTargetCode is `FeeTargetCodeCustom`.
+	CustomFee string `json:"customFee"`
+}
+
+// bumpFee returns the minimum fee of a replacement transaction, rounded up.
+func bumpFee(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+replacementPriceBump))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// maxBig returns the larger of the two numbers.
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// replaceableTransaction returns the stored outgoing transaction with the given hash if it is still
+// pending and was not replaced yet.
+func (account *Account) replaceableTransaction(txID string) (*ethtypes.TransactionWithMetadata, error) {
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+	outgoingTransactions, err := dbTx.OutgoingTransactions()
+	if err != nil {
+		return nil, err
+	}
+	hash := ethcommon.HexToHash(txID)
+	for _, tx := range outgoingTransactions {
+		if tx.Transaction.Hash() == hash {
+			if tx.Height > 0 || tx.ReplacedBy != nil {
+				return nil, errp.WithStack(errors.ErrTxNotReplaceable)
+			}
+			return tx, nil
+		}
+	}
+	return nil, errp.WithStack(errors.ErrTxNotReplaceable)
+}
+
+// newReplacementTx creates a replacement for a pending transaction. A speed-up resends the same
+// transaction, a cancellation sends nothing to ourselves, both with the same nonce and higher fees.
+func (account *Account) newReplacementTx(args *ReplaceTxArgs) (*TxProposal, error) {
+	if !account.Synced() {
+		return nil, errp.WithStack(errors.ErrAccountNotsynced)
+	}
+	replaced, err := account.replaceableTransaction(args.TxID)
+	if err != nil {
+		return nil, err
+	}
+	suggestedGasFeeCap, suggestedGasTipCap, err := account.gasFees(&accounts.TxProposalArgs{
+		FeeTargetCode: args.FeeTargetCode,
+		CustomFee:     args.CustomFee,
+	})
+	if err != nil {
+		if _, ok := errp.Cause(err).(errors.TxValidationError); ok {
+			return nil, err
+		}
+		account.log.WithError(err).Error("error getting the gas price")
+		return nil, errp.WithStack(errors.ErrFeesNotAvailable)
+	}
+	// For legacy transactions, both caps are the gas price.
+	gasFeeCap := maxBig(suggestedGasFeeCap, bumpFee(replaced.Transaction.GasFeeCap()))
+	gasTipCap := maxBig(suggestedGasTipCap, bumpFee(replaced.Transaction.GasTipCap()))
+	if gasTipCap.Cmp(gasFeeCap) > 0 {
+		gasFeeCap = gasTipCap
+	}
+
+	oldTx := replaced.Transaction
+	message := ethereum.CallMsg{
+		From:  account.address.Address,
+		To:    oldTx.To(),
+		Value: oldTx.Value(),
+		Data:  oldTx.Data(),
+	}
+	gasLimit := oldTx.Gas()
+	value := replaced.TransactionData(
+		account.blockNumber.Uint64(), account.coin.erc20Token, account.address.Address.Hex()).Amount.BigInt()
+	if args.Cancel {
+		to := account.address.Address
+		message.To = &to
+		message.Value = big.NewInt(0)
+		message.Data = nil
+		gasLimit = params.TxGas
+		value = big.NewInt(0)
+	}
+
+	// The gas limit is not estimated again, as the replaced transaction might not be valid anymore
+	// in the pending state of the chain.
+	gasLimit, fee, err := account.txGas(
+		&accounts.TxProposalArgs{GasLimit: gasLimit}, message, oldTx.Nonce(), gasFeeCap, gasTipCap)
+	if err != nil {
+		return nil, err
+	}
+	if account.coin.erc20Token == nil {
+		// The balance does not include the pending transaction being replaced.
+		oldCost := new(big.Int).Mul(new(big.Int).SetUint64(oldTx.Gas()), oldTx.GasFeeCap())
+		oldCost.Add(oldCost, oldTx.Value())
+		available := new(big.Int).Add(account.balance.BigInt(), oldCost)
+		if new(big.Int).Add(message.Value, fee).Cmp(available) > 0 {
+			return nil, errp.WithStack(errors.ErrInsufficientFunds)
+		}
+	} else {
+		// The fee of a token transfer is paid in ether. The confirmed balance does not include the
+		// fee of the pending transaction being replaced.
+		etherBalance, err := account.coin.client.Balance(context.TODO(), account.address.Address)
+		if err != nil {
+			account.log.WithError(err).Error("Could not get the ether balance.")
+			return nil, errp.WithStack(errors.ErrFeesNotAvailable)
+		}
+		if fee.Cmp(etherBalance) > 0 {
+			return nil, errp.WithStack(errors.ErrInsufficientFunds)
+		}
+	}
+
+	tx, err := account.buildTx(message, oldTx.Nonce(), gasLimit, gasFeeCap, gasTipCap)
+	if err != nil {
+		return nil, err
+	}
+	replacedHash := oldTx.Hash()
+	return &TxProposal{
+		Coin:     account.coin,
+		Tx:       tx,
+		Fee:      fee,
+		Value:    value,
+		Signer:   types.NewLondonSigner(account.coin.net.ChainID),
+		Keypath:  account.signingConfiguration.AbsoluteKeypath(),
+		Replaces: &replacedHash,
+	}, nil
+}
+
+// ReplaceTxProposal proposes a transaction replacing one of our pending transactions, to speed it
+// up or to cancel it. It is sent with SendTx(), after which the replaced transaction is shown as
+// dropped. The returned values are the same as the ones of TxProposal().
+func (account *Account) ReplaceTxProposal(args *ReplaceTxArgs) (coin.Amount, coin.Amount, coin.Amount, error) {
+	defer account.updateLock.Lock()()
+	txProposal, err := account.newReplacementTx(args)
+	if err != nil {
+		return coin.Amount{}, coin.Amount{}, coin.Amount{}, err
+	}
+	account.activeTxProposal = txProposal
+	account.ProposeTxFiatValues(coin.NewAmount(txProposal.Value))
+	if !args.Cancel {
+		account.ProposeTxNote(account.TxNote(args.TxID))
+	}
+
+	var total *big.Int
+	if account.coin.erc20Token != nil {
+		total = txProposal.Value
+	} else {
+		total = new(big.Int).Add(txProposal.Value, txProposal.Fee)
+	}
+	return coin.NewAmount(txProposal.Value), coin.NewAmount(txProposal.Fee), coin.NewAmount(total), nil
+}
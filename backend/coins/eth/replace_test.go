@@ -0,0 +1,284 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	ethtypes "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
+	keystoremock "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBumpFee(t *testing.T) {
+	require.Equal(t, big.NewInt(110), bumpFee(big.NewInt(100)))
+	require.Equal(t, big.NewInt(2), bumpFee(big.NewInt(1)))
+	require.Equal(t, big.NewInt(2200000000), bumpFee(big.NewInt(2000000000)))
+}
+
+func outgoingTransactions(t *testing.T, acct *Account) map[common.Hash]*ethtypes.TransactionWithMetadata {
+	t.Helper()
+	dbTx, err := acct.db.Begin()
+	require.NoError(t, err)
+	defer dbTx.Rollback()
+	txs, err := dbTx.OutgoingTransactions()
+	require.NoError(t, err)
+	result := map[common.Hash]*ethtypes.TransactionWithMetadata{}
+	for _, tx := range txs {
+		result[tx.Transaction.Hash()] = tx
+	}
+	return result
+}
+
+func TestReplaceTx(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	var mu sync.Mutex
+	var sent []*types.Transaction
+	var mined *rpcclient.RPCTransactionReceipt
+	acct.coin.TstSetClient(&mocks.InterfaceMock{
+		BlockNumberFunc: func(ctx context.Context) (*big.Int, error) {
+			return big.NewInt(100), nil
+		},
+		BalanceFunc: func(ctx context.Context, account common.Address) (*big.Int, error) {
+			return big.NewInt(1e18), nil
+		},
+		PendingNonceAtFunc: func(ctx context.Context, account common.Address) (uint64, error) {
+			return 0, nil
+		},
+		SendTransactionFunc: func(ctx context.Context, tx *types.Transaction) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, tx)
+			return nil
+		},
+		TransactionReceiptWithBlockNumberFunc: func(
+			ctx context.Context, hash common.Hash) (*rpcclient.RPCTransactionReceipt, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if mined != nil && mined.TxHash == hash {
+				return mined, nil
+			}
+			return nil, nil
+		},
+		TransactionByHashFunc: func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+			return nil, true, nil
+		},
+	})
+	notifier := &accountsMocks.Notifier{}
+	notifier.On("Put", mock.Anything).Return(nil)
+	acct.notifier = notifier
+	acct.Config().ConnectKeystore = func() (keystore.Keystore, error) {
+		return &keystoremock.KeystoreMock{
+			SupportsEIP1559Func: func() bool {
+				return true
+			},
+			SignTransactionFunc: func(proposedTx interface{}) error {
+				return nil
+			},
+		}, nil
+	}
+
+	recipient := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	stuckTx := types.NewTx(&types.DynamicFeeTx{
+		Nonce:     0,
+		GasTipCap: big.NewInt(2e9),
+		GasFeeCap: big.NewInt(20e9),
+		Gas:       21000,
+		To:        &recipient,
+		Value:     big.NewInt(1e17),
+	})
+	require.NoError(t, acct.storePendingOutgoingTransaction(stuckTx, nil))
+	require.NoError(t, acct.SetTxNote(stuckTx.Hash().Hex(), "rent"))
+
+	_, _, _, err := acct.ReplaceTxProposal(&ReplaceTxArgs{
+		TxID:          common.HexToHash("0x01").Hex(),
+		FeeTargetCode: accounts.FeeTargetCodeCustom,
+		CustomFee:     "30",
+	})
+	require.Equal(t, errors.ErrTxNotReplaceable, errp.Cause(err))
+
+	t.Run("speed-up", func(t *testing.T) {
+		// The fee cap is raised to the minimum the node accepts. The custom fee is also used as the
+		// tip, which is higher than needed.
+		value, fee, total, err := acct.ReplaceTxProposal(&ReplaceTxArgs{
+			TxID:          stuckTx.Hash().Hex(),
+			FeeTargetCode: accounts.FeeTargetCodeCustom,
+			CustomFee:     "5",
+		})
+		require.NoError(t, err)
+		require.Equal(t, coin.NewAmountFromInt64(1e17), value)
+		require.Equal(t, coin.NewAmountFromInt64(21000*22e9), fee)
+		require.Equal(t, coin.NewAmountFromInt64(1e17+21000*22e9), total)
+		tx := acct.activeTxProposal.Tx
+		require.Equal(t, uint64(0), tx.Nonce())
+		require.Equal(t, recipient, *tx.To())
+		require.Equal(t, big.NewInt(22e9), tx.GasFeeCap())
+		require.Equal(t, big.NewInt(5e9), tx.GasTipCap())
+		require.Equal(t, stuckTx.Hash(), *acct.activeTxProposal.Replaces)
+		require.Equal(t, "rent", acct.GetAndClearProposedTxNote())
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		_, _, _, err := acct.ReplaceTxProposal(&ReplaceTxArgs{
+			TxID:          stuckTx.Hash().Hex(),
+			FeeTargetCode: accounts.FeeTargetCodeCustom,
+			CustomFee:     "50000",
+		})
+		require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		value, fee, _, err := acct.ReplaceTxProposal(&ReplaceTxArgs{
+			TxID:          stuckTx.Hash().Hex(),
+			Cancel:        true,
+			FeeTargetCode: accounts.FeeTargetCodeCustom,
+			CustomFee:     "30",
+		})
+		require.NoError(t, err)
+		require.Equal(t, coin.NewAmountFromInt64(0), value)
+		require.Equal(t, coin.NewAmountFromInt64(21000*30e9), fee)
+		cancelTx := acct.activeTxProposal.Tx
+		require.Equal(t, acct.address.Address, *cancelTx.To())
+		require.Equal(t, big.NewInt(0), cancelTx.Value())
+		require.Empty(t, cancelTx.Data())
+		require.Equal(t, big.NewInt(30e9), cancelTx.GasTipCap())
+
+		require.NoError(t, acct.SendTx())
+		mu.Lock()
+		require.Len(t, sent, 1)
+		require.Equal(t, cancelTx.Hash(), sent[0].Hash())
+		mu.Unlock()
+
+		txs := outgoingTransactions(t, acct)
+		require.Len(t, txs, 2)
+		replaced := txs[stuckTx.Hash()]
+		require.Equal(t, cancelTx.Hash(), *replaced.ReplacedBy)
+		require.Equal(t, accounts.TxStatusDropped,
+			replaced.TransactionData(100, nil, acct.address.Address.Hex()).Status)
+		require.Equal(t, accounts.TxStatusPending,
+			txs[cancelTx.Hash()].TransactionData(100, nil, acct.address.Address.Hex()).Status)
+
+		// A replaced transaction can't be replaced again.
+		_, _, _, err = acct.ReplaceTxProposal(&ReplaceTxArgs{
+			TxID:          stuckTx.Hash().Hex(),
+			FeeTargetCode: accounts.FeeTargetCodeCustom,
+			CustomFee:     "50",
+		})
+		require.Equal(t, errors.ErrTxNotReplaceable, errp.Cause(err))
+
+		// If the original transaction is confirmed anyway, the cancellation is dropped instead.
+		mu.Lock()
+		mined = &rpcclient.RPCTransactionReceipt{
+			Receipt:     types.Receipt{TxHash: stuckTx.Hash(), Status: types.ReceiptStatusSuccessful, GasUsed: 21000},
+			BlockNumber: 99,
+		}
+		mu.Unlock()
+		acct.updateOutgoingTransactions(100)
+		txs = outgoingTransactions(t, acct)
+		require.Equal(t, accounts.TxStatusPending,
+			txs[stuckTx.Hash()].TransactionData(100, nil, acct.address.Address.Hex()).Status)
+		require.Equal(t, stuckTx.Hash(), *txs[cancelTx.Hash()].ReplacedBy)
+		require.Equal(t, accounts.TxStatusDropped,
+			txs[cancelTx.Hash()].TransactionData(100, nil, acct.address.Address.Hex()).Status)
+	})
+}
+
+func TestReplaceTokenTx(t *testing.T) {
+	var mu sync.Mutex
+	etherBalance := big.NewInt(1e15)
+	client := newClientMock()
+	client.BalanceFunc = func(ctx context.Context, account common.Address) (*big.Int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return new(big.Int).Set(etherBalance), nil
+	}
+	client.ERC20BalanceFunc = func(account common.Address, erc20Token *erc20.Token) (*big.Int, error) {
+		return big.NewInt(1e6), nil
+	}
+	token := erc20.NewToken("0x0000000000000000000000000000000000000001", 6)
+	acct := newAccountWithCoin(t, NewCoin(
+		client, "ERC20TEST", "ERC20Test", "TOK", "GOETH", params.GoerliChainConfig, "", nil, token))
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	recipient := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	data, err := packERC20Call("transfer", &recipient, big.NewInt(1000))
+	require.NoError(t, err)
+	contractAddress := token.ContractAddress()
+	stuckTx := types.NewTx(&types.DynamicFeeTx{
+		Nonce:     0,
+		GasTipCap: big.NewInt(2e9),
+		GasFeeCap: big.NewInt(20e9),
+		Gas:       60000,
+		To:        &contractAddress,
+		Value:     big.NewInt(0),
+		Data:      data,
+	})
+	require.NoError(t, acct.storePendingOutgoingTransaction(stuckTx, nil))
+
+	speedUp := &ReplaceTxArgs{
+		TxID:          stuckTx.Hash().Hex(),
+		FeeTargetCode: accounts.FeeTargetCodeCustom,
+		CustomFee:     "5",
+	}
+	// The bumped fee is paid in ether, of which there is not enough.
+	_, _, _, err = acct.ReplaceTxProposal(speedUp)
+	require.Equal(t, errors.ErrInsufficientFunds, errp.Cause(err))
+
+	mu.Lock()
+	etherBalance = big.NewInt(1e16)
+	mu.Unlock()
+	value, fee, total, err := acct.ReplaceTxProposal(speedUp)
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(1000), value)
+	require.Equal(t, coin.NewAmountFromInt64(60000*22e9), fee)
+	require.Equal(t, value, total)
+	tx := acct.activeTxProposal.Tx
+	require.Equal(t, contractAddress, *tx.To())
+	require.Equal(t, data, tx.Data())
+	require.Equal(t, uint64(60000), tx.Gas())
+	require.Equal(t, big.NewInt(22e9), tx.GasFeeCap())
+
+	_, fee, _, err = acct.ReplaceTxProposal(&ReplaceTxArgs{
+		TxID:          stuckTx.Hash().Hex(),
+		Cancel:        true,
+		FeeTargetCode: accounts.FeeTargetCodeCustom,
+		CustomFee:     "30",
+	})
+	require.NoError(t, err)
+	require.Equal(t, coin.NewAmountFromInt64(21000*30e9), fee)
+	cancelTx := acct.activeTxProposal.Tx
+	require.Equal(t, acct.address.Address, *cancelTx.To())
+	require.Empty(t, cancelTx.Data())
+}
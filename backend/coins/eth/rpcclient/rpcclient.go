@@ -42,6 +42,12 @@ type Interface interface {
 	SendTransaction(ctx context.Context, tx *types.Transaction) error
 	// PendingNonceAt retrieves the current pending nonce associated with an account.
 	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	// NonceAt retrieves the nonce associated with an account in the latest block, i.e. the number
+	// of its confirmed transactions.
+	NonceAt(ctx context.Context, account common.Address) (uint64, error)
+	// CallContract executes a message call without creating a transaction, e.g. to read the state
+	// of a contract. blockNumber must be nil, which selects the latest block.
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
 	// EstimateGas tries to estimate the gas needed to execute a specific
 	// transaction based on the current pending state of the backend blockchain.
 	// There is no guarantee that this is the true gas limit requirement as other
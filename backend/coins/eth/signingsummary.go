@@ -0,0 +1,221 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/decoder"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// ContractVerifier can be implemented by a TransactionsSource that knows whether the source code of
+// a contract is published, e.g. a block explorer.
+type ContractVerifier interface {
+	ContractVerified(address ethcommon.Address) (bool, error)
+}
+
+// SummaryToken is a token referenced in a SigningSummary.
+type SummaryToken struct {
+	Address string `json:"address"`
+	// Name and Symbol are empty if the token metadata could not be read.
+	Name   string `json:"name,omitempty"`
+	Symbol string `json:"symbol,omitempty"`
+}
+
+// SigningSummary is the human-readable interpretation of a transaction or typed message proposed
+// by a dapp via WalletConnect, shown to the user before signing. See decoder.Summary for the
+// meaning of the fields. Amounts are formatted with their unit if the token is known, and in the
+// smallest unit of the token otherwise.
+type SigningSummary struct {
+	Action   decoder.Action `json:"action"`
+	Method   string         `json:"method,omitempty"`
+	Contract string         `json:"contract,omitempty"`
+	// ContractVerified is false if the source code of the contract is not published, which is a
+	// common trait of scams. It is nil if this could not be checked.
+	ContractVerified *bool `json:"contractVerified,omitempty"`
+	// Value is the amount of the native coin sent with a transaction, if any.
+	Value     string        `json:"value,omitempty"`
+	Token     *SummaryToken `json:"token,omitempty"`
+	Amount    string        `json:"amount,omitempty"`
+	TokenID   string        `json:"tokenID,omitempty"`
+	Recipient string        `json:"recipient,omitempty"`
+	Spender   string        `json:"spender,omitempty"`
+	// UnlimitedApproval is true if the spender may spend an unlimited amount of the token, or all
+	// NFTs of a collection.
+	UnlimitedApproval bool `json:"unlimitedApproval"`
+	// Expiration is the time a permit or approval expires, nil if it does not expire.
+	Expiration *time.Time    `json:"expiration,omitempty"`
+	TokenOut   *SummaryToken `json:"tokenOut,omitempty"`
+	AmountOut  string        `json:"amountOut,omitempty"`
+	Tokens     int           `json:"tokens,omitempty"`
+}
+
+// maxExpiration is the latest expiration time shown. Permits without an expiration use huge
+// deadlines, usually the maximum integer.
+var maxExpiration = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// formatNative formats an amount of the native coin with its unit.
+func (account *Account) formatNative(amount *big.Int) string {
+	return account.coin.FormatAmount(coin.NewAmount(amount), true) + " " + account.coin.Unit(true)
+}
+
+// summaryToken fetches the metadata of the token. The metadata is nil if the contract is not an
+// ERC20 token.
+func (account *Account) summaryToken(address *ethcommon.Address) (*SummaryToken, *erc20.Metadata) {
+	if address == nil {
+		return nil, nil
+	}
+	token := &SummaryToken{Address: address.Hex()}
+	metadata, err := account.coin.ERC20Metadata(context.TODO(), *address)
+	if err != nil {
+		account.log.WithError(err).Infof("No ERC20 metadata for %s", address.Hex())
+		return token, nil
+	}
+	token.Name = metadata.Name
+	token.Symbol = metadata.Symbol
+	return token, metadata
+}
+
+// formatTokenAmount formats an amount of a token, or of the native coin if token is nil.
+func (account *Account) formatTokenAmount(amount *big.Int, token *SummaryToken, metadata *erc20.Metadata) string {
+	switch {
+	case amount == nil:
+		return ""
+	case token == nil:
+		return account.formatNative(amount)
+	case metadata == nil:
+		return amount.String()
+	}
+	formatted := erc20.FormatAmount(amount, uint(metadata.Decimals))
+	if metadata.Symbol != "" {
+		formatted += " " + metadata.Symbol
+	}
+	return formatted
+}
+
+// signingSummary converts a decoded summary for display. value is the native coin sent with a
+// transaction, nil for typed messages.
+func (account *Account) signingSummary(decoded *decoder.Summary, value *big.Int) *SigningSummary {
+	summary := &SigningSummary{
+		Action:            decoded.Action,
+		Method:            decoded.Method,
+		UnlimitedApproval: decoded.Unlimited,
+		Tokens:            decoded.Tokens,
+	}
+	if value != nil && value.Sign() > 0 {
+		summary.Value = account.formatNative(value)
+	}
+	if decoded.Recipient != nil {
+		summary.Recipient = decoded.Recipient.Hex()
+	}
+	if decoded.Spender != nil {
+		summary.Spender = decoded.Spender.Hex()
+	}
+	if decoded.TokenID != nil {
+		summary.TokenID = decoded.TokenID.String()
+	}
+	if decoded.Expiration != nil && decoded.Expiration.Sign() > 0 &&
+		decoded.Expiration.Cmp(big.NewInt(maxExpiration.Unix())) < 0 {
+		expiration := time.Unix(decoded.Expiration.Int64(), 0).UTC()
+		summary.Expiration = &expiration
+	}
+
+	var metadata *erc20.Metadata
+	switch decoded.Action {
+	case decoder.ActionSend:
+		summary.Value = ""
+		summary.Amount = account.formatTokenAmount(decoded.Amount, nil, nil)
+		return summary
+	case decoder.ActionSwap:
+		summary.Token, metadata = account.summaryToken(decoded.Token)
+		summary.Amount = account.formatTokenAmount(decoded.Amount, summary.Token, metadata)
+		var metadataOut *erc20.Metadata
+		summary.TokenOut, metadataOut = account.summaryToken(decoded.TokenOut)
+		summary.AmountOut = account.formatTokenAmount(decoded.AmountOut, summary.TokenOut, metadataOut)
+	default:
+		summary.Token, metadata = account.summaryToken(decoded.Token)
+		calledToken := decoded.Contract != nil && decoded.Token != nil && *decoded.Contract == *decoded.Token
+		if calledToken && metadata == nil && (decoded.Method == "transferFrom" || decoded.Method == "approve") {
+			// transferFrom and approve of ERC721 have the same signature as in ERC20, with the
+			// token ID instead of the amount. NFT contracts have no decimals, so no metadata.
+			summary.TokenID = decoded.Amount.String()
+			summary.UnlimitedApproval = false
+			if decoded.Action == decoder.ActionTransfer {
+				summary.Action = decoder.ActionNFTTransfer
+			}
+			break
+		}
+		if decoded.Amount != nil && !decoded.Unlimited {
+			summary.Amount = account.formatTokenAmount(decoded.Amount, summary.Token, metadata)
+		}
+	}
+
+	if decoded.Contract != nil {
+		summary.Contract = decoded.Contract.Hex()
+		if verifier, ok := account.coin.transactionsSource.(ContractVerifier); ok {
+			verified, err := verifier.ContractVerified(*decoded.Contract)
+			if err != nil {
+				account.log.WithError(err).Error("Could not check if the contract is verified")
+			} else {
+				summary.ContractVerified = &verified
+			}
+		}
+	}
+	return summary
+}
+
+// DescribeWalletConnectTx interprets a transaction proposed via WalletConnect, to be shown before
+// it is signed with EthSignWalletConnectTx().
+func (account *Account) DescribeWalletConnectTx(chainID uint64, proposedTx WalletConnectArgs) (*SigningSummary, error) {
+	if chainID != account.coin.ChainID() {
+		return nil, errp.Newf("Unsupported EVM network with chain ID %d", chainID)
+	}
+	if !IsValidEthAddress(proposedTx.To) {
+		return nil, errp.WithStack(errors.ErrInvalidAddress)
+	}
+	value := big.NewInt(0)
+	if proposedTx.Value != "" {
+		parsed, ok := new(big.Int).SetString(strings.TrimPrefix(proposedTx.Value, "0x"), 16)
+		if !ok {
+			return nil, errp.New("error setting transaction value")
+		}
+		value = parsed
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(proposedTx.Data, "0x"))
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	decoded := decoder.DecodeTx(ethcommon.HexToAddress(proposedTx.To), value, data)
+	return account.signingSummary(decoded, value), nil
+}
+
+// DescribeTypedMsg interprets an EIP-712 typed message proposed via WalletConnect, to be shown
+// before it is signed with SignTypedMsg().
+func (account *Account) DescribeTypedMsg(data string) (*SigningSummary, error) {
+	decoded, err := decoder.DecodeTypedData([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	return account.signingSummary(decoded, nil), nil
+}
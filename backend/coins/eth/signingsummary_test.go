@@ -0,0 +1,189 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/decoder"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// mockContractVerifier is a transactions source that knows which contracts are verified.
+type mockContractVerifier struct {
+	verified map[common.Address]bool
+}
+
+func (m *mockContractVerifier) Transactions(
+	blockTipHeight *big.Int,
+	address common.Address, endBlock *big.Int, erc20Token *erc20.Token) (
+	[]*accounts.TransactionData, error) {
+	return nil, nil
+}
+
+func (m *mockContractVerifier) ContractVerified(address common.Address) (bool, error) {
+	return m.verified[address], nil
+}
+
+func TestDescribeWalletConnectTx(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	token := common.HexToAddress("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+	nft := common.HexToAddress("0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d")
+	spender := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	parsed, err := abi.JSON(strings.NewReader(erc20.IERC20ABI))
+	require.NoError(t, err)
+	acct.coin.TstSetClient(&mocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			if *msg.To != token {
+				return nil, nil
+			}
+			for name, value := range map[string]interface{}{
+				"name":     "USD Coin",
+				"symbol":   "USDC",
+				"decimals": uint8(6),
+			} {
+				method := parsed.Methods[name]
+				if bytes.Equal(msg.Data, method.ID) {
+					return method.Outputs.Pack(value)
+				}
+			}
+			return nil, nil
+		},
+	})
+	acct.coin.TstSetTransactionsSource(&mockContractVerifier{
+		verified: map[common.Address]bool{token: true},
+	})
+
+	encode := func(method string, args ...interface{}) string {
+		data, err := parsed.Pack(method, args...)
+		require.NoError(t, err)
+		return "0x" + hex.EncodeToString(data)
+	}
+	chainID := acct.coin.ChainID()
+
+	// Wrong network.
+	_, err = acct.DescribeWalletConnectTx(chainID+1, WalletConnectArgs{To: token.Hex()})
+	require.Error(t, err)
+
+	// Plain send of the native coin.
+	summary, err := acct.DescribeWalletConnectTx(chainID, WalletConnectArgs{
+		To:    spender.Hex(),
+		Value: "0xde0b6b3a7640000",
+	})
+	require.NoError(t, err)
+	require.Equal(t, decoder.ActionSend, summary.Action)
+	require.Equal(t, "1 GOETH", summary.Amount)
+	require.Empty(t, summary.Value)
+
+	// Token transfer, formatted with the metadata of the token.
+	summary, err = acct.DescribeWalletConnectTx(chainID, WalletConnectArgs{
+		To:   token.Hex(),
+		Data: encode("transfer", spender, big.NewInt(1500000)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, decoder.ActionTransfer, summary.Action)
+	require.Equal(t, "1.5 USDC", summary.Amount)
+	require.Equal(t, &SummaryToken{Address: token.Hex(), Name: "USD Coin", Symbol: "USDC"}, summary.Token)
+	require.Equal(t, spender.Hex(), summary.Recipient)
+	require.NotNil(t, summary.ContractVerified)
+	require.True(t, *summary.ContractVerified)
+
+	// Unlimited approval.
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	summary, err = acct.DescribeWalletConnectTx(chainID, WalletConnectArgs{
+		To:   token.Hex(),
+		Data: encode("approve", spender, maxUint256),
+	})
+	require.NoError(t, err)
+	require.Equal(t, decoder.ActionApprove, summary.Action)
+	require.True(t, summary.UnlimitedApproval)
+	require.Empty(t, summary.Amount)
+	require.Equal(t, spender.Hex(), summary.Spender)
+
+	// ERC721 approvals have the same signature, but approve a single token ID. The NFT contract
+	// is not verified.
+	summary, err = acct.DescribeWalletConnectTx(chainID, WalletConnectArgs{
+		To:   nft.Hex(),
+		Data: encode("approve", spender, big.NewInt(1234)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, decoder.ActionApprove, summary.Action)
+	require.Equal(t, "1234", summary.TokenID)
+	require.Empty(t, summary.Amount)
+	require.NotNil(t, summary.ContractVerified)
+	require.False(t, *summary.ContractVerified)
+
+	// Unknown calls only report the contract.
+	summary, err = acct.DescribeWalletConnectTx(chainID, WalletConnectArgs{
+		To:    nft.Hex(),
+		Value: "0x1",
+		Data:  "0x12345678",
+	})
+	require.NoError(t, err)
+	require.Equal(t, decoder.ActionUnknown, summary.Action)
+	require.Equal(t, nft.Hex(), summary.Contract)
+	require.Equal(t, "0.000000000000000001 GOETH", summary.Value)
+}
+
+func TestDescribeTypedMsg(t *testing.T) {
+	acct := newAccount(t)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+	acct.coin.TstSetClient(&mocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			return nil, nil
+		},
+	})
+
+	_, err := acct.DescribeTypedMsg("not json")
+	require.Error(t, err)
+
+	summary, err := acct.DescribeTypedMsg(`{
+		"primaryType": "Permit",
+		"domain": {"verifyingContract": "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"},
+		"message": {
+			"owner": "0x2222222222222222222222222222222222222222",
+			"spender": "0x1111111111111111111111111111111111111111",
+			"value": "1000",
+			"nonce": 0,
+			"deadline": 1700000000
+		}
+	}`)
+	require.NoError(t, err)
+	require.Equal(t, decoder.ActionPermit, summary.Action)
+	require.Equal(t, "Permit", summary.Method)
+	// Without token metadata, the amount is in the smallest unit.
+	require.Equal(t, "1000", summary.Amount)
+	require.False(t, summary.UnlimitedApproval)
+	require.Equal(t, time.Unix(1700000000, 0).UTC(), *summary.Expiration)
+	// The transactions source can't check contracts.
+	require.Nil(t, summary.ContractVerified)
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// intrinsicGas returns the gas a transaction with the given calldata costs before any contract
+// code is executed, see EIP-2028.
+func intrinsicGas(data []byte) uint64 {
+	gas := params.TxGas
+	for _, b := range data {
+		if b == 0 {
+			gas += params.TxDataZeroGas
+		} else {
+			gas += params.TxDataNonZeroGasEIP2028
+		}
+	}
+	return gas
+}
+
+// txNonce returns the nonce of a new transaction, which is the next nonce of the account unless
+// overridden in args. A lower nonce replaces the pending transaction with the same nonce, whose
+// hash is returned as well if it was sent from this account. The fees must be high enough for the
+// node to accept the replacement. Nonces of confirmed transactions are rejected.
+func (account *Account) txNonce(
+	args *accounts.TxProposalArgs, gasFeeCap, gasTipCap *big.Int) (uint64, *ethcommon.Hash, error) {
+	if args.Nonce == nil || *args.Nonce == account.nextNonce {
+		return account.nextNonce, nil, nil
+	}
+	nonce := *args.Nonce
+	if nonce > account.nextNonce {
+		return 0, nil, errp.WithStack(errors.ErrNonceGap)
+	}
+	confirmedNonce, err := account.coin.client.NonceAt(context.TODO(), account.address.Address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if nonce < confirmedNonce {
+		return 0, nil, errp.WithStack(errors.ErrNonceTooLow)
+	}
+	dbTx, err := account.db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer dbTx.Rollback()
+	outgoingTransactions, err := dbTx.OutgoingTransactions()
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, tx := range outgoingTransactions {
+		if tx.Transaction.Nonce() != nonce {
+			continue
+		}
+		if tx.Height > 0 {
+			return 0, nil, errp.WithStack(errors.ErrNonceTooLow)
+		}
+		if tx.ReplacedBy != nil {
+			continue
+		}
+		if gasFeeCap.Cmp(bumpFee(tx.Transaction.GasFeeCap())) < 0 ||
+			gasTipCap.Cmp(bumpFee(tx.Transaction.GasTipCap())) < 0 {
+			return 0, nil, errp.WithStack(errors.ErrFeeTooLow)
+		}
+		hash := tx.Transaction.Hash()
+		return nonce, &hash, nil
+	}
+	// E.g. a transaction stuck in the mempool that was sent from another wallet. The node rejects
+	// the transaction if the nonce was already used.
+	return nonce, nil, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/errors"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrinsicGas(t *testing.T) {
+	require.Equal(t, uint64(21000), intrinsicGas(nil))
+	require.Equal(t, uint64(21000+4+16), intrinsicGas([]byte{0, 1}))
+}
+
+func TestTxProposalOverrides(t *testing.T) {
+	client := newClientMock()
+	var estimated []ethereum.CallMsg
+	client.EstimateGasFunc = func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+		estimated = append(estimated, call)
+		return 50000, nil
+	}
+	client.PendingNonceAtFunc = func(ctx context.Context, account common.Address) (uint64, error) {
+		return 3, nil
+	}
+	client.NonceAtFunc = func(ctx context.Context, account common.Address) (uint64, error) {
+		return 1, nil
+	}
+	acct := newAccountWithNet(t, params.GoerliChainConfig, client)
+	defer acct.Close()
+	acct.Synchronizer.WaitSynchronized()
+
+	recipient := common.HexToAddress("0xa29163852021BF4C139D03Dff59ae763AC73e84e")
+	args := func() *accounts.TxProposalArgs {
+		return &accounts.TxProposalArgs{
+			RecipientAddress: recipient.Hex(),
+			Amount:           coin.NewSendAmount("0"),
+			FeeTargetCode:    accounts.FeeTargetCodeCustom,
+			CustomFee:        "20",
+		}
+	}
+	nonce := func(n uint64) *uint64 { return &n }
+
+	t.Run("data", func(t *testing.T) {
+		txArgs := args()
+		txArgs.Data = []byte{0xa9, 0x05, 0x9c, 0xbb}
+		_, fee, _, err := acct.TxProposal(txArgs)
+		require.NoError(t, err)
+		require.Equal(t, coin.NewAmountFromInt64(50000*20e9), fee)
+		require.Equal(t, txArgs.Data, estimated[len(estimated)-1].Data)
+		tx := acct.activeTxProposal.Tx
+		require.Equal(t, txArgs.Data, tx.Data())
+		require.Equal(t, uint64(3), tx.Nonce())
+		require.Nil(t, acct.activeTxProposal.Replaces)
+	})
+
+	t.Run("gas limit", func(t *testing.T) {
+		calls := len(estimated)
+		txArgs := args()
+		txArgs.Data = []byte{0xa9, 0x05, 0x9c, 0xbb}
+		txArgs.GasLimit = 100000
+		_, fee, _, err := acct.TxProposal(txArgs)
+		require.NoError(t, err)
+		require.Equal(t, coin.NewAmountFromInt64(100000*20e9), fee)
+		require.Equal(t, uint64(100000), acct.activeTxProposal.Tx.Gas())
+		require.Len(t, estimated, calls)
+
+		txArgs.GasLimit = 21000
+		_, _, _, err = acct.TxProposal(txArgs)
+		require.Equal(t, errors.ErrGasLimitTooLow, errp.Cause(err))
+	})
+
+	t.Run("nonce", func(t *testing.T) {
+		txArgs := args()
+		txArgs.Nonce = nonce(3)
+		_, _, _, err := acct.TxProposal(txArgs)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), acct.activeTxProposal.Tx.Nonce())
+
+		txArgs.Nonce = nonce(4)
+		_, _, _, err = acct.TxProposal(txArgs)
+		require.Equal(t, errors.ErrNonceGap, errp.Cause(err))
+
+		// Already confirmed.
+		txArgs.Nonce = nonce(0)
+		_, _, _, err = acct.TxProposal(txArgs)
+		require.Equal(t, errors.ErrNonceTooLow, errp.Cause(err))
+
+		// Pending, but not sent from this account, so the fees can't be checked.
+		txArgs.Nonce = nonce(2)
+		_, _, _, err = acct.TxProposal(txArgs)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), acct.activeTxProposal.Tx.Nonce())
+		require.Nil(t, acct.activeTxProposal.Replaces)
+	})
+
+	t.Run("replace stored transaction", func(t *testing.T) {
+		stuckTx := types.NewTx(&types.DynamicFeeTx{
+			Nonce:     1,
+			GasTipCap: big.NewInt(2e9),
+			GasFeeCap: big.NewInt(20e9),
+			Gas:       21000,
+			To:        &recipient,
+			Value:     big.NewInt(1e17),
+		})
+		require.NoError(t, acct.storePendingOutgoingTransaction(stuckTx, nil))
+
+		txArgs := args()
+		txArgs.Nonce = nonce(1)
+		_, _, _, err := acct.TxProposal(txArgs)
+		require.Equal(t, errors.ErrFeeTooLow, errp.Cause(err))
+
+		txArgs.CustomFee = "22"
+		_, _, _, err = acct.TxProposal(txArgs)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), acct.activeTxProposal.Tx.Nonce())
+		require.Equal(t, stuckTx.Hash(), *acct.activeTxProposal.Replaces)
+	})
+}
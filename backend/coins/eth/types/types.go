@@ -44,6 +44,9 @@ type TransactionWithMetadata struct {
 	Success bool
 	// Number of broadcast attempts.
 	BroadcastAttempts uint16
+	// ReplacedBy is the hash of the transaction with the same nonce that replaced this one, e.g. to
+	// speed it up or to cancel it. nil if it was not replaced.
+	ReplacedBy *common.Hash
 }
 
 // FeeTarget contains the gas price for a specific fee target.
@@ -83,6 +86,7 @@ func (txh *TransactionWithMetadata) MarshalJSON() ([]byte, error) {
 		"gasUsed":           hexutil.Uint64(txh.GasUsed),
 		"success":           txh.Success,
 		"broadcastAttempts": txh.BroadcastAttempts,
+		"replacedBy":        txh.ReplacedBy,
 	})
 }
 
@@ -94,6 +98,7 @@ func (txh *TransactionWithMetadata) UnmarshalJSON(input []byte) error {
 		GasUsed           hexutil.Uint64 `json:"gasUsed"`
 		Success           bool           `json:"success"`
 		BroadcastAttempts uint16         `json:"broadcastAttempts"`
+		ReplacedBy        *common.Hash   `json:"replacedBy"`
 	}{}
 	if err := json.Unmarshal(input, &m); err != nil {
 		return err
@@ -106,6 +111,7 @@ func (txh *TransactionWithMetadata) UnmarshalJSON(input []byte) error {
 	txh.GasUsed = uint64(m.GasUsed)
 	txh.Success = m.Success
 	txh.BroadcastAttempts = m.BroadcastAttempts
+	txh.ReplacedBy = m.ReplacedBy
 	return nil
 }
 
@@ -120,7 +126,8 @@ func (txh *TransactionWithMetadata) TransactionData(
 	amount := coin.NewAmount(txh.Transaction.Value())
 	address := txh.Transaction.To().Hex()
 
-	if erc20Token != nil {
+	// Cancellations in token accounts are plain zero-value transactions, see IsCancellation().
+	if erc20Token != nil && !txh.IsCancellation(common.HexToAddress(accountAddress)) {
 		// ERC20 transfer.
 
 		// An ERC20-Token transfer looks like this:
@@ -196,7 +203,22 @@ func (txh *TransactionWithMetadata) numConfirmations(tipHeight uint64) int {
 	return confs
 }
 
+// Dropped returns true if the transaction was replaced and can't be confirmed anymore.
+func (txh *TransactionWithMetadata) Dropped() bool {
+	return txh.Height == 0 && txh.ReplacedBy != nil
+}
+
+// IsCancellation returns true if the transaction is a zero-value transaction to the account address
+// without data, which is how pending transactions are cancelled.
+func (txh *TransactionWithMetadata) IsCancellation(accountAddress common.Address) bool {
+	return txh.Transaction.To() != nil && *txh.Transaction.To() == accountAddress &&
+		txh.Transaction.Value().Sign() == 0 && len(txh.Transaction.Data()) == 0
+}
+
 func (txh *TransactionWithMetadata) status(numConfirmations int) accounts.TxStatus {
+	if txh.Dropped() {
+		return accounts.TxStatusDropped
+	}
 	if numConfirmations == 0 {
 		return accounts.TxStatusPending
 	}
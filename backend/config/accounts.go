@@ -17,6 +17,7 @@ package config
 
 import (
 	"bytes"
+	"strings"
 	"time"
 
 	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
@@ -98,10 +99,37 @@ type Keystore struct {
 	LastConnected time.Time `json:"lastConnected"`
 }
 
+// Contact is an entry of the address book.
+type Contact struct {
+	// ID identifies the contact. It is assigned when the contact is added.
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	CoinCode coin.Code `json:"coinCode"`
+	// Address is a receive address of the coin.
+	Address string `json:"address"`
+	Notes   string `json:"notes"`
+}
+
+// ERC20Token is an ERC20 token added by the user by its contract address, in addition to the
+// tokens built into the app. The metadata is read from the contract when the token is added.
+type ERC20Token struct {
+	// Code is the token code, e.g. "eth-erc20-abc0x1234". Like the codes of the built-in tokens,
+	// it is used in `Account.ActiveTokens`.
+	Code            coin.Code `json:"code"`
+	ContractAddress string    `json:"contractAddress"`
+	Name            string    `json:"name"`
+	Symbol          string    `json:"symbol"`
+	Decimals        uint      `json:"decimals"`
+}
+
 // AccountsConfig persists the list of accounts added to the app.
 type AccountsConfig struct {
 	Accounts  []*Account  `json:"accounts"`
 	Keystores []*Keystore `json:"keystores"`
+	// Contacts is the address book.
+	Contacts []*Contact `json:"contacts"`
+	// ERC20Tokens are the tokens added by the user. They can be activated on any ETH account.
+	ERC20Tokens []*ERC20Token `json:"erc20Tokens,omitempty"`
 }
 
 // newDefaultAccountsonfig returns the default accounts config.
@@ -132,6 +160,50 @@ func (cfg AccountsConfig) LookupKeystore(rootFingerprint []byte) (*Keystore, err
 	return nil, errp.Newf("could not retrieve keystore for fingerprint %x", rootFingerprint)
 }
 
+// LookupContact returns the contact with the given ID, or nil if no such contact exists.
+// A reference is returned, so the contact can be modified by the caller.
+func (cfg AccountsConfig) LookupContact(id string) *Contact {
+	for _, contact := range cfg.Contacts {
+		if contact.ID == id {
+			return contact
+		}
+	}
+	return nil
+}
+
+// LookupERC20Token returns the user-added ERC20 token with the given code, or nil if no such token
+// exists.
+func (cfg AccountsConfig) LookupERC20Token(code coin.Code) *ERC20Token {
+	for _, token := range cfg.ERC20Tokens {
+		if token.Code == code {
+			return token
+		}
+	}
+	return nil
+}
+
+// LookupERC20TokenByAddress returns the user-added ERC20 token with the given contract address, or
+// nil if no such token exists.
+func (cfg AccountsConfig) LookupERC20TokenByAddress(contractAddress string) *ERC20Token {
+	for _, token := range cfg.ERC20Tokens {
+		if strings.EqualFold(token.ContractAddress, contractAddress) {
+			return token
+		}
+	}
+	return nil
+}
+
+// LookupContactByAddress returns the contact with the given coin code and address, or nil if no
+// such contact exists.
+func (cfg AccountsConfig) LookupContactByAddress(coinCode coin.Code, address string) *Contact {
+	for _, contact := range cfg.Contacts {
+		if contact.CoinCode == coinCode && contact.Address == address {
+			return contact
+		}
+	}
+	return nil
+}
+
 // IsKeystoreWatchonly returns true if the keystore's watchonly setting is true.
 // If no such keystore exists, false is returned.
 func (cfg AccountsConfig) IsKeystoreWatchonly(rootFingerprint []byte) bool {
@@ -44,6 +44,38 @@ func TestByCode(t *testing.T) {
 	require.Equal(t, "foo", cfg.Accounts[0].Name)
 }
 
+func TestLookupContact(t *testing.T) {
+	cfg := AccountsConfig{
+		Contacts: []*Contact{
+			{ID: "1", Name: "Alice", CoinCode: coin.CodeBTC, Address: "bc1qalice"},
+			{ID: "2", Name: "Bob", CoinCode: coin.CodeLTC, Address: "ltc1qbob"},
+		},
+	}
+	contact := cfg.LookupContact("2")
+	require.NotNil(t, contact)
+	require.Equal(t, "Bob", contact.Name)
+	require.Nil(t, cfg.LookupContact("3"))
+
+	// A reference is returned.
+	contact.Name = "Bobby"
+	require.Equal(t, "Bobby", cfg.Contacts[1].Name)
+
+	require.Equal(t, cfg.Contacts[0], cfg.LookupContactByAddress(coin.CodeBTC, "bc1qalice"))
+	require.Nil(t, cfg.LookupContactByAddress(coin.CodeLTC, "bc1qalice"))
+	require.Nil(t, cfg.LookupContactByAddress(coin.CodeBTC, "bc1qbob"))
+}
+
+func TestLookupERC20Token(t *testing.T) {
+	cfg := AccountsConfig{
+		ERC20Tokens: []*ERC20Token{
+			{Code: "eth-erc20-abc0x1234", Symbol: "ABC"},
+			{Code: "eth-erc20-def0x5678", Symbol: "DEF"},
+		},
+	}
+	require.Equal(t, cfg.ERC20Tokens[1], cfg.LookupERC20Token("eth-erc20-def0x5678"))
+	require.Nil(t, cfg.LookupERC20Token("eth-erc20-usdt"))
+}
+
 func TestSetTokenActive(t *testing.T) {
 	// not an ETH account.
 	require.Error(t, (&Account{CoinCode: coin.CodeGOETH}).SetTokenActive("TOKEN", true))
@@ -54,11 +54,27 @@ const (
 	ETHTransactionsSourceNone ETHTransactionsSource = "none"
 	// ETHTransactionsSourceEtherScan configures to get transactions from EtherScan.
 	ETHTransactionsSourceEtherScan ETHTransactionsSource = "etherScan"
+	// ETHTransactionsSourceNode configures to rebuild transactions from the JSON-RPC node, see
+	// ethCoinConfig.NodeURL.
+	ETHTransactionsSourceNode ETHTransactionsSource = "node"
 )
 
 // ethCoinConfig holds configurations for ethereum coins.
 type ethCoinConfig struct {
 	DeprecatedActiveERC20Tokens []string `json:"activeERC20Tokens"`
+
+	// NodeURL is the URL of a JSON-RPC node, e.g. a self-hosted Geth node, used instead of
+	// EtherScan for the network of the coin. If empty, EtherScan is used.
+	NodeURL string `json:"nodeURL,omitempty"`
+	// TransactionsSource is where transactions are fetched from if NodeURL is set. Defaults to
+	// ETHTransactionsSourceNode.
+	TransactionsSource ETHTransactionsSource `json:"transactionsSource,omitempty"`
+	// IndexerURL is the URL of an EtherScan-compatible API, e.g. a self-hosted Blockscout, which
+	// the node source uses to find ETH transactions. If empty, the most recent blocks are scanned.
+	IndexerURL string `json:"indexerURL,omitempty"`
+	// ScanBlocks is the number of recent blocks scanned for ETH transactions if there is no
+	// indexer. If 0, a default is used.
+	ScanBlocks uint64 `json:"scanBlocks,omitempty"`
 }
 
 type proxyConfig struct {
@@ -76,12 +92,19 @@ type Backend struct {
 
 	Authentication bool `json:"authentication"`
 
-	BTC  btcCoinConfig `json:"btc"`
-	TBTC btcCoinConfig `json:"tbtc"`
-	RBTC btcCoinConfig `json:"rbtc"`
-	LTC  btcCoinConfig `json:"ltc"`
-	TLTC btcCoinConfig `json:"tltc"`
-	ETH  ethCoinConfig `json:"eth"`
+	BTC   btcCoinConfig `json:"btc"`
+	TBTC  btcCoinConfig `json:"tbtc"`
+	SBTC  btcCoinConfig `json:"sbtc"`
+	TBTC4 btcCoinConfig `json:"tbtc4"`
+	RBTC  btcCoinConfig `json:"rbtc"`
+	LTC   btcCoinConfig `json:"ltc"`
+	TLTC  btcCoinConfig `json:"tltc"`
+	ETH   ethCoinConfig `json:"eth"`
+	// EVM networks other than Ethereum. The ERC20 token settings of ethCoinConfig do not apply.
+	ARBETH  ethCoinConfig `json:"arbeth"`
+	OPETH   ethCoinConfig `json:"opeth"`
+	BASEETH ethCoinConfig `json:"baseeth"`
+	POL     ethCoinConfig `json:"pol"`
 
 	// Removed in v4.35 - don't reuse these two keys.
 	TETH struct{} `json:"teth"`
@@ -102,6 +125,10 @@ type Backend struct {
 
 	// BtcUnit is the unit used to represent Bitcoin amounts. See `coin.BtcUnit` for details.
 	BtcUnit coin.BtcUnit `json:"btcUnit"`
+
+	// RatesProviders are the sources of exchange rates, in fallback order. If empty, the CoinGecko
+	// mirror run by Shift Crypto is used. Changes take effect after a restart.
+	RatesProviders []rates.ProviderConfig `json:"ratesProviders,omitempty"`
 }
 
 // DeprecatedCoinActive returns the Active setting for a coin by code.  This call is should not be
@@ -109,7 +136,7 @@ type Backend struct {
 // kept in the accounts config.
 func (backend Backend) DeprecatedCoinActive(code coin.Code) bool {
 	switch code {
-	case coin.CodeBTC, coin.CodeTBTC, coin.CodeRBTC:
+	case coin.CodeBTC, coin.CodeTBTC, coin.CodeSBTC, coin.CodeTBTC4, coin.CodeRBTC:
 		return backend.DeprecatedBitcoinActive
 	case coin.CodeLTC, coin.CodeTLTC:
 		return backend.DeprecatedLitecoinActive
@@ -142,6 +169,44 @@ hbWFoWMI
 -----END CERTIFICATE-----
 `
 
+// ISRGRootX1 is the Let's Encrypt root certificate, pinned for public servers using certificates
+// issued by Let's Encrypt.
+// C=US, O=Internet Security Research Group, CN=ISRG Root X1
+// Serial: 8210cfb0d240e3594463e0bb63828b00.
+const ISRGRootX1 = `
+-----BEGIN CERTIFICATE-----
+MIIFazCCA1OgAwIBAgIRAIIQz7DSQONZRGPgu2OCiwAwDQYJKoZIhvcNAQELBQAw
+TzELMAkGA1UEBhMCVVMxKTAnBgNVBAoTIEludGVybmV0IFNlY3VyaXR5IFJlc2Vh
+cmNoIEdyb3VwMRUwEwYDVQQDEwxJU1JHIFJvb3QgWDEwHhcNMTUwNjA0MTEwNDM4
+WhcNMzUwNjA0MTEwNDM4WjBPMQswCQYDVQQGEwJVUzEpMCcGA1UEChMgSW50ZXJu
+ZXQgU2VjdXJpdHkgUmVzZWFyY2ggR3JvdXAxFTATBgNVBAMTDElTUkcgUm9vdCBY
+MTCCAiIwDQYJKoZIhvcNAQEBBQADggIPADCCAgoCggIBAK3oJHP0FDfzm54rVygc
+h77ct984kIxuPOZXoHj3dcKi/vVqbvYATyjb3miGbESTtrFj/RQSa78f0uoxmyF+
+0TM8ukj13Xnfs7j/EvEhmkvBioZxaUpmZmyPfjxwv60pIgbz5MDmgK7iS4+3mX6U
+A5/TR5d8mUgjU+g4rk8Kb4Mu0UlXjIB0ttov0DiNewNwIRt18jA8+o+u3dpjq+sW
+T8KOEUt+zwvo/7V3LvSye0rgTBIlDHCNAymg4VMk7BPZ7hm/ELNKjD+Jo2FR3qyH
+B5T0Y3HsLuJvW5iB4YlcNHlsdu87kGJ55tukmi8mxdAQ4Q7e2RCOFvu396j3x+UC
+B5iPNgiV5+I3lg02dZ77DnKxHZu8A/lJBdiB3QW0KtZB6awBdpUKD9jf1b0SHzUv
+KBds0pjBqAlkd25HN7rOrFleaJ1/ctaJxQZBKT5ZPt0m9STJEadao0xAH0ahmbWn
+OlFuhjuefXKnEgV4We0+UXgVCwOPjdAvBbI+e0ocS3MFEvzG6uBQE3xDk3SzynTn
+jh8BCNAw1FtxNrQHusEwMFxIt4I7mKZ9YIqioymCzLq9gwQbooMDQaHWBfEbwrbw
+qHyGO0aoSCqI3Haadr8faqU9GY/rOPNk3sgrDQoo//fb4hVC1CLQJ13hef4Y53CI
+rU7m2Ys6xt0nUW7/vGT1M0NPAgMBAAGjQjBAMA4GA1UdDwEB/wQEAwIBBjAPBgNV
+HRMBAf8EBTADAQH/MB0GA1UdDgQWBBR5tFnme7bl5AFzgAiIyBpY9umbbjANBgkq
+hkiG9w0BAQsFAAOCAgEAVR9YqbyyqFDQDLHYGmkgJykIrGF1XIpu+ILlaS/V9lZL
+ubhzEFnTIZd+50xx+7LSYK05qAvqFyFWhfFQDlnrzuBZ6brJFe+GnY+EgPbk6ZGQ
+3BebYhtF8GaV0nxvwuo77x/Py9auJ/GpsMiu/X1+mvoiBOv/2X/qkSsisRcOj/KK
+NFtY2PwByVS5uCbMiogziUwthDyC3+6WVwW6LLv3xLfHTjuCvjHIInNzktHCgKQ5
+ORAzI4JMPJ+GslWYHb4phowim57iaztXOoJwTdwJx4nLCgdNbOhdjsnvzqvHu7Ur
+TkXWStAmzOVyyghqpZXjFaH3pO3JLF+l+/+sKAIuvtd7u+Nxe5AW0wdeRlN8NwdC
+jNPElpzVmbUq4JUagEiuTDkHzsxHpFKVK7q4+63SM1N95R1NbdWhscdCb+ZAJzVc
+oyi3B43njTOQ5yOf+1CceWxG1bQVs5ZufpsMljq4Ui0/1lvh+wjChP4kqKOJ2qxq
+4RgqsahDYVvTH9w7jXbyLeiNdd8XM2w9U/t7y0Ff/9yi0GE44Za4rF2LN9d11TPA
+mRGunUHBcnWEvgJBQl9nJEiU0Zsnvgc/ubhPgXRR4Xq37Z0j4r7g1SgEEzwxA57d
+emyPxgcYxn/eR44/KJ4EBs+lVDR3veyJm+kXQ99b21/+jh5Xos1AnX5iItreGCc=
+-----END CERTIFICATE-----
+`
+
 // NewDefaultAppConfig returns the default app config.
 func NewDefaultAppConfig() AppConfig {
 	return AppConfig{
@@ -183,6 +248,25 @@ func NewDefaultAppConfig() AppConfig {
 					},
 				},
 			},
+			// Public servers with Let's Encrypt certificates.
+			SBTC: btcCoinConfig{
+				ElectrumServers: []*ServerInfo{
+					{
+						Server:  "mempool.space:60602",
+						TLS:     true,
+						PEMCert: ISRGRootX1,
+					},
+				},
+			},
+			TBTC4: btcCoinConfig{
+				ElectrumServers: []*ServerInfo{
+					{
+						Server:  "mempool.space:40002",
+						TLS:     true,
+						PEMCert: ISRGRootX1,
+					},
+				},
+			},
 			RBTC: btcCoinConfig{
 				ElectrumServers: []*ServerInfo{
 					{
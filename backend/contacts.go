@@ -0,0 +1,171 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/random"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// errContactInvalidAddress is returned if the address of a contact is not valid for its coin.
+	errContactInvalidAddress errp.ErrorCode = "contactInvalidAddress"
+	// errContactAlreadyExists is returned if another contact has the same coin and address.
+	errContactAlreadyExists errp.ErrorCode = "contactAlreadyExists"
+)
+
+// Contacts returns the address book, sorted by name.
+func (backend *Backend) Contacts() []config.Contact {
+	result := []config.Contact{}
+	for _, contact := range backend.config.AccountsConfig().Contacts {
+		result = append(result, *contact)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
+
+// AddContact validates the contact and adds it to the address book. The ID of the new contact is
+// returned.
+func (backend *Backend) AddContact(contact config.Contact) (string, error) {
+	if err := backend.validateContact(&contact); err != nil {
+		return "", err
+	}
+	contact.ID = hex.EncodeToString(random.BytesOrPanic(16))
+	err := backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		if accountsConfig.LookupContactByAddress(contact.CoinCode, contact.Address) != nil {
+			return errp.WithStack(errContactAlreadyExists)
+		}
+		accountsConfig.Contacts = append(accountsConfig.Contacts, &contact)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	backend.emitContactsChanged()
+	return contact.ID, nil
+}
+
+// UpdateContact validates the contact and replaces the contact with the same ID.
+func (backend *Backend) UpdateContact(contact config.Contact) error {
+	if err := backend.validateContact(&contact); err != nil {
+		return err
+	}
+	err := backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		other := accountsConfig.LookupContactByAddress(contact.CoinCode, contact.Address)
+		if other != nil && other.ID != contact.ID {
+			return errp.WithStack(errContactAlreadyExists)
+		}
+		for i, existing := range accountsConfig.Contacts {
+			if existing.ID == contact.ID {
+				// Replace instead of modifying the contact, as references to it may be in use.
+				accountsConfig.Contacts[i] = &contact
+				return nil
+			}
+		}
+		return errp.Newf("Could not find contact %s", contact.ID)
+	})
+	if err != nil {
+		return err
+	}
+	backend.emitContactsChanged()
+	return nil
+}
+
+// DeleteContact removes the contact with the given ID from the address book.
+func (backend *Backend) DeleteContact(id string) error {
+	err := backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		contacts := []*config.Contact{}
+		for _, contact := range accountsConfig.Contacts {
+			if contact.ID != id {
+				contacts = append(contacts, contact)
+			}
+		}
+		if len(contacts) == len(accountsConfig.Contacts) {
+			return errp.Newf("Could not find contact %s", id)
+		}
+		accountsConfig.Contacts = contacts
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	backend.emitContactsChanged()
+	return nil
+}
+
+// validateContact checks the name and the address of the contact. The address is normalized, so
+// that it can be matched against the addresses of transactions.
+func (backend *Backend) validateContact(contact *config.Contact) error {
+	contact.Name = strings.TrimSpace(contact.Name)
+	if contact.Name == "" {
+		return errp.New("Name cannot be empty")
+	}
+	contact.Address = strings.TrimSpace(contact.Address)
+	coin, err := backend.Coin(contact.CoinCode)
+	if err != nil {
+		return err
+	}
+	switch specificCoin := coin.(type) {
+	case *btc.Coin:
+		// Extended public keys are not accepted, as the addresses derived from them could not be
+		// matched against transaction addresses.
+		address, err := specificCoin.DecodeAddress(contact.Address)
+		if err == nil {
+			contact.Address = address.EncodeAddress()
+			return nil
+		}
+	case *eth.Coin:
+		if eth.IsValidEthAddress(contact.Address) {
+			contact.Address = ethcommon.HexToAddress(contact.Address).Hex()
+			return nil
+		}
+	}
+	return errp.WithStack(errContactInvalidAddress)
+}
+
+// contactName returns the name of the contact with the given address in the address book, or an
+// empty string if there is none. ERC20 token transactions are resolved using the contacts of the
+// Ethereum network the token is on.
+func (backend *Backend) contactName(coin coinpkg.Coin, address string) string {
+	coinCode := coin.Code()
+	if ethCoin, ok := coin.(*eth.Coin); ok && ethCoin.ERC20Token() != nil {
+		coinCode = coinpkg.CodeETH
+	}
+	contact := backend.config.AccountsConfig().LookupContactByAddress(coinCode, address)
+	if contact == nil {
+		return ""
+	}
+	return contact.Name
+}
+
+func (backend *Backend) emitContactsChanged() {
+	backend.Notify(observable.Event{
+		Subject: "contacts",
+		Action:  action.Reload,
+	})
+}
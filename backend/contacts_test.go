@@ -0,0 +1,126 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContacts(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	require.Equal(t, []config.Contact{}, b.Contacts())
+
+	// Addresses are normalized.
+	bobID, err := b.AddContact(config.Contact{
+		Name:     " Bob ",
+		CoinCode: coinpkg.CodeBTC,
+		Address:  "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+		Notes:    "Rent",
+	})
+	require.NoError(t, err)
+	aliceID, err := b.AddContact(config.Contact{
+		Name:     "alice",
+		CoinCode: coinpkg.CodeETH,
+		Address:  "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+	})
+	require.NoError(t, err)
+	_, err = b.AddContact(config.Contact{
+		Name:     "Carol",
+		CoinCode: coinpkg.CodeBTC,
+		Address:  "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq",
+	})
+	require.NoError(t, err)
+
+	contacts := b.Contacts()
+	require.Len(t, contacts, 3)
+	require.Equal(t, config.Contact{
+		ID:       aliceID,
+		Name:     "alice",
+		CoinCode: coinpkg.CodeETH,
+		Address:  "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	}, contacts[0])
+	require.Equal(t, config.Contact{
+		ID:       bobID,
+		Name:     "Bob",
+		CoinCode: coinpkg.CodeBTC,
+		Address:  "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		Notes:    "Rent",
+	}, contacts[1])
+	require.Equal(t, "Carol", contacts[2].Name)
+
+	// Invalid entries.
+	for _, contact := range []config.Contact{
+		{Name: "", CoinCode: coinpkg.CodeBTC, Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{Name: "Dave", CoinCode: coinpkg.CodeLTC, Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{Name: "Dave", CoinCode: coinpkg.CodeETH, Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"},
+		{Name: "Dave", CoinCode: coinpkg.CodeBTC, Address: "xpub6GP83vJASH1kS7dQPWXFjVHDfYajopbG8U3j8peBH67CRCnb8QmDxZJfWpbgCQNHAzCDJ4MyVYjoh7Yv9yo7PQuZ9YyktgrtD9vmeo67Y4E"},
+		{Name: "Dave", CoinCode: coinpkg.CodeBTC, Address: "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"},
+		{Name: "Dave", CoinCode: "unknown", Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+	} {
+		_, err := b.AddContact(contact)
+		require.Error(t, err)
+	}
+	_, err = b.AddContact(config.Contact{Name: "Bob 2", CoinCode: coinpkg.CodeBTC, Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"})
+	require.Equal(t, errContactAlreadyExists, errp.Cause(err))
+
+	// Resolving contact names.
+	btcCoin, err := b.Coin(coinpkg.CodeBTC)
+	require.NoError(t, err)
+	ethCoin, err := b.Coin(coinpkg.CodeETH)
+	require.NoError(t, err)
+	tokenCoin, err := b.Coin("eth-erc20-usdt")
+	require.NoError(t, err)
+	require.Equal(t, "Bob", b.contactName(btcCoin, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"))
+	require.Equal(t, "", b.contactName(btcCoin, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"))
+	require.Equal(t, "alice", b.contactName(ethCoin, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"))
+	require.Equal(t, "alice", b.contactName(tokenCoin, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"))
+
+	// Update.
+	require.NoError(t, b.UpdateContact(config.Contact{
+		ID:       bobID,
+		Name:     "Robert",
+		CoinCode: coinpkg.CodeBTC,
+		Address:  "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+	}))
+	require.Equal(t, "Robert", b.contactName(btcCoin, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"))
+	require.Error(t, b.UpdateContact(config.Contact{
+		ID:       "unknown",
+		Name:     "Robert",
+		CoinCode: coinpkg.CodeBTC,
+		Address:  "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+	}))
+	require.Equal(t, errContactAlreadyExists, errp.Cause(b.UpdateContact(config.Contact{
+		ID:       aliceID,
+		Name:     "alice",
+		CoinCode: coinpkg.CodeBTC,
+		Address:  "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+	})))
+
+	// Delete.
+	require.NoError(t, b.DeleteContact(bobID))
+	require.Error(t, b.DeleteContact(bobID))
+	require.Len(t, b.Contacts(), 2)
+	require.Equal(t, "", b.contactName(btcCoin, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"))
+
+	// Persisted.
+	require.Len(t, b.config.AccountsConfig().Contacts, 2)
+}
@@ -20,8 +20,10 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
@@ -257,6 +259,17 @@ func (keystore *keystore) SignBTCMessage(message []byte, keypath signing.Absolut
 	return nil, errp.New("unsupported")
 }
 
+// CanSignMessageBIP322 implements keystore.Keystore.
+func (keystore *keystore) CanSignMessageBIP322(coin.Code) bool {
+	return false
+}
+
+// SignBTCMessageBIP322 implements keystore.Keystore.
+func (keystore *keystore) SignBTCMessageBIP322(
+	[]byte, signing.AbsoluteKeypath, signing.ScriptType) (wire.TxWitness, error) {
+	return nil, errp.New("unsupported")
+}
+
 // SignETHMessage implements keystore.Keystore.
 func (keystore *keystore) SignETHMessage(message []byte, keypath signing.AbsoluteKeypath) ([]byte, error) {
 	return nil, errp.New("unsupported")
@@ -267,6 +280,27 @@ func (keystore *keystore) SignETHTypedMessage(chainId uint64, data []byte, keypa
 	return nil, errp.New("unsupported")
 }
 
+// SupportsPayjoin implements keystore.Keystore.
+func (keystore *keystore) SupportsPayjoin(coin.Coin) bool {
+	return false
+}
+
+// SupportsProofOfReserves implements keystore.Keystore.
+func (keystore *keystore) SupportsProofOfReserves(coin.Coin) bool {
+	return false
+}
+
+// SupportsSilentPayments implements keystore.Keystore.
+func (keystore *keystore) SupportsSilentPayments(coin.Coin) bool {
+	return false
+}
+
+// SilentPaymentSharedSecret implements keystore.Keystore.
+func (keystore *keystore) SilentPaymentSharedSecret(
+	[]*signing.Configuration, []byte, *btcec.PublicKey) (*btcec.PublicKey, error) {
+	return nil, errp.New("unsupported")
+}
+
 // SignETHWalletConnectTransaction implements keystore.Keystore.
 func (keystore *keystore) SignETHWalletConnectTransaction(chainID uint64, tx *types.Transaction, keypath signing.AbsoluteKeypath) ([]byte, error) {
 	return nil, errp.New("unsupported")
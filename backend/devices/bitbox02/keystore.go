@@ -20,9 +20,11 @@ import (
 	"math/big"
 	"sync"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/blockchain"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/types"
@@ -107,7 +109,7 @@ func (keystore *keystore) SupportsAccount(coin coinpkg.Coin, meta interface{}) b
 		if scriptType == signing.ScriptTypeP2TR {
 			// Taproot available since v9.10.0.
 			switch coin.Code() {
-			case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeRBTC:
+			case coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeSBTC, coinpkg.CodeTBTC4, coinpkg.CodeRBTC:
 				return keystore.device.Version().AtLeast(semver.NewSemVer(9, 10, 0))
 			default:
 				return false
@@ -562,6 +564,18 @@ func (keystore *keystore) SignBTCMessage(message []byte, keypath signing.Absolut
 	return electrum65, err
 }
 
+// CanSignMessageBIP322 implements keystore.Keystore.
+func (keystore *keystore) CanSignMessageBIP322(coinpkg.Code) bool {
+	// The firmware only signs messages in the legacy format.
+	return false
+}
+
+// SignBTCMessageBIP322 implements keystore.Keystore.
+func (keystore *keystore) SignBTCMessageBIP322(
+	[]byte, signing.AbsoluteKeypath, signing.ScriptType) (wire.TxWitness, error) {
+	return nil, errp.New("unsupported")
+}
+
 // SignETHMessage implements keystore.Keystore.
 func (keystore *keystore) SignETHMessage(message []byte, keypath signing.AbsoluteKeypath) ([]byte, error) {
 	signature, err := keystore.device.ETHSignMessage(params.MainnetChainConfig.ChainID.Uint64(), keypath.ToUInt32(), message)
@@ -586,6 +600,31 @@ func (keystore *keystore) SignETHTypedMessage(chainId uint64, data []byte, keypa
 	return signature, nil
 }
 
+// SupportsPayjoin implements keystore.Keystore.
+func (keystore *keystore) SupportsPayjoin(coinpkg.Coin) bool {
+	// The firmware only signs transactions in which all inputs belong to the account.
+	return false
+}
+
+// SupportsProofOfReserves implements keystore.Keystore.
+func (keystore *keystore) SupportsProofOfReserves(coinpkg.Coin) bool {
+	// The firmware needs the previous transaction of each input, which does not exist for the
+	// commitment input.
+	return false
+}
+
+// SupportsSilentPayments implements keystore.Keystore.
+func (keystore *keystore) SupportsSilentPayments(coinpkg.Coin) bool {
+	// The firmware API does not expose the ECDH operation needed for silent payments yet.
+	return false
+}
+
+// SilentPaymentSharedSecret implements keystore.Keystore.
+func (keystore *keystore) SilentPaymentSharedSecret(
+	[]*signing.Configuration, []byte, *btcec.PublicKey) (*btcec.PublicKey, error) {
+	return nil, errp.New("unsupported")
+}
+
 // SignETHWalletConnectTransaction implements keystore.Keystore.
 func (keystore *keystore) SignETHWalletConnectTransaction(chainId uint64, tx *ethTypes.Transaction, keypath signing.AbsoluteKeypath) ([]byte, error) {
 	signature, err := keystore.device.ETHSign(
@@ -26,8 +26,12 @@ import (
 var btcMsgCoinMap = map[coin.Code]messages.BTCCoin{
 	coin.CodeBTC:  messages.BTCCoin_BTC,
 	coin.CodeTBTC: messages.BTCCoin_TBTC,
-	coin.CodeLTC:  messages.BTCCoin_LTC,
-	coin.CodeTLTC: messages.BTCCoin_TLTC,
+	// The firmware has no separate coins for signet and testnet4, which use the same keypaths and
+	// address formats as testnet3.
+	coin.CodeSBTC:  messages.BTCCoin_TBTC,
+	coin.CodeTBTC4: messages.BTCCoin_TBTC,
+	coin.CodeLTC:   messages.BTCCoin_LTC,
+	coin.CodeTLTC:  messages.BTCCoin_TLTC,
 }
 
 var btcMsgScriptTypeMap = map[signing.ScriptType]messages.BTCScriptConfig_SimpleType{
@@ -16,10 +16,64 @@
 package backend
 
 import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// errERC20InvalidAddress is returned if the contract address of a token is not valid.
+	errERC20InvalidAddress errp.ErrorCode = "erc20InvalidAddress"
+	// errERC20NotAToken is returned if the metadata of a token could not be read from the contract.
+	errERC20NotAToken errp.ErrorCode = "erc20NotAToken"
+
+	// erc20MetadataTimeout is the timeout for reading the metadata of a token from the contract.
+	erc20MetadataTimeout = 30 * time.Second
+	// The name and symbol of user-added tokens are truncated to these lengths.
+	erc20MaxNameLength   = 64
+	erc20MaxSymbolLength = 16
+	// erc20MaxCodeSymbolLength is how much of the symbol is used in the code of a user-added token.
+	erc20MaxCodeSymbolLength = 10
+)
+
+// ERC20TokenWarning flags something unusual about a token added by the user, which the user should
+// check before trusting the token.
+type ERC20TokenWarning string
+
+const (
+	// ERC20WarningNoName means that the contract does not report a name.
+	ERC20WarningNoName ERC20TokenWarning = "noName"
+	// ERC20WarningUnusualSymbol means that the symbol is missing, overly long or contains
+	// whitespace or control characters.
+	ERC20WarningUnusualSymbol ERC20TokenWarning = "unusualSymbol"
+	// ERC20WarningUnusualDecimals means that the token has no decimals or more than ETH.
+	ERC20WarningUnusualDecimals ERC20TokenWarning = "unusualDecimals"
+	// ERC20WarningSymbolInUse means that the symbol is the same as that of another coin or token.
+	// Scam tokens often imitate well-known tokens.
+	ERC20WarningSymbolInUse ERC20TokenWarning = "symbolInUse"
+	// ERC20WarningNoPrice means that there is no exchange rate for the token, so no fiat values are
+	// shown.
+	ERC20WarningNoPrice ERC20TokenWarning = "noPrice"
 )
 
+// AddedERC20Token is the result of adding an ERC20 token by its contract address.
+type AddedERC20Token struct {
+	// TokenCode is the code of the token that was activated, e.g. "eth-erc20-abc0x1234".
+	TokenCode string `json:"tokenCode"`
+	// Warnings are empty for built-in tokens.
+	Warnings []ERC20TokenWarning `json:"warnings"`
+}
+
 type erc20Token struct {
 	code  coin.Code
 	name  string
@@ -88,6 +142,24 @@ var erc20Tokens = []erc20Token{
 	},
 }
 
+// erc20Allowlist are well-known tokens that are not built into the app. Discovered tokens that are
+// neither built in, in this list nor added by the user are marked as spam, as unsolicited airdrops
+// are a common way to lure users to scam websites.
+var erc20Allowlist = map[ethcommon.Address]bool{
+	ethcommon.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"): true, // WETH
+	ethcommon.HexToAddress("0xae7ab96520DE3A18E5e111B5EaAb095312D7fE84"): true, // stETH
+	ethcommon.HexToAddress("0x1f9840a85d5aF5bf1D1762F925BDADdC4201F984"): true, // UNI
+	ethcommon.HexToAddress("0x7Fc66500c84A76Ad7e9c93437bFc5Ac33E2DDaE9"): true, // AAVE
+	ethcommon.HexToAddress("0x5A98FcBEA516Cf06857215779Fd812CA3beF1B32"): true, // LDO
+	ethcommon.HexToAddress("0xD533a949740bb3306d119CC777fa900bA034cd52"): true, // CRV
+	ethcommon.HexToAddress("0xc00e94Cb662C3520282E6f5717214004A7f26888"): true, // COMP
+	ethcommon.HexToAddress("0xC18360217D8F7Ab5e7c516566761Ea12Ce7F9D72"): true, // ENS
+	ethcommon.HexToAddress("0x111111111117dC0aa78b770fA6A738034120C302"): true, // 1INCH
+	ethcommon.HexToAddress("0x95aD61b0a150d79219dCF64E1E6Cc01f0B64C4cE"): true, // SHIB
+	ethcommon.HexToAddress("0x7D1AfA7B718fb893dB30A3aBc0Cfc608AaCfeBB0"): true, // MATIC
+}
+
+// erc20TokenByCode returns the built-in token with the given code, or nil if there is none.
 func erc20TokenByCode(code coin.Code) *erc20Token {
 	for _, token := range erc20Tokens {
 		if code == token.code {
@@ -97,3 +169,317 @@ func erc20TokenByCode(code coin.Code) *erc20Token {
 	}
 	return nil
 }
+
+// erc20TokenByAddress returns the built-in token with the given contract address, or nil if there
+// is none.
+func erc20TokenByAddress(contractAddress ethcommon.Address) *erc20Token {
+	for _, token := range erc20Tokens {
+		if token.token.ContractAddress() == contractAddress {
+			token := token
+			return &token
+		}
+	}
+	return nil
+}
+
+// customERC20TokenByCode returns the token with the given code that was added by the user, or nil
+// if there is none.
+func customERC20TokenByCode(accountsConfig config.AccountsConfig, code coin.Code) *erc20Token {
+	token := accountsConfig.LookupERC20Token(code)
+	if token == nil || !ethcommon.IsHexAddress(token.ContractAddress) {
+		return nil
+	}
+	return &erc20Token{
+		code:  token.Code,
+		name:  token.Name,
+		unit:  token.Symbol,
+		token: erc20.NewToken(token.ContractAddress, token.Decimals),
+	}
+}
+
+// customERC20TokenCode returns a code for a token added by the user that is not used by any other
+// token, made from its symbol and the start of its contract address like "eth-erc20-dai0x6b17".
+func customERC20TokenCode(accountsConfig *config.AccountsConfig, symbol string, contractAddress ethcommon.Address) coin.Code {
+	var codeSymbol strings.Builder
+	for _, r := range strings.ToLower(symbol) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			codeSymbol.WriteRune(r)
+		}
+		if codeSymbol.Len() == erc20MaxCodeSymbolLength {
+			break
+		}
+	}
+	if codeSymbol.Len() == 0 {
+		codeSymbol.WriteString("token")
+	}
+	address := strings.ToLower(contractAddress.Hex())
+	for length := 6; ; length++ {
+		code := coin.Code("eth-erc20-" + codeSymbol.String() + address[:length])
+		if (erc20TokenByCode(code) == nil && accountsConfig.LookupERC20Token(code) == nil) ||
+			length == len(address) {
+			return code
+		}
+	}
+}
+
+// truncateString returns the first maxRunes runes of s.
+func truncateString(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) > maxRunes {
+		return string(runes[:maxRunes])
+	}
+	return s
+}
+
+// erc20MetadataWarnings returns the warnings about the metadata of a token to be added. The symbol
+// is compared to those of the built-in coins and tokens and of the other tokens added by the user.
+func erc20MetadataWarnings(accountsConfig *config.AccountsConfig, metadata *erc20.Metadata) []ERC20TokenWarning {
+	warnings := []ERC20TokenWarning{}
+	if strings.TrimSpace(metadata.Name) == "" {
+		warnings = append(warnings, ERC20WarningNoName)
+	}
+	unusualSymbol := metadata.Symbol == "" ||
+		len([]rune(metadata.Symbol)) > erc20MaxSymbolLength ||
+		strings.IndexFunc(metadata.Symbol, func(r rune) bool {
+			return unicode.IsSpace(r) || !unicode.IsPrint(r)
+		}) >= 0
+	if unusualSymbol {
+		warnings = append(warnings, ERC20WarningUnusualSymbol)
+	}
+	if metadata.Decimals == 0 || metadata.Decimals > 18 {
+		warnings = append(warnings, ERC20WarningUnusualDecimals)
+	}
+	symbols := []string{"BTC", "LTC", "ETH"}
+	for _, token := range erc20Tokens {
+		symbols = append(symbols, token.unit)
+	}
+	for _, token := range accountsConfig.ERC20Tokens {
+		symbols = append(symbols, token.Symbol)
+	}
+	for _, symbol := range symbols {
+		if metadata.Symbol != "" && strings.EqualFold(strings.TrimSpace(metadata.Symbol), symbol) {
+			warnings = append(warnings, ERC20WarningSymbolInUse)
+			break
+		}
+	}
+	return warnings
+}
+
+// AddERC20Token activates the ERC20 token with the given contract address on an ETH account. If it
+// is not a built-in token, its name, symbol and decimals are read from the contract and the token
+// is persisted in the accounts config, so that it can be loaded like the built-in tokens.
+func (backend *Backend) AddERC20Token(accountCode accountsTypes.Code, contractAddress string) (*AddedERC20Token, error) {
+	added, err := backend.AddERC20Tokens(accountCode, []string{contractAddress})
+	if err != nil {
+		return nil, err
+	}
+	return added[0], nil
+}
+
+// AddERC20Tokens is like AddERC20Token for several tokens at once, e.g. to activate the tokens
+// found by DiscoverERC20Tokens. Either all or none of the tokens are added.
+func (backend *Backend) AddERC20Tokens(accountCode accountsTypes.Code, contractAddresses []string) ([]*AddedERC20Token, error) {
+	addresses := make([]ethcommon.Address, len(contractAddresses))
+	for i, contractAddress := range contractAddresses {
+		contractAddress = strings.TrimSpace(contractAddress)
+		if !ethcommon.IsHexAddress(contractAddress) {
+			return nil, errp.WithStack(errERC20InvalidAddress)
+		}
+		addresses[i] = ethcommon.HexToAddress(contractAddress)
+	}
+	accountsConfig := backend.config.AccountsConfig()
+	acct := accountsConfig.Lookup(accountCode)
+	if acct == nil {
+		return nil, errp.Newf("Could not find account %s", accountCode)
+	}
+	if acct.CoinCode != coin.CodeETH {
+		return nil, errp.New("tokens are only enabled for ETH")
+	}
+
+	// Read the metadata of the new tokens before modifying the config, as it needs network access.
+	metadata := map[ethcommon.Address]*erc20.Metadata{}
+	for _, address := range addresses {
+		if erc20TokenByAddress(address) != nil ||
+			accountsConfig.LookupERC20TokenByAddress(address.Hex()) != nil ||
+			metadata[address] != nil {
+			continue
+		}
+		ethCoin, err := backend.Coin(coin.CodeETH)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), erc20MetadataTimeout)
+		tokenMetadata, err := ethCoin.(*eth.Coin).ERC20Metadata(ctx, address)
+		cancel()
+		if err != nil {
+			backend.log.WithError(err).Errorf("Could not read the metadata of token %s", address.Hex())
+			return nil, errp.WithStack(errERC20NotAToken)
+		}
+		metadata[address] = tokenMetadata
+	}
+
+	var result []*AddedERC20Token
+	err := backend.config.ModifyAccountsConfig(func(accountsConfig *config.AccountsConfig) error {
+		acct := accountsConfig.Lookup(accountCode)
+		if acct == nil {
+			return errp.Newf("Could not find account %s", accountCode)
+		}
+		result = nil
+		for _, address := range addresses {
+			added, err := addERC20Token(accountsConfig, address, metadata[address])
+			if err != nil {
+				return err
+			}
+			token := accountsConfig.LookupERC20Token(coin.Code(added.TokenCode))
+			if token != nil && len(backend.RatesUpdater().LatestPrice()[token.Symbol]) == 0 {
+				added.Warnings = append(added.Warnings, ERC20WarningNoPrice)
+			}
+			if err := acct.SetTokenActive(added.TokenCode, true); err != nil {
+				return err
+			}
+			result = append(result, added)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	backend.ReinitializeAccounts()
+	return result, nil
+}
+
+// addERC20Token returns the code of the built-in or user-added token with the given contract
+// address. If there is none, the token is added to the accounts config using the metadata read from
+// the contract.
+func addERC20Token(
+	accountsConfig *config.AccountsConfig,
+	address ethcommon.Address,
+	metadata *erc20.Metadata) (*AddedERC20Token, error) {
+	if token := erc20TokenByAddress(address); token != nil {
+		return &AddedERC20Token{TokenCode: string(token.code), Warnings: []ERC20TokenWarning{}}, nil
+	}
+	if token := accountsConfig.LookupERC20TokenByAddress(address.Hex()); token != nil {
+		return &AddedERC20Token{TokenCode: string(token.Code), Warnings: []ERC20TokenWarning{}}, nil
+	}
+	if metadata == nil {
+		return nil, errp.WithStack(errERC20NotAToken)
+	}
+	warnings := erc20MetadataWarnings(accountsConfig, metadata)
+	symbol := truncateString(strings.TrimSpace(metadata.Symbol), erc20MaxSymbolLength)
+	if symbol == "" {
+		symbol = "TOKEN"
+	}
+	name := truncateString(strings.TrimSpace(metadata.Name), erc20MaxNameLength)
+	if name == "" {
+		name = symbol
+	}
+	token := &config.ERC20Token{
+		Code:            customERC20TokenCode(accountsConfig, metadata.Symbol, address),
+		ContractAddress: address.Hex(),
+		Name:            name,
+		Symbol:          symbol,
+		Decimals:        uint(metadata.Decimals),
+	}
+	accountsConfig.ERC20Tokens = append(accountsConfig.ERC20Tokens, token)
+	return &AddedERC20Token{TokenCode: string(token.Code), Warnings: warnings}, nil
+}
+
+// DiscoveredERC20Token is an ERC20 token found in the history of an ETH account.
+type DiscoveredERC20Token struct {
+	ContractAddress string `json:"contractAddress"`
+	// TokenCode is the code of the built-in or user-added token with this contract address, or
+	// empty if the token is unknown.
+	TokenCode string `json:"tokenCode,omitempty"`
+	Name      string `json:"name"`
+	Symbol    string `json:"symbol"`
+	Decimals  uint   `json:"decimals"`
+	// Balance is the current balance in the unit of the token, or nil if it could not be fetched.
+	Balance *string `json:"balance"`
+	// Transfers is the number of token transfers from or to the account.
+	Transfers int `json:"transfers"`
+	// Active is true if the token is activated on the account.
+	Active bool `json:"active"`
+	// Spam is true if the token is neither built in, well-known nor added by the user.
+	Spam bool `json:"spam"`
+}
+
+// discoveredERC20Tokens annotates the tokens found in the history of an ETH account with the
+// active tokens of the account and the known tokens. Tokens that are not spam come first, followed
+// by those with a balance.
+func discoveredERC20Tokens(
+	accountsConfig config.AccountsConfig,
+	activeTokens []string,
+	discovered []*eth.DiscoveredERC20Token) []*DiscoveredERC20Token {
+	result := []*DiscoveredERC20Token{}
+	for _, token := range discovered {
+		entry := &DiscoveredERC20Token{
+			ContractAddress: token.ContractAddress.Hex(),
+			Name:            token.Name,
+			Symbol:          token.Symbol,
+			Decimals:        token.Decimals,
+			Transfers:       token.Transfers,
+		}
+		if builtin := erc20TokenByAddress(token.ContractAddress); builtin != nil {
+			entry.TokenCode = string(builtin.code)
+			entry.Name = builtin.name
+			entry.Symbol = builtin.unit
+			entry.Decimals = builtin.token.Decimals()
+		} else if custom := accountsConfig.LookupERC20TokenByAddress(token.ContractAddress.Hex()); custom != nil {
+			entry.TokenCode = string(custom.Code)
+			entry.Name = custom.Name
+			entry.Symbol = custom.Symbol
+			entry.Decimals = custom.Decimals
+		} else {
+			entry.Spam = !erc20Allowlist[token.ContractAddress]
+		}
+		if token.Balance != nil {
+			balance := erc20.FormatAmount(token.Balance, entry.Decimals)
+			entry.Balance = &balance
+		}
+		for _, activeToken := range activeTokens {
+			if activeToken == entry.TokenCode {
+				entry.Active = true
+				break
+			}
+		}
+		result = append(result, entry)
+	}
+	hasBalance := func(token *DiscoveredERC20Token) bool {
+		return token.Balance != nil && *token.Balance != "0"
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Spam != result[j].Spam {
+			return !result[i].Spam
+		}
+		return hasBalance(result[i]) && !hasBalance(result[j])
+	})
+	return result
+}
+
+// DiscoverERC20Tokens lists all ERC20 tokens an ETH account has sent or received, including tokens
+// that are not activated, so that the user can find and activate tokens they did not know about.
+func (backend *Backend) DiscoverERC20Tokens(accountCode accountsTypes.Code) ([]*DiscoveredERC20Token, error) {
+	acct := backend.Accounts().lookup(accountCode)
+	if acct == nil {
+		return nil, errp.Newf("Could not find account %s", accountCode)
+	}
+	ethAccount, ok := acct.(*eth.Account)
+	if !ok || acct.Coin().Code() != coin.CodeETH {
+		return nil, errp.New("tokens are only enabled for ETH")
+	}
+	discovered, err := ethAccount.DiscoverERC20Tokens()
+	if err != nil {
+		return nil, err
+	}
+	return discoveredERC20Tokens(
+		backend.config.AccountsConfig(), acct.Config().Config.ActiveTokens, discovered), nil
+}
+
+// ERC20Tokens returns the ERC20 tokens added by the user.
+func (backend *Backend) ERC20Tokens() []config.ERC20Token {
+	result := []config.ERC20Token{}
+	for _, token := range backend.config.AccountsConfig().ERC20Tokens {
+		result = append(result, *token)
+	}
+	return result
+}
@@ -0,0 +1,245 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/erc20"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/eth/rpcclient/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// mockERC20Contracts returns an rpc client mock serving the metadata of the given tokens, keyed by
+// contract address. Calls to other addresses return nothing, like calls to an address without a
+// contract.
+func mockERC20Contracts(t *testing.T, tokens map[common.Address]erc20.Metadata) *mocks.InterfaceMock {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(erc20.IERC20ABI))
+	require.NoError(t, err)
+	return &mocks.InterfaceMock{
+		CallContractFunc: func(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+			require.Nil(t, blockNumber)
+			token, ok := tokens[*msg.To]
+			if !ok {
+				return nil, nil
+			}
+			for name, value := range map[string]interface{}{
+				"name":     token.Name,
+				"symbol":   token.Symbol,
+				"decimals": token.Decimals,
+			} {
+				method := parsed.Methods[name]
+				if bytes.Equal(msg.Data, method.ID) {
+					return method.Outputs.Pack(value)
+				}
+			}
+			return nil, errp.New("unexpected call")
+		},
+	}
+}
+
+func TestAddERC20Token(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+	b.registerKeystore(makeBitBox02Multi())
+	const ethAccountCode = "v0-55555555-eth-0"
+
+	tokenAddress := common.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0e")
+	scamAddress := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	ethCoin, err := b.Coin(coinpkg.CodeETH)
+	require.NoError(t, err)
+	ethCoin.(*eth.Coin).TstSetClient(mockERC20Contracts(t, map[common.Address]erc20.Metadata{
+		tokenAddress: {Name: "Some Token", Symbol: "DAI", Decimals: 6},
+		scamAddress:  {Name: "", Symbol: "USD T", Decimals: 30},
+	}))
+
+	// Invalid address.
+	_, err = b.AddERC20Token(ethAccountCode, "0x1234")
+	require.Equal(t, errERC20InvalidAddress, errp.Cause(err))
+
+	// No contract at the address.
+	_, err = b.AddERC20Token(ethAccountCode, "0x2222222222222222222222222222222222222222")
+	require.Equal(t, errERC20NotAToken, errp.Cause(err))
+
+	// Tokens can only be added to ETH accounts.
+	_, err = b.AddERC20Token("v0-55555555-btc-0", tokenAddress.Hex())
+	require.Error(t, err)
+
+	// Built-in tokens are activated without reading the contract.
+	added, err := b.AddERC20Token(ethAccountCode, " 0xdac17f958d2ee523a2206206994597c13d831ec7")
+	require.NoError(t, err)
+	require.Equal(t, &AddedERC20Token{TokenCode: "eth-erc20-usdt", Warnings: []ERC20TokenWarning{}}, added)
+	require.Empty(t, b.ERC20Tokens())
+
+	// The code must not clash with the built-in DAI token.
+	added, err = b.AddERC20Token(ethAccountCode, strings.ToUpper(tokenAddress.Hex()[2:]))
+	require.NoError(t, err)
+	require.Equal(t, "eth-erc20-dai0x6b175", added.TokenCode)
+	require.Equal(t, []ERC20TokenWarning{ERC20WarningSymbolInUse, ERC20WarningNoPrice}, added.Warnings)
+	require.Equal(t, []config.ERC20Token{{
+		Code:            "eth-erc20-dai0x6b175",
+		ContractAddress: tokenAddress.Hex(),
+		Name:            "Some Token",
+		Symbol:          "DAI",
+		Decimals:        6,
+	}}, b.ERC20Tokens())
+	require.Equal(t,
+		[]string{"eth-erc20-usdt", "eth-erc20-dai0x6b175"},
+		b.Config().AccountsConfig().Lookup(ethAccountCode).ActiveTokens,
+	)
+	tokenAccount := lookup(b.Accounts(), "v0-55555555-eth-0-eth-erc20-dai0x6b175")
+	require.NotNil(t, tokenAccount)
+	require.Equal(t, "DAI", tokenAccount.Coin().Unit(false))
+	require.Equal(t, uint(6), tokenAccount.Coin().(*eth.Coin).Decimals(false))
+	require.Equal(t, tokenAddress, tokenAccount.Coin().(*eth.Coin).ERC20Token().ContractAddress())
+
+	// Adding the same token again only activates it.
+	require.NoError(t, b.SetTokenActive(ethAccountCode, "eth-erc20-dai0x6b175", false))
+	added, err = b.AddERC20Token(ethAccountCode, tokenAddress.Hex())
+	require.NoError(t, err)
+	require.Equal(t, "eth-erc20-dai0x6b175", added.TokenCode)
+	require.Len(t, b.ERC20Tokens(), 1)
+
+	added, err = b.AddERC20Token(ethAccountCode, scamAddress.Hex())
+	require.NoError(t, err)
+	require.Equal(t, "eth-erc20-usdt0x1111", added.TokenCode)
+	require.Equal(t, []ERC20TokenWarning{
+		ERC20WarningNoName,
+		ERC20WarningUnusualSymbol,
+		ERC20WarningUnusualDecimals,
+		ERC20WarningNoPrice,
+	}, added.Warnings)
+	require.Equal(t, "USD T", b.Config().AccountsConfig().LookupERC20Token("eth-erc20-usdt0x1111").Name)
+}
+
+func TestDiscoveredERC20Tokens(t *testing.T) {
+	usdt := common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	custom := common.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0e")
+	spam := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	accountsConfig := config.AccountsConfig{
+		ERC20Tokens: []*config.ERC20Token{{
+			Code:            "eth-erc20-abc0x6b175",
+			ContractAddress: custom.Hex(),
+			Name:            "ABC Token",
+			Symbol:          "ABC",
+			Decimals:        2,
+		}},
+	}
+	discovered := []*eth.DiscoveredERC20Token{
+		{
+			TokenActivity: erc20.TokenActivity{ContractAddress: spam, Name: "Claim at scam.example", Symbol: "CLAIM", Decimals: 18, Transfers: 1},
+			Balance:       big.NewInt(5e18),
+		},
+		{
+			TokenActivity: erc20.TokenActivity{ContractAddress: usdt, Name: "Tether", Symbol: "USDT", Decimals: 6, Transfers: 3},
+			Balance:       big.NewInt(0),
+		},
+		{
+			TokenActivity: erc20.TokenActivity{ContractAddress: weth, Name: "Wrapped Ether", Symbol: "WETH", Decimals: 18, Transfers: 1},
+		},
+		{
+			TokenActivity: erc20.TokenActivity{ContractAddress: custom, Name: "", Symbol: "", Decimals: 2, Transfers: 1},
+			Balance:       big.NewInt(12345),
+		},
+	}
+
+	tokens := discoveredERC20Tokens(accountsConfig, []string{"eth-erc20-usdt", "eth-erc20-bat"}, discovered)
+	str := func(s string) *string { return &s }
+	require.Equal(t, []*DiscoveredERC20Token{
+		{
+			ContractAddress: custom.Hex(),
+			TokenCode:       "eth-erc20-abc0x6b175",
+			Name:            "ABC Token",
+			Symbol:          "ABC",
+			Decimals:        2,
+			Balance:         str("123.45"),
+			Transfers:       1,
+		},
+		{
+			ContractAddress: usdt.Hex(),
+			TokenCode:       "eth-erc20-usdt",
+			Name:            "Tether USD",
+			Symbol:          "USDT",
+			Decimals:        6,
+			Balance:         str("0"),
+			Transfers:       3,
+			Active:          true,
+		},
+		{
+			ContractAddress: weth.Hex(),
+			Name:            "Wrapped Ether",
+			Symbol:          "WETH",
+			Decimals:        18,
+			Transfers:       1,
+		},
+		{
+			ContractAddress: spam.Hex(),
+			Name:            "Claim at scam.example",
+			Symbol:          "CLAIM",
+			Decimals:        18,
+			Balance:         str("5"),
+			Transfers:       1,
+			Spam:            true,
+		},
+	}, tokens)
+}
+
+func TestAddERC20Tokens(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+	b.registerKeystore(makeBitBox02Multi())
+	const ethAccountCode = "v0-55555555-eth-0"
+
+	tokenA := common.HexToAddress("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	tokenB := common.HexToAddress("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+	ethCoin, err := b.Coin(coinpkg.CodeETH)
+	require.NoError(t, err)
+	ethCoin.(*eth.Coin).TstSetClient(mockERC20Contracts(t, map[common.Address]erc20.Metadata{
+		tokenA: {Name: "Token A", Symbol: "A", Decimals: 18},
+		tokenB: {Name: "Token B", Symbol: "B", Decimals: 18},
+	}))
+
+	// Nothing is added if one of the tokens is invalid.
+	_, err = b.AddERC20Tokens(ethAccountCode, []string{
+		tokenA.Hex(), "0x2222222222222222222222222222222222222222"})
+	require.Equal(t, errERC20NotAToken, errp.Cause(err))
+	require.Empty(t, b.ERC20Tokens())
+
+	added, err := b.AddERC20Tokens(ethAccountCode, []string{
+		tokenA.Hex(), "0x0d8775f648430679a709e98d2b0cb6250d2887ef", tokenB.Hex()})
+	require.NoError(t, err)
+	require.Len(t, added, 3)
+	require.Equal(t, "eth-erc20-a0xaaaa", added[0].TokenCode)
+	require.Equal(t, "eth-erc20-bat", added[1].TokenCode)
+	require.Equal(t, "eth-erc20-b0xbbbb", added[2].TokenCode)
+	require.Equal(t,
+		[]string{"eth-erc20-a0xaaaa", "eth-erc20-bat", "eth-erc20-b0xbbbb"},
+		b.Config().AccountsConfig().Lookup(ethAccountCode).ActiveTokens,
+	)
+	require.NotNil(t, lookup(b.Accounts(), "v0-55555555-eth-0-eth-erc20-b0xbbbb"))
+}
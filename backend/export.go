@@ -0,0 +1,118 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/export"
+	utilConfig "github.com/digitalbitbox/bitbox-wallet-app/util/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// saveAndOpenFile lets the user choose where to save a file, suggesting the given name in the
+// downloads folder, writes it and opens it. Returns the path of the file, or an empty string if the
+// user aborted.
+func (backend *Backend) saveAndOpenFile(name string, write func(io.Writer) error) (string, error) {
+	downloadsDir, err := utilConfig.DownloadsDir()
+	if err != nil {
+		return "", err
+	}
+	path := backend.environment.GetSaveFilename(filepath.Join(downloadsDir, name))
+	if path == "" {
+		return "", nil
+	}
+	backend.log.Infof("Export to %s.", path)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", errp.WithStack(err)
+	}
+	if err := write(file); err != nil {
+		_ = file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", errp.WithStack(err)
+	}
+	if err := backend.environment.SystemOpen(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// exportTransactions returns the transactions of the given accounts in the time range [start, end),
+// for all active accounts if accountCodes is empty. start and end can be nil.
+func (backend *Backend) exportTransactions(
+	accountCodes []accountsTypes.Code, start, end *time.Time) ([]*export.Transaction, error) {
+	selected := map[accountsTypes.Code]bool{}
+	for _, code := range accountCodes {
+		if backend.Accounts().lookup(code) == nil {
+			return nil, errp.Newf("Could not find account %s", code)
+		}
+		selected[code] = true
+	}
+	filter := &accounts.TransactionsFilter{Start: start, End: end}
+	result := []*export.Transaction{}
+	for _, account := range backend.Accounts() {
+		if account.Config().Config.Inactive || account.FatalError() {
+			continue
+		}
+		if len(selected) > 0 && !selected[account.Config().Config.Code] {
+			continue
+		}
+		if err := account.Initialize(); err != nil {
+			return nil, err
+		}
+		txs, err := account.Transactions()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, export.FromAccount(account, txs, filter)...)
+	}
+	return result, nil
+}
+
+// ExportTransactions exports the transactions of the given accounts in the time range [start,
+// end) in one file of the given format, see `export.Formats()`. All active accounts are exported if
+// accountCodes is empty. Fiat values are in the given fiat currency. Returns the path of the file,
+// or an empty string if the user aborted.
+func (backend *Backend) ExportTransactions(
+	accountCodes []accountsTypes.Code,
+	format export.Format,
+	fiat string,
+	start, end *time.Time,
+) (string, error) {
+	exporter, err := export.Lookup(format)
+	if err != nil {
+		return "", err
+	}
+	transactions, err := backend.exportTransactions(accountCodes, start, end)
+	if err != nil {
+		return "", err
+	}
+	options := &export.Options{Fiat: fiat, Price: backend.RatesUpdater().HistoricalPriceAt}
+	name := fmt.Sprintf("%s-transactions-%s.%s",
+		time.Now().Format("2006-01-02-at-15-04-05"), format, exporter.Extension())
+	return backend.saveAndOpenFile(name, func(w io.Writer) error {
+		return export.Export(w, format, transactions, options)
+	})
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// coinTrackingExporter writes the CSV import format of CoinTracking. Receives are deposits and
+// sends are withdrawals, with the account name as the exchange. Transactions only paying a fee are
+// of type "Other Fee".
+//
+// Unconfirmed transactions are left out.
+type coinTrackingExporter struct{}
+
+// Extension implements Exporter.
+func (coinTrackingExporter) Extension() string {
+	return "csv"
+}
+
+// Export implements Exporter.
+func (coinTrackingExporter) Export(w io.Writer, transactions []*Transaction, _ *Options) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"Type",
+		"Buy Amount",
+		"Buy Currency",
+		"Sell Amount",
+		"Sell Currency",
+		"Fee",
+		"Fee Currency",
+		"Exchange",
+		"Trade-Group",
+		"Comment",
+		"Date",
+		"Tx-ID",
+	})
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, tx := range transactions {
+		if tx.Data.Timestamp == nil || tx.Data.Status == accounts.TxStatusPending {
+			continue
+		}
+		fee, feeCurrency := "", ""
+		if tx.paysFee() {
+			fee, feeCurrency = tx.fee(), tx.Coin.Unit(true)
+		}
+		var txType, buyAmount, buyCurrency, sellAmount, sellCurrency string
+		switch {
+		case tx.Data.Status == accounts.TxStatusFailed || tx.Data.Type == accounts.TxTypeSendSelf:
+			if fee == "" {
+				continue
+			}
+			// The fee is recorded as the sold amount of a fee-only transaction.
+			txType, sellAmount, sellCurrency = "Other Fee", fee, feeCurrency
+			fee, feeCurrency = "", ""
+		case tx.Data.Type == accounts.TxTypeReceive:
+			txType, buyAmount, buyCurrency = "Deposit", tx.amount(), tx.Coin.Unit(false)
+		case tx.Data.Type == accounts.TxTypeSend:
+			txType, sellAmount, sellCurrency = "Withdrawal", tx.amount(), tx.Coin.Unit(false)
+		}
+		tradeGroup := ""
+		if tx.Data.IsInternalTransfer() {
+			tradeGroup = "Internal transfer"
+		}
+		err := writer.Write([]string{
+			txType,
+			buyAmount,
+			buyCurrency,
+			sellAmount,
+			sellCurrency,
+			fee,
+			feeCurrency,
+			tx.AccountName,
+			tradeGroup,
+			tx.Note,
+			tx.Data.Timestamp.UTC().Format("2006-01-02 15:04:05"),
+			tx.Data.TxID,
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	writer.Flush()
+	return errp.WithStack(writer.Error())
+}
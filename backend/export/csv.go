@@ -0,0 +1,115 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// csvExporter writes one row per transaction, with amounts in the standard unit of the coin and
+// their fiat values at the time of the transaction.
+type csvExporter struct{}
+
+// Extension implements Exporter.
+func (csvExporter) Extension() string {
+	return "csv"
+}
+
+// Export implements Exporter.
+func (csvExporter) Export(w io.Writer, transactions []*Transaction, options *Options) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"Time",
+		"Account",
+		"Account name",
+		"Type",
+		"Status",
+		"Amount",
+		"Unit",
+		"Fiat value",
+		"Fee",
+		"Fee unit",
+		"Fee fiat value",
+		"Fiat currency",
+		"Height",
+		"Confirmations",
+		"Internal transfer",
+		"Internal transfer accounts",
+		"Addresses",
+		"Transaction ID",
+		"Note",
+	})
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, tx := range transactions {
+		timeString := ""
+		if txTime := tx.txTime(); txTime != nil {
+			timeString = txTime.UTC().Format(time.RFC3339)
+		}
+		fee, feeUnit, fiatFee := "", "", ""
+		if tx.paysFee() {
+			fee, feeUnit, fiatFee = tx.fee(), tx.Coin.Unit(true), tx.fiatFee(options)
+		}
+		internalTransfer := "no"
+		transferAccounts := []string{}
+		if tx.Data.IsInternalTransfer() {
+			internalTransfer = "yes"
+			for _, transfer := range tx.Data.InternalTransfers {
+				transferAccounts = append(transferAccounts, string(transfer.AccountCode))
+			}
+		}
+		addresses := []string{}
+		for _, address := range tx.Data.Addresses {
+			addresses = append(addresses, address.Address)
+		}
+		height := ""
+		if tx.Data.Height > 0 {
+			height = strconv.Itoa(tx.Data.Height)
+		}
+		err := writer.Write([]string{
+			timeString,
+			string(tx.AccountCode),
+			tx.AccountName,
+			typeName(tx.Data.Type),
+			string(tx.Data.Status),
+			tx.amount(),
+			tx.Coin.Unit(false),
+			tx.fiatAmount(options),
+			fee,
+			feeUnit,
+			fiatFee,
+			options.Fiat,
+			height,
+			strconv.Itoa(tx.Data.NumConfirmations),
+			internalTransfer,
+			strings.Join(transferAccounts, " "),
+			strings.Join(addresses, " "),
+			tx.Data.TxID,
+			tx.Note,
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	writer.Flush()
+	return errp.WithStack(writer.Error())
+}
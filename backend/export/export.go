@@ -0,0 +1,249 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package export writes the transaction history of one or more accounts in various file formats,
+// e.g. for importing it into tax software.
+package export
+
+import (
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// Format identifies an export format.
+type Format string
+
+const (
+	// FormatCSV is the native CSV format with fiat values, heights and internal transfers.
+	FormatCSV Format = "csv"
+	// FormatJSON contains all transaction data.
+	FormatJSON Format = "json"
+	// FormatKoinly is the universal CSV format of Koinly.
+	FormatKoinly Format = "koinly"
+	// FormatCoinTracking is the CSV import format of CoinTracking.
+	FormatCoinTracking Format = "cointracking"
+)
+
+// Transaction is a transaction to export, together with the account it belongs to.
+type Transaction struct {
+	AccountCode accountsTypes.Code
+	AccountName string
+	Coin        coin.Coin
+	Data        *accounts.TransactionData
+	Note        string
+	// FiatValues are the fiat values of the amount recorded when the transaction was sent or first
+	// seen, keyed by fiat currency. They are preferred over values computed using Options.Price.
+	FiatValues map[string]string
+}
+
+// PriceFunc returns the price of one unit (e.g. one BTC) of the coin with the given code in the fiat
+// currency at the given time, or 0 if it is not available.
+type PriceFunc func(coinCode string, fiat string, at time.Time) float64
+
+// Options configures an export.
+type Options struct {
+	// Fiat is the currency of the fiat values. If empty, fiat values are left out.
+	Fiat  string
+	Price PriceFunc
+}
+
+// Exporter writes transactions in a file format.
+type Exporter interface {
+	// Extension returns the file name extension, e.g. "csv".
+	Extension() string
+	// Export writes the transactions, which are sorted from oldest to newest.
+	Export(w io.Writer, transactions []*Transaction, options *Options) error
+}
+
+var exporters = map[Format]Exporter{}
+
+// Register makes an exporter available under the given format. It panics if the format is already
+// registered.
+func Register(format Format, exporter Exporter) {
+	if _, ok := exporters[format]; ok {
+		panic("export format registered twice: " + string(format))
+	}
+	exporters[format] = exporter
+}
+
+func init() {
+	Register(FormatCSV, csvExporter{})
+	Register(FormatJSON, jsonExporter{})
+	Register(FormatKoinly, koinlyExporter{})
+	Register(FormatCoinTracking, coinTrackingExporter{})
+}
+
+// Lookup returns the exporter of the format.
+func Lookup(format Format) (Exporter, error) {
+	exporter, ok := exporters[format]
+	if !ok {
+		return nil, errp.Newf("Unknown export format: %s", format)
+	}
+	return exporter, nil
+}
+
+// Formats returns the registered formats, sorted by name.
+func Formats() []Format {
+	formats := make([]Format, 0, len(exporters))
+	for format := range exporters {
+		formats = append(formats, format)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+	return formats
+}
+
+// FromAccount returns the transactions of the account passing the filter, e.g. to restrict the
+// export to a date range. The filter can be nil.
+func FromAccount(
+	account accounts.Interface,
+	txs accounts.OrderedTransactions,
+	filter *accounts.TransactionsFilter,
+) []*Transaction {
+	result := []*Transaction{}
+	for _, tx := range txs {
+		note := account.TxNote(tx.InternalID)
+		if filter != nil && !filter.Match(tx, note) {
+			continue
+		}
+		result = append(result, &Transaction{
+			AccountCode: account.Config().Config.Code,
+			AccountName: account.Config().Config.Name,
+			Coin:        account.Coin(),
+			Data:        tx,
+			Note:        note,
+			FiatValues:  account.TxFiatValues(tx.InternalID),
+		})
+	}
+	return result
+}
+
+// Export sorts the transactions from oldest to newest, with unconfirmed transactions last, and
+// writes them in the given format.
+func Export(w io.Writer, format Format, transactions []*Transaction, options *Options) error {
+	exporter, err := Lookup(format)
+	if err != nil {
+		return err
+	}
+	sorted := append([]*Transaction(nil), transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		timeI, timeJ := sorted[i].Data.Time(), sorted[j].Data.Time()
+		switch {
+		case timeI == nil:
+			return false
+		case timeJ == nil:
+			return true
+		default:
+			return timeI.Before(*timeJ)
+		}
+	})
+	return exporter.Export(w, sorted, options)
+}
+
+// formatDecimal formats an amount given in the smallest unit as a plain decimal number in the
+// standard unit, without trailing zeros, e.g. "0.0015" for 150000 satoshi.
+func formatDecimal(amount *big.Int, decimals uint) string {
+	formatted := new(big.Rat).SetFrac(
+		amount,
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil),
+	).FloatString(int(decimals))
+	if strings.Contains(formatted, ".") {
+		formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	}
+	return formatted
+}
+
+// amount formats the main amount of the transaction.
+func (tx *Transaction) amount() string {
+	return formatDecimal(tx.Data.Amount.BigInt(), tx.Coin.Decimals(false))
+}
+
+// fee formats the fee of the transaction. Returns an empty string if there is no fee.
+func (tx *Transaction) fee() string {
+	if tx.Data.Fee == nil {
+		return ""
+	}
+	return formatDecimal(tx.Data.Fee.BigInt(), tx.Coin.Decimals(true))
+}
+
+// feeCoinCode returns the code of the coin the fee is paid in. The fee unit of ERC20 tokens is the
+// unit of the Ethereum network, whose coin code is the lowercase unit, e.g. "eth" for "ETH".
+func (tx *Transaction) feeCoinCode() string {
+	if tx.Data.FeeIsDifferentUnit {
+		return strings.ToLower(tx.Coin.Unit(true))
+	}
+	return string(tx.Coin.Code())
+}
+
+// fiatValue returns the fiat value of the amount at the time of the transaction, or an empty
+// string if it is not known.
+func fiatValue(
+	options *Options, coinCode string, amount *big.Int, decimals uint, at *time.Time) string {
+	if options.Fiat == "" || options.Price == nil || at == nil {
+		return ""
+	}
+	price := options.Price(coinCode, options.Fiat, *at)
+	if price == 0 {
+		return ""
+	}
+	value := new(big.Rat).Mul(
+		new(big.Rat).SetFrac(amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)),
+		new(big.Rat).SetFloat64(price),
+	)
+	return coin.FormatAsPlainCurrency(value, options.Fiat == "BTC", false)
+}
+
+// fiatAmount returns the fiat value of the main amount of the transaction, preferring the recorded
+// fiat value.
+func (tx *Transaction) fiatAmount(options *Options) string {
+	if value, ok := tx.FiatValues[options.Fiat]; ok && options.Fiat != "" {
+		return value
+	}
+	return fiatValue(options, string(tx.Coin.Code()), tx.Data.Amount.BigInt(), tx.Coin.Decimals(false), tx.Data.Timestamp)
+}
+
+// fiatFee returns the fiat value of the fee of the transaction.
+func (tx *Transaction) fiatFee(options *Options) string {
+	if tx.Data.Fee == nil {
+		return ""
+	}
+	return fiatValue(options, tx.feeCoinCode(), tx.Data.Fee.BigInt(), tx.Coin.Decimals(true), tx.Data.Timestamp)
+}
+
+// paysFee returns true if the fee is paid by the transaction from the account, i.e. it is not a
+// receive.
+func (tx *Transaction) paysFee() bool {
+	return tx.Data.Fee != nil && tx.Data.Type != accounts.TxTypeReceive
+}
+
+// txTime returns the time of the transaction, or nil if it is not known.
+func (tx *Transaction) txTime() *time.Time {
+	return tx.Data.Time()
+}
+
+// typeName returns the name of the transaction type used by the exports.
+func typeName(txType accounts.TxType) string {
+	return map[accounts.TxType]string{
+		accounts.TxTypeReceive:  "received",
+		accounts.TxTypeSend:     "sent",
+		accounts.TxTypeSendSelf: "sent_to_yourself",
+	}[txType]
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	coinMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/stretchr/testify/require"
+)
+
+func testTransactions() []*Transaction {
+	btc := &coinMocks.CoinMock{
+		CodeFunc:         func() coin.Code { return coin.CodeBTC },
+		UnitFunc:         func(bool) string { return "BTC" },
+		DecimalsFunc:     func(bool) uint { return 8 },
+		SmallestUnitFunc: func() string { return "satoshi" },
+	}
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC)
+		return &t
+	}
+	fee := coin.NewAmountFromInt64(1000)
+	return []*Transaction{
+		{
+			AccountCode: "btc-2", AccountName: "Savings", Coin: btc, Note: "from spending",
+			Data: &accounts.TransactionData{
+				TxID: "tx2", InternalID: "tx2", Type: accounts.TxTypeReceive,
+				Status: accounts.TxStatusComplete, Timestamp: day(2), Height: 102, NumConfirmations: 6,
+				Amount:            coin.NewAmountFromInt64(50000000),
+				Addresses:         []accounts.AddressAndAmount{{Address: "addr-2", Amount: coin.NewAmountFromInt64(50000000), Ours: true}},
+				InternalTransfers: []accounts.InternalTransfer{{AccountCode: "btc-1", InternalID: "tx2", Amount: coin.NewAmountFromInt64(50000000)}},
+			},
+		},
+		{
+			AccountCode: "btc-1", AccountName: "Spending", Coin: btc,
+			Data: &accounts.TransactionData{
+				TxID: "pending", InternalID: "pending", Type: accounts.TxTypeSend,
+				Status: accounts.TxStatusPending, CreatedTimestamp: day(3), Fee: &fee,
+				Amount: coin.NewAmountFromInt64(1),
+			},
+		},
+		{
+			AccountCode: "btc-1", AccountName: "Spending", Coin: btc,
+			Data: &accounts.TransactionData{
+				TxID: "tx1", InternalID: "tx1", Type: accounts.TxTypeSend,
+				Status: accounts.TxStatusComplete, Timestamp: day(1), Height: 101, NumConfirmations: 7,
+				Amount: coin.NewAmountFromInt64(150000), Fee: &fee,
+				Addresses: []accounts.AddressAndAmount{
+					{Address: "addr-1", Amount: coin.NewAmountFromInt64(100000)},
+					{Address: "addr-3", Amount: coin.NewAmountFromInt64(50000)},
+				},
+			},
+		},
+	}
+}
+
+func testOptions() *Options {
+	return &Options{
+		Fiat: "USD",
+		Price: func(coinCode string, fiat string, at time.Time) float64 {
+			if coinCode != "btc" || fiat != "USD" {
+				return 0
+			}
+			return 40000
+		},
+	}
+}
+
+func export(t *testing.T, format Format, options *Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, Export(&buf, format, testTransactions(), options))
+	return buf.String()
+}
+
+func TestRegistry(t *testing.T) {
+	require.Equal(t,
+		[]Format{FormatCoinTracking, FormatCSV, FormatJSON, FormatKoinly},
+		Formats())
+	exporter, err := Lookup(FormatJSON)
+	require.NoError(t, err)
+	require.Equal(t, "json", exporter.Extension())
+	_, err = Lookup("unknown")
+	require.Error(t, err)
+	require.Panics(t, func() { Register(FormatCSV, csvExporter{}) })
+}
+
+func TestFromAccount(t *testing.T) {
+	cfg := &accounts.AccountConfig{Config: &config.Account{Code: "btc-1", Name: "Spending"}}
+	account := &accountsMocks.InterfaceMock{
+		ConfigFunc: func() *accounts.AccountConfig { return cfg },
+		CoinFunc:   func() coin.Coin { return &coinMocks.CoinMock{} },
+		TxNoteFunc: func(internalID string) string { return "note " + internalID },
+		TxFiatValuesFunc: func(internalID string) map[string]string {
+			if internalID == "tx2" {
+				return map[string]string{"USD": "100.00"}
+			}
+			return nil
+		},
+	}
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	txs := accounts.OrderedTransactions{}
+	for _, tx := range testTransactions() {
+		txs = append(txs, tx.Data)
+	}
+	result := FromAccount(account, txs, &accounts.TransactionsFilter{Start: &start})
+	require.Len(t, result, 2)
+	require.Equal(t, "tx2", result[0].Data.TxID)
+	require.Equal(t, "note tx2", result[0].Note)
+	require.Equal(t, map[string]string{"USD": "100.00"}, result[0].FiatValues)
+	require.Nil(t, result[1].FiatValues)
+	require.Equal(t, "Spending", result[1].AccountName)
+	require.Len(t, FromAccount(account, txs, nil), 3)
+}
+
+func TestCSV(t *testing.T) {
+	require.Equal(t,
+		"Time,Account,Account name,Type,Status,Amount,Unit,Fiat value,Fee,Fee unit,Fee fiat value,"+
+			"Fiat currency,Height,Confirmations,Internal transfer,Internal transfer accounts,Addresses,"+
+			"Transaction ID,Note\n"+
+			"2024-01-01T12:00:00Z,btc-1,Spending,sent,complete,0.0015,BTC,60.00,0.00001,BTC,0.40,USD,101,7,"+
+			"no,,addr-1 addr-3,tx1,\n"+
+			"2024-01-02T12:00:00Z,btc-2,Savings,received,complete,0.5,BTC,20000.00,,,,USD,102,6,yes,btc-1,"+
+			"addr-2,tx2,from spending\n"+
+			"2024-01-03T12:00:00Z,btc-1,Spending,sent,pending,0.00000001,BTC,,0.00001,BTC,,USD,,0,no,,,"+
+			"pending,\n",
+		export(t, FormatCSV, testOptions()))
+}
+
+func TestRecordedFiatValues(t *testing.T) {
+	transactions := testTransactions()
+	transactions[1].FiatValues = map[string]string{"USD": "0.01"}
+	transactions[2].FiatValues = map[string]string{"USD": "61.23", "EUR": "55.00"}
+	var buf bytes.Buffer
+	require.NoError(t, Export(&buf, FormatCSV, transactions, testOptions()))
+	// The recorded values are preferred, also for pending transactions. The fee is still valued
+	// using the historical price.
+	require.Contains(t, buf.String(), ",sent,complete,0.0015,BTC,61.23,0.00001,BTC,0.40,USD,")
+	require.Contains(t, buf.String(), ",received,complete,0.5,BTC,20000.00,")
+	require.Contains(t, buf.String(), ",sent,pending,0.00000001,BTC,0.01,0.00001,BTC,,USD,")
+
+	buf.Reset()
+	require.NoError(t, Export(&buf, FormatCSV, transactions, &Options{}))
+	require.NotContains(t, buf.String(), "61.23")
+}
+
+func TestKoinly(t *testing.T) {
+	require.Equal(t,
+		"Date,Sent Amount,Sent Currency,Received Amount,Received Currency,Fee Amount,Fee Currency,"+
+			"Net Worth Amount,Net Worth Currency,Label,Description,TxHash\n"+
+			"2024-01-01 12:00:00 UTC,0.0015,BTC,,,0.00001,BTC,60.00,USD,,,tx1\n"+
+			"2024-01-02 12:00:00 UTC,,,0.5,BTC,,,20000.00,USD,,from spending,tx2\n",
+		export(t, FormatKoinly, testOptions()))
+	// Without fiat values.
+	require.Contains(t,
+		export(t, FormatKoinly, &Options{}),
+		"2024-01-01 12:00:00 UTC,0.0015,BTC,,,0.00001,BTC,,,,,tx1\n")
+}
+
+func TestCoinTracking(t *testing.T) {
+	require.Equal(t,
+		"Type,Buy Amount,Buy Currency,Sell Amount,Sell Currency,Fee,Fee Currency,Exchange,"+
+			"Trade-Group,Comment,Date,Tx-ID\n"+
+			"Withdrawal,,,0.0015,BTC,0.00001,BTC,Spending,,,2024-01-01 12:00:00,tx1\n"+
+			"Deposit,0.5,BTC,,,,,Savings,Internal transfer,from spending,2024-01-02 12:00:00,tx2\n",
+		export(t, FormatCoinTracking, testOptions()))
+}
+
+func TestJSON(t *testing.T) {
+	var result struct {
+		Fiat         string `json:"fiat"`
+		Transactions []struct {
+			TxID              string  `json:"txID"`
+			Amount            string  `json:"amount"`
+			FiatAmount        string  `json:"fiatAmount"`
+			Fee               *string `json:"fee"`
+			FiatFee           string  `json:"fiatFee"`
+			Time              *string `json:"time"`
+			Height            int     `json:"height"`
+			InternalTransfers []struct {
+				AccountCode string `json:"accountCode"`
+			} `json:"internalTransfers"`
+		} `json:"transactions"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(export(t, FormatJSON, testOptions())), &result))
+	require.Equal(t, "USD", result.Fiat)
+	require.Len(t, result.Transactions, 3)
+	tx := result.Transactions[0]
+	require.Equal(t, "tx1", tx.TxID)
+	require.Equal(t, "150000", tx.Amount)
+	require.Equal(t, "60.00", tx.FiatAmount)
+	require.Equal(t, "1000", *tx.Fee)
+	require.Equal(t, "0.40", tx.FiatFee)
+	require.Equal(t, "2024-01-01T12:00:00Z", *tx.Time)
+	require.Equal(t, 101, tx.Height)
+	require.Equal(t, "btc-1", result.Transactions[1].InternalTransfers[0].AccountCode)
+	require.Nil(t, result.Transactions[1].Fee)
+	require.Nil(t, result.Transactions[2].Time)
+}
+
+func TestFormatDecimal(t *testing.T) {
+	require.Equal(t, "0", formatDecimal(big.NewInt(0), 8))
+	require.Equal(t, "1", formatDecimal(big.NewInt(100000000), 8))
+	require.Equal(t, "0.00000001", formatDecimal(big.NewInt(1), 8))
+	require.Equal(t, "1234", formatDecimal(big.NewInt(1234), 0))
+	require.Equal(t, "1.5", formatDecimal(new(big.Int).Mul(big.NewInt(15), big.NewInt(1e17)), 18))
+}
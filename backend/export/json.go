@@ -0,0 +1,165 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// jsonExporter writes all data of the transactions. Amounts are in the smallest unit of the coin,
+// so that no precision is lost.
+type jsonExporter struct{}
+
+// Extension implements Exporter.
+func (jsonExporter) Extension() string {
+	return "json"
+}
+
+// Export implements Exporter.
+func (jsonExporter) Export(w io.Writer, transactions []*Transaction, options *Options) error {
+	type address struct {
+		Address     string `json:"address"`
+		Amount      string `json:"amount"`
+		Ours        bool   `json:"ours"`
+		ContactName string `json:"contactName,omitempty"`
+	}
+	type internalTransfer struct {
+		AccountCode accountsTypes.Code `json:"accountCode"`
+		InternalID  string             `json:"internalID"`
+		Amount      string             `json:"amount"`
+	}
+	type transaction struct {
+		AccountCode        accountsTypes.Code `json:"accountCode"`
+		AccountName        string             `json:"accountName"`
+		CoinCode           coin.Code          `json:"coinCode"`
+		Unit               string             `json:"unit"`
+		SmallestUnit       string             `json:"smallestUnit"`
+		Decimals           uint               `json:"decimals"`
+		TxID               string             `json:"txID"`
+		InternalID         string             `json:"internalID"`
+		Type               accounts.TxType    `json:"type"`
+		Status             accounts.TxStatus  `json:"status"`
+		Time               *string            `json:"time"`
+		CreatedTime        *string            `json:"createdTime"`
+		Height             int                `json:"height"`
+		NumConfirmations   int                `json:"numConfirmations"`
+		Amount             string             `json:"amount"`
+		FiatAmount         string             `json:"fiatAmount,omitempty"`
+		Fee                *string            `json:"fee"`
+		FeeUnit            string             `json:"feeUnit"`
+		FeeIsDifferentUnit bool               `json:"feeIsDifferentUnit"`
+		FiatFee            string             `json:"fiatFee,omitempty"`
+		Addresses          []address          `json:"addresses"`
+		InternalTransfers  []internalTransfer `json:"internalTransfers"`
+		Note               string             `json:"note"`
+		// BTC specific fields.
+		VSize        int64  `json:"vsize,omitempty"`
+		Size         int64  `json:"size,omitempty"`
+		Weight       int64  `json:"weight,omitempty"`
+		FeeRatePerKb *int64 `json:"feeRatePerKb,omitempty"`
+		// ETH specific fields.
+		Gas     uint64  `json:"gas,omitempty"`
+		Nonce   *uint64 `json:"nonce,omitempty"`
+		IsErc20 bool    `json:"isErc20,omitempty"`
+	}
+	formatTime := func(t *time.Time) *string {
+		if t == nil {
+			return nil
+		}
+		formatted := t.UTC().Format(time.RFC3339)
+		return &formatted
+	}
+
+	result := []transaction{}
+	for _, tx := range transactions {
+		data := tx.Data
+		addresses := []address{}
+		for _, addressAndAmount := range data.Addresses {
+			addresses = append(addresses, address{
+				Address:     addressAndAmount.Address,
+				Amount:      addressAndAmount.Amount.BigInt().String(),
+				Ours:        addressAndAmount.Ours,
+				ContactName: addressAndAmount.ContactName,
+			})
+		}
+		internalTransfers := []internalTransfer{}
+		for _, transfer := range data.InternalTransfers {
+			internalTransfers = append(internalTransfers, internalTransfer{
+				AccountCode: transfer.AccountCode,
+				InternalID:  transfer.InternalID,
+				Amount:      transfer.Amount.BigInt().String(),
+			})
+		}
+		var fee *string
+		if data.Fee != nil {
+			feeString := data.Fee.BigInt().String()
+			fee = &feeString
+		}
+		var feeRatePerKb *int64
+		if data.FeeRatePerKb != nil {
+			rate := int64(*data.FeeRatePerKb)
+			feeRatePerKb = &rate
+		}
+		result = append(result, transaction{
+			AccountCode:        tx.AccountCode,
+			AccountName:        tx.AccountName,
+			CoinCode:           tx.Coin.Code(),
+			Unit:               tx.Coin.Unit(false),
+			SmallestUnit:       tx.Coin.SmallestUnit(),
+			Decimals:           tx.Coin.Decimals(false),
+			TxID:               data.TxID,
+			InternalID:         data.InternalID,
+			Type:               data.Type,
+			Status:             data.Status,
+			Time:               formatTime(data.Timestamp),
+			CreatedTime:        formatTime(data.CreatedTimestamp),
+			Height:             data.Height,
+			NumConfirmations:   data.NumConfirmations,
+			Amount:             data.Amount.BigInt().String(),
+			FiatAmount:         tx.fiatAmount(options),
+			Fee:                fee,
+			FeeUnit:            tx.Coin.Unit(true),
+			FeeIsDifferentUnit: data.FeeIsDifferentUnit,
+			FiatFee:            tx.fiatFee(options),
+			Addresses:          addresses,
+			InternalTransfers:  internalTransfers,
+			Note:               tx.Note,
+			VSize:              data.VSize,
+			Size:               data.Size,
+			Weight:             data.Weight,
+			FeeRatePerKb:       feeRatePerKb,
+			Gas:                data.Gas,
+			Nonce:              data.Nonce,
+			IsErc20:            data.IsErc20,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return errp.WithStack(encoder.Encode(struct {
+		Fiat         string        `json:"fiat,omitempty"`
+		Transactions []transaction `json:"transactions"`
+	}{
+		Fiat:         options.Fiat,
+		Transactions: result,
+	}))
+}
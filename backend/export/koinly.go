@@ -0,0 +1,104 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// koinlyExporter writes the Koinly universal CSV format, see
+// https://support.koinly.io/en/articles/9489976-how-to-create-a-custom-csv-file-with-your-data.
+//
+// Unconfirmed transactions are left out. Koinly matches internal transfers by the transaction hash
+// when both accounts are imported.
+type koinlyExporter struct{}
+
+// Extension implements Exporter.
+func (koinlyExporter) Extension() string {
+	return "csv"
+}
+
+// Export implements Exporter.
+func (koinlyExporter) Export(w io.Writer, transactions []*Transaction, options *Options) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"Date",
+		"Sent Amount",
+		"Sent Currency",
+		"Received Amount",
+		"Received Currency",
+		"Fee Amount",
+		"Fee Currency",
+		"Net Worth Amount",
+		"Net Worth Currency",
+		"Label",
+		"Description",
+		"TxHash",
+	})
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	for _, tx := range transactions {
+		if tx.Data.Timestamp == nil || tx.Data.Status == accounts.TxStatusPending {
+			continue
+		}
+		var sentAmount, sentCurrency, receivedAmount, receivedCurrency, label string
+		netWorth := tx.fiatAmount(options)
+		switch {
+		case tx.Data.Status == accounts.TxStatusFailed || tx.Data.Type == accounts.TxTypeSendSelf:
+			// Only the fee is spent.
+			label = "cost"
+			netWorth = ""
+		case tx.Data.Type == accounts.TxTypeReceive:
+			receivedAmount, receivedCurrency = tx.amount(), tx.Coin.Unit(false)
+		case tx.Data.Type == accounts.TxTypeSend:
+			sentAmount, sentCurrency = tx.amount(), tx.Coin.Unit(false)
+		}
+		fee, feeCurrency := "", ""
+		if tx.paysFee() {
+			fee, feeCurrency = tx.fee(), tx.Coin.Unit(true)
+		}
+		if sentAmount == "" && receivedAmount == "" && fee == "" {
+			continue
+		}
+		netWorthCurrency := ""
+		if netWorth != "" {
+			netWorthCurrency = options.Fiat
+		}
+		err := writer.Write([]string{
+			tx.Data.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC"),
+			sentAmount,
+			sentCurrency,
+			receivedAmount,
+			receivedCurrency,
+			fee,
+			feeCurrency,
+			netWorth,
+			netWorthCurrency,
+			label,
+			tx.Note,
+			tx.Data.TxID,
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	writer.Flush()
+	return errp.WithStack(writer.Error())
+}
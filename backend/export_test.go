@@ -0,0 +1,89 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	coinMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/export"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportTransactions(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	makeAccount := func(code accountsTypes.Code, inactive bool, txs accounts.OrderedTransactions) accounts.Interface {
+		cfg := &accounts.AccountConfig{Config: &config.Account{Code: code, Inactive: inactive}}
+		return &accountsMocks.InterfaceMock{
+			ConfigFunc:       func() *accounts.AccountConfig { return cfg },
+			CoinFunc:         func() coinpkg.Coin { return &coinMocks.CoinMock{} },
+			FatalErrorFunc:   func() bool { return false },
+			InitializeFunc:   func() error { return nil },
+			TransactionsFunc: func() (accounts.OrderedTransactions, error) { return txs, nil },
+			TxNoteFunc:       func(string) string { return "" },
+			TxFiatValuesFunc: func(string) map[string]string { return nil },
+			CloseFunc:        func() {},
+		}
+	}
+	b.accounts = AccountsList{
+		makeAccount("btc", false, accounts.OrderedTransactions{
+			{InternalID: "btc-2", Timestamp: day(3)},
+			{InternalID: "btc-1", Timestamp: day(1)},
+		}),
+		makeAccount("ltc", false, accounts.OrderedTransactions{
+			{InternalID: "ltc-1", Timestamp: day(2)},
+		}),
+		makeAccount("inactive", true, accounts.OrderedTransactions{
+			{InternalID: "inactive-1", Timestamp: day(2)},
+		}),
+	}
+	ids := func(txs []*export.Transaction) []string {
+		result := []string{}
+		for _, tx := range txs {
+			result = append(result, string(tx.AccountCode)+"/"+tx.Data.InternalID)
+		}
+		return result
+	}
+
+	txs, err := b.exportTransactions(nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"btc/btc-2", "btc/btc-1", "ltc/ltc-1"}, ids(txs))
+
+	txs, err = b.exportTransactions(nil, day(2), day(3))
+	require.NoError(t, err)
+	require.Equal(t, []string{"ltc/ltc-1"}, ids(txs))
+
+	txs, err = b.exportTransactions([]accountsTypes.Code{"btc"}, day(2), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"btc/btc-2"}, ids(txs))
+
+	_, err = b.exportTransactions([]accountsTypes.Code{"unknown"}, nil, nil)
+	require.Error(t, err)
+
+	_, err = b.ExportTransactions(nil, "unknown", "USD", nil, nil)
+	require.Error(t, err)
+}
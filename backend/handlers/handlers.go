@@ -25,6 +25,8 @@ import (
 	"math/big"
 	"net/http"
 	"runtime/debug"
+	"strconv"
+	"time"
 
 	"github.com/digitalbitbox/bitbox-wallet-app/backend"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
@@ -46,8 +48,10 @@ import (
 	bitbox02bootloaderHandlers "github.com/digitalbitbox/bitbox-wallet-app/backend/devices/bitbox02bootloader/handlers"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/devices/device"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/exchanges"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/export"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/rates"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/tax"
 	utilConfig "github.com/digitalbitbox/bitbox-wallet-app/util/config"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/jsonp"
@@ -94,12 +98,42 @@ type Backend interface {
 	Banners() *banners.Banners
 	Environment() backend.Environment
 	ChartData() (*backend.Chart, error)
+	BalanceHistory(accountCode accountsTypes.Code) (*backend.BalanceHistory, error)
 	SupportedCoins(keystore.Keystore) []coinpkg.Code
 	CanAddAccount(coinpkg.Code, keystore.Keystore) (string, bool)
 	CreateAndPersistAccountConfig(coinCode coinpkg.Code, name string, keystore keystore.Keystore) (accountsTypes.Code, error)
 	SetAccountActive(accountCode accountsTypes.Code, active bool) error
 	SetTokenActive(accountCode accountsTypes.Code, tokenCode string, active bool) error
+	AddERC20Token(accountCode accountsTypes.Code, contractAddress string) (*backend.AddedERC20Token, error)
+	AddERC20Tokens(accountCode accountsTypes.Code, contractAddresses []string) ([]*backend.AddedERC20Token, error)
+	DiscoverERC20Tokens(accountCode accountsTypes.Code) ([]*backend.DiscoveredERC20Token, error)
+	ERC20Tokens() []config.ERC20Token
 	RenameAccount(accountCode accountsTypes.Code, name string) error
+	SearchTransactions(
+		filter *accounts.TransactionsFilter,
+		sortBy accounts.TransactionsSort,
+		cursor string,
+		limit int,
+	) ([]backend.AccountTransaction, string, error)
+	InternalTransfers() ([]*backend.InternalTransfer, error)
+	ExportTransactions(
+		accountCodes []accountsTypes.Code,
+		format export.Format,
+		fiat string,
+		start, end *time.Time,
+	) (string, error)
+	TaxReport(accountCode accountsTypes.Code, year int, fiat string, method tax.Method) (*tax.Report, error)
+	ExportTaxReport(
+		accountCode accountsTypes.Code,
+		year int,
+		fiat string,
+		method tax.Method,
+		format string,
+	) (string, error)
+	Contacts() []config.Contact
+	AddContact(contact config.Contact) (string, error)
+	UpdateContact(contact config.Contact) error
+	DeleteContact(id string) error
 	AOPP() backend.AOPP
 	AOPPCancel()
 	AOPPApprove()
@@ -216,17 +250,35 @@ func NewHandlers(
 	getAPIRouter(apiRouter)("/accounts/total-balance", handlers.getAccountsTotalBalanceHandler).Methods("GET")
 	getAPIRouterNoError(apiRouter)("/set-account-active", handlers.postSetAccountActiveHandler).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/set-token-active", handlers.postSetTokenActiveHandler).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/erc20-tokens", handlers.getERC20TokensHandler).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/erc20-tokens/add", handlers.postAddERC20TokenHandler).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/erc20-tokens/add-multiple", handlers.postAddERC20TokensHandler).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/erc20-tokens/discover", handlers.getDiscoverERC20TokensHandler).Methods("GET")
 	getAPIRouterNoError(apiRouter)("/rename-account", handlers.postRenameAccountHandler).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/accounts/reinitialize", handlers.postAccountsReinitializeHandler).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/contacts", handlers.getContactsHandler).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/contacts/add", handlers.postAddContactHandler).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/contacts/update", handlers.postUpdateContactHandler).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/contacts/delete", handlers.postDeleteContactHandler).Methods("POST")
 	getAPIRouter(apiRouter)("/account-summary", handlers.getAccountSummary).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/balance-history", handlers.getBalanceHistoryHandler).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/transactions/search", handlers.getSearchTransactionsHandler).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/internal-transfers", handlers.getInternalTransfersHandler).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/export-transactions/formats", handlers.getExportFormatsHandler).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/export-transactions", handlers.postExportTransactionsHandler).Methods("POST")
+	getAPIRouterNoError(apiRouter)("/tax-report", handlers.getTaxReportHandler).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/tax-report/export", handlers.postExportTaxReportHandler).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/supported-coins", handlers.getSupportedCoinsHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/test/register", handlers.postRegisterTestKeystoreHandler).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/test/deregister", handlers.postDeregisterTestKeystoreHandler).Methods("POST")
 	getAPIRouterNoError(apiRouter)("/rates", handlers.getRatesHandler).Methods("GET")
+	getAPIRouterNoError(apiRouter)("/rates/providers", handlers.getRatesProvidersHandler).Methods("GET")
 	getAPIRouterNoError(apiRouter)("/coins/convert-to-plain-fiat", handlers.getConvertToPlainFiatHandler).Methods("GET")
 	getAPIRouterNoError(apiRouter)("/coins/convert-from-fiat", handlers.getConvertFromFiatHandler).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/tltc/headers/status", handlers.getHeadersStatus(coinpkg.CodeTLTC)).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/tbtc/headers/status", handlers.getHeadersStatus(coinpkg.CodeTBTC)).Methods("GET")
+	getAPIRouter(apiRouter)("/coins/tbtc4/headers/status", handlers.getHeadersStatus(coinpkg.CodeTBTC4)).Methods("GET")
+	getAPIRouter(apiRouter)("/coins/sbtc/headers/status", handlers.getHeadersStatus(coinpkg.CodeSBTC)).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/ltc/headers/status", handlers.getHeadersStatus(coinpkg.CodeLTC)).Methods("GET")
 	getAPIRouter(apiRouter)("/coins/btc/headers/status", handlers.getHeadersStatus(coinpkg.CodeBTC)).Methods("GET")
 	getAPIRouterNoError(apiRouter)("/coins/btc/set-unit", handlers.postBtcFormatUnit).Methods("POST")
@@ -669,17 +721,15 @@ func (handlers *Handlers) postBtcFormatUnit(r *http.Request) interface{} {
 	unit := request.Unit
 
 	// update BTC format unit for Coins
-	btcCoin, err := handlers.backend.Coin(coinpkg.CodeBTC)
-	if err != nil {
-		return response{Success: false}
-	}
-	btcCoin.(*btc.Coin).SetFormatUnit(unit)
-
-	btcCoin, err = handlers.backend.Coin(coinpkg.CodeTBTC)
-	if err != nil {
-		return response{Success: false}
+	for _, code := range []coinpkg.Code{
+		coinpkg.CodeBTC, coinpkg.CodeTBTC, coinpkg.CodeTBTC4, coinpkg.CodeSBTC,
+	} {
+		btcCoin, err := handlers.backend.Coin(code)
+		if err != nil {
+			return response{Success: false}
+		}
+		btcCoin.(*btc.Coin).SetFormatUnit(unit)
 	}
-	btcCoin.(*btc.Coin).SetFormatUnit(unit)
 
 	// update BTC format unit for fiat conversions
 	for _, account := range handlers.backend.Accounts() {
@@ -809,6 +859,80 @@ func (handlers *Handlers) postSetTokenActiveHandler(r *http.Request) interface{}
 	return response{Success: true}
 }
 
+func (handlers *Handlers) getERC20TokensHandler(*http.Request) interface{} {
+	return handlers.backend.ERC20Tokens()
+}
+
+func (handlers *Handlers) postAddERC20TokenHandler(r *http.Request) interface{} {
+	var jsonBody struct {
+		AccountCode     accountsTypes.Code `json:"accountCode"`
+		ContractAddress string             `json:"contractAddress"`
+	}
+
+	type response struct {
+		Success      bool                        `json:"success"`
+		TokenCode    string                      `json:"tokenCode,omitempty"`
+		Warnings     []backend.ERC20TokenWarning `json:"warnings,omitempty"`
+		ErrorMessage string                      `json:"errorMessage,omitempty"`
+		ErrorCode    string                      `json:"errorCode,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	added, err := handlers.backend.AddERC20Token(jsonBody.AccountCode, jsonBody.ContractAddress)
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not add ERC20 token")
+		if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+			return response{Success: false, ErrorCode: string(errCode)}
+		}
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, TokenCode: added.TokenCode, Warnings: added.Warnings}
+}
+
+func (handlers *Handlers) postAddERC20TokensHandler(r *http.Request) interface{} {
+	var jsonBody struct {
+		AccountCode       accountsTypes.Code `json:"accountCode"`
+		ContractAddresses []string           `json:"contractAddresses"`
+	}
+
+	type response struct {
+		Success      bool                       `json:"success"`
+		Added        []*backend.AddedERC20Token `json:"added,omitempty"`
+		ErrorMessage string                     `json:"errorMessage,omitempty"`
+		ErrorCode    string                     `json:"errorCode,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	added, err := handlers.backend.AddERC20Tokens(jsonBody.AccountCode, jsonBody.ContractAddresses)
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not add ERC20 tokens")
+		if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+			return response{Success: false, ErrorCode: string(errCode)}
+		}
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, Added: added}
+}
+
+func (handlers *Handlers) getDiscoverERC20TokensHandler(r *http.Request) interface{} {
+	type response struct {
+		Success      bool                            `json:"success"`
+		Tokens       []*backend.DiscoveredERC20Token `json:"tokens,omitempty"`
+		ErrorMessage string                          `json:"errorMessage,omitempty"`
+	}
+	accountCode := accountsTypes.Code(r.URL.Query().Get("accountCode"))
+	tokens, err := handlers.backend.DiscoverERC20Tokens(accountCode)
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not discover ERC20 tokens")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, Tokens: tokens}
+}
+
 func (handlers *Handlers) postRenameAccountHandler(r *http.Request) interface{} {
 	var jsonBody struct {
 		AccountCode accountsTypes.Code `json:"accountCode"`
@@ -833,6 +957,291 @@ func (handlers *Handlers) postRenameAccountHandler(r *http.Request) interface{}
 	return response{Success: true}
 }
 
+// getSearchTransactionsHandler searches the transactions of all active accounts. The query
+// parameters are documented at `accountHandlers.ParseTransactionsQuery()`, except that amounts
+// can't be filtered and sorted by.
+func (handlers *Handlers) getSearchTransactionsHandler(r *http.Request) interface{} {
+	type amount struct {
+		Amount string `json:"amount"`
+		Unit   string `json:"unit"`
+	}
+	type transaction struct {
+		AccountCode  accountsTypes.Code `json:"accountCode"`
+		CoinCode     coinpkg.Code       `json:"coinCode"`
+		TxID         string             `json:"txID"`
+		InternalID   string             `json:"internalID"`
+		Type         string             `json:"type"`
+		Status       accounts.TxStatus  `json:"status"`
+		Amount       amount             `json:"amount"`
+		Time         *string            `json:"time"`
+		Addresses    []string           `json:"addresses"`
+		ContactNames map[string]string  `json:"contactNames"`
+		Note         string             `json:"note"`
+		// InternalTransfer is true if the transaction moves coins between our own accounts.
+		InternalTransfer bool `json:"internalTransfer"`
+	}
+	type response struct {
+		Success      bool          `json:"success"`
+		Transactions []transaction `json:"list,omitempty"`
+		NextCursor   string        `json:"nextCursor,omitempty"`
+		ErrorMessage string        `json:"errorMessage,omitempty"`
+	}
+
+	query, err := accountHandlers.ParseTransactionsQuery(r.URL.Query(), nil)
+	if err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	results, nextCursor, err := handlers.backend.SearchTransactions(
+		&query.Filter, query.Sort, query.Cursor, query.Limit)
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not search transactions")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	transactions := []transaction{}
+	for _, result := range results {
+		account, tx := result.Account, result.Transaction
+		var formattedTime *string
+		if txTime := tx.Time(); txTime != nil {
+			t := txTime.Format(time.RFC3339)
+			formattedTime = &t
+		}
+		addresses := []string{}
+		contactNames := map[string]string{}
+		for _, addressAndAmount := range tx.Addresses {
+			addresses = append(addresses, addressAndAmount.Address)
+			if addressAndAmount.ContactName != "" {
+				contactNames[addressAndAmount.Address] = addressAndAmount.ContactName
+			}
+		}
+		transactions = append(transactions, transaction{
+			AccountCode: account.Config().Config.Code,
+			CoinCode:    account.Coin().Code(),
+			TxID:        tx.TxID,
+			InternalID:  tx.InternalID,
+			Type:        accountHandlers.TxTypeName(tx.Type),
+			Status:      tx.Status,
+			Amount: amount{
+				Amount: account.Coin().FormatAmount(tx.Amount, false),
+				Unit:   account.Coin().GetFormatUnit(false),
+			},
+			Time:             formattedTime,
+			Addresses:        addresses,
+			ContactNames:     contactNames,
+			Note:             account.TxNote(tx.InternalID),
+			InternalTransfer: tx.IsInternalTransfer(),
+		})
+	}
+	return response{Success: true, Transactions: transactions, NextCursor: nextCursor}
+}
+
+// getInternalTransfersHandler returns the transactions moving coins between our own accounts.
+func (handlers *Handlers) getInternalTransfersHandler(_ *http.Request) interface{} {
+	type transfer struct {
+		CoinCode       coinpkg.Code       `json:"coinCode"`
+		TxID           string             `json:"txID"`
+		From           accountsTypes.Code `json:"from"`
+		FromInternalID string             `json:"fromInternalID"`
+		To             accountsTypes.Code `json:"to"`
+		ToInternalID   string             `json:"toInternalID"`
+		Amount         string             `json:"amount"`
+		Unit           string             `json:"unit"`
+	}
+	type response struct {
+		Success      bool       `json:"success"`
+		Transfers    []transfer `json:"transfers,omitempty"`
+		ErrorMessage string     `json:"errorMessage,omitempty"`
+	}
+	transfers, err := handlers.backend.InternalTransfers()
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not match internal transfers")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	result := []transfer{}
+	for _, internalTransfer := range transfers {
+		coin, err := handlers.backend.Coin(internalTransfer.CoinCode)
+		if err != nil {
+			return response{Success: false, ErrorMessage: err.Error()}
+		}
+		result = append(result, transfer{
+			CoinCode:       internalTransfer.CoinCode,
+			TxID:           internalTransfer.TxID,
+			From:           internalTransfer.From,
+			FromInternalID: internalTransfer.FromInternalID,
+			To:             internalTransfer.To,
+			ToInternalID:   internalTransfer.ToInternalID,
+			Amount:         coin.FormatAmount(internalTransfer.Amount, false),
+			Unit:           coin.GetFormatUnit(false),
+		})
+	}
+	return response{Success: true, Transfers: result}
+}
+
+func (handlers *Handlers) getExportFormatsHandler(_ *http.Request) interface{} {
+	return export.Formats()
+}
+
+// postExportTransactionsHandler exports the transactions of several accounts, or of all active
+// accounts if no account codes are given, in one file.
+func (handlers *Handlers) postExportTransactionsHandler(r *http.Request) interface{} {
+	var request struct {
+		AccountCodes []accountsTypes.Code `json:"accountCodes"`
+		Format       export.Format        `json:"format"`
+		Fiat         string               `json:"fiat"`
+		Start        *time.Time           `json:"start"`
+		End          *time.Time           `json:"end"`
+	}
+	type response struct {
+		Success      bool   `json:"success"`
+		Aborted      bool   `json:"aborted,omitempty"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	if request.Fiat == "" {
+		request.Fiat = handlers.backend.Config().AppConfig().Backend.MainFiat
+	}
+	path, err := handlers.backend.ExportTransactions(
+		request.AccountCodes, request.Format, request.Fiat, request.Start, request.End)
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not export transactions")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, Aborted: path == ""}
+}
+
+// getTaxReportHandler returns the capital gains report of a year. Query parameters:
+//   - year: the year of the report, default: the previous year.
+//   - fiat: the fiat currency, default: the main fiat currency.
+//   - method: fifo, lifo or hifo, default: fifo.
+//   - accountCode: restricts the report to one account, default: all accounts.
+func (handlers *Handlers) getTaxReportHandler(r *http.Request) interface{} {
+	type response struct {
+		Success      bool        `json:"success"`
+		Report       *tax.Report `json:"report,omitempty"`
+		ErrorMessage string      `json:"errorMessage,omitempty"`
+	}
+	query := r.URL.Query()
+	year := time.Now().Year() - 1
+	if yearString := query.Get("year"); yearString != "" {
+		var err error
+		year, err = strconv.Atoi(yearString)
+		if err != nil {
+			return response{Success: false, ErrorMessage: "Invalid year"}
+		}
+	}
+	fiat := query.Get("fiat")
+	if fiat == "" {
+		fiat = handlers.backend.Config().AppConfig().Backend.MainFiat
+	}
+	method := tax.Method(query.Get("method"))
+	if method == "" {
+		method = tax.MethodFIFO
+	}
+	report, err := handlers.backend.TaxReport(
+		accountsTypes.Code(query.Get("accountCode")), year, fiat, method)
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not compute tax report")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, Report: report}
+}
+
+func (handlers *Handlers) postExportTaxReportHandler(r *http.Request) interface{} {
+	var request struct {
+		AccountCode accountsTypes.Code `json:"accountCode"`
+		Year        int                `json:"year"`
+		Fiat        string             `json:"fiat"`
+		Method      tax.Method         `json:"method"`
+		Format      string             `json:"format"`
+	}
+	type response struct {
+		Success      bool   `json:"success"`
+		Aborted      bool   `json:"aborted,omitempty"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	if request.Fiat == "" {
+		request.Fiat = handlers.backend.Config().AppConfig().Backend.MainFiat
+	}
+	if request.Method == "" {
+		request.Method = tax.MethodFIFO
+	}
+	path, err := handlers.backend.ExportTaxReport(
+		request.AccountCode, request.Year, request.Fiat, request.Method, request.Format)
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not export tax report")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, Aborted: path == ""}
+}
+
+func (handlers *Handlers) getContactsHandler(_ *http.Request) interface{} {
+	return handlers.backend.Contacts()
+}
+
+func (handlers *Handlers) postAddContactHandler(r *http.Request) interface{} {
+	type response struct {
+		Success      bool   `json:"success"`
+		ID           string `json:"id,omitempty"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+		ErrorCode    string `json:"errorCode,omitempty"`
+	}
+
+	var contact config.Contact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	id, err := handlers.backend.AddContact(contact)
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not add contact")
+		if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+			return response{Success: false, ErrorCode: string(errCode)}
+		}
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, ID: id}
+}
+
+func (handlers *Handlers) postUpdateContactHandler(r *http.Request) interface{} {
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+		ErrorCode    string `json:"errorCode,omitempty"`
+	}
+
+	var contact config.Contact
+	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	if err := handlers.backend.UpdateContact(contact); err != nil {
+		handlers.log.WithError(err).Error("Could not update contact")
+		if errCode, ok := errp.Cause(err).(errp.ErrorCode); ok {
+			return response{Success: false, ErrorCode: string(errCode)}
+		}
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
+func (handlers *Handlers) postDeleteContactHandler(r *http.Request) interface{} {
+	type response struct {
+		Success      bool   `json:"success"`
+		ErrorMessage string `json:"errorMessage,omitempty"`
+	}
+
+	var id string
+	if err := json.NewDecoder(r.Body).Decode(&id); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	if err := handlers.backend.DeleteContact(id); err != nil {
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true}
+}
+
 func (handlers *Handlers) postAccountsReinitializeHandler(_ *http.Request) interface{} {
 	handlers.backend.ReinitializeAccounts()
 	return nil
@@ -868,6 +1277,10 @@ func (handlers *Handlers) getRatesHandler(_ *http.Request) interface{} {
 	return handlers.backend.RatesUpdater().LatestPrice()
 }
 
+func (handlers *Handlers) getRatesProvidersHandler(_ *http.Request) interface{} {
+	return handlers.backend.RatesUpdater().ProvidersHealth()
+}
+
 func (handlers *Handlers) getBTCParseExternalAmount(r *http.Request) interface{} {
 	type response struct {
 		Success bool   `json:"success"`
@@ -1149,6 +1562,22 @@ func (handlers *Handlers) getAccountSummary(_ *http.Request) (interface{}, error
 	return handlers.backend.ChartData()
 }
 
+// getBalanceHistoryHandler returns the balance history per account and per coin. The optional
+// `accountCode` query parameter restricts it to one account.
+func (handlers *Handlers) getBalanceHistoryHandler(r *http.Request) interface{} {
+	type response struct {
+		Success      bool                    `json:"success"`
+		History      *backend.BalanceHistory `json:"history,omitempty"`
+		ErrorMessage string                  `json:"errorMessage,omitempty"`
+	}
+	history, err := handlers.backend.BalanceHistory(accountsTypes.Code(r.URL.Query().Get("accountCode")))
+	if err != nil {
+		handlers.log.WithError(err).Error("Could not compute balance history")
+		return response{Success: false, ErrorMessage: err.Error()}
+	}
+	return response{Success: true, History: history}
+}
+
 // getSupportedCoinsHandler returns an array of coin codes for which you can add an account.
 // Exactly one keystore must be connected, otherwise an empty array is returned.
 func (handlers *Handlers) getSupportedCoinsHandler(_ *http.Request) interface{} {
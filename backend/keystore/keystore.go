@@ -18,7 +18,9 @@ package keystore
 import (
 	"errors"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -97,6 +99,18 @@ type Keystore interface {
 	// Electrum format.
 	SignBTCMessage(message []byte, keypath signing.AbsoluteKeypath, scriptType signing.ScriptType) ([]byte, error)
 
+	// CanSignMessageBIP322 returns true if the keystore can sign BIP-322 messages for a coin with
+	// SignBTCMessageBIP322().
+	CanSignMessageBIP322(coin.Code) bool
+
+	// SignBTCMessageBIP322 signs the message according to BIP-322 using the private key at the
+	// keypath. Only the p2wpkh and p2tr script types are supported. The result is the witness of the
+	// signed BIP-322 to_sign transaction, see the bip322 package for encoding it. Returns
+	// ErrSigningAborted if the user aborts.
+	SignBTCMessageBIP322(
+		message []byte, keypath signing.AbsoluteKeypath, scriptType signing.ScriptType,
+	) (wire.TxWitness, error)
+
 	// SignETHMessage signs the message using the private key at the keypath. The result contains a
 	// 65 byte signature. The first 64 bytes are the secp256k1 signature in / compact format (R and
 	// S values), and the last byte is the recoverable id (recid). 27 is added to the recID to denote
@@ -113,6 +127,27 @@ type Keystore interface {
 	// aborts.
 	SignTransaction(interface{}) error
 
+	// SupportsPayjoin returns true if the keystore can sign transactions of the given coin which also
+	// contain inputs of another party, as needed for payjoin (BIP-78). Only the inputs belonging to
+	// the account are signed.
+	SupportsPayjoin(coin.Coin) bool
+
+	// SupportsProofOfReserves returns true if the keystore can sign BIP-127 proofs of reserves for
+	// the given coin. Their commitment input spends an output which does not exist.
+	SupportsProofOfReserves(coin.Coin) bool
+
+	// SupportsSilentPayments returns true if the keystore can take part in sending to BIP-352
+	// silent payment addresses for the given coin, i.e. if it implements
+	// SilentPaymentSharedSecret().
+	SupportsSilentPayments(coin.Coin) bool
+
+	// SilentPaymentSharedSecret computes the BIP-352 ECDH shared secret input_hash·a·B_scan, where
+	// a is the sum of the private keys of the inputs at the given configurations (tweaked and
+	// negated for taproot inputs as required by BIP-352).
+	SilentPaymentSharedSecret(
+		inputs []*signing.Configuration, inputHash []byte, scanPubKey *btcec.PublicKey,
+	) (*btcec.PublicKey, error)
+
 	// SignETHWalletConnectTransaction signs a transaction proposed by Wallet Connect. Returns ErrSigningAborted if the user
 	// aborts.
 	SignETHWalletConnectTransaction(chainID uint64, tx *types.Transaction, keypath signing.AbsoluteKeypath) ([]byte, error)
@@ -4,7 +4,9 @@
 package mocks
 
 import (
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
@@ -25,6 +27,9 @@ var _ keystore.Keystore = &KeystoreMock{}
 //			CanSignMessageFunc: func(code coin.Code) bool {
 //				panic("mock out the CanSignMessage method")
 //			},
+//			CanSignMessageBIP322Func: func(code coin.Code) bool {
+//				panic("mock out the CanSignMessageBIP322 method")
+//			},
 //			CanVerifyAddressFunc: func(coinMoqParam coin.Coin) (bool, bool, error) {
 //				panic("mock out the CanVerifyAddress method")
 //			},
@@ -43,6 +48,9 @@ var _ keystore.Keystore = &KeystoreMock{}
 //			SignBTCMessageFunc: func(message []byte, keypath signing.AbsoluteKeypath, scriptType signing.ScriptType) ([]byte, error) {
 //				panic("mock out the SignBTCMessage method")
 //			},
+//			SignBTCMessageBIP322Func: func(message []byte, keypath signing.AbsoluteKeypath, scriptType signing.ScriptType) (wire.TxWitness, error) {
+//				panic("mock out the SignBTCMessageBIP322 method")
+//			},
 //			SignETHMessageFunc: func(message []byte, keypath signing.AbsoluteKeypath) ([]byte, error) {
 //				panic("mock out the SignETHMessage method")
 //			},
@@ -55,6 +63,9 @@ var _ keystore.Keystore = &KeystoreMock{}
 //			SignTransactionFunc: func(ifaceVal interface{}) error {
 //				panic("mock out the SignTransaction method")
 //			},
+//			SilentPaymentSharedSecretFunc: func(inputs []*signing.Configuration, inputHash []byte, scanPubKey *btcec.PublicKey) (*btcec.PublicKey, error) {
+//				panic("mock out the SilentPaymentSharedSecret method")
+//			},
 //			SupportsAccountFunc: func(coinInstance coin.Coin, meta interface{}) bool {
 //				panic("mock out the SupportsAccount method")
 //			},
@@ -67,6 +78,15 @@ var _ keystore.Keystore = &KeystoreMock{}
 //			SupportsMultipleAccountsFunc: func() bool {
 //				panic("mock out the SupportsMultipleAccounts method")
 //			},
+//			SupportsPayjoinFunc: func(coinMoqParam coin.Coin) bool {
+//				panic("mock out the SupportsPayjoin method")
+//			},
+//			SupportsProofOfReservesFunc: func(coinMoqParam coin.Coin) bool {
+//				panic("mock out the SupportsProofOfReserves method")
+//			},
+//			SupportsSilentPaymentsFunc: func(coinMoqParam coin.Coin) bool {
+//				panic("mock out the SupportsSilentPayments method")
+//			},
 //			SupportsUnifiedAccountsFunc: func() bool {
 //				panic("mock out the SupportsUnifiedAccounts method")
 //			},
@@ -89,6 +109,9 @@ type KeystoreMock struct {
 	// CanSignMessageFunc mocks the CanSignMessage method.
 	CanSignMessageFunc func(code coin.Code) bool
 
+	// CanSignMessageBIP322Func mocks the CanSignMessageBIP322 method.
+	CanSignMessageBIP322Func func(code coin.Code) bool
+
 	// CanVerifyAddressFunc mocks the CanVerifyAddress method.
 	CanVerifyAddressFunc func(coinMoqParam coin.Coin) (bool, bool, error)
 
@@ -107,6 +130,9 @@ type KeystoreMock struct {
 	// SignBTCMessageFunc mocks the SignBTCMessage method.
 	SignBTCMessageFunc func(message []byte, keypath signing.AbsoluteKeypath, scriptType signing.ScriptType) ([]byte, error)
 
+	// SignBTCMessageBIP322Func mocks the SignBTCMessageBIP322 method.
+	SignBTCMessageBIP322Func func(message []byte, keypath signing.AbsoluteKeypath, scriptType signing.ScriptType) (wire.TxWitness, error)
+
 	// SignETHMessageFunc mocks the SignETHMessage method.
 	SignETHMessageFunc func(message []byte, keypath signing.AbsoluteKeypath) ([]byte, error)
 
@@ -119,6 +145,9 @@ type KeystoreMock struct {
 	// SignTransactionFunc mocks the SignTransaction method.
 	SignTransactionFunc func(ifaceVal interface{}) error
 
+	// SilentPaymentSharedSecretFunc mocks the SilentPaymentSharedSecret method.
+	SilentPaymentSharedSecretFunc func(inputs []*signing.Configuration, inputHash []byte, scanPubKey *btcec.PublicKey) (*btcec.PublicKey, error)
+
 	// SupportsAccountFunc mocks the SupportsAccount method.
 	SupportsAccountFunc func(coinInstance coin.Coin, meta interface{}) bool
 
@@ -131,6 +160,15 @@ type KeystoreMock struct {
 	// SupportsMultipleAccountsFunc mocks the SupportsMultipleAccounts method.
 	SupportsMultipleAccountsFunc func() bool
 
+	// SupportsPayjoinFunc mocks the SupportsPayjoin method.
+	SupportsPayjoinFunc func(coinMoqParam coin.Coin) bool
+
+	// SupportsProofOfReservesFunc mocks the SupportsProofOfReserves method.
+	SupportsProofOfReservesFunc func(coinMoqParam coin.Coin) bool
+
+	// SupportsSilentPaymentsFunc mocks the SupportsSilentPayments method.
+	SupportsSilentPaymentsFunc func(coinMoqParam coin.Coin) bool
+
 	// SupportsUnifiedAccountsFunc mocks the SupportsUnifiedAccounts method.
 	SupportsUnifiedAccountsFunc func() bool
 
@@ -150,6 +188,11 @@ type KeystoreMock struct {
 			// Code is the code argument value.
 			Code coin.Code
 		}
+		// CanSignMessageBIP322 holds details about calls to the CanSignMessageBIP322 method.
+		CanSignMessageBIP322 []struct {
+			// Code is the code argument value.
+			Code coin.Code
+		}
 		// CanVerifyAddress holds details about calls to the CanVerifyAddress method.
 		CanVerifyAddress []struct {
 			// CoinMoqParam is the coinMoqParam argument value.
@@ -180,6 +223,15 @@ type KeystoreMock struct {
 			// ScriptType is the scriptType argument value.
 			ScriptType signing.ScriptType
 		}
+		// SignBTCMessageBIP322 holds details about calls to the SignBTCMessageBIP322 method.
+		SignBTCMessageBIP322 []struct {
+			// Message is the message argument value.
+			Message []byte
+			// Keypath is the keypath argument value.
+			Keypath signing.AbsoluteKeypath
+			// ScriptType is the scriptType argument value.
+			ScriptType signing.ScriptType
+		}
 		// SignETHMessage holds details about calls to the SignETHMessage method.
 		SignETHMessage []struct {
 			// Message is the message argument value.
@@ -210,6 +262,15 @@ type KeystoreMock struct {
 			// IfaceVal is the ifaceVal argument value.
 			IfaceVal interface{}
 		}
+		// SilentPaymentSharedSecret holds details about calls to the SilentPaymentSharedSecret method.
+		SilentPaymentSharedSecret []struct {
+			// Inputs is the inputs argument value.
+			Inputs []*signing.Configuration
+			// InputHash is the inputHash argument value.
+			InputHash []byte
+			// ScanPubKey is the scanPubKey argument value.
+			ScanPubKey *btcec.PublicKey
+		}
 		// SupportsAccount holds details about calls to the SupportsAccount method.
 		SupportsAccount []struct {
 			// CoinInstance is the coinInstance argument value.
@@ -228,6 +289,21 @@ type KeystoreMock struct {
 		// SupportsMultipleAccounts holds details about calls to the SupportsMultipleAccounts method.
 		SupportsMultipleAccounts []struct {
 		}
+		// SupportsPayjoin holds details about calls to the SupportsPayjoin method.
+		SupportsPayjoin []struct {
+			// CoinMoqParam is the coinMoqParam argument value.
+			CoinMoqParam coin.Coin
+		}
+		// SupportsProofOfReserves holds details about calls to the SupportsProofOfReserves method.
+		SupportsProofOfReserves []struct {
+			// CoinMoqParam is the coinMoqParam argument value.
+			CoinMoqParam coin.Coin
+		}
+		// SupportsSilentPayments holds details about calls to the SupportsSilentPayments method.
+		SupportsSilentPayments []struct {
+			// CoinMoqParam is the coinMoqParam argument value.
+			CoinMoqParam coin.Coin
+		}
 		// SupportsUnifiedAccounts holds details about calls to the SupportsUnifiedAccounts method.
 		SupportsUnifiedAccounts []struct {
 		}
@@ -250,20 +326,26 @@ type KeystoreMock struct {
 		}
 	}
 	lockCanSignMessage                  sync.RWMutex
+	lockCanSignMessageBIP322            sync.RWMutex
 	lockCanVerifyAddress                sync.RWMutex
 	lockCanVerifyExtendedPublicKey      sync.RWMutex
 	lockExtendedPublicKey               sync.RWMutex
 	lockName                            sync.RWMutex
 	lockRootFingerprint                 sync.RWMutex
 	lockSignBTCMessage                  sync.RWMutex
+	lockSignBTCMessageBIP322            sync.RWMutex
 	lockSignETHMessage                  sync.RWMutex
 	lockSignETHTypedMessage             sync.RWMutex
 	lockSignETHWalletConnectTransaction sync.RWMutex
 	lockSignTransaction                 sync.RWMutex
+	lockSilentPaymentSharedSecret       sync.RWMutex
 	lockSupportsAccount                 sync.RWMutex
 	lockSupportsCoin                    sync.RWMutex
 	lockSupportsEIP1559                 sync.RWMutex
 	lockSupportsMultipleAccounts        sync.RWMutex
+	lockSupportsPayjoin                 sync.RWMutex
+	lockSupportsProofOfReserves         sync.RWMutex
+	lockSupportsSilentPayments          sync.RWMutex
 	lockSupportsUnifiedAccounts         sync.RWMutex
 	lockType                            sync.RWMutex
 	lockVerifyAddress                   sync.RWMutex
@@ -302,6 +384,38 @@ func (mock *KeystoreMock) CanSignMessageCalls() []struct {
 	return calls
 }
 
+// CanSignMessageBIP322 calls CanSignMessageBIP322Func.
+func (mock *KeystoreMock) CanSignMessageBIP322(code coin.Code) bool {
+	if mock.CanSignMessageBIP322Func == nil {
+		panic("KeystoreMock.CanSignMessageBIP322Func: method is nil but Keystore.CanSignMessageBIP322 was just called")
+	}
+	callInfo := struct {
+		Code coin.Code
+	}{
+		Code: code,
+	}
+	mock.lockCanSignMessageBIP322.Lock()
+	mock.calls.CanSignMessageBIP322 = append(mock.calls.CanSignMessageBIP322, callInfo)
+	mock.lockCanSignMessageBIP322.Unlock()
+	return mock.CanSignMessageBIP322Func(code)
+}
+
+// CanSignMessageBIP322Calls gets all the calls that were made to CanSignMessageBIP322.
+// Check the length with:
+//
+//	len(mockedKeystore.CanSignMessageBIP322Calls())
+func (mock *KeystoreMock) CanSignMessageBIP322Calls() []struct {
+	Code coin.Code
+} {
+	var calls []struct {
+		Code coin.Code
+	}
+	mock.lockCanSignMessageBIP322.RLock()
+	calls = mock.calls.CanSignMessageBIP322
+	mock.lockCanSignMessageBIP322.RUnlock()
+	return calls
+}
+
 // CanVerifyAddress calls CanVerifyAddressFunc.
 func (mock *KeystoreMock) CanVerifyAddress(coinMoqParam coin.Coin) (bool, bool, error) {
 	if mock.CanVerifyAddressFunc == nil {
@@ -491,6 +605,46 @@ func (mock *KeystoreMock) SignBTCMessageCalls() []struct {
 	return calls
 }
 
+// SignBTCMessageBIP322 calls SignBTCMessageBIP322Func.
+func (mock *KeystoreMock) SignBTCMessageBIP322(message []byte, keypath signing.AbsoluteKeypath, scriptType signing.ScriptType) (wire.TxWitness, error) {
+	if mock.SignBTCMessageBIP322Func == nil {
+		panic("KeystoreMock.SignBTCMessageBIP322Func: method is nil but Keystore.SignBTCMessageBIP322 was just called")
+	}
+	callInfo := struct {
+		Message    []byte
+		Keypath    signing.AbsoluteKeypath
+		ScriptType signing.ScriptType
+	}{
+		Message:    message,
+		Keypath:    keypath,
+		ScriptType: scriptType,
+	}
+	mock.lockSignBTCMessageBIP322.Lock()
+	mock.calls.SignBTCMessageBIP322 = append(mock.calls.SignBTCMessageBIP322, callInfo)
+	mock.lockSignBTCMessageBIP322.Unlock()
+	return mock.SignBTCMessageBIP322Func(message, keypath, scriptType)
+}
+
+// SignBTCMessageBIP322Calls gets all the calls that were made to SignBTCMessageBIP322.
+// Check the length with:
+//
+//	len(mockedKeystore.SignBTCMessageBIP322Calls())
+func (mock *KeystoreMock) SignBTCMessageBIP322Calls() []struct {
+	Message    []byte
+	Keypath    signing.AbsoluteKeypath
+	ScriptType signing.ScriptType
+} {
+	var calls []struct {
+		Message    []byte
+		Keypath    signing.AbsoluteKeypath
+		ScriptType signing.ScriptType
+	}
+	mock.lockSignBTCMessageBIP322.RLock()
+	calls = mock.calls.SignBTCMessageBIP322
+	mock.lockSignBTCMessageBIP322.RUnlock()
+	return calls
+}
+
 // SignETHMessage calls SignETHMessageFunc.
 func (mock *KeystoreMock) SignETHMessage(message []byte, keypath signing.AbsoluteKeypath) ([]byte, error) {
 	if mock.SignETHMessageFunc == nil {
@@ -639,6 +793,46 @@ func (mock *KeystoreMock) SignTransactionCalls() []struct {
 	return calls
 }
 
+// SilentPaymentSharedSecret calls SilentPaymentSharedSecretFunc.
+func (mock *KeystoreMock) SilentPaymentSharedSecret(inputs []*signing.Configuration, inputHash []byte, scanPubKey *btcec.PublicKey) (*btcec.PublicKey, error) {
+	if mock.SilentPaymentSharedSecretFunc == nil {
+		panic("KeystoreMock.SilentPaymentSharedSecretFunc: method is nil but Keystore.SilentPaymentSharedSecret was just called")
+	}
+	callInfo := struct {
+		Inputs     []*signing.Configuration
+		InputHash  []byte
+		ScanPubKey *btcec.PublicKey
+	}{
+		Inputs:     inputs,
+		InputHash:  inputHash,
+		ScanPubKey: scanPubKey,
+	}
+	mock.lockSilentPaymentSharedSecret.Lock()
+	mock.calls.SilentPaymentSharedSecret = append(mock.calls.SilentPaymentSharedSecret, callInfo)
+	mock.lockSilentPaymentSharedSecret.Unlock()
+	return mock.SilentPaymentSharedSecretFunc(inputs, inputHash, scanPubKey)
+}
+
+// SilentPaymentSharedSecretCalls gets all the calls that were made to SilentPaymentSharedSecret.
+// Check the length with:
+//
+//	len(mockedKeystore.SilentPaymentSharedSecretCalls())
+func (mock *KeystoreMock) SilentPaymentSharedSecretCalls() []struct {
+	Inputs     []*signing.Configuration
+	InputHash  []byte
+	ScanPubKey *btcec.PublicKey
+} {
+	var calls []struct {
+		Inputs     []*signing.Configuration
+		InputHash  []byte
+		ScanPubKey *btcec.PublicKey
+	}
+	mock.lockSilentPaymentSharedSecret.RLock()
+	calls = mock.calls.SilentPaymentSharedSecret
+	mock.lockSilentPaymentSharedSecret.RUnlock()
+	return calls
+}
+
 // SupportsAccount calls SupportsAccountFunc.
 func (mock *KeystoreMock) SupportsAccount(coinInstance coin.Coin, meta interface{}) bool {
 	if mock.SupportsAccountFunc == nil {
@@ -761,6 +955,102 @@ func (mock *KeystoreMock) SupportsMultipleAccountsCalls() []struct {
 	return calls
 }
 
+// SupportsPayjoin calls SupportsPayjoinFunc.
+func (mock *KeystoreMock) SupportsPayjoin(coinMoqParam coin.Coin) bool {
+	if mock.SupportsPayjoinFunc == nil {
+		panic("KeystoreMock.SupportsPayjoinFunc: method is nil but Keystore.SupportsPayjoin was just called")
+	}
+	callInfo := struct {
+		CoinMoqParam coin.Coin
+	}{
+		CoinMoqParam: coinMoqParam,
+	}
+	mock.lockSupportsPayjoin.Lock()
+	mock.calls.SupportsPayjoin = append(mock.calls.SupportsPayjoin, callInfo)
+	mock.lockSupportsPayjoin.Unlock()
+	return mock.SupportsPayjoinFunc(coinMoqParam)
+}
+
+// SupportsPayjoinCalls gets all the calls that were made to SupportsPayjoin.
+// Check the length with:
+//
+//	len(mockedKeystore.SupportsPayjoinCalls())
+func (mock *KeystoreMock) SupportsPayjoinCalls() []struct {
+	CoinMoqParam coin.Coin
+} {
+	var calls []struct {
+		CoinMoqParam coin.Coin
+	}
+	mock.lockSupportsPayjoin.RLock()
+	calls = mock.calls.SupportsPayjoin
+	mock.lockSupportsPayjoin.RUnlock()
+	return calls
+}
+
+// SupportsProofOfReserves calls SupportsProofOfReservesFunc.
+func (mock *KeystoreMock) SupportsProofOfReserves(coinMoqParam coin.Coin) bool {
+	if mock.SupportsProofOfReservesFunc == nil {
+		panic("KeystoreMock.SupportsProofOfReservesFunc: method is nil but Keystore.SupportsProofOfReserves was just called")
+	}
+	callInfo := struct {
+		CoinMoqParam coin.Coin
+	}{
+		CoinMoqParam: coinMoqParam,
+	}
+	mock.lockSupportsProofOfReserves.Lock()
+	mock.calls.SupportsProofOfReserves = append(mock.calls.SupportsProofOfReserves, callInfo)
+	mock.lockSupportsProofOfReserves.Unlock()
+	return mock.SupportsProofOfReservesFunc(coinMoqParam)
+}
+
+// SupportsProofOfReservesCalls gets all the calls that were made to SupportsProofOfReserves.
+// Check the length with:
+//
+//	len(mockedKeystore.SupportsProofOfReservesCalls())
+func (mock *KeystoreMock) SupportsProofOfReservesCalls() []struct {
+	CoinMoqParam coin.Coin
+} {
+	var calls []struct {
+		CoinMoqParam coin.Coin
+	}
+	mock.lockSupportsProofOfReserves.RLock()
+	calls = mock.calls.SupportsProofOfReserves
+	mock.lockSupportsProofOfReserves.RUnlock()
+	return calls
+}
+
+// SupportsSilentPayments calls SupportsSilentPaymentsFunc.
+func (mock *KeystoreMock) SupportsSilentPayments(coinMoqParam coin.Coin) bool {
+	if mock.SupportsSilentPaymentsFunc == nil {
+		panic("KeystoreMock.SupportsSilentPaymentsFunc: method is nil but Keystore.SupportsSilentPayments was just called")
+	}
+	callInfo := struct {
+		CoinMoqParam coin.Coin
+	}{
+		CoinMoqParam: coinMoqParam,
+	}
+	mock.lockSupportsSilentPayments.Lock()
+	mock.calls.SupportsSilentPayments = append(mock.calls.SupportsSilentPayments, callInfo)
+	mock.lockSupportsSilentPayments.Unlock()
+	return mock.SupportsSilentPaymentsFunc(coinMoqParam)
+}
+
+// SupportsSilentPaymentsCalls gets all the calls that were made to SupportsSilentPayments.
+// Check the length with:
+//
+//	len(mockedKeystore.SupportsSilentPaymentsCalls())
+func (mock *KeystoreMock) SupportsSilentPaymentsCalls() []struct {
+	CoinMoqParam coin.Coin
+} {
+	var calls []struct {
+		CoinMoqParam coin.Coin
+	}
+	mock.lockSupportsSilentPayments.RLock()
+	calls = mock.calls.SupportsSilentPayments
+	mock.lockSupportsSilentPayments.RUnlock()
+	return calls
+}
+
 // SupportsUnifiedAccounts calls SupportsUnifiedAccountsFunc.
 func (mock *KeystoreMock) SupportsUnifiedAccounts() bool {
 	if mock.SupportsUnifiedAccountsFunc == nil {
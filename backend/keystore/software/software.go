@@ -22,12 +22,16 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/bip322"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/silentpayments"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/types"
 	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
 	keystorePkg "github.com/digitalbitbox/bitbox-wallet-app/backend/keystore"
@@ -198,6 +202,10 @@ func (keystore *Keystore) SignTransaction(
 			return errp.New("There needs to be exactly one output being spent per input.")
 		}
 		address := btcProposedTx.GetAccountAddress(spentOutput.ScriptHashHex())
+		if address == nil {
+			// Input of another party, e.g. the receiver in a payjoin. It is already signed.
+			continue
+		}
 
 		xprv, err := address.Configuration.AbsoluteKeypath().Derive(keystore.master)
 		if err != nil {
@@ -271,6 +279,48 @@ func (keystore *Keystore) SignBTCMessage(message []byte, keypath signing.Absolut
 	return nil, errp.New("unsupported")
 }
 
+// CanSignMessageBIP322 implements keystore.Keystore.
+func (keystore *Keystore) CanSignMessageBIP322(code coin.Code) bool {
+	switch code {
+	case coin.CodeBTC, coin.CodeTBTC, coin.CodeSBTC, coin.CodeTBTC4, coin.CodeRBTC:
+		return true
+	default:
+		return false
+	}
+}
+
+// SignBTCMessageBIP322 implements keystore.Keystore.
+func (keystore *Keystore) SignBTCMessageBIP322(
+	message []byte, keypath signing.AbsoluteKeypath, scriptType signing.ScriptType,
+) (wire.TxWitness, error) {
+	xprv, err := keypath.Derive(keystore.master)
+	if err != nil {
+		return nil, err
+	}
+	prv, err := xprv.ECPrivKey()
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	pkScript, err := bip322.PkScript(scriptType, prv.PubKey())
+	if err != nil {
+		return nil, err
+	}
+	toSign := bip322.ToSign(bip322.ToSpend(message, pkScript))
+	sigHashes := txscript.NewTxSigHashes(toSign, bip322.PrevOutputFetcher(pkScript))
+	var witness wire.TxWitness
+	if scriptType == signing.ScriptTypeP2TR {
+		witness, err = txscript.TaprootWitnessSignature(
+			toSign, sigHashes, 0, 0, pkScript, txscript.SigHashDefault, prv)
+	} else {
+		witness, err = txscript.WitnessSignature(
+			toSign, sigHashes, 0, 0, pkScript, txscript.SigHashAll, prv, true)
+	}
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	return witness, nil
+}
+
 // SignETHMessage implements keystore.Keystore.
 func (keystore *Keystore) SignETHMessage(message []byte, keypath signing.AbsoluteKeypath) ([]byte, error) {
 	return nil, errp.New("unsupported")
@@ -281,6 +331,56 @@ func (keystore *Keystore) SignETHTypedMessage(chainId uint64, data []byte, keypa
 	return nil, errp.New("unsupported")
 }
 
+// SupportsPayjoin implements keystore.Keystore.
+func (keystore *Keystore) SupportsPayjoin(c coin.Coin) bool {
+	// Payjoin is not deployed on Litecoin.
+	switch c.Code() {
+	case coin.CodeBTC, coin.CodeTBTC, coin.CodeSBTC, coin.CodeTBTC4, coin.CodeRBTC:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsProofOfReserves implements keystore.Keystore.
+func (keystore *Keystore) SupportsProofOfReserves(coin coin.Coin) bool {
+	_, ok := coin.(*btc.Coin)
+	return ok
+}
+
+// SupportsSilentPayments implements keystore.Keystore.
+func (keystore *Keystore) SupportsSilentPayments(c coin.Coin) bool {
+	// Silent payments are only defined for Bitcoin.
+	switch c.Code() {
+	case coin.CodeBTC, coin.CodeTBTC, coin.CodeSBTC, coin.CodeTBTC4, coin.CodeRBTC:
+		return true
+	default:
+		return false
+	}
+}
+
+// SilentPaymentSharedSecret implements keystore.Keystore.
+func (keystore *Keystore) SilentPaymentSharedSecret(
+	inputs []*signing.Configuration, inputHash []byte, scanPubKey *btcec.PublicKey,
+) (*btcec.PublicKey, error) {
+	privateKeys := make([]*btcec.PrivateKey, len(inputs))
+	for index, input := range inputs {
+		xprv, err := input.AbsoluteKeypath().Derive(keystore.master)
+		if err != nil {
+			return nil, err
+		}
+		prv, err := xprv.ECPrivKey()
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		privateKeys[index], err = silentpayments.InputPrivateKey(input.ScriptType(), prv)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return silentpayments.SharedSecret(privateKeys, inputHash, scanPubKey)
+}
+
 // SignETHWalletConnectTransaction implements keystore.Keystore.
 func (keystore *Keystore) SignETHWalletConnectTransaction(chainID uint64, tx *ethTypes.Transaction, keypath signing.AbsoluteKeypath) ([]byte, error) {
 	return nil, errp.New("unsupported")
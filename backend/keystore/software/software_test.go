@@ -17,7 +17,13 @@ package software
 import (
 	"testing"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/btc/silentpayments"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	coinMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/signing"
 	"github.com/stretchr/testify/require"
 )
 
@@ -32,3 +38,75 @@ func TestRootFingerprint(t *testing.T) {
 	// Verified by comparing to the root fingerprint produced by the BitBox02 and Electrum.
 	require.Equal(t, []byte{0xfb, 0x70, 0x89, 0xbd}, rootFingerprint)
 }
+
+func TestSupportsPayjoin(t *testing.T) {
+	keystore := &Keystore{}
+	for code, supported := range map[coin.Code]bool{
+		coin.CodeBTC:  true,
+		coin.CodeTBTC: true,
+		coin.CodeLTC:  false,
+		coin.CodeETH:  false,
+	} {
+		code := code
+		mockCoin := &coinMocks.CoinMock{CodeFunc: func() coin.Code { return code }}
+		require.Equal(t, supported, keystore.SupportsPayjoin(mockCoin), code)
+	}
+}
+
+func TestSupportsSilentPayments(t *testing.T) {
+	keystore := &Keystore{}
+	for code, supported := range map[coin.Code]bool{
+		coin.CodeBTC:   true,
+		coin.CodeTBTC4: true,
+		coin.CodeLTC:   false,
+		coin.CodeTLTC:  false,
+		coin.CodeETH:   false,
+	} {
+		code := code
+		mockCoin := &coinMocks.CoinMock{CodeFunc: func() coin.Code { return code }}
+		require.Equal(t, supported, keystore.SupportsSilentPayments(mockCoin), code)
+	}
+}
+
+func TestSilentPaymentSharedSecret(t *testing.T) {
+	rootXprv, err := hdkeychain.NewKeyFromString("xprv9s21ZrQH143K3uDh9hiNXB3a9GVzcCujEmCwmZA9g8m4i5nUDVdLHJjsLMPzV26vj8Q7ceGrUhX119Y3XzGhJqq5K6LWP1h6gjv2cbkMEH1")
+	require.NoError(t, err)
+	keystore := NewKeystore(rootXprv)
+
+	inputConfiguration := func(scriptType signing.ScriptType, keypath string) *signing.Configuration {
+		absoluteKeypath, err := signing.NewAbsoluteKeypath(keypath)
+		require.NoError(t, err)
+		xprv, err := absoluteKeypath.Derive(rootXprv)
+		require.NoError(t, err)
+		xpub, err := xprv.Neuter()
+		require.NoError(t, err)
+		return signing.NewBitcoinConfiguration(scriptType, []byte{1, 2, 3, 4}, absoluteKeypath, xpub)
+	}
+	inputs := []*signing.Configuration{
+		inputConfiguration(signing.ScriptTypeP2WPKH, "m/84'/0'/0'/0/0"),
+		inputConfiguration(signing.ScriptTypeP2TR, "m/86'/0'/0'/0/1"),
+	}
+	publicKeys := []*btcec.PublicKey{}
+	for _, input := range inputs {
+		publicKey, err := silentpayments.InputPublicKey(input.ScriptType(), input.PublicKey())
+		require.NoError(t, err)
+		publicKeys = append(publicKeys, publicKey)
+	}
+	sumPublicKeys, err := silentpayments.SumPublicKeys(publicKeys)
+	require.NoError(t, err)
+	inputHash := chainhash.HashB([]byte("input hash"))
+	scanKey, _ := btcec.PrivKeyFromBytes(chainhash.HashB([]byte("scan")))
+
+	sharedSecret, err := keystore.SilentPaymentSharedSecret(inputs, inputHash, scanKey.PubKey())
+	require.NoError(t, err)
+
+	// The receiver computes the same secret as input_hash·b_scan·A.
+	var tweak btcec.ModNScalar
+	tweak.SetByteSlice(inputHash)
+	tweak.Mul(&scanKey.Key)
+	var sumPoint, expected btcec.JacobianPoint
+	sumPublicKeys.AsJacobian(&sumPoint)
+	btcec.ScalarMultNonConst(&tweak, &sumPoint, &expected)
+	expected.ToAffine()
+	require.True(t, btcec.NewPublicKey(&expected.X, &expected.Y).IsEqual(sharedSecret))
+}
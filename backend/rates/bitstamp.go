@@ -0,0 +1,172 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/ratelimit"
+)
+
+const (
+	// See https://www.bitstamp.net/api/.
+	bitstampAPI = "https://www.bitstamp.net/api/v2"
+	// Bitstamp returns at most 1000 OHLC entries at once.
+	bitstampMaxEntries = 1000
+)
+
+// bitstampPairs contains the fiats of each supported coin unit.
+var bitstampPairs = map[string][]string{
+	"BTC":  {"USD", "EUR", "GBP"},
+	"ETH":  {"USD", "EUR", "GBP", "BTC"},
+	"LTC":  {"USD", "EUR", "GBP", "BTC"},
+	"USDT": {"USD", "EUR"},
+	"USDC": {"USD", "EUR"},
+	"DAI":  {"USD"},
+	"LINK": {"USD", "EUR", "GBP", "BTC"},
+}
+
+// bitstampProvider fetches exchange rates from the public market data API of Bitstamp, with hourly
+// historical rates.
+type bitstampProvider struct {
+	httpClient *http.Client
+	url        string
+	limiter    *ratelimit.LimitedCall
+}
+
+// NewBitstampProvider returns a provider using the public Bitstamp API.
+func NewBitstampProvider(client *http.Client) Provider {
+	return &bitstampProvider{
+		httpClient: client,
+		url:        bitstampAPI,
+		limiter:    ratelimit.NewLimitedCall(apiRateLimit(bitstampAPI)),
+	}
+}
+
+// Name implements Provider.
+func (provider *bitstampProvider) Name() string {
+	return string(ProviderBitstamp)
+}
+
+// Supports implements Provider.
+func (provider *bitstampProvider) Supports(coinUnit, fiat string) bool {
+	for _, supported := range bitstampPairs[coinUnit] {
+		if supported == fiat {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxHistoryRange implements Provider.
+func (provider *bitstampProvider) MaxHistoryRange() time.Duration {
+	return bitstampMaxEntries * time.Hour
+}
+
+// LatestRates implements Provider.
+func (provider *bitstampProvider) LatestRates(
+	ctx context.Context, coinUnits, fiats []string) (map[string]map[string]float64, error) {
+	// All tickers are fetched at once.
+	var tickers []struct {
+		// E.g. "BTC/USD".
+		Pair string `json:"pair"`
+		Last string `json:"last"`
+	}
+	err := provider.limiter.Call(ctx, "latest rates", func() error {
+		return getJSON(ctx, provider.httpClient, provider.url+"/ticker/", 1<<20, &tickers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, unit := range coinUnits {
+		for _, fiat := range fiats {
+			if provider.Supports(unit, fiat) {
+				wanted[unit+"/"+fiat] = true
+			}
+		}
+	}
+	rates := map[string]map[string]float64{}
+	for _, ticker := range tickers {
+		if !wanted[ticker.Pair] {
+			continue
+		}
+		price, err := strconv.ParseFloat(ticker.Last, 64)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		unit, fiat, _ := strings.Cut(ticker.Pair, "/")
+		if rates[unit] == nil {
+			rates[unit] = map[string]float64{}
+		}
+		rates[unit][fiat] = price
+	}
+	return rates, nil
+}
+
+// HistoricalRates implements Provider.
+func (provider *bitstampProvider) HistoricalRates(
+	ctx context.Context, coinUnit, fiat string, start, end time.Time) ([]Rate, error) {
+	if !provider.Supports(coinUnit, fiat) {
+		return nil, errp.Newf("bitstamp: unsupported pair %s/%s", coinUnit, fiat)
+	}
+	pair := strings.ToLower(coinUnit + fiat)
+	// Bitstamp returns `limit` entries up to the end.
+	limit := int(end.Sub(start)/time.Hour) + 1
+	if limit > bitstampMaxEntries {
+		limit = bitstampMaxEntries
+	}
+	endpoint := fmt.Sprintf("%s/ohlc/%s/?%s", provider.url, pair, url.Values{
+		"step":  {"3600"},
+		"limit": {strconv.Itoa(limit)},
+		"end":   {strconv.FormatInt(end.Unix(), 10)},
+	}.Encode())
+	var response struct {
+		Data struct {
+			OHLC []struct {
+				Timestamp string `json:"timestamp"`
+				Close     string `json:"close"`
+			} `json:"ohlc"`
+		} `json:"data"`
+	}
+	msg := fmt.Sprintf("fetch bitstamp pair=%s start=%s", pair, start)
+	err := provider.limiter.Call(ctx, msg, func() error {
+		return getJSON(ctx, provider.httpClient, endpoint, 1<<20, &response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	rates := []Rate{}
+	for _, entry := range response.Data.OHLC {
+		timestamp, err := strconv.ParseInt(entry.Timestamp, 10, 64)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		price, err := strconv.ParseFloat(entry.Close, 64)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		// The close price is at the end of the hour.
+		rates = append(rates, Rate{Time: time.Unix(timestamp, 0).Add(time.Hour), Value: price})
+	}
+	return ratesInRange(rates, start, end), nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// csvProvider reads exchange rates from a local CSV file, for installations without internet
+// access. Each row contains the time as RFC3339 or unix seconds, the coin unit, the fiat and the
+// price, with an optional header:
+//
+//	time,coin,fiat,price
+//	2024-01-01T00:00:00Z,BTC,USD,42000.5
+//
+// The latest rate of a pair is the one with the latest time. The file is read again when it
+// changes.
+type csvProvider struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	// rates are keyed by coin unit and fiat, sorted by time.
+	rates map[string]map[string][]Rate
+}
+
+// NewCSVProvider returns a provider reading the rates from the CSV file at the given path.
+func NewCSVProvider(path string) Provider {
+	return &csvProvider{path: path}
+}
+
+// Name implements Provider.
+func (provider *csvProvider) Name() string {
+	return string(ProviderCSV)
+}
+
+// load returns the rates of the file, reading it if it changed.
+func (provider *csvProvider) load() (map[string]map[string][]Rate, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	info, err := os.Stat(provider.path)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	if provider.rates != nil && info.ModTime().Equal(provider.modTime) {
+		return provider.rates, nil
+	}
+	file, err := os.Open(provider.path)
+	if err != nil {
+		return nil, errp.WithStack(err)
+	}
+	defer file.Close() //nolint:errcheck
+	rates, err := parseRatesCSV(file)
+	if err != nil {
+		return nil, err
+	}
+	provider.rates = rates
+	provider.modTime = info.ModTime()
+	return rates, nil
+}
+
+// parseRatesCSV parses the rates in the format described at csvProvider.
+func parseRatesCSV(r io.Reader) (map[string]map[string][]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+	rates := map[string]map[string][]Rate{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if line == 1 && strings.EqualFold(record[0], "time") {
+			continue
+		}
+		var timestamp time.Time
+		if seconds, err := strconv.ParseInt(record[0], 10, 64); err == nil {
+			timestamp = time.Unix(seconds, 0)
+		} else {
+			timestamp, err = time.Parse(time.RFC3339, record[0])
+			if err != nil {
+				return nil, errp.Newf("invalid time in line %d: %s", line, record[0])
+			}
+		}
+		price, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, errp.Newf("invalid price in line %d: %s", line, record[3])
+		}
+		unit, fiat := strings.ToUpper(record[1]), strings.ToUpper(record[2])
+		if rates[unit] == nil {
+			rates[unit] = map[string][]Rate{}
+		}
+		rates[unit][fiat] = append(rates[unit][fiat], Rate{Time: timestamp, Value: price})
+	}
+	for _, fiats := range rates {
+		for _, pairRates := range fiats {
+			sort.SliceStable(pairRates, func(i, j int) bool {
+				return pairRates[i].Time.Before(pairRates[j].Time)
+			})
+		}
+	}
+	return rates, nil
+}
+
+// Supports implements Provider.
+func (provider *csvProvider) Supports(coinUnit, fiat string) bool {
+	rates, err := provider.load()
+	if err != nil {
+		return false
+	}
+	return len(rates[coinUnit][fiat]) > 0
+}
+
+// MaxHistoryRange implements Provider.
+func (provider *csvProvider) MaxHistoryRange() time.Duration {
+	// The whole file is available at once.
+	return 10 * 365 * 24 * time.Hour
+}
+
+// LatestRates implements Provider.
+func (provider *csvProvider) LatestRates(
+	_ context.Context, coinUnits, fiats []string) (map[string]map[string]float64, error) {
+	rates, err := provider.load()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]map[string]float64{}
+	for _, unit := range coinUnits {
+		for _, fiat := range fiats {
+			pairRates := rates[unit][fiat]
+			if len(pairRates) == 0 {
+				continue
+			}
+			if result[unit] == nil {
+				result[unit] = map[string]float64{}
+			}
+			result[unit][fiat] = pairRates[len(pairRates)-1].Value
+		}
+	}
+	return result, nil
+}
+
+// HistoricalRates implements Provider.
+func (provider *csvProvider) HistoricalRates(
+	_ context.Context, coinUnit, fiat string, start, end time.Time) ([]Rate, error) {
+	rates, err := provider.load()
+	if err != nil {
+		return nil, err
+	}
+	return ratesInRange(rates[coinUnit][fiat], start, end), nil
+}
@@ -0,0 +1,293 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/ratelimit"
+)
+
+const (
+	// fxCoin is the pseudo coin code under which the USD/fiat exchange rates of an FXSource are
+	// stored in the history, e.g. "usdMXN".
+	fxCoin = "usd"
+	// fxMaxAge is how long an FX rate is used after its time. FX rates are usually published once
+	// per business day, so there are none on weekends and holidays.
+	fxMaxAge = 7 * 24 * time.Hour
+	// fxLatestInterval is the interval at which the latest FX rates are fetched.
+	fxLatestInterval = time.Hour
+
+	// See https://www.frankfurter.app/docs/.
+	frankfurterAPI = "https://api.frankfurter.app"
+	// Frankfurter returns weekly instead of daily rates for long time ranges.
+	frankfurterMaxRange = 90 * 24 * time.Hour
+)
+
+// frankfurterEarliest is the first day of the ECB reference rates served by Frankfurter.
+var frankfurterEarliest = time.Date(1999, 1, 4, 0, 0, 0, 0, time.UTC)
+
+// frankfurterFiats are the currencies of the ECB reference rates.
+var frankfurterFiats = map[string]bool{
+	"AUD": true, "BGN": true, "BRL": true, "CAD": true, "CHF": true, "CNY": true, "CZK": true,
+	"DKK": true, "EUR": true, "GBP": true, "HKD": true, "HUF": true, "IDR": true, "ILS": true,
+	"INR": true, "ISK": true, "JPY": true, "KRW": true, "MXN": true, "MYR": true, "NOK": true,
+	"NZD": true, "PHP": true, "PLN": true, "RON": true, "SEK": true, "SGD": true, "THB": true,
+	"TRY": true, "ZAR": true,
+}
+
+// FXSource is a source of fiat-to-fiat exchange rates, used to derive the rates of coins in fiat
+// currencies the providers do not support from their rates in USD. All rates are the price of one
+// USD in the fiat currency, identified by its ISO-4217 code.
+type FXSource interface {
+	// Name identifies the source in logs and in the health report.
+	Name() string
+	// Supports returns true if the source has USD exchange rates in the fiat currency.
+	Supports(fiat string) bool
+	// LatestFXRates fetches the latest USD exchange rates in the given fiat currencies. Fiats
+	// missing in the result are not available.
+	LatestFXRates(ctx context.Context, fiats []string) (map[string]float64, error)
+	// HistoricalFXRates fetches the USD exchange rates in the fiat currency between start and end,
+	// sorted by time. An empty result means that there are no rates in this time range.
+	HistoricalFXRates(ctx context.Context, fiat string, start, end time.Time) ([]Rate, error)
+	// MaxHistoryRange is the longest time range HistoricalFXRates can fetch at once.
+	MaxHistoryRange() time.Duration
+}
+
+// frankfurterFX fetches the daily reference rates of the European Central Bank from the
+// Frankfurter API.
+type frankfurterFX struct {
+	httpClient *http.Client
+	url        string
+	limiter    *ratelimit.LimitedCall
+}
+
+// NewFrankfurterFX returns an FX source using the Frankfurter API.
+func NewFrankfurterFX(client *http.Client) FXSource {
+	return &frankfurterFX{
+		httpClient: client,
+		url:        frankfurterAPI,
+		limiter:    ratelimit.NewLimitedCall(apiRateLimit(frankfurterAPI)),
+	}
+}
+
+// Name implements FXSource.
+func (source *frankfurterFX) Name() string {
+	return "frankfurter"
+}
+
+// Supports implements FXSource.
+func (source *frankfurterFX) Supports(fiat string) bool {
+	return frankfurterFiats[fiat] || fiat == USD.String()
+}
+
+// MaxHistoryRange implements FXSource.
+func (source *frankfurterFX) MaxHistoryRange() time.Duration {
+	return frankfurterMaxRange
+}
+
+// LatestFXRates implements FXSource.
+func (source *frankfurterFX) LatestFXRates(ctx context.Context, fiats []string) (map[string]float64, error) {
+	result := map[string]float64{}
+	var supported []string
+	for _, fiat := range fiats {
+		switch {
+		case fiat == USD.String():
+			result[fiat] = 1
+		case source.Supports(fiat):
+			supported = append(supported, fiat)
+		}
+	}
+	if len(supported) == 0 {
+		return result, nil
+	}
+	endpoint := fmt.Sprintf("%s/latest?%s", source.url, url.Values{
+		"from": {USD.String()},
+		"to":   {strings.Join(supported, ",")},
+	}.Encode())
+	var response struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	err := source.limiter.Call(ctx, "latest fx rates", func() error {
+		return getJSON(ctx, source.httpClient, endpoint, 1<<16, &response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for fiat, rate := range response.Rates {
+		result[fiat] = rate
+	}
+	return result, nil
+}
+
+// HistoricalFXRates implements FXSource. The rates are at midnight UTC of their day.
+func (source *frankfurterFX) HistoricalFXRates(
+	ctx context.Context, fiat string, start, end time.Time) ([]Rate, error) {
+	if !source.Supports(fiat) {
+		return nil, errp.Newf("frankfurter: unsupported currency %s", fiat)
+	}
+	if end.Before(frankfurterEarliest) {
+		return []Rate{}, nil
+	}
+	if fiat == USD.String() {
+		return nil, errp.New("frankfurter: no history of USD in USD")
+	}
+	const dateFormat = "2006-01-02"
+	endpoint := fmt.Sprintf("%s/%s..%s?%s", source.url,
+		start.UTC().Format(dateFormat), end.UTC().Format(dateFormat),
+		url.Values{"from": {USD.String()}, "to": {fiat}}.Encode())
+	var response struct {
+		// Keyed by date, then by fiat.
+		Rates map[string]map[string]float64 `json:"rates"`
+	}
+	msg := fmt.Sprintf("fetch frankfurter fiat=%s start=%s", fiat, start)
+	err := source.limiter.Call(ctx, msg, func() error {
+		return getJSON(ctx, source.httpClient, endpoint, 1<<20, &response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	rates := []Rate{}
+	for date, dayRates := range response.Rates {
+		day, err := time.Parse(dateFormat, date)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if rate, ok := dayRates[fiat]; ok {
+			rates = append(rates, Rate{Time: day, Value: rate})
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Time.Before(rates[j].Time) })
+	return ratesInRange(rates, start, end), nil
+}
+
+// SetFXSource replaces the source of fiat-to-fiat exchange rates. If nil, no rates are derived.
+//
+// SetFXSource must be called before StartCurrentRates and ReconfigureHistory.
+func (updater *RateUpdater) SetFXSource(source FXSource) {
+	updater.fx = source
+}
+
+// isCrossPair returns true if the rates of the coin in the fiat are derived from its rates in USD.
+// The historyMu lock must be held.
+func (updater *RateUpdater) isCrossPair(coin, fiat string) bool {
+	return coin != fxCoin && updater.crossPairs[coin+fiat]
+}
+
+// fxAt returns the USD exchange rate in the fiat at the given time, using the last known rate for
+// up to fxMaxAge. Returns 0 if no rate is available. The historyMu lock must be held.
+func (updater *RateUpdater) fxAt(fiat string, at time.Time) float64 {
+	data := updater.history[fxCoin+fiat]
+	if len(data) == 0 {
+		return 0
+	}
+	if last := data[len(data)-1]; at.After(last.timestamp) {
+		if at.Sub(last.timestamp) > fxMaxAge {
+			return 0
+		}
+		return last.value
+	}
+	return priceAt(data, at)
+}
+
+// crossLatestTimestamp is HistoryLatestTimestamp of a cross pair. The historyMu lock must be held.
+func (updater *RateUpdater) crossLatestTimestamp(coin, fiat string) time.Time {
+	usdData, fxData := updater.history[coin+USD.String()], updater.history[fxCoin+fiat]
+	if len(usdData) == 0 || len(fxData) == 0 {
+		return time.Time{}
+	}
+	latest := usdData[len(usdData)-1].timestamp
+	if fxLatest := fxData[len(fxData)-1].timestamp.Add(fxMaxAge); fxLatest.Before(latest) {
+		return fxLatest
+	}
+	return latest
+}
+
+// crossEarliestTimestamp is HistoryEarliestTimestamp of a cross pair. The historyMu lock must be
+// held.
+func (updater *RateUpdater) crossEarliestTimestamp(coin, fiat string) time.Time {
+	usdData, fxData := updater.history[coin+USD.String()], updater.history[fxCoin+fiat]
+	if len(usdData) == 0 || len(fxData) == 0 {
+		return time.Time{}
+	}
+	earliest := usdData[0].timestamp
+	if fxData[0].timestamp.After(earliest) {
+		return fxData[0].timestamp
+	}
+	return earliest
+}
+
+// deriveLatestFXRates adds the rates of the fiats missing in rates, derived from the rates in USD.
+// The latest FX rates are fetched at most every fxLatestInterval.
+func (updater *RateUpdater) deriveLatestFXRates(ctx context.Context, rates map[string]map[string]float64, fiats []string) {
+	if updater.fx == nil {
+		return
+	}
+	var missing []string
+	for _, fiat := range fiats {
+		if !updater.fx.Supports(fiat) {
+			continue
+		}
+		for _, unitRates := range rates {
+			_, hasFiat := unitRates[fiat]
+			_, hasUSD := unitRates[USD.String()]
+			if !hasFiat && hasUSD {
+				missing = append(missing, fiat)
+				break
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	stale := time.Since(updater.fxLastUpdated) > fxLatestInterval
+	for _, fiat := range missing {
+		if _, ok := updater.fxLast[fiat]; !ok {
+			stale = true
+		}
+	}
+	if stale {
+		fxRates, err := updater.fx.LatestFXRates(ctx, missing)
+		if ctx.Err() != nil {
+			return
+		}
+		updater.recordProviderResult(updater.fx.Name(), err)
+		if err != nil {
+			updater.log.WithError(err).Errorf("updateLast: fx source %s", updater.fx.Name())
+		} else {
+			updater.fxLast = fxRates
+			updater.fxLastUpdated = time.Now()
+		}
+	}
+	for _, fiat := range missing {
+		fxRate, ok := updater.fxLast[fiat]
+		if !ok {
+			continue
+		}
+		for _, unitRates := range rates {
+			usdRate, hasUSD := unitRates[USD.String()]
+			if _, ok := unitRates[fiat]; !ok && hasUSD {
+				unitRates[fiat] = usdRate * fxRate
+			}
+		}
+	}
+}
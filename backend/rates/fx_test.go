@@ -0,0 +1,181 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// staticFX is an FX source with fixed rates for testing.
+type staticFX struct {
+	latest  map[string]float64
+	history []Rate
+	// calls counts the LatestFXRates calls.
+	calls int
+}
+
+func (source *staticFX) Name() string { return "static-fx" }
+
+func (source *staticFX) Supports(fiat string) bool {
+	_, ok := source.latest[fiat]
+	return ok
+}
+
+func (source *staticFX) MaxHistoryRange() time.Duration { return 30 * 24 * time.Hour }
+
+func (source *staticFX) LatestFXRates(_ context.Context, fiats []string) (map[string]float64, error) {
+	source.calls++
+	return source.latest, nil
+}
+
+func (source *staticFX) HistoricalFXRates(
+	_ context.Context, fiat string, start, end time.Time) ([]Rate, error) {
+	return ratesInRange(source.history, start, end), nil
+}
+
+func TestCrossRatesLatest(t *testing.T) {
+	updater := NewRateUpdater(nil, "/dev/null")
+	defer updater.Stop()
+	updater.SetProviders(&staticProvider{
+		name:  "static",
+		pairs: map[string]map[string]float64{"BTC": {"USD": 100, "EUR": 90}, "ETH": {"EUR": 9}},
+	})
+	fx := &staticFX{latest: map[string]float64{"MXN": 20, "EUR": 0.8}}
+	updater.SetFXSource(fx)
+	updater.ReconfigureHistory(nil, []string{"USD", "MXN", "XYZ"})
+	require.Equal(t, []string{"MXN", "XYZ"}, updater.extraFiats)
+
+	updater.updateLast(context.Background())
+	require.Equal(t, 2000.0, updater.last["BTC"]["MXN"])
+	require.Equal(t, 2000.0, updater.last["TBTC"]["MXN"])
+	// Rates of the providers are not replaced.
+	require.Equal(t, 90.0, updater.last["BTC"]["EUR"])
+	// No USD rate to derive from.
+	require.NotContains(t, updater.last["ETH"], "MXN")
+	require.NotContains(t, updater.last["BTC"], "XYZ")
+
+	// The latest FX rates are cached.
+	updater.updateLast(context.Background())
+	require.Equal(t, 1, fx.calls)
+
+	health := updater.ProvidersHealth()
+	require.Len(t, health, 2)
+	require.Equal(t, "static-fx", health[1].Name)
+	require.Equal(t, 1, health[1].Successes)
+}
+
+func TestCrossRatesHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	updater := NewRateUpdater(nil, "/dev/null")
+	defer updater.Stop()
+	updater.SetFXSource(&staticFX{latest: map[string]float64{"MXN": 20}})
+	updater.crossPairs = map[string]bool{"btcMXN": true}
+	updater.history = map[string][]exchangeRate{
+		"btcUSD": {
+			{value: 100, timestamp: day(1)},
+			{value: 200, timestamp: day(2)},
+			{value: 300, timestamp: day(12)},
+		},
+		"usdMXN": {
+			{value: 10, timestamp: day(2)},
+			{value: 20, timestamp: day(3)},
+		},
+	}
+
+	require.Equal(t, 0.0, updater.HistoricalPriceAt("btc", "MXN", day(1)))
+	require.Equal(t, 2000.0, updater.HistoricalPriceAt("btc", "MXN", day(2)))
+	require.Equal(t, 205.0*15, updater.HistoricalPriceAt("btc", "MXN", day(2).Add(12*time.Hour)))
+	// The last FX rate is used for up to a week.
+	require.Equal(t, 250.0*20, updater.HistoricalPriceAt("btc", "MXN", day(7)))
+	require.Equal(t, 0.0, updater.HistoricalPriceAt("btc", "MXN", day(11)))
+	// The FX rates themselves are not derived.
+	require.Equal(t, 10.0, updater.HistoricalPriceAt(fxCoin, "MXN", day(2)))
+	require.Equal(t, 100.0, updater.HistoricalPriceAt("btc", "USD", day(1)))
+
+	require.Equal(t, day(2), updater.HistoryEarliestTimestamp("btc", "MXN"))
+	require.Equal(t, day(10), updater.HistoryLatestTimestamp("btc", "MXN"))
+	require.Equal(t, day(10), updater.HistoryLatestTimestampAll([]string{"btc"}, "MXN"))
+	require.True(t, updater.HistoryLatestTimestamp("eth", "MXN").IsZero())
+}
+
+func TestReconfigureHistoryCrossPairs(t *testing.T) {
+	updater := NewRateUpdater(nil, "/dev/null")
+	defer updater.Stop()
+	updater.SetProviders(&staticProvider{
+		name:  "static",
+		pairs: map[string]map[string]float64{"BTC": {"USD": 100, "EUR": 90}},
+	})
+	updater.SetFXSource(&staticFX{latest: map[string]float64{"MXN": 20, "EUR": 0.8}})
+	updater.ReconfigureHistory([]string{"btc", "ltc"}, []string{"EUR", "MXN"})
+
+	updater.historyMu.RLock()
+	defer updater.historyMu.RUnlock()
+	require.Equal(t, map[string]bool{"btcMXN": true}, updater.crossPairs)
+	require.Contains(t, updater.historyGo, "btcEUR")
+	require.Contains(t, updater.historyGo, "btcUSD")
+	require.Contains(t, updater.historyGo, "usdMXN")
+	require.NotContains(t, updater.historyGo, "btcMXN")
+	require.NotContains(t, updater.historyGo, "ltcMXN")
+}
+
+func TestFrankfurterFX(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "USD", r.URL.Query().Get("from"))
+		switch r.URL.Path {
+		case "/latest":
+			require.Equal(t, "MXN,INR", r.URL.Query().Get("to"))
+			fmt.Fprintln(w, `{"amount": 1.0, "base": "USD", "date": "2024-01-05", "rates": {"INR": 83.1, "MXN": 17.0}}`)
+		case "/2024-01-01..2024-01-05":
+			require.Equal(t, "MXN", r.URL.Query().Get("to"))
+			fmt.Fprintln(w, `{"amount": 1.0, "base": "USD", "rates": {
+				"2024-01-05": {"MXN": 17.2},
+				"2023-12-29": {"MXN": 16.9},
+				"2024-01-02": {"MXN": 17.0}
+			}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	source := NewFrankfurterFX(http.DefaultClient).(*frankfurterFX)
+	source.url = ts.URL
+	require.True(t, source.Supports("ZAR"))
+	require.False(t, source.Supports("XYZ"))
+
+	rates, err := source.LatestFXRates(context.Background(), []string{"USD", "MXN", "XYZ", "INR"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{"USD": 1, "MXN": 17, "INR": 83.1}, rates)
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	history, err := source.HistoricalFXRates(context.Background(), "MXN", day(1), day(5))
+	require.NoError(t, err)
+	require.Equal(t, []Rate{{Time: day(2), Value: 17}, {Time: day(5), Value: 17.2}}, history)
+
+	history, err = source.HistoricalFXRates(
+		context.Background(), "MXN", time.Date(1998, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1998, 3, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	_, err = source.HistoricalFXRates(context.Background(), "XYZ", day(1), day(5))
+	require.Error(t, err)
+}
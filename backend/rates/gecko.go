@@ -1,6 +1,17 @@
 package rates
 
-import "time"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/ratelimit"
+)
 
 const (
 	// See the following for docs and details: https://www.coingecko.com/en/api.
@@ -41,8 +52,15 @@ var (
 		"btc": "bitcoin",
 		"ltc": "litecoin",
 		"eth": "ethereum",
+		"pol": "polygon-ecosystem-token",
+		// Ether on L2 networks.
+		"arbeth":  "ethereum",
+		"opeth":   "ethereum",
+		"baseeth": "ethereum",
 		// Useful for testing with testnets.
 		"tbtc":   "bitcoin",
+		"tbtc4":  "bitcoin",
+		"sbtc":   "bitcoin",
 		"rbtc":   "bitcoin",
 		"tltc":   "litecoin",
 		"goeth":  "ethereum",
@@ -60,12 +78,24 @@ var (
 		"eth-erc20-paxg":      "pax-gold",
 	}
 
+	// The keys are BitBoxApp coin units.
+	// The values are CoinGecko coin codes.
+	unitToGeckoCoin = func() map[string]string {
+		result := map[string]string{}
+		for geckoCoin, unit := range geckoCoinToUnit {
+			result[unit] = geckoCoin
+		}
+		return result
+	}()
+
 	// The keys are CoinGecko coin codes.
 	// The values are BitBoxApp coin units.
 	geckoCoinToUnit = map[string]string{
 		"bitcoin":  "BTC",
 		"litecoin": "LTC",
 		"ethereum": "ETH",
+		// Native token of Polygon PoS.
+		"polygon-ecosystem-token": "POL",
 		// ERC20 tokens as used in the backend.
 		"basic-attention-token": "BAT",
 		"dai":                   "DAI",
@@ -126,3 +156,125 @@ var (
 		"czk": "CZK",
 	}
 )
+
+// coinGeckoProvider fetches exchange rates from the CoinGecko API or one of its mirrors.
+type coinGeckoProvider struct {
+	httpClient *http.Client
+	url        string
+	// All requests to url are rate-limited using limiter.
+	limiter *ratelimit.LimitedCall
+}
+
+// NewCoinGeckoProvider returns a provider using the CoinGecko API at the given URL, e.g.
+// https://api.coingecko.com/api/v3.
+func NewCoinGeckoProvider(client *http.Client, url string) Provider {
+	return &coinGeckoProvider{
+		httpClient: client,
+		url:        url,
+		limiter:    ratelimit.NewLimitedCall(apiRateLimit(url)),
+	}
+}
+
+// Name implements Provider.
+func (provider *coinGeckoProvider) Name() string {
+	return string(ProviderCoinGecko)
+}
+
+// Supports implements Provider.
+func (provider *coinGeckoProvider) Supports(coinUnit, fiat string) bool {
+	return unitToGeckoCoin[coinUnit] != "" && toGeckoFiat[fiat] != ""
+}
+
+// MaxHistoryRange implements Provider.
+func (provider *coinGeckoProvider) MaxHistoryRange() time.Duration {
+	return maxGeckoRange
+}
+
+// LatestRates implements Provider.
+func (provider *coinGeckoProvider) LatestRates(
+	ctx context.Context, coinUnits, fiats []string) (map[string]map[string]float64, error) {
+	ids := []string{}
+	for _, unit := range coinUnits {
+		if id := unitToGeckoCoin[unit]; id != "" {
+			ids = append(ids, id)
+		}
+	}
+	currencies := []string{}
+	for _, fiat := range fiats {
+		if geckoFiat := toGeckoFiat[fiat]; geckoFiat != "" {
+			currencies = append(currencies, geckoFiat)
+		}
+	}
+	param := url.Values{
+		"ids":           {strings.Join(ids, ",")},
+		"vs_currencies": {strings.Join(currencies, ",")},
+	}
+	endpoint := fmt.Sprintf("%s/simple/price?%s", provider.url, param.Encode())
+
+	var geckoRates map[string]map[string]float64
+	err := provider.limiter.Call(ctx, "updateLast", func() error {
+		return getJSON(ctx, provider.httpClient, endpoint, 10240, &geckoRates)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Convert the map with coingecko coin/fiat codes to a map of coin/fiat units.
+	rates := map[string]map[string]float64{}
+	for geckoCoin, val := range geckoRates {
+		coinUnit := geckoCoinToUnit[geckoCoin]
+		if coinUnit == "" {
+			continue
+		}
+		newVal := map[string]float64{}
+		for geckoFiat, rate := range val {
+			fiat, ok := fromGeckoFiat[geckoFiat]
+			if !ok {
+				continue
+			}
+			newVal[fiat] = rate
+		}
+		rates[coinUnit] = newVal
+	}
+	return rates, nil
+}
+
+// HistoricalRates implements Provider using CoinGecko's "market_chart/range" API.
+func (provider *coinGeckoProvider) HistoricalRates(
+	ctx context.Context, coinUnit, fiat string, start, end time.Time) ([]Rate, error) {
+	gcoin := unitToGeckoCoin[coinUnit]
+	if gcoin == "" {
+		return nil, errp.Newf("unsupported coin %s", coinUnit)
+	}
+	gfiat := toGeckoFiat[fiat]
+	if gfiat == "" {
+		return nil, errp.Newf("unsupported fiat %s", fiat)
+	}
+
+	// Make the call, abiding the upstream rate limits.
+	msg := fmt.Sprintf("fetch coingecko coin=%s fiat=%s start=%s", coinUnit, fiat, start)
+	var jsonBody struct{ Prices [][2]float64 } // [timestamp in milliseconds, value]
+	callErr := provider.limiter.Call(ctx, msg, func() error {
+		param := url.Values{
+			"from":        {strconv.FormatInt(start.Unix(), 10)},
+			"to":          {strconv.FormatInt(end.Unix(), 10)},
+			"vs_currency": {gfiat},
+		}
+		endpoint := fmt.Sprintf("%s/coins/%s/market_chart/range?%s", provider.url, gcoin, param.Encode())
+		// 1Mb is more than enough for a single response, but make sure initial
+		// download with empty cache fits here. See maxGeckoRange.
+		return getJSON(ctx, provider.httpClient, endpoint, 1<<20, &jsonBody)
+	})
+	if callErr != nil {
+		return nil, callErr
+	}
+
+	// Transform the response into a usable result.
+	rates := make([]Rate, len(jsonBody.Prices))
+	for i, v := range jsonBody.Prices {
+		rates[i] = Rate{
+			Value: v[1],
+			Time:  time.Unix(int64(v[0])/1000, 0), // local timezone
+		}
+	}
+	return rates, nil
+}
@@ -2,21 +2,19 @@ package rates
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
+	"errors"
 	"math/rand"
-	"net/http"
-	"net/url"
 	"sort"
-	"strconv"
 	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
 )
 
 // ReconfigureHistory resets all currently running historical rates goroutines.
 // The end result is only coin/fiat pairs present in the arguments are active.
-// Duplicate or unsupported values in coins and fiats are ignored.
-// Supported fiats are currently hardcoded in the unexported geckoFiat map in this package.
+// Duplicate or unsupported values in coins and fiats are ignored. A pair is supported if one of the
+// providers supports it, or if its rates can be derived from the rates of the coin in USD using
+// the fx source.
 func (updater *RateUpdater) ReconfigureHistory(coins, fiats []string) {
 	updater.log.Printf("ReconfigureHistory: coins=%q; fiats=%q", coins, fiats)
 	updater.historyMu.Lock()
@@ -26,36 +24,57 @@ func (updater *RateUpdater) ReconfigureHistory(coins, fiats []string) {
 		stop()
 		delete(updater.historyGo, key)
 	}
+	updater.crossPairs = make(map[string]bool)
+	updater.extraFiats = nil
+	for _, fiat := range fiats {
+		if _, ok := toGeckoFiat[fiat]; ok {
+			continue
+		}
+		if !containsString(updater.extraFiats, fiat) {
+			updater.extraFiats = append(updater.extraFiats, fiat)
+		}
+	}
 	// Enable those requested.
 	for _, coin := range coins {
-		if geckoCoin[coin] == "" {
+		if historyCoinUnit(coin) == "" {
 			updater.log.Errorf("ReconfigureHistory: unsupported coin %q", coin)
 			continue
 		}
 		for _, fiat := range fiats {
-			if toGeckoFiat[fiat] == "" {
-				updater.log.Errorf("ReconfigureHistory: unsupported fiat %q", fiat)
-				continue
+			switch {
+			case updater.supportsHistory(coin, fiat):
+				updater.startHistory(coin, fiat)
+			case updater.fx != nil && updater.fx.Supports(fiat) && updater.supportsHistory(coin, USD.String()):
+				updater.crossPairs[coin+fiat] = true
+				updater.startHistory(coin, USD.String())
+				updater.startHistory(fxCoin, fiat)
+			default:
+				updater.log.Errorf("ReconfigureHistory: unsupported pair %q/%q", coin, fiat)
 			}
-			key := coin + fiat
-			// The coins+fiats args may have duplicates.
-			if _, exists := updater.historyGo[key]; exists {
-				continue // already running
-			}
-			if rates, err := updater.loadHistoryBucket(key); err != nil {
-				// Non-critical: can continue without database cache.
-				updater.log.Errorf("loadHistoryBucket(%q): %v", key, err)
-			} else {
-				updater.history[key] = rates
-			}
-			ctx, cancel := context.WithCancel(context.Background())
-			updater.historyGo[key] = cancel
-			go updater.historyUpdateLoop(ctx, coin, fiat)
-			go updater.backfillHistory(ctx, coin, fiat)
 		}
 	}
 }
 
+// startHistory loads the historical rates of the pair from the database and starts the goroutines
+// updating them, unless they are already running. The historyMu lock must be held.
+func (updater *RateUpdater) startHistory(coin, fiat string) {
+	key := coin + fiat
+	// The coins+fiats args may have duplicates.
+	if _, exists := updater.historyGo[key]; exists {
+		return // already running
+	}
+	if rates, err := updater.loadHistoryBucket(key); err != nil {
+		// Non-critical: can continue without database cache.
+		updater.log.Errorf("loadHistoryBucket(%q): %v", key, err)
+	} else {
+		updater.history[key] = rates
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	updater.historyGo[key] = cancel
+	go updater.historyUpdateLoop(ctx, coin, fiat)
+	go updater.backfillHistory(ctx, coin, fiat)
+}
+
 // stopAllHistory shuts down all historical exchange rates goroutines.
 // It may return before the goroutines have exited.
 func (updater *RateUpdater) stopAllHistory() {
@@ -82,8 +101,8 @@ func (updater *RateUpdater) historyUpdateLoop(ctx context.Context, coin, fiat st
 				start: start,
 				end: func() time.Time {
 					// Make sure we're not requesting too much data at once.
-					if time.Since(start) > maxGeckoRange {
-						return start.Add(maxGeckoRange)
+					if maxRange := updater.maxHistoryRange(coin, fiat); time.Since(start) > maxRange {
+						return start.Add(maxRange)
 					}
 					return time.Now()
 				},
@@ -128,15 +147,18 @@ func (updater *RateUpdater) backfillHistory(ctx context.Context, coin, fiat stri
 			// First time; don't have historical data yet.
 			end = time.Now()
 			start = end.Add(-90*24*time.Hour + time.Hour) // +1h to be sure
+			if maxRange := updater.maxHistoryRange(coin, fiat); end.Sub(start) > maxRange {
+				start = end.Add(-maxRange)
+			}
 		} else {
 			// Use the max allowed range so that it fits response size limits.
 			end = end.Add(-24 * time.Hour)
-			start = end.Add(-maxGeckoRange)
+			start = end.Add(-updater.maxHistoryRange(coin, fiat))
 		}
 
 		n, err := updater.updateHistory(ctx, coin, fiat, fixedTimeRange(start, end))
 		switch {
-		// CoinGecko returns an empty list if we're too far back in history.
+		// The providers return an empty list if we're too far back in history.
 		// Use it to detect when to stop.
 		case err == nil && n == 0:
 			updater.log.Printf("backfillHistory for %s/%s: reached end of data at %s", coin, fiat, start)
@@ -165,7 +187,7 @@ func (updater *RateUpdater) backfillHistory(ctx context.Context, coin, fiat stri
 // for later use. It returns the number of the newly fetched and stored entries.
 // The data is stored in updater.history.
 func (updater *RateUpdater) updateHistory(ctx context.Context, coin, fiat string, t fetchTimeRange) (n int, err error) {
-	fetchedRates, err := updater.fetchGeckoMarketRange(ctx, coin, fiat, t)
+	fetchedRates, err := updater.fetchHistory(ctx, coin, fiat, t)
 	if err != nil {
 		return 0, err
 	}
@@ -193,6 +215,9 @@ func (updater *RateUpdater) HistoryLatestTimestamp(coin, fiat string) time.Time
 	key := coin + fiat
 	updater.historyMu.RLock()
 	defer updater.historyMu.RUnlock()
+	if updater.isCrossPair(coin, fiat) {
+		return updater.crossLatestTimestamp(coin, fiat)
+	}
 	var t time.Time
 	if n := len(updater.history[key]); n > 0 {
 		t = updater.history[key][n-1].timestamp
@@ -206,6 +231,9 @@ func (updater *RateUpdater) HistoryEarliestTimestamp(coin, fiat string) time.Tim
 	key := coin + fiat
 	updater.historyMu.RLock()
 	defer updater.historyMu.RUnlock()
+	if updater.isCrossPair(coin, fiat) {
+		return updater.crossEarliestTimestamp(coin, fiat)
+	}
 	var t time.Time
 	if len(updater.history[key]) > 0 {
 		t = updater.history[key][0].timestamp
@@ -241,59 +269,98 @@ func fixedTimeRange(start, end time.Time) fetchTimeRange {
 	}
 }
 
-// fetchGeckoMarketRange slurps historical exchange rates in the specified time range
-// using CoinGecko's "market_chart/range" API.
-func (updater *RateUpdater) fetchGeckoMarketRange(ctx context.Context, coin, fiat string, timeRange fetchTimeRange) ([]exchangeRate, error) {
-	// Prepare a request URL to call the upstream API.
-	gcoin := geckoCoin[coin]
-	if gcoin == "" {
-		return nil, fmt.Errorf("fetchGeckoMarketRange: unsupported coin %s", coin)
+// supportsHistory returns true if one of the providers has historical rates of the pair.
+func (updater *RateUpdater) supportsHistory(coin, fiat string) bool {
+	unit := historyCoinUnit(coin)
+	for _, provider := range updater.providers {
+		if provider.Supports(unit, fiat) {
+			return true
+		}
 	}
-	gfiat := toGeckoFiat[fiat]
-	if gfiat == "" {
-		return nil, fmt.Errorf("fetchGeckoMarketRange: unsupported fiat %s", fiat)
+	return false
+}
+
+// maxHistoryRange is the longest time range which can be fetched at once by the first provider
+// supporting the pair. Providers which can't fetch ranges this long are skipped in fetchHistory.
+func (updater *RateUpdater) maxHistoryRange(coin, fiat string) time.Duration {
+	if coin == fxCoin && updater.fx != nil {
+		return updater.fx.MaxHistoryRange()
+	}
+	unit := historyCoinUnit(coin)
+	for _, provider := range updater.providers {
+		if provider.Supports(unit, fiat) {
+			return provider.MaxHistoryRange()
+		}
 	}
+	return maxGeckoRange
+}
 
-	// Make the call, abiding the upstream rate limits.
-	msg := fmt.Sprintf("fetch coingecko coin=%s fiat=%s start=%s", coin, fiat, timeRange.start)
-	var jsonBody struct{ Prices [][2]float64 } // [timestamp in milliseconds, value]
-	callErr := updater.geckoLimiter.Call(ctx, msg, func() error {
-		param := url.Values{
-			"from":        {strconv.FormatInt(timeRange.start.Unix(), 10)},
-			"to":          {strconv.FormatInt(timeRange.end().Unix(), 10)},
-			"vs_currency": {gfiat},
+// fetchHistory fetches historical exchange rates in the specified time range from the first
+// provider which supports the pair and range and returns rates. If no provider returns rates, the
+// result is empty, unless one of them failed, in which case the error is returned so that the
+// range is fetched again later.
+func (updater *RateUpdater) fetchHistory(ctx context.Context, coin, fiat string, timeRange fetchTimeRange) ([]exchangeRate, error) {
+	if coin == fxCoin && updater.fx != nil {
+		rates, err := updater.fx.HistoricalFXRates(ctx, fiat, timeRange.start, timeRange.end())
+		if errors.Is(err, context.Canceled) {
+			return nil, context.Canceled
 		}
-		endpoint := fmt.Sprintf("%s/coins/%s/market_chart/range?%s", updater.coingeckoURL, gcoin, param.Encode())
-		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+		updater.recordProviderResult(updater.fx.Name(), err)
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		defer cancel()
-		res, err := updater.httpClient.Do(req.WithContext(ctx))
-		if err != nil {
-			return err
+		return toExchangeRates(rates), nil
+	}
+	unit := historyCoinUnit(coin)
+	if unit == "" {
+		return nil, errp.Newf("fetchHistory: unsupported coin %s", coin)
+	}
+	start, end := timeRange.start, timeRange.end()
+	var err error
+	supported := false
+	for _, provider := range updater.providers {
+		if !provider.Supports(unit, fiat) || end.Sub(start) > provider.MaxHistoryRange() {
+			continue
 		}
-		defer res.Body.Close() //nolint:errcheck
-		if res.StatusCode != http.StatusOK {
-			return fmt.Errorf("fetchGeckoMarketRange: bad response code %d", res.StatusCode)
+		supported = true
+		rates, providerErr := provider.HistoricalRates(ctx, unit, fiat, start, end)
+		if errors.Is(providerErr, context.Canceled) {
+			return nil, context.Canceled
 		}
-		// 1Mb is more than enough for a single response, but make sure initial
-		// download with empty cache fits here. See maxGeckoRange.
-		return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&jsonBody)
-	})
-	if callErr != nil {
-		return nil, callErr
+		updater.recordProviderResult(provider.Name(), providerErr)
+		if providerErr != nil {
+			updater.log.WithError(providerErr).Errorf("fetchHistory: provider %s", provider.Name())
+			err = providerErr
+			continue
+		}
+		if len(rates) == 0 {
+			// The provider may not go back this far, but the next one might.
+			continue
+		}
+		return toExchangeRates(rates), nil
+	}
+	if !supported {
+		return nil, errp.Newf("fetchHistory: unsupported pair %s/%s", coin, fiat)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return []exchangeRate{}, nil
+}
+
+func toExchangeRates(rates []Rate) []exchangeRate {
+	result := make([]exchangeRate, len(rates))
+	for i, rate := range rates {
+		result[i] = exchangeRate{value: rate.Value, timestamp: rate.Time}
+	}
+	return result
+}
 
-	// Transform the response into a usable result.
-	rates := make([]exchangeRate, len(jsonBody.Prices))
-	for i, v := range jsonBody.Prices {
-		rates[i] = exchangeRate{
-			value:     v[1],
-			timestamp: time.Unix(int64(v[0])/1000, 0), // local timezone
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
 	}
-	return rates, nil
+	return false
 }
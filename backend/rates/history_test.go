@@ -81,7 +81,7 @@ func TestUpdateHistory(t *testing.T) {
 	dbdir := test.TstTempDir("TestUpdateHistory")
 	defer os.RemoveAll(dbdir)
 	updater := NewRateUpdater(http.DefaultClient, dbdir)
-	updater.coingeckoURL = ts.URL
+	updater.SetCoingeckoURL(ts.URL)
 	updater.history = map[string][]exchangeRate{
 		"btcUSD": {
 			{value: 1.0, timestamp: time.Unix(1598832062, 0)}, // 2020-08-31 00:01:02
@@ -106,12 +106,12 @@ func TestUpdateHistory(t *testing.T) {
 
 	updater2 := NewRateUpdater(http.DefaultClient, dbdir)
 	defer updater2.Stop()
-	updater2.coingeckoURL = "unused"
+	updater2.SetCoingeckoURL("unused")
 	updater2.loadHistoryBucket("btcUSD")
 	assert.Equal(t, wantHistory, updater.history, "updater2.history")
 }
 
-func TestFetchGeckoMarketRangeInvalidCoinFiat(t *testing.T) {
+func TestCoinGeckoHistoricalRatesInvalidCoinFiat(t *testing.T) {
 	tt := []struct{ coin, fiat string }{
 		{"BTC", "invalid"},
 		{"BTC", ""},
@@ -120,10 +120,9 @@ func TestFetchGeckoMarketRangeInvalidCoinFiat(t *testing.T) {
 	}
 	for _, test := range tt {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-		var updater RateUpdater
-		g := fixedTimeRange(time.Now().Add(-time.Hour), time.Now())
-		_, err := updater.fetchGeckoMarketRange(ctx, test.coin, test.fiat, g)
-		assert.Error(t, err, "fetchGeckoMarketRange(%q, %q) returned nil error", test.coin, test.fiat)
+		provider := NewCoinGeckoProvider(nil, "unused")
+		_, err := provider.HistoricalRates(ctx, test.coin, test.fiat, time.Now().Add(-time.Hour), time.Now())
+		assert.Error(t, err, "HistoricalRates(%q, %q) returned nil error", test.coin, test.fiat)
 		cancel()
 	}
 }
@@ -207,7 +206,7 @@ func TestReconfigureHistoryLoadsFromDB(t *testing.T) {
 	updater1.Stop() // close dbdir so updater2 can load
 
 	updater2 := NewRateUpdater(http.DefaultClient, dbdir)
-	updater2.coingeckoURL = "unused" // avoid hitting real API
+	updater2.SetCoingeckoURL("unused") // avoid hitting real API
 	defer updater2.Stop()
 	updater2.ReconfigureHistory([]string{"btc"}, []string{"USD"})
 	// Loading from bbolt DB may result in unsorted slice.
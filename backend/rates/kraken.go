@@ -0,0 +1,265 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/ratelimit"
+)
+
+const (
+	// See https://docs.kraken.com/rest/#tag/Market-Data.
+	krakenAPI = "https://api.kraken.com/0/public"
+	// Kraken only returns the latest 720 OHLC entries of an interval.
+	krakenMaxEntries = 720
+)
+
+// krakenPair is a trading pair on Kraken. Kraken names pairs like "XBTUSD" in requests, but may
+// use a different key like "XXBTZUSD" in responses.
+type krakenPair struct {
+	name string
+	key  string
+}
+
+// krakenPairs contains the supported pairs, keyed by coin unit and fiat.
+var krakenPairs = map[string]map[string]krakenPair{
+	"BTC": {
+		"USD": {"XBTUSD", "XXBTZUSD"},
+		"EUR": {"XBTEUR", "XXBTZEUR"},
+		"GBP": {"XBTGBP", "XXBTZGBP"},
+		"CAD": {"XBTCAD", "XXBTZCAD"},
+		"JPY": {"XBTJPY", "XXBTZJPY"},
+		"CHF": {"XBTCHF", "XBTCHF"},
+		"AUD": {"XBTAUD", "XBTAUD"},
+	},
+	"ETH": {
+		"USD": {"ETHUSD", "XETHZUSD"},
+		"EUR": {"ETHEUR", "XETHZEUR"},
+		"GBP": {"ETHGBP", "XETHZGBP"},
+		"CAD": {"ETHCAD", "XETHZCAD"},
+		"JPY": {"ETHJPY", "XETHZJPY"},
+		"CHF": {"ETHCHF", "ETHCHF"},
+		"AUD": {"ETHAUD", "ETHAUD"},
+		"BTC": {"ETHXBT", "XETHXXBT"},
+	},
+	"LTC": {
+		"USD": {"LTCUSD", "XLTCZUSD"},
+		"EUR": {"LTCEUR", "XLTCZEUR"},
+		"BTC": {"LTCXBT", "XLTCXXBT"},
+	},
+	"POL": {
+		"USD": {"POLUSD", "POLUSD"},
+		"EUR": {"POLEUR", "POLEUR"},
+	},
+	"USDT": {
+		"USD": {"USDTUSD", "USDTZUSD"},
+		"EUR": {"USDTEUR", "USDTEUR"},
+	},
+	"USDC": {
+		"USD": {"USDCUSD", "USDCUSD"},
+		"EUR": {"USDCEUR", "USDCEUR"},
+	},
+	"DAI": {
+		"USD": {"DAIUSD", "DAIUSD"},
+		"EUR": {"DAIEUR", "DAIEUR"},
+	},
+	"LINK": {
+		"USD": {"LINKUSD", "LINKUSD"},
+		"EUR": {"LINKEUR", "LINKEUR"},
+	},
+}
+
+// krakenProvider fetches exchange rates from the public market data API of Kraken.
+//
+// Kraken only returns the latest 720 entries of historical data, so hourly rates are available for
+// the past 30 days and daily rates for the past two years.
+type krakenProvider struct {
+	httpClient *http.Client
+	url        string
+	limiter    *ratelimit.LimitedCall
+}
+
+// NewKrakenProvider returns a provider using the public Kraken API.
+func NewKrakenProvider(client *http.Client) Provider {
+	return &krakenProvider{
+		httpClient: client,
+		url:        krakenAPI,
+		limiter:    ratelimit.NewLimitedCall(apiRateLimit(krakenAPI)),
+	}
+}
+
+// Name implements Provider.
+func (provider *krakenProvider) Name() string {
+	return string(ProviderKraken)
+}
+
+// Supports implements Provider.
+func (provider *krakenProvider) Supports(coinUnit, fiat string) bool {
+	_, ok := krakenPairs[coinUnit][fiat]
+	return ok
+}
+
+// MaxHistoryRange implements Provider.
+func (provider *krakenProvider) MaxHistoryRange() time.Duration {
+	return krakenMaxEntries * time.Hour
+}
+
+// get calls the endpoint and returns the result of the response.
+func (provider *krakenProvider) get(ctx context.Context, msg string, endpoint string, maxSize int64) (
+	map[string]json.RawMessage, error) {
+	var response struct {
+		Error  []string                   `json:"error"`
+		Result map[string]json.RawMessage `json:"result"`
+	}
+	err := provider.limiter.Call(ctx, msg, func() error {
+		return getJSON(ctx, provider.httpClient, provider.url+endpoint, maxSize, &response)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Error) > 0 {
+		return nil, errp.Newf("kraken: %s", strings.Join(response.Error, ", "))
+	}
+	return response.Result, nil
+}
+
+// result returns the entry of the pair in the result of a response.
+func (pair krakenPair) result(result map[string]json.RawMessage) (json.RawMessage, bool) {
+	if value, ok := result[pair.key]; ok {
+		return value, true
+	}
+	value, ok := result[pair.name]
+	return value, ok
+}
+
+// LatestRates implements Provider.
+func (provider *krakenProvider) LatestRates(
+	ctx context.Context, coinUnits, fiats []string) (map[string]map[string]float64, error) {
+	type unitFiat struct{ unit, fiat string }
+	pairs := map[unitFiat]krakenPair{}
+	names := []string{}
+	for _, unit := range coinUnits {
+		for _, fiat := range fiats {
+			if pair, ok := krakenPairs[unit][fiat]; ok {
+				pairs[unitFiat{unit, fiat}] = pair
+				names = append(names, pair.name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return map[string]map[string]float64{}, nil
+	}
+	sort.Strings(names)
+	endpoint := "/Ticker?" + url.Values{"pair": {strings.Join(names, ",")}}.Encode()
+	result, err := provider.get(ctx, "latest rates", endpoint, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	rates := map[string]map[string]float64{}
+	for key, pair := range pairs {
+		raw, ok := pair.result(result)
+		if !ok {
+			continue
+		}
+		var ticker struct {
+			// Last trade closed: [price, lot volume].
+			C []string `json:"c"`
+		}
+		if err := json.Unmarshal(raw, &ticker); err != nil || len(ticker.C) == 0 {
+			return nil, errp.Newf("kraken: could not parse ticker of %s", pair.name)
+		}
+		price, err := strconv.ParseFloat(ticker.C[0], 64)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		if rates[key.unit] == nil {
+			rates[key.unit] = map[string]float64{}
+		}
+		rates[key.unit][key.fiat] = price
+	}
+	return rates, nil
+}
+
+// HistoricalRates implements Provider.
+func (provider *krakenProvider) HistoricalRates(
+	ctx context.Context, coinUnit, fiat string, start, end time.Time) ([]Rate, error) {
+	pair, ok := krakenPairs[coinUnit][fiat]
+	if !ok {
+		return nil, errp.Newf("kraken: unsupported pair %s/%s", coinUnit, fiat)
+	}
+	// Use the smallest interval which still covers the start.
+	var interval time.Duration
+	switch {
+	case time.Since(start) < krakenMaxEntries*time.Hour:
+		interval = time.Hour
+	case time.Since(start) < krakenMaxEntries*24*time.Hour:
+		interval = 24 * time.Hour
+	default:
+		return []Rate{}, nil
+	}
+	endpoint := "/OHLC?" + url.Values{
+		"pair":     {pair.name},
+		"interval": {strconv.Itoa(int(interval.Minutes()))},
+		"since":    {strconv.FormatInt(start.Add(-interval).Unix(), 10)},
+	}.Encode()
+	msg := fmt.Sprintf("fetch kraken pair=%s start=%s", pair.name, start)
+	result, err := provider.get(ctx, msg, endpoint, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := pair.result(result)
+	if !ok {
+		return nil, errp.Newf("kraken: missing OHLC data of %s", pair.name)
+	}
+	// [time, open, high, low, close, vwap, volume, count]
+	var entries [][]interface{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, errp.WithStack(err)
+	}
+	rates := []Rate{}
+	for _, entry := range entries {
+		if len(entry) < 5 {
+			return nil, errp.Newf("kraken: invalid OHLC entry of %s", pair.name)
+		}
+		timestamp, ok := entry[0].(float64)
+		if !ok {
+			return nil, errp.Newf("kraken: invalid OHLC time of %s", pair.name)
+		}
+		closeString, ok := entry[4].(string)
+		if !ok {
+			return nil, errp.Newf("kraken: invalid OHLC price of %s", pair.name)
+		}
+		price, err := strconv.ParseFloat(closeString, 64)
+		if err != nil {
+			return nil, errp.WithStack(err)
+		}
+		// The close price is at the end of the interval.
+		rates = append(rates, Rate{
+			Time:  time.Unix(int64(timestamp), 0).Add(interval),
+			Value: price,
+		})
+	}
+	return ratesInRange(rates, start, end), nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
+	"go.etcd.io/bbolt"
+)
+
+// MockPrices are the constant prices in USD used by MockRateUpdater, keyed by coin code.
+var MockPrices = map[string]float64{
+	"btc": 20000,
+	"ltc": 100,
+	"eth": 2000,
+}
+
+// mockUnits maps the coin codes of MockPrices to their units.
+var mockUnits = map[string]string{
+	"btc": "BTC",
+	"ltc": "LTC",
+	"eth": "ETH",
+}
+
+// MockRateUpdater returns a RateUpdater for tests, with the latest rates and hourly historical
+// rates from `from` until `until` using the constant MockPrices in USD. It does not fetch any
+// rates.
+func MockRateUpdater(from, until time.Time) *RateUpdater {
+	updater := &RateUpdater{
+		last:       make(map[string]map[string]float64),
+		history:    make(map[string][]exchangeRate),
+		historyGo:  make(map[string]context.CancelFunc),
+		historyDB:  &bbolt.DB{},
+		log:        logging.Get().WithGroup("rates"),
+		health:     make(map[string]*ProviderHealth),
+		crossPairs: make(map[string]bool),
+	}
+	for coin, price := range MockPrices {
+		updater.last[mockUnits[coin]] = map[string]float64{USD.String(): price}
+		history := []exchangeRate{}
+		for t := from.Truncate(time.Hour); !t.After(until); t = t.Add(time.Hour) {
+			history = append(history, exchangeRate{value: price, timestamp: t})
+		}
+		updater.history[coin+USD.String()] = history
+	}
+	return updater
+}
@@ -0,0 +1,245 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// Rate is an exchange rate at a point in time.
+type Rate struct {
+	Time  time.Time
+	Value float64
+}
+
+// Provider is a source of exchange rates. Coins are identified by their unit, e.g. "BTC" or
+// "USDT", and fiat currencies by their code, e.g. "USD".
+type Provider interface {
+	// Name identifies the provider in logs and in the health report.
+	Name() string
+	// Supports returns true if the provider has exchange rates of the coin in the fiat currency.
+	Supports(coinUnit, fiat string) bool
+	// LatestRates fetches the latest exchange rates of the given coins in the given fiat
+	// currencies, keyed by coin unit and fiat. Pairs missing in the result are not available.
+	LatestRates(ctx context.Context, coinUnits, fiats []string) (map[string]map[string]float64, error)
+	// HistoricalRates fetches the exchange rates of the coin in the fiat currency between start
+	// and end, sorted by time. An empty result means that the provider has no rates in this time
+	// range. The next provider is asked then, and if none has rates, the backfill of older rates
+	// stops.
+	HistoricalRates(ctx context.Context, coinUnit, fiat string, start, end time.Time) ([]Rate, error)
+	// MaxHistoryRange is the longest time range HistoricalRates can fetch at once.
+	MaxHistoryRange() time.Duration
+}
+
+// ProviderType identifies an implementation of Provider.
+type ProviderType string
+
+const (
+	// ProviderCoinGecko is the CoinGecko API or a mirror of it.
+	ProviderCoinGecko ProviderType = "coingecko"
+	// ProviderServer is a self-hosted price server, see serverProvider.
+	ProviderServer ProviderType = "server"
+	// ProviderKraken uses the public Kraken market data API.
+	ProviderKraken ProviderType = "kraken"
+	// ProviderBitstamp uses the public Bitstamp market data API.
+	ProviderBitstamp ProviderType = "bitstamp"
+	// ProviderCSV reads the rates from a local CSV file, see csvProvider.
+	ProviderCSV ProviderType = "csv"
+)
+
+// ProviderConfig configures an exchange rates provider.
+type ProviderConfig struct {
+	Type ProviderType `json:"type"`
+	// URL is the API endpoint of a CoinGecko mirror or of a self-hosted price server. Optional for
+	// CoinGecko, where it defaults to the mirror run by Shift Crypto.
+	URL string `json:"url,omitempty"`
+	// Path is the location of the file of a CSV provider.
+	Path string `json:"path,omitempty"`
+}
+
+// NewProvider creates the provider described by the config.
+func NewProvider(client *http.Client, config ProviderConfig) (Provider, error) {
+	switch config.Type {
+	case ProviderCoinGecko:
+		url := config.URL
+		if url == "" {
+			url = shiftGeckoMirrorAPIV3
+		}
+		return NewCoinGeckoProvider(client, url), nil
+	case ProviderServer:
+		if config.URL == "" {
+			return nil, errp.New("the price server needs a URL")
+		}
+		return NewServerProvider(client, config.URL), nil
+	case ProviderKraken:
+		return NewKrakenProvider(client), nil
+	case ProviderBitstamp:
+		return NewBitstampProvider(client), nil
+	case ProviderCSV:
+		if config.Path == "" {
+			return nil, errp.New("the CSV provider needs a file path")
+		}
+		return NewCSVProvider(config.Path), nil
+	default:
+		return nil, errp.Newf("unknown exchange rates provider type %q", config.Type)
+	}
+}
+
+// ProviderHealth reports the recent requests made to a provider.
+type ProviderHealth struct {
+	Name string `json:"name"`
+	// Healthy is false if the last request failed.
+	Healthy             bool       `json:"healthy"`
+	LastSuccess         *time.Time `json:"lastSuccess"`
+	LastFailure         *time.Time `json:"lastFailure"`
+	LastError           string     `json:"lastError,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Successes           int        `json:"successes"`
+	Failures            int        `json:"failures"`
+}
+
+// recordProviderResult updates the health of the provider or fx source with the given name after a
+// request.
+func (updater *RateUpdater) recordProviderResult(name string, err error) {
+	updater.healthMu.Lock()
+	defer updater.healthMu.Unlock()
+	health, ok := updater.health[name]
+	if !ok {
+		health = &ProviderHealth{Name: name, Healthy: true}
+		updater.health[name] = health
+	}
+	now := time.Now()
+	if err != nil {
+		health.Healthy = false
+		health.LastFailure = &now
+		health.LastError = err.Error()
+		health.ConsecutiveFailures++
+		health.Failures++
+		return
+	}
+	health.Healthy = true
+	health.LastSuccess = &now
+	health.ConsecutiveFailures = 0
+	health.Successes++
+}
+
+// ProvidersHealth returns the health of all providers, in fallback order, followed by the fx
+// source.
+func (updater *RateUpdater) ProvidersHealth() []ProviderHealth {
+	updater.healthMu.Lock()
+	defer updater.healthMu.Unlock()
+	var names []string
+	for _, provider := range updater.providers {
+		names = append(names, provider.Name())
+	}
+	if updater.fx != nil {
+		names = append(names, updater.fx.Name())
+	}
+	result := []ProviderHealth{}
+	for _, name := range names {
+		health, ok := updater.health[name]
+		if !ok {
+			// Not used yet.
+			result = append(result, ProviderHealth{Name: name, Healthy: true})
+			continue
+		}
+		result = append(result, *health)
+	}
+	return result
+}
+
+// SetProviders replaces the exchange rates providers. They are tried in the given order, falling
+// back to the next one if a provider fails or does not support a pair.
+//
+// SetProviders must be called before StartCurrentRates and ReconfigureHistory.
+func (updater *RateUpdater) SetProviders(providers ...Provider) {
+	updater.providers = providers
+}
+
+// historyCoinUnit returns the unit of the coin, identified by its code as used in the history of
+// exchange rates, e.g. "BTC" for "tbtc". Returns an empty string for unknown coins.
+func historyCoinUnit(coin string) string {
+	return geckoCoinToUnit[geckoCoin[coin]]
+}
+
+// allCoinUnits returns the units of all coins we fetch the latest exchange rates for.
+func allCoinUnits() []string {
+	units := make([]string, 0, len(geckoCoinToUnit))
+	for _, unit := range geckoCoinToUnit {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return units
+}
+
+// allFiats returns all fiat currencies we fetch the latest exchange rates in.
+func allFiats() []string {
+	fiats := make([]string, 0, len(toGeckoFiat))
+	for fiat := range toGeckoFiat {
+		fiats = append(fiats, fiat)
+	}
+	sort.Strings(fiats)
+	return fiats
+}
+
+// getJSON fetches the endpoint and decodes its JSON response into result. Responses larger than
+// maxSize bytes are rejected.
+func getJSON(ctx context.Context, client *http.Client, endpoint string, maxSize int64, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close() //nolint:errcheck
+	if res.StatusCode != http.StatusOK {
+		return errp.Newf("bad response code %d", res.StatusCode)
+	}
+	responseBody, err := io.ReadAll(io.LimitReader(res.Body, maxSize+1))
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	if int64(len(responseBody)) > maxSize {
+		return errp.Newf("response too long (> %d bytes)", maxSize)
+	}
+	if err := json.Unmarshal(responseBody, result); err != nil {
+		return errp.WithMessage(err,
+			fmt.Sprintf("could not parse response: %s", string(responseBody)))
+	}
+	return nil
+}
+
+// ratesInRange returns the rates between start and end. The rates must be sorted by time.
+func ratesInRange(rates []Rate, start, end time.Time) []Rate {
+	result := []Rate{}
+	for _, rate := range rates {
+		if !rate.Time.Before(start) && !rate.Time.After(end) {
+			result = append(result, rate)
+		}
+	}
+	return result
+}
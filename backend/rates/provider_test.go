@@ -0,0 +1,368 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/test"
+	"github.com/stretchr/testify/require"
+)
+
+// staticProvider is a provider with fixed rates for testing.
+type staticProvider struct {
+	name    string
+	pairs   map[string]map[string]float64
+	history []Rate
+	err     error
+	// maxRange is the MaxHistoryRange, 24h if not set.
+	maxRange time.Duration
+	// requested records the coin units of each LatestRates call.
+	requested [][]string
+}
+
+func (provider *staticProvider) Name() string { return provider.name }
+
+func (provider *staticProvider) Supports(coinUnit, fiat string) bool {
+	_, ok := provider.pairs[coinUnit][fiat]
+	return ok
+}
+
+func (provider *staticProvider) MaxHistoryRange() time.Duration {
+	if provider.maxRange == 0 {
+		return 24 * time.Hour
+	}
+	return provider.maxRange
+}
+
+func (provider *staticProvider) LatestRates(
+	_ context.Context, coinUnits, fiats []string) (map[string]map[string]float64, error) {
+	provider.requested = append(provider.requested, coinUnits)
+	if provider.err != nil {
+		return nil, provider.err
+	}
+	return provider.pairs, nil
+}
+
+func (provider *staticProvider) HistoricalRates(
+	_ context.Context, coinUnit, fiat string, start, end time.Time) ([]Rate, error) {
+	if provider.err != nil {
+		return nil, provider.err
+	}
+	return ratesInRange(provider.history, start, end), nil
+}
+
+func TestProviderFallback(t *testing.T) {
+	updater := NewRateUpdater(nil, "/dev/null")
+	defer updater.Stop()
+	failing := &staticProvider{
+		name:  "failing",
+		pairs: map[string]map[string]float64{"BTC": {"USD": 1}},
+		err:   errors.New("unavailable"),
+	}
+	first := &staticProvider{
+		name:  "first",
+		pairs: map[string]map[string]float64{"BTC": {"USD": 100, "EUR": 90}},
+		history: []Rate{
+			{Time: time.Unix(1000, 0), Value: 1},
+			{Time: time.Unix(2000, 0), Value: 2},
+		},
+	}
+	second := &staticProvider{
+		name:  "second",
+		pairs: map[string]map[string]float64{"BTC": {"USD": 200}, "ETH": {"USD": 10}},
+	}
+	updater.SetProviders(failing, first, second)
+	updater.SetFXSource(nil)
+
+	updater.updateLast(context.Background())
+	require.Equal(t, 100.0, updater.last["BTC"]["USD"])
+	require.Equal(t, 90.0, updater.last["BTC"]["EUR"])
+	require.Equal(t, 10.0, updater.last["ETH"]["USD"])
+	require.Equal(t, 100.0, updater.last["TBTC"]["USD"])
+	// The second provider is only asked for the pairs missing after the first.
+	require.Equal(t, [][]string{{"ETH"}}, second.requested)
+
+	require.True(t, updater.supportsHistory("btc", "EUR"))
+	require.False(t, updater.supportsHistory("ltc", "USD"))
+	require.Equal(t, 24*time.Hour, updater.maxHistoryRange("btc", "USD"))
+	n, err := updater.updateHistory(
+		context.Background(), "btc", "USD", fixedTimeRange(time.Unix(0, 0), time.Unix(1500, 0)))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 1.0, updater.HistoricalPriceAt("btc", "USD", time.Unix(1000, 0)))
+	_, err = updater.updateHistory(
+		context.Background(), "ltc", "USD", fixedTimeRange(time.Unix(0, 0), time.Unix(1500, 0)))
+	require.Error(t, err)
+
+	health := updater.ProvidersHealth()
+	require.Len(t, health, 3)
+	require.Equal(t, "failing", health[0].Name)
+	require.False(t, health[0].Healthy)
+	require.Equal(t, 2, health[0].ConsecutiveFailures)
+	require.Equal(t, "unavailable", health[0].LastError)
+	require.Nil(t, health[0].LastSuccess)
+	require.True(t, health[1].Healthy)
+	require.Equal(t, 2, health[1].Successes)
+	require.Equal(t, 1, health[2].Successes)
+
+	// No rates at all.
+	updater.SetProviders(failing)
+	updater.updateLast(context.Background())
+	require.Nil(t, updater.last)
+}
+
+func TestHistoryProviderFallback(t *testing.T) {
+	updater := NewRateUpdater(nil, "/dev/null")
+	defer updater.Stop()
+	shallow := &staticProvider{
+		name:     "shallow",
+		pairs:    map[string]map[string]float64{"BTC": {"USD": 1}},
+		history:  []Rate{{Time: time.Unix(100000, 0), Value: 3}},
+		maxRange: time.Hour,
+	}
+	deep := &staticProvider{
+		name:  "deep",
+		pairs: map[string]map[string]float64{"BTC": {"USD": 1}},
+		history: []Rate{
+			{Time: time.Unix(1000, 0), Value: 1},
+			{Time: time.Unix(2000, 0), Value: 2},
+		},
+		maxRange: 48 * time.Hour,
+	}
+	updater.SetProviders(shallow, deep)
+	updater.SetFXSource(nil)
+
+	// The range of the first provider is used, not the shortest one.
+	require.Equal(t, time.Hour, updater.maxHistoryRange("btc", "USD"))
+
+	// The shallow provider has no rates this far back, so the deep one is asked.
+	n, err := updater.updateHistory(
+		context.Background(), "btc", "USD", fixedTimeRange(time.Unix(0, 0), time.Unix(1500, 0)))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 1.0, updater.HistoricalPriceAt("btc", "USD", time.Unix(1000, 0)))
+
+	// Ranges longer than the shallow provider supports are only fetched from the deep one.
+	n, err = updater.updateHistory(
+		context.Background(), "btc", "USD", fixedTimeRange(time.Unix(1500, 0), time.Unix(100000, 0)))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 2.0, updater.HistoricalPriceAt("btc", "USD", time.Unix(2000, 0)))
+
+	// No provider has rates: the end of the data is reached.
+	n, err = updater.updateHistory(
+		context.Background(), "btc", "USD", fixedTimeRange(time.Unix(0, 0), time.Unix(500, 0)))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// If a provider fails, the range is retried later instead.
+	deep.err = errors.New("unavailable")
+	_, err = updater.updateHistory(
+		context.Background(), "btc", "USD", fixedTimeRange(time.Unix(0, 0), time.Unix(500, 0)))
+	require.Error(t, err)
+}
+
+func TestNewProvider(t *testing.T) {
+	for _, providerType := range []ProviderType{ProviderCoinGecko, ProviderKraken, ProviderBitstamp} {
+		provider, err := NewProvider(http.DefaultClient, ProviderConfig{Type: providerType})
+		require.NoError(t, err)
+		require.Equal(t, string(providerType), provider.Name())
+	}
+	_, err := NewProvider(http.DefaultClient, ProviderConfig{Type: ProviderServer})
+	require.Error(t, err)
+	_, err = NewProvider(http.DefaultClient, ProviderConfig{Type: ProviderCSV})
+	require.Error(t, err)
+	_, err = NewProvider(http.DefaultClient, ProviderConfig{Type: "unknown"})
+	require.Error(t, err)
+}
+
+func TestServerProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			require.Equal(t, "BTC,ETH", r.URL.Query().Get("coins"))
+			require.Equal(t, "USD", r.URL.Query().Get("fiats"))
+			fmt.Fprintln(w, `{"BTC": {"USD": 60000.5}}`)
+		case "/history":
+			require.Equal(t, "BTC", r.URL.Query().Get("coin"))
+			require.Equal(t, "USD", r.URL.Query().Get("fiat"))
+			require.Equal(t, "1000", r.URL.Query().Get("from"))
+			require.Equal(t, "2000", r.URL.Query().Get("to"))
+			fmt.Fprintln(w, `{"prices": [[1000, 1.5], [2000, 2.5]]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	provider := NewServerProvider(http.DefaultClient, ts.URL+"/")
+	require.True(t, provider.Supports("BTC", "CHF"))
+	rates, err := provider.LatestRates(context.Background(), []string{"BTC", "ETH"}, []string{"USD"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]float64{"BTC": {"USD": 60000.5}}, rates)
+	history, err := provider.HistoricalRates(
+		context.Background(), "BTC", "USD", time.Unix(1000, 0), time.Unix(2000, 0))
+	require.NoError(t, err)
+	require.Equal(t, []Rate{
+		{Time: time.Unix(1000, 0), Value: 1.5},
+		{Time: time.Unix(2000, 0), Value: 2.5},
+	}, history)
+}
+
+func TestKrakenProvider(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Ticker":
+			require.Equal(t, "ETHUSD,XBTUSD", r.URL.Query().Get("pair"))
+			fmt.Fprintln(w, `{"error": [], "result": {
+				"XXBTZUSD": {"c": ["60000.10000", "0.1"]},
+				"ETHUSD": {"c": ["3000.5", "1"]}
+			}}`)
+		case "/OHLC":
+			require.Equal(t, "XBTUSD", r.URL.Query().Get("pair"))
+			require.Equal(t, "60", r.URL.Query().Get("interval"))
+			fmt.Fprintf(w, `{"error": [], "result": {"XXBTZUSD": [
+				[%d, "1", "1", "1", "100.5", "1", "1", 1],
+				[%d, "1", "1", "1", "101.5", "1", "1", 1],
+				[%d, "1", "1", "1", "102.5", "1", "1", 1]
+			], "last": 0}}`,
+				now.Add(-3*time.Hour).Unix(), now.Add(-2*time.Hour).Unix(), now.Add(-time.Hour).Unix())
+		default:
+			fmt.Fprintln(w, `{"error": ["EQuery:Unknown asset pair"]}`)
+		}
+	}))
+	defer ts.Close()
+
+	provider := NewKrakenProvider(http.DefaultClient).(*krakenProvider)
+	provider.url = ts.URL
+	require.True(t, provider.Supports("BTC", "USD"))
+	require.False(t, provider.Supports("BTC", "KRW"))
+
+	rates, err := provider.LatestRates(context.Background(), []string{"BTC", "ETH", "BAT"}, []string{"USD", "KRW"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]float64{"BTC": {"USD": 60000.1}, "ETH": {"USD": 3000.5}}, rates)
+
+	// The close price is at the end of each interval. The last interval is not closed yet.
+	history, err := provider.HistoricalRates(
+		context.Background(), "BTC", "USD", now.Add(-3*time.Hour), now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []Rate{
+		{Time: now.Add(-2 * time.Hour), Value: 100.5},
+		{Time: now.Add(-time.Hour), Value: 101.5},
+	}, history)
+
+	// Too old.
+	history, err = provider.HistoricalRates(
+		context.Background(), "BTC", "USD", now.AddDate(-3, 0, 0), now.AddDate(-3, 0, 1))
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	_, err = provider.HistoricalRates(context.Background(), "BTC", "KRW", now.Add(-time.Hour), now)
+	require.Error(t, err)
+
+	provider.url = ts.URL + "/error"
+	_, err = provider.LatestRates(context.Background(), []string{"BTC"}, []string{"USD"})
+	require.Error(t, err)
+}
+
+func TestBitstampProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ticker/":
+			fmt.Fprintln(w, `[
+				{"pair": "BTC/USD", "last": "60000.5"},
+				{"pair": "BTC/EUR", "last": "55000"},
+				{"pair": "XRP/USD", "last": "0.5"}
+			]`)
+		case "/ohlc/btcusd/":
+			require.Equal(t, "3600", r.URL.Query().Get("step"))
+			require.Equal(t, "3", r.URL.Query().Get("limit"))
+			require.Equal(t, "10800", r.URL.Query().Get("end"))
+			fmt.Fprintln(w, `{"data": {"pair": "BTC/USD", "ohlc": [
+				{"timestamp": "0", "close": "100.5"},
+				{"timestamp": "3600", "close": "101.5"},
+				{"timestamp": "7200", "close": "102.5"}
+			]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	provider := NewBitstampProvider(http.DefaultClient).(*bitstampProvider)
+	provider.url = ts.URL
+	require.True(t, provider.Supports("ETH", "BTC"))
+	require.False(t, provider.Supports("BTC", "CHF"))
+
+	rates, err := provider.LatestRates(context.Background(), []string{"BTC"}, []string{"USD"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]float64{"BTC": {"USD": 60000.5}}, rates)
+
+	history, err := provider.HistoricalRates(
+		context.Background(), "BTC", "USD", time.Unix(3600, 0), time.Unix(3*3600, 0))
+	require.NoError(t, err)
+	require.Equal(t, []Rate{
+		{Time: time.Unix(3600, 0), Value: 100.5},
+		{Time: time.Unix(7200, 0), Value: 101.5},
+		{Time: time.Unix(10800, 0), Value: 102.5},
+	}, history)
+}
+
+func TestCSVProvider(t *testing.T) {
+	dir := test.TstTempDir("TestCSVProvider")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rates.csv")
+	require.NoError(t, os.WriteFile(path, []byte(
+		"time,coin,fiat,price\n"+
+			"2024-01-02T00:00:00Z,BTC,USD,43000\n"+
+			"2024-01-01T00:00:00Z,btc,usd,42000.5\n"+
+			"1704153600,ETH,EUR,2000\n"), 0600))
+
+	provider := NewCSVProvider(path)
+	require.True(t, provider.Supports("BTC", "USD"))
+	require.False(t, provider.Supports("BTC", "EUR"))
+
+	rates, err := provider.LatestRates(context.Background(), []string{"BTC", "ETH"}, []string{"USD", "EUR"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]float64{"BTC": {"USD": 43000}, "ETH": {"EUR": 2000}}, rates)
+
+	history, err := provider.HistoricalRates(context.Background(), "BTC", "USD",
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, []Rate{{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Value: 42000.5}}, history)
+
+	// The file is read again after it changed.
+	require.NoError(t, os.WriteFile(path, []byte("2024-01-03T00:00:00Z,BTC,USD,44000\n"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Hour)))
+	rates, err = provider.LatestRates(context.Background(), []string{"BTC"}, []string{"USD"})
+	require.NoError(t, err)
+	require.Equal(t, 44000.0, rates["BTC"]["USD"])
+
+	require.NoError(t, os.WriteFile(path, []byte("invalid,BTC,USD,1\n"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Hour)))
+	_, err = provider.LatestRates(context.Background(), []string{"BTC"}, []string{"USD"})
+	require.Error(t, err)
+	require.False(t, NewCSVProvider(filepath.Join(dir, "missing.csv")).Supports("BTC", "USD"))
+}
@@ -17,11 +17,7 @@ package rates
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"reflect"
 	"sort"
 	"sync"
@@ -31,15 +27,11 @@ import (
 	"github.com/digitalbitbox/bitbox-wallet-app/util/logging"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
 	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
-	"github.com/digitalbitbox/bitbox-wallet-app/util/ratelimit"
 	"github.com/sirupsen/logrus"
 	"go.etcd.io/bbolt"
 )
 
 const (
-	// Latest rates are fetched for all these (coin, fiat) pairs.
-	simplePriceAllIDs        = "bitcoin,litecoin,ethereum,basic-attention-token,dai,chainlink,maker,usd-coin,tether,0x,wrapped-bitcoin,pax-gold"
-	simplePriceAllCurrencies = "usd,eur,chf,gbp,jpy,krw,cny,rub,cad,aud,ils,btc,sgd,hkd,brl,nok,sek,pln,czk"
 	// RatesEventSubject is the Subject of the event generated by new rates fetching.
 	RatesEventSubject = "rates"
 
@@ -49,6 +41,17 @@ const (
 
 const interval = time.Minute
 
+// testnetUnits maps testnet coin units to the mainnet units whose rates they use.
+var testnetUnits = map[string]string{
+	"TBTC":   "BTC",
+	"TBTC4":  "BTC",
+	"SBTC":   "BTC",
+	"RBTC":   "BTC",
+	"TLTC":   "LTC",
+	"GOETH":  "ETH",
+	"SEPETH": "ETH",
+}
+
 type exchangeRate struct {
 	value     float64
 	timestamp time.Time
@@ -111,11 +114,24 @@ type RateUpdater struct {
 	// For example, BTC/EUR pair's key is "btcEUR".
 	historyGo map[string]context.CancelFunc
 
-	// CoinGecko is where updater gets the historical conversion rates.
-	// See https://www.coingecko.com/en/api for details.
-	coingeckoURL string
-	// All requests to coingeckoURL are rate-limited using geckoLimiter.
-	geckoLimiter *ratelimit.LimitedCall
+	// crossPairs contains the coin+fiat pairs whose rates are derived from the rates of the coin
+	// in USD and the USD/fiat rates of the fx source. Guarded by historyMu.
+	crossPairs map[string]bool
+	// extraFiats are the enabled fiats which are not fetched by default. Their latest rates are
+	// fetched from the providers, or derived using the fx source. Guarded by historyMu.
+	extraFiats []string
+
+	// providers are the sources of exchange rates, in fallback order. See SetProviders.
+	providers []Provider
+	// fx is the source of fiat-to-fiat exchange rates. See SetFXSource.
+	fx FXSource
+	// fxLast contains the latest USD/fiat rates of fx, keyed by fiat, fetched at fxLastUpdated.
+	// Only used by updateLast.
+	fxLast        map[string]float64
+	fxLastUpdated time.Time
+	healthMu      sync.Mutex // guards health
+	// health of the providers, keyed by provider name.
+	health map[string]*ProviderHealth
 }
 
 // NewRateUpdater returns a new rates updater.
@@ -142,22 +158,23 @@ func NewRateUpdater(client *http.Client, dbdir string) *RateUpdater {
 		// An unopened DB will simply return bbolt.ErrDatabaseNotOpen on all operations.
 		db = &bbolt.DB{}
 	}
-	apiURL := shiftGeckoMirrorAPIV3
 	return &RateUpdater{
-		last:         make(map[string]map[string]float64),
-		history:      make(map[string][]exchangeRate),
-		historyGo:    make(map[string]context.CancelFunc),
-		historyDB:    db,
-		log:          log,
-		httpClient:   client,
-		coingeckoURL: apiURL,
-		geckoLimiter: ratelimit.NewLimitedCall(apiRateLimit(apiURL)),
+		last:       make(map[string]map[string]float64),
+		history:    make(map[string][]exchangeRate),
+		historyGo:  make(map[string]context.CancelFunc),
+		historyDB:  db,
+		log:        log,
+		httpClient: client,
+		crossPairs: make(map[string]bool),
+		providers:  []Provider{NewCoinGeckoProvider(client, shiftGeckoMirrorAPIV3)},
+		fx:         NewFrankfurterFX(client),
+		health:     make(map[string]*ProviderHealth),
 	}
 }
 
-// SetCoingeckoURL overrides the default URL the rates updater connects to. Useful for testing.
+// SetCoingeckoURL replaces the providers by CoinGecko at the given URL. Useful for testing.
 func (updater *RateUpdater) SetCoingeckoURL(url string) {
-	updater.coingeckoURL = url
+	updater.SetProviders(NewCoinGeckoProvider(updater.httpClient, url))
 }
 
 // LatestPrice returns the most recent conversion rates.
@@ -185,10 +202,21 @@ func (updater *RateUpdater) LatestPriceForPair(coinUnit, fiat string) (float64,
 // If no data is available with the given args, HistoricalPriceAt returns 0.
 // The latest rates can lag behind by many minutes (5-30min). Use `LatestPrice` get the latest
 // rates.
+//
+// Rates in fiats not supported by the providers are derived from the rates in USD, see
+// SetFXSource.
 func (updater *RateUpdater) HistoricalPriceAt(coin, fiat string, at time.Time) float64 {
 	updater.historyMu.RLock()
 	defer updater.historyMu.RUnlock()
-	data := updater.history[coin+fiat]
+	if updater.isCrossPair(coin, fiat) {
+		return priceAt(updater.history[coin+USD.String()], at) * updater.fxAt(fiat, at)
+	}
+	return priceAt(updater.history[coin+fiat], at)
+}
+
+// priceAt returns the rate at the given time, interpolating between the rates of data, which must
+// be sorted by time. Returns 0 if at is outside of the range of data.
+func priceAt(data []exchangeRate, at time.Time) float64 {
 	if len(data) == 0 {
 		return 0 // no data at all
 	}
@@ -257,80 +285,74 @@ func (updater *RateUpdater) lastUpdateLoop(ctx context.Context) {
 	}
 }
 
+// updateLast fetches the latest rates of all coins in all fiats. Each provider is asked for the
+// pairs not provided by the previous providers. The rates in the remaining fiats are derived from
+// the rates in USD.
 func (updater *RateUpdater) updateLast(ctx context.Context) {
-	param := url.Values{
-		"ids":           {simplePriceAllIDs},
-		"vs_currencies": {simplePriceAllCurrencies},
-	}
-	endpoint := fmt.Sprintf("%s/simple/price?%s", updater.coingeckoURL, param.Encode())
-	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
-	if err != nil {
-		updater.log.WithError(err).Error("could not create request")
-		updater.last = nil
-		return
-	}
-
-	var geckoRates map[string]map[string]float64
-	callErr := updater.geckoLimiter.Call(ctx, "updateLast", func() error {
-		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-		defer cancel()
-		res, err := updater.httpClient.Do(req.WithContext(ctx))
-		if err != nil {
-			return errp.WithStack(err)
-		}
-		defer res.Body.Close() //nolint:errcheck
-		if res.StatusCode != http.StatusOK {
-			return errp.Newf("bad response code %d", res.StatusCode)
+	coinUnits, fiats := allCoinUnits(), allFiats()
+	updater.historyMu.RLock()
+	fiats = append(fiats, updater.extraFiats...)
+	updater.historyMu.RUnlock()
+	rates := map[string]map[string]float64{}
+	for _, provider := range updater.providers {
+		// Only ask for the coins and fiats of missing pairs supported by the provider.
+		missingUnits, missingFiats := []string{}, []string{}
+		missingFiat := map[string]bool{}
+		for _, unit := range coinUnits {
+			missing := false
+			for _, fiat := range fiats {
+				if _, ok := rates[unit][fiat]; ok || !provider.Supports(unit, fiat) {
+					continue
+				}
+				missing = true
+				missingFiat[fiat] = true
+			}
+			if missing {
+				missingUnits = append(missingUnits, unit)
+			}
 		}
-		const max = 10240
-		responseBody, err := io.ReadAll(io.LimitReader(res.Body, max+1))
-		if err != nil {
-			return errp.WithStack(err)
+		for _, fiat := range fiats {
+			if missingFiat[fiat] {
+				missingFiats = append(missingFiats, fiat)
+			}
 		}
-		if len(responseBody) > max {
-			return errp.Newf("rates response too long (> %d bytes)", max)
+		if len(missingUnits) == 0 {
+			continue
 		}
-		if err := json.Unmarshal(responseBody, &geckoRates); err != nil {
-			return errp.WithMessage(err,
-				fmt.Sprintf("could not parse rates response: %s", string(responseBody)))
+		providerRates, err := provider.LatestRates(ctx, missingUnits, missingFiats)
+		if ctx.Err() != nil {
+			return
 		}
-		return nil
-	})
-	if callErr != nil {
-		updater.log.WithError(callErr).Errorf("updateLast")
-		updater.last = nil
-		return
-	}
-	// Convert the map with coingecko coin/fiat codes to a map of coin/fiat units.
-	rates := map[string]map[string]float64{}
-	for coin, val := range geckoRates {
-		coinUnit := geckoCoinToUnit[coin]
-		if coinUnit == "" {
-			updater.log.Errorf("unsupported CoinGecko coin: %s", coin)
+		updater.recordProviderResult(provider.Name(), err)
+		if err != nil {
+			updater.log.WithError(err).Errorf("updateLast: provider %s", provider.Name())
 			continue
 		}
-		newVal := map[string]float64{}
-		for geckoFiat, rates := range val {
-			fiat, ok := fromGeckoFiat[geckoFiat]
-			if !ok {
-				updater.log.Errorf("unsupported fiat: %s", geckoFiat)
-				continue
+		for unit, unitRates := range providerRates {
+			for fiat, rate := range unitRates {
+				if _, ok := rates[unit][fiat]; ok {
+					continue
+				}
+				if rates[unit] == nil {
+					rates[unit] = map[string]float64{}
+				}
+				rates[unit][fiat] = rate
 			}
-			newVal[fiat] = rates
 		}
-		rates[coinUnit] = newVal
+	}
+	updater.deriveLatestFXRates(ctx, rates, fiats)
+	if ctx.Err() != nil {
+		return
+	}
+	if len(rates) == 0 {
+		updater.log.Error("updateLast: no rates available")
+		updater.last = nil
+		return
 	}
 
 	// Provide conversion rates for testnets as well, useful for testing.
-	for _, testnetUnit := range []string{"TBTC", "RBTC", "TLTC", "GOETH", "SEPETH"} {
-		switch testnetUnit {
-		case "GOETH":
-			rates[testnetUnit] = rates[testnetUnit[2:]]
-		case "SEPETH":
-			rates[testnetUnit] = rates[testnetUnit[3:]]
-		default:
-			rates[testnetUnit] = rates[testnetUnit[1:]]
-		}
+	for testnetUnit, mainnetUnit := range testnetUnits {
+		rates[testnetUnit] = rates[mainnetUnit]
 	}
 
 	if reflect.DeepEqual(rates, updater.last) {
@@ -0,0 +1,112 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/util/ratelimit"
+)
+
+// serverProvider fetches exchange rates from a self-hosted price server, which implements two
+// endpoints using the coin units and fiat codes of the app:
+//
+//	GET <url>/latest?coins=BTC,ETH&fiats=USD,EUR
+//	{"BTC": {"USD": 60000.5, "EUR": 55000.1}, "ETH": {"USD": 3000.2}}
+//
+//	GET <url>/history?coin=BTC&fiat=USD&from=<unix seconds>&to=<unix seconds>
+//	{"prices": [[<unix seconds>, 60000.5], ...]}
+//
+// The server is assumed to have rates of all pairs. Missing ones are simply left out.
+type serverProvider struct {
+	httpClient *http.Client
+	url        string
+	limiter    *ratelimit.LimitedCall
+}
+
+// NewServerProvider returns a provider using the self-hosted price server at the given URL.
+func NewServerProvider(client *http.Client, url string) Provider {
+	return &serverProvider{
+		httpClient: client,
+		url:        strings.TrimSuffix(url, "/"),
+		limiter:    ratelimit.NewLimitedCall(apiRateLimit(url)),
+	}
+}
+
+// Name implements Provider.
+func (provider *serverProvider) Name() string {
+	return string(ProviderServer)
+}
+
+// Supports implements Provider.
+func (provider *serverProvider) Supports(coinUnit, fiat string) bool {
+	return true
+}
+
+// MaxHistoryRange implements Provider.
+func (provider *serverProvider) MaxHistoryRange() time.Duration {
+	return maxGeckoRange
+}
+
+// LatestRates implements Provider.
+func (provider *serverProvider) LatestRates(
+	ctx context.Context, coinUnits, fiats []string) (map[string]map[string]float64, error) {
+	param := url.Values{
+		"coins": {strings.Join(coinUnits, ",")},
+		"fiats": {strings.Join(fiats, ",")},
+	}
+	endpoint := fmt.Sprintf("%s/latest?%s", provider.url, param.Encode())
+	var rates map[string]map[string]float64
+	err := provider.limiter.Call(ctx, "latest rates", func() error {
+		return getJSON(ctx, provider.httpClient, endpoint, 10240, &rates)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// HistoricalRates implements Provider.
+func (provider *serverProvider) HistoricalRates(
+	ctx context.Context, coinUnit, fiat string, start, end time.Time) ([]Rate, error) {
+	param := url.Values{
+		"coin": {coinUnit},
+		"fiat": {fiat},
+		"from": {strconv.FormatInt(start.Unix(), 10)},
+		"to":   {strconv.FormatInt(end.Unix(), 10)},
+	}
+	endpoint := fmt.Sprintf("%s/history?%s", provider.url, param.Encode())
+	var jsonBody struct {
+		Prices [][2]float64 `json:"prices"`
+	}
+	msg := fmt.Sprintf("fetch history coin=%s fiat=%s start=%s", coinUnit, fiat, start)
+	err := provider.limiter.Call(ctx, msg, func() error {
+		return getJSON(ctx, provider.httpClient, endpoint, 1<<20, &jsonBody)
+	})
+	if err != nil {
+		return nil, err
+	}
+	rates := make([]Rate, len(jsonBody.Prices))
+	for i, v := range jsonBody.Prices {
+		rates[i] = Rate{Time: time.Unix(int64(v[0]), 0), Value: v[1]}
+	}
+	return rates, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// AccountTransaction is a transaction found by `SearchTransactions()`, together with the account it
+// belongs to.
+type AccountTransaction struct {
+	Account     accounts.Interface
+	Transaction *accounts.TransactionData
+}
+
+// cursor identifies the search result across accounts.
+func (accountTx AccountTransaction) cursor() string {
+	return fmt.Sprintf("%s/%s", accountTx.Account.Config().Config.Code, accountTx.Transaction.InternalID)
+}
+
+// SearchTransactions searches the transactions of all active accounts. See
+// `accounts.OrderedTransactions.Search()` for the filter and the pagination.
+//
+// The amounts of different coins can't be compared, so filtering and sorting by amount is not
+// supported. Results can be sorted by time only, where transactions without a time count as the
+// newest ones.
+func (backend *Backend) SearchTransactions(
+	filter *accounts.TransactionsFilter,
+	sortBy accounts.TransactionsSort,
+	cursor string,
+	limit int,
+) ([]AccountTransaction, string, error) {
+	if filter.HasAmountRange() {
+		return nil, "", errp.New("Filtering by amount is only possible within an account")
+	}
+	switch sortBy {
+	case "", accounts.TransactionsSortNewest, accounts.TransactionsSortOldest:
+	default:
+		return nil, "", errp.Newf("Unsupported sort order across accounts: %s", sortBy)
+	}
+
+	result := []AccountTransaction{}
+	for _, account := range backend.Accounts() {
+		if account.Config().Config.Inactive || account.FatalError() {
+			continue
+		}
+		if err := account.Initialize(); err != nil {
+			return nil, "", err
+		}
+		txs, err := account.Transactions()
+		if err != nil {
+			return nil, "", err
+		}
+		page, err := txs.Search(filter, accounts.TransactionsSortNewest, "", 0, account.TxNote)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, tx := range page.Transactions {
+			result = append(result, AccountTransaction{Account: account, Transaction: tx})
+		}
+	}
+
+	// Stable, so that transactions of the same time keep their order.
+	sort.SliceStable(result, func(i, j int) bool {
+		timeI, timeJ := result[i].Transaction.Time(), result[j].Transaction.Time()
+		switch {
+		case timeI == nil:
+			return timeJ != nil
+		case timeJ == nil:
+			return false
+		default:
+			return timeI.After(*timeJ)
+		}
+	})
+	if sortBy == accounts.TransactionsSortOldest {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+	return accounts.Paginate(result, AccountTransaction.cursor, cursor, limit)
+}
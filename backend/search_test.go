@@ -0,0 +1,104 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchTransactions(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	makeAccount := func(code accountsTypes.Code, inactive bool, txs accounts.OrderedTransactions) accounts.Interface {
+		cfg := &accounts.AccountConfig{Config: &config.Account{Code: code, Inactive: inactive}}
+		return &accountsMocks.InterfaceMock{
+			ConfigFunc:       func() *accounts.AccountConfig { return cfg },
+			FatalErrorFunc:   func() bool { return false },
+			InitializeFunc:   func() error { return nil },
+			TransactionsFunc: func() (accounts.OrderedTransactions, error) { return txs, nil },
+			CloseFunc:        func() {},
+			TxNoteFunc: func(internalID string) string {
+				if internalID == "btc-2" {
+					return "rent"
+				}
+				return ""
+			},
+		}
+	}
+	b.accounts = AccountsList{
+		makeAccount("btc", false, accounts.OrderedTransactions{
+			{InternalID: "btc-pending", Type: accounts.TxTypeSend, Amount: coinpkg.NewAmountFromInt64(1)},
+			{InternalID: "btc-2", Type: accounts.TxTypeSend, Timestamp: day(4), Amount: coinpkg.NewAmountFromInt64(1)},
+			{InternalID: "btc-1", Type: accounts.TxTypeReceive, Timestamp: day(1), Amount: coinpkg.NewAmountFromInt64(1)},
+		}),
+		makeAccount("eth", false, accounts.OrderedTransactions{
+			{InternalID: "eth-2", Type: accounts.TxTypeReceive, Timestamp: day(3), Amount: coinpkg.NewAmountFromInt64(1)},
+			{InternalID: "eth-1", Type: accounts.TxTypeSend, Timestamp: day(2), Amount: coinpkg.NewAmountFromInt64(1)},
+		}),
+		makeAccount("inactive", true, accounts.OrderedTransactions{
+			{InternalID: "inactive-1", Type: accounts.TxTypeSend, Timestamp: day(2), Amount: coinpkg.NewAmountFromInt64(1)},
+		}),
+	}
+	ids := func(results []AccountTransaction) []string {
+		result := []string{}
+		for _, r := range results {
+			result = append(result, r.cursor())
+		}
+		return result
+	}
+
+	results, cursor, err := b.SearchTransactions(&accounts.TransactionsFilter{}, "", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, "", cursor)
+	require.Equal(t,
+		[]string{"btc/btc-pending", "btc/btc-2", "eth/eth-2", "eth/eth-1", "btc/btc-1"},
+		ids(results))
+
+	results, cursor, err = b.SearchTransactions(
+		&accounts.TransactionsFilter{Types: []accounts.TxType{accounts.TxTypeSend}},
+		accounts.TransactionsSortOldest, "", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"eth/eth-1", "btc/btc-2"}, ids(results))
+	require.Equal(t, "btc/btc-2", cursor)
+	results, cursor, err = b.SearchTransactions(
+		&accounts.TransactionsFilter{Types: []accounts.TxType{accounts.TxTypeSend}},
+		accounts.TransactionsSortOldest, cursor, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"btc/btc-pending"}, ids(results))
+	require.Equal(t, "", cursor)
+
+	results, _, err = b.SearchTransactions(&accounts.TransactionsFilter{Note: "RENT"}, "", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"btc/btc-2"}, ids(results))
+
+	minAmount := coinpkg.NewAmountFromInt64(1)
+	_, _, err = b.SearchTransactions(&accounts.TransactionsFilter{MinAmount: &minAmount}, "", "", 0)
+	require.Error(t, err)
+	_, _, err = b.SearchTransactions(&accounts.TransactionsFilter{}, accounts.TransactionsSortAmountDesc, "", 0)
+	require.Error(t, err)
+}
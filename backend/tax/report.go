@@ -0,0 +1,386 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tax
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math/big"
+	"time"
+
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// FormatAmountFunc formats an amount of the coin, given in its smallest unit.
+type FormatAmountFunc func(coinCode coin.Code, amount *big.Int) string
+
+// ReportParams configures a yearly report.
+type ReportParams struct {
+	Year   int
+	Fiat   string
+	Method Method
+	// AccountCode restricts the report to one account. If empty, the report covers all accounts.
+	AccountCode accountsTypes.Code
+	// Price returns the price at the time of acquisitions and disposals.
+	Price PriceFunc
+	// MarketPrice returns the price used to value the holdings at the end of the year.
+	MarketPrice PriceFunc
+	// FormatAmount formats coin amounts in the report.
+	FormatAmount FormatAmountFunc
+}
+
+// Holding is a lot held at the end of the year, valued at the market price.
+type Holding struct {
+	Lot
+	MarketValue    *big.Rat
+	UnrealizedGain *big.Rat
+	// MarketPriceMissing is true if the market price is not available and the market value is
+	// counted as zero.
+	MarketPriceMissing bool
+}
+
+// Totals sums up a report.
+type Totals struct {
+	Proceeds      *big.Rat
+	CostBasis     *big.Rat
+	RealizedGain  *big.Rat
+	ShortTermGain *big.Rat
+	LongTermGain  *big.Rat
+	// MarketValue, HoldingsCostBasis and UnrealizedGain are about the holdings at the end of the
+	// year.
+	MarketValue       *big.Rat
+	HoldingsCostBasis *big.Rat
+	UnrealizedGain    *big.Rat
+}
+
+func newTotals() Totals {
+	return Totals{
+		Proceeds:          new(big.Rat),
+		CostBasis:         new(big.Rat),
+		RealizedGain:      new(big.Rat),
+		ShortTermGain:     new(big.Rat),
+		LongTermGain:      new(big.Rat),
+		MarketValue:       new(big.Rat),
+		HoldingsCostBasis: new(big.Rat),
+		UnrealizedGain:    new(big.Rat),
+	}
+}
+
+// Report is the yearly capital gains report of one account or of the whole portfolio.
+type Report struct {
+	params ReportParams
+
+	Disposals []*Disposal
+	Holdings  []*Holding
+	Totals    Totals
+	// Incomplete is true if a price is missing, so that some values are counted as zero.
+	Incomplete bool
+}
+
+// yearBounds returns the start and the end of the year in UTC.
+func yearBounds(year int) (time.Time, time.Time) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// NewReport computes the lots from the events up to the end of the year and reports the
+// disposals of the year and the holdings at the end of the year.
+//
+// The events of all accounts are needed even if the report is restricted to one account, as lots
+// can be transferred between accounts.
+func NewReport(events []Event, params ReportParams) (*Report, error) {
+	if params.Year <= 0 {
+		return nil, errp.Newf("Invalid year: %d", params.Year)
+	}
+	start, end := yearBounds(params.Year)
+	untilEnd := []Event{}
+	for _, event := range events {
+		if event.Time.Before(end) {
+			untilEnd = append(untilEnd, event)
+		}
+	}
+	ledger, err := Compute(untilEnd, params.Method, params.Price)
+	if err != nil {
+		return nil, err
+	}
+	included := func(accountCode accountsTypes.Code) bool {
+		return params.AccountCode == "" || params.AccountCode == accountCode
+	}
+
+	report := &Report{
+		params:    params,
+		Disposals: []*Disposal{},
+		Holdings:  []*Holding{},
+		Totals:    newTotals(),
+	}
+	totals := &report.Totals
+	for _, disposal := range ledger.Disposals {
+		if disposal.Time.Before(start) || !included(disposal.AccountCode) {
+			continue
+		}
+		report.Disposals = append(report.Disposals, disposal)
+		totals.Proceeds.Add(totals.Proceeds, disposal.Proceeds)
+		totals.CostBasis.Add(totals.CostBasis, disposal.CostBasis)
+		totals.RealizedGain.Add(totals.RealizedGain, disposal.Gain)
+		for _, match := range disposal.Lots {
+			if match.LongTerm {
+				totals.LongTermGain.Add(totals.LongTermGain, match.Gain)
+			} else {
+				totals.ShortTermGain.Add(totals.ShortTermGain, match.Gain)
+			}
+		}
+		if disposal.PriceMissing {
+			report.Incomplete = true
+		}
+	}
+	for _, lot := range ledger.Holdings {
+		if !included(lot.AccountCode) {
+			continue
+		}
+		holding := &Holding{Lot: *lot, MarketValue: new(big.Rat)}
+		if unitPrice := params.MarketPrice(lot.CoinCode, end); unitPrice != nil {
+			holding.MarketValue.Mul(unitPrice, new(big.Rat).SetInt(lot.Amount))
+		} else {
+			holding.MarketPriceMissing = true
+		}
+		holding.UnrealizedGain = new(big.Rat).Sub(holding.MarketValue, lot.CostBasis)
+		report.Holdings = append(report.Holdings, holding)
+		totals.MarketValue.Add(totals.MarketValue, holding.MarketValue)
+		totals.HoldingsCostBasis.Add(totals.HoldingsCostBasis, lot.CostBasis)
+		totals.UnrealizedGain.Add(totals.UnrealizedGain, holding.UnrealizedGain)
+		if lot.PriceMissing || holding.MarketPriceMissing {
+			report.Incomplete = true
+		}
+	}
+	return report, nil
+}
+
+func (report *Report) formatFiat(amount *big.Rat) string {
+	return coin.FormatAsPlainCurrency(amount, report.params.Fiat == "BTC", false)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+type lotJSON struct {
+	AccountCode  accountsTypes.Code `json:"accountCode"`
+	CoinCode     coin.Code          `json:"coinCode"`
+	TxID         string             `json:"txID"`
+	Acquired     string             `json:"acquired"`
+	Amount       string             `json:"amount"`
+	CostBasis    string             `json:"costBasis"`
+	PriceMissing bool               `json:"priceMissing"`
+}
+
+func (report *Report) lotJSON(lot *Lot) lotJSON {
+	return lotJSON{
+		AccountCode:  lot.AccountCode,
+		CoinCode:     lot.CoinCode,
+		TxID:         lot.TxID,
+		Acquired:     formatTime(lot.Acquired),
+		Amount:       report.params.FormatAmount(lot.CoinCode, lot.Amount),
+		CostBasis:    report.formatFiat(lot.CostBasis),
+		PriceMissing: lot.PriceMissing,
+	}
+}
+
+// MarshalJSON implements json.Marshaler. Coin amounts are formatted in the unit of the coin and fiat
+// amounts as decimals.
+func (report *Report) MarshalJSON() ([]byte, error) {
+	type lotMatchJSON struct {
+		lotJSON
+		Proceeds string `json:"proceeds"`
+		Gain     string `json:"gain"`
+		LongTerm bool   `json:"longTerm"`
+	}
+	type disposalJSON struct {
+		AccountCode  accountsTypes.Code `json:"accountCode"`
+		CoinCode     coin.Code          `json:"coinCode"`
+		TxID         string             `json:"txID"`
+		Time         string             `json:"time"`
+		Amount       string             `json:"amount"`
+		Fee          bool               `json:"fee"`
+		Proceeds     string             `json:"proceeds"`
+		CostBasis    string             `json:"costBasis"`
+		Gain         string             `json:"gain"`
+		PriceMissing bool               `json:"priceMissing"`
+		Lots         []lotMatchJSON     `json:"lots"`
+	}
+	type holdingJSON struct {
+		lotJSON
+		MarketValue        string `json:"marketValue"`
+		UnrealizedGain     string `json:"unrealizedGain"`
+		MarketPriceMissing bool   `json:"marketPriceMissing"`
+	}
+	type totalsJSON struct {
+		Proceeds          string `json:"proceeds"`
+		CostBasis         string `json:"costBasis"`
+		RealizedGain      string `json:"realizedGain"`
+		ShortTermGain     string `json:"shortTermGain"`
+		LongTermGain      string `json:"longTermGain"`
+		MarketValue       string `json:"marketValue"`
+		HoldingsCostBasis string `json:"holdingsCostBasis"`
+		UnrealizedGain    string `json:"unrealizedGain"`
+	}
+
+	disposals := make([]disposalJSON, len(report.Disposals))
+	for i, disposal := range report.Disposals {
+		lots := make([]lotMatchJSON, len(disposal.Lots))
+		for j, match := range disposal.Lots {
+			lots[j] = lotMatchJSON{
+				lotJSON:  report.lotJSON(&match.Lot),
+				Proceeds: report.formatFiat(match.Proceeds),
+				Gain:     report.formatFiat(match.Gain),
+				LongTerm: match.LongTerm,
+			}
+		}
+		disposals[i] = disposalJSON{
+			AccountCode:  disposal.AccountCode,
+			CoinCode:     disposal.CoinCode,
+			TxID:         disposal.TxID,
+			Time:         formatTime(disposal.Time),
+			Amount:       report.params.FormatAmount(disposal.CoinCode, disposal.Amount),
+			Fee:          disposal.Fee,
+			Proceeds:     report.formatFiat(disposal.Proceeds),
+			CostBasis:    report.formatFiat(disposal.CostBasis),
+			Gain:         report.formatFiat(disposal.Gain),
+			PriceMissing: disposal.PriceMissing,
+			Lots:         lots,
+		}
+	}
+	holdings := make([]holdingJSON, len(report.Holdings))
+	for i, holding := range report.Holdings {
+		holdings[i] = holdingJSON{
+			lotJSON:            report.lotJSON(&holding.Lot),
+			MarketValue:        report.formatFiat(holding.MarketValue),
+			UnrealizedGain:     report.formatFiat(holding.UnrealizedGain),
+			MarketPriceMissing: holding.MarketPriceMissing,
+		}
+	}
+	totals := report.Totals
+	return json.Marshal(struct {
+		Year        int                `json:"year"`
+		Fiat        string             `json:"fiat"`
+		Method      Method             `json:"method"`
+		AccountCode accountsTypes.Code `json:"accountCode"`
+		Disposals   []disposalJSON     `json:"disposals"`
+		Holdings    []holdingJSON      `json:"holdings"`
+		Totals      totalsJSON         `json:"totals"`
+		Incomplete  bool               `json:"incomplete"`
+	}{
+		Year:        report.params.Year,
+		Fiat:        report.params.Fiat,
+		Method:      report.params.Method,
+		AccountCode: report.params.AccountCode,
+		Disposals:   disposals,
+		Holdings:    holdings,
+		Totals: totalsJSON{
+			Proceeds:          report.formatFiat(totals.Proceeds),
+			CostBasis:         report.formatFiat(totals.CostBasis),
+			RealizedGain:      report.formatFiat(totals.RealizedGain),
+			ShortTermGain:     report.formatFiat(totals.ShortTermGain),
+			LongTermGain:      report.formatFiat(totals.LongTermGain),
+			MarketValue:       report.formatFiat(totals.MarketValue),
+			HoldingsCostBasis: report.formatFiat(totals.HoldingsCostBasis),
+			UnrealizedGain:    report.formatFiat(totals.UnrealizedGain),
+		},
+		Incomplete: report.Incomplete,
+	})
+}
+
+// WriteCSV writes the report lot by lot: one row per lot consumed by a disposal ("realized"), and
+// one row per lot held at the end of the year ("unrealized").
+func (report *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"Kind",
+		"Account",
+		"Coin",
+		"Amount",
+		"Acquired",
+		"Acquisition transaction ID",
+		"Disposed",
+		"Disposal transaction ID",
+		"Fee",
+		"Cost basis (" + report.params.Fiat + ")",
+		"Proceeds or market value (" + report.params.Fiat + ")",
+		"Gain (" + report.params.Fiat + ")",
+		"Term",
+		"Price missing",
+	})
+	if err != nil {
+		return errp.WithStack(err)
+	}
+	boolString := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	for _, disposal := range report.Disposals {
+		for _, match := range disposal.Lots {
+			term := "short"
+			if match.LongTerm {
+				term = "long"
+			}
+			err := writer.Write([]string{
+				"realized",
+				string(disposal.AccountCode),
+				string(disposal.CoinCode),
+				report.params.FormatAmount(match.CoinCode, match.Amount),
+				formatTime(match.Acquired),
+				match.TxID,
+				formatTime(disposal.Time),
+				disposal.TxID,
+				boolString(disposal.Fee),
+				report.formatFiat(match.CostBasis),
+				report.formatFiat(match.Proceeds),
+				report.formatFiat(match.Gain),
+				term,
+				boolString(match.PriceMissing || disposal.PriceMissing),
+			})
+			if err != nil {
+				return errp.WithStack(err)
+			}
+		}
+	}
+	for _, holding := range report.Holdings {
+		err := writer.Write([]string{
+			"unrealized",
+			string(holding.AccountCode),
+			string(holding.CoinCode),
+			report.params.FormatAmount(holding.CoinCode, holding.Amount),
+			formatTime(holding.Acquired),
+			holding.TxID,
+			"",
+			"",
+			"",
+			report.formatFiat(holding.CostBasis),
+			report.formatFiat(holding.MarketValue),
+			report.formatFiat(holding.UnrealizedGain),
+			"",
+			boolString(holding.PriceMissing || holding.MarketPriceMissing),
+		})
+		if err != nil {
+			return errp.WithStack(err)
+		}
+	}
+	writer.Flush()
+	return errp.WithStack(writer.Error())
+}
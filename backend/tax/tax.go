@@ -0,0 +1,342 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tax computes capital gains using tax lots. Every acquisition of coins creates a lot with
+// the fiat value at the time of the acquisition as its cost basis. Disposals consume lots according
+// to the chosen method, realizing the difference between the fiat value at the time of the disposal
+// (the proceeds) and the cost basis of the consumed lots.
+//
+// Lots are tracked per account. Transfers between accounts move the lots, keeping their cost basis
+// and acquisition time, and are not taxable.
+package tax
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// Method is the method of choosing the lots consumed by a disposal.
+type Method string
+
+const (
+	// MethodFIFO consumes the earliest acquired lots first (first in, first out).
+	MethodFIFO Method = "fifo"
+	// MethodLIFO consumes the latest acquired lots first (last in, first out).
+	MethodLIFO Method = "lifo"
+	// MethodHIFO consumes the lots with the highest cost per unit first (highest in, first out).
+	MethodHIFO Method = "hifo"
+)
+
+// longTermHoldingPeriod is the holding period after which gains are considered long-term.
+const longTermHoldingPeriod = 365 * 24 * time.Hour
+
+// EventType is the type of an event, see the EventType* constants.
+type EventType int
+
+// The event types are ordered as they are processed if they happen at the same time, so that
+// coins are available before they are disposed.
+const (
+	// EventTypeAcquire is an acquisition of coins, e.g. a received transaction.
+	EventTypeAcquire EventType = iota
+	// EventTypeTransferOut is the sending side of a transfer between two accounts.
+	EventTypeTransferOut
+	// EventTypeTransferIn is the receiving side of a transfer between two accounts.
+	EventTypeTransferIn
+	// EventTypeDispose is a disposal of coins, e.g. a sent transaction or a network fee.
+	EventTypeDispose
+)
+
+// Event is a change of the coins held by an account.
+type Event struct {
+	Type        EventType
+	AccountCode accountsTypes.Code
+	CoinCode    coin.Code
+	TxID        string
+	Time        time.Time
+	// Amount is in the smallest unit of the coin.
+	Amount *big.Int
+	// TransferID links the two sides of a transfer between accounts.
+	TransferID string
+	// Fee is true if the disposal pays a network fee.
+	Fee bool
+}
+
+// PriceFunc returns the fiat price of one smallest unit of the coin (e.g. a satoshi) at the given
+// time, or nil if it is not available.
+type PriceFunc func(coinCode coin.Code, at time.Time) *big.Rat
+
+// Lot is an amount of coins acquired at once.
+type Lot struct {
+	AccountCode accountsTypes.Code
+	CoinCode    coin.Code
+	// TxID is the transaction which acquired the coins.
+	TxID     string
+	Acquired time.Time
+	// Amount is in the smallest unit of the coin.
+	Amount    *big.Int
+	CostBasis *big.Rat
+	// PriceMissing is true if the cost basis is unknown and counted as zero. This happens if the
+	// price at the acquisition is not available, or if more coins are disposed than acquired, e.g.
+	// because the transaction history is incomplete.
+	PriceMissing bool
+}
+
+// split splits off the given amount from the lot, dividing the cost basis proportionally. The
+// split off part is returned and the lot keeps the rest.
+func (lot *Lot) split(amount *big.Int) *Lot {
+	part := *lot
+	part.Amount = new(big.Int).Set(amount)
+	part.CostBasis = new(big.Rat).Mul(lot.CostBasis, new(big.Rat).SetFrac(amount, lot.Amount))
+	lot.Amount = new(big.Int).Sub(lot.Amount, amount)
+	lot.CostBasis = new(big.Rat).Sub(lot.CostBasis, part.CostBasis)
+	return &part
+}
+
+// LotMatch is the part of a lot consumed by a disposal.
+type LotMatch struct {
+	Lot
+	Proceeds *big.Rat
+	Gain     *big.Rat
+	// LongTerm is true if the lot was held for more than a year.
+	LongTerm bool
+}
+
+// Disposal is a disposal of coins with the lots it consumed.
+type Disposal struct {
+	AccountCode accountsTypes.Code
+	CoinCode    coin.Code
+	TxID        string
+	Time        time.Time
+	Amount      *big.Int
+	// Fee is true if the disposal pays a network fee.
+	Fee       bool
+	Proceeds  *big.Rat
+	CostBasis *big.Rat
+	Gain      *big.Rat
+	// PriceMissing is true if the proceeds or the cost basis of a lot are unknown and counted as
+	// zero.
+	PriceMissing bool
+	Lots         []*LotMatch
+}
+
+// Ledger is the result of processing events.
+type Ledger struct {
+	Disposals []*Disposal
+	// Holdings are the remaining lots, sorted by account, coin and acquisition time.
+	Holdings []*Lot
+}
+
+type poolKey struct {
+	accountCode accountsTypes.Code
+	coinCode    coin.Code
+}
+
+// Compute processes the events in chronological order and returns the disposals and the remaining
+// lots. A transfer in without a matching transfer out is treated as an acquisition.
+func Compute(events []Event, method Method, price PriceFunc) (*Ledger, error) {
+	switch method {
+	case MethodFIFO, MethodLIFO, MethodHIFO:
+	default:
+		return nil, errp.Newf("Unknown method: %s", method)
+	}
+	events = append([]Event(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Time.Equal(events[j].Time) {
+			return events[i].Time.Before(events[j].Time)
+		}
+		return events[i].Type < events[j].Type
+	})
+
+	// pools holds the lots of each account and coin, sorted by acquisition time.
+	pools := map[poolKey][]*Lot{}
+	// inTransfer holds the lots sent by a transfer out until the transfer in.
+	inTransfer := map[string][]*Lot{}
+	ledger := &Ledger{Disposals: []*Disposal{}}
+
+	acquire := func(event *Event) {
+		key := poolKey{event.AccountCode, event.CoinCode}
+		costBasis := new(big.Rat)
+		unitPrice := price(event.CoinCode, event.Time)
+		if unitPrice != nil {
+			costBasis.Mul(unitPrice, new(big.Rat).SetInt(event.Amount))
+		}
+		pools[key] = insertLot(pools[key], &Lot{
+			AccountCode:  event.AccountCode,
+			CoinCode:     event.CoinCode,
+			TxID:         event.TxID,
+			Acquired:     event.Time,
+			Amount:       new(big.Int).Set(event.Amount),
+			CostBasis:    costBasis,
+			PriceMissing: unitPrice == nil,
+		})
+	}
+
+	for i := range events {
+		event := &events[i]
+		if event.Amount.Sign() <= 0 {
+			continue
+		}
+		key := poolKey{event.AccountCode, event.CoinCode}
+		switch event.Type {
+		case EventTypeAcquire:
+			acquire(event)
+		case EventTypeTransferOut:
+			var lots []*Lot
+			pools[key], lots = take(pools[key], event, method)
+			inTransfer[event.TransferID] = append(inTransfer[event.TransferID], lots...)
+		case EventTypeTransferIn:
+			lots, ok := inTransfer[event.TransferID]
+			if !ok {
+				acquire(event)
+				continue
+			}
+			delete(inTransfer, event.TransferID)
+			for _, lot := range lots {
+				lot.AccountCode = event.AccountCode
+				pools[key] = insertLot(pools[key], lot)
+			}
+		case EventTypeDispose:
+			var lots []*Lot
+			pools[key], lots = take(pools[key], event, method)
+			ledger.Disposals = append(ledger.Disposals, newDisposal(event, lots, price(event.CoinCode, event.Time)))
+		default:
+			return nil, errp.Newf("Unknown event type: %d", event.Type)
+		}
+	}
+
+	keys := make([]poolKey, 0, len(pools))
+	for key := range pools {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].accountCode != keys[j].accountCode {
+			return keys[i].accountCode < keys[j].accountCode
+		}
+		return keys[i].coinCode < keys[j].coinCode
+	})
+	ledger.Holdings = []*Lot{}
+	for _, key := range keys {
+		ledger.Holdings = append(ledger.Holdings, pools[key]...)
+	}
+	return ledger, nil
+}
+
+// insertLot inserts the lot into the pool, keeping it sorted by acquisition time.
+func insertLot(pool []*Lot, lot *Lot) []*Lot {
+	index := sort.Search(len(pool), func(i int) bool {
+		return pool[i].Acquired.After(lot.Acquired)
+	})
+	pool = append(pool, nil)
+	copy(pool[index+1:], pool[index:])
+	pool[index] = lot
+	return pool
+}
+
+// take removes lots worth the amount of the event from the pool, chosen according to the method.
+// If the pool does not hold enough coins, a lot with an unknown cost basis makes up for the rest.
+// The remaining pool and the removed lots are returned.
+func take(pool []*Lot, event *Event, method Method) ([]*Lot, []*Lot) {
+	order := make([]*Lot, len(pool))
+	copy(order, pool)
+	switch method {
+	case MethodLIFO:
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	case MethodHIFO:
+		// Stable, so that lots with the same cost per unit are consumed first in, first out.
+		sort.SliceStable(order, func(i, j int) bool {
+			return unitCost(order[i]).Cmp(unitCost(order[j])) > 0
+		})
+	}
+
+	remaining := new(big.Int).Set(event.Amount)
+	taken := []*Lot{}
+	consumed := map[*Lot]bool{}
+	for _, lot := range order {
+		if remaining.Sign() == 0 {
+			break
+		}
+		if lot.Amount.Cmp(remaining) <= 0 {
+			taken = append(taken, lot)
+			consumed[lot] = true
+			remaining.Sub(remaining, lot.Amount)
+			continue
+		}
+		taken = append(taken, lot.split(remaining))
+		remaining.SetInt64(0)
+	}
+	if remaining.Sign() > 0 {
+		taken = append(taken, &Lot{
+			AccountCode:  event.AccountCode,
+			CoinCode:     event.CoinCode,
+			Acquired:     event.Time,
+			Amount:       remaining,
+			CostBasis:    new(big.Rat),
+			PriceMissing: true,
+		})
+	}
+
+	newPool := []*Lot{}
+	for _, lot := range pool {
+		if !consumed[lot] {
+			newPool = append(newPool, lot)
+		}
+	}
+	return newPool, taken
+}
+
+func unitCost(lot *Lot) *big.Rat {
+	return new(big.Rat).Quo(lot.CostBasis, new(big.Rat).SetInt(lot.Amount))
+}
+
+func newDisposal(event *Event, lots []*Lot, unitPrice *big.Rat) *Disposal {
+	disposal := &Disposal{
+		AccountCode:  event.AccountCode,
+		CoinCode:     event.CoinCode,
+		TxID:         event.TxID,
+		Time:         event.Time,
+		Amount:       new(big.Int).Set(event.Amount),
+		Fee:          event.Fee,
+		Proceeds:     new(big.Rat),
+		CostBasis:    new(big.Rat),
+		Gain:         new(big.Rat),
+		PriceMissing: unitPrice == nil,
+		Lots:         make([]*LotMatch, 0, len(lots)),
+	}
+	for _, lot := range lots {
+		proceeds := new(big.Rat)
+		if unitPrice != nil {
+			proceeds.Mul(unitPrice, new(big.Rat).SetInt(lot.Amount))
+		}
+		disposal.Lots = append(disposal.Lots, &LotMatch{
+			Lot:      *lot,
+			Proceeds: proceeds,
+			Gain:     new(big.Rat).Sub(proceeds, lot.CostBasis),
+			LongTerm: event.Time.Sub(lot.Acquired) > longTermHoldingPeriod,
+		})
+		disposal.Proceeds.Add(disposal.Proceeds, proceeds)
+		disposal.CostBasis.Add(disposal.CostBasis, lot.CostBasis)
+		if lot.PriceMissing {
+			disposal.PriceMissing = true
+		}
+	}
+	disposal.Gain.Sub(disposal.Proceeds, disposal.CostBasis)
+	return disposal
+}
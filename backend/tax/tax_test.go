@@ -0,0 +1,228 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tax
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/stretchr/testify/require"
+)
+
+func day(d int) time.Time {
+	return time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, d)
+}
+
+// prices returns a price function with the given price per unit starting at the given days.
+func prices(pricePerDay map[int]int64) PriceFunc {
+	return func(_ coin.Code, at time.Time) *big.Rat {
+		var result *big.Rat
+		latest := -1
+		for d, price := range pricePerDay {
+			if !day(d).After(at) && d > latest {
+				latest = d
+				result = big.NewRat(price, 1)
+			}
+		}
+		return result
+	}
+}
+
+func event(eventType EventType, account string, d int, amount int64) Event {
+	return Event{
+		Type:        eventType,
+		AccountCode: accountsTypes.Code("acct-" + account),
+		CoinCode:    coin.CodeBTC,
+		TxID:        account + "-" + day(d).Format("0102"),
+		Time:        day(d),
+		Amount:      big.NewInt(amount),
+	}
+}
+
+func TestComputeMethods(t *testing.T) {
+	events := []Event{
+		event(EventTypeAcquire, "a", 0, 10),   // price 1, cost 10
+		event(EventTypeAcquire, "a", 10, 10),  // price 3, cost 30
+		event(EventTypeAcquire, "a", 20, 10),  // price 2, cost 20
+		event(EventTypeDispose, "a", 30, 15),  // price 4, proceeds 60
+		event(EventTypeDispose, "a", 400, 10), // price 5, proceeds 50
+	}
+	price := prices(map[int]int64{0: 1, 10: 3, 20: 2, 30: 4, 400: 5})
+
+	tests := []struct {
+		method     Method
+		costBasis  []*big.Rat
+		holdingsTx string
+	}{
+		{MethodFIFO, []*big.Rat{big.NewRat(25, 1), big.NewRat(25, 1)}, "a-0121"},
+		{MethodLIFO, []*big.Rat{big.NewRat(35, 1), big.NewRat(20, 1)}, "a-0101"},
+		{MethodHIFO, []*big.Rat{big.NewRat(40, 1), big.NewRat(15, 1)}, "a-0101"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.method), func(t *testing.T) {
+			ledger, err := Compute(events, test.method, price)
+			require.NoError(t, err)
+			require.Len(t, ledger.Disposals, 2)
+			for i, disposal := range ledger.Disposals {
+				require.Equal(t, test.costBasis[i].String(), disposal.CostBasis.String())
+				require.False(t, disposal.PriceMissing)
+			}
+			require.Equal(t, big.NewRat(60, 1).String(), ledger.Disposals[0].Proceeds.String())
+			require.Equal(t, big.NewRat(50, 1).String(), ledger.Disposals[1].Proceeds.String())
+			require.Len(t, ledger.Holdings, 1)
+			require.Equal(t, big.NewInt(5), ledger.Holdings[0].Amount)
+			require.Equal(t, test.holdingsTx, ledger.Holdings[0].TxID)
+		})
+	}
+
+	_, err := Compute(events, "unknown", price)
+	require.Error(t, err)
+}
+
+func TestComputeLongTerm(t *testing.T) {
+	ledger, err := Compute([]Event{
+		event(EventTypeAcquire, "a", 0, 10),
+		event(EventTypeAcquire, "a", 100, 10),
+		event(EventTypeDispose, "a", 400, 15),
+	}, MethodFIFO, prices(map[int]int64{0: 1}))
+	require.NoError(t, err)
+	lots := ledger.Disposals[0].Lots
+	require.Len(t, lots, 2)
+	require.True(t, lots[0].LongTerm)
+	require.Equal(t, big.NewInt(10), lots[0].Amount)
+	require.False(t, lots[1].LongTerm)
+	require.Equal(t, big.NewInt(5), lots[1].Amount)
+}
+
+func TestComputeShortfall(t *testing.T) {
+	ledger, err := Compute([]Event{
+		event(EventTypeAcquire, "a", 0, 10),
+		event(EventTypeDispose, "a", 1, 15),
+	}, MethodFIFO, prices(map[int]int64{0: 2}))
+	require.NoError(t, err)
+	disposal := ledger.Disposals[0]
+	require.True(t, disposal.PriceMissing)
+	require.Len(t, disposal.Lots, 2)
+	require.True(t, disposal.Lots[1].PriceMissing)
+	require.Equal(t, big.NewInt(5), disposal.Lots[1].Amount)
+	require.Equal(t, big.NewRat(20, 1).String(), disposal.CostBasis.String())
+	require.Equal(t, big.NewRat(10, 1).String(), disposal.Gain.String())
+	require.Empty(t, ledger.Holdings)
+}
+
+func TestComputeTransfer(t *testing.T) {
+	transferOut := event(EventTypeTransferOut, "a", 10, 6)
+	transferOut.TransferID = "transfer"
+	transferIn := event(EventTypeTransferIn, "b", 10, 6)
+	transferIn.TransferID = "transfer"
+	// Unmatched transfers in are acquisitions.
+	unmatched := event(EventTypeTransferIn, "b", 11, 1)
+	unmatched.TransferID = "other"
+
+	ledger, err := Compute([]Event{
+		transferIn,
+		event(EventTypeAcquire, "a", 0, 10),
+		transferOut,
+		unmatched,
+		event(EventTypeDispose, "b", 20, 7),
+	}, MethodFIFO, prices(map[int]int64{0: 1, 10: 5, 11: 6, 20: 10}))
+	require.NoError(t, err)
+
+	require.Len(t, ledger.Disposals, 1)
+	disposal := ledger.Disposals[0]
+	require.Len(t, disposal.Lots, 2)
+	// The transferred lot keeps its cost basis, acquisition time and transaction.
+	require.Equal(t, day(0), disposal.Lots[0].Acquired)
+	require.Equal(t, "a-0101", disposal.Lots[0].TxID)
+	require.Equal(t, big.NewRat(6, 1).String(), disposal.Lots[0].CostBasis.String())
+	require.Equal(t, big.NewRat(6, 1).String(), disposal.Lots[1].CostBasis.String())
+	require.Equal(t, big.NewRat(58, 1).String(), disposal.Gain.String())
+
+	require.Len(t, ledger.Holdings, 1)
+	require.Equal(t, "acct-a", string(ledger.Holdings[0].AccountCode))
+	require.Equal(t, big.NewInt(4), ledger.Holdings[0].Amount)
+	require.Equal(t, big.NewRat(4, 1).String(), ledger.Holdings[0].CostBasis.String())
+}
+
+func TestReport(t *testing.T) {
+	price := prices(map[int]int64{0: 1, 400: 3, 500: 4})
+	events := []Event{
+		event(EventTypeAcquire, "a", 0, 10),
+		event(EventTypeDispose, "a", 100, 2),
+		event(EventTypeAcquire, "b", 300, 10),
+		event(EventTypeDispose, "a", 400, 4),
+		event(EventTypeDispose, "b", 500, 5),
+		// Next year, ignored.
+		event(EventTypeDispose, "a", 800, 1),
+	}
+	formatAmount := func(_ coin.Code, amount *big.Int) string {
+		return amount.String()
+	}
+	params := ReportParams{
+		Year:         2021,
+		Fiat:         "USD",
+		Method:       MethodFIFO,
+		Price:        price,
+		MarketPrice:  prices(map[int]int64{0: 10}),
+		FormatAmount: formatAmount,
+	}
+
+	report, err := NewReport(events, params)
+	require.NoError(t, err)
+	require.Len(t, report.Disposals, 2)
+	require.Equal(t, big.NewRat(32, 1).String(), report.Totals.Proceeds.String())
+	require.Equal(t, big.NewRat(9, 1).String(), report.Totals.CostBasis.String())
+	require.Equal(t, big.NewRat(8, 1).String(), report.Totals.LongTermGain.String())
+	require.Equal(t, big.NewRat(15, 1).String(), report.Totals.ShortTermGain.String())
+	require.Equal(t, big.NewRat(23, 1).String(), report.Totals.RealizedGain.String())
+	require.Len(t, report.Holdings, 2)
+	require.Equal(t, big.NewRat(90, 1).String(), report.Totals.MarketValue.String())
+	require.Equal(t, big.NewRat(81, 1).String(), report.Totals.UnrealizedGain.String())
+	require.False(t, report.Incomplete)
+
+	params.AccountCode = "acct-b"
+	report, err = NewReport(events, params)
+	require.NoError(t, err)
+	require.Len(t, report.Disposals, 1)
+	require.Equal(t, big.NewRat(15, 1).String(), report.Totals.RealizedGain.String())
+
+	jsonBytes, err := json.Marshal(report)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(jsonBytes, &decoded))
+	require.Equal(t, "15.00", decoded["totals"].(map[string]interface{})["realizedGain"])
+	require.Equal(t, "acct-b", decoded["accountCode"])
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, "Cost basis (USD)", rows[0][9])
+	require.Equal(t, []string{
+		"realized", "acct-b", "btc", "5", "2020-10-27T00:00:00Z", "b-1027", "2021-05-15T00:00:00Z",
+		"b-0515", "no", "5.00", "20.00", "15.00", "short", "no",
+	}, rows[1])
+	require.Equal(t, "unrealized", rows[2][0])
+
+	_, err = NewReport(events, ReportParams{Method: MethodFIFO})
+	require.Error(t, err)
+}
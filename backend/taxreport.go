@@ -0,0 +1,211 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/tax"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/errp"
+)
+
+// taxEvents converts the transactions of our accounts into tax events.
+//
+// Internal transfers (see `matchInternalTransfers()`) are not taxable and move the lots to the
+// receiving account. Network fees are disposals, except if they are paid in a different coin (e.g.
+// the ETH fee of an ERC20 token transaction), which is not covered by the transactions of the
+// account.
+func taxEvents(txs []AccountTransaction) []tax.Event {
+	type txKey struct {
+		accountCode accountsTypes.Code
+		internalID  string
+	}
+	sentTransfers := map[txKey][]*InternalTransfer{}
+	receivedTransfers := map[txKey][]*InternalTransfer{}
+	for _, transfer := range matchInternalTransfers(txs) {
+		from := txKey{transfer.From, transfer.FromInternalID}
+		sentTransfers[from] = append(sentTransfers[from], transfer)
+		to := txKey{transfer.To, transfer.ToInternalID}
+		receivedTransfers[to] = append(receivedTransfers[to], transfer)
+	}
+	transferID := func(transfer *InternalTransfer) string {
+		return fmt.Sprintf("%s/%s", transfer.TxID, transfer.To)
+	}
+
+	events := []tax.Event{}
+	for _, accountTx := range txs {
+		tx := accountTx.Transaction
+		accountCode := accountTx.Account.Config().Config.Code
+		key := txKey{accountCode, tx.InternalID}
+		newEvent := func(eventType tax.EventType, amount *big.Int) tax.Event {
+			return tax.Event{
+				Type:        eventType,
+				AccountCode: accountCode,
+				CoinCode:    accountTx.Account.Coin().Code(),
+				TxID:        tx.TxID,
+				Time:        *tx.Timestamp,
+				Amount:      amount,
+			}
+		}
+
+		if tx.Status != accounts.TxStatusFailed {
+			switch tx.Type {
+			case accounts.TxTypeReceive:
+				acquired := new(big.Int).Set(tx.Amount.BigInt())
+				for _, transfer := range receivedTransfers[key] {
+					event := newEvent(tax.EventTypeTransferIn, transfer.Amount.BigInt())
+					event.TransferID = transferID(transfer)
+					events = append(events, event)
+					acquired.Sub(acquired, transfer.Amount.BigInt())
+				}
+				if acquired.Sign() > 0 {
+					events = append(events, newEvent(tax.EventTypeAcquire, acquired))
+				}
+			case accounts.TxTypeSend:
+				disposed := new(big.Int).Set(tx.Amount.BigInt())
+				for _, transfer := range sentTransfers[key] {
+					event := newEvent(tax.EventTypeTransferOut, transfer.Amount.BigInt())
+					event.TransferID = transferID(transfer)
+					events = append(events, event)
+					disposed.Sub(disposed, transfer.Amount.BigInt())
+				}
+				if disposed.Sign() > 0 {
+					events = append(events, newEvent(tax.EventTypeDispose, disposed))
+				}
+			}
+		}
+		if tx.Fee != nil && !tx.FeeIsDifferentUnit && tx.Type != accounts.TxTypeReceive {
+			event := newEvent(tax.EventTypeDispose, tx.Fee.BigInt())
+			event.Fee = true
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// TaxReport computes the capital gains report of the given year in the given fiat currency. If
+// accountCode is not empty, the report is restricted to that account, otherwise it covers all
+// active accounts.
+//
+// Only confirmed transactions are considered. The holdings at the end of the year are valued at
+// the latest price if the year is not over yet.
+func (backend *Backend) TaxReport(
+	accountCode accountsTypes.Code,
+	year int,
+	fiat string,
+	method tax.Method,
+) (*tax.Report, error) {
+	if accountCode != "" && backend.Accounts().lookup(accountCode) == nil {
+		return nil, errp.Newf("Could not find account %s", accountCode)
+	}
+	coins := map[coinpkg.Code]coinpkg.Coin{}
+	txs := []AccountTransaction{}
+	for _, account := range backend.Accounts() {
+		if account.Config().Config.Inactive || account.FatalError() {
+			continue
+		}
+		if err := account.Initialize(); err != nil {
+			return nil, err
+		}
+		accountTxs, err := account.Transactions()
+		if err != nil {
+			return nil, err
+		}
+		coins[account.Coin().Code()] = account.Coin()
+		for _, tx := range accountTxs {
+			if tx.Status == accounts.TxStatusPending || tx.Timestamp == nil {
+				continue
+			}
+			txs = append(txs, AccountTransaction{Account: account, Transaction: tx})
+		}
+	}
+
+	ratesUpdater := backend.RatesUpdater()
+	// unitPrice converts the price of a coin to the price of its smallest unit.
+	unitPrice := func(coinCode coinpkg.Code, price float64) *big.Rat {
+		if price == 0 {
+			return nil
+		}
+		decimals := new(big.Int).Exp(
+			big.NewInt(10),
+			big.NewInt(int64(coins[coinCode].Decimals(false))),
+			nil,
+		)
+		return new(big.Rat).Quo(new(big.Rat).SetFloat64(price), new(big.Rat).SetInt(decimals))
+	}
+	historicalPrice := func(coinCode coinpkg.Code, at time.Time) *big.Rat {
+		return unitPrice(coinCode, ratesUpdater.HistoricalPriceAt(string(coinCode), fiat, at))
+	}
+	marketPrice := historicalPrice
+	if year == time.Now().Year() {
+		marketPrice = func(coinCode coinpkg.Code, _ time.Time) *big.Rat {
+			price, err := ratesUpdater.LatestPriceForPair(coins[coinCode].Unit(false), fiat)
+			if err != nil {
+				return nil
+			}
+			return unitPrice(coinCode, price)
+		}
+	}
+
+	return tax.NewReport(taxEvents(txs), tax.ReportParams{
+		Year:        year,
+		Fiat:        fiat,
+		Method:      method,
+		AccountCode: accountCode,
+		Price:       historicalPrice,
+		MarketPrice: marketPrice,
+		FormatAmount: func(coinCode coinpkg.Code, amount *big.Int) string {
+			return coins[coinCode].FormatAmount(coinpkg.NewAmount(amount), false)
+		},
+	})
+}
+
+// ExportTaxReport computes the tax report like `TaxReport()` and lets the user save it as a CSV or
+// JSON file, which is opened afterwards. Returns the path of the file, or an empty string if the
+// user aborted.
+func (backend *Backend) ExportTaxReport(
+	accountCode accountsTypes.Code,
+	year int,
+	fiat string,
+	method tax.Method,
+	format string,
+) (string, error) {
+	if format != "csv" && format != "json" {
+		return "", errp.Newf("Unknown format: %s", format)
+	}
+	report, err := backend.TaxReport(accountCode, year, fiat, method)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("tax-report-%d-%s", year, fiat)
+	if accountCode != "" {
+		name += "-" + string(accountCode)
+	}
+	return backend.saveAndOpenFile(name+"."+format, func(w io.Writer) error {
+		if format == "csv" {
+			return report.WriteCSV(w)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return errp.WithStack(encoder.Encode(report))
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	coinMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/tax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaxEvents(t *testing.T) {
+	makeAccount := func(code accountsTypes.Code, coinCode coinpkg.Code) accounts.Interface {
+		cfg := &accounts.AccountConfig{Config: &config.Account{Code: code}}
+		coin := &coinMocks.CoinMock{CodeFunc: func() coinpkg.Code { return coinCode }}
+		return &accountsMocks.InterfaceMock{
+			ConfigFunc: func() *accounts.AccountConfig { return cfg },
+			CoinFunc:   func() coinpkg.Coin { return coin },
+		}
+	}
+	btc1 := makeAccount("btc-1", coinpkg.CodeBTC)
+	btc2 := makeAccount("btc-2", coinpkg.CodeBTC)
+	ltc := makeAccount("ltc", coinpkg.CodeLTC)
+	now := time.Now()
+	amount := coinpkg.NewAmountFromInt64
+	fee := amount(1)
+
+	events := taxEvents([]AccountTransaction{
+		{btc1, &accounts.TransactionData{TxID: "a", InternalID: "a", Type: accounts.TxTypeReceive, Amount: amount(100), Timestamp: &now}},
+		// Sends 30 to btc-2 and 20 to someone else.
+		{btc1, &accounts.TransactionData{TxID: "b", InternalID: "b", Type: accounts.TxTypeSend, Amount: amount(50), Fee: &fee, Timestamp: &now}},
+		{btc2, &accounts.TransactionData{TxID: "b", InternalID: "b", Type: accounts.TxTypeReceive, Amount: amount(30), Timestamp: &now}},
+		// Same transaction ID in another coin is not a transfer.
+		{ltc, &accounts.TransactionData{TxID: "b", InternalID: "b", Type: accounts.TxTypeReceive, Amount: amount(5), Timestamp: &now}},
+		{btc2, &accounts.TransactionData{TxID: "c", InternalID: "c", Type: accounts.TxTypeSendSelf, Amount: amount(10), Fee: &fee, Timestamp: &now}},
+		{btc2, &accounts.TransactionData{TxID: "d", InternalID: "d", Type: accounts.TxTypeSend, Amount: amount(10), Fee: &fee, Timestamp: &now, Status: accounts.TxStatusFailed}},
+	})
+
+	type simpleEvent struct {
+		eventType   tax.EventType
+		accountCode accountsTypes.Code
+		txID        string
+		amount      int64
+		transferID  string
+		fee         bool
+	}
+	simple := []simpleEvent{}
+	for _, event := range events {
+		simple = append(simple, simpleEvent{
+			event.Type, event.AccountCode, event.TxID, event.Amount.Int64(), event.TransferID, event.Fee,
+		})
+	}
+	require.Equal(t, []simpleEvent{
+		{tax.EventTypeAcquire, "btc-1", "a", 100, "", false},
+		{tax.EventTypeTransferOut, "btc-1", "b", 30, "b/btc-2", false},
+		{tax.EventTypeDispose, "btc-1", "b", 20, "", false},
+		{tax.EventTypeDispose, "btc-1", "b", 1, "", true},
+		{tax.EventTypeTransferIn, "btc-2", "b", 30, "b/btc-2", false},
+		{tax.EventTypeAcquire, "ltc", "b", 5, "", false},
+		{tax.EventTypeDispose, "btc-2", "c", 1, "", true},
+		{tax.EventTypeDispose, "btc-2", "d", 1, "", true},
+	}, simple)
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"math/big"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable"
+	"github.com/digitalbitbox/bitbox-wallet-app/util/observable/action"
+)
+
+// InternalTransfer is a transaction moving coins from one of our accounts to another.
+type InternalTransfer struct {
+	CoinCode coinpkg.Code
+	// TxID is the transaction ID, or the transaction hash for Ethereum.
+	TxID string
+	// From and FromInternalID identify the sending transaction.
+	From           accountsTypes.Code
+	FromInternalID string
+	// To and ToInternalID identify the receiving transaction.
+	To           accountsTypes.Code
+	ToInternalID string
+	// Amount is the amount received by the receiving account.
+	Amount coinpkg.Amount
+}
+
+// transferKey identifies a transaction on a network. The coin code is part of the key, as e.g. an
+// ERC20 token transaction has the same hash in the Ethereum account and in the token account.
+type transferKey struct {
+	coinCode coinpkg.Code
+	txID     string
+}
+
+// matchInternalTransfers finds the internal transfers in the transactions of our accounts: a send
+// in one account and a receive in another account of the same coin with the same transaction ID.
+//
+// The transfer amount is the received amount, capped by the sent amount, as a send can pay
+// several of our accounts and other recipients at once. Failed transactions are ignored.
+func matchInternalTransfers(txs []AccountTransaction) []*InternalTransfer {
+	receives := map[transferKey][]AccountTransaction{}
+	for _, accountTx := range txs {
+		tx := accountTx.Transaction
+		if tx.Type != accounts.TxTypeReceive || tx.Status == accounts.TxStatusFailed {
+			continue
+		}
+		key := transferKey{accountTx.Account.Coin().Code(), tx.TxID}
+		receives[key] = append(receives[key], accountTx)
+	}
+
+	transfers := []*InternalTransfer{}
+	for _, accountTx := range txs {
+		tx := accountTx.Transaction
+		if tx.Type != accounts.TxTypeSend || tx.Status == accounts.TxStatusFailed {
+			continue
+		}
+		from := accountTx.Account.Config().Config.Code
+		key := transferKey{accountTx.Account.Coin().Code(), tx.TxID}
+		remaining := new(big.Int).Set(tx.Amount.BigInt())
+		for _, receive := range receives[key] {
+			to := receive.Account.Config().Config.Code
+			if to == from || remaining.Sign() <= 0 {
+				continue
+			}
+			amount := receive.Transaction.Amount.BigInt()
+			if amount.Cmp(remaining) > 0 {
+				amount = remaining
+			}
+			remaining = new(big.Int).Sub(remaining, amount)
+			transfers = append(transfers, &InternalTransfer{
+				CoinCode:       key.coinCode,
+				TxID:           tx.TxID,
+				From:           from,
+				FromInternalID: tx.InternalID,
+				To:             to,
+				ToInternalID:   receive.Transaction.InternalID,
+				Amount:         coinpkg.NewAmount(new(big.Int).Set(amount)),
+			})
+		}
+	}
+	return transfers
+}
+
+// InternalTransfers finds the internal transfers between all active accounts. The links of the
+// transactions of the accounts are updated accordingly.
+func (backend *Backend) InternalTransfers() ([]*InternalTransfer, error) {
+	txs := []AccountTransaction{}
+	for _, account := range backend.Accounts() {
+		if account.Config().Config.Inactive || account.FatalError() {
+			continue
+		}
+		if err := account.Initialize(); err != nil {
+			return nil, err
+		}
+		accountTxs, err := account.Transactions()
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range accountTxs {
+			txs = append(txs, AccountTransaction{Account: account, Transaction: tx})
+		}
+	}
+	transfers := matchInternalTransfers(txs)
+	backend.setInternalTransfers(transfers)
+	return transfers, nil
+}
+
+// updateInternalTransfers matches the internal transfers between the active accounts which are
+// synced. It is called when an account finished syncing, so the links of the transactions are up to
+// date.
+func (backend *Backend) updateInternalTransfers() {
+	txs := []AccountTransaction{}
+	for _, account := range backend.Accounts() {
+		if account.Config().Config.Inactive || account.FatalError() || !account.Synced() {
+			continue
+		}
+		accountTxs, err := account.Transactions()
+		if err != nil {
+			backend.log.WithError(err).Error("could not get transactions to match internal transfers")
+			return
+		}
+		for _, tx := range accountTxs {
+			txs = append(txs, AccountTransaction{Account: account, Transaction: tx})
+		}
+	}
+	backend.setInternalTransfers(matchInternalTransfers(txs))
+}
+
+// setInternalTransfers stores the links of the internal transfers, by account and internal
+// transaction ID, and notifies the frontend if they changed.
+func (backend *Backend) setInternalTransfers(transfers []*InternalTransfer) {
+	links := map[accountsTypes.Code]map[string][]accounts.InternalTransfer{}
+	addLink := func(accountCode accountsTypes.Code, internalID string, link accounts.InternalTransfer) {
+		if links[accountCode] == nil {
+			links[accountCode] = map[string][]accounts.InternalTransfer{}
+		}
+		links[accountCode][internalID] = append(links[accountCode][internalID], link)
+	}
+	for _, transfer := range transfers {
+		addLink(transfer.From, transfer.FromInternalID, accounts.InternalTransfer{
+			AccountCode: transfer.To,
+			InternalID:  transfer.ToInternalID,
+			Amount:      transfer.Amount,
+		})
+		addLink(transfer.To, transfer.ToInternalID, accounts.InternalTransfer{
+			AccountCode: transfer.From,
+			InternalID:  transfer.FromInternalID,
+			Amount:      transfer.Amount,
+		})
+	}
+
+	unlock := backend.internalTransfersLock.Lock()
+	changed := len(links) != len(backend.internalTransfers) || !sameInternalTransfers(links, backend.internalTransfers)
+	backend.internalTransfers = links
+	unlock()
+	if changed {
+		backend.Notify(observable.Event{
+			Subject: "internal-transfers",
+			Action:  action.Reload,
+		})
+	}
+}
+
+func sameInternalTransfers(a, b map[accountsTypes.Code]map[string][]accounts.InternalTransfer) bool {
+	for accountCode, accountLinks := range a {
+		if len(accountLinks) != len(b[accountCode]) {
+			return false
+		}
+		for internalID, txLinks := range accountLinks {
+			other := b[accountCode][internalID]
+			if len(txLinks) != len(other) {
+				return false
+			}
+			for i := range txLinks {
+				if txLinks[i].AccountCode != other[i].AccountCode ||
+					txLinks[i].InternalID != other[i].InternalID ||
+					txLinks[i].Amount.BigInt().Cmp(other[i].Amount.BigInt()) != 0 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// internalTransferLinks returns the links of the transaction of the account to the transactions
+// of our other accounts, as found by the last matching.
+func (backend *Backend) internalTransferLinks(
+	accountCode accountsTypes.Code, internalID string) []accounts.InternalTransfer {
+	defer backend.internalTransfersLock.RLock()()
+	return backend.internalTransfers[accountCode][internalID]
+}
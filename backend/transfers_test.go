@@ -0,0 +1,97 @@
+// Copyright 2024 Shift Crypto AG
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/accounts"
+	accountsMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/mocks"
+	accountsTypes "github.com/digitalbitbox/bitbox-wallet-app/backend/accounts/types"
+	coinpkg "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin"
+	coinMocks "github.com/digitalbitbox/bitbox-wallet-app/backend/coins/coin/mocks"
+	"github.com/digitalbitbox/bitbox-wallet-app/backend/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInternalTransfers(t *testing.T) {
+	b := newBackend(t, testnetDisabled, regtestDisabled)
+	defer b.Close()
+
+	makeAccount := func(
+		code accountsTypes.Code, coinCode coinpkg.Code, txs accounts.OrderedTransactions) accounts.Interface {
+		cfg := &accounts.AccountConfig{Config: &config.Account{Code: code}}
+		coin := &coinMocks.CoinMock{CodeFunc: func() coinpkg.Code { return coinCode }}
+		return &accountsMocks.InterfaceMock{
+			ConfigFunc:       func() *accounts.AccountConfig { return cfg },
+			CoinFunc:         func() coinpkg.Coin { return coin },
+			FatalErrorFunc:   func() bool { return false },
+			InitializeFunc:   func() error { return nil },
+			TransactionsFunc: func() (accounts.OrderedTransactions, error) { return txs, nil },
+			CloseFunc:        func() {},
+		}
+	}
+	amount := coinpkg.NewAmountFromInt64
+	b.accounts = AccountsList{
+		makeAccount("btc-1", coinpkg.CodeBTC, accounts.OrderedTransactions{
+			// Pays 30 to btc-2, 40 to btc-3 and 10 to someone else.
+			{TxID: "a", InternalID: "a", Type: accounts.TxTypeSend, Amount: amount(80)},
+			{TxID: "b", InternalID: "b", Type: accounts.TxTypeReceive, Amount: amount(10)},
+		}),
+		makeAccount("btc-2", coinpkg.CodeBTC, accounts.OrderedTransactions{
+			{TxID: "a", InternalID: "a", Type: accounts.TxTypeReceive, Amount: amount(30)},
+		}),
+		makeAccount("btc-3", coinpkg.CodeBTC, accounts.OrderedTransactions{
+			{TxID: "a", InternalID: "a", Type: accounts.TxTypeReceive, Amount: amount(40)},
+			// Failed transactions are not transfers.
+			{TxID: "b", InternalID: "b", Type: accounts.TxTypeSend, Amount: amount(10), Status: accounts.TxStatusFailed},
+		}),
+		// Same hash on another network, e.g. an ERC20 token transfer next to the Ethereum
+		// transaction.
+		makeAccount("eth", coinpkg.CodeETH, accounts.OrderedTransactions{
+			{TxID: "0xa", InternalID: "0xa", Type: accounts.TxTypeSend, Amount: amount(1)},
+		}),
+		makeAccount("eth-erc20-usdt", "eth-erc20-usdt", accounts.OrderedTransactions{
+			{TxID: "0xa", InternalID: "0xa-0", Type: accounts.TxTypeReceive, Amount: amount(5)},
+		}),
+	}
+
+	transfers, err := b.InternalTransfers()
+	require.NoError(t, err)
+	require.Equal(t, []*InternalTransfer{
+		{
+			CoinCode: coinpkg.CodeBTC, TxID: "a",
+			From: "btc-1", FromInternalID: "a", To: "btc-2", ToInternalID: "a",
+			Amount: amount(30),
+		},
+		{
+			CoinCode: coinpkg.CodeBTC, TxID: "a",
+			From: "btc-1", FromInternalID: "a", To: "btc-3", ToInternalID: "a",
+			Amount: amount(40),
+		},
+	}, transfers)
+
+	require.Equal(t,
+		[]accounts.InternalTransfer{
+			{AccountCode: "btc-2", InternalID: "a", Amount: amount(30)},
+			{AccountCode: "btc-3", InternalID: "a", Amount: amount(40)},
+		},
+		b.internalTransferLinks("btc-1", "a"))
+	require.Equal(t,
+		[]accounts.InternalTransfer{{AccountCode: "btc-1", InternalID: "a", Amount: amount(30)}},
+		b.internalTransferLinks("btc-2", "a"))
+	require.Empty(t, b.internalTransferLinks("btc-1", "b"))
+	require.Empty(t, b.internalTransferLinks("eth", "0xa"))
+}
@@ -19,15 +19,15 @@ import { ChartData } from '../routes/account/summary/chart';
 import type { TDetailStatus } from './bitsurance';
 import { SuccessResponse } from './response';
 
-export type CoinCode = 'btc' | 'tbtc' | 'ltc' | 'tltc' | 'eth' | 'goeth' | 'sepeth';
+export type CoinCode = 'btc' | 'tbtc' | 'tbtc4' | 'sbtc' | 'ltc' | 'tltc' | 'eth' | 'goeth' | 'sepeth' | 'arbeth' | 'opeth' | 'baseeth' | 'pol';
 
 export type AccountCode = string;
 
-export type Fiat = 'AUD' | 'BRL' | 'BTC' | 'CAD' | 'CHF' | 'CNY' | 'CZK' | 'EUR' | 'GBP' | 'HKD' | 'ILS' | 'JPY' | 'KRW' | 'NOK' | 'PLN' | 'RUB' | 'SEK' | 'SGD' | 'USD';
+export type Fiat = 'AUD' | 'BGN' | 'BRL' | 'BTC' | 'CAD' | 'CHF' | 'CNY' | 'CZK' | 'DKK' | 'EUR' | 'GBP' | 'HKD' | 'HUF' | 'IDR' | 'ILS' | 'INR' | 'ISK' | 'JPY' | 'KRW' | 'MXN' | 'MYR' | 'NOK' | 'NZD' | 'PHP' | 'PLN' | 'RON' | 'RUB' | 'SEK' | 'SGD' | 'THB' | 'TRY' | 'USD' | 'ZAR';
 
 export type ConversionUnit = Fiat | 'sat'
 
-export type CoinUnit = 'BTC' | 'sat' | 'LTC' | 'ETH' | 'TBTC' | 'tsat' | 'TLTC' | 'GOETH' | 'SEPETH';
+export type CoinUnit = 'BTC' | 'sat' | 'LTC' | 'ETH' | 'TBTC' | 'TBTC4' | 'SBTC' | 'tsat' | 'TLTC' | 'GOETH' | 'SEPETH' | 'POL';
 
 export type ERC20TokenUnit = 'USDT' | 'USDC' | 'LINK' | 'BAT' | 'MKR' | 'ZRX' | 'WBTC' | 'PAXG' | 'DAI';
 
@@ -179,6 +179,42 @@ export const getSummary = (): Promise<ISummary> => {
   return apiGet('account-summary');
 };
 
+export interface IBalanceHistoryEntry {
+    time: number;
+    amount: string;
+    formattedAmount: string;
+    fiatValue: number | null;
+    formattedFiatValue: string;
+}
+
+export interface IBalanceHistorySeries {
+    accountCode?: AccountCode;
+    coinCode: CoinCode;
+    unit: CoinUnit;
+    dataMissing: boolean;
+    daily: IBalanceHistoryEntry[];
+    hourly: IBalanceHistoryEntry[];
+}
+
+export interface IBalanceHistory {
+    fiat: Fiat;
+    accounts: IBalanceHistorySeries[];
+    coins: IBalanceHistorySeries[];
+    lastTimestamp: number;
+}
+
+export type TBalanceHistoryResponse = {
+    success: true;
+    history: IBalanceHistory;
+} | {
+    success: false;
+    errorMessage?: string;
+};
+
+export const getBalanceHistory = (code?: AccountCode): Promise<TBalanceHistoryResponse> => {
+  return apiGet(code ? `balance-history?accountCode=${encodeURIComponent(code)}` : 'balance-history');
+};
+
 export type Conversions = {
     [key in Fiat]: string;
 }
@@ -200,8 +236,17 @@ export const getBalance = (code: AccountCode): Promise<IBalance> => {
   return apiGet(`account/${code}/balance`);
 };
 
+export interface IInternalTransfer {
+    accountCode: AccountCode;
+    internalID: string;
+    amount: IAmount;
+}
+
 export interface ITransaction {
     addresses: string[];
+    contactNames: { [address: string]: string };
+    ensNames: { [address: string]: string };
+    internalTransfers: IInternalTransfer[];
     amount: IAmount;
     amountAtTime: IAmount | null;
     fee: IAmount;
@@ -213,7 +258,7 @@ export interface ITransaction {
     numConfirmations: number;
     numConfirmationsComplete: number;
     size: number;
-    status: 'complete' | 'pending' | 'failed';
+    status: 'complete' | 'pending' | 'failed' | 'dropped';
     time: string | null;
     type: 'send' | 'receive' | 'self';
     txID: string;
@@ -221,7 +266,58 @@ export interface ITransaction {
     weight: number;
 }
 
-export type TTransactions = { success: false } | { success: true; list: ITransaction[]; };
+export type TTransactions = { success: false; errorMessage?: string; } | { success: true; list: ITransaction[]; nextCursor?: string; };
+
+export type TTransactionsQuery = {
+    start?: string;
+    end?: string;
+    type?: string;
+    status?: string;
+    minAmount?: string;
+    maxAmount?: string;
+    note?: string;
+    address?: string;
+    sort?: 'newest' | 'oldest' | 'amountDesc' | 'amountAsc';
+    cursor?: string;
+    limit?: number;
+};
+
+export type TSearchTransaction = Pick<ITransaction, 'txID' | 'internalID' | 'type' | 'status' | 'time' | 'addresses' | 'contactNames' | 'note'> & {
+    accountCode: AccountCode;
+    coinCode: CoinCode;
+    amount: { amount: string; unit: CoinUnit; };
+    internalTransfer: boolean;
+};
+
+export type TSearchTransactions = { success: false; errorMessage?: string; } | { success: true; list: TSearchTransaction[]; nextCursor?: string; };
+
+const transactionsQueryString = (query: TTransactionsQuery): string => {
+  const params = new URLSearchParams();
+  Object.entries(query).forEach(([key, value]) => {
+    if (value !== undefined && value !== '') {
+      params.set(key, String(value));
+    }
+  });
+  const queryString = params.toString();
+  return queryString ? `?${queryString}` : '';
+};
+
+export type TInternalTransfer = {
+    coinCode: CoinCode;
+    txID: string;
+    from: AccountCode;
+    fromInternalID: string;
+    to: AccountCode;
+    toInternalID: string;
+    amount: string;
+    unit: CoinUnit;
+};
+
+export type TInternalTransfers = { success: false; errorMessage?: string; } | { success: true; transfers: TInternalTransfer[]; };
+
+export const getInternalTransfers = (): Promise<TInternalTransfers> => {
+  return apiGet('internal-transfers');
+};
 
 export interface INoteTx {
     internalTxID: string;
@@ -239,8 +335,12 @@ export const proposeTxNote = (code: AccountCode, note: string): Promise<null> =>
   return apiPost(`account/${code}/propose-tx-note`, note);
 };
 
-export const getTransactionList = (code: AccountCode): Promise<TTransactions> => {
-  return apiGet(`account/${code}/transactions`);
+export const getTransactionList = (code: AccountCode, query: TTransactionsQuery = {}): Promise<TTransactions> => {
+  return apiGet(`account/${code}/transactions${transactionsQueryString(query)}`);
+};
+
+export const searchTransactions = (query: Omit<TTransactionsQuery, 'minAmount' | 'maxAmount'> = {}): Promise<TSearchTransactions> => {
+  return apiGet(`transactions/search${transactionsQueryString(query)}`);
 };
 
 export const getTransaction = (code: AccountCode, id: ITransaction['internalID']): Promise<ITransaction | null> => {
@@ -253,8 +353,33 @@ export interface IExport {
     errorMessage: string;
 }
 
-export const exportAccount = (code: AccountCode): Promise<IExport | null> => {
-  return apiPost(`account/${code}/export`);
+export type TExportFormat = 'csv' | 'json' | 'koinly' | 'cointracking';
+
+export type TExportOptions = {
+    format?: TExportFormat;
+    fiat?: string;
+    // RFC3339 timestamps.
+    start?: string;
+    end?: string;
+};
+
+export const exportAccount = (code: AccountCode, options: TExportOptions = {}): Promise<IExport | null> => {
+  return apiPost(`account/${code}/export`, options);
+};
+
+export type TExportTransactions = { success: boolean; aborted?: boolean; errorMessage?: string; };
+
+export const getExportFormats = (): Promise<TExportFormat[]> => {
+  return apiGet('export-transactions/formats');
+};
+
+// exportTransactions exports the transactions of the given accounts, or of all active accounts if
+// accountCodes is empty, into one file.
+export const exportTransactions = (
+  accountCodes: AccountCode[],
+  options: TExportOptions & { format: TExportFormat },
+): Promise<TExportTransactions> => {
+  return apiPost('export-transactions', { accountCodes, ...options });
 };
 
 export const verifyXPub = (
@@ -293,6 +418,69 @@ export const sendTx = (code: AccountCode): Promise<ISendTx> => {
 
 export type FeeTargetCode = 'custom' | 'low' | 'economy' | 'normal' | 'high';
 
+export type TEthReplaceTxProposal = {
+  success: true;
+  amount: IAmount;
+  fee: IAmount;
+  total: IAmount;
+} | {
+  success: false;
+  errorCode?: string;
+};
+
+/**
+ * Proposes a transaction replacing a pending Ethereum transaction with the same nonce and higher
+ * fees, to speed it up or to cancel it. It is signed and sent with `sendTx()`.
+ */
+export const proposeEthReplaceTx = (
+  code: AccountCode,
+  txID: string,
+  cancel: boolean,
+  feeTarget: FeeTargetCode,
+  customFee = '',
+): Promise<TEthReplaceTxProposal> => {
+  return apiPost(`account/${code}/eth-replace-tx-proposal`, { txID, cancel, feeTarget, customFee });
+};
+
+export type TEthERC20Allowance = {
+  contractAddress: string;
+  name: string;
+  symbol: string;
+  spender: string;
+  amount: string | null; // in the unit of the token, null if it could not be fetched
+  unlimited: boolean;
+};
+
+export type TEthERC20AllowancesResponse = {
+  success: true;
+  allowances?: TEthERC20Allowance[];
+} | {
+  success: false;
+  errorMessage?: string;
+};
+
+/**
+ * Lists the outstanding ERC20 token allowances of an Ethereum account.
+ */
+export const getEthERC20Allowances = (code: AccountCode): Promise<TEthERC20AllowancesResponse> => {
+  return apiGet(`account/${code}/eth-erc20-allowances`);
+};
+
+/**
+ * Proposes a transaction setting the allowance of a spender, in the unit of the token. An amount
+ * of '0' revokes the allowance. It is signed and sent with `sendTx()`.
+ */
+export const proposeEthApproveTx = (
+  code: AccountCode,
+  contractAddress: string,
+  spender: string,
+  amount: string,
+  feeTarget: FeeTargetCode,
+  customFee = '',
+): Promise<TEthReplaceTxProposal> => {
+  return apiPost(`account/${code}/eth-approve-tx-proposal`, { contractAddress, spender, amount, feeTarget, customFee });
+};
+
 export interface IProposeTxData {
     address?: string;
     amount?: number;
@@ -399,6 +587,48 @@ export const ethSignWalletConnectTx = (code: AccountCode, send: boolean, chainId
   return apiPost(`account/${code}/eth-sign-wallet-connect-tx`, { send, chainId, tx });
 };
 
+export type TSigningSummaryAction = 'send' | 'transfer' | 'approve' | 'approveAll' | 'nftTransfer' | 'permit' | 'swap' | 'unknown';
+
+export type TSigningSummaryToken = {
+  address: string;
+  name?: string;
+  symbol?: string;
+}
+
+export type TSigningSummary = {
+  action: TSigningSummaryAction;
+  method?: string;
+  contract?: string;
+  contractVerified?: boolean;
+  value?: string;
+  token?: TSigningSummaryToken;
+  amount?: string;
+  tokenID?: string;
+  recipient?: string;
+  spender?: string;
+  unlimitedApproval: boolean;
+  expiration?: string;
+  tokenOut?: TSigningSummaryToken;
+  amountOut?: string;
+  tokens?: number;
+}
+
+export type TDescribeSigningRequest = {
+  success: false;
+  errorMessage?: string;
+} | {
+  success: true;
+  summary: TSigningSummary;
+}
+
+export const ethDescribeTypedMessage = (code: AccountCode, data: any): Promise<TDescribeSigningRequest> => {
+  return apiPost(`account/${code}/eth-describe-typed-msg`, { data });
+};
+
+export const ethDescribeWalletConnectTx = (code: AccountCode, chainId: number, tx: any): Promise<TDescribeSigningRequest> => {
+  return apiPost(`account/${code}/eth-describe-wallet-connect-tx`, { chainId, tx });
+};
+
 export type AddressSignResponse = {
   success: true;
   signature: string;
@@ -409,7 +639,79 @@ export type AddressSignResponse = {
   errorCode?: 'userAbort' | 'wrongKeystore';
 }
 
-export const signAddress = (format: string, msg: string, code: AccountCode): Promise<AddressSignResponse> => {
-  return apiPost(`account/${code}/sign-address`, { format, msg, code });
+export type TMessageSignatureFormat = 'legacy' | 'bip322-simple' | 'bip322-full';
+
+export const signAddress = (
+  format: string,
+  msg: string,
+  code: AccountCode,
+  signatureFormat?: TMessageSignatureFormat,
+): Promise<AddressSignResponse> => {
+  return apiPost(`account/${code}/sign-address`, { format, msg, code, signatureFormat });
+};
+
+export type TVerifyMessageResponse = {
+  success: true;
+  valid: true;
+  format: TMessageSignatureFormat;
+} | {
+  success: true;
+  valid: false;
+  errorMessage: string;
+} | {
+  success: false;
+  errorMessage: string;
+};
+
+export const verifyMessage = (
+  code: AccountCode,
+  address: string,
+  msg: string,
+  signature: string,
+): Promise<TVerifyMessageResponse> => {
+  return apiPost(`account/${code}/verify-message`, { address, msg, signature });
+};
+
+
+export type TProofOfReservesResponse = {
+  success: true;
+  proof: string;
+} | {
+  success: false;
+  errorMessage?: string;
+  errorCode?: 'userAbort' | 'wrongKeystore';
+};
+
+/**
+ * Creates a BIP-127 proof of reserves for the message, proving the given outpoints (`txid:vout`),
+ * or all outputs of the account if none are given. The proof is a base64 encoded PSBT.
+ */
+export const proofOfReserves = (
+  code: AccountCode,
+  message: string,
+  outPoints: string[] = [],
+  saveToFile: boolean = false,
+): Promise<TProofOfReservesResponse> => {
+  return apiPost(`account/${code}/proof-of-reserves`, { message, outPoints, saveToFile });
+};
+
+export type TVerifyProofOfReservesResponse = {
+  success: true;
+  valid: true;
+  amount: IAmount;
+} | {
+  success: true;
+  valid: false;
+  errorMessage: string;
+} | {
+  success: false;
+  errorMessage: string;
 };
 
+export const verifyProofOfReserves = (
+  code: AccountCode,
+  message: string,
+  proof: string,
+): Promise<TVerifyProofOfReservesResponse> => {
+  return apiPost(`account/${code}/verify-proof-of-reserves`, { message, proof });
+};
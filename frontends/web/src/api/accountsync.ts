@@ -76,3 +76,18 @@ export const syncdone = (
     }
   });
 };
+
+/**
+ * Fired when the transactions of the account changed outside of a sync,
+ * e.g. when the ENS names of their addresses were found.
+ * Returns a method to unsubscribe.
+ */
+export const transactionsChanged = (
+  cb: (code: accountAPI.AccountCode) => void,
+): TUnsubscribe => {
+  return subscribeLegacy('transactionsChanged', event => {
+    if (event.type === 'account' && event.code) {
+      cb(event.code);
+    }
+  });
+};
@@ -44,6 +44,54 @@ export const setTokenActive = (accountCode: AccountCode, tokenCode: string, acti
   return apiPost('set-token-active', { accountCode, tokenCode, active });
 };
 
+export type TERC20TokenWarning = 'noName' | 'unusualSymbol' | 'unusualDecimals' | 'symbolInUse' | 'noPrice';
+
+export type TERC20TokenErrorCode = 'erc20InvalidAddress' | 'erc20NotAToken';
+
+export type TCustomERC20Token = {
+  code: string;
+  contractAddress: string;
+  name: string;
+  symbol: string;
+  decimals: number;
+};
+
+export const getERC20Tokens = (): Promise<TCustomERC20Token[]> => {
+  return apiGet('erc20-tokens');
+};
+
+export const addERC20Token = (
+  accountCode: AccountCode,
+  contractAddress: string,
+): Promise<ISuccess & { tokenCode?: string; warnings?: TERC20TokenWarning[] }> => {
+  return apiPost('erc20-tokens/add', { accountCode, contractAddress });
+};
+
+export const addERC20Tokens = (
+  accountCode: AccountCode,
+  contractAddresses: string[],
+): Promise<ISuccess & { added?: { tokenCode: string; warnings: TERC20TokenWarning[] }[] }> => {
+  return apiPost('erc20-tokens/add-multiple', { accountCode, contractAddresses });
+};
+
+export type TDiscoveredERC20Token = {
+  contractAddress: string;
+  tokenCode?: string;
+  name: string;
+  symbol: string;
+  decimals: number;
+  balance: string | null;
+  transfers: number;
+  active: boolean;
+  spam: boolean;
+};
+
+export type TDiscoverERC20TokensResponse = FailResponse | (SuccessResponse & { tokens?: TDiscoveredERC20Token[] });
+
+export const discoverERC20Tokens = (accountCode: AccountCode): Promise<TDiscoverERC20TokensResponse> => {
+  return apiGet(`erc20-tokens/discover?accountCode=${encodeURIComponent(accountCode)}`);
+};
+
 export const renameAccount = (accountCode: AccountCode, name: string): Promise<ISuccess> => {
   return apiPost('rename-account', { accountCode, name });
 };
@@ -128,3 +176,18 @@ export const subscribeAuth = (
 export const onAuthSettingChanged = (): Promise<void> => {
   return apiPost('on-auth-setting-changed');
 };
+
+export type TRatesProviderHealth = {
+  name: string;
+  healthy: boolean;
+  lastSuccess: string | null;
+  lastFailure: string | null;
+  lastError?: string;
+  consecutiveFailures: number;
+  successes: number;
+  failures: number;
+};
+
+export const getRatesProviders = (): Promise<TRatesProviderHealth[]> => {
+  return apiGet('rates/providers');
+};
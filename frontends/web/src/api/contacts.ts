@@ -0,0 +1,45 @@
+/**
+ * Copyright 2024 Shift Crypto AG
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import { CoinCode } from './account';
+import { apiGet, apiPost } from '../utils/request';
+import { ISuccess } from './backend';
+
+export type TContactErrorCode = 'contactInvalidAddress' | 'contactAlreadyExists';
+
+export interface IContact {
+  id: string;
+  name: string;
+  coinCode: CoinCode;
+  address: string;
+  notes: string;
+}
+
+export const getContacts = (): Promise<IContact[]> => {
+  return apiGet('contacts');
+};
+
+export const addContact = (contact: Omit<IContact, 'id'>): Promise<ISuccess & { id?: string }> => {
+  return apiPost('contacts/add', contact);
+};
+
+export const updateContact = (contact: IContact): Promise<ISuccess> => {
+  return apiPost('contacts/update', contact);
+};
+
+export const deleteContact = (id: string): Promise<ISuccess> => {
+  return apiPost('contacts/delete', id);
+};
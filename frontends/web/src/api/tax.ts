@@ -0,0 +1,117 @@
+/**
+ * Copyright 2024 Shift Crypto AG
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import { AccountCode, CoinCode } from './account';
+import { apiGet, apiPost } from '../utils/request';
+
+export type TTaxMethod = 'fifo' | 'lifo' | 'hifo';
+
+export type TTaxReportQuery = {
+  year: number;
+  fiat?: string;
+  method?: TTaxMethod;
+  accountCode?: AccountCode;
+};
+
+export interface ITaxLot {
+  accountCode: AccountCode;
+  coinCode: CoinCode;
+  txID: string;
+  acquired: string;
+  amount: string;
+  costBasis: string;
+  priceMissing: boolean;
+}
+
+export interface ITaxLotMatch extends ITaxLot {
+  proceeds: string;
+  gain: string;
+  longTerm: boolean;
+}
+
+export interface ITaxDisposal {
+  accountCode: AccountCode;
+  coinCode: CoinCode;
+  txID: string;
+  time: string;
+  amount: string;
+  fee: boolean;
+  proceeds: string;
+  costBasis: string;
+  gain: string;
+  priceMissing: boolean;
+  lots: ITaxLotMatch[];
+}
+
+export interface ITaxHolding extends ITaxLot {
+  marketValue: string;
+  unrealizedGain: string;
+  marketPriceMissing: boolean;
+}
+
+export interface ITaxReport {
+  year: number;
+  fiat: string;
+  method: TTaxMethod;
+  accountCode: AccountCode;
+  disposals: ITaxDisposal[];
+  holdings: ITaxHolding[];
+  totals: {
+    proceeds: string;
+    costBasis: string;
+    realizedGain: string;
+    shortTermGain: string;
+    longTermGain: string;
+    marketValue: string;
+    holdingsCostBasis: string;
+    unrealizedGain: string;
+  };
+  incomplete: boolean;
+}
+
+export type TTaxReportResponse = {
+  success: true;
+  report: ITaxReport;
+} | {
+  success: false;
+  errorMessage: string;
+};
+
+export const getTaxReport = (query: TTaxReportQuery): Promise<TTaxReportResponse> => {
+  const params = new URLSearchParams({ year: String(query.year) });
+  if (query.fiat) {
+    params.set('fiat', query.fiat);
+  }
+  if (query.method) {
+    params.set('method', query.method);
+  }
+  if (query.accountCode) {
+    params.set('accountCode', query.accountCode);
+  }
+  return apiGet(`tax-report?${params.toString()}`);
+};
+
+export type TTaxReportExportResponse = {
+  success: boolean;
+  aborted?: boolean;
+  errorMessage?: string;
+};
+
+export const exportTaxReport = (
+  query: TTaxReportQuery & { format: 'csv' | 'json' },
+): Promise<TTaxReportExportResponse> => {
+  return apiPost('tax-report/export', query);
+};
@@ -64,6 +64,8 @@ export const Amount = ({ amount, unit, removeBtcTrailingZeroes, alwaysShowAmount
   switch (unit) {
   case 'BTC':
   case 'TBTC':
+  case 'TBTC4':
+  case 'SBTC':
   case 'LTC':
   case 'TLTC':
     if (removeBtcTrailingZeroes && amount.includes('.')) {
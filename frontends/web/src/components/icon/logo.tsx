@@ -77,12 +77,18 @@ type LogoMap = {
 const logoMap: LogoMap = {
   'btc': [BTC, BTC_GREY],
   'tbtc': [BTC, BTC_GREY],
+  'tbtc4': [BTC, BTC_GREY],
+  'sbtc': [BTC, BTC_GREY],
   'rbtc': [BTC, BTC_GREY],
   'ltc': [LTC, LTC_GREY],
   'tltc': [LTC, LTC_GREY],
   'eth': [ETH, ETH_GREY],
   'goeth': [ETH, ETH_GREY],
   'sepeth': [ETH, ETH_GREY],
+  'arbeth': [ETH, ETH_GREY],
+  'opeth': [ETH, ETH_GREY],
+  'baseeth': [ETH, ETH_GREY],
+  'pol': [ETH, ETH_GREY],
   'erc20Test': [ETH, ETH_GREY],
 
   'eth-erc20-usdt': [USDT, USDT_GREY],
@@ -27,23 +27,37 @@ type FiatWithDisplayName = {
 
 export const currenciesWithDisplayName: FiatWithDisplayName[] = [
   { currency: 'AUD', displayName: 'Australian Dollar' },
+  { currency: 'BGN', displayName: 'Bulgarian Lev' },
   { currency: 'BRL', displayName: 'Brazilian Real' },
   { currency: 'CAD', displayName: 'Canadian Dollar' },
   { currency: 'CHF', displayName: 'Swiss franc' },
   { currency: 'CNY', displayName: 'Chinese Yuan' },
   { currency: 'CZK', displayName: 'Czech Koruna' },
+  { currency: 'DKK', displayName: 'Danish Krone' },
   { currency: 'EUR', displayName: 'Euro' },
   { currency: 'GBP', displayName: 'British Pound' },
   { currency: 'HKD', displayName: 'Hong Kong Dollar' },
+  { currency: 'HUF', displayName: 'Hungarian Forint' },
+  { currency: 'IDR', displayName: 'Indonesian Rupiah' },
   { currency: 'ILS', displayName: 'Israeli New Shekel' },
+  { currency: 'INR', displayName: 'Indian Rupee' },
+  { currency: 'ISK', displayName: 'Icelandic Krona' },
   { currency: 'JPY', displayName: 'Japanese Yen' },
   { currency: 'KRW', displayName: 'South Korean Won' },
+  { currency: 'MXN', displayName: 'Mexican Peso' },
+  { currency: 'MYR', displayName: 'Malaysian Ringgit' },
   { currency: 'NOK', displayName: 'Norwegian Krone' },
+  { currency: 'NZD', displayName: 'New Zealand Dollar' },
+  { currency: 'PHP', displayName: 'Philippine Peso' },
   { currency: 'PLN', displayName: 'Polish Zloty' },
+  { currency: 'RON', displayName: 'Romanian Leu' },
   { currency: 'RUB', displayName: 'Russian ruble' },
   { currency: 'SEK', displayName: 'Swedish Krona' },
   { currency: 'SGD', displayName: 'Singapore Dollar' },
+  { currency: 'THB', displayName: 'Thai Baht' },
+  { currency: 'TRY', displayName: 'Turkish Lira' },
   { currency: 'USD', displayName: 'United States Dollar' },
+  { currency: 'ZAR', displayName: 'South African Rand' },
   { currency: 'BTC', displayName: 'Bitcoin' }
 ];
 
@@ -128,6 +128,9 @@ class Transaction extends Component<Props, State> {
       numConfirmationsComplete,
       time,
       addresses,
+      contactNames,
+      ensNames,
+      internalTransfers,
       status,
       note = '',
     } = this.props;
@@ -151,6 +154,7 @@ class Transaction extends Component<Props, State> {
       complete: t('transaction.status.complete'),
       pending: t('transaction.status.pending'),
       failed: t('transaction.status.failed'),
+      dropped: t('transaction.status.dropped'),
     }[status];
     const progress = numConfirmations < numConfirmationsComplete ? (numConfirmations / numConfirmationsComplete) * 100 : 100;
     const darkmode = getDarkmode();
@@ -175,10 +179,14 @@ class Transaction extends Component<Props, State> {
             ) : (
               <div className={parentStyle.activity}>
                 <span className={style.label}>
-                  {t(type === 'receive' ? 'transaction.tx.received' : 'transaction.tx.sent')}
+                  {internalTransfers.length > 0 ? (
+                    t(type === 'receive' ? 'transaction.tx.internalTransferIn' : 'transaction.tx.internalTransferOut')
+                  ) : (
+                    t(type === 'receive' ? 'transaction.tx.received' : 'transaction.tx.sent')
+                  )}
                 </span>
                 <span className={style.address}>
-                  {addresses[0]}
+                  {contactNames[addresses[0]] || ensNames[addresses[0]] || addresses[0]}
                   {addresses.length > 1 && (
                     <span className={style.badge}>
                                             (+{addresses.length - 1})
@@ -18,6 +18,8 @@ import { useTranslation } from 'react-i18next';
 import { useDarkmode } from '../../hooks/darkmode';
 import { Dialog, DialogButtons } from '../dialog/dialog';
 import { Button } from '../forms';
+import { Message } from '../message/message';
+import { TSigningSummary, TSigningSummaryToken } from '../../api/account';
 import { SUPPORTED_CHAINS, truncateAddress } from '../../utils/walletconnect';
 import { TRequestDialogContent } from '../../utils/walletconnect-eth-sign-handlers';
 import { AnimatedChecked, PointToBitBox02, WalletConnectDark, WalletConnectLight } from '../icon';
@@ -53,6 +55,85 @@ const RequestSuccessfullySigned = () => {
   );
 };
 
+const formatToken = (token: TSigningSummaryToken) => {
+  return token.symbol || token.name || truncateAddress(token.address);
+};
+
+type TSummaryItemProps = {
+  label: string;
+  value?: string;
+}
+
+const SummaryItem = ({ label, value }: TSummaryItemProps) => {
+  if (!value) {
+    return null;
+  }
+  return (
+    <li className={styles.item}>
+      <p className={styles.label}>{label}</p>
+      <p className={styles.itemText}>{value}</p>
+    </li>
+  );
+};
+
+const SigningSummary = ({ summary }: { summary: TSigningSummary }) => {
+  const { t } = useTranslation();
+  const amount = summary.unlimitedApproval ?
+    t('walletConnect.signingRequest.summary.unlimited') :
+    summary.amount;
+  return (
+    <>
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.action.label')}
+        value={t(`walletConnect.signingRequest.summary.action.${summary.action}`)} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.token')}
+        value={summary.token && formatToken(summary.token)} />
+      {summary.tokens ? (
+        <SummaryItem
+          label={t('walletConnect.signingRequest.summary.token')}
+          value={t('walletConnect.signingRequest.summary.tokens', { count: summary.tokens })} />
+      ) : null}
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.amount')}
+        value={amount} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.tokenID')}
+        value={summary.tokenID} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.tokenOut')}
+        value={summary.tokenOut && formatToken(summary.tokenOut)} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.amountOut')}
+        value={summary.amountOut} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.value')}
+        value={summary.value} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.recipient')}
+        value={summary.recipient} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.spender')}
+        value={summary.spender} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.expiration')}
+        value={summary.expiration && new Date(summary.expiration).toLocaleString()} />
+      <SummaryItem
+        label={t('walletConnect.signingRequest.summary.contract')}
+        value={summary.contract} />
+      {summary.unlimitedApproval && (
+        <li className={styles.item}>
+          <Message type="warning">{t('walletConnect.signingRequest.summary.unlimitedWarning')}</Message>
+        </li>
+      )}
+      {summary.contractVerified === false && (
+        <li className={styles.item}>
+          <Message type="warning">{t('walletConnect.signingRequest.summary.unverifiedContract')}</Message>
+        </li>
+      )}
+    </>
+  );
+};
 
 export const WCIncomingSignRequestDialog = ({
   open,
@@ -63,7 +144,7 @@ export const WCIncomingSignRequestDialog = ({
 }: TRequestDialogProps) => {
   const { t } = useTranslation();
   const { isDarkMode } = useDarkmode();
-  const { accountAddress, accountName, signingData, chain, method, currentSession } = content;
+  const { accountAddress, accountName, signingData, chain, method, currentSession, summary } = content;
 
   const formattedChain = chain in SUPPORTED_CHAINS ? SUPPORTED_CHAINS[chain].name : chain;
   const chainIcon = chain in SUPPORTED_CHAINS ? SUPPORTED_CHAINS[chain].icon : null;
@@ -113,6 +194,8 @@ export const WCIncomingSignRequestDialog = ({
                 <p className={styles.itemText}>{method}</p>
               </li>
 
+              {summary && <SigningSummary summary={summary} />}
+
               {signingData &&
             (
               <li className={styles.item}>
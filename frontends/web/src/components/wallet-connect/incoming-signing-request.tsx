@@ -37,7 +37,7 @@ export const WCSigningRequest = () => {
   const requestDataRef = useRef<TSigningRequestData>();
 
   const launchSignDialog = ({ topic, id, apiCaller, dialogContent }: TLaunchSignDialog) => {
-    const { signingData, currentSession, accountAddress, accountName, chain, method } = dialogContent;
+    const { signingData, currentSession, accountAddress, accountName, chain, method, summary } = dialogContent;
 
     // storing data to be used whenever
     // user accepts or rejects later
@@ -54,7 +54,8 @@ export const WCSigningRequest = () => {
       signingData,
       chain,
       currentSession,
-      method
+      method,
+      summary
     });
 
     // opening the dialog
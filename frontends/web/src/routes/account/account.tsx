@@ -19,7 +19,7 @@ import { useCallback, useEffect, useState } from 'react';
 import { useTranslation } from 'react-i18next';
 import { Link } from 'react-router-dom';
 import * as accountApi from '../../api/account';
-import { statusChanged, syncAddressesCount, syncdone } from '../../api/accountsync';
+import { statusChanged, syncAddressesCount, syncdone, transactionsChanged } from '../../api/accountsync';
 import { bitsuranceLookup } from '../../api/bitsurance';
 import { TDevices } from '../../api/devices';
 import { getExchangeBuySupported, SupportedExchanges } from '../../api/exchanges';
@@ -181,6 +181,7 @@ export function Account({
       syncAddressesCount(code)(setSyncedAddressesCount),
       statusChanged((eventCode) => eventCode === code && onStatusChanged()),
       syncdone((eventCode) => eventCode === code && onAccountChanged(code, status)),
+      transactionsChanged((eventCode) => eventCode === code && onAccountChanged(code, status)),
     ];
     return () => unsubscribe(subscriptions);
   }, [code, onAccountChanged, onStatusChanged, status]);
@@ -21,7 +21,7 @@ import { useLoad } from '../../../hooks/api';
 import { useEsc } from '../../../hooks/keyboard';
 import * as accountApi from '../../../api/account';
 import { route } from '../../../utils/route';
-import { getScriptName, isEthereumBased } from '../utils';
+import { getScriptName, isBitcoinOnly, isEthereumBased } from '../utils';
 import { alertUser } from '../../../components/alert/Alert';
 import { CopyableInput } from '../../../components/copy/Copy';
 import { Dialog, DialogButtons } from '../../../components/dialog/dialog';
@@ -136,7 +136,7 @@ export const Receive = ({
 
   let uriPrefix = '';
   if (account) {
-    if (account.coinCode === 'btc' || account.coinCode === 'tbtc') {
+    if (isBitcoinOnly(account.coinCode)) {
       uriPrefix = 'bitcoin:';
     } else if (account.coinCode === 'ltc' || account.coinCode === 'tltc') {
       uriPrefix = 'litecoin:';
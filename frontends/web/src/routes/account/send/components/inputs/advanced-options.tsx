@@ -0,0 +1,84 @@
+/**
+ * Copyright 2024 Shift Crypto AG
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import { ChangeEvent } from 'react';
+import { useTranslation } from 'react-i18next';
+import { Input } from '../../../../../components/forms';
+import style from './note-input.module.css';
+
+export type TAdvancedOptions = {
+  nonce: string;
+  gasLimit: string;
+  data: string;
+}
+
+type TProps = TAdvancedOptions & {
+  nonceError?: string;
+  gasLimitError?: string;
+  dataError?: string;
+  onChange: (options: Partial<TAdvancedOptions>) => void;
+}
+
+/**
+ * Optional overrides of the nonce, gas limit and calldata of Ethereum transactions, e.g. to call
+ * a contract or to replace a stuck transaction. Empty fields are computed by the backend.
+ */
+export const AdvancedOptions = ({
+  nonce,
+  gasLimit,
+  data,
+  nonceError,
+  gasLimitError,
+  dataError,
+  onChange,
+}: TProps) => {
+  const { t } = useTranslation();
+  return (
+    <>
+      <Input
+        label={t('send.advanced.nonce.label')}
+        labelSection={
+          <span className={style.labelDescription}>
+            {t('send.advanced.nonce.description')}
+          </span>
+        }
+        id="nonce"
+        error={nonceError}
+        onInput={(e: ChangeEvent<HTMLInputElement>) => onChange({ nonce: e.target.value.trim() })}
+        value={nonce}
+        placeholder={t('send.advanced.automatic')} />
+      <Input
+        label={t('send.advanced.gasLimit')}
+        id="gasLimit"
+        error={gasLimitError}
+        onInput={(e: ChangeEvent<HTMLInputElement>) => onChange({ gasLimit: e.target.value.trim() })}
+        value={gasLimit}
+        placeholder={t('send.advanced.automatic')} />
+      <Input
+        label={t('send.advanced.data.label')}
+        labelSection={
+          <span className={style.labelDescription}>
+            {t('send.advanced.data.description')}
+          </span>
+        }
+        id="data"
+        error={dataError}
+        onInput={(e: ChangeEvent<HTMLInputElement>) => onChange({ data: e.target.value.trim() })}
+        value={data}
+        placeholder="0x" />
+    </>
+  );
+};
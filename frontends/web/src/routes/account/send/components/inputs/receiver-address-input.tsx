@@ -33,6 +33,8 @@ type TReceiverAddressInputProps = {
     addressError?: string;
     onInputChange: (value: string) => void;
     recipientAddress: string;
+    // resolvedAddress is shown if the recipient is a name, e.g. an ENS name, resolving to it.
+    resolvedAddress?: string;
     activeScanQR: boolean;
     parseQRResult: (uri: string) => void;
     onChangeActiveScanQR: (activeScanQR: boolean) => void
@@ -51,6 +53,7 @@ export const ReceiverAddressInput = ({
   addressError,
   onInputChange,
   recipientAddress,
+  resolvedAddress,
   activeScanQR,
   parseQRResult,
   onChangeActiveScanQR
@@ -103,6 +106,11 @@ export const ReceiverAddressInput = ({
         autoFocus>
         <ScanQRButton onClick={toggleScanQR} />
       </Input>
+      {resolvedAddress && (
+        <p className={style.resolvedAddress}>
+          {t('send.address.resolved', { address: resolvedAddress })}
+        </p>
+      )}
     </>
   );
 };
\ No newline at end of file
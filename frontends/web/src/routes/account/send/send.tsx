@@ -34,7 +34,7 @@ import { FeeTargets } from './feetargets';
 import { route } from '../../../utils/route';
 import { signConfirm, signProgress, TSignProgress } from '../../../api/devicessync';
 import { UnsubscribeList, unsubscribe } from '../../../utils/subscriptions';
-import { isBitcoinBased, findAccount } from '../utils';
+import { isBitcoinBased, isBitcoinOnly, isEthereumBased, findAccount } from '../utils';
 import { ConfirmingWaitDialog } from './components/dialogs/confirm-wait-dialog';
 import { SendGuide } from './send-guide';
 import { MessageWaitDialog } from './components/dialogs/message-wait-dialog';
@@ -42,6 +42,7 @@ import { ReceiverAddressInput } from './components/inputs/receiver-address-input
 import { CoinInput } from './components/inputs/coin-input';
 import { FiatInput } from './components/inputs/fiat-input';
 import { NoteInput } from './components/inputs/note-input';
+import { AdvancedOptions, TAdvancedOptions } from './components/inputs/advanced-options';
 import { TSelectedUTXOs, UTXOs } from './utxos';
 import { TProposalError, txProposalErrorHandling } from './services';
 import style from './send.module.css';
@@ -64,6 +65,10 @@ export type State = {
     proposedFee?: accountApi.IAmount;
     proposedTotal?: accountApi.IAmount;
     recipientAddress: string;
+    // Address an ENS name entered as recipient resolves to, as returned by the tx proposal.
+    proposedRecipientAddress?: string;
+    // BIP-78 payjoin endpoint from the `pj` parameter of a scanned BIP-21 URI.
+    payjoinURL: string;
     proposedAmount?: accountApi.IAmount;
     valid: boolean;
     amount: string;
@@ -79,6 +84,9 @@ export type State = {
     addressError?: TProposalError['addressError'];
     amountError?: TProposalError['amountError'];
     feeError?: TProposalError['feeError'];
+    nonceError?: TProposalError['nonceError'];
+    gasLimitError?: TProposalError['gasLimitError'];
+    dataError?: TProposalError['dataError'];
     paired?: boolean;
     noMobileChannelError?: boolean;
     signProgress?: TSignProgress;
@@ -89,6 +97,9 @@ export type State = {
     activeCoinControl: boolean;
     activeScanQR: boolean;
     note: string;
+    // Nonce, gas limit and data overrides of Ethereum transactions.
+    activeAdvancedOptions: boolean;
+    advancedOptions: TAdvancedOptions;
 }
 
 class Send extends Component<Props, State> {
@@ -103,6 +114,7 @@ class Send extends Component<Props, State> {
 
   public readonly state: State = {
     recipientAddress: '',
+    payjoinURL: '',
     amount: '',
     fiatAmount: '',
     valid: false,
@@ -119,6 +131,8 @@ class Send extends Component<Props, State> {
     activeCoinControl: false,
     activeScanQR: false,
     note: '',
+    activeAdvancedOptions: false,
+    advancedOptions: { nonce: '', gasLimit: '', data: '' },
     customFee: '',
   };
 
@@ -215,6 +229,8 @@ class Send extends Component<Props, State> {
           isConfirming: false,
           isSent: true,
           recipientAddress: '',
+          proposedRecipientAddress: undefined,
+          payjoinURL: '',
           proposedAmount: undefined,
           proposedFee: undefined,
           proposedTotal: undefined,
@@ -222,6 +238,7 @@ class Send extends Component<Props, State> {
           amount: '',
           note: '',
           customFee: '',
+          advancedOptions: { nonce: '', gasLimit: '', data: '' },
         });
         this.selectedUTXOs = {};
         setTimeout(() => this.setState({
@@ -234,6 +251,7 @@ class Send extends Component<Props, State> {
       } else {
         switch (result.errorCode) {
         case 'erc20InsufficientGasFunds':
+        case 'silentPaymentsNotSupported':
           alertUser(this.props.t(`send.error.${result.errorCode}`));
           break;
         default:
@@ -254,8 +272,10 @@ class Send extends Component<Props, State> {
     amount: this.state.amount,
     feeTarget: this.state.feeTarget || '',
     customFee: this.state.customFee,
+    payjoinURL: this.state.payjoinURL,
     sendAll: this.state.sendAll ? 'yes' : 'no',
     selectedUTXOs: Object.keys(this.selectedUTXOs),
+    ...this.state.advancedOptions,
   });
 
   private sendDisabled = () => {
@@ -266,9 +286,13 @@ class Send extends Component<Props, State> {
   private validateAndDisplayFee = (updateFiat: boolean = true) => {
     this.setState({
       proposedTotal: undefined,
+      proposedRecipientAddress: undefined,
       addressError: undefined,
       amountError: undefined,
       feeError: undefined,
+      nonceError: undefined,
+      gasLimitError: undefined,
+      dataError: undefined,
     });
     if (this.sendDisabled()) {
       return;
@@ -311,6 +335,7 @@ class Send extends Component<Props, State> {
         fee: accountApi.IAmount;
         success: boolean;
         total: accountApi.IAmount;
+        recipientAddress?: string;
     }) => {
     this.setState({ valid: result.success });
     if (result.success) {
@@ -321,6 +346,7 @@ class Send extends Component<Props, State> {
         proposedFee: result.fee,
         proposedAmount: result.amount,
         proposedTotal: result.total,
+        proposedRecipientAddress: result.recipientAddress,
         isUpdatingProposal: false,
       });
       if (updateFiat) {
@@ -402,6 +428,19 @@ class Send extends Component<Props, State> {
     });
   };
 
+  private toggleAdvancedOptions = () => {
+    this.setState(({ activeAdvancedOptions }) => ({
+      activeAdvancedOptions: !activeAdvancedOptions,
+      advancedOptions: { nonce: '', gasLimit: '', data: '' },
+    }), this.validateAndDisplayFee);
+  };
+
+  private onAdvancedOptionsChange = (options: Partial<TAdvancedOptions>) => {
+    this.setState(({ advancedOptions }) => ({
+      advancedOptions: { ...advancedOptions, ...options },
+    }), () => this.validateAndDisplayFee(false));
+  };
+
   private setActiveScanQR = (activeScanQR: boolean) => {
     this.setState({ activeScanQR });
   };
@@ -409,6 +448,7 @@ class Send extends Component<Props, State> {
   private parseQRResult = async (uri: string) => {
     let address;
     let amount = '';
+    let payjoinURL = '';
     try {
       const url = new URL(uri);
       if (url.protocol !== 'bitcoin:' && url.protocol !== 'litecoin:' && url.protocol !== 'ethereum:') {
@@ -418,19 +458,21 @@ class Send extends Component<Props, State> {
       address = url.pathname;
       if (this.isBitcoinBased()) {
         amount = url.searchParams.get('amount') || '';
+        payjoinURL = url.searchParams.get('pj') || '';
       }
     } catch {
       address = uri;
     }
     let updateState = {
       recipientAddress: address,
+      payjoinURL,
       sendAll: false,
       fiatAmount: ''
     } as Pick<State, keyof State>;
 
     const coinCode = this.getAccount()!.coinCode;
     if (amount) {
-      if (coinCode === 'btc' || coinCode === 'tbtc') {
+      if (isBitcoinOnly(coinCode)) {
         const result = await parseExternalBtcAmount(amount);
         if (result.success) {
           updateState['amount'] = result.amount;
@@ -456,7 +498,7 @@ class Send extends Component<Props, State> {
   };
 
   private onReceiverAddressInputChange = (recipientAddress: string) => {
-    this.setState({ recipientAddress }, () => {
+    this.setState({ recipientAddress, payjoinURL: '' }, () => {
       this.validateAndDisplayFee(true);
     });
   };
@@ -484,6 +526,7 @@ class Send extends Component<Props, State> {
       proposedFee,
       proposedTotal,
       recipientAddress,
+      proposedRecipientAddress,
       proposedAmount,
       valid,
       amount,
@@ -500,6 +543,9 @@ class Send extends Component<Props, State> {
       addressError,
       amountError,
       feeError,
+      nonceError,
+      gasLimitError,
+      dataError,
       paired,
       signProgress,
       signConfirm,
@@ -508,15 +554,21 @@ class Send extends Component<Props, State> {
       activeCoinControl,
       activeScanQR,
       note,
+      activeAdvancedOptions,
+      advancedOptions,
     } = this.state;
 
+    const resolvedAddress = proposedRecipientAddress
+      && proposedRecipientAddress.toLowerCase() !== recipientAddress.trim().toLowerCase()
+      ? proposedRecipientAddress : undefined;
+
     const waitDialogTransactionDetails = {
       proposedFee,
       proposedAmount,
       proposedTotal,
       customFee,
       feeTarget,
-      recipientAddress,
+      recipientAddress: resolvedAddress ? `${resolvedAddress} (${recipientAddress})` : recipientAddress,
       fiatUnit,
     };
 
@@ -568,6 +620,14 @@ class Send extends Component<Props, State> {
                       {t('send.toggleCoinControl')}
                     </Button>
                   )}
+                  { isEthereumBased(account.coinCode) && !account.isToken && (
+                    <Button
+                      className="m-bottom-quarter p-right-none"
+                      transparent
+                      onClick={this.toggleAdvancedOptions}>
+                      {t('send.advanced.toggle')}
+                    </Button>
+                  )}
                 </div>
                 <Grid col="1">
                   <Column>
@@ -576,6 +636,7 @@ class Send extends Component<Props, State> {
                       addressError={addressError}
                       onInputChange={this.onReceiverAddressInputChange}
                       recipientAddress={recipientAddress}
+                      resolvedAddress={resolvedAddress}
                       parseQRResult={this.parseQRResult}
                       activeScanQR={activeScanQR}
                       onChangeActiveScanQR={this.setActiveScanQR}
@@ -605,6 +666,18 @@ class Send extends Component<Props, State> {
                     />
                   </Column>
                 </Grid>
+                { activeAdvancedOptions && (
+                  <Grid col="1">
+                    <Column>
+                      <AdvancedOptions
+                        {...advancedOptions}
+                        nonceError={nonceError}
+                        gasLimitError={gasLimitError}
+                        dataError={dataError}
+                        onChange={this.onAdvancedOptionsChange} />
+                    </Column>
+                  </Grid>
+                )}
                 <Grid>
                   <Column>
                     <FeeTargets
@@ -33,6 +33,21 @@ describe('send services', () => {
       expect(result).toEqual({ addressError: 'send.error.invalidAddress' });
     });
 
+    it('returns ENS name not found message on ensNameNotFound error', () => {
+      const result = txProposalErrorHandling(mockRegisterEvents, mockUnregisterEvents, 'ensNameNotFound');
+      expect(result).toEqual({ addressError: 'send.error.ensNameNotFound' });
+    });
+
+    it('returns nonce gap message on nonceGap error', () => {
+      const result = txProposalErrorHandling(mockRegisterEvents, mockUnregisterEvents, 'nonceGap');
+      expect(result).toEqual({ nonceError: 'send.error.nonceGap' });
+    });
+
+    it('returns gas limit too low message on gasLimitTooLow error', () => {
+      const result = txProposalErrorHandling(mockRegisterEvents, mockUnregisterEvents, 'gasLimitTooLow');
+      expect(result).toEqual({ gasLimitError: 'send.error.gasLimitTooLow' });
+    });
+
     it('returns invalid amount message on invalidAmount error', () => {
       const result = txProposalErrorHandling(mockRegisterEvents, mockUnregisterEvents, 'invalidAmount');
       expect(result).toEqual({ amountError: 'send.error.invalidAmount', proposedFee: undefined });
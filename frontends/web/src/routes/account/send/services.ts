@@ -22,19 +22,33 @@ export type TProposalError = {
     addressError: string;
     amountError: string;
     feeError: string;
+    nonceError: string;
+    gasLimitError: string;
+    dataError: string;
 }
 
 export const txProposalErrorHandling = (registerEvents: () => void, unregisterEvents: () => void, errorCode?: string) => {
   const { t } = i18n;
   switch (errorCode) {
   case 'invalidAddress':
-    return { addressError: t('send.error.invalidAddress') };
+  case 'ensNameNotFound':
+  case 'silentPaymentsNotSupported':
+    return { addressError: t(`send.error.${errorCode}`) };
   case 'invalidAmount':
   case 'insufficientFunds':
     return { amountError: t(`send.error.${errorCode}`), proposedFee: undefined };
   case 'feeTooLow':
   case 'feesNotAvailable':
     return { feeError: t(`send.error.${errorCode}`) };
+  case 'invalidNonce':
+  case 'nonceGap':
+  case 'nonceTooLow':
+    return { nonceError: t(`send.error.${errorCode}`) };
+  case 'invalidGasLimit':
+  case 'gasLimitTooLow':
+    return { gasLimitError: t(`send.error.${errorCode}`) };
+  case 'invalidData':
+    return { dataError: t(`send.error.${errorCode}`) };
   default:
     if (errorCode) {
       unregisterEvents();
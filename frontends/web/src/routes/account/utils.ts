@@ -32,18 +32,22 @@ export function isBitcoinOnly(coinCode: CoinCode): boolean {
   switch (coinCode) {
   case 'btc':
   case 'tbtc':
+  case 'tbtc4':
+  case 'sbtc':
     return true;
   default:
     return false;
   }
 }
 
-export const isBitcoinCoin = (coin: CoinUnit) => (coin === 'BTC') || (coin === 'TBTC') || (coin === 'sat') || (coin === 'tsat');
+export const isBitcoinCoin = (coin: CoinUnit) => (coin === 'BTC') || (coin === 'TBTC') || (coin === 'TBTC4') || (coin === 'SBTC') || (coin === 'sat') || (coin === 'tsat');
 
 export function isBitcoinBased(coinCode: CoinCode): boolean {
   switch (coinCode) {
   case 'btc':
   case 'tbtc':
+  case 'tbtc4':
+  case 'sbtc':
   case 'ltc':
   case 'tltc':
     return true;
@@ -53,13 +57,26 @@ export function isBitcoinBased(coinCode: CoinCode): boolean {
 }
 
 export function isEthereumBased(coinCode: CoinCode): boolean {
-  return coinCode === 'eth' || coinCode === 'goeth' || coinCode === 'sepeth' || coinCode.startsWith('eth-erc20-');
+  switch (coinCode) {
+  case 'eth':
+  case 'goeth':
+  case 'sepeth':
+  case 'arbeth':
+  case 'opeth':
+  case 'baseeth':
+  case 'pol':
+    return true;
+  default:
+    return coinCode.startsWith('eth-erc20-');
+  }
 }
 
 export function getCoinCode(coinCode: CoinCode): CoinCode | undefined {
   switch (coinCode) {
   case 'btc':
   case 'tbtc':
+  case 'tbtc4':
+  case 'sbtc':
     return 'btc';
   case 'ltc':
   case 'tltc':
@@ -67,6 +84,10 @@ export function getCoinCode(coinCode: CoinCode): CoinCode | undefined {
   case 'eth':
   case 'goeth':
   case 'sepeth':
+  case 'arbeth':
+  case 'opeth':
+  case 'baseeth':
+  case 'pol':
     return 'eth';
   }
 }
@@ -1,7 +1,7 @@
 import { t } from 'i18next';
 import { SessionTypes } from '@walletconnect/types';
 import { EIP155_SIGNING_METHODS, decodeEthMessage } from './walletconnect';
-import { ethSignMessage, ethSignTypedMessage, ethSignWalletConnectTx, getEthAccountCodeAndNameByAddress } from '../api/account';
+import { ethDescribeTypedMessage, ethDescribeWalletConnectTx, ethSignMessage, ethSignTypedMessage, ethSignWalletConnectTx, getEthAccountCodeAndNameByAddress, TDescribeSigningRequest, TSigningSummary } from '../api/account';
 import { alertUser } from '../components/alert/Alert';
 
 type TWCParams = {
@@ -27,6 +27,7 @@ export type TRequestDialogContent = {
   signingData: string; // data / message coming from dapp
   currentSession: SessionTypes.Struct;
   method: string;
+  summary?: TSigningSummary; // human-readable interpretation of the data, if it could be decoded
 }
 
 export type TLaunchSignDialog = {
@@ -46,6 +47,20 @@ const fetchAccountNameAndAddress = async (address: string) => {
   return { accountName: name, accountCode: code };
 };
 
+const fetchSummary = async (describe: () => Promise<TDescribeSigningRequest>) => {
+  try {
+    const result = await describe();
+    if (!result.success) {
+      console.log('Failed to describe signing request', result.errorMessage); //silently fails
+      return undefined;
+    }
+    return result.summary;
+  } catch (e) {
+    console.error('Failed to describe signing request', e);
+    return undefined;
+  }
+};
+
 export async function handleWcEthSignRequest(method: string, args: TEthSignHandlerParams) {
   switch (method) {
   case EIP155_SIGNING_METHODS.ETH_SIGN:
@@ -123,6 +138,7 @@ async function ethSignTypedDataHandler({ params, launchSignDialog, topic, id, cu
     return;
     // If JSON parsing fails, typedData will be original data (unchanged).
   }
+  const summary = await fetchSummary(() => ethDescribeTypedMessage(accountCode, data));
 
   const apiCaller = async () => {
     // If the typed data to be signed includes its own chainId, we use that.
@@ -149,7 +165,8 @@ async function ethSignTypedDataHandler({ params, launchSignDialog, topic, id, cu
       accountName,
       accountAddress,
       chain: params.chainId,
-      method: t('walletConnect.signingRequest.method.signTypedData')
+      method: t('walletConnect.signingRequest.method.signTypedData'),
+      summary,
     }
   });
 }
@@ -164,9 +181,9 @@ async function ethSignOrSendTransactionHandler(args: TEthSignHandlerParams, meth
   const accountAddress = requestParams[0].from; // this is our wallet address
   const data = requestParams[0];
   const { accountName, accountCode } = await fetchAccountNameAndAddress(accountAddress);
+  const chainId = Number(params.chainId.replace(/^eip155:/, ''));
+  const summary = await fetchSummary(() => ethDescribeWalletConnectTx(accountCode, chainId, data));
   const apiCaller = async () => {
-    // If the typed data to be signed includes its own chainId, we use that, otherwise use the id in the params
-    const chainId = Number(params.chainId.replace(/^eip155:/, ''));
     const result = await ethSignWalletConnectTx(accountCode, isSendAndSign, chainId, data);
     if (result.success) {
       const response = { id, jsonrpc: '2.0', result: isSendAndSign ? result.txHash : result.rawTx };
@@ -190,6 +207,7 @@ async function ethSignOrSendTransactionHandler(args: TEthSignHandlerParams, meth
       accountAddress,
       chain: params.chainId,
       method: formattedMethod,
+      summary,
     }
   });
 }
@@ -6,6 +6,7 @@ require (
 	github.com/btcsuite/btcd v0.23.4
 	github.com/btcsuite/btcd/btcec/v2 v2.3.2
 	github.com/btcsuite/btcd/btcutil v1.1.3
+	github.com/btcsuite/btcd/btcutil/psbt v1.1.8
 	github.com/btcsuite/btcd/chaincfg/chainhash v1.0.3-0.20230901205239-f12a0b3b56c3
 	github.com/digitalbitbox/bitbox02-api-go v0.0.0-20231228154428-2ac5d3dfecc1
 	github.com/digitalbitbox/block-client-go v0.0.0-20230103135723-d8eaf468753d